@@ -0,0 +1,50 @@
+// Command reconcile checks the ledger consistency once and prints the report.
+// It exits with status 2 if any discrepancies are found.
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"os"
+
+	"github.com/go-kit/kit/log"
+	_ "github.com/lib/pq"
+	"github.com/twonegatives/coinsph_challenge/pkg/config"
+	"github.com/twonegatives/coinsph_challenge/pkg/pgstorage"
+	"github.com/twonegatives/coinsph_challenge/pkg/reconciliation"
+)
+
+const exitDiscrepancies = 2
+
+func main() {
+	cfg := config.NewConfig()
+	logger := log.NewJSONLogger(log.NewSyncWriter(os.Stderr))
+	logger = log.With(logger, "ts", log.DefaultTimestampUTC, "caller", log.DefaultCaller)
+
+	db, err := sql.Open("postgres", cfg.GetString("DB"))
+	if err != nil {
+		logger.Log("func", "main", "err", "can't open DB connection", err)
+		os.Exit(1)
+	}
+	defer db.Close()
+
+	svc := reconciliation.NewService(pgstorage.NewPgStorage(db))
+	report, err := svc.Reconcile(context.Background())
+	if err != nil {
+		logger.Log("func", "main", "err", "can't reconcile ledger", err)
+		db.Close()
+		os.Exit(1)
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(report); err != nil {
+		logger.Log("func", "main", "err", "can't encode report", err)
+	}
+
+	if !report.Consistent {
+		db.Close()
+		os.Exit(exitDiscrepancies)
+	}
+}
@@ -2,7 +2,8 @@ package main
 
 import (
 	"context"
-	"fmt"
+	"encoding/hex"
+	"io/ioutil"
 	"net/http"
 	"os"
 	"os/signal"
@@ -12,35 +13,123 @@ import (
 
 	"github.com/go-kit/kit/log"
 	_ "github.com/lib/pq"
+	"github.com/pkg/errors"
 	"github.com/twonegatives/coinsph_challenge/pkg/banking"
 	"github.com/twonegatives/coinsph_challenge/pkg/config"
+	"github.com/twonegatives/coinsph_challenge/pkg/entities"
+	"github.com/twonegatives/coinsph_challenge/pkg/fees"
+	"github.com/twonegatives/coinsph_challenge/pkg/fx"
+	"github.com/twonegatives/coinsph_challenge/pkg/jwt"
+	"github.com/twonegatives/coinsph_challenge/pkg/memstorage"
 	"github.com/twonegatives/coinsph_challenge/pkg/pgstorage"
+	"github.com/twonegatives/coinsph_challenge/pkg/reconciliation"
+	"github.com/twonegatives/coinsph_challenge/pkg/storage"
 )
 
 func main() {
 	cfg := config.NewConfig()
 	logger := initLogger()
 
-	dbString := cfg.GetString("DB")
-	db, err := sql.Open("postgres", dbString)
+	appStorage, closeStorage, err := initStorage(cfg.GetString("STORAGE"), cfg.GetString("DB"))
+	if err != nil {
+		logger.Log("func", "main", "err", "can't initialize storage", err)
+		os.Exit(1)
+	}
+
 	defer func() {
-		if err = db.Close(); err != nil {
-			logger.Log("func", "main", "err", fmt.Sprintf("can't close DB connection to %s", dbString), err)
+		if err := closeStorage(); err != nil {
+			logger.Log("func", "main", "err", "can't close storage", err)
 		}
 	}()
 
+	currencies, err := entities.ParseCurrencyRegistry(cfg.GetString("CURRENCIES"))
+	if err != nil {
+		logger.Log("func", "main", "err", "can't parse currencies configuration", err)
+		os.Exit(1)
+	}
+
+	rates, err := fx.ParseStaticRates(cfg.GetString("FX_RATES"))
+	if err != nil {
+		logger.Log("func", "main", "err", "can't parse exchange rates configuration", err)
+		os.Exit(1)
+	}
+
+	feeSchedule, err := fees.ParseSchedule(cfg.GetString("FEE_SCHEDULE"))
+	if err != nil {
+		logger.Log("func", "main", "err", "can't parse fee schedule configuration", err)
+		os.Exit(1)
+	}
+
+	serviceOpts := []banking.ServiceOption{
+		banking.WithCurrencies(currencies),
+		banking.WithRateProvider(rates),
+		banking.WithFeeSchedule(feeSchedule),
+		banking.WithQuoteTTL(cfg.GetDuration("QUOTE_TTL")),
+		banking.WithHoldTTL(cfg.GetDuration("HOLD_TTL")),
+		banking.WithScheduleLeaseTTL(cfg.GetDuration("SCHEDULE_LEASE_TTL")),
+		banking.WithScheduleRetries(cfg.GetInt("SCHEDULE_MAX_RETRIES"), cfg.GetDuration("SCHEDULE_RETRY_INTERVAL")),
+	}
+	if id := cfg.GetString("SCHEDULER_ID"); id != "" {
+		serviceOpts = append(serviceOpts, banking.WithSchedulerID(id))
+	}
+
+	handlerOpts := []banking.HandlerOption{
+		banking.WithIdempotencyStore(appStorage),
+		banking.WithOperatorToken(cfg.GetString("OPERATOR_TOKEN")),
+		banking.WithAdminToken(cfg.GetString("ADMIN_TOKEN")),
+	}
+	if cfg.GetBool("AUTH_REQUIRED") {
+		handlerOpts = append(handlerOpts, banking.WithAuthentication(appStorage))
+	}
+
+	if sealingKey := cfg.GetString("API_KEY_SEALING_KEY"); sealingKey != "" {
+		secrets, err := initSecretBox(sealingKey)
+		if err != nil {
+			logger.Log("func", "main", "err", "can't set up api key sealing", err)
+			os.Exit(1)
+		}
+		serviceOpts = append(serviceOpts, banking.WithSecretBox(secrets))
+		handlerOpts = append(handlerOpts, banking.WithSignedRequests(secrets, appStorage))
+	}
+
+	tokenKeys, err := initTokenKeys(cfg.GetString("JWT_JWKS_FILE"), cfg.GetString("JWT_HS256_SECRET"), cfg.GetString("JWT_PUBLIC_KEY_FILE"))
 	if err != nil {
-		logger.Log("func", "main", "err", fmt.Sprintf("can't open DB connection to %s", dbString), err)
+		logger.Log("func", "main", "err", "can't load JWT keys", err)
 		os.Exit(1)
 	}
+	if tokenKeys.Len() > 0 {
+		verifier := jwt.NewVerifier(tokenKeys, jwt.WithIssuer(cfg.GetString("JWT_ISSUER")), jwt.WithAudience(cfg.GetString("JWT_AUDIENCE")))
+		handlerOpts = append(handlerOpts, banking.WithJWT(verifier, cfg.GetString("JWT_OWNER_CLAIM")))
+	}
 
-	pgStorage := pgstorage.NewPgStorage(db)
+	reconciliationService := reconciliation.NewService(appStorage)
+	reconciliationHandler := reconciliation.MakeHandler(reconciliationService, logger)
+	handlerOpts = append(handlerOpts, banking.WithAdminHandler(reconciliation.ReportPath, reconciliationHandler))
 
-	bankingService := banking.NewService(pgStorage)
-	bankingHandler := banking.MakeHandler(bankingService, logger)
+	bankingService := banking.NewService(appStorage, serviceOpts...)
+	auditedService := banking.NewAuditedService(bankingService)
+	bankingHandler := banking.MakeHandler(auditedService, logger, handlerOpts...)
+
+	ctxHoldExpiry, stopHoldExpiry := context.WithCancel(context.Background())
+	defer stopHoldExpiry()
+	if interval := cfg.GetDuration("HOLD_EXPIRY_INTERVAL"); interval > 0 {
+		go banking.ExpireHoldsPeriodically(ctxHoldExpiry, auditedService, interval, logger)
+	}
+
+	ctxScheduler, stopScheduler := context.WithCancel(context.Background())
+	defer stopScheduler()
+	if interval := cfg.GetDuration("SCHEDULER_INTERVAL"); interval > 0 {
+		go banking.RunScheduledPaymentsPeriodically(ctxScheduler, auditedService, interval, logger)
+	}
+
+	ctxReconciliation, stopReconciliation := context.WithCancel(context.Background())
+	defer stopReconciliation()
+	if interval := cfg.GetDuration("RECONCILE_INTERVAL"); interval > 0 {
+		go reconciliation.RunPeriodically(ctxReconciliation, reconciliationService, interval, logger)
+	}
 
 	mux := http.NewServeMux()
-	mux.Handle("/api/v1/", http.StripPrefix("/api/v1", bankingHandler))
+	mux.Handle(banking.APIPrefix+"/", http.StripPrefix(banking.APIPrefix, bankingHandler))
 
 	srv := &http.Server{
 		Addr:    cfg.GetString("LISTEN"),
@@ -53,7 +142,7 @@ func main() {
 		errs <- srv.ListenAndServe()
 	}()
 
-	stop := make(chan os.Signal)
+	stop := make(chan os.Signal, 1)
 	signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
 
 	select {
@@ -75,3 +164,59 @@ func initLogger() log.Logger {
 	kitLogger = log.With(kitLogger, "ts", log.DefaultTimestampUTC, "caller", log.DefaultCaller)
 	return kitLogger
 }
+
+// initStorage returns a storage of requested kind along with a function
+// releasing resources it holds
+func initStorage(kind string, dbString string) (storage.Storage, func() error, error) {
+	switch kind {
+	case "memory":
+		return memstorage.NewMemStorage(), func() error { return nil }, nil
+	case "postgres":
+		db, err := sql.Open("postgres", dbString)
+		if err != nil {
+			return nil, nil, errors.Wrapf(err, "can't open DB connection to %s", dbString)
+		}
+		return pgstorage.NewPgStorage(db), db.Close, nil
+	default:
+		return nil, nil, errors.Errorf("unknown storage %s", kind)
+	}
+}
+
+// initTokenKeys returns keys verifying JWTs: those of the JWKS file along with
+// the shared HS256 secret and the PEM encoded public key, whichever are configured
+func initTokenKeys(jwksFile string, secret string, publicKeyFile string) (*jwt.KeySet, error) {
+	keys := jwt.NewKeySet()
+	if jwksFile != "" {
+		var err error
+		if keys, err = jwt.LoadJWKS(jwksFile); err != nil {
+			return nil, err
+		}
+	}
+
+	if secret != "" {
+		if err := keys.AddSecret("", []byte(secret)); err != nil {
+			return nil, err
+		}
+	}
+
+	if publicKeyFile != "" {
+		data, err := ioutil.ReadFile(publicKeyFile)
+		if err != nil {
+			return nil, errors.Wrapf(err, "can't read %s", publicKeyFile)
+		}
+
+		if err := keys.AddPublicKeyPEM("", data); err != nil {
+			return nil, err
+		}
+	}
+	return keys, nil
+}
+
+// initSecretBox sets up sealing of API key secrets with a hex encoded key
+func initSecretBox(key string) (*banking.SecretBox, error) {
+	raw, err := hex.DecodeString(key)
+	if err != nil {
+		return nil, errors.Wrap(err, "sealing key should be hex encoded")
+	}
+	return banking.NewSecretBox(raw)
+}
@@ -0,0 +1,11 @@
+
+-- +migrate Up
+ALTER TABLE accounts ALTER COLUMN currency TYPE varchar USING currency::text;
+ALTER TABLE payments ALTER COLUMN currency TYPE varchar USING currency::text;
+DROP TYPE IF EXISTS currency;
+
+-- +migrate Down
+
+CREATE TYPE currency AS ENUM('usd');
+ALTER TABLE accounts ALTER COLUMN currency TYPE currency USING currency::currency;
+ALTER TABLE payments ALTER COLUMN currency TYPE currency USING currency::currency;
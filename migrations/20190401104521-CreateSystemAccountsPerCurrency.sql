@@ -0,0 +1,21 @@
+
+-- +migrate Up
+
+INSERT INTO accounts(name, balance, currency)
+VALUES
+  ('SYSTEM_EUR', 0, 'eur'),
+  ('SYSTEM_GBP', 0, 'gbp'),
+  ('SYSTEM_JPY', 0, 'jpy'),
+  ('SYSTEM_PHP', 0, 'php'),
+  ('SYSTEM_BTC', 0, 'btc'),
+  ('SYSTEM_ETH', 0, 'eth');
+
+ALTER TABLE accounts DROP CONSTRAINT valid_balance;
+ALTER TABLE accounts ADD CONSTRAINT valid_balance CHECK (balance >= 0 OR name = 'SYSTEM' OR name LIKE 'SYSTEM\_%');
+
+-- +migrate Down
+
+ALTER TABLE accounts DROP CONSTRAINT valid_balance;
+ALTER TABLE accounts ADD CONSTRAINT valid_balance CHECK (balance >= 0 OR name = 'SYSTEM');
+
+DELETE FROM accounts WHERE name LIKE 'SYSTEM\_%';
@@ -0,0 +1,20 @@
+
+-- +migrate Up
+CREATE TABLE fx_quotes (
+  id serial,
+  from_currency  varchar     NOT NULL,
+  to_currency    varchar     NOT NULL,
+  rate           decimal     NOT NULL,
+  source_amount  decimal     NOT NULL,
+  target_amount  decimal     NOT NULL,
+  created_at     TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+  expires_at     TIMESTAMP WITH TIME ZONE NOT NULL,
+  transaction_id integer     UNIQUE REFERENCES transactions(id) ON DELETE RESTRICT,
+  PRIMARY KEY(id)
+);
+
+ALTER TABLE fx_quotes ADD CONSTRAINT valid_quote CHECK (rate > 0 AND source_amount > 0 AND target_amount > 0 AND from_currency != to_currency);
+
+-- +migrate Down
+
+DROP TABLE IF EXISTS fx_quotes;
@@ -0,0 +1,47 @@
+
+-- +migrate Up
+
+-- +migrate StatementBegin
+
+CREATE OR REPLACE FUNCTION check_if_tx_balanced()
+RETURNS TRIGGER
+AS $$
+DECLARE
+  unbalanced RECORD;
+BEGIN
+  SELECT currency, SUM(CASE WHEN direction = 'outgoing' THEN amount * -1 ELSE amount END) AS total
+  INTO unbalanced
+  FROM payments
+  WHERE transaction_id = NEW.id
+  GROUP BY currency
+  HAVING SUM(CASE WHEN direction = 'outgoing' THEN amount * -1 ELSE amount END) != 0
+  LIMIT 1;
+
+  IF FOUND THEN
+    RAISE EXCEPTION 'Sum of % payments (%) not equals zero for given transaction (id %)', unbalanced.currency, unbalanced.total, NEW.id;
+  END IF;
+  RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+-- +migrate StatementEnd
+
+-- +migrate Down
+
+-- +migrate StatementBegin
+
+CREATE OR REPLACE FUNCTION check_if_tx_balanced()
+RETURNS TRIGGER
+AS $$
+DECLARE
+  total integer;
+BEGIN
+  total := (SELECT SUM(CASE WHEN direction = 'outgoing' THEN amount * -1 ELSE amount END) FROM payments WHERE transaction_id = NEW.id);
+  IF (total != 0) THEN
+    RAISE EXCEPTION 'Sum of payments (%) not equals zero for given transaction (id %)', total, NEW.id;
+  END IF;
+  RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+-- +migrate StatementEnd
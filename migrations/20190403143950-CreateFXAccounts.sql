@@ -0,0 +1,22 @@
+
+-- +migrate Up
+
+INSERT INTO accounts(name, balance, currency)
+VALUES
+  ('FX_USD', 0, 'usd'),
+  ('FX_EUR', 0, 'eur'),
+  ('FX_GBP', 0, 'gbp'),
+  ('FX_JPY', 0, 'jpy'),
+  ('FX_PHP', 0, 'php'),
+  ('FX_BTC', 0, 'btc'),
+  ('FX_ETH', 0, 'eth');
+
+ALTER TABLE accounts DROP CONSTRAINT valid_balance;
+ALTER TABLE accounts ADD CONSTRAINT valid_balance CHECK (balance >= 0 OR name = 'SYSTEM' OR name LIKE 'SYSTEM\_%' OR name LIKE 'FX\_%');
+
+-- +migrate Down
+
+ALTER TABLE accounts DROP CONSTRAINT valid_balance;
+ALTER TABLE accounts ADD CONSTRAINT valid_balance CHECK (balance >= 0 OR name = 'SYSTEM' OR name LIKE 'SYSTEM\_%');
+
+DELETE FROM accounts WHERE name LIKE 'FX\_%';
@@ -0,0 +1,16 @@
+
+-- +migrate Up
+CREATE TABLE idempotency_keys (
+  key              varchar(255) NOT NULL,
+  request_hash     varchar(64)  NOT NULL,
+  response_status  integer,
+  response_headers jsonb,
+  response_body    bytea,
+  created_at       TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+  completed_at     TIMESTAMP WITH TIME ZONE,
+  PRIMARY KEY(key)
+);
+
+-- +migrate Down
+
+DROP TABLE IF EXISTS idempotency_keys;
@@ -0,0 +1,10 @@
+-- +migrate Up
+CREATE INDEX transactions_created_at_id_idx ON transactions(created_at, id);
+CREATE INDEX payments_transaction_id_id_idx ON payments(transaction_id, id);
+CREATE INDEX payments_account_id_idx ON payments(account_id);
+
+-- +migrate Down
+
+DROP INDEX IF EXISTS payments_account_id_idx;
+DROP INDEX IF EXISTS payments_transaction_id_id_idx;
+DROP INDEX IF EXISTS transactions_created_at_id_idx;
@@ -0,0 +1,14 @@
+-- +migrate Up
+ALTER TABLE accounts ADD COLUMN created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW();
+
+CREATE INDEX accounts_name_pattern_idx ON accounts(name varchar_pattern_ops);
+CREATE INDEX accounts_balance_id_idx ON accounts(balance, id);
+CREATE INDEX accounts_created_at_id_idx ON accounts(created_at, id);
+
+-- +migrate Down
+
+DROP INDEX IF EXISTS accounts_created_at_id_idx;
+DROP INDEX IF EXISTS accounts_balance_id_idx;
+DROP INDEX IF EXISTS accounts_name_pattern_idx;
+
+ALTER TABLE accounts DROP COLUMN IF EXISTS created_at;
@@ -0,0 +1,14 @@
+-- +migrate Up
+ALTER TABLE transactions ADD COLUMN kind varchar(16) NOT NULL DEFAULT 'transfer';
+ALTER TABLE transactions ADD COLUMN external_reference varchar(255);
+ALTER TABLE transactions ADD CONSTRAINT valid_kind CHECK (kind IN ('transfer', 'deposit', 'withdrawal'));
+
+CREATE UNIQUE INDEX transactions_kind_external_reference_idx ON transactions(kind, external_reference) WHERE external_reference IS NOT NULL;
+
+-- +migrate Down
+
+DROP INDEX IF EXISTS transactions_kind_external_reference_idx;
+
+ALTER TABLE transactions DROP CONSTRAINT IF EXISTS valid_kind;
+ALTER TABLE transactions DROP COLUMN IF EXISTS external_reference;
+ALTER TABLE transactions DROP COLUMN IF EXISTS kind;
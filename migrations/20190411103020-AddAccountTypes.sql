@@ -0,0 +1,17 @@
+-- +migrate Up
+ALTER TABLE accounts ADD COLUMN type varchar(16) NOT NULL DEFAULT 'user';
+ALTER TABLE accounts ADD CONSTRAINT valid_type CHECK (type IN ('user', 'system', 'settlement', 'fee', 'suspense'));
+
+UPDATE accounts SET type = 'system' WHERE name = 'SYSTEM' OR name LIKE 'SYSTEM\_%';
+UPDATE accounts SET type = 'settlement' WHERE name LIKE 'FX\_%';
+
+ALTER TABLE accounts DROP CONSTRAINT valid_balance;
+ALTER TABLE accounts ADD CONSTRAINT valid_balance CHECK (balance >= 0 OR type IN ('system', 'settlement', 'suspense'));
+
+-- +migrate Down
+
+ALTER TABLE accounts DROP CONSTRAINT valid_balance;
+ALTER TABLE accounts ADD CONSTRAINT valid_balance CHECK (balance >= 0 OR name = 'SYSTEM' OR name LIKE 'SYSTEM\_%' OR name LIKE 'FX\_%');
+
+ALTER TABLE accounts DROP CONSTRAINT IF EXISTS valid_type;
+ALTER TABLE accounts DROP COLUMN IF EXISTS type;
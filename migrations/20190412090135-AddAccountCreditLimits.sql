@@ -0,0 +1,29 @@
+-- +migrate Up
+ALTER TABLE accounts ADD COLUMN credit_limit decimal NOT NULL DEFAULT 0;
+ALTER TABLE accounts ADD CONSTRAINT valid_credit_limit CHECK (credit_limit >= 0);
+
+ALTER TABLE accounts DROP CONSTRAINT valid_balance;
+ALTER TABLE accounts ADD CONSTRAINT valid_balance CHECK (balance >= -credit_limit OR type IN ('system', 'settlement', 'suspense'));
+
+CREATE TABLE credit_limit_changes (
+  id serial,
+  account_id     integer     NOT NULL REFERENCES accounts(id) ON DELETE RESTRICT,
+  previous_limit decimal     NOT NULL,
+  new_limit      decimal     NOT NULL,
+  changed_by     varchar     NOT NULL,
+  reason         text,
+  created_at     TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+  PRIMARY KEY(id)
+);
+
+CREATE INDEX credit_limit_changes_account_id_idx ON credit_limit_changes(account_id, id);
+
+-- +migrate Down
+
+DROP TABLE IF EXISTS credit_limit_changes;
+
+ALTER TABLE accounts DROP CONSTRAINT valid_balance;
+ALTER TABLE accounts ADD CONSTRAINT valid_balance CHECK (balance >= 0 OR type IN ('system', 'settlement', 'suspense'));
+
+ALTER TABLE accounts DROP CONSTRAINT IF EXISTS valid_credit_limit;
+ALTER TABLE accounts DROP COLUMN IF EXISTS credit_limit;
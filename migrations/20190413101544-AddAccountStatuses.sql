@@ -0,0 +1,10 @@
+-- +migrate Up
+ALTER TABLE accounts ADD COLUMN status varchar NOT NULL DEFAULT 'active';
+ALTER TABLE accounts ADD CONSTRAINT valid_status CHECK (status IN ('active', 'frozen', 'closed'));
+ALTER TABLE accounts ADD CONSTRAINT closed_with_zero_balance CHECK (status <> 'closed' OR balance = 0);
+
+-- +migrate Down
+
+ALTER TABLE accounts DROP CONSTRAINT IF EXISTS closed_with_zero_balance;
+ALTER TABLE accounts DROP CONSTRAINT IF EXISTS valid_status;
+ALTER TABLE accounts DROP COLUMN IF EXISTS status;
@@ -0,0 +1,18 @@
+-- +migrate Up
+ALTER TABLE transactions DROP CONSTRAINT valid_kind;
+ALTER TABLE transactions ADD CONSTRAINT valid_kind CHECK (kind IN ('transfer', 'deposit', 'withdrawal', 'reversal', 'refund'));
+
+ALTER TABLE transactions ADD COLUMN original_transaction_id integer REFERENCES transactions(id) ON DELETE RESTRICT;
+ALTER TABLE transactions ADD CONSTRAINT linked_compensation CHECK ((kind IN ('reversal', 'refund')) = (original_transaction_id IS NOT NULL));
+
+CREATE INDEX transactions_original_transaction_id_idx ON transactions(original_transaction_id) WHERE original_transaction_id IS NOT NULL;
+
+-- +migrate Down
+
+DROP INDEX IF EXISTS transactions_original_transaction_id_idx;
+
+ALTER TABLE transactions DROP CONSTRAINT IF EXISTS linked_compensation;
+ALTER TABLE transactions DROP COLUMN IF EXISTS original_transaction_id;
+
+ALTER TABLE transactions DROP CONSTRAINT valid_kind;
+ALTER TABLE transactions ADD CONSTRAINT valid_kind CHECK (kind IN ('transfer', 'deposit', 'withdrawal'));
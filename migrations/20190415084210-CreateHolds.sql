@@ -0,0 +1,37 @@
+-- +migrate Up
+ALTER TABLE accounts ADD COLUMN held decimal NOT NULL DEFAULT 0;
+ALTER TABLE accounts ADD CONSTRAINT valid_held CHECK (held >= 0);
+ALTER TABLE accounts ADD CONSTRAINT closed_without_holds CHECK (status <> 'closed' OR held = 0);
+
+ALTER TABLE accounts DROP CONSTRAINT valid_balance;
+ALTER TABLE accounts ADD CONSTRAINT valid_balance CHECK (balance - held >= -credit_limit OR type IN ('system', 'settlement', 'suspense'));
+
+CREATE TABLE holds (
+  id serial,
+  account_id      integer     NOT NULL REFERENCES accounts(id) ON DELETE RESTRICT,
+  counterparty_id integer     NOT NULL REFERENCES accounts(id) ON DELETE RESTRICT,
+  amount          decimal     NOT NULL,
+  currency        varchar     NOT NULL,
+  status          varchar     NOT NULL DEFAULT 'pending',
+  created_at      TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+  expires_at      TIMESTAMP WITH TIME ZONE NOT NULL,
+  transaction_id  integer     UNIQUE REFERENCES transactions(id) ON DELETE RESTRICT,
+  PRIMARY KEY(id)
+);
+
+ALTER TABLE holds ADD CONSTRAINT valid_hold CHECK (amount > 0 AND account_id != counterparty_id);
+ALTER TABLE holds ADD CONSTRAINT valid_hold_status CHECK (status IN ('pending', 'captured', 'voided', 'expired'));
+ALTER TABLE holds ADD CONSTRAINT captured_with_transaction CHECK ((status = 'captured') = (transaction_id IS NOT NULL));
+
+CREATE INDEX holds_pending_expires_at_idx ON holds(expires_at) WHERE status = 'pending';
+
+-- +migrate Down
+
+DROP TABLE IF EXISTS holds;
+
+ALTER TABLE accounts DROP CONSTRAINT valid_balance;
+ALTER TABLE accounts ADD CONSTRAINT valid_balance CHECK (balance >= -credit_limit OR type IN ('system', 'settlement', 'suspense'));
+
+ALTER TABLE accounts DROP CONSTRAINT IF EXISTS closed_without_holds;
+ALTER TABLE accounts DROP CONSTRAINT IF EXISTS valid_held;
+ALTER TABLE accounts DROP COLUMN IF EXISTS held;
@@ -0,0 +1,20 @@
+-- +migrate Up
+ALTER TABLE transactions ADD COLUMN fee decimal NOT NULL DEFAULT 0;
+ALTER TABLE transactions ADD CONSTRAINT valid_fee CHECK (fee >= 0);
+
+INSERT INTO accounts(name, type, balance, currency)
+VALUES
+  ('FEE_USD', 'fee', 0, 'usd'),
+  ('FEE_EUR', 'fee', 0, 'eur'),
+  ('FEE_GBP', 'fee', 0, 'gbp'),
+  ('FEE_JPY', 'fee', 0, 'jpy'),
+  ('FEE_PHP', 'fee', 0, 'php'),
+  ('FEE_BTC', 'fee', 0, 'btc'),
+  ('FEE_ETH', 'fee', 0, 'eth');
+
+-- +migrate Down
+
+DELETE FROM accounts WHERE name LIKE 'FEE\_%';
+
+ALTER TABLE transactions DROP CONSTRAINT IF EXISTS valid_fee;
+ALTER TABLE transactions DROP COLUMN IF EXISTS fee;
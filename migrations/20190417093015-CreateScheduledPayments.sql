@@ -0,0 +1,50 @@
+-- +migrate Up
+CREATE TABLE scheduled_payments (
+  id serial,
+  account_id       integer     NOT NULL REFERENCES accounts(id) ON DELETE RESTRICT,
+  counterparty_id  integer     NOT NULL REFERENCES accounts(id) ON DELETE RESTRICT,
+  amount           decimal     NOT NULL,
+  currency         varchar     NOT NULL,
+  frequency        varchar(16) NOT NULL,
+  cron             varchar(255),
+  status           varchar(16) NOT NULL DEFAULT 'active',
+  start_at         TIMESTAMP WITH TIME ZONE NOT NULL,
+  end_at           TIMESTAMP WITH TIME ZONE,
+  next_run_at      TIMESTAMP WITH TIME ZONE NOT NULL,
+  retry_at         TIMESTAMP WITH TIME ZONE,
+  attempts         integer     NOT NULL DEFAULT 0,
+  lease_owner      varchar(255),
+  lease_expires_at TIMESTAMP WITH TIME ZONE,
+  created_at       TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+  PRIMARY KEY(id)
+);
+
+ALTER TABLE scheduled_payments ADD CONSTRAINT valid_scheduled_payment CHECK (amount > 0 AND account_id != counterparty_id AND attempts >= 0);
+ALTER TABLE scheduled_payments ADD CONSTRAINT valid_frequency CHECK (frequency IN ('once', 'daily', 'weekly', 'monthly', 'cron'));
+ALTER TABLE scheduled_payments ADD CONSTRAINT cron_with_expression CHECK ((frequency = 'cron') = (cron IS NOT NULL));
+ALTER TABLE scheduled_payments ADD CONSTRAINT valid_schedule_status CHECK (status IN ('active', 'paused', 'completed', 'cancelled'));
+
+CREATE INDEX scheduled_payments_due_idx ON scheduled_payments(COALESCE(retry_at, next_run_at)) WHERE status = 'active';
+CREATE INDEX scheduled_payments_account_id_idx ON scheduled_payments(account_id);
+
+CREATE TABLE scheduled_payment_runs (
+  id serial,
+  scheduled_payment_id integer     NOT NULL REFERENCES scheduled_payments(id) ON DELETE RESTRICT,
+  scheduled_for        TIMESTAMP WITH TIME ZONE NOT NULL,
+  attempt              integer     NOT NULL,
+  status               varchar(16) NOT NULL,
+  transaction_id       integer     UNIQUE REFERENCES transactions(id) ON DELETE RESTRICT,
+  error                text,
+  created_at           TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+  PRIMARY KEY(id)
+);
+
+ALTER TABLE scheduled_payment_runs ADD CONSTRAINT valid_run_status CHECK (status IN ('succeeded', 'failed'));
+ALTER TABLE scheduled_payment_runs ADD CONSTRAINT succeeded_with_transaction CHECK ((status = 'succeeded') = (transaction_id IS NOT NULL));
+
+CREATE INDEX scheduled_payment_runs_scheduled_payment_id_idx ON scheduled_payment_runs(scheduled_payment_id);
+
+-- +migrate Down
+
+DROP TABLE IF EXISTS scheduled_payment_runs;
+DROP TABLE IF EXISTS scheduled_payments;
@@ -0,0 +1,22 @@
+-- +migrate Up
+ALTER TABLE transactions ADD COLUMN description text;
+ALTER TABLE transactions ADD COLUMN metadata jsonb;
+ALTER TABLE transactions ADD COLUMN sender_id integer REFERENCES accounts(id) ON DELETE RESTRICT;
+ALTER TABLE transactions ADD CONSTRAINT sender_with_reference CHECK (sender_id IS NULL OR external_reference IS NOT NULL);
+
+-- references of transfers are unique per sender, the ones of deposits and withdrawals stay unique per kind
+DROP INDEX IF EXISTS transactions_kind_external_reference_idx;
+CREATE UNIQUE INDEX transactions_kind_external_reference_idx ON transactions(kind, external_reference) WHERE external_reference IS NOT NULL AND sender_id IS NULL;
+CREATE UNIQUE INDEX transactions_sender_external_reference_idx ON transactions(sender_id, external_reference) WHERE sender_id IS NOT NULL;
+CREATE INDEX transactions_external_reference_idx ON transactions(external_reference) WHERE external_reference IS NOT NULL;
+
+-- +migrate Down
+
+DROP INDEX IF EXISTS transactions_external_reference_idx;
+DROP INDEX IF EXISTS transactions_sender_external_reference_idx;
+DROP INDEX IF EXISTS transactions_kind_external_reference_idx;
+CREATE UNIQUE INDEX transactions_kind_external_reference_idx ON transactions(kind, external_reference) WHERE external_reference IS NOT NULL;
+ALTER TABLE transactions DROP CONSTRAINT IF EXISTS sender_with_reference;
+ALTER TABLE transactions DROP COLUMN IF EXISTS sender_id;
+ALTER TABLE transactions DROP COLUMN IF EXISTS metadata;
+ALTER TABLE transactions DROP COLUMN IF EXISTS description;
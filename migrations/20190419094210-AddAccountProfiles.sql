@@ -0,0 +1,12 @@
+-- +migrate Up
+ALTER TABLE accounts ADD COLUMN owner_id varchar;
+ALTER TABLE accounts ADD COLUMN display_name varchar;
+ALTER TABLE accounts ADD COLUMN labels varchar[] NOT NULL DEFAULT '{}';
+CREATE INDEX accounts_owner_id_idx ON accounts (owner_id) WHERE owner_id IS NOT NULL;
+
+-- +migrate Down
+
+DROP INDEX IF EXISTS accounts_owner_id_idx;
+ALTER TABLE accounts DROP COLUMN IF EXISTS labels;
+ALTER TABLE accounts DROP COLUMN IF EXISTS display_name;
+ALTER TABLE accounts DROP COLUMN IF EXISTS owner_id;
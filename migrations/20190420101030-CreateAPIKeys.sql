@@ -0,0 +1,24 @@
+-- +migrate Up
+CREATE TABLE api_keys (
+  id serial,
+  key_id      varchar     NOT NULL UNIQUE,
+  name        varchar     NOT NULL,
+  secret_hash varchar     NOT NULL,
+  role        varchar     NOT NULL,
+  owner_id    varchar,
+  created_at  TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+  revoked_at  TIMESTAMP WITH TIME ZONE,
+  PRIMARY KEY(id)
+);
+
+ALTER TABLE api_keys ADD CONSTRAINT valid_api_key_role CHECK (role IN ('viewer', 'account-owner', 'operator', 'admin'));
+ALTER TABLE api_keys ADD CONSTRAINT account_owner_with_owner CHECK (role <> 'account-owner' OR owner_id IS NOT NULL);
+
+-- idempotency keys are scoped by the caller, which makes them longer than the ones clients send
+ALTER TABLE idempotency_keys ALTER COLUMN key TYPE varchar;
+
+-- +migrate Down
+
+ALTER TABLE idempotency_keys ALTER COLUMN key TYPE varchar(255);
+
+DROP TABLE IF EXISTS api_keys;
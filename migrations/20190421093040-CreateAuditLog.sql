@@ -0,0 +1,41 @@
+-- +migrate Up
+CREATE TABLE audit_log (
+  id             integer     NOT NULL,
+  subject        varchar     NOT NULL,
+  role           varchar     NOT NULL,
+  action         varchar     NOT NULL,
+  payload_hash   varchar     NOT NULL,
+  outcome        varchar     NOT NULL,
+  error          varchar     NOT NULL,
+  correlation_id varchar     NOT NULL,
+  created_at     TIMESTAMP WITH TIME ZONE NOT NULL,
+  prev_hash      varchar     NOT NULL,
+  hash           varchar     NOT NULL,
+  PRIMARY KEY(id)
+);
+
+ALTER TABLE audit_log ADD CONSTRAINT valid_audit_outcome CHECK (outcome IN ('succeeded', 'failed'));
+
+CREATE INDEX audit_log_subject_idx ON audit_log (subject, id);
+CREATE INDEX audit_log_correlation_id_idx ON audit_log (correlation_id);
+
+-- +migrate StatementBegin
+
+CREATE OR REPLACE FUNCTION forbid_audit_log_changes()
+RETURNS TRIGGER
+AS $$
+BEGIN
+  RAISE EXCEPTION 'Audit log entries can not be changed or removed';
+END;
+$$ LANGUAGE plpgsql;
+
+-- +migrate StatementEnd
+
+CREATE TRIGGER audit_log_append_only BEFORE UPDATE OR DELETE ON audit_log FOR EACH ROW EXECUTE PROCEDURE forbid_audit_log_changes();
+CREATE TRIGGER audit_log_not_truncated BEFORE TRUNCATE ON audit_log FOR EACH STATEMENT EXECUTE PROCEDURE forbid_audit_log_changes();
+
+-- +migrate Down
+
+DROP TABLE IF EXISTS audit_log;
+
+DROP FUNCTION IF EXISTS forbid_audit_log_changes();
@@ -0,0 +1,12 @@
+-- +migrate Up
+ALTER TABLE idempotency_keys ADD COLUMN lock_id varchar(32) NOT NULL DEFAULT '';
+ALTER TABLE idempotency_keys ADD COLUMN locked_until TIMESTAMP WITH TIME ZONE;
+ALTER TABLE idempotency_keys ADD COLUMN committed_at TIMESTAMP WITH TIME ZONE;
+ALTER TABLE idempotency_keys ADD COLUMN transaction_id integer REFERENCES transactions(id) ON DELETE RESTRICT;
+
+-- +migrate Down
+
+ALTER TABLE idempotency_keys DROP COLUMN IF EXISTS transaction_id;
+ALTER TABLE idempotency_keys DROP COLUMN IF EXISTS committed_at;
+ALTER TABLE idempotency_keys DROP COLUMN IF EXISTS locked_until;
+ALTER TABLE idempotency_keys DROP COLUMN IF EXISTS lock_id;
@@ -0,0 +1,7 @@
+-- +migrate Up
+-- secrets of keys sealed with the server side key, blank for keys which can't sign requests
+ALTER TABLE api_keys ADD COLUMN sealed_secret varchar NOT NULL DEFAULT '';
+
+-- +migrate Down
+
+ALTER TABLE api_keys DROP COLUMN IF EXISTS sealed_secret;
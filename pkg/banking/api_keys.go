@@ -0,0 +1,139 @@
+package banking
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/pkg/errors"
+	"github.com/twonegatives/coinsph_challenge/pkg/entities"
+	"github.com/twonegatives/coinsph_challenge/pkg/storage"
+)
+
+const (
+	apiKeyIDPrefix       = "ak_"
+	apiKeyIDBytes        = 8
+	apiKeySecretBytes    = 32
+	maxAPIKeyNameLength  = 100
+	apiKeyTokenSeparator = "."
+)
+
+var (
+	errAPIKeyNameBlank    = errors.New("api key name should be present")
+	errAPIKeyNameTooLong  = errors.Errorf("api key name should not be longer than %d characters", maxAPIKeyNameLength)
+	errInvalidRole        = errors.New("role should be one of viewer, account-owner, operator or admin")
+	errAPIKeyOwnerMissing = errors.New("account owner keys should have an owner id")
+	errAPIKeyNotFound     = errors.New("api key not found")
+)
+
+// CreateAPIKey issues a new API key of the given role. Keys of account owners
+// should be linked to an owner, keys of other roles may be linked to one too.
+// Returns the key along with its secret, which is not stored in plain form and can't be shown again.
+// The secret is kept sealed with the server side key, if any, for request signatures to be verified.
+func (svc *Service) CreateAPIKey(ctx context.Context, name string, role entities.Role, ownerID string) (entities.IssuedAPIKey, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return entities.IssuedAPIKey{}, errAPIKeyNameBlank
+	}
+
+	if utf8.RuneCountInString(name) > maxAPIKeyNameLength {
+		return entities.IssuedAPIKey{}, errAPIKeyNameTooLong
+	}
+
+	if !role.IsValid() {
+		return entities.IssuedAPIKey{}, errInvalidRole
+	}
+
+	ownerID = strings.TrimSpace(ownerID)
+	if utf8.RuneCountInString(ownerID) > maxOwnerIDLength {
+		return entities.IssuedAPIKey{}, errOwnerIDTooLong
+	}
+
+	if role == entities.AccountOwnerRole && ownerID == "" {
+		return entities.IssuedAPIKey{}, errAPIKeyOwnerMissing
+	}
+
+	keyID, err := randomHex(apiKeyIDBytes)
+	if err != nil {
+		return entities.IssuedAPIKey{}, errors.Wrap(err, "can't generate api key id")
+	}
+
+	secret, err := randomHex(apiKeySecretBytes)
+	if err != nil {
+		return entities.IssuedAPIKey{}, errors.Wrap(err, "can't generate api key secret")
+	}
+
+	key := entities.APIKey{
+		KeyID:      apiKeyIDPrefix + keyID,
+		Name:       name,
+		SecretHash: hashSecret(secret),
+		Role:       role,
+		OwnerID:    ownerID,
+	}
+
+	if svc.secrets != nil {
+		if key.SealedSecret, err = svc.secrets.seal(key.KeyID, secret); err != nil {
+			return entities.IssuedAPIKey{}, errors.Wrap(err, "can't seal api key secret")
+		}
+	}
+
+	err = svc.inTx(ctx, func(txStorage storage.Storage) (err error) {
+		key, err = txStorage.CreateAPIKey(ctx, key)
+		return errors.Wrap(err, "failed to create api key in database")
+	})
+	if err != nil {
+		return entities.IssuedAPIKey{}, err
+	}
+
+	return entities.IssuedAPIKey{APIKey: key, Secret: secret}, nil
+}
+
+// GetAPIKeys returns all the API keys issued so far, revoked ones included.
+func (svc *Service) GetAPIKeys(ctx context.Context) ([]entities.APIKey, error) {
+	keys, err := svc.store.GetAPIKeys(ctx)
+	return keys, errors.Wrap(err, "failed to fetch api keys from database")
+}
+
+// RevokeAPIKey makes the key unusable for authentication. Revoking a key
+// twice keeps its original revocation time. Returns the revoked APIKey on success.
+func (svc *Service) RevokeAPIKey(ctx context.Context, keyID string) (entities.APIKey, error) {
+	var key entities.APIKey
+	err := svc.inTx(ctx, func(txStorage storage.Storage) (err error) {
+		key, err = txStorage.GetAPIKey(ctx, keyID)
+		if errors.Cause(err) == storage.ErrNotFound {
+			return errAPIKeyNotFound
+		}
+		if err != nil {
+			return errors.Wrap(err, "failed to fetch api key from database")
+		}
+
+		key.RevokedAt = svc.now()
+		if err := txStorage.RevokeAPIKey(ctx, key); err != nil {
+			return errors.Wrap(err, "failed to revoke api key in database")
+		}
+
+		key, err = txStorage.GetAPIKey(ctx, keyID)
+		return errors.Wrap(err, "failed to fetch api key from database")
+	})
+	if err != nil {
+		return entities.APIKey{}, err
+	}
+	return key, nil
+}
+
+// hashSecret returns a hex encoded SHA-256 hash of the API key secret
+func hashSecret(secret string) string {
+	hash := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(hash[:])
+}
+
+func randomHex(size int) (string, error) {
+	buf := make([]byte, size)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
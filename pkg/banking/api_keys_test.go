@@ -0,0 +1,89 @@
+package banking_test
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/twonegatives/coinsph_challenge/pkg/banking"
+	"github.com/twonegatives/coinsph_challenge/pkg/entities"
+	"github.com/twonegatives/coinsph_challenge/pkg/memstorage"
+)
+
+func TestBankingSvcAPIKeys(t *testing.T) {
+	t.Run("issues key storing hash of its secret only", func(t *testing.T) {
+		store := memstorage.NewMemStorage()
+		svc := banking.NewService(store)
+
+		issued, err := svc.CreateAPIKey(ctx, " Alice's shop ", entities.AccountOwnerRole, " customer-1 ")
+		require.NoError(t, err)
+		assert.True(t, strings.HasPrefix(issued.KeyID, "ak_"))
+		assert.Equal(t, "Alice's shop", issued.Name)
+		assert.Equal(t, "customer-1", issued.OwnerID)
+		assert.Len(t, issued.Secret, 64)
+		assert.Equal(t, issued.KeyID+"."+issued.Secret, issued.Token())
+
+		stored, err := store.GetAPIKey(ctx, issued.KeyID)
+		require.NoError(t, err)
+		hash := sha256.Sum256([]byte(issued.Secret))
+		assert.Equal(t, hex.EncodeToString(hash[:]), stored.SecretHash)
+		assert.NotContains(t, stored.SecretHash, issued.Secret)
+		assert.Empty(t, stored.SealedSecret)
+	})
+
+	t.Run("seals secret with the box", func(t *testing.T) {
+		store := memstorage.NewMemStorage()
+		box, err := banking.NewSecretBox([]byte(strings.Repeat("k", banking.SecretBoxKeySize)))
+		require.NoError(t, err)
+
+		issued, err := banking.NewService(store, banking.WithSecretBox(box)).CreateAPIKey(ctx, "back office", entities.OperatorRole, "")
+		require.NoError(t, err)
+
+		stored, err := store.GetAPIKey(ctx, issued.KeyID)
+		require.NoError(t, err)
+		assert.NotEmpty(t, stored.SealedSecret)
+		assert.NotContains(t, stored.SealedSecret, issued.Secret)
+	})
+
+	t.Run("revokes key", func(t *testing.T) {
+		svc := banking.NewService(memstorage.NewMemStorage())
+		issued, err := svc.CreateAPIKey(ctx, "back office", entities.OperatorRole, "")
+		require.NoError(t, err)
+
+		revoked, err := svc.RevokeAPIKey(ctx, issued.KeyID)
+		require.NoError(t, err)
+		assert.True(t, revoked.IsRevoked())
+
+		keys, err := svc.GetAPIKeys(ctx)
+		require.NoError(t, err)
+		require.Len(t, keys, 1)
+		assert.True(t, keys[0].IsRevoked())
+
+		_, err = svc.RevokeAPIKey(ctx, "ak_unknown")
+		assert.EqualError(t, err, "api key not found")
+	})
+
+	t.Run("validates key attributes", func(t *testing.T) {
+		svc := banking.NewService(memstorage.NewMemStorage())
+		cases := []struct {
+			name    string
+			role    entities.Role
+			ownerID string
+			err     string
+		}{
+			{name: " ", role: entities.ViewerRole, err: "api key name should be present"},
+			{name: strings.Repeat("n", 101), role: entities.ViewerRole, err: "api key name should not be longer than 100 characters"},
+			{name: "root", role: entities.Role("root"), err: "role should be one of viewer, account-owner, operator or admin"},
+			{name: "shop", role: entities.AccountOwnerRole, err: "account owner keys should have an owner id"},
+			{name: "shop", role: entities.AccountOwnerRole, ownerID: strings.Repeat("o", 65), err: "owner id should not be longer than 64 characters"},
+		}
+
+		for _, tc := range cases {
+			_, err := svc.CreateAPIKey(ctx, tc.name, tc.role, tc.ownerID)
+			assert.EqualError(t, err, tc.err)
+		}
+	})
+}
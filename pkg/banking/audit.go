@@ -0,0 +1,390 @@
+package banking
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/shopspring/decimal"
+	"github.com/twonegatives/coinsph_challenge/pkg/entities"
+	"github.com/twonegatives/coinsph_challenge/pkg/storage"
+)
+
+// Actions audit entries are recorded for, one per mutating BankingService method.
+const (
+	CreateAccountAction          = "accounts.create"
+	UpdateAccountAction          = "accounts.update"
+	FreezeAccountAction          = "accounts.freeze"
+	UnfreezeAccountAction        = "accounts.unfreeze"
+	CloseAccountAction           = "accounts.close"
+	SendPaymentAction            = "payments.send"
+	SendFXPaymentAction          = "fx_payments.send"
+	SendPaymentBatchAction       = "payment_batches.send"
+	PostTransactionAction        = "transactions.post"
+	ReverseTransactionAction     = "transactions.reverse"
+	RefundTransactionAction      = "transactions.refund"
+	DepositAction                = "deposits.create"
+	WithdrawAction               = "withdrawals.create"
+	SetCreditLimitAction         = "credit_limits.set"
+	CreateQuoteAction            = "quotes.create"
+	AuthorizeAction              = "authorizations.create"
+	CaptureHoldAction            = "authorizations.capture"
+	VoidHoldAction               = "authorizations.void"
+	CreateScheduledPaymentAction = "scheduled_payments.create"
+	UpdateScheduledPaymentAction = "scheduled_payments.update"
+	CancelScheduledPaymentAction = "scheduled_payments.cancel"
+	CreateAPIKeyAction           = "api_keys.create"
+	RevokeAPIKeyAction           = "api_keys.revoke"
+	ExpireHoldsAction            = "authorizations.expire"
+	RunScheduledPaymentsAction   = "scheduled_payments.run"
+)
+
+var errInvalidOutcome = errors.New("outcome should be either succeeded or failed")
+
+type auditContextKey struct{}
+
+// pendingAudit is an audit entry of a call in progress. A succeeded entry is recorded
+// by every storage transaction the call commits, commits counts them.
+type pendingAudit struct {
+	entry   entities.AuditEntry
+	commits int
+}
+
+// auditedService is a BankingService recording audit entries of mutating calls.
+// Every storage transaction a call commits records a succeeded entry along with its changes,
+// so calls committing several transactions (e.g. best-effort batches) leave an entry per each.
+// Calls which fail, even after committing some of their transactions, record a failed entry
+// within a transaction of their own, as well as calls which succeed without committing anything.
+type auditedService struct {
+	*Service
+}
+
+// NewAuditedService returns the service wrapped so that every mutating call is recorded
+// into the audit log. Calls the service makes on its own (expiring holds and running
+// scheduled payments) are recorded on behalf of the system principal of the scheduler.
+func NewAuditedService(svc *Service) BankingService {
+	return auditedService{Service: svc}
+}
+
+// systemPrincipal is the caller of the calls the service makes on its own. Workers act
+// on any account the way they do without a principal, so it is given the admin role.
+func (s auditedService) systemPrincipal() entities.Principal {
+	return entities.Principal{Subject: "scheduler:" + s.schedulerID, Role: entities.AdminRole}
+}
+
+// begin returns a copy of the context carrying a pending audit entry of the call
+func (s auditedService) begin(ctx context.Context, action string, payload ...interface{}) (context.Context, *pendingAudit) {
+	principal, _ := PrincipalFromContext(ctx)
+	pending := &pendingAudit{entry: entities.AuditEntry{
+		Subject:       principal.Subject,
+		Role:          principal.Role,
+		Action:        action,
+		PayloadHash:   payloadHash(payload),
+		Outcome:       entities.SucceededOutcome,
+		CorrelationID: CorrelationIDFromContext(ctx),
+	}}
+	return context.WithValue(ctx, auditContextKey{}, pending), pending
+}
+
+// finish records the final outcome of the call unless it succeeded and its storage
+// transactions recorded it already. The error of the call is returned as it is,
+// while failures to record entries of successful calls are returned instead of nil.
+func (s auditedService) finish(ctx context.Context, pending *pendingAudit, err error) error {
+	if err == nil && pending.commits > 0 {
+		return nil
+	}
+
+	auditErr := s.record(ctx, pending, err)
+	if err != nil {
+		return err
+	}
+	return auditErr
+}
+
+// finishRun records the outcome of a worker call. Workers are called every interval and mostly
+// find nothing to do, so successful calls are left to the entries of transactions they commit.
+func (s auditedService) finishRun(ctx context.Context, pending *pendingAudit, err error) error {
+	if err == nil {
+		return nil
+	}
+	return s.finish(ctx, pending, err)
+}
+
+// record appends an entry of the call failed with the error (succeeded if it is nil)
+// within a storage transaction of its own. The entry is recorded even if the call context is done.
+func (s auditedService) record(ctx context.Context, pending *pendingAudit, callErr error) error {
+	entry := pending.entry
+	if callErr != nil {
+		entry.Outcome = entities.FailedOutcome
+		entry.Error = callErr.Error()
+	}
+
+	ctx = detach(ctx)
+	txStorage, err := s.store.BeginTx(ctx, nil)
+	if err != nil {
+		return errors.Wrap(err, "can't open transaction")
+	}
+
+	defer txStorage.RollbackTx(ctx)
+
+	if err := s.appendAuditEntry(ctx, txStorage, entry); err != nil {
+		return err
+	}
+	return errors.Wrap(txStorage.CommitTx(ctx), "transaction commit failed")
+}
+
+// batchFailure describes payments of the batch which were not booked, nil if there are none
+func batchFailure(receipt entities.BatchReceipt) error {
+	failures := []string{}
+	for index, item := range receipt.Items {
+		if item.Err != nil {
+			failures = append(failures, fmt.Sprintf("payments[%d]: %s", index, item.Err))
+		}
+	}
+
+	if len(failures) == 0 {
+		return nil
+	}
+	return errors.New(strings.Join(failures, "; "))
+}
+
+// appendAuditEntry appends the entry within the storage transaction.
+// Storage stamps the entry once it holds the lock of the chain.
+func (svc *Service) appendAuditEntry(ctx context.Context, txStorage storage.Storage, entry entities.AuditEntry) error {
+	_, err := txStorage.AppendAuditEntry(ctx, entry)
+	return errors.Wrap(err, "can't record audit entry")
+}
+
+// payloadHash returns a hex encoded SHA-256 hash of JSON encoded arguments of the call
+func payloadHash(payload []interface{}) string {
+	// arguments are plain values, structures and maps of them, so marshalling can't fail
+	encoded, _ := json.Marshal(payload)
+	hash := sha256.Sum256(encoded)
+	return hex.EncodeToString(hash[:])
+}
+
+// GetAuditLog returns a page of audit entries matching the filter in order they were recorded.
+// Paging works the same way as it does for GetPaymentsList.
+func (svc *Service) GetAuditLog(ctx context.Context, filter entities.AuditFilter) (entities.AuditPage, error) {
+	if err := validateLimit(&filter.Limit); err != nil {
+		return entities.AuditPage{}, err
+	}
+
+	if filter.Outcome != "" && filter.Outcome != entities.SucceededOutcome && filter.Outcome != entities.FailedOutcome {
+		return entities.AuditPage{}, errInvalidOutcome
+	}
+
+	if !filter.FromDate.IsZero() && !filter.ToDate.IsZero() && !filter.FromDate.Before(filter.ToDate) {
+		return entities.AuditPage{}, errInvalidDateRange
+	}
+
+	// one extra entry is requested to find out whether the next page exists
+	limit := filter.Limit
+	filter.Limit++
+
+	entries, err := svc.store.GetAuditEntries(ctx, filter)
+	if err != nil {
+		return entities.AuditPage{}, errors.Wrap(err, "failed to fetch audit entries from database")
+	}
+
+	page := entities.AuditPage{Entries: entries}
+	if len(entries) > limit {
+		page.Entries = entries[:limit]
+		page.NextCursor = entities.AuditCursor{ID: entries[limit-1].ID}.Encode()
+	}
+
+	return page, nil
+}
+
+// VerifyAuditLog walks through the whole audit log and checks every entry follows
+// the previous one: it has the next ID, refers to the hash of the previous entry
+// and its own hash matches its attributes.
+func (svc *Service) VerifyAuditLog(ctx context.Context) (entities.AuditVerification, error) {
+	verification := entities.AuditVerification{Consistent: true}
+	var previous entities.AuditEntry
+	for {
+		filter := entities.AuditFilter{Limit: MaxPageLimit}
+		if previous.ID > 0 {
+			filter.After = &entities.AuditCursor{ID: previous.ID}
+		}
+
+		entries, err := svc.store.GetAuditEntries(ctx, filter)
+		if err != nil {
+			return entities.AuditVerification{}, errors.Wrap(err, "failed to fetch audit entries from database")
+		}
+
+		for _, entry := range entries {
+			verification.EntriesChecked++
+			if !entry.Follows(previous) {
+				verification.Consistent = false
+				verification.BrokenAt = entry.ID
+				return verification, nil
+			}
+
+			previous = entry
+			verification.LastHash = entry.Hash
+		}
+
+		if len(entries) < MaxPageLimit {
+			return verification, nil
+		}
+	}
+}
+
+func (s auditedService) CreateAccount(ctx context.Context, accountName string, currency entities.Currency, profile entities.AccountProfile) (entities.Account, error) {
+	ctx, pending := s.begin(ctx, CreateAccountAction, accountName, currency, profile)
+	account, err := s.Service.CreateAccount(ctx, accountName, currency, profile)
+	return account, s.finish(ctx, pending, err)
+}
+
+func (s auditedService) UpdateAccount(ctx context.Context, accountName string, update entities.AccountUpdate) (entities.Account, error) {
+	ctx, pending := s.begin(ctx, UpdateAccountAction, accountName, update)
+	account, err := s.Service.UpdateAccount(ctx, accountName, update)
+	return account, s.finish(ctx, pending, err)
+}
+
+func (s auditedService) FreezeAccount(ctx context.Context, accountName string) (entities.Account, error) {
+	ctx, pending := s.begin(ctx, FreezeAccountAction, accountName)
+	account, err := s.Service.FreezeAccount(ctx, accountName)
+	return account, s.finish(ctx, pending, err)
+}
+
+func (s auditedService) UnfreezeAccount(ctx context.Context, accountName string) (entities.Account, error) {
+	ctx, pending := s.begin(ctx, UnfreezeAccountAction, accountName)
+	account, err := s.Service.UnfreezeAccount(ctx, accountName)
+	return account, s.finish(ctx, pending, err)
+}
+
+func (s auditedService) CloseAccount(ctx context.Context, accountName string) (entities.Account, error) {
+	ctx, pending := s.begin(ctx, CloseAccountAction, accountName)
+	account, err := s.Service.CloseAccount(ctx, accountName)
+	return account, s.finish(ctx, pending, err)
+}
+
+func (s auditedService) SendPayment(ctx context.Context, from entities.Account, to entities.Account, amount decimal.Decimal, details entities.PaymentDetails) (entities.TransferReceipt, error) {
+	ctx, pending := s.begin(ctx, SendPaymentAction, from.Name, to.Name, amount, details)
+	receipt, err := s.Service.SendPayment(ctx, from, to, amount, details)
+	return receipt, s.finish(ctx, pending, err)
+}
+
+func (s auditedService) SendFXPayment(ctx context.Context, from entities.Account, to entities.Account, amount decimal.Decimal, quoteID int, details entities.PaymentDetails) (entities.TransferReceipt, error) {
+	ctx, pending := s.begin(ctx, SendFXPaymentAction, from.Name, to.Name, amount, quoteID, details)
+	receipt, err := s.Service.SendFXPayment(ctx, from, to, amount, quoteID, details)
+	return receipt, s.finish(ctx, pending, err)
+}
+
+func (s auditedService) SendPaymentBatch(ctx context.Context, orders []entities.PaymentOrder, mode entities.BatchMode) (entities.BatchReceipt, error) {
+	ctx, pending := s.begin(ctx, SendPaymentBatchAction, orders, mode)
+	receipt, err := s.Service.SendPaymentBatch(ctx, orders, mode)
+	if failure := batchFailure(receipt); err == nil && failure != nil {
+		// booked payments were recorded by their transactions, while the batch as a whole failed
+		return receipt, s.record(ctx, pending, failure)
+	}
+	return receipt, s.finish(ctx, pending, err)
+}
+
+func (s auditedService) PostTransaction(ctx context.Context, legs []entities.Leg) (entities.PostingReceipt, error) {
+	ctx, pending := s.begin(ctx, PostTransactionAction, legs)
+	receipt, err := s.Service.PostTransaction(ctx, legs)
+	return receipt, s.finish(ctx, pending, err)
+}
+
+func (s auditedService) ReverseTransaction(ctx context.Context, id int) (entities.TransferReceipt, error) {
+	ctx, pending := s.begin(ctx, ReverseTransactionAction, id)
+	receipt, err := s.Service.ReverseTransaction(ctx, id)
+	return receipt, s.finish(ctx, pending, err)
+}
+
+func (s auditedService) RefundTransaction(ctx context.Context, id int, amount decimal.Decimal) (entities.TransferReceipt, error) {
+	ctx, pending := s.begin(ctx, RefundTransactionAction, id, amount)
+	receipt, err := s.Service.RefundTransaction(ctx, id, amount)
+	return receipt, s.finish(ctx, pending, err)
+}
+
+func (s auditedService) Deposit(ctx context.Context, accountName string, amount decimal.Decimal, reference string) (entities.TransferReceipt, error) {
+	ctx, pending := s.begin(ctx, DepositAction, accountName, amount, reference)
+	receipt, err := s.Service.Deposit(ctx, accountName, amount, reference)
+	return receipt, s.finish(ctx, pending, err)
+}
+
+func (s auditedService) Withdraw(ctx context.Context, accountName string, amount decimal.Decimal, reference string) (entities.TransferReceipt, error) {
+	ctx, pending := s.begin(ctx, WithdrawAction, accountName, amount, reference)
+	receipt, err := s.Service.Withdraw(ctx, accountName, amount, reference)
+	return receipt, s.finish(ctx, pending, err)
+}
+
+func (s auditedService) SetCreditLimit(ctx context.Context, accountName string, limit decimal.Decimal, changedBy string, reason string) (entities.Account, error) {
+	ctx, pending := s.begin(ctx, SetCreditLimitAction, accountName, limit, changedBy, reason)
+	account, err := s.Service.SetCreditLimit(ctx, accountName, limit, changedBy, reason)
+	return account, s.finish(ctx, pending, err)
+}
+
+func (s auditedService) CreateQuote(ctx context.Context, from entities.Currency, to entities.Currency, amount decimal.Decimal) (entities.Quote, error) {
+	ctx, pending := s.begin(ctx, CreateQuoteAction, from, to, amount)
+	quote, err := s.Service.CreateQuote(ctx, from, to, amount)
+	return quote, s.finish(ctx, pending, err)
+}
+
+func (s auditedService) Authorize(ctx context.Context, from entities.Account, to entities.Account, amount decimal.Decimal) (entities.Hold, error) {
+	ctx, pending := s.begin(ctx, AuthorizeAction, from.Name, to.Name, amount)
+	hold, err := s.Service.Authorize(ctx, from, to, amount)
+	return hold, s.finish(ctx, pending, err)
+}
+
+func (s auditedService) CaptureHold(ctx context.Context, id int, amount decimal.Decimal) (entities.TransferReceipt, error) {
+	ctx, pending := s.begin(ctx, CaptureHoldAction, id, amount)
+	receipt, err := s.Service.CaptureHold(ctx, id, amount)
+	return receipt, s.finish(ctx, pending, err)
+}
+
+func (s auditedService) VoidHold(ctx context.Context, id int) (entities.Hold, error) {
+	ctx, pending := s.begin(ctx, VoidHoldAction, id)
+	hold, err := s.Service.VoidHold(ctx, id)
+	return hold, s.finish(ctx, pending, err)
+}
+
+func (s auditedService) ExpireHolds(ctx context.Context) (int, error) {
+	ctx, pending := s.begin(ContextWithPrincipal(ctx, s.systemPrincipal()), ExpireHoldsAction)
+	released, err := s.Service.ExpireHolds(ctx)
+	return released, s.finishRun(ctx, pending, err)
+}
+
+func (s auditedService) CreateScheduledPayment(ctx context.Context, payment entities.ScheduledPayment) (entities.ScheduledPayment, error) {
+	ctx, pending := s.begin(ctx, CreateScheduledPaymentAction, payment)
+	created, err := s.Service.CreateScheduledPayment(ctx, payment)
+	return created, s.finish(ctx, pending, err)
+}
+
+func (s auditedService) UpdateScheduledPayment(ctx context.Context, id int, update entities.ScheduledPaymentUpdate) (entities.ScheduledPayment, error) {
+	ctx, pending := s.begin(ctx, UpdateScheduledPaymentAction, id, update)
+	payment, err := s.Service.UpdateScheduledPayment(ctx, id, update)
+	return payment, s.finish(ctx, pending, err)
+}
+
+func (s auditedService) CancelScheduledPayment(ctx context.Context, id int) (entities.ScheduledPayment, error) {
+	ctx, pending := s.begin(ctx, CancelScheduledPaymentAction, id)
+	payment, err := s.Service.CancelScheduledPayment(ctx, id)
+	return payment, s.finish(ctx, pending, err)
+}
+
+func (s auditedService) CreateAPIKey(ctx context.Context, name string, role entities.Role, ownerID string) (entities.IssuedAPIKey, error) {
+	ctx, pending := s.begin(ctx, CreateAPIKeyAction, name, role, ownerID)
+	key, err := s.Service.CreateAPIKey(ctx, name, role, ownerID)
+	return key, s.finish(ctx, pending, err)
+}
+
+func (s auditedService) RevokeAPIKey(ctx context.Context, keyID string) (entities.APIKey, error) {
+	ctx, pending := s.begin(ctx, RevokeAPIKeyAction, keyID)
+	key, err := s.Service.RevokeAPIKey(ctx, keyID)
+	return key, s.finish(ctx, pending, err)
+}
+
+func (s auditedService) RunScheduledPayments(ctx context.Context) (int, error) {
+	ctx, pending := s.begin(ContextWithPrincipal(ctx, s.systemPrincipal()), RunScheduledPaymentsAction)
+	runs, err := s.Service.RunScheduledPayments(ctx)
+	return runs, s.finishRun(ctx, pending, err)
+}
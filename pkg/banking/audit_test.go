@@ -0,0 +1,236 @@
+package banking_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/twonegatives/coinsph_challenge/pkg/banking"
+	"github.com/twonegatives/coinsph_challenge/pkg/entities"
+	"github.com/twonegatives/coinsph_challenge/pkg/memstorage"
+	"github.com/twonegatives/coinsph_challenge/pkg/mocks"
+)
+
+func TestBankingSvcAuditLog(t *testing.T) {
+	operator := entities.Principal{Subject: "ak_0123456789abcdef", Role: entities.OperatorRole}
+
+	t.Run("records calls along with their callers", func(t *testing.T) {
+		svc := banking.NewService(memstorage.NewMemStorage())
+		audited := banking.NewAuditedService(svc)
+		callCtx := banking.ContextWithCorrelationID(banking.ContextWithPrincipal(ctx, operator), "req-1")
+
+		_, err := audited.CreateAccount(callCtx, "molly", entities.USD, entities.AccountProfile{})
+		require.NoError(t, err)
+
+		_, err = audited.CreateAccount(callCtx, "molly", entities.USD, entities.AccountProfile{})
+		require.Error(t, err)
+
+		_, err = audited.GetAccount(callCtx, "molly")
+		require.NoError(t, err)
+
+		page, err := svc.GetAuditLog(ctx, entities.AuditFilter{})
+		require.NoError(t, err)
+		require.Len(t, page.Entries, 2)
+
+		for _, entry := range page.Entries {
+			assert.Equal(t, operator.Subject, entry.Subject)
+			assert.Equal(t, entities.OperatorRole, entry.Role)
+			assert.Equal(t, banking.CreateAccountAction, entry.Action)
+			assert.Equal(t, "req-1", entry.CorrelationID)
+			assert.Len(t, entry.PayloadHash, 64)
+		}
+
+		assert.Equal(t, entities.SucceededOutcome, page.Entries[0].Outcome)
+		assert.Empty(t, page.Entries[0].Error)
+		assert.Equal(t, entities.FailedOutcome, page.Entries[1].Outcome)
+		assert.NotEmpty(t, page.Entries[1].Error)
+		assert.Equal(t, page.Entries[0].PayloadHash, page.Entries[1].PayloadHash)
+
+		verification, err := svc.VerifyAuditLog(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, entities.AuditVerification{EntriesChecked: 2, Consistent: true, LastHash: page.Entries[1].Hash}, verification)
+	})
+
+	t.Run("records every committed payment of a best-effort batch and its final outcome", func(t *testing.T) {
+		svc := banking.NewService(memstorage.NewMemStorage())
+		for _, name := range []string{"alice", "bob"} {
+			_, err := svc.CreateAccount(ctx, name, entities.USD, entities.AccountProfile{})
+			require.NoError(t, err)
+		}
+		_, err := svc.Deposit(ctx, "alice", decimal.New(10, 0), "wire-1")
+		require.NoError(t, err)
+
+		order := func(amount int64) entities.PaymentOrder {
+			return entities.PaymentOrder{From: entities.Account{Name: "alice"}, To: entities.Account{Name: "bob"}, Amount: decimal.New(amount, 0)}
+		}
+
+		receipt, err := banking.NewAuditedService(svc).SendPaymentBatch(ctx, []entities.PaymentOrder{order(4), order(100), order(5)}, entities.BestEffortBatch)
+		require.NoError(t, err)
+		require.Equal(t, 1, receipt.Failed())
+
+		page, err := svc.GetAuditLog(ctx, entities.AuditFilter{Action: banking.SendPaymentBatchAction})
+		require.NoError(t, err)
+		require.Len(t, page.Entries, 3)
+
+		assert.Equal(t, entities.SucceededOutcome, page.Entries[0].Outcome)
+		assert.Equal(t, entities.SucceededOutcome, page.Entries[1].Outcome)
+		assert.Equal(t, entities.FailedOutcome, page.Entries[2].Outcome)
+		assert.Equal(t, "payments[1]: "+receipt.Items[1].Err.Error(), page.Entries[2].Error)
+	})
+
+	t.Run("records worker calls on behalf of the scheduler", func(t *testing.T) {
+		svc := banking.NewService(memstorage.NewMemStorage(), banking.WithHoldTTL(-time.Minute), banking.WithSchedulerID("worker-1"))
+		for _, name := range []string{"barry", "shop"} {
+			_, err := svc.CreateAccount(ctx, name, entities.USD, entities.AccountProfile{})
+			require.NoError(t, err)
+		}
+		_, err := svc.Deposit(ctx, "barry", decimal.New(10, 0), "wire-1")
+		require.NoError(t, err)
+		_, err = svc.Authorize(ctx, entities.Account{Name: "barry"}, entities.Account{Name: "shop"}, decimal.New(5, 0))
+		require.NoError(t, err)
+
+		audited := banking.NewAuditedService(svc)
+		released, err := audited.ExpireHolds(ctx)
+		require.NoError(t, err)
+		require.Equal(t, 1, released)
+
+		// runs which change nothing leave no entry
+		released, err = audited.ExpireHolds(ctx)
+		require.NoError(t, err)
+		require.Equal(t, 0, released)
+		_, err = audited.RunScheduledPayments(ctx)
+		require.NoError(t, err)
+
+		page, err := svc.GetAuditLog(ctx, entities.AuditFilter{})
+		require.NoError(t, err)
+		require.Len(t, page.Entries, 1)
+		assert.Equal(t, "scheduler:worker-1", page.Entries[0].Subject)
+		assert.Equal(t, entities.AdminRole, page.Entries[0].Role)
+		assert.Equal(t, banking.ExpireHoldsAction, page.Entries[0].Action)
+		assert.Equal(t, entities.SucceededOutcome, page.Entries[0].Outcome)
+	})
+
+	t.Run("pages through entries", func(t *testing.T) {
+		svc := banking.NewService(memstorage.NewMemStorage())
+		audited := banking.NewAuditedService(svc)
+		for _, name := range []string{"alice", "bob", "carol"} {
+			_, err := audited.CreateAccount(ctx, name, entities.USD, entities.AccountProfile{})
+			require.NoError(t, err)
+		}
+
+		page, err := svc.GetAuditLog(ctx, entities.AuditFilter{Limit: 2})
+		require.NoError(t, err)
+		require.Len(t, page.Entries, 2)
+		require.NotEmpty(t, page.NextCursor)
+
+		cursor, err := entities.DecodeAuditCursor(page.NextCursor)
+		require.NoError(t, err)
+
+		page, err = svc.GetAuditLog(ctx, entities.AuditFilter{Limit: 2, After: &cursor})
+		require.NoError(t, err)
+		require.Len(t, page.Entries, 1)
+		assert.Equal(t, 3, page.Entries[0].ID)
+		assert.Empty(t, page.NextCursor)
+	})
+
+	t.Run("validates filter", func(t *testing.T) {
+		svc := banking.NewService(memstorage.NewMemStorage())
+
+		_, err := svc.GetAuditLog(ctx, entities.AuditFilter{Outcome: "pending"})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "outcome should be either succeeded or failed")
+
+		now := time.Now()
+		_, err = svc.GetAuditLog(ctx, entities.AuditFilter{FromDate: now, ToDate: now.Add(-time.Hour)})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "from_date should be earlier than to_date")
+	})
+
+	t.Run("detects tampered entries", func(t *testing.T) {
+		mCtrl := gomock.NewController(t)
+		defer mCtrl.Finish()
+		storage := mocks.NewMockStorage(mCtrl)
+
+		first := entities.AuditEntry{ID: 1, Action: banking.DepositAction, Outcome: entities.SucceededOutcome, CreatedAt: time.Now().UTC()}
+		first.Hash = first.ChainHash()
+		second := entities.AuditEntry{ID: 2, Action: banking.WithdrawAction, Outcome: entities.SucceededOutcome, CreatedAt: time.Now().UTC(), PrevHash: first.Hash}
+		second.Hash = second.ChainHash()
+		second.Outcome = entities.FailedOutcome
+
+		storage.EXPECT().GetAuditEntries(ctx, gomock.Any()).Return([]entities.AuditEntry{first, second}, nil)
+
+		verification, err := banking.NewService(storage).VerifyAuditLog(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, entities.AuditVerification{EntriesChecked: 2, Consistent: false, BrokenAt: 2, LastHash: first.Hash}, verification)
+	})
+}
+
+func TestAuditTransport(t *testing.T) {
+	svc := banking.NewService(memstorage.NewMemStorage())
+	srv := httptest.NewServer(banking.MakeHandler(banking.NewAuditedService(svc), mocks.TestLogger{T: t}, banking.WithOperatorToken("operator-secret")))
+	defer srv.Close()
+
+	do := func(t *testing.T, method string, path string, body string, header http.Header) (*http.Response, map[string]interface{}) {
+		req, err := http.NewRequest(method, srv.URL+path, strings.NewReader(body))
+		require.NoError(t, err)
+		for name, values := range header {
+			req.Header[name] = values
+		}
+
+		resp, err := srv.Client().Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		var response map[string]interface{}
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&response))
+		return resp, response
+	}
+
+	operator := http.Header{"Authorization": {"Bearer operator-secret"}}
+
+	t.Run("passes correlation id through", func(t *testing.T) {
+		resp, _ := do(t, http.MethodPost, "/accounts", `{"account": {"name": "molly", "currency": "usd"}}`, http.Header{banking.CorrelationIDHeader: {"req-42"}})
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, "req-42", resp.Header.Get(banking.CorrelationIDHeader))
+
+		resp, _ = do(t, http.MethodGet, "/accounts/molly", "", nil)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Len(t, resp.Header.Get(banking.CorrelationIDHeader), 32)
+	})
+
+	t.Run("lists entries to privileged callers", func(t *testing.T) {
+		resp, _ := do(t, http.MethodGet, "/admin/audit", "", nil)
+		assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+
+		resp, response := do(t, http.MethodGet, "/admin/audit?correlation_id=req-42&outcome=succeeded", "", operator)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+
+		entries := response["entries"].([]interface{})
+		require.Len(t, entries, 1)
+		entry := entries[0].(map[string]interface{})
+		assert.Equal(t, banking.CreateAccountAction, entry["action"])
+		assert.Equal(t, "req-42", entry["correlation_id"])
+		assert.Equal(t, "succeeded", entry["outcome"])
+		assert.Nil(t, response["next_cursor"])
+
+		resp, response = do(t, http.MethodGet, "/admin/audit?outcome=unknown", "", operator)
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+		assert.Equal(t, "outcome should be either succeeded or failed", response["error"])
+	})
+
+	t.Run("verifies the chain", func(t *testing.T) {
+		resp, response := do(t, http.MethodGet, "/admin/audit/verify", "", operator)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+
+		verification := response["verification"].(map[string]interface{})
+		assert.Equal(t, true, verification["consistent"])
+		assert.Equal(t, float64(1), verification["entries_checked"])
+	})
+}
@@ -0,0 +1,230 @@
+package banking
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/pkg/errors"
+	"github.com/twonegatives/coinsph_challenge/pkg/entities"
+	"github.com/twonegatives/coinsph_challenge/pkg/storage"
+)
+
+// Headers of requests signed with an API key instead of presenting it as a bearer token.
+const (
+	APIKeyHeader    = "X-Api-Key"
+	TimestampHeader = "X-Timestamp"
+	SignatureHeader = "X-Signature"
+)
+
+// maxSignatureAge is how far timestamps of signed requests may drift from the server clock
+const maxSignatureAge = 5 * time.Minute
+
+var (
+	errCredentialsMissing = errors.New("credentials are missing")
+	errCredentialsInvalid = errors.New("credentials are invalid")
+	errSignatureExpired   = errors.New("request signature has expired")
+	errSignatureReplayed  = errors.New("request signature was already used")
+	errRoleNotPermitted   = errors.New("role of the caller does not permit the operation")
+)
+
+// APIKeyStore is an abstraction over persistence of API keys.
+// It is satisfied by storage.Storage implementations.
+type APIKeyStore interface {
+	GetAPIKey(ctx context.Context, keyID string) (entities.APIKey, error)
+}
+
+// authenticator resolves callers by the credentials they present: static
+// operator and admin tokens, JWTs or API keys, either as bearer tokens or as request signatures.
+type authenticator struct {
+	keys          APIKeyStore
+	tokens        *tokenAuthority
+	secrets       *SecretBox
+	signatures    IdempotencyStore
+	operatorToken string
+	adminToken    string
+	logger        log.Logger
+	now           func() time.Time
+}
+
+// authenticated wraps a handler so that it is served to callers having at least the role.
+// The caller is passed further as a principal within the request context.
+func authenticated(auth authenticator, role entities.Role, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		principal, err := auth.authenticate(r)
+		if err != nil {
+			if cause := errors.Cause(err); cause != errCredentialsMissing && cause != errCredentialsInvalid && cause != errSignatureExpired && cause != errSignatureReplayed {
+				auth.logger.Log("func", "authenticated", "err", err)
+			}
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			errorEncoder(ctx, err, w)
+			return
+		}
+
+		if !principal.Role.Permits(role) {
+			errorEncoder(ctx, errRoleNotPermitted, w)
+			return
+		}
+
+		next.ServeHTTP(w, r.WithContext(ContextWithPrincipal(ctx, principal)))
+	})
+}
+
+// authenticate returns the caller presenting credentials with the request.
+// Signed requests get their body read and put back for the handlers down the chain.
+func (auth authenticator) authenticate(r *http.Request) (entities.Principal, error) {
+	if keyID := r.Header.Get(APIKeyHeader); keyID != "" {
+		return auth.verifySignature(r, keyID)
+	}
+
+	token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if token == "" {
+		return entities.Principal{}, errCredentialsMissing
+	}
+
+	if matchesToken(token, auth.adminToken) {
+		return entities.Principal{Subject: "admin-token", Role: entities.AdminRole}, nil
+	}
+
+	if matchesToken(token, auth.operatorToken) {
+		return entities.Principal{Subject: "operator-token", Role: entities.OperatorRole}, nil
+	}
+
+	if auth.tokens != nil && isJWT(token) {
+		return auth.tokens.principal(token)
+	}
+
+	separator := strings.Index(token, apiKeyTokenSeparator)
+	if separator < 0 {
+		return entities.Principal{}, errCredentialsInvalid
+	}
+
+	key, err := auth.activeKey(r.Context(), token[:separator])
+	if err != nil {
+		return entities.Principal{}, err
+	}
+
+	if !matchesToken(hashSecret(token[separator+1:]), key.SecretHash) {
+		return entities.Principal{}, errCredentialsInvalid
+	}
+	return key.Principal(), nil
+}
+
+// verifySignature checks the request is signed with the API key. The signature is
+// a hex encoded HMAC-SHA256 of the string to sign keyed by the key secret, which is unsealed
+// with the server side key: neither the hash of the secret nor the sealed one can sign requests.
+// Every signature is accepted once, so that captured requests can't be replayed while their timestamp is fresh.
+func (auth authenticator) verifySignature(r *http.Request, keyID string) (entities.Principal, error) {
+	signature, err := hex.DecodeString(r.Header.Get(SignatureHeader))
+	if err != nil || len(signature) == 0 {
+		return entities.Principal{}, errCredentialsInvalid
+	}
+
+	timestamp := r.Header.Get(TimestampHeader)
+	signedAt, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return entities.Principal{}, errCredentialsInvalid
+	}
+
+	if age := auth.now().Sub(time.Unix(signedAt, 0)); age > maxSignatureAge || age < -maxSignatureAge {
+		return entities.Principal{}, errSignatureExpired
+	}
+
+	if auth.secrets == nil || auth.signatures == nil {
+		return entities.Principal{}, errCredentialsInvalid
+	}
+
+	key, err := auth.activeKey(r.Context(), keyID)
+	if err != nil {
+		return entities.Principal{}, err
+	}
+
+	if key.SealedSecret == "" {
+		return entities.Principal{}, errCredentialsInvalid
+	}
+
+	secret, err := auth.secrets.open(key.KeyID, key.SealedSecret)
+	if err != nil {
+		return entities.Principal{}, errors.Wrapf(err, "can't unseal secret of api key %s", key.KeyID)
+	}
+
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		return entities.Principal{}, errBadRequest
+	}
+	r.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(StringToSign(r.Method, r.URL.RequestURI(), timestamp, body)))
+	if !hmac.Equal(mac.Sum(nil), signature) {
+		return entities.Principal{}, errCredentialsInvalid
+	}
+
+	if err := auth.useSignature(r.Context(), key.KeyID, signature, time.Unix(signedAt, 0)); err != nil {
+		return entities.Principal{}, err
+	}
+	return key.Principal(), nil
+}
+
+// useSignature records the signature of the key as used until its timestamp expires.
+// Signatures are kept in the idempotency store, so that replicas of the service share them.
+// Returns errSignatureReplayed if the signature was already used.
+func (auth authenticator) useSignature(ctx context.Context, keyID string, signature []byte, signedAt time.Time) error {
+	encoded := hex.EncodeToString(signature)
+	created, err := auth.signatures.CreateIdempotencyRecord(ctx, entities.IdempotencyRecord{
+		Key:         "signature/" + keyID + "/" + encoded,
+		RequestHash: encoded,
+		LockedUntil: signedAt.Add(maxSignatureAge),
+	})
+	if err != nil {
+		return errors.Wrap(err, "can't record request signature")
+	}
+
+	if !created {
+		return errSignatureReplayed
+	}
+	return nil
+}
+
+// activeKey returns the API key unless it is unknown or revoked
+func (auth authenticator) activeKey(ctx context.Context, keyID string) (entities.APIKey, error) {
+	if auth.keys == nil {
+		return entities.APIKey{}, errCredentialsInvalid
+	}
+
+	key, err := auth.keys.GetAPIKey(ctx, keyID)
+	if errors.Cause(err) == storage.ErrNotFound {
+		return entities.APIKey{}, errCredentialsInvalid
+	}
+	if err != nil {
+		return entities.APIKey{}, errors.Wrap(err, "can't obtain api key")
+	}
+
+	if key.IsRevoked() {
+		return entities.APIKey{}, errCredentialsInvalid
+	}
+	return key, nil
+}
+
+// StringToSign builds the string clients sign with their API keys: request method,
+// path with query, unix timestamp and hex encoded SHA-256 of the body separated by new lines.
+func StringToSign(method string, requestURI string, timestamp string, body []byte) string {
+	bodyHash := sha256.Sum256(body)
+	return strings.Join([]string{method, requestURI, timestamp, hex.EncodeToString(bodyHash[:])}, "\n")
+}
+
+// matchesToken compares the presented token with the expected one in constant time.
+// Blank expected tokens match nothing.
+func matchesToken(presented string, expected string) bool {
+	return expected != "" && subtle.ConstantTimeCompare([]byte(presented), []byte(expected)) == 1
+}
@@ -0,0 +1,330 @@
+package banking_test
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/twonegatives/coinsph_challenge/pkg/banking"
+	"github.com/twonegatives/coinsph_challenge/pkg/entities"
+	"github.com/twonegatives/coinsph_challenge/pkg/memstorage"
+	"github.com/twonegatives/coinsph_challenge/pkg/mocks"
+	"github.com/twonegatives/coinsph_challenge/pkg/reconciliation"
+)
+
+func TestAuthentication(t *testing.T) {
+	store := memstorage.NewMemStorage()
+	box, err := banking.NewSecretBox([]byte(strings.Repeat("k", banking.SecretBoxKeySize)))
+	require.NoError(t, err)
+	keys := banking.NewService(store, banking.WithSecretBox(box))
+	issue := func(role entities.Role, ownerID string) entities.IssuedAPIKey {
+		key, err := keys.CreateAPIKey(ctx, string(role)+" key", role, ownerID)
+		require.NoError(t, err)
+		return key
+	}
+
+	viewer := issue(entities.ViewerRole, "")
+	owner := issue(entities.AccountOwnerRole, "customer-1")
+	revoked := issue(entities.AdminRole, "")
+	_, err = keys.RevokeAPIKey(ctx, revoked.KeyID)
+	require.NoError(t, err)
+
+	unsealed, err := banking.NewService(store).CreateAPIKey(ctx, "unsealed key", entities.OperatorRole, "")
+	require.NoError(t, err)
+
+	opts := []banking.HandlerOption{
+		banking.WithAuthentication(store),
+		banking.WithOperatorToken("s3cret"),
+		banking.WithAdminToken("r00t"),
+		banking.WithSignedRequests(box, store),
+	}
+
+	// do sends the request with the headers and returns the response status along with its error description
+	do := func(t *testing.T, dep dependencies, method string, path string, body string, headers map[string]string) (int, string) {
+		req, err := http.NewRequest(method, dep.TestServer.URL+path, strings.NewReader(body))
+		require.NoError(t, err)
+		for header, value := range headers {
+			req.Header.Set(header, value)
+		}
+
+		resp, err := dep.TestServer.Client().Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		var response map[string]interface{}
+		json.NewDecoder(resp.Body).Decode(&response)
+		description, _ := response["error"].(string)
+		return resp.StatusCode, description
+	}
+
+	bearer := func(token string) map[string]string {
+		return map[string]string{"Authorization": "Bearer " + token}
+	}
+
+	// signedWith returns headers of the request signed by the key with the secret at the given time
+	signedWith := func(key entities.IssuedAPIKey, secret string, method string, path string, body string, at time.Time) map[string]string {
+		timestamp := strconv.FormatInt(at.Unix(), 10)
+		mac := hmac.New(sha256.New, []byte(secret))
+		mac.Write([]byte(banking.StringToSign(method, path, timestamp, []byte(body))))
+		return map[string]string{
+			banking.APIKeyHeader:    key.KeyID,
+			banking.TimestampHeader: timestamp,
+			banking.SignatureHeader: hex.EncodeToString(mac.Sum(nil)),
+		}
+	}
+
+	signed := func(key entities.IssuedAPIKey, method string, path string, body string, at time.Time) map[string]string {
+		return signedWith(key, key.Secret, method, path, body, at)
+	}
+
+	t.Run("refuses requests without valid credentials", func(t *testing.T) {
+		dep, cleanUp := setupServer(t, opts...)
+		defer cleanUp()
+
+		cases := []struct {
+			title   string
+			headers map[string]string
+			err     string
+		}{
+			{title: "without credentials", err: "credentials are missing"},
+			{title: "with unknown token", headers: bearer("guess"), err: "credentials are invalid"},
+			{title: "with unknown key", headers: bearer("ak_unknown." + viewer.Secret), err: "credentials are invalid"},
+			{title: "with wrong secret", headers: bearer(viewer.KeyID + "." + owner.Secret), err: "credentials are invalid"},
+			{title: "with revoked key", headers: bearer(revoked.Token()), err: "credentials are invalid"},
+		}
+
+		for _, tc := range cases {
+			status, description := do(t, dep, http.MethodGet, "/accounts/alice", "", tc.headers)
+			assert.Equal(t, http.StatusUnauthorized, status, tc.title)
+			assert.Equal(t, tc.err, description, tc.title)
+		}
+	})
+
+	t.Run("passes the caller to service", func(t *testing.T) {
+		dep, cleanUp := setupServer(t, opts...)
+		defer cleanUp()
+
+		dep.Service.EXPECT().GetAccount(gomock.Any(), "alice").DoAndReturn(func(ctx context.Context, _ string) (entities.Account, error) {
+			principal, ok := banking.PrincipalFromContext(ctx)
+			require.True(t, ok)
+			assert.Equal(t, entities.Principal{Subject: viewer.KeyID, Role: entities.ViewerRole}, principal)
+			return entities.Account{Name: "alice"}, nil
+		})
+
+		status, _ := do(t, dep, http.MethodGet, "/accounts/alice", "", bearer(viewer.Token()))
+		assert.Equal(t, http.StatusOK, status)
+	})
+
+	t.Run("serves callers whose role permits the route", func(t *testing.T) {
+		dep, cleanUp := setupServer(t, opts...)
+		defer cleanUp()
+
+		body := `{"payment": {"from": "alice", "to": "bob", "amount": 1}}`
+		status, description := do(t, dep, http.MethodPost, "/payments", body, bearer(viewer.Token()))
+		assert.Equal(t, http.StatusForbidden, status)
+		assert.Equal(t, "role of the caller does not permit the operation", description)
+
+		status, _ = do(t, dep, http.MethodPost, "/accounts/alice/deposits", `{}`, bearer(owner.Token()))
+		assert.Equal(t, http.StatusForbidden, status)
+
+		status, _ = do(t, dep, http.MethodGet, "/admin/api-keys", "", bearer("s3cret"))
+		assert.Equal(t, http.StatusForbidden, status)
+
+		dep.Service.EXPECT().Deposit(gomock.Any(), "alice", decimal.New(4, 0), "wire-1").Return(entities.TransferReceipt{Payments: []entities.Payment{}}, nil)
+		status, _ = do(t, dep, http.MethodPost, "/accounts/alice/deposits", `{"deposit": {"amount": 4, "reference": "wire-1"}}`, bearer("s3cret"))
+		assert.Equal(t, http.StatusCreated, status)
+
+		dep.Service.EXPECT().GetAPIKeys(gomock.Any()).Return([]entities.APIKey{viewer.APIKey}, nil)
+		status, _ = do(t, dep, http.MethodGet, "/admin/api-keys", "", bearer("r00t"))
+		assert.Equal(t, http.StatusOK, status)
+	})
+
+	t.Run("accepts signed requests", func(t *testing.T) {
+		dep, cleanUp := setupServer(t, opts...)
+		defer cleanUp()
+
+		body := `{"payment": {"from": "alice", "to": "bob", "amount": 1}}`
+		receipt := entities.TransferReceipt{Transaction: entities.Transaction{ID: 42}, Payments: []entities.Payment{}}
+		dep.Service.EXPECT().SendPayment(gomock.Any(), gomock.Any(), gomock.Any(), decimal.New(1, 0), entities.PaymentDetails{}).DoAndReturn(
+			func(ctx context.Context, from entities.Account, _ entities.Account, _ decimal.Decimal, _ entities.PaymentDetails) (entities.TransferReceipt, error) {
+				principal, _ := banking.PrincipalFromContext(ctx)
+				assert.Equal(t, owner.KeyID, principal.Subject)
+				assert.Equal(t, "customer-1", principal.OwnerID)
+				assert.Equal(t, "alice", from.Name)
+				return receipt, nil
+			})
+
+		status, _ := do(t, dep, http.MethodPost, "/payments", body, signed(owner, http.MethodPost, "/payments", body, time.Now()))
+		assert.Equal(t, http.StatusCreated, status)
+
+		tampered := strings.Replace(body, `"amount": 1`, `"amount": 100`, 1)
+		status, description := do(t, dep, http.MethodPost, "/payments", tampered, signed(owner, http.MethodPost, "/payments", body, time.Now()))
+		assert.Equal(t, http.StatusUnauthorized, status)
+		assert.Equal(t, "credentials are invalid", description)
+
+		status, description = do(t, dep, http.MethodPost, "/payments", body, signed(owner, http.MethodPost, "/payments", body, time.Now().Add(-10*time.Minute)))
+		assert.Equal(t, http.StatusUnauthorized, status)
+		assert.Equal(t, "request signature has expired", description)
+	})
+
+	t.Run("refuses replayed signatures", func(t *testing.T) {
+		dep, cleanUp := setupServer(t, opts...)
+		defer cleanUp()
+
+		dep.Service.EXPECT().GetAccount(gomock.Any(), "alice").Return(entities.Account{Name: "alice"}, nil)
+
+		headers := signed(viewer, http.MethodGet, "/accounts/alice", "", time.Now())
+		status, _ := do(t, dep, http.MethodGet, "/accounts/alice", "", headers)
+		assert.Equal(t, http.StatusOK, status)
+
+		status, description := do(t, dep, http.MethodGet, "/accounts/alice", "", headers)
+		assert.Equal(t, http.StatusUnauthorized, status)
+		assert.Equal(t, "request signature was already used", description)
+
+		// the same request signed at another moment is a request of its own
+		dep.Service.EXPECT().GetAccount(gomock.Any(), "alice").Return(entities.Account{Name: "alice"}, nil)
+		status, _ = do(t, dep, http.MethodGet, "/accounts/alice", "", signed(viewer, http.MethodGet, "/accounts/alice", "", time.Now().Add(-time.Second)))
+		assert.Equal(t, http.StatusOK, status)
+	})
+
+	t.Run("refuses signatures made without the plain secret", func(t *testing.T) {
+		dep, cleanUp := setupServer(t, opts...)
+		defer cleanUp()
+
+		stored, err := store.GetAPIKey(ctx, owner.KeyID)
+		require.NoError(t, err)
+
+		for _, secret := range []string{stored.SecretHash, stored.SealedSecret} {
+			status, description := do(t, dep, http.MethodGet, "/accounts/alice", "", signedWith(owner, secret, http.MethodGet, "/accounts/alice", "", time.Now()))
+			assert.Equal(t, http.StatusUnauthorized, status)
+			assert.Equal(t, "credentials are invalid", description)
+		}
+
+		status, _ := do(t, dep, http.MethodGet, "/accounts/alice", "", signed(unsealed, http.MethodGet, "/accounts/alice", "", time.Now()))
+		assert.Equal(t, http.StatusUnauthorized, status)
+
+		withoutBox, cleanUpWithoutBox := setupServer(t, opts[:len(opts)-1]...)
+		defer cleanUpWithoutBox()
+
+		status, _ = do(t, withoutBox, http.MethodGet, "/accounts/alice", "", signed(owner, http.MethodGet, "/accounts/alice", "", time.Now()))
+		assert.Equal(t, http.StatusUnauthorized, status)
+	})
+
+	t.Run("scopes idempotency keys by caller", func(t *testing.T) {
+		dep, cleanUp := setupServer(t, append(opts, banking.WithIdempotencyStore(store))...)
+		defer cleanUp()
+
+		body := `{"payment": {"from": "alice", "to": "bob", "amount": 1}}`
+		receipt := entities.TransferReceipt{Transaction: entities.Transaction{ID: 42}, Payments: []entities.Payment{}}
+		dep.Service.EXPECT().SendPayment(gomock.Any(), gomock.Any(), gomock.Any(), decimal.New(1, 0), entities.PaymentDetails{}).Return(receipt, nil).Times(2)
+
+		headers := bearer(owner.Token())
+		headers[banking.IdempotencyKeyHeader] = "payment-1"
+		status, _ := do(t, dep, http.MethodPost, "/payments", body, headers)
+		assert.Equal(t, http.StatusCreated, status)
+
+		headers = bearer("r00t")
+		headers[banking.IdempotencyKeyHeader] = "payment-1"
+		status, _ = do(t, dep, http.MethodPost, "/payments", body, headers)
+		assert.Equal(t, http.StatusCreated, status)
+	})
+
+	t.Run("restricts admin handlers of other packages", func(t *testing.T) {
+		report := reconciliation.MakeHandler(reconciliation.NewService(store), mocks.TestLogger{T: t})
+		dep, cleanUp := setupServer(t, append(opts, banking.WithAdminHandler(reconciliation.ReportPath, report))...)
+		defer cleanUp()
+
+		status, description := do(t, dep, http.MethodGet, reconciliation.ReportPath, "", nil)
+		assert.Equal(t, http.StatusUnauthorized, status)
+		assert.Equal(t, "credentials are missing", description)
+
+		status, _ = do(t, dep, http.MethodGet, reconciliation.ReportPath, "", bearer("s3cret"))
+		assert.Equal(t, http.StatusForbidden, status)
+
+		status, _ = do(t, dep, http.MethodGet, reconciliation.ReportPath, "", bearer("r00t"))
+		assert.Equal(t, http.StatusOK, status)
+	})
+
+	t.Run("serves api key routes only when authentication is required", func(t *testing.T) {
+		dep, cleanUp := setupServer(t, banking.WithAdminToken("r00t"))
+		defer cleanUp()
+
+		status, _ := do(t, dep, http.MethodGet, "/admin/api-keys", "", bearer("r00t"))
+		assert.Equal(t, http.StatusNotFound, status)
+	})
+}
+
+func TestAPIKeyRoutes(t *testing.T) {
+	admin := banking.WithAuthentication(memstorage.NewMemStorage())
+
+	t.Run("renders issued key along with its token", func(t *testing.T) {
+		dep, cleanUp := setupServer(t, admin, banking.WithAdminToken("r00t"))
+		client := dep.TestServer.Client()
+		defer cleanUp()
+
+		createdAt := time.Date(2019, 4, 20, 10, 0, 0, 0, time.UTC)
+		issued := entities.IssuedAPIKey{
+			APIKey: entities.APIKey{ID: 1, KeyID: "ak_1", Name: "shop", SecretHash: "hash", Role: entities.AccountOwnerRole, OwnerID: "customer-1", CreatedAt: createdAt},
+			Secret: "secret",
+		}
+		dep.Service.EXPECT().CreateAPIKey(gomock.Any(), "shop", entities.AccountOwnerRole, "customer-1").Return(issued, nil)
+
+		requestBody := `{"api_key": {"name": "shop", "role": "Account-Owner", "owner_id": "customer-1"}}`
+		req, err := http.NewRequest(http.MethodPost, dep.TestServer.URL+"/admin/api-keys", strings.NewReader(requestBody))
+		require.NoError(t, err)
+		req.Header.Set("Authorization", "Bearer r00t")
+
+		resp, err := client.Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		var actualBody map[string]map[string]interface{}
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&actualBody))
+
+		assert.Equal(t, http.StatusCreated, resp.StatusCode)
+		assert.Equal(t, map[string]interface{}{
+			"key_id":     "ak_1",
+			"name":       "shop",
+			"role":       "account-owner",
+			"owner_id":   "customer-1",
+			"created_at": "2019-04-20T10:00:00Z",
+			"secret":     "secret",
+			"token":      "ak_1.secret",
+		}, actualBody["api_key"])
+	})
+
+	t.Run("revokes key", func(t *testing.T) {
+		dep, cleanUp := setupServer(t, admin, banking.WithAdminToken("r00t"))
+		client := dep.TestServer.Client()
+		defer cleanUp()
+
+		revokedAt := time.Date(2019, 4, 21, 10, 0, 0, 0, time.UTC)
+		key := entities.APIKey{ID: 1, KeyID: "ak_1", Name: "shop", Role: entities.ViewerRole, RevokedAt: revokedAt}
+		dep.Service.EXPECT().RevokeAPIKey(gomock.Any(), "ak_1").Return(key, nil)
+
+		req, err := http.NewRequest(http.MethodDelete, dep.TestServer.URL+"/admin/api-keys/ak_1", nil)
+		require.NoError(t, err)
+		req.Header.Set("Authorization", "Bearer r00t")
+
+		resp, err := client.Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		var actualBody map[string]map[string]interface{}
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&actualBody))
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, "2019-04-21T10:00:00Z", actualBody["api_key"]["revoked_at"])
+		assert.NotContains(t, actualBody["api_key"], "secret_hash")
+	})
+}
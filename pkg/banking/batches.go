@@ -0,0 +1,111 @@
+package banking
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+	"github.com/twonegatives/coinsph_challenge/pkg/entities"
+)
+
+// maxBatchSize limits the number of payments submitted within a single batch
+const maxBatchSize = 500
+
+var (
+	errBatchEmpty       = errors.New("batch should contain at least one payment")
+	errBatchTooLarge    = errors.New("batch should not contain more than 500 payments")
+	errInvalidBatchMode = errors.New("batch mode should be either atomic or best_effort")
+)
+
+// SendPaymentBatch books a list of transfers. Every transfer is checked and booked
+// the same way SendPayment does it, fees included.
+// In atomic mode all the transfers are booked within a single database transaction,
+// so that either all of them succeed or none. Accounts of the whole batch are locked
+// upfront in the order SendPayment locks them. The error of the first failing transfer
+// is returned, prefixed with its position in the batch.
+// In best effort mode every transfer is booked on its own and outcomes are reported per item.
+// Returns a BatchReceipt listing the outcome of every transfer in order of submission.
+func (svc *Service) SendPaymentBatch(ctx context.Context, orders []entities.PaymentOrder, mode entities.BatchMode) (entities.BatchReceipt, error) {
+	if len(orders) == 0 {
+		return entities.BatchReceipt{}, errBatchEmpty
+	}
+
+	if len(orders) > maxBatchSize {
+		return entities.BatchReceipt{}, errBatchTooLarge
+	}
+
+	switch mode {
+	case entities.AtomicBatch:
+		return svc.sendAtomicBatch(ctx, orders)
+	case entities.BestEffortBatch:
+		return svc.sendBestEffortBatch(ctx, orders), nil
+	default:
+		return entities.BatchReceipt{}, errInvalidBatchMode
+	}
+}
+
+// sendAtomicBatch books all the orders within a single storage transaction
+func (svc *Service) sendAtomicBatch(ctx context.Context, orders []entities.PaymentOrder) (entities.BatchReceipt, error) {
+	for index, order := range orders {
+		if err := validatePaymentOrder(order.From, order.To, order.Amount); err != nil {
+			return entities.BatchReceipt{}, errors.Wrapf(err, "payments[%d]", index)
+		}
+
+		if err := validatePaymentDetails(order.Details); err != nil {
+			return entities.BatchReceipt{}, errors.Wrapf(err, "payments[%d]", index)
+		}
+	}
+
+	txStorage, err := svc.store.BeginTx(ctx, nil)
+	if err != nil {
+		return entities.BatchReceipt{}, errors.Wrap(err, "can't open transaction")
+	}
+
+	defer txStorage.RollbackTx(ctx)
+
+	accounts, sides := batchSides(orders)
+	if err := lockAccounts(ctx, txStorage, sides...); err != nil {
+		return entities.BatchReceipt{}, err
+	}
+
+	receipt := entities.BatchReceipt{Mode: entities.AtomicBatch, Items: make([]entities.BatchItem, len(orders))}
+	for index, order := range orders {
+		transferReceipt, err := svc.transfer(ctx, txStorage, accounts[order.From.Name], accounts[order.To.Name], order.Amount, order.Details)
+		if err != nil {
+			return entities.BatchReceipt{}, errors.Wrapf(err, "payments[%d]", index)
+		}
+		receipt.Items[index] = entities.BatchItem{Receipt: transferReceipt}
+	}
+
+	if err := svc.commitTx(ctx, txStorage); err != nil {
+		return entities.BatchReceipt{}, errors.Wrap(err, "transaction commit failed")
+	}
+
+	return receipt, nil
+}
+
+// sendBestEffortBatch books every order as a separate payment
+func (svc *Service) sendBestEffortBatch(ctx context.Context, orders []entities.PaymentOrder) entities.BatchReceipt {
+	receipt := entities.BatchReceipt{Mode: entities.BestEffortBatch, Items: make([]entities.BatchItem, len(orders))}
+	for index, order := range orders {
+		transferReceipt, err := svc.SendPayment(ctx, order.From, order.To, order.Amount, order.Details)
+		receipt.Items[index] = entities.BatchItem{Receipt: transferReceipt, Err: err}
+	}
+	return receipt
+}
+
+// batchSides returns a payment side per each distinct account of the orders.
+// Orders referring to the same account share its side, so that balance changes
+// booked by one of them are seen by the following ones.
+func batchSides(orders []entities.PaymentOrder) (map[string]*entities.Account, []paymentSide) {
+	accounts := make(map[string]*entities.Account)
+	sides := []paymentSide{}
+	for _, order := range orders {
+		for _, name := range []string{order.From.Name, order.To.Name} {
+			if _, ok := accounts[name]; !ok {
+				accounts[name] = &entities.Account{Name: name}
+				sides = append(sides, paymentSide{account: accounts[name], label: name})
+			}
+		}
+	}
+	return accounts, sides
+}
@@ -0,0 +1,131 @@
+package banking_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/twonegatives/coinsph_challenge/pkg/banking"
+	"github.com/twonegatives/coinsph_challenge/pkg/entities"
+	"github.com/twonegatives/coinsph_challenge/pkg/mocks"
+)
+
+func TestBankingSvcSendPaymentBatch(t *testing.T) {
+	company := entities.Account{Name: "company", Balance: decimal.New(100, 0), Currency: entities.USD}
+	alice := entities.Account{Name: "alice", Balance: decimal.New(0, 0), Currency: entities.USD}
+	bob := entities.Account{Name: "bob", Balance: decimal.New(0, 0), Currency: entities.USD}
+
+	payroll := func(amounts ...int64) []entities.PaymentOrder {
+		orders := []entities.PaymentOrder{}
+		for index, amount := range amounts {
+			to := alice
+			if index%2 == 1 {
+				to = bob
+			}
+			orders = append(orders, entities.PaymentOrder{From: entities.Account{Name: company.Name}, To: entities.Account{Name: to.Name}, Amount: decimal.New(amount, 0)})
+		}
+		return orders
+	}
+
+	t.Run("books atomic batch within a single transaction", func(t *testing.T) {
+		mCtrl := gomock.NewController(t)
+		defer mCtrl.Finish()
+		storage := mocks.NewMockStorage(mCtrl)
+
+		storage.EXPECT().BeginTx(ctx, nil).Return(storage, nil)
+		storage.EXPECT().RollbackTx(ctx)
+		expectAccountsLocked(storage, company, alice, bob)
+		storage.EXPECT().CreateTransaction(ctx, gomock.Any()).Times(2).DoAndReturn(
+			func(_ context.Context, transaction entities.Transaction) (entities.Transaction, error) {
+				return transaction, nil
+			},
+		)
+		storage.EXPECT().SendPayment(ctx, gomock.Any()).Times(4)
+
+		balances := []string{}
+		storage.EXPECT().SetAccountBalance(ctx, gomock.Any()).Times(4).DoAndReturn(
+			func(_ context.Context, account entities.Account) error {
+				balances = append(balances, account.Name+":"+account.Balance.String())
+				return nil
+			},
+		)
+		storage.EXPECT().CommitTx(ctx)
+
+		receipt, err := banking.NewService(storage).SendPaymentBatch(ctx, payroll(30, 20), entities.AtomicBatch)
+		require.NoError(t, err)
+
+		assert.Equal(t, entities.AtomicBatch, receipt.Mode)
+		assert.Equal(t, 0, receipt.Failed())
+		require.Len(t, receipt.Items, 2)
+		assert.Equal(t, "50", receipt.Items[1].Receipt.SenderBalance.String())
+		assert.Equal(t, []string{"company:70", "alice:30", "company:50", "bob:20"}, balances)
+	})
+
+	t.Run("fails atomic batch as a whole", func(t *testing.T) {
+		mCtrl := gomock.NewController(t)
+		defer mCtrl.Finish()
+		storage := mocks.NewMockStorage(mCtrl)
+
+		storage.EXPECT().BeginTx(ctx, nil).Return(storage, nil)
+		storage.EXPECT().RollbackTx(ctx)
+		expectAccountsLocked(storage, company, alice, bob)
+		storage.EXPECT().CreateTransaction(ctx, gomock.Any()).Return(entities.Transaction{ID: 1}, nil)
+		storage.EXPECT().SendPayment(ctx, gomock.Any()).Times(2)
+		storage.EXPECT().SetAccountBalance(ctx, gomock.Any()).Times(2)
+
+		_, err := banking.NewService(storage).SendPaymentBatch(ctx, payroll(60, 50), entities.AtomicBatch)
+		require.Error(t, err)
+		assert.Equal(t, "payments[1]: sender account has insufficient funds", err.Error())
+	})
+
+	t.Run("validates atomic batch before locking accounts", func(t *testing.T) {
+		mCtrl := gomock.NewController(t)
+		defer mCtrl.Finish()
+		storage := mocks.NewMockStorage(mCtrl)
+
+		_, err := banking.NewService(storage).SendPaymentBatch(ctx, payroll(10, 0), entities.AtomicBatch)
+		require.Error(t, err)
+		assert.Equal(t, "payments[1]: amount transferred should be a positive number", err.Error())
+	})
+
+	t.Run("reports outcomes of best effort batch per item", func(t *testing.T) {
+		mCtrl := gomock.NewController(t)
+		defer mCtrl.Finish()
+		storage := mocks.NewMockStorage(mCtrl)
+
+		storage.EXPECT().BeginTx(ctx, nil).Return(storage, nil)
+		storage.EXPECT().RollbackTx(ctx)
+		expectAccountsLocked(storage, company, bob)
+		storage.EXPECT().CreateTransaction(ctx, gomock.Any()).Return(entities.Transaction{ID: 7}, nil)
+		storage.EXPECT().SendPayment(ctx, gomock.Any()).Times(2)
+		storage.EXPECT().SetAccountBalance(ctx, gomock.Any()).Times(2)
+		storage.EXPECT().CommitTx(ctx)
+
+		receipt, err := banking.NewService(storage).SendPaymentBatch(ctx, payroll(0, 20), entities.BestEffortBatch)
+		require.NoError(t, err)
+
+		assert.Equal(t, 1, receipt.Failed())
+		require.Len(t, receipt.Items, 2)
+		assert.EqualError(t, receipt.Items[0].Err, "amount transferred should be a positive number")
+		assert.NoError(t, receipt.Items[1].Err)
+		assert.Equal(t, 7, receipt.Items[1].Receipt.Transaction.ID)
+	})
+
+	t.Run("rejects malformed batches", func(t *testing.T) {
+		mCtrl := gomock.NewController(t)
+		defer mCtrl.Finish()
+		svc := banking.NewService(mocks.NewMockStorage(mCtrl))
+
+		_, err := svc.SendPaymentBatch(ctx, nil, entities.AtomicBatch)
+		assert.EqualError(t, err, "batch should contain at least one payment")
+
+		_, err = svc.SendPaymentBatch(ctx, make([]entities.PaymentOrder, 501), entities.AtomicBatch)
+		assert.EqualError(t, err, "batch should not contain more than 500 payments")
+
+		_, err = svc.SendPaymentBatch(ctx, payroll(10), entities.BatchMode("sometimes"))
+		assert.EqualError(t, err, "batch mode should be either atomic or best_effort")
+	})
+}
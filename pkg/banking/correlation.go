@@ -0,0 +1,45 @@
+package banking
+
+import (
+	"context"
+	"net/http"
+	"unicode/utf8"
+)
+
+// CorrelationIDHeader is a header of requests and responses carrying an id the request
+// is known by. Clients may pass their own one, otherwise the service generates it.
+const CorrelationIDHeader = "X-Correlation-Id"
+
+const (
+	maxCorrelationIDLength = 128
+	correlationIDBytes     = 16
+)
+
+type correlationIDContextKey struct{}
+
+// ContextWithCorrelationID returns a copy of the context carrying the correlation id of the request.
+func ContextWithCorrelationID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, correlationIDContextKey{}, id)
+}
+
+// CorrelationIDFromContext returns the correlation id of the request, blank if there is none.
+func CorrelationIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(correlationIDContextKey{}).(string)
+	return id
+}
+
+// correlated returns a middleware which passes the correlation id of the request
+// further within the context and renders it back in the response header.
+// Ids which are missing or too long are replaced with generated ones.
+func correlated(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(CorrelationIDHeader)
+		if id == "" || utf8.RuneCountInString(id) > maxCorrelationIDLength {
+			// the id is merely a label, so a failed generation leaves it blank
+			id, _ = randomHex(correlationIDBytes)
+		}
+
+		w.Header().Set(CorrelationIDHeader, id)
+		next.ServeHTTP(w, r.WithContext(ContextWithCorrelationID(r.Context(), id)))
+	})
+}
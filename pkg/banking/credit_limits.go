@@ -0,0 +1,112 @@
+package banking
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/shopspring/decimal"
+	"github.com/twonegatives/coinsph_challenge/pkg/entities"
+)
+
+var (
+	errCreditLimitNegative     = errors.New("credit limit can't be negative")
+	errCreditLimitBelowBalance = errors.New("account balance is below the new credit limit")
+	errCreditLimitHouseAccount = errors.New("credit limit can't be set for house accounts")
+	errChangedByBlank          = errors.New("changed_by should be present")
+	errChangedByMismatch       = errors.New("changed_by should match the authenticated caller")
+)
+
+// SetCreditLimit changes the amount account balance may go below zero by.
+// The change is recorded along with the name of whoever made it and an optional reason.
+// Authenticated callers are recorded by the subject of their principal; 'changedBy'
+// is only required when the call carries no principal (i.e. authentication is disabled).
+// Returns the account with its new credit limit on success.
+// Returns an error if:
+// - 'limit' is negative or has more decimal places than the account currency allows
+// - 'changedBy' is blank while there is no principal, or differs from the principal subject
+// - account is not present in system, it is a house or a closed one
+// - account balance is already below the new limit
+func (svc *Service) SetCreditLimit(ctx context.Context, accountName string, limit decimal.Decimal, changedBy string, reason string) (entities.Account, error) {
+	if limit.IsNegative() {
+		return entities.Account{}, errCreditLimitNegative
+	}
+
+	changedBy = strings.TrimSpace(changedBy)
+	if principal, ok := PrincipalFromContext(ctx); ok {
+		if changedBy != "" && changedBy != principal.Subject {
+			return entities.Account{}, errChangedByMismatch
+		}
+		changedBy = principal.Subject
+	}
+
+	if changedBy == "" {
+		return entities.Account{}, errChangedByBlank
+	}
+
+	txStorage, err := svc.store.BeginTx(ctx, nil)
+	if err != nil {
+		return entities.Account{}, errors.Wrap(err, "can't open transaction")
+	}
+
+	defer txStorage.RollbackTx(ctx)
+
+	account := entities.Account{Name: accountName}
+	if err := txStorage.GetAccountForUpdate(ctx, &account); err != nil {
+		if errors.Cause(err) == sql.ErrNoRows {
+			return entities.Account{}, errAccountNotFound
+		}
+		return entities.Account{}, errors.Wrap(err, "can't obtain account")
+	}
+
+	if account.Type.IsHouse() {
+		return entities.Account{}, errCreditLimitHouseAccount
+	}
+
+	if account.Status == entities.ClosedAccount {
+		return entities.Account{}, errAccountClosed
+	}
+
+	if err := svc.validateAmount(account.Currency, limit); err != nil {
+		return entities.Account{}, err
+	}
+
+	change := entities.CreditLimitChange{
+		AccountID:     account.ID,
+		PreviousLimit: account.CreditLimit,
+		NewLimit:      limit,
+		ChangedBy:     changedBy,
+		Reason:        strings.TrimSpace(reason),
+	}
+
+	account.CreditLimit = limit
+	if !account.IsBalanceValid() {
+		return entities.Account{}, errCreditLimitBelowBalance
+	}
+
+	if err := txStorage.SetAccountCreditLimit(ctx, account); err != nil {
+		return entities.Account{}, err
+	}
+
+	if _, err := txStorage.CreateCreditLimitChange(ctx, change); err != nil {
+		return entities.Account{}, err
+	}
+
+	if err := svc.commitTx(ctx, txStorage); err != nil {
+		return entities.Account{}, errors.Wrap(err, "transaction commit failed")
+	}
+
+	return account, nil
+}
+
+// GetCreditLimitChanges returns the history of credit limit changes of the account, oldest first.
+func (svc *Service) GetCreditLimitChanges(ctx context.Context, accountName string) ([]entities.CreditLimitChange, error) {
+	account, err := svc.GetAccount(ctx, accountName)
+	if err != nil {
+		return nil, err
+	}
+
+	changes, err := svc.store.GetCreditLimitChanges(ctx, account.ID)
+	return changes, errors.Wrap(err, "failed to get credit limit changes from database")
+}
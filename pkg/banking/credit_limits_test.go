@@ -0,0 +1,140 @@
+package banking_test
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/pkg/errors"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/twonegatives/coinsph_challenge/pkg/banking"
+	"github.com/twonegatives/coinsph_challenge/pkg/entities"
+	"github.com/twonegatives/coinsph_challenge/pkg/memstorage"
+	"github.com/twonegatives/coinsph_challenge/pkg/mocks"
+)
+
+func TestBankingSvcSetCreditLimit(t *testing.T) {
+	user := entities.Account{ID: 7, Name: "molly", Type: entities.UserAccount, Balance: decimal.New(-20, 0), CreditLimit: decimal.New(50, 0), Currency: entities.USD}
+
+	t.Run("updates limit and records the change", func(t *testing.T) {
+		mCtrl := gomock.NewController(t)
+		defer mCtrl.Finish()
+		storage := mocks.NewMockStorage(mCtrl)
+
+		updated := user
+		updated.CreditLimit = decimal.New(30, 0)
+
+		storage.EXPECT().BeginTx(ctx, nil).Return(storage, nil)
+		expectAccountsLocked(storage, user)
+		storage.EXPECT().SetAccountCreditLimit(ctx, updated).Return(nil)
+		storage.EXPECT().CreateCreditLimitChange(ctx, entities.CreditLimitChange{
+			AccountID:     7,
+			PreviousLimit: decimal.New(50, 0),
+			NewLimit:      decimal.New(30, 0),
+			ChangedBy:     "alice",
+			Reason:        "risk review",
+		}).Return(entities.CreditLimitChange{ID: 1}, nil)
+		storage.EXPECT().CommitTx(ctx).Return(nil)
+		storage.EXPECT().RollbackTx(ctx).Return(nil)
+
+		account, err := banking.NewService(storage).SetCreditLimit(ctx, "molly", decimal.New(30, 0), " alice ", "risk review")
+		require.NoError(t, err)
+		assert.Equal(t, "30", account.CreditLimit.String())
+	})
+
+	t.Run("records the authenticated caller as the author", func(t *testing.T) {
+		svc := banking.NewService(memstorage.NewMemStorage())
+		_, err := svc.CreateAccount(ctx, "molly", entities.USD, entities.AccountProfile{})
+		require.NoError(t, err)
+
+		callCtx := banking.ContextWithPrincipal(ctx, entities.Principal{Subject: "ak_0123456789abcdef", Role: entities.OperatorRole})
+		_, err = svc.SetCreditLimit(callCtx, "molly", decimal.New(30, 0), "", "risk review")
+		require.NoError(t, err)
+
+		_, err = svc.SetCreditLimit(callCtx, "molly", decimal.New(40, 0), "ak_0123456789abcdef", "")
+		require.NoError(t, err)
+
+		_, err = svc.SetCreditLimit(callCtx, "molly", decimal.New(50, 0), "alice", "")
+		require.Error(t, err)
+		assert.Equal(t, "changed_by should match the authenticated caller", err.Error())
+
+		changes, err := svc.GetCreditLimitChanges(ctx, "molly")
+		require.NoError(t, err)
+		require.Len(t, changes, 2)
+		for _, change := range changes {
+			assert.Equal(t, "ak_0123456789abcdef", change.ChangedBy)
+		}
+	})
+
+	t.Run("rejects limit the balance is already below", func(t *testing.T) {
+		mCtrl := gomock.NewController(t)
+		defer mCtrl.Finish()
+		storage := mocks.NewMockStorage(mCtrl)
+
+		storage.EXPECT().BeginTx(ctx, nil).Return(storage, nil)
+		expectAccountsLocked(storage, user)
+		storage.EXPECT().RollbackTx(ctx).Return(nil)
+
+		_, err := banking.NewService(storage).SetCreditLimit(ctx, "molly", decimal.New(10, 0), "alice", "")
+		require.Error(t, err)
+		assert.Equal(t, "account balance is below the new credit limit", err.Error())
+	})
+
+	t.Run("rejects house accounts", func(t *testing.T) {
+		mCtrl := gomock.NewController(t)
+		defer mCtrl.Finish()
+		storage := mocks.NewMockStorage(mCtrl)
+
+		storage.EXPECT().BeginTx(ctx, nil).Return(storage, nil)
+		expectAccountsLocked(storage, entities.Account{Name: "SYSTEM", Type: entities.SystemAccount, Currency: entities.USD})
+		storage.EXPECT().RollbackTx(ctx).Return(nil)
+
+		_, err := banking.NewService(storage).SetCreditLimit(ctx, "SYSTEM", decimal.New(10, 0), "alice", "")
+		require.Error(t, err)
+		assert.Equal(t, "credit limit can't be set for house accounts", err.Error())
+	})
+
+	t.Run("returns not found error for unknown account", func(t *testing.T) {
+		mCtrl := gomock.NewController(t)
+		defer mCtrl.Finish()
+		storage := mocks.NewMockStorage(mCtrl)
+
+		storage.EXPECT().BeginTx(ctx, nil).Return(storage, nil)
+		storage.EXPECT().GetAccountForUpdate(ctx, gomock.Any()).Return(errors.Wrap(sql.ErrNoRows, "can't obtain account nobody"))
+		storage.EXPECT().RollbackTx(ctx).Return(nil)
+
+		_, err := banking.NewService(storage).SetCreditLimit(ctx, "nobody", decimal.New(10, 0), "alice", "")
+		require.Error(t, err)
+		assert.Equal(t, "account not found", err.Error())
+	})
+
+	t.Run("validates request before touching storage", func(t *testing.T) {
+		mCtrl := gomock.NewController(t)
+		defer mCtrl.Finish()
+		storage := mocks.NewMockStorage(mCtrl)
+		svc := banking.NewService(storage)
+
+		_, err := svc.SetCreditLimit(ctx, "molly", decimal.New(-1, 0), "alice", "")
+		assert.Equal(t, "credit limit can't be negative", err.Error())
+
+		_, err = svc.SetCreditLimit(ctx, "molly", decimal.New(1, 0), " ", "")
+		assert.Equal(t, "changed_by should be present", err.Error())
+	})
+}
+
+func TestBankingSvcGetCreditLimitChanges(t *testing.T) {
+	mCtrl := gomock.NewController(t)
+	defer mCtrl.Finish()
+	storage := mocks.NewMockStorage(mCtrl)
+
+	changes := []entities.CreditLimitChange{{ID: 1, AccountID: 7, NewLimit: decimal.New(50, 0), ChangedBy: "alice"}}
+	storage.EXPECT().GetAccount(ctx, "molly").Return(entities.Account{ID: 7, Name: "molly"}, nil)
+	storage.EXPECT().GetCreditLimitChanges(ctx, 7).Return(changes, nil)
+
+	actual, err := banking.NewService(storage).GetCreditLimitChanges(context.Background(), "molly")
+	require.NoError(t, err)
+	assert.Equal(t, changes, actual)
+}
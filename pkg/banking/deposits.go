@@ -0,0 +1,114 @@
+package banking
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+	"github.com/shopspring/decimal"
+	"github.com/twonegatives/coinsph_challenge/pkg/entities"
+	"github.com/twonegatives/coinsph_challenge/pkg/storage"
+)
+
+var (
+	errExternalReferenceBlank = errors.New("external reference should be present")
+	errExternalReferenceUsed  = errors.New("external reference has already been used")
+	errHouseAccountExternal   = errors.New("deposits and withdrawals can't be booked to house accounts")
+)
+
+// Deposit credits the account with 'amount' of money coming from outside of the system.
+// Money is moved from SYSTEM account holding the account currency. Reference identifies
+// the operation in an external system (e.g. bank transfer reference) and may be used
+// by a single deposit only.
+// Returns a TransferReceipt with ReceiverBalance set to the new balance of the account.
+func (svc *Service) Deposit(ctx context.Context, accountName string, amount decimal.Decimal, reference string) (entities.TransferReceipt, error) {
+	return svc.bookExternal(ctx, entities.DepositTransaction, accountName, amount, reference)
+}
+
+// Withdraw debits the account with 'amount' of money leaving the system.
+// Money is moved to SYSTEM account holding the account currency. Reference identifies
+// the operation in an external system (e.g. card authorization id) and may be used
+// by a single withdrawal only.
+// Returns a TransferReceipt with SenderBalance set to the new balance of the account.
+func (svc *Service) Withdraw(ctx context.Context, accountName string, amount decimal.Decimal, reference string) (entities.TransferReceipt, error) {
+	return svc.bookExternal(ctx, entities.WithdrawalTransaction, accountName, amount, reference)
+}
+
+// bookExternal moves money between the account and SYSTEM account of its currency
+// in a transaction of the given kind
+func (svc *Service) bookExternal(ctx context.Context, kind entities.TransactionKind, accountName string, amount decimal.Decimal, reference string) (entities.TransferReceipt, error) {
+	if !amount.IsPositive() {
+		return entities.TransferReceipt{}, errAmountShouldBePositive
+	}
+
+	if accountName == "" {
+		return entities.TransferReceipt{}, errAccountNameBlank
+	}
+
+	if reference == "" {
+		return entities.TransferReceipt{}, errExternalReferenceBlank
+	}
+
+	// currency is needed to pick the SYSTEM account prior to locking,
+	// it never changes once the account is opened
+	account, err := svc.GetAccount(ctx, accountName)
+	if err != nil {
+		return entities.TransferReceipt{}, err
+	}
+
+	if account.Type.IsHouse() {
+		return entities.TransferReceipt{}, errHouseAccountExternal
+	}
+
+	if err := svc.validateAmount(account.Currency, amount); err != nil {
+		return entities.TransferReceipt{}, err
+	}
+
+	system := entities.Account{Name: entities.SystemAccountName(account.Currency)}
+	from, to := &system, &account
+	if kind == entities.WithdrawalTransaction {
+		from, to = &account, &system
+	}
+
+	txStorage, err := svc.store.BeginTx(ctx, nil)
+	if err != nil {
+		return entities.TransferReceipt{}, errors.Wrap(err, "can't open transaction")
+	}
+
+	defer txStorage.RollbackTx(ctx)
+
+	if err := lockAccounts(ctx, txStorage, paymentSide{account: from, label: "sender"}, paymentSide{account: to, label: "receiver"}); err != nil {
+		return entities.TransferReceipt{}, err
+	}
+
+	if err := checkAccountsActive(*from, *to); err != nil {
+		return entities.TransferReceipt{}, err
+	}
+
+	if !from.CanSpend(amount) {
+		return entities.TransferReceipt{}, errInsufficientFunds
+	}
+
+	transaction, err := createTransaction(ctx, txStorage, entities.Transaction{Kind: kind, ExternalReference: reference})
+	if errors.Cause(err) == storage.ErrAlreadyExists {
+		return entities.TransferReceipt{}, errExternalReferenceUsed
+	}
+	if err != nil {
+		return entities.TransferReceipt{}, errors.Wrap(err, "can't insert new transaction")
+	}
+
+	payments, err := bookPayments(ctx, txStorage, transaction, from, to, amount)
+	if err != nil {
+		return entities.TransferReceipt{}, err
+	}
+
+	if err := svc.commitTx(ctx, txStorage); err != nil {
+		return entities.TransferReceipt{}, errors.Wrap(err, "transaction commit failed")
+	}
+
+	return entities.TransferReceipt{
+		Transaction:     transaction,
+		Payments:        payments,
+		SenderBalance:   from.Balance,
+		ReceiverBalance: to.Balance,
+	}, nil
+}
@@ -0,0 +1,152 @@
+package banking_test
+
+import (
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/pkg/errors"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/twonegatives/coinsph_challenge/pkg/banking"
+	"github.com/twonegatives/coinsph_challenge/pkg/entities"
+	"github.com/twonegatives/coinsph_challenge/pkg/mocks"
+	pkgstorage "github.com/twonegatives/coinsph_challenge/pkg/storage"
+)
+
+func TestBankingSvcDeposit(t *testing.T) {
+	user := entities.Account{ID: 7, Name: "molly", Type: entities.UserAccount, Balance: decimal.New(10, 0), Currency: entities.EUR}
+	system := entities.Account{ID: 2, Name: "SYSTEM_EUR", Type: entities.SystemAccount, Balance: decimal.New(-10, 0), Currency: entities.EUR}
+
+	t.Run("moves money from SYSTEM account of the account currency", func(t *testing.T) {
+		mCtrl := gomock.NewController(t)
+		defer mCtrl.Finish()
+		storage := mocks.NewMockStorage(mCtrl)
+
+		amount := decimal.New(25, 0)
+		transaction := entities.Transaction{ID: 9, Kind: entities.DepositTransaction, ExternalReference: "wire-1"}
+
+		storage.EXPECT().GetAccount(ctx, "molly").Return(user, nil)
+		storage.EXPECT().BeginTx(ctx, nil).Return(storage, nil)
+		expectAccountsLocked(storage, user, system)
+		storage.EXPECT().CreateTransaction(ctx, entities.Transaction{Kind: entities.DepositTransaction, ExternalReference: "wire-1"}).Return(transaction, nil)
+		storage.EXPECT().SendPayment(ctx, entities.Payment{Transaction: transaction, Account: system, Counterparty: user, Direction: entities.Outgoing, Amount: amount, Currency: entities.EUR}).Return(nil)
+		storage.EXPECT().SendPayment(ctx, entities.Payment{Transaction: transaction, Account: user, Counterparty: system, Direction: entities.Incoming, Amount: amount, Currency: entities.EUR}).Return(nil)
+		storage.EXPECT().SetAccountBalance(ctx, gomock.Any()).Times(2).Return(nil)
+		storage.EXPECT().CommitTx(ctx).Return(nil)
+		storage.EXPECT().RollbackTx(ctx).Return(nil)
+
+		receipt, err := banking.NewService(storage).Deposit(ctx, "molly", amount, "wire-1")
+		require.NoError(t, err)
+		assert.Equal(t, transaction, receipt.Transaction)
+		assert.Equal(t, "35", receipt.ReceiverBalance.String())
+		assert.Equal(t, "-35", receipt.SenderBalance.String())
+	})
+
+	t.Run("rejects reused reference", func(t *testing.T) {
+		mCtrl := gomock.NewController(t)
+		defer mCtrl.Finish()
+		storage := mocks.NewMockStorage(mCtrl)
+
+		storage.EXPECT().GetAccount(ctx, "molly").Return(user, nil)
+		storage.EXPECT().BeginTx(ctx, nil).Return(storage, nil)
+		expectAccountsLocked(storage, user, system)
+		storage.EXPECT().CreateTransaction(ctx, gomock.Any()).Return(entities.Transaction{}, errors.Wrap(pkgstorage.ErrAlreadyExists, "deposit with reference wire-1"))
+		storage.EXPECT().RollbackTx(ctx).Return(nil)
+
+		_, err := banking.NewService(storage).Deposit(ctx, "molly", decimal.New(25, 0), "wire-1")
+		require.Error(t, err)
+		assert.Equal(t, "external reference has already been used", err.Error())
+	})
+
+	t.Run("validates request before touching storage", func(t *testing.T) {
+		mCtrl := gomock.NewController(t)
+		defer mCtrl.Finish()
+		storage := mocks.NewMockStorage(mCtrl)
+		svc := banking.NewService(storage)
+
+		cases := []struct {
+			name      string
+			amount    decimal.Decimal
+			reference string
+			err       string
+		}{
+			{name: "molly", amount: decimal.New(-1, 0), reference: "wire-1", err: "should be a positive number"},
+			{name: "", amount: decimal.New(1, 0), reference: "wire-1", err: "account name should be present"},
+			{name: "molly", amount: decimal.New(1, 0), reference: "", err: "external reference should be present"},
+		}
+
+		for _, tc := range cases {
+			_, err := svc.Deposit(ctx, tc.name, tc.amount, tc.reference)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tc.err)
+		}
+	})
+
+	t.Run("rejects house accounts", func(t *testing.T) {
+		mCtrl := gomock.NewController(t)
+		defer mCtrl.Finish()
+		storage := mocks.NewMockStorage(mCtrl)
+
+		storage.EXPECT().GetAccount(ctx, "SYSTEM_EUR").Return(system, nil)
+
+		_, err := banking.NewService(storage).Deposit(ctx, "SYSTEM_EUR", decimal.New(1, 0), "wire-1")
+		require.Error(t, err)
+		assert.Equal(t, "deposits and withdrawals can't be booked to house accounts", err.Error())
+	})
+
+	t.Run("returns not found error for unknown account", func(t *testing.T) {
+		mCtrl := gomock.NewController(t)
+		defer mCtrl.Finish()
+		storage := mocks.NewMockStorage(mCtrl)
+
+		storage.EXPECT().GetAccount(ctx, "nobody").Return(entities.Account{}, errors.Wrap(pkgstorage.ErrNotFound, "account nobody"))
+
+		_, err := banking.NewService(storage).Deposit(ctx, "nobody", decimal.New(1, 0), "wire-1")
+		require.Error(t, err)
+		assert.Equal(t, "account not found", err.Error())
+	})
+}
+
+func TestBankingSvcWithdraw(t *testing.T) {
+	user := entities.Account{ID: 7, Name: "molly", Type: entities.UserAccount, Balance: decimal.New(10, 0), Currency: entities.EUR}
+	system := entities.Account{ID: 2, Name: "SYSTEM_EUR", Type: entities.SystemAccount, Balance: decimal.New(-10, 0), Currency: entities.EUR}
+
+	t.Run("moves money to SYSTEM account of the account currency", func(t *testing.T) {
+		mCtrl := gomock.NewController(t)
+		defer mCtrl.Finish()
+		storage := mocks.NewMockStorage(mCtrl)
+
+		amount := decimal.New(4, 0)
+		transaction := entities.Transaction{ID: 9, Kind: entities.WithdrawalTransaction, ExternalReference: "card-1"}
+
+		storage.EXPECT().GetAccount(ctx, "molly").Return(user, nil)
+		storage.EXPECT().BeginTx(ctx, nil).Return(storage, nil)
+		expectAccountsLocked(storage, user, system)
+		storage.EXPECT().CreateTransaction(ctx, entities.Transaction{Kind: entities.WithdrawalTransaction, ExternalReference: "card-1"}).Return(transaction, nil)
+		storage.EXPECT().SendPayment(ctx, entities.Payment{Transaction: transaction, Account: user, Counterparty: system, Direction: entities.Outgoing, Amount: amount, Currency: entities.EUR}).Return(nil)
+		storage.EXPECT().SendPayment(ctx, entities.Payment{Transaction: transaction, Account: system, Counterparty: user, Direction: entities.Incoming, Amount: amount, Currency: entities.EUR}).Return(nil)
+		storage.EXPECT().SetAccountBalance(ctx, gomock.Any()).Times(2).Return(nil)
+		storage.EXPECT().CommitTx(ctx).Return(nil)
+		storage.EXPECT().RollbackTx(ctx).Return(nil)
+
+		receipt, err := banking.NewService(storage).Withdraw(ctx, "molly", amount, "card-1")
+		require.NoError(t, err)
+		assert.Equal(t, "6", receipt.SenderBalance.String())
+	})
+
+	t.Run("catches withdrawals exceeding balance", func(t *testing.T) {
+		mCtrl := gomock.NewController(t)
+		defer mCtrl.Finish()
+		storage := mocks.NewMockStorage(mCtrl)
+
+		storage.EXPECT().GetAccount(ctx, "molly").Return(user, nil)
+		storage.EXPECT().BeginTx(ctx, nil).Return(storage, nil)
+		expectAccountsLocked(storage, user, system)
+		storage.EXPECT().RollbackTx(ctx).Return(nil)
+
+		_, err := banking.NewService(storage).Withdraw(ctx, "molly", decimal.New(11, 0), "card-1")
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "insufficient funds")
+	})
+}
@@ -0,0 +1,33 @@
+package banking
+
+import (
+	"context"
+	"time"
+)
+
+// detachedContext carries values of its parent, while neither its cancellation
+// nor its deadline apply. It lets cleanups of a request finish after the client is gone.
+type detachedContext struct {
+	parent context.Context
+}
+
+// detach returns a context carrying values of the parent which is never done
+func detach(parent context.Context) context.Context {
+	return detachedContext{parent: parent}
+}
+
+func (detachedContext) Deadline() (time.Time, bool) {
+	return time.Time{}, false
+}
+
+func (detachedContext) Done() <-chan struct{} {
+	return nil
+}
+
+func (detachedContext) Err() error {
+	return nil
+}
+
+func (c detachedContext) Value(key interface{}) interface{} {
+	return c.parent.Value(key)
+}
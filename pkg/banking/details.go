@@ -0,0 +1,63 @@
+package banking
+
+import (
+	"unicode/utf8"
+
+	"github.com/pkg/errors"
+	"github.com/twonegatives/coinsph_challenge/pkg/entities"
+)
+
+const (
+	maxDescriptionLength   = 255
+	maxReferenceLength     = 255
+	maxMetadataKeys        = 20
+	maxMetadataKeyLength   = 40
+	maxMetadataValueLength = 500
+)
+
+var (
+	errDescriptionTooLong   = errors.Errorf("description should not be longer than %d characters", maxDescriptionLength)
+	errReferenceTooLong     = errors.Errorf("external reference should not be longer than %d characters", maxReferenceLength)
+	errMetadataTooLarge     = errors.Errorf("metadata should not have more than %d keys", maxMetadataKeys)
+	errInvalidMetadataKey   = errors.Errorf("metadata keys should be 1 to %d characters long", maxMetadataKeyLength)
+	errMetadataValueTooLong = errors.Errorf("metadata values should not be longer than %d characters", maxMetadataValueLength)
+)
+
+// validatePaymentDetails checks sizes of the attributes a client attaches to a transfer.
+func validatePaymentDetails(details entities.PaymentDetails) error {
+	if utf8.RuneCountInString(details.Description) > maxDescriptionLength {
+		return errDescriptionTooLong
+	}
+
+	if utf8.RuneCountInString(details.ExternalReference) > maxReferenceLength {
+		return errReferenceTooLong
+	}
+
+	if len(details.Metadata) > maxMetadataKeys {
+		return errMetadataTooLarge
+	}
+
+	for key, value := range details.Metadata {
+		if length := utf8.RuneCountInString(key); length == 0 || length > maxMetadataKeyLength {
+			return errInvalidMetadataKey
+		}
+
+		if utf8.RuneCountInString(value) > maxMetadataValueLength {
+			return errMetadataValueTooLong
+		}
+	}
+
+	return nil
+}
+
+// withDetails attaches the details to a transfer made by the sender.
+// The reference is scoped to the sender, so that different senders may use the same one.
+func withDetails(transaction entities.Transaction, sender entities.Account, details entities.PaymentDetails) entities.Transaction {
+	transaction.Description = details.Description
+	transaction.Metadata = details.Metadata
+	if details.ExternalReference != "" {
+		transaction.ExternalReference = details.ExternalReference
+		transaction.SenderID = sender.ID
+	}
+	return transaction
+}
@@ -0,0 +1,106 @@
+package banking_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/twonegatives/coinsph_challenge/pkg/banking"
+	"github.com/twonegatives/coinsph_challenge/pkg/entities"
+	"github.com/twonegatives/coinsph_challenge/pkg/memstorage"
+)
+
+func TestBankingSvcPaymentDetails(t *testing.T) {
+	// setup returns a service with funded 'alice' and 'bob' accounts and an empty 'shop' account
+	setup := func(t *testing.T) *banking.Service {
+		svc := banking.NewService(memstorage.NewMemStorage())
+		for _, name := range []string{"alice", "bob", "shop"} {
+			_, err := svc.CreateAccount(ctx, name, entities.USD, entities.AccountProfile{})
+			require.NoError(t, err)
+		}
+
+		for _, name := range []string{"alice", "bob"} {
+			_, err := svc.Deposit(ctx, name, decimal.New(100, 0), "wire-"+name)
+			require.NoError(t, err)
+		}
+		return svc
+	}
+
+	pay := func(svc *banking.Service, from string, details entities.PaymentDetails) (entities.TransferReceipt, error) {
+		return svc.SendPayment(ctx, entities.Account{Name: from}, entities.Account{Name: "shop"}, decimal.New(1, 0), details)
+	}
+
+	t.Run("stores details along with the transfer", func(t *testing.T) {
+		svc := setup(t)
+		details := entities.PaymentDetails{
+			Description:       "order #17",
+			ExternalReference: "order-17",
+			Metadata:          map[string]string{"channel": "web"},
+		}
+
+		receipt, err := pay(svc, "alice", details)
+		require.NoError(t, err)
+
+		transaction, _, err := svc.GetTransaction(ctx, receipt.Transaction.ID)
+		require.NoError(t, err)
+		assert.Equal(t, "order #17", transaction.Description)
+		assert.Equal(t, "order-17", transaction.ExternalReference)
+		assert.Equal(t, map[string]string{"channel": "web"}, transaction.Metadata)
+
+		page, err := svc.GetPaymentsList(ctx, entities.PaymentsFilter{Reference: "order-17"})
+		require.NoError(t, err)
+		require.Len(t, page.Payments, 2)
+		for _, payment := range page.Payments {
+			assert.Equal(t, receipt.Transaction.ID, payment.Transaction.ID)
+			assert.Equal(t, "order #17", payment.Transaction.Description)
+			assert.Equal(t, map[string]string{"channel": "web"}, payment.Transaction.Metadata)
+		}
+	})
+
+	t.Run("keeps references unique per sender", func(t *testing.T) {
+		svc := setup(t)
+		_, err := pay(svc, "alice", entities.PaymentDetails{ExternalReference: "order-17"})
+		require.NoError(t, err)
+
+		_, err = pay(svc, "alice", entities.PaymentDetails{ExternalReference: "order-17"})
+		assert.EqualError(t, err, "external reference has already been used")
+
+		_, err = pay(svc, "bob", entities.PaymentDetails{ExternalReference: "order-17"})
+		assert.NoError(t, err)
+
+		_, err = pay(svc, "alice", entities.PaymentDetails{})
+		assert.NoError(t, err)
+		_, err = pay(svc, "alice", entities.PaymentDetails{})
+		assert.NoError(t, err)
+
+		alice, err := svc.GetAccount(ctx, "alice")
+		require.NoError(t, err)
+		assert.Equal(t, "97", alice.Balance.String())
+	})
+
+	t.Run("validates details", func(t *testing.T) {
+		svc := setup(t)
+		tooManyKeys := map[string]string{}
+		for _, key := range strings.Split("abcdefghijklmnopqrstu", "") {
+			tooManyKeys[key] = "value"
+		}
+
+		cases := []struct {
+			details entities.PaymentDetails
+			err     string
+		}{
+			{details: entities.PaymentDetails{Description: strings.Repeat("d", 256)}, err: "description should not be longer than 255 characters"},
+			{details: entities.PaymentDetails{ExternalReference: strings.Repeat("r", 256)}, err: "external reference should not be longer than 255 characters"},
+			{details: entities.PaymentDetails{Metadata: tooManyKeys}, err: "metadata should not have more than 20 keys"},
+			{details: entities.PaymentDetails{Metadata: map[string]string{"": "value"}}, err: "metadata keys should be 1 to 40 characters long"},
+			{details: entities.PaymentDetails{Metadata: map[string]string{"key": strings.Repeat("v", 501)}}, err: "metadata values should not be longer than 500 characters"},
+		}
+
+		for _, tc := range cases {
+			_, err := pay(svc, "alice", tc.details)
+			assert.EqualError(t, err, tc.err)
+		}
+	})
+}
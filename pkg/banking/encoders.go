@@ -14,13 +14,23 @@ import (
 type paymentsJSONEncoder struct {
 }
 
-// encode is the method which takes application-level entity.Payment
+// encode is the method which takes a page of application-level entity.Payment
 // and converts it to JSON format of paymentsJSONEncoder
-func (e *paymentsJSONEncoder) encode(payments []entities.Payment) ([]byte, error) {
-	if payments == nil {
+func (e *paymentsJSONEncoder) encode(page entities.PaymentsPage) ([]byte, error) {
+	if page.Payments == nil {
 		return nil, errors.New("payments array should be initialized in order to encode it")
 	}
 
+	result := map[string]interface{}{
+		"payments":    e.elements(page.Payments),
+		"next_cursor": nextCursor(page.NextCursor),
+	}
+
+	return json.Marshal(result)
+}
+
+// elements converts payments into a slice of their JSON representations
+func (e *paymentsJSONEncoder) elements(payments []entities.Payment) []map[string]interface{} {
 	elements := make([]map[string]interface{}, len(payments))
 
 	for index, payment := range payments {
@@ -29,6 +39,29 @@ func (e *paymentsJSONEncoder) encode(payments []entities.Payment) ([]byte, error
 			"amount":    payment.Amount,
 			"direction": payment.Direction,
 			"currency":  payment.Currency,
+			"kind":      payment.Transaction.Kind,
+		}
+
+		if payment.Transaction.ExternalReference != "" {
+			element["external_reference"] = payment.Transaction.ExternalReference
+		}
+
+		if payment.Transaction.OriginalID != 0 {
+			element["original_transaction_id"] = payment.Transaction.OriginalID
+		}
+
+		if payment.Transaction.Description != "" {
+			element["description"] = payment.Transaction.Description
+		}
+
+		if len(payment.Transaction.Metadata) > 0 {
+			element["metadata"] = payment.Transaction.Metadata
+		}
+
+		// the fee charged by the transaction is shown along with each of its payments,
+		// payments of the fee itself are the ones to FEE account
+		if payment.Transaction.Fee.IsPositive() {
+			element["fee"] = payment.Transaction.Fee
 		}
 
 		if payment.Direction == entities.Outgoing {
@@ -40,9 +73,5 @@ func (e *paymentsJSONEncoder) encode(payments []entities.Payment) ([]byte, error
 		elements[index] = element
 	}
 
-	result := map[string]interface{}{
-		"payments": elements,
-	}
-
-	return json.Marshal(result)
+	return elements
 }
@@ -11,29 +11,298 @@ import (
 func MakeCreateAccountEndpoint(svc BankingService) endpoint.Endpoint {
 	return func(ctx context.Context, request interface{}) (interface{}, error) {
 		req := request.(createAccountRequest)
-		account, err := svc.CreateAccount(ctx, req.Name)
+		account, err := svc.CreateAccount(ctx, req.Name, req.Currency, req.Profile)
 		return createAccountResponse{Account: account}, err
 	}
 }
 
+func MakeUpdateAccountEndpoint(svc BankingService) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(updateAccountRequest)
+		account, err := svc.UpdateAccount(ctx, req.Name, req.Update)
+		return updateAccountResponse{Account: account}, err
+	}
+}
+
 func MakeGetAccountsEndpoint(svc BankingService) endpoint.Endpoint {
-	return func(ctx context.Context, _request interface{}) (interface{}, error) {
-		accounts, err := svc.GetAccountsList(ctx)
-		return getAccountsResponse{Accounts: accounts}, err
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(getAccountsRequest)
+		page, err := svc.GetAccountsList(ctx, req.Filter)
+		return getAccountsResponse{Accounts: page.Accounts, NextCursor: nextCursor(page.NextCursor)}, err
+	}
+}
+
+func MakeGetAccountEndpoint(svc BankingService) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(getAccountRequest)
+		account, err := svc.GetAccount(ctx, req.Name)
+		return getAccountResponse{Account: account}, err
+	}
+}
+
+func MakeGetAccountPaymentsEndpoint(svc BankingService) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(getAccountPaymentsRequest)
+		page, err := svc.GetAccountPayments(ctx, req.Name, req.Filter)
+		return getPaymentsResponse{Page: page}, err
 	}
 }
 
 func MakeGetPaymentsEndpoint(svc BankingService) endpoint.Endpoint {
-	return func(ctx context.Context, _request interface{}) (interface{}, error) {
-		payments, err := svc.GetPaymentsList(ctx)
-		return getPaymentsResponse{Payments: payments}, err
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(getPaymentsRequest)
+		page, err := svc.GetPaymentsList(ctx, req.Filter)
+		return getPaymentsResponse{Page: page}, err
 	}
 }
 
 func MakeSendPaymentEndpoint(svc BankingService) endpoint.Endpoint {
 	return func(ctx context.Context, request interface{}) (interface{}, error) {
 		req := request.(sendPaymentRequest)
-		err := svc.SendPayment(ctx, req.From, req.To, req.Amount)
-		return map[string]interface{}{}, err
+		if req.QuoteID != 0 {
+			receipt, err := svc.SendFXPayment(ctx, req.From, req.To, req.Amount, req.QuoteID, req.Details)
+			return sendPaymentResponse{Receipt: receipt}, err
+		}
+
+		receipt, err := svc.SendPayment(ctx, req.From, req.To, req.Amount, req.Details)
+		return sendPaymentResponse{Receipt: receipt}, err
+	}
+}
+
+func MakePreviewPaymentEndpoint(svc BankingService) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(sendPaymentRequest)
+		preview, err := svc.PreviewPayment(ctx, req.From, req.To, req.Amount)
+		return previewPaymentResponse{Preview: preview}, err
+	}
+}
+
+func MakeSendPaymentBatchEndpoint(svc BankingService) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(sendPaymentBatchRequest)
+		receipt, err := svc.SendPaymentBatch(ctx, req.Orders, req.Mode)
+		return sendPaymentBatchResponse{Receipt: receipt}, err
+	}
+}
+
+func MakePostTransactionEndpoint(svc BankingService) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(postTransactionRequest)
+		receipt, err := svc.PostTransaction(ctx, req.Legs)
+		return postTransactionResponse{Receipt: receipt}, err
+	}
+}
+
+func MakeDepositEndpoint(svc BankingService) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(externalOperationRequest)
+		receipt, err := svc.Deposit(ctx, req.Name, req.Amount, req.Reference)
+		return externalOperationResponse{Receipt: receipt, Balance: receipt.ReceiverBalance}, err
+	}
+}
+
+func MakeWithdrawEndpoint(svc BankingService) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(externalOperationRequest)
+		receipt, err := svc.Withdraw(ctx, req.Name, req.Amount, req.Reference)
+		return externalOperationResponse{Receipt: receipt, Balance: receipt.SenderBalance}, err
+	}
+}
+
+func MakeFreezeAccountEndpoint(svc BankingService) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(changeAccountStatusRequest)
+		account, err := svc.FreezeAccount(ctx, req.Name)
+		return changeAccountStatusResponse{Account: account}, err
+	}
+}
+
+func MakeUnfreezeAccountEndpoint(svc BankingService) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(changeAccountStatusRequest)
+		account, err := svc.UnfreezeAccount(ctx, req.Name)
+		return changeAccountStatusResponse{Account: account}, err
+	}
+}
+
+func MakeCloseAccountEndpoint(svc BankingService) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(changeAccountStatusRequest)
+		account, err := svc.CloseAccount(ctx, req.Name)
+		return changeAccountStatusResponse{Account: account}, err
+	}
+}
+
+func MakeSetCreditLimitEndpoint(svc BankingService) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(setCreditLimitRequest)
+		account, err := svc.SetCreditLimit(ctx, req.Name, req.Limit, req.ChangedBy, req.Reason)
+		return setCreditLimitResponse{Account: account}, err
+	}
+}
+
+func MakeGetCreditLimitChangesEndpoint(svc BankingService) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(getCreditLimitChangesRequest)
+		changes, err := svc.GetCreditLimitChanges(ctx, req.Name)
+		return getCreditLimitChangesResponse{Changes: changes}, err
+	}
+}
+
+func MakeGetTransactionEndpoint(svc BankingService) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(getTransactionRequest)
+		transaction, payments, err := svc.GetTransaction(ctx, req.ID)
+		return getTransactionResponse{Transaction: transaction, Payments: payments}, err
+	}
+}
+
+func MakeReverseTransactionEndpoint(svc BankingService) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(compensateTransactionRequest)
+		receipt, err := svc.ReverseTransaction(ctx, req.ID)
+		return compensateTransactionResponse{Receipt: receipt}, err
+	}
+}
+
+func MakeRefundTransactionEndpoint(svc BankingService) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(compensateTransactionRequest)
+		receipt, err := svc.RefundTransaction(ctx, req.ID, req.Amount)
+		return compensateTransactionResponse{Receipt: receipt}, err
+	}
+}
+
+func MakeAuthorizeEndpoint(svc BankingService) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(authorizeRequest)
+		hold, err := svc.Authorize(ctx, req.From, req.To, req.Amount)
+		return authorizeResponse{Hold: hold}, err
+	}
+}
+
+func MakeGetHoldEndpoint(svc BankingService) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(holdRequest)
+		hold, err := svc.GetHold(ctx, req.ID)
+		return holdResponse{Hold: hold}, err
+	}
+}
+
+func MakeCaptureHoldEndpoint(svc BankingService) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(captureHoldRequest)
+		receipt, err := svc.CaptureHold(ctx, req.ID, req.Amount)
+		return sendPaymentResponse{Receipt: receipt}, err
+	}
+}
+
+func MakeVoidHoldEndpoint(svc BankingService) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(holdRequest)
+		hold, err := svc.VoidHold(ctx, req.ID)
+		return holdResponse{Hold: hold}, err
+	}
+}
+
+func MakeCreateScheduledPaymentEndpoint(svc BankingService) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(createScheduledPaymentRequest)
+		payment, err := svc.CreateScheduledPayment(ctx, req.Payment)
+		return createScheduledPaymentResponse{Payment: payment}, err
+	}
+}
+
+func MakeGetScheduledPaymentsEndpoint(svc BankingService) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(getScheduledPaymentsRequest)
+		payments, err := svc.GetScheduledPayments(ctx, req.AccountName)
+		return getScheduledPaymentsResponse{Payments: payments}, err
+	}
+}
+
+func MakeGetScheduledPaymentEndpoint(svc BankingService) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(scheduledPaymentRequest)
+		payment, err := svc.GetScheduledPayment(ctx, req.ID)
+		return scheduledPaymentResponse{Payment: payment}, err
+	}
+}
+
+func MakeUpdateScheduledPaymentEndpoint(svc BankingService) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(updateScheduledPaymentRequest)
+		payment, err := svc.UpdateScheduledPayment(ctx, req.ID, req.Update)
+		return scheduledPaymentResponse{Payment: payment}, err
+	}
+}
+
+func MakeCancelScheduledPaymentEndpoint(svc BankingService) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(scheduledPaymentRequest)
+		payment, err := svc.CancelScheduledPayment(ctx, req.ID)
+		return scheduledPaymentResponse{Payment: payment}, err
+	}
+}
+
+func MakeGetScheduledRunsEndpoint(svc BankingService) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(scheduledPaymentRequest)
+		runs, err := svc.GetScheduledRuns(ctx, req.ID)
+		return getScheduledRunsResponse{Runs: runs}, err
+	}
+}
+
+func MakeCreateQuoteEndpoint(svc BankingService) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(createQuoteRequest)
+		quote, err := svc.CreateQuote(ctx, req.From, req.To, req.Amount)
+		return createQuoteResponse{Quote: quote}, err
+	}
+}
+
+func MakeCreateAPIKeyEndpoint(svc BankingService) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(createAPIKeyRequest)
+		key, err := svc.CreateAPIKey(ctx, req.Name, req.Role, req.OwnerID)
+		return createAPIKeyResponse{Key: key}, err
+	}
+}
+
+func MakeGetAPIKeysEndpoint(svc BankingService) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		keys, err := svc.GetAPIKeys(ctx)
+		return getAPIKeysResponse{Keys: keys}, err
+	}
+}
+
+func MakeRevokeAPIKeyEndpoint(svc BankingService) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(revokeAPIKeyRequest)
+		key, err := svc.RevokeAPIKey(ctx, req.KeyID)
+		return revokeAPIKeyResponse{Key: key}, err
+	}
+}
+
+func MakeGetAuditLogEndpoint(svc BankingService) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(getAuditLogRequest)
+		page, err := svc.GetAuditLog(ctx, req.Filter)
+		return getAuditLogResponse{Entries: page.Entries, NextCursor: nextCursor(page.NextCursor)}, err
+	}
+}
+
+func MakeVerifyAuditLogEndpoint(svc BankingService) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		verification, err := svc.VerifyAuditLog(ctx)
+		return verifyAuditLogResponse{Verification: verification}, err
+	}
+}
+
+// nextCursor converts blank cursor of the last page into nil, so that it is rendered as null
+func nextCursor(cursor string) *string {
+	if cursor == "" {
+		return nil
 	}
+	return &cursor
 }
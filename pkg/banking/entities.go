@@ -1,38 +1,242 @@
 package banking
 
 import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
 	"github.com/shopspring/decimal"
 	"github.com/twonegatives/coinsph_challenge/pkg/entities"
 )
 
 // sendPaymentRequest is a structure which banking transport layer
 // uses to pass data further to endpoint layer on
-// POST /api/v1/payments request
+// POST /api/v1/payments and POST /api/v1/payments/preview requests.
+// QuoteID and Details are not taken into account by previews
 type sendPaymentRequest struct {
+	From    entities.Account
+	To      entities.Account
+	Amount  decimal.Decimal
+	QuoteID int
+	Details entities.PaymentDetails
+}
+
+// sendPaymentBatchRequest is a structure which banking transport layer
+// uses to pass data further to endpoint layer on
+// POST /api/v1/payment-batches request
+type sendPaymentBatchRequest struct {
+	Mode   entities.BatchMode
+	Orders []entities.PaymentOrder
+}
+
+// postTransactionRequest is a structure which banking transport layer
+// uses to pass data further to endpoint layer on
+// POST /api/v1/transactions request
+type postTransactionRequest struct {
+	Legs []entities.Leg
+}
+
+// externalOperationRequest is a structure which banking transport layer
+// uses to pass data further to endpoint layer on
+// POST /api/v1/accounts/{name}/deposits and POST /api/v1/accounts/{name}/withdrawals requests
+type externalOperationRequest struct {
+	Name      string
+	Amount    decimal.Decimal
+	Reference string
+}
+
+// setCreditLimitRequest is a structure which banking transport layer
+// uses to pass data further to endpoint layer on
+// PUT /api/v1/admin/accounts/{name}/credit-limit request
+type setCreditLimitRequest struct {
+	Name      string
+	Limit     decimal.Decimal
+	ChangedBy string
+	Reason    string
+}
+
+// getCreditLimitChangesRequest is a structure which banking transport layer
+// uses to pass data further to endpoint layer on
+// GET /api/v1/admin/accounts/{name}/credit-limit-changes request
+type getCreditLimitChangesRequest struct {
+	Name string
+}
+
+// compensateTransactionRequest is a structure which banking transport layer
+// uses to pass data further to endpoint layer on
+// POST /api/v1/transactions/{id}/reverse and POST /api/v1/transactions/{id}/refund requests.
+// Amount is only set for refunds.
+type compensateTransactionRequest struct {
+	ID     int
+	Amount decimal.Decimal
+}
+
+// authorizeRequest is a structure which banking transport layer
+// uses to pass data further to endpoint layer on
+// POST /api/v1/authorizations request
+type authorizeRequest struct {
 	From   entities.Account
 	To     entities.Account
 	Amount decimal.Decimal
 }
 
+// holdRequest is a structure which banking transport layer
+// uses to pass data further to endpoint layer on
+// GET /api/v1/authorizations/{id} and POST /api/v1/authorizations/{id}/void requests
+type holdRequest struct {
+	ID int
+}
+
+// captureHoldRequest is a structure which banking transport layer
+// uses to pass data further to endpoint layer on
+// POST /api/v1/authorizations/{id}/capture request.
+// Zero Amount stands for the authorized one.
+type captureHoldRequest struct {
+	ID     int
+	Amount decimal.Decimal
+}
+
+// createScheduledPaymentRequest is a structure which banking transport layer
+// uses to pass data further to endpoint layer on
+// POST /api/v1/scheduled-payments request
+type createScheduledPaymentRequest struct {
+	Payment entities.ScheduledPayment
+}
+
+// getScheduledPaymentsRequest is a structure which banking transport layer
+// uses to pass data further to endpoint layer on
+// GET /api/v1/scheduled-payments request. Blank AccountName stands for all accounts.
+type getScheduledPaymentsRequest struct {
+	AccountName string
+}
+
+// scheduledPaymentRequest is a structure which banking transport layer
+// uses to pass data further to endpoint layer on GET and DELETE /api/v1/scheduled-payments/{id}
+// and GET /api/v1/scheduled-payments/{id}/runs requests
+type scheduledPaymentRequest struct {
+	ID int
+}
+
+// updateScheduledPaymentRequest is a structure which banking transport layer
+// uses to pass data further to endpoint layer on
+// PATCH /api/v1/scheduled-payments/{id} request
+type updateScheduledPaymentRequest struct {
+	ID     int
+	Update entities.ScheduledPaymentUpdate
+}
+
+// changeAccountStatusRequest is a structure which banking transport layer
+// uses to pass data further to endpoint layer on
+// POST /api/v1/accounts/{name}/freeze, /unfreeze and /close requests
+type changeAccountStatusRequest struct {
+	Name string
+}
+
+// getTransactionRequest is a structure which banking transport layer
+// uses to pass data further to endpoint layer on
+// GET /api/v1/transactions/{id} request
+type getTransactionRequest struct {
+	ID int
+}
+
+// getAccountsRequest is a structure which banking transport layer
+// uses to pass data further to endpoint layer on
+// GET /api/v1/accounts request
+type getAccountsRequest struct {
+	Filter entities.AccountsFilter
+}
+
+// getAccountRequest is a structure which banking transport layer
+// uses to pass data further to endpoint layer on
+// GET /api/v1/accounts/{name} request
+type getAccountRequest struct {
+	Name string
+}
+
+// getAccountPaymentsRequest is a structure which banking transport layer
+// uses to pass data further to endpoint layer on
+// GET /api/v1/accounts/{name}/payments request
+type getAccountPaymentsRequest struct {
+	Name   string
+	Filter entities.PaymentsFilter
+}
+
+// getPaymentsRequest is a structure which banking transport layer
+// uses to pass data further to endpoint layer on
+// GET /api/v1/payments request
+type getPaymentsRequest struct {
+	Filter entities.PaymentsFilter
+}
+
+// createQuoteRequest is a structure which banking transport layer
+// uses to pass data further to endpoint layer on
+// POST /api/v1/quotes request
+type createQuoteRequest struct {
+	From   entities.Currency
+	To     entities.Currency
+	Amount decimal.Decimal
+}
+
 // createAccountRequest is a structure which banking transport layer
 // uses to pass data further to endpoint layer on
 // POST /api/v1/accounts request
 type createAccountRequest struct {
-	Name string
+	Name     string
+	Currency entities.Currency
+	Profile  entities.AccountProfile
+}
+
+// updateAccountRequest is a structure which banking transport layer
+// uses to pass data further to endpoint layer on
+// PATCH /api/v1/accounts/{name} request
+type updateAccountRequest struct {
+	Name   string
+	Update entities.AccountUpdate
+}
+
+// createAPIKeyRequest is a structure which banking transport layer
+// uses to pass data further to endpoint layer on
+// POST /api/v1/admin/api-keys request
+type createAPIKeyRequest struct {
+	Name    string
+	Role    entities.Role
+	OwnerID string
+}
+
+// revokeAPIKeyRequest is a structure which banking transport layer
+// uses to pass data further to endpoint layer on
+// DELETE /api/v1/admin/api-keys/{key_id} request
+type revokeAPIKeyRequest struct {
+	KeyID string
+}
+
+// getAuditLogRequest is a structure which banking transport layer
+// uses to pass data further to endpoint layer on
+// GET /api/v1/admin/audit request
+type getAuditLogRequest struct {
+	Filter entities.AuditFilter
 }
 
 // getAccountsResponse is a structure which banking endpoint layer
 // uses to pass data upside down to transport layer on
 // GET /api/v1/accounts
 type getAccountsResponse struct {
-	Accounts []entities.Account `json:"accounts"`
+	Accounts   []entities.Account `json:"accounts"`
+	NextCursor *string            `json:"next_cursor"`
+}
+
+// getAccountResponse is a structure which banking endpoint layer
+// uses to pass data upside down to transport layer on
+// GET /api/v1/accounts/{name}
+type getAccountResponse struct {
+	Account entities.Account `json:"account"`
 }
 
 // getPaymentsResponse is a structure which banking endpoint layer
 // uses to pass data upside down to transport layer on
-// GET /api/v1/payments
+// GET /api/v1/payments and GET /api/v1/accounts/{name}/payments
 type getPaymentsResponse struct {
-	Payments []entities.Payment `json:"payments"`
+	Page entities.PaymentsPage
 }
 
 // createAccountsResponse is a structure which banking endpoint layer
@@ -41,3 +245,502 @@ type getPaymentsResponse struct {
 type createAccountResponse struct {
 	Account entities.Account `json:"account"`
 }
+
+// updateAccountResponse is a structure which banking endpoint layer
+// uses to pass data upside down to transport layer on
+// PATCH /api/v1/accounts/{name}
+type updateAccountResponse struct {
+	Account entities.Account `json:"account"`
+}
+
+// changeAccountStatusResponse is a structure which banking endpoint layer
+// uses to pass data upside down to transport layer on
+// POST /api/v1/accounts/{name}/freeze, /unfreeze and /close
+type changeAccountStatusResponse struct {
+	Account entities.Account `json:"account"`
+}
+
+// setCreditLimitResponse is a structure which banking endpoint layer
+// uses to pass data upside down to transport layer on
+// PUT /api/v1/admin/accounts/{name}/credit-limit
+type setCreditLimitResponse struct {
+	Account entities.Account `json:"account"`
+}
+
+// getCreditLimitChangesResponse is a structure which banking endpoint layer
+// uses to pass data upside down to transport layer on
+// GET /api/v1/admin/accounts/{name}/credit-limit-changes
+type getCreditLimitChangesResponse struct {
+	Changes []entities.CreditLimitChange `json:"credit_limit_changes"`
+}
+
+// createQuoteResponse is a structure which banking endpoint layer
+// uses to pass data upside down to transport layer on
+// POST /api/v1/quotes
+type createQuoteResponse struct {
+	Quote entities.Quote `json:"quote"`
+}
+
+// sendPaymentResponse is a structure which banking endpoint layer
+// uses to pass data upside down to transport layer on
+// POST /api/v1/payments and POST /api/v1/authorizations/{id}/capture. It is rendered with 201 status and
+// Location header pointing to the created transaction.
+type sendPaymentResponse struct {
+	Receipt entities.TransferReceipt
+}
+
+func (r sendPaymentResponse) StatusCode() int {
+	return http.StatusCreated
+}
+
+func (r sendPaymentResponse) Headers() http.Header {
+	return http.Header{"Location": []string{transactionLocation(r.Receipt.Transaction)}}
+}
+
+func (r sendPaymentResponse) MarshalJSON() ([]byte, error) {
+	return json.Marshal(map[string]interface{}{"receipt": transferReceiptElement(r.Receipt)})
+}
+
+// transferReceiptElement renders the receipt of a transfer along with its payments
+func transferReceiptElement(receipt entities.TransferReceipt) map[string]interface{} {
+	encoder := paymentsJSONEncoder{}
+	element := map[string]interface{}{
+		"transaction_id": receipt.Transaction.ID,
+		"created_at":     receipt.Transaction.CreatedAt,
+		"payments":       encoder.elements(receipt.Payments),
+		"sender_balance": receipt.SenderBalance,
+	}
+
+	if receipt.Fee.IsPositive() {
+		element["fee"] = receipt.Fee
+	}
+
+	return element
+}
+
+// sendPaymentBatchResponse is a structure which banking endpoint layer
+// uses to pass data upside down to transport layer on
+// POST /api/v1/payment-batches. It is rendered with 201 status when every payment
+// of the batch was booked and with 207 status otherwise. Every item carries
+// a status its payment would get if it was sent on its own.
+type sendPaymentBatchResponse struct {
+	Receipt entities.BatchReceipt
+}
+
+func (r sendPaymentBatchResponse) StatusCode() int {
+	if r.Receipt.Failed() > 0 {
+		return http.StatusMultiStatus
+	}
+	return http.StatusCreated
+}
+
+func (r sendPaymentBatchResponse) MarshalJSON() ([]byte, error) {
+	items := make([]map[string]interface{}, len(r.Receipt.Items))
+	for index, item := range r.Receipt.Items {
+		if item.Err != nil {
+			status, description := errorStatus(item.Err)
+			items[index] = map[string]interface{}{"index": index, "status": status, "error": description}
+			continue
+		}
+
+		items[index] = map[string]interface{}{"index": index, "status": http.StatusCreated, "receipt": transferReceiptElement(item.Receipt)}
+	}
+
+	failed := r.Receipt.Failed()
+	return json.Marshal(map[string]interface{}{
+		"batch": map[string]interface{}{
+			"mode":      r.Receipt.Mode,
+			"succeeded": len(r.Receipt.Items) - failed,
+			"failed":    failed,
+			"items":     items,
+		},
+	})
+}
+
+// postTransactionResponse is a structure which banking endpoint layer
+// uses to pass data upside down to transport layer on
+// POST /api/v1/transactions. It is rendered with 201 status and
+// Location header pointing to the created transaction.
+type postTransactionResponse struct {
+	Receipt entities.PostingReceipt
+}
+
+func (r postTransactionResponse) StatusCode() int {
+	return http.StatusCreated
+}
+
+func (r postTransactionResponse) Headers() http.Header {
+	return http.Header{"Location": []string{transactionLocation(r.Receipt.Transaction)}}
+}
+
+func (r postTransactionResponse) MarshalJSON() ([]byte, error) {
+	encoder := paymentsJSONEncoder{}
+	balances := make(map[string]decimal.Decimal)
+	for _, account := range r.Receipt.Accounts {
+		balances[account.Name] = account.Balance
+	}
+
+	return json.Marshal(map[string]interface{}{
+		"receipt": map[string]interface{}{
+			"transaction_id": r.Receipt.Transaction.ID,
+			"created_at":     r.Receipt.Transaction.CreatedAt,
+			"kind":           r.Receipt.Transaction.Kind,
+			"payments":       encoder.elements(r.Receipt.Payments),
+			"balances":       balances,
+		},
+	})
+}
+
+// previewPaymentResponse is a structure which banking endpoint layer
+// uses to pass data upside down to transport layer on
+// POST /api/v1/payments/preview
+type previewPaymentResponse struct {
+	Preview entities.PaymentPreview `json:"preview"`
+}
+
+// externalOperationResponse is a structure which banking endpoint layer
+// uses to pass data upside down to transport layer on
+// POST /api/v1/accounts/{name}/deposits and POST /api/v1/accounts/{name}/withdrawals.
+// Balance is the one the user account was left with.
+// It is rendered the same way as sendPaymentResponse.
+type externalOperationResponse struct {
+	Receipt entities.TransferReceipt
+	Balance decimal.Decimal
+}
+
+func (r externalOperationResponse) StatusCode() int {
+	return http.StatusCreated
+}
+
+func (r externalOperationResponse) Headers() http.Header {
+	return http.Header{"Location": []string{transactionLocation(r.Receipt.Transaction)}}
+}
+
+func (r externalOperationResponse) MarshalJSON() ([]byte, error) {
+	encoder := paymentsJSONEncoder{}
+	return json.Marshal(map[string]interface{}{
+		"receipt": map[string]interface{}{
+			"transaction_id":     r.Receipt.Transaction.ID,
+			"created_at":         r.Receipt.Transaction.CreatedAt,
+			"kind":               r.Receipt.Transaction.Kind,
+			"external_reference": r.Receipt.Transaction.ExternalReference,
+			"payments":           encoder.elements(r.Receipt.Payments),
+			"balance":            r.Balance,
+		},
+	})
+}
+
+// compensateTransactionResponse is a structure which banking endpoint layer
+// uses to pass data upside down to transport layer on
+// POST /api/v1/transactions/{id}/reverse and POST /api/v1/transactions/{id}/refund.
+// It is rendered with 201 status and Location header pointing to the created transaction.
+type compensateTransactionResponse struct {
+	Receipt entities.TransferReceipt
+}
+
+func (r compensateTransactionResponse) StatusCode() int {
+	return http.StatusCreated
+}
+
+func (r compensateTransactionResponse) Headers() http.Header {
+	return http.Header{"Location": []string{transactionLocation(r.Receipt.Transaction)}}
+}
+
+func (r compensateTransactionResponse) MarshalJSON() ([]byte, error) {
+	encoder := paymentsJSONEncoder{}
+	return json.Marshal(map[string]interface{}{
+		"receipt": map[string]interface{}{
+			"transaction_id":          r.Receipt.Transaction.ID,
+			"created_at":              r.Receipt.Transaction.CreatedAt,
+			"kind":                    r.Receipt.Transaction.Kind,
+			"original_transaction_id": r.Receipt.Transaction.OriginalID,
+			"payments":                encoder.elements(r.Receipt.Payments),
+		},
+	})
+}
+
+// authorizeResponse is a structure which banking endpoint layer
+// uses to pass data upside down to transport layer on
+// POST /api/v1/authorizations. It is rendered with 201 status and
+// Location header pointing to the created authorization.
+type authorizeResponse struct {
+	Hold entities.Hold
+}
+
+func (r authorizeResponse) StatusCode() int {
+	return http.StatusCreated
+}
+
+func (r authorizeResponse) Headers() http.Header {
+	return http.Header{"Location": []string{APIPrefix + "/authorizations/" + strconv.Itoa(r.Hold.ID)}}
+}
+
+func (r authorizeResponse) MarshalJSON() ([]byte, error) {
+	return json.Marshal(map[string]interface{}{"authorization": holdElement(r.Hold)})
+}
+
+// holdResponse is a structure which banking endpoint layer
+// uses to pass data upside down to transport layer on
+// GET /api/v1/authorizations/{id} and POST /api/v1/authorizations/{id}/void
+type holdResponse struct {
+	Hold entities.Hold
+}
+
+func (r holdResponse) MarshalJSON() ([]byte, error) {
+	return json.Marshal(map[string]interface{}{"authorization": holdElement(r.Hold)})
+}
+
+// holdElement renders the hold along with names of its accounts
+func holdElement(hold entities.Hold) map[string]interface{} {
+	element := map[string]interface{}{
+		"id":         hold.ID,
+		"account":    hold.Account.Name,
+		"to_account": hold.Counterparty.Name,
+		"amount":     hold.Amount,
+		"currency":   hold.Currency,
+		"status":     hold.Status,
+		"created_at": hold.CreatedAt,
+		"expires_at": hold.ExpiresAt,
+	}
+
+	if hold.TransactionID != 0 {
+		element["transaction_id"] = hold.TransactionID
+	}
+
+	return element
+}
+
+// createScheduledPaymentResponse is a structure which banking endpoint layer
+// uses to pass data upside down to transport layer on
+// POST /api/v1/scheduled-payments. It is rendered with 201 status and
+// Location header pointing to the created scheduled payment.
+type createScheduledPaymentResponse struct {
+	Payment entities.ScheduledPayment
+}
+
+func (r createScheduledPaymentResponse) StatusCode() int {
+	return http.StatusCreated
+}
+
+func (r createScheduledPaymentResponse) Headers() http.Header {
+	return http.Header{"Location": []string{APIPrefix + "/scheduled-payments/" + strconv.Itoa(r.Payment.ID)}}
+}
+
+func (r createScheduledPaymentResponse) MarshalJSON() ([]byte, error) {
+	return json.Marshal(map[string]interface{}{"scheduled_payment": scheduledPaymentElement(r.Payment)})
+}
+
+// scheduledPaymentResponse is a structure which banking endpoint layer
+// uses to pass data upside down to transport layer on
+// GET, PATCH and DELETE /api/v1/scheduled-payments/{id}
+type scheduledPaymentResponse struct {
+	Payment entities.ScheduledPayment
+}
+
+func (r scheduledPaymentResponse) MarshalJSON() ([]byte, error) {
+	return json.Marshal(map[string]interface{}{"scheduled_payment": scheduledPaymentElement(r.Payment)})
+}
+
+// getScheduledPaymentsResponse is a structure which banking endpoint layer
+// uses to pass data upside down to transport layer on
+// GET /api/v1/scheduled-payments
+type getScheduledPaymentsResponse struct {
+	Payments []entities.ScheduledPayment
+}
+
+func (r getScheduledPaymentsResponse) MarshalJSON() ([]byte, error) {
+	elements := make([]map[string]interface{}, 0, len(r.Payments))
+	for _, payment := range r.Payments {
+		elements = append(elements, scheduledPaymentElement(payment))
+	}
+	return json.Marshal(map[string]interface{}{"scheduled_payments": elements})
+}
+
+// scheduledPaymentElement renders the scheduled payment along with names of its accounts.
+// Lease of the payment is an internal detail of the scheduler and is not rendered.
+func scheduledPaymentElement(payment entities.ScheduledPayment) map[string]interface{} {
+	element := map[string]interface{}{
+		"id":          payment.ID,
+		"account":     payment.Account.Name,
+		"to_account":  payment.Counterparty.Name,
+		"amount":      payment.Amount,
+		"currency":    payment.Currency,
+		"frequency":   payment.Frequency,
+		"status":      payment.Status,
+		"start_at":    payment.StartAt,
+		"next_run_at": payment.NextRunAt,
+		"attempts":    payment.Attempts,
+		"created_at":  payment.CreatedAt,
+	}
+
+	if payment.Cron != "" {
+		element["cron"] = payment.Cron
+	}
+
+	if !payment.EndAt.IsZero() {
+		element["end_at"] = payment.EndAt
+	}
+
+	if !payment.RetryAt.IsZero() {
+		element["retry_at"] = payment.RetryAt
+	}
+
+	return element
+}
+
+// getScheduledRunsResponse is a structure which banking endpoint layer
+// uses to pass data upside down to transport layer on
+// GET /api/v1/scheduled-payments/{id}/runs
+type getScheduledRunsResponse struct {
+	Runs []entities.ScheduledRun `json:"runs"`
+}
+
+// getTransactionResponse is a structure which banking endpoint layer
+// uses to pass data upside down to transport layer on
+// GET /api/v1/transactions/{id}
+type getTransactionResponse struct {
+	Transaction entities.Transaction
+	Payments    []entities.Payment
+}
+
+func (r getTransactionResponse) MarshalJSON() ([]byte, error) {
+	encoder := paymentsJSONEncoder{}
+	transaction := map[string]interface{}{
+		"id":         r.Transaction.ID,
+		"created_at": r.Transaction.CreatedAt,
+		"kind":       r.Transaction.Kind,
+		"payments":   encoder.elements(r.Payments),
+	}
+
+	if r.Transaction.ExternalReference != "" {
+		transaction["external_reference"] = r.Transaction.ExternalReference
+	}
+
+	if r.Transaction.OriginalID != 0 {
+		transaction["original_transaction_id"] = r.Transaction.OriginalID
+	}
+
+	if r.Transaction.Fee.IsPositive() {
+		transaction["fee"] = r.Transaction.Fee
+	}
+
+	if r.Transaction.Description != "" {
+		transaction["description"] = r.Transaction.Description
+	}
+
+	if len(r.Transaction.Metadata) > 0 {
+		transaction["metadata"] = r.Transaction.Metadata
+	}
+
+	return json.Marshal(map[string]interface{}{"transaction": transaction})
+}
+
+// createAPIKeyResponse is a structure which banking endpoint layer
+// uses to pass data upside down to transport layer on
+// POST /api/v1/admin/api-keys. It is rendered with 201 status and
+// the only copy of the key secret along with the bearer token made of it.
+type createAPIKeyResponse struct {
+	Key entities.IssuedAPIKey
+}
+
+func (r createAPIKeyResponse) StatusCode() int {
+	return http.StatusCreated
+}
+
+func (r createAPIKeyResponse) MarshalJSON() ([]byte, error) {
+	element := apiKeyElement(r.Key.APIKey)
+	element["secret"] = r.Key.Secret
+	element["token"] = r.Key.Token()
+	return json.Marshal(map[string]interface{}{"api_key": element})
+}
+
+// getAPIKeysResponse is a structure which banking endpoint layer
+// uses to pass data upside down to transport layer on
+// GET /api/v1/admin/api-keys
+type getAPIKeysResponse struct {
+	Keys []entities.APIKey
+}
+
+func (r getAPIKeysResponse) MarshalJSON() ([]byte, error) {
+	elements := make([]map[string]interface{}, 0, len(r.Keys))
+	for _, key := range r.Keys {
+		elements = append(elements, apiKeyElement(key))
+	}
+	return json.Marshal(map[string]interface{}{"api_keys": elements})
+}
+
+// revokeAPIKeyResponse is a structure which banking endpoint layer
+// uses to pass data upside down to transport layer on
+// DELETE /api/v1/admin/api-keys/{key_id}
+type revokeAPIKeyResponse struct {
+	Key entities.APIKey
+}
+
+func (r revokeAPIKeyResponse) MarshalJSON() ([]byte, error) {
+	return json.Marshal(map[string]interface{}{"api_key": apiKeyElement(r.Key)})
+}
+
+// getAuditLogResponse is a structure which banking endpoint layer
+// uses to pass data upside down to transport layer on
+// GET /api/v1/admin/audit
+type getAuditLogResponse struct {
+	Entries    []entities.AuditEntry
+	NextCursor *string
+}
+
+func (r getAuditLogResponse) MarshalJSON() ([]byte, error) {
+	elements := make([]map[string]interface{}, 0, len(r.Entries))
+	for _, entry := range r.Entries {
+		element := map[string]interface{}{
+			"id":             entry.ID,
+			"subject":        entry.Subject,
+			"role":           entry.Role,
+			"action":         entry.Action,
+			"payload_hash":   entry.PayloadHash,
+			"outcome":        entry.Outcome,
+			"correlation_id": entry.CorrelationID,
+			"created_at":     entry.CreatedAt,
+			"prev_hash":      entry.PrevHash,
+			"hash":           entry.Hash,
+		}
+
+		if entry.Error != "" {
+			element["error"] = entry.Error
+		}
+
+		elements = append(elements, element)
+	}
+	return json.Marshal(map[string]interface{}{"entries": elements, "next_cursor": r.NextCursor})
+}
+
+// verifyAuditLogResponse is a structure which banking endpoint layer
+// uses to pass data upside down to transport layer on
+// GET /api/v1/admin/audit/verify
+type verifyAuditLogResponse struct {
+	Verification entities.AuditVerification `json:"verification"`
+}
+
+// apiKeyElement renders the API key. Hash of its secret is never rendered.
+func apiKeyElement(key entities.APIKey) map[string]interface{} {
+	element := map[string]interface{}{
+		"key_id":     key.KeyID,
+		"name":       key.Name,
+		"role":       key.Role,
+		"created_at": key.CreatedAt,
+	}
+
+	if key.OwnerID != "" {
+		element["owner_id"] = key.OwnerID
+	}
+
+	if key.IsRevoked() {
+		element["revoked_at"] = key.RevokedAt
+	}
+
+	return element
+}
+
+// transactionLocation returns an URL path of the transaction resource
+func transactionLocation(transaction entities.Transaction) string {
+	return APIPrefix + "/transactions/" + strconv.Itoa(transaction.ID)
+}
@@ -0,0 +1,95 @@
+package banking
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+	"github.com/shopspring/decimal"
+	"github.com/twonegatives/coinsph_challenge/pkg/entities"
+	"github.com/twonegatives/coinsph_challenge/pkg/storage"
+)
+
+// PreviewPayment quotes the fee of transferring 'amount' between 'from' and 'to' Accounts
+// without moving any money. It fails for the same reasons SendPayment would fail
+// with at the moment, though nothing stops balances from changing before the payment is sent.
+// Returns a PaymentPreview with the fee and the total amount 'from' would be charged on success.
+func (svc *Service) PreviewPayment(ctx context.Context, from entities.Account, to entities.Account, amount decimal.Decimal) (entities.PaymentPreview, error) {
+	if !amount.IsPositive() {
+		return entities.PaymentPreview{}, errAmountShouldBePositive
+	}
+
+	if from.Name == "" || to.Name == "" {
+		return entities.PaymentPreview{}, errNamesNotPresent
+	}
+
+	if from.Name == to.Name {
+		return entities.PaymentPreview{}, errSenderIsReceiver
+	}
+
+	from, err := svc.GetAccount(ctx, from.Name)
+	if err != nil {
+		return entities.PaymentPreview{}, err
+	}
+
+	to, err = svc.GetAccount(ctx, to.Name)
+	if err != nil {
+		return entities.PaymentPreview{}, err
+	}
+
+	if from.Type.IsHouse() || to.Type.IsHouse() {
+		return entities.PaymentPreview{}, errHouseAccountTransfer
+	}
+
+	if err := checkAccountsActive(from, to); err != nil {
+		return entities.PaymentPreview{}, err
+	}
+
+	if from.Currency != to.Currency {
+		return entities.PaymentPreview{}, errCurrencyMismatch
+	}
+
+	if err := svc.validateAmount(from.Currency, amount); err != nil {
+		return entities.PaymentPreview{}, err
+	}
+
+	fee, err := svc.transferFee(from.Currency, amount)
+	if err != nil {
+		return entities.PaymentPreview{}, err
+	}
+
+	total := amount.Add(fee)
+	if !from.CanSpend(total) {
+		return entities.PaymentPreview{}, errInsufficientFunds
+	}
+
+	return entities.PaymentPreview{Amount: amount, Fee: fee, Total: total, Currency: from.Currency}, nil
+}
+
+// transferFee returns the fee of transferring the amount rounded to the currency precision
+func (svc *Service) transferFee(code entities.Currency, amount decimal.Decimal) (decimal.Decimal, error) {
+	currency, ok := svc.currencies.Lookup(code)
+	if !ok {
+		return decimal.Decimal{}, errUnsupportedCurrency
+	}
+
+	return svc.feeSchedule.Fee(code, amount).Round(currency.Precision), nil
+}
+
+// bookFee stores a pair of payments moving the fee from the locked sender account
+// to FEE account of its currency. Nothing is booked for zero fee.
+// Returns the stored payments.
+func bookFee(ctx context.Context, txStorage storage.Storage, transaction entities.Transaction, from *entities.Account, fee decimal.Decimal) ([]entities.Payment, error) {
+	if !fee.IsPositive() {
+		return nil, nil
+	}
+
+	// FEE account is locked after the transfer sides rather than in their order.
+	// Transactions holding it never wait for other accounts, so no deadlock is possible.
+	feeAccount := entities.Account{Name: entities.FeeAccountName(from.Currency)}
+	if err := lockAccounts(ctx, txStorage, paymentSide{account: &feeAccount, label: "fee"}); err != nil {
+		return nil, err
+	}
+
+	payments, err := bookPayments(ctx, txStorage, transaction, from, &feeAccount, fee)
+	return payments, errors.Wrap(err, "can't book fee payments")
+}
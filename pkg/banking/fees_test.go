@@ -0,0 +1,257 @@
+package banking_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/twonegatives/coinsph_challenge/pkg/banking"
+	"github.com/twonegatives/coinsph_challenge/pkg/entities"
+	"github.com/twonegatives/coinsph_challenge/pkg/fees"
+	"github.com/twonegatives/coinsph_challenge/pkg/memstorage"
+	"github.com/twonegatives/coinsph_challenge/pkg/mocks"
+	pkgstorage "github.com/twonegatives/coinsph_challenge/pkg/storage"
+)
+
+func newFeeSchedule() *fees.Schedule {
+	return fees.NewSchedule(fees.Rule{
+		Currency: entities.USD,
+		Tiers:    []fees.Tier{{Flat: decimal.New(25, -2), Percent: decimal.New(1, 0)}},
+	})
+}
+
+func TestBankingSvcSendPaymentWithFees(t *testing.T) {
+	sender := entities.Account{Name: "sender", Balance: decimal.New(20, 0), Currency: entities.USD}
+	receiver := entities.Account{Name: "receiver", Balance: decimal.New(0, 0), Currency: entities.USD}
+	feeAccount := entities.Account{Name: "FEE_USD", Type: entities.FeeAccount, Balance: decimal.New(0, 0), Currency: entities.USD}
+
+	t.Run("charges fee on top of the amount", func(t *testing.T) {
+		mCtrl := gomock.NewController(t)
+		defer mCtrl.Finish()
+		storage := mocks.NewMockStorage(mCtrl)
+
+		storage.EXPECT().BeginTx(ctx, nil).Return(storage, nil)
+		storage.EXPECT().RollbackTx(ctx)
+		expectAccountsLocked(storage, sender, receiver)
+		storage.EXPECT().CreateTransaction(ctx, gomock.Any()).DoAndReturn(
+			func(_ context.Context, transaction entities.Transaction) (entities.Transaction, error) {
+				assert.Equal(t, "0.35", transaction.Fee.String())
+				transaction.ID = 12
+				return transaction, nil
+			},
+		)
+		expectAccountsLocked(storage, feeAccount)
+		storage.EXPECT().SendPayment(ctx, gomock.Any()).Times(4)
+		storage.EXPECT().SetAccountBalance(ctx, gomock.Any()).Times(4)
+		storage.EXPECT().CommitTx(ctx)
+
+		svc := banking.NewService(storage, banking.WithFeeSchedule(newFeeSchedule()))
+		receipt, err := svc.SendPayment(ctx, entities.Account{Name: "sender"}, entities.Account{Name: "receiver"}, decimal.New(10, 0), entities.PaymentDetails{})
+		require.NoError(t, err)
+
+		assert.Equal(t, "0.35", receipt.Fee.String())
+		assert.Equal(t, "9.65", receipt.SenderBalance.String())
+		assert.Equal(t, "10", receipt.ReceiverBalance.String())
+		require.Len(t, receipt.Payments, 4)
+		assert.Equal(t, "FEE_USD", receipt.Payments[2].Counterparty.Name)
+		assert.Equal(t, "0.35", receipt.Payments[2].Amount.String())
+	})
+
+	t.Run("rejects transfer which balance can't cover along with the fee", func(t *testing.T) {
+		mCtrl := gomock.NewController(t)
+		defer mCtrl.Finish()
+		storage := mocks.NewMockStorage(mCtrl)
+
+		storage.EXPECT().BeginTx(ctx, nil).Return(storage, nil)
+		storage.EXPECT().RollbackTx(ctx)
+		expectAccountsLocked(storage, sender, receiver)
+
+		svc := banking.NewService(storage, banking.WithFeeSchedule(newFeeSchedule()))
+		_, err := svc.SendPayment(ctx, entities.Account{Name: "sender"}, entities.Account{Name: "receiver"}, decimal.New(1990, -2), entities.PaymentDetails{})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "sender account has insufficient funds")
+	})
+}
+
+func TestBankingSvcCaptureHoldWithFees(t *testing.T) {
+	setup := func(t *testing.T, deposit int64) *banking.Service {
+		svc := banking.NewService(memstorage.NewMemStorage(), banking.WithFeeSchedule(newFeeSchedule()))
+		for _, name := range []string{"barry", "shop"} {
+			_, err := svc.CreateAccount(ctx, name, entities.USD, entities.AccountProfile{})
+			require.NoError(t, err)
+		}
+		_, err := svc.Deposit(ctx, "barry", decimal.New(deposit, 0), "wire-1")
+		require.NoError(t, err)
+		return svc
+	}
+
+	t.Run("charges fee of the captured amount", func(t *testing.T) {
+		svc := setup(t, 20)
+		hold, err := svc.Authorize(ctx, entities.Account{Name: "barry"}, entities.Account{Name: "shop"}, decimal.New(10, 0))
+		require.NoError(t, err)
+
+		receipt, err := svc.CaptureHold(ctx, hold.ID, decimal.New(0, 0))
+		require.NoError(t, err)
+		assert.Equal(t, "0.35", receipt.Fee.String())
+		assert.Equal(t, "0.35", receipt.Transaction.Fee.String())
+		assert.Equal(t, "9.65", receipt.SenderBalance.String())
+		require.Len(t, receipt.Payments, 4)
+		assert.Equal(t, "FEE_USD", receipt.Payments[2].Counterparty.Name)
+
+		feeAccount, err := svc.GetAccount(ctx, "FEE_USD")
+		require.NoError(t, err)
+		assert.Equal(t, "0.35", feeAccount.Balance.String())
+
+		barry, err := svc.GetAccount(ctx, "barry")
+		require.NoError(t, err)
+		assert.True(t, barry.Held.IsZero())
+	})
+
+	t.Run("rejects holds which balance can't cover along with the fee", func(t *testing.T) {
+		svc := setup(t, 10)
+		_, err := svc.Authorize(ctx, entities.Account{Name: "barry"}, entities.Account{Name: "shop"}, decimal.New(10, 0))
+		require.Error(t, err)
+		assert.Equal(t, "sender account has insufficient funds", err.Error())
+	})
+}
+
+func TestBankingSvcSendFXPaymentWithFees(t *testing.T) {
+	john := entities.Account{Name: "john", Balance: decimal.New(20, 0), Currency: entities.USD}
+	juan := entities.Account{Name: "juan", Balance: decimal.New(0, 0), Currency: entities.PHP}
+	fxUSD := entities.Account{Name: "FX_USD", Balance: decimal.New(0, 0), Currency: entities.USD}
+	fxPHP := entities.Account{Name: "FX_PHP", Balance: decimal.New(0, 0), Currency: entities.PHP}
+	feeAccount := entities.Account{Name: "FEE_USD", Type: entities.FeeAccount, Balance: decimal.New(0, 0), Currency: entities.USD}
+
+	expectQuote := func(storage *mocks.MockStorage, amount decimal.Decimal) {
+		storage.EXPECT().GetQuoteForUpdate(ctx, gomock.Any()).DoAndReturn(
+			func(_ context.Context, quote *entities.Quote) error {
+				*quote = entities.Quote{
+					ID:           7,
+					FromCurrency: entities.USD,
+					ToCurrency:   entities.PHP,
+					Rate:         decimal.New(52, 0),
+					SourceAmount: amount,
+					TargetAmount: amount.Mul(decimal.New(52, 0)),
+					ExpiresAt:    time.Now().Add(time.Minute),
+				}
+				return nil
+			},
+		)
+	}
+
+	t.Run("charges fee in sender currency", func(t *testing.T) {
+		mCtrl := gomock.NewController(t)
+		defer mCtrl.Finish()
+		storage := mocks.NewMockStorage(mCtrl)
+
+		storage.EXPECT().BeginTx(ctx, nil).Return(storage, nil)
+		storage.EXPECT().RollbackTx(ctx)
+		expectQuote(storage, decimal.New(10, 0))
+		expectAccountsLocked(storage, john, juan, fxUSD, fxPHP)
+		storage.EXPECT().CreateTransaction(ctx, gomock.Any()).DoAndReturn(
+			func(_ context.Context, transaction entities.Transaction) (entities.Transaction, error) {
+				assert.Equal(t, "0.35", transaction.Fee.String())
+				transaction.ID = 12
+				return transaction, nil
+			},
+		)
+		expectAccountsLocked(storage, feeAccount)
+		storage.EXPECT().SendPayment(ctx, gomock.Any()).Times(6)
+		storage.EXPECT().SetAccountBalance(ctx, gomock.Any()).Times(6)
+		storage.EXPECT().SetQuoteTransaction(ctx, gomock.Any())
+		storage.EXPECT().CommitTx(ctx)
+
+		svc := banking.NewService(storage, banking.WithFeeSchedule(newFeeSchedule()))
+		receipt, err := svc.SendFXPayment(ctx, entities.Account{Name: "john"}, entities.Account{Name: "juan"}, decimal.New(10, 0), 7, entities.PaymentDetails{})
+		require.NoError(t, err)
+
+		assert.Equal(t, "0.35", receipt.Fee.String())
+		assert.Equal(t, "9.65", receipt.SenderBalance.String())
+		assert.Equal(t, "520", receipt.ReceiverBalance.String())
+		require.Len(t, receipt.Payments, 6)
+		assert.Equal(t, "FEE_USD", receipt.Payments[4].Counterparty.Name)
+		assert.Equal(t, "0.35", receipt.Payments[4].Amount.String())
+	})
+
+	t.Run("rejects transfer which balance can't cover along with the fee", func(t *testing.T) {
+		mCtrl := gomock.NewController(t)
+		defer mCtrl.Finish()
+		storage := mocks.NewMockStorage(mCtrl)
+
+		storage.EXPECT().BeginTx(ctx, nil).Return(storage, nil)
+		storage.EXPECT().RollbackTx(ctx)
+		expectQuote(storage, decimal.New(1990, -2))
+		expectAccountsLocked(storage, john, juan, fxUSD, fxPHP)
+
+		svc := banking.NewService(storage, banking.WithFeeSchedule(newFeeSchedule()))
+		_, err := svc.SendFXPayment(ctx, entities.Account{Name: "john"}, entities.Account{Name: "juan"}, decimal.New(1990, -2), 7, entities.PaymentDetails{})
+		require.Error(t, err)
+		assert.Equal(t, "sender account has insufficient funds", err.Error())
+	})
+}
+
+func TestBankingSvcPreviewPayment(t *testing.T) {
+	expectAccounts := func(storage *mocks.MockStorage, accounts ...entities.Account) {
+		for _, account := range accounts {
+			storage.EXPECT().GetAccount(ctx, account.Name).Return(account, nil)
+		}
+	}
+
+	sender := entities.Account{Name: "sender", Balance: decimal.New(20, 0), Currency: entities.USD}
+	receiver := entities.Account{Name: "receiver", Balance: decimal.New(0, 0), Currency: entities.USD}
+
+	t.Run("quotes fee rounded to the currency precision", func(t *testing.T) {
+		mCtrl := gomock.NewController(t)
+		defer mCtrl.Finish()
+		storage := mocks.NewMockStorage(mCtrl)
+		expectAccounts(storage, sender, receiver)
+
+		svc := banking.NewService(storage, banking.WithFeeSchedule(newFeeSchedule()))
+		preview, err := svc.PreviewPayment(ctx, entities.Account{Name: "sender"}, entities.Account{Name: "receiver"}, decimal.New(1055, -2))
+		require.NoError(t, err)
+
+		assert.Equal(t, "10.55", preview.Amount.String())
+		assert.Equal(t, "0.36", preview.Fee.String())
+		assert.Equal(t, "10.91", preview.Total.String())
+		assert.Equal(t, entities.USD, preview.Currency)
+	})
+
+	t.Run("quotes zero fee without schedule", func(t *testing.T) {
+		mCtrl := gomock.NewController(t)
+		defer mCtrl.Finish()
+		storage := mocks.NewMockStorage(mCtrl)
+		expectAccounts(storage, sender, receiver)
+
+		preview, err := banking.NewService(storage).PreviewPayment(ctx, entities.Account{Name: "sender"}, entities.Account{Name: "receiver"}, decimal.New(20, 0))
+		require.NoError(t, err)
+		assert.True(t, preview.Fee.IsZero())
+		assert.Equal(t, "20", preview.Total.String())
+	})
+
+	t.Run("fails the way payment would fail", func(t *testing.T) {
+		mCtrl := gomock.NewController(t)
+		defer mCtrl.Finish()
+		storage := mocks.NewMockStorage(mCtrl)
+		expectAccounts(storage, sender, receiver)
+
+		svc := banking.NewService(storage, banking.WithFeeSchedule(newFeeSchedule()))
+		_, err := svc.PreviewPayment(ctx, entities.Account{Name: "sender"}, entities.Account{Name: "receiver"}, decimal.New(20, 0))
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "sender account has insufficient funds")
+	})
+
+	t.Run("fails for unknown accounts", func(t *testing.T) {
+		mCtrl := gomock.NewController(t)
+		defer mCtrl.Finish()
+		storage := mocks.NewMockStorage(mCtrl)
+		storage.EXPECT().GetAccount(ctx, "nobody").Return(entities.Account{}, pkgstorage.ErrNotFound)
+
+		_, err := banking.NewService(storage).PreviewPayment(ctx, entities.Account{Name: "nobody"}, entities.Account{Name: "receiver"}, decimal.New(1, 0))
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "account not found")
+	})
+}
@@ -0,0 +1,218 @@
+package banking
+
+import (
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/shopspring/decimal"
+	"github.com/twonegatives/coinsph_challenge/pkg/entities"
+	"github.com/twonegatives/coinsph_challenge/pkg/fx"
+	"github.com/twonegatives/coinsph_challenge/pkg/storage"
+)
+
+const defaultQuoteTTL = 30 * time.Second
+
+var (
+	errSameCurrencyQuote     = errors.New("quote currencies should differ")
+	errRateUnavailable       = errors.New("exchange rate is not available for requested currencies")
+	errAmountTooSmall        = errors.New("amount is too small to be exchanged")
+	errQuoteNotFound         = errors.New("quote not found")
+	errQuoteExpired          = errors.New("quote has expired")
+	errQuoteAlreadyUsed      = errors.New("quote has already been used")
+	errQuoteAmountMismatch   = errors.New("payment amount should be equal to quoted amount")
+	errQuoteCurrencyMismatch = errors.New("sender and receiver currencies should match quoted ones")
+)
+
+// CreateQuote offers an exchange rate for converting 'amount' of 'from' currency into 'to' currency.
+// Quoted target amount is rounded down to the precision of 'to' currency.
+// The quote expires after a configured period of time and may be used by a single payment.
+func (svc *Service) CreateQuote(ctx context.Context, from entities.Currency, to entities.Currency, amount decimal.Decimal) (entities.Quote, error) {
+	if !amount.IsPositive() {
+		return entities.Quote{}, errAmountShouldBePositive
+	}
+
+	if from == to {
+		return entities.Quote{}, errSameCurrencyQuote
+	}
+
+	if err := svc.validateAmount(from, amount); err != nil {
+		return entities.Quote{}, err
+	}
+
+	target, ok := svc.currencies.Lookup(to)
+	if !ok {
+		return entities.Quote{}, errUnsupportedCurrency
+	}
+
+	rate, err := svc.rates.Rate(ctx, from, to)
+	if errors.Cause(err) == fx.ErrRateUnavailable {
+		return entities.Quote{}, errRateUnavailable
+	}
+	if err != nil {
+		return entities.Quote{}, errors.Wrap(err, "failed to obtain exchange rate")
+	}
+
+	targetAmount := amount.Mul(rate).Truncate(target.Precision)
+	if !targetAmount.IsPositive() {
+		return entities.Quote{}, errAmountTooSmall
+	}
+
+	quote := entities.Quote{
+		FromCurrency: from,
+		ToCurrency:   to,
+		Rate:         rate,
+		SourceAmount: amount,
+		TargetAmount: targetAmount,
+		ExpiresAt:    svc.now().Add(svc.quoteTTL),
+	}
+
+	err = svc.inTx(ctx, func(txStorage storage.Storage) (err error) {
+		quote, err = txStorage.CreateQuote(ctx, quote)
+		return errors.Wrap(err, "failed to create new quote in database")
+	})
+	return quote, err
+}
+
+// SendFXPayment attempts to transfer 'amount' of sender currency and deliver
+// the quoted amount of receiver currency. The transfer is booked as a single
+// transaction consisting of two pairs of payments: sender pays to FX account
+// of his currency, and FX account of receiver currency pays to receiver.
+// This way every currency of the transaction stays balanced. Sender is charged
+// the transfer fee of 'amount' in his currency on top of it, the same way SendPayment charges it.
+// Returns error in the following cases:
+// - quote does not exist, has expired or has already been used
+// - 'amount' or accounts currencies differ from the quoted ones
+// - any of the reasons SendPayment fails with
+// Details are stored along with the transaction the same way SendPayment stores them.
+// Returns a TransferReceipt listing all four payments, followed by the fee ones if any, on success.
+func (svc *Service) SendFXPayment(ctx context.Context, from entities.Account, to entities.Account, amount decimal.Decimal, quoteID int, details entities.PaymentDetails) (entities.TransferReceipt, error) {
+	if !amount.IsPositive() {
+		return entities.TransferReceipt{}, errAmountShouldBePositive
+	}
+
+	if from.Name == "" || to.Name == "" {
+		return entities.TransferReceipt{}, errNamesNotPresent
+	}
+
+	if from.Name == to.Name {
+		return entities.TransferReceipt{}, errSenderIsReceiver
+	}
+
+	if err := validatePaymentDetails(details); err != nil {
+		return entities.TransferReceipt{}, err
+	}
+
+	txStorage, err := svc.store.BeginTx(ctx, nil)
+	defer txStorage.RollbackTx(ctx)
+
+	if err != nil {
+		return entities.TransferReceipt{}, errors.Wrap(err, "can't open transaction")
+	}
+
+	// quote is locked prior to any account, so that concurrent
+	// payments could not spend it twice
+	quote := entities.Quote{ID: quoteID}
+	err = txStorage.GetQuoteForUpdate(ctx, &quote)
+	if errors.Cause(err) == storage.ErrNotFound {
+		return entities.TransferReceipt{}, errQuoteNotFound
+	}
+	if err != nil {
+		return entities.TransferReceipt{}, errors.Wrap(err, "can't obtain quote")
+	}
+
+	if quote.IsUsed() {
+		return entities.TransferReceipt{}, errQuoteAlreadyUsed
+	}
+
+	if svc.now().After(quote.ExpiresAt) {
+		return entities.TransferReceipt{}, errQuoteExpired
+	}
+
+	if !quote.SourceAmount.Equal(amount) {
+		return entities.TransferReceipt{}, errQuoteAmountMismatch
+	}
+
+	sourceFX := entities.Account{Name: entities.FXAccountName(quote.FromCurrency)}
+	targetFX := entities.Account{Name: entities.FXAccountName(quote.ToCurrency)}
+
+	err = lockAccounts(ctx, txStorage,
+		paymentSide{account: &from, label: "sender"},
+		paymentSide{account: &to, label: "receiver"},
+		paymentSide{account: &sourceFX, label: "sender currency FX"},
+		paymentSide{account: &targetFX, label: "receiver currency FX"},
+	)
+	if err != nil {
+		return entities.TransferReceipt{}, err
+	}
+
+	if from.Type.IsHouse() || to.Type.IsHouse() {
+		return entities.TransferReceipt{}, errHouseAccountTransfer
+	}
+
+	if err := checkOwnership(ctx, from); err != nil {
+		return entities.TransferReceipt{}, err
+	}
+
+	if err := checkAccountsActive(from, to); err != nil {
+		return entities.TransferReceipt{}, err
+	}
+
+	if from.Currency != quote.FromCurrency || to.Currency != quote.ToCurrency {
+		return entities.TransferReceipt{}, errQuoteCurrencyMismatch
+	}
+
+	fee, err := svc.transferFee(from.Currency, amount)
+	if err != nil {
+		return entities.TransferReceipt{}, err
+	}
+
+	if !from.CanSpend(amount.Add(fee)) {
+		return entities.TransferReceipt{}, errInsufficientFunds
+	}
+
+	transaction := withDetails(entities.Transaction{Kind: entities.TransferTransaction}, from, details)
+	if fee.IsPositive() {
+		transaction.Fee = fee
+	}
+
+	transaction, err = createTransaction(ctx, txStorage, transaction)
+	if errors.Cause(err) == storage.ErrAlreadyExists {
+		return entities.TransferReceipt{}, errExternalReferenceUsed
+	}
+	if err != nil {
+		return entities.TransferReceipt{}, errors.Wrap(err, "can't insert new transaction")
+	}
+
+	sourcePayments, err := bookPayments(ctx, txStorage, transaction, &from, &sourceFX, quote.SourceAmount)
+	if err != nil {
+		return entities.TransferReceipt{}, errors.Wrap(err, "can't book sender currency payments")
+	}
+
+	targetPayments, err := bookPayments(ctx, txStorage, transaction, &targetFX, &to, quote.TargetAmount)
+	if err != nil {
+		return entities.TransferReceipt{}, errors.Wrap(err, "can't book receiver currency payments")
+	}
+
+	feePayments, err := bookFee(ctx, txStorage, transaction, &from, fee)
+	if err != nil {
+		return entities.TransferReceipt{}, err
+	}
+
+	quote.TransactionID = transaction.ID
+	if err := txStorage.SetQuoteTransaction(ctx, quote); err != nil {
+		return entities.TransferReceipt{}, errors.Wrap(err, "can't mark quote as used")
+	}
+
+	if err := svc.commitTx(ctx, txStorage); err != nil {
+		return entities.TransferReceipt{}, errors.Wrap(err, "transaction commit failed")
+	}
+
+	return entities.TransferReceipt{
+		Transaction:     transaction,
+		Payments:        append(append(sourcePayments, targetPayments...), feePayments...),
+		Fee:             transaction.Fee,
+		SenderBalance:   from.Balance,
+		ReceiverBalance: to.Balance,
+	}, nil
+}
@@ -0,0 +1,249 @@
+package banking_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/twonegatives/coinsph_challenge/pkg/banking"
+	"github.com/twonegatives/coinsph_challenge/pkg/entities"
+	"github.com/twonegatives/coinsph_challenge/pkg/fx"
+	"github.com/twonegatives/coinsph_challenge/pkg/mocks"
+	"github.com/twonegatives/coinsph_challenge/pkg/storage"
+)
+
+// expectAccountsLocked sets up storage to fill in locked accounts from the given ones by name
+func expectAccountsLocked(storage *mocks.MockStorage, accounts ...entities.Account) {
+	byName := make(map[string]entities.Account, len(accounts))
+	for _, account := range accounts {
+		byName[account.Name] = account
+	}
+
+	storage.EXPECT().GetAccountForUpdate(gomock.Any(), gomock.Any()).Times(len(accounts)).DoAndReturn(
+		func(_ context.Context, account *entities.Account) error {
+			*account = byName[account.Name]
+			return nil
+		},
+	)
+}
+
+func newRateProvider() *fx.StaticRateProvider {
+	rates := fx.NewStaticRateProvider()
+	rates.SetRate(entities.PHP, entities.USD, decimal.New(191, -4))
+	return rates
+}
+
+func TestBankingSvcCreateQuote(t *testing.T) {
+	t.Run("returns quote with amount rounded down", func(t *testing.T) {
+		mCtrl := gomock.NewController(t)
+		defer mCtrl.Finish()
+		storage := mocks.NewMockStorage(mCtrl)
+
+		var stored entities.Quote
+		storage.EXPECT().BeginTx(ctx, nil).Return(storage, nil)
+		storage.EXPECT().CommitTx(ctx).Return(nil)
+		storage.EXPECT().RollbackTx(ctx).Return(nil)
+		storage.EXPECT().CreateQuote(ctx, gomock.Any()).DoAndReturn(
+			func(_ context.Context, quote entities.Quote) (entities.Quote, error) {
+				stored = quote
+				quote.ID = 15
+				return quote, nil
+			},
+		)
+
+		svc := banking.NewService(storage, banking.WithRateProvider(newRateProvider()), banking.WithQuoteTTL(time.Minute))
+		quote, err := svc.CreateQuote(ctx, entities.PHP, entities.USD, decimal.New(1001, 0))
+		require.NoError(t, err)
+
+		assert.Equal(t, 15, quote.ID)
+		assert.Equal(t, decimal.New(1911, -2), stored.TargetAmount)
+		assert.Equal(t, decimal.New(1001, 0), stored.SourceAmount)
+		assert.Equal(t, decimal.New(191, -4), stored.Rate)
+		assert.WithinDuration(t, time.Now().Add(time.Minute), stored.ExpiresAt, 5*time.Second)
+	})
+
+	t.Run("catches unavailable rates", func(t *testing.T) {
+		mCtrl := gomock.NewController(t)
+		defer mCtrl.Finish()
+		storage := mocks.NewMockStorage(mCtrl)
+
+		svc := banking.NewService(storage, banking.WithRateProvider(newRateProvider()))
+		_, err := svc.CreateQuote(ctx, entities.USD, entities.PHP, decimal.New(10, 0))
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "exchange rate is not available for requested currencies")
+	})
+
+	t.Run("catches amounts which become zero after exchange", func(t *testing.T) {
+		mCtrl := gomock.NewController(t)
+		defer mCtrl.Finish()
+		storage := mocks.NewMockStorage(mCtrl)
+
+		svc := banking.NewService(storage, banking.WithRateProvider(newRateProvider()))
+		_, err := svc.CreateQuote(ctx, entities.PHP, entities.USD, decimal.New(1, -1))
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "amount is too small to be exchanged")
+	})
+
+	t.Run("catches same currency quotes", func(t *testing.T) {
+		mCtrl := gomock.NewController(t)
+		defer mCtrl.Finish()
+		storage := mocks.NewMockStorage(mCtrl)
+
+		_, err := banking.NewService(storage).CreateQuote(ctx, entities.USD, entities.USD, decimal.New(10, 0))
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "quote currencies should differ")
+	})
+}
+
+func TestBankingSvcSendFXPayment(t *testing.T) {
+	juan := entities.Account{Name: "juan", Balance: decimal.New(5000, 0), Currency: entities.PHP}
+	john := entities.Account{Name: "john", Balance: decimal.New(10, 0), Currency: entities.USD}
+	fxPHP := entities.Account{Name: "FX_PHP", Balance: decimal.New(0, 0), Currency: entities.PHP}
+	fxUSD := entities.Account{Name: "FX_USD", Balance: decimal.New(0, 0), Currency: entities.USD}
+
+	validQuote := func() entities.Quote {
+		return entities.Quote{
+			ID:           7,
+			FromCurrency: entities.PHP,
+			ToCurrency:   entities.USD,
+			Rate:         decimal.New(191, -4),
+			SourceAmount: decimal.New(1000, 0),
+			TargetAmount: decimal.New(1910, -2),
+			ExpiresAt:    time.Now().Add(time.Minute),
+		}
+	}
+
+	expectQuote := func(storage *mocks.MockStorage, quote entities.Quote) {
+		storage.EXPECT().GetQuoteForUpdate(gomock.Any(), &entities.Quote{ID: quote.ID}).DoAndReturn(
+			func(_ context.Context, q *entities.Quote) error {
+				*q = quote
+				return nil
+			},
+		)
+	}
+
+	t.Run("books balanced payments per currency", func(t *testing.T) {
+		mCtrl := gomock.NewController(t)
+		defer mCtrl.Finish()
+		storage := mocks.NewMockStorage(mCtrl)
+
+		var payments []entities.Payment
+		balances := make(map[string]decimal.Decimal)
+
+		quote := validQuote()
+		storage.EXPECT().BeginTx(gomock.Any(), nil).Return(storage, nil)
+		expectQuote(storage, quote)
+		expectAccountsLocked(storage, juan, john, fxPHP, fxUSD)
+		storage.EXPECT().CreateTransaction(gomock.Any(), entities.Transaction{Kind: entities.TransferTransaction}).Return(entities.Transaction{ID: 42}, nil)
+		storage.EXPECT().SendPayment(gomock.Any(), gomock.Any()).Times(4).DoAndReturn(
+			func(_ context.Context, payment entities.Payment) error {
+				payments = append(payments, payment)
+				return nil
+			},
+		)
+		storage.EXPECT().SetAccountBalance(gomock.Any(), gomock.Any()).Times(4).DoAndReturn(
+			func(_ context.Context, account entities.Account) error {
+				balances[account.Name] = account.Balance
+				return nil
+			},
+		)
+		usedQuote := quote
+		usedQuote.TransactionID = 42
+		storage.EXPECT().SetQuoteTransaction(gomock.Any(), usedQuote).Return(nil)
+		storage.EXPECT().CommitTx(gomock.Any()).Return(nil)
+		storage.EXPECT().RollbackTx(gomock.Any()).Return(nil)
+
+		receipt, err := banking.NewService(storage).SendFXPayment(ctx, entities.Account{Name: "juan"}, entities.Account{Name: "john"}, decimal.New(1000, 0), 7, entities.PaymentDetails{})
+		require.NoError(t, err)
+		assert.Equal(t, 42, receipt.Transaction.ID)
+		assert.Equal(t, payments, receipt.Payments)
+		assert.Equal(t, decimal.New(4000, 0).String(), receipt.SenderBalance.String())
+
+		require.Len(t, payments, 4)
+		assert.Equal(t, "juan", payments[0].Account.Name)
+		assert.Equal(t, "FX_PHP", payments[0].Counterparty.Name)
+		assert.Equal(t, entities.PHP, payments[0].Currency)
+		assert.Equal(t, "john", payments[3].Account.Name)
+		assert.Equal(t, "FX_USD", payments[3].Counterparty.Name)
+		assert.Equal(t, entities.USD, payments[3].Currency)
+		assert.Equal(t, decimal.New(1910, -2), payments[3].Amount)
+
+		assert.Equal(t, decimal.New(4000, 0).String(), balances["juan"].String())
+		assert.Equal(t, decimal.New(1000, 0).String(), balances["FX_PHP"].String())
+		assert.Equal(t, decimal.New(-1910, -2).String(), balances["FX_USD"].String())
+		assert.Equal(t, decimal.New(2910, -2).String(), balances["john"].String())
+	})
+
+	rejectedQuotes := []struct {
+		title   string
+		quote   func() entities.Quote
+		message string
+	}{
+		{
+			title:   "catches expired quotes",
+			quote:   func() entities.Quote { q := validQuote(); q.ExpiresAt = time.Now().Add(-time.Second); return q },
+			message: "quote has expired",
+		},
+		{
+			title:   "catches used quotes",
+			quote:   func() entities.Quote { q := validQuote(); q.TransactionID = 3; return q },
+			message: "quote has already been used",
+		},
+		{
+			title:   "catches amounts differing from quoted",
+			quote:   func() entities.Quote { q := validQuote(); q.SourceAmount = decimal.New(999, 0); return q },
+			message: "payment amount should be equal to quoted amount",
+		},
+	}
+
+	for _, tt := range rejectedQuotes {
+		t.Run(tt.title, func(t *testing.T) {
+			mCtrl := gomock.NewController(t)
+			defer mCtrl.Finish()
+			storage := mocks.NewMockStorage(mCtrl)
+
+			storage.EXPECT().BeginTx(gomock.Any(), nil).Return(storage, nil)
+			expectQuote(storage, tt.quote())
+			storage.EXPECT().RollbackTx(gomock.Any()).Return(nil)
+
+			_, err := banking.NewService(storage).SendFXPayment(ctx, juan, john, decimal.New(1000, 0), 7, entities.PaymentDetails{})
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.message)
+		})
+	}
+
+	t.Run("catches accounts currencies differing from quoted", func(t *testing.T) {
+		mCtrl := gomock.NewController(t)
+		defer mCtrl.Finish()
+		storage := mocks.NewMockStorage(mCtrl)
+
+		euro := entities.Account{Name: "pierre", Currency: entities.EUR}
+
+		storage.EXPECT().BeginTx(gomock.Any(), nil).Return(storage, nil)
+		expectQuote(storage, validQuote())
+		expectAccountsLocked(storage, juan, euro, fxPHP, fxUSD)
+		storage.EXPECT().RollbackTx(gomock.Any()).Return(nil)
+
+		_, err := banking.NewService(storage).SendFXPayment(ctx, juan, euro, decimal.New(1000, 0), 7, entities.PaymentDetails{})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "sender and receiver currencies should match quoted ones")
+	})
+
+	t.Run("catches unknown quotes", func(t *testing.T) {
+		mCtrl := gomock.NewController(t)
+		defer mCtrl.Finish()
+		mockStorage := mocks.NewMockStorage(mCtrl)
+
+		mockStorage.EXPECT().BeginTx(gomock.Any(), nil).Return(mockStorage, nil)
+		mockStorage.EXPECT().GetQuoteForUpdate(gomock.Any(), gomock.Any()).Return(storage.ErrNotFound)
+		mockStorage.EXPECT().RollbackTx(gomock.Any()).Return(nil)
+
+		_, err := banking.NewService(mockStorage).SendFXPayment(ctx, juan, john, decimal.New(1000, 0), 7, entities.PaymentDetails{})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "quote not found")
+	})
+}
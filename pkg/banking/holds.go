@@ -0,0 +1,315 @@
+package banking
+
+import (
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/shopspring/decimal"
+	"github.com/twonegatives/coinsph_challenge/pkg/entities"
+	"github.com/twonegatives/coinsph_challenge/pkg/storage"
+)
+
+const (
+	defaultHoldTTL = 7 * 24 * time.Hour
+
+	// expiredHoldsBatch limits the number of holds released by a single ExpireHolds call
+	expiredHoldsBatch = 100
+)
+
+var (
+	errHoldNotFound       = errors.New("authorization not found")
+	errHoldNotPending     = errors.New("authorization has already been captured, voided or expired")
+	errHoldExpired        = errors.New("authorization has expired")
+	errCaptureExceedsHold = errors.New("captured amount can't exceed the authorized one")
+	errAccountHasHolds    = errors.New("account should have no pending authorizations to close it")
+)
+
+// Authorize places a hold of 'amount' on 'from' account in favour of 'to' account.
+// Held amount stays on 'from' ledger balance, but can't be spent until the hold
+// is captured, voided or expired. Validation rules are the same as SendPayment ones,
+// so the sender should afford the transfer fee on top of the amount, though the fee
+// is not reserved: it is charged on capture. Returns the pending Hold on success.
+func (svc *Service) Authorize(ctx context.Context, from entities.Account, to entities.Account, amount decimal.Decimal) (entities.Hold, error) {
+	if !amount.IsPositive() {
+		return entities.Hold{}, errAmountShouldBePositive
+	}
+
+	if from.Name == "" || to.Name == "" {
+		return entities.Hold{}, errNamesNotPresent
+	}
+
+	if from.Name == to.Name {
+		return entities.Hold{}, errSenderIsReceiver
+	}
+
+	txStorage, err := svc.store.BeginTx(ctx, nil)
+	if err != nil {
+		return entities.Hold{}, errors.Wrap(err, "can't open transaction")
+	}
+
+	defer txStorage.RollbackTx(ctx)
+
+	if err := lockAccounts(ctx, txStorage, paymentSide{account: &from, label: "sender"}, paymentSide{account: &to, label: "receiver"}); err != nil {
+		return entities.Hold{}, err
+	}
+
+	if from.Type.IsHouse() || to.Type.IsHouse() {
+		return entities.Hold{}, errHouseAccountTransfer
+	}
+
+	if err := checkOwnership(ctx, from); err != nil {
+		return entities.Hold{}, err
+	}
+
+	if err := checkAccountsActive(from, to); err != nil {
+		return entities.Hold{}, err
+	}
+
+	if from.Currency != to.Currency {
+		return entities.Hold{}, errCurrencyMismatch
+	}
+
+	if err := svc.validateAmount(from.Currency, amount); err != nil {
+		return entities.Hold{}, err
+	}
+
+	fee, err := svc.transferFee(from.Currency, amount)
+	if err != nil {
+		return entities.Hold{}, err
+	}
+
+	if !from.CanSpend(amount.Add(fee)) {
+		return entities.Hold{}, errInsufficientFunds
+	}
+
+	from.Held = from.Held.Add(amount)
+	if err := txStorage.SetAccountHeld(ctx, from); err != nil {
+		return entities.Hold{}, errors.Wrap(err, "can't update sender held amount")
+	}
+
+	hold, err := txStorage.CreateHold(ctx, entities.Hold{
+		Account:      from,
+		Counterparty: to,
+		Amount:       amount,
+		Currency:     from.Currency,
+		Status:       entities.PendingHold,
+		ExpiresAt:    svc.now().Add(svc.holdTTL),
+	})
+	if err != nil {
+		return entities.Hold{}, errors.Wrap(err, "can't insert new hold")
+	}
+
+	if err := svc.commitTx(ctx, txStorage); err != nil {
+		return entities.Hold{}, errors.Wrap(err, "transaction commit failed")
+	}
+
+	return hold, nil
+}
+
+// GetHold returns a Hold found by its ID.
+func (svc *Service) GetHold(ctx context.Context, id int) (entities.Hold, error) {
+	hold, err := svc.store.GetHold(ctx, id)
+	if errors.Cause(err) == storage.ErrNotFound {
+		return entities.Hold{}, errHoldNotFound
+	}
+	return hold, errors.Wrap(err, "failed to fetch hold from database")
+}
+
+// CaptureHold converts a pending hold into a transfer of 'amount' to its counterparty.
+// Zero amount captures the authorized one in full, a smaller one releases the rest.
+// The transfer fee of the captured amount is charged on top of it, the same way SendPayment does.
+// Returns error if the hold is not pending or expired, 'amount' exceeds the authorized one,
+// or any of the accounts is not active anymore.
+// Returns a TransferReceipt with the booked transaction on success.
+func (svc *Service) CaptureHold(ctx context.Context, id int, amount decimal.Decimal) (entities.TransferReceipt, error) {
+	if amount.IsNegative() {
+		return entities.TransferReceipt{}, errAmountShouldBePositive
+	}
+
+	txStorage, err := svc.store.BeginTx(ctx, nil)
+	if err != nil {
+		return entities.TransferReceipt{}, errors.Wrap(err, "can't open transaction")
+	}
+
+	defer txStorage.RollbackTx(ctx)
+
+	hold, err := svc.lockHold(ctx, txStorage, id)
+	if err != nil {
+		return entities.TransferReceipt{}, err
+	}
+
+	if hold.IsExpired(svc.now()) {
+		return entities.TransferReceipt{}, errHoldExpired
+	}
+
+	if amount.IsZero() {
+		amount = hold.Amount
+	}
+
+	if amount.GreaterThan(hold.Amount) {
+		return entities.TransferReceipt{}, errCaptureExceedsHold
+	}
+
+	if err := svc.validateAmount(hold.Currency, amount); err != nil {
+		return entities.TransferReceipt{}, err
+	}
+
+	if err := checkAccountsActive(hold.Account, hold.Counterparty); err != nil {
+		return entities.TransferReceipt{}, err
+	}
+
+	if err := releaseHeld(ctx, txStorage, &hold.Account, hold.Amount); err != nil {
+		return entities.TransferReceipt{}, err
+	}
+
+	fee, err := svc.transferFee(hold.Currency, amount)
+	if err != nil {
+		return entities.TransferReceipt{}, err
+	}
+
+	// the amount was reserved, but neither was the fee, nor could credit limit stay the same since then
+	if !hold.Account.CanSpend(amount.Add(fee)) {
+		return entities.TransferReceipt{}, errInsufficientFunds
+	}
+
+	transaction := entities.Transaction{Kind: entities.TransferTransaction}
+	if fee.IsPositive() {
+		transaction.Fee = fee
+	}
+
+	transaction, err = createTransaction(ctx, txStorage, transaction)
+	if err != nil {
+		return entities.TransferReceipt{}, errors.Wrap(err, "can't insert new transaction")
+	}
+
+	payments, err := bookPayments(ctx, txStorage, transaction, &hold.Account, &hold.Counterparty, amount)
+	if err != nil {
+		return entities.TransferReceipt{}, err
+	}
+
+	feePayments, err := bookFee(ctx, txStorage, transaction, &hold.Account, fee)
+	if err != nil {
+		return entities.TransferReceipt{}, err
+	}
+
+	hold.Status = entities.CapturedHold
+	hold.TransactionID = transaction.ID
+	if err := txStorage.SetHoldStatus(ctx, hold); err != nil {
+		return entities.TransferReceipt{}, errors.Wrap(err, "can't update hold status")
+	}
+
+	if err := svc.commitTx(ctx, txStorage); err != nil {
+		return entities.TransferReceipt{}, errors.Wrap(err, "transaction commit failed")
+	}
+
+	return entities.TransferReceipt{
+		Transaction:     transaction,
+		Payments:        append(payments, feePayments...),
+		Fee:             transaction.Fee,
+		SenderBalance:   hold.Account.Balance,
+		ReceiverBalance: hold.Counterparty.Balance,
+	}, nil
+}
+
+// VoidHold releases the amount reserved by a pending hold without moving any money.
+// Holds may be voided even after they expire, as long as the expiry worker
+// has not released them yet. Returns the voided Hold on success.
+func (svc *Service) VoidHold(ctx context.Context, id int) (entities.Hold, error) {
+	return svc.releaseHold(ctx, id, entities.VoidedHold)
+}
+
+// ExpireHolds releases pending holds which were not captured in time.
+// Every hold is released within a transaction of its own, so that a failure
+// keeps the holds released before it. Up to expiredHoldsBatch holds are released per call.
+// Returns the number of holds released.
+func (svc *Service) ExpireHolds(ctx context.Context) (int, error) {
+	holds, err := svc.store.GetExpiredHolds(ctx, svc.now(), expiredHoldsBatch)
+	if err != nil {
+		return 0, errors.Wrap(err, "failed to fetch expired holds from database")
+	}
+
+	released := 0
+	for _, hold := range holds {
+		_, err := svc.releaseHold(ctx, hold.ID, entities.ExpiredHold)
+		switch errors.Cause(err) {
+		case nil:
+			released++
+		case errHoldNotPending:
+			// captured or voided concurrently
+		default:
+			return released, errors.Wrapf(err, "can't expire hold %d", hold.ID)
+		}
+	}
+
+	return released, nil
+}
+
+// releaseHold moves a pending hold to the given final status
+// and releases the amount reserved by it.
+func (svc *Service) releaseHold(ctx context.Context, id int, status entities.HoldStatus) (entities.Hold, error) {
+	txStorage, err := svc.store.BeginTx(ctx, nil)
+	if err != nil {
+		return entities.Hold{}, errors.Wrap(err, "can't open transaction")
+	}
+
+	defer txStorage.RollbackTx(ctx)
+
+	hold, err := svc.lockHold(ctx, txStorage, id)
+	if err != nil {
+		return entities.Hold{}, err
+	}
+
+	if err := releaseHeld(ctx, txStorage, &hold.Account, hold.Amount); err != nil {
+		return entities.Hold{}, err
+	}
+
+	hold.Status = status
+	if err := txStorage.SetHoldStatus(ctx, hold); err != nil {
+		return entities.Hold{}, errors.Wrap(err, "can't update hold status")
+	}
+
+	if err := svc.commitTx(ctx, txStorage); err != nil {
+		return entities.Hold{}, errors.Wrap(err, "transaction commit failed")
+	}
+
+	return hold, nil
+}
+
+// lockHold locks both accounts of the hold and returns the hold in its current state
+// with the accounts filled in. Concurrent captures and releases of the same hold
+// wait for each other, since all of them lock the account it is placed on.
+func (svc *Service) lockHold(ctx context.Context, txStorage storage.Storage, id int) (entities.Hold, error) {
+	hold, err := svc.GetHold(ctx, id)
+	if err != nil {
+		return entities.Hold{}, err
+	}
+
+	if err := lockAccounts(ctx, txStorage, paymentSide{account: &hold.Account, label: "sender"}, paymentSide{account: &hold.Counterparty, label: "receiver"}); err != nil {
+		return entities.Hold{}, err
+	}
+
+	// holds are settled either by the payer or by the merchant they were placed for
+	if err := checkOwnership(ctx, hold.Account, hold.Counterparty); err != nil {
+		return entities.Hold{}, err
+	}
+
+	// the hold could have been captured or released while the lock was awaited
+	current, err := txStorage.GetHold(ctx, id)
+	if err != nil {
+		return entities.Hold{}, errors.Wrap(err, "can't obtain hold")
+	}
+
+	if current.Status != entities.PendingHold {
+		return entities.Hold{}, errHoldNotPending
+	}
+
+	current.Account, current.Counterparty = hold.Account, hold.Counterparty
+	return current, nil
+}
+
+// releaseHeld returns the amount reserved by a hold to the available balance of the locked account
+func releaseHeld(ctx context.Context, txStorage storage.Storage, account *entities.Account, amount decimal.Decimal) error {
+	account.Held = account.Held.Sub(amount)
+	return errors.Wrap(txStorage.SetAccountHeld(ctx, *account), "can't update held amount")
+}
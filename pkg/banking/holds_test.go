@@ -0,0 +1,275 @@
+package banking_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/pkg/errors"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/twonegatives/coinsph_challenge/pkg/banking"
+	"github.com/twonegatives/coinsph_challenge/pkg/entities"
+	"github.com/twonegatives/coinsph_challenge/pkg/mocks"
+	pkgstorage "github.com/twonegatives/coinsph_challenge/pkg/storage"
+)
+
+// expectHeldSet expects held amount of the account to be updated to the given one
+func expectHeldSet(t *testing.T, storage *mocks.MockStorage, held string) {
+	storage.EXPECT().SetAccountHeld(ctx, gomock.Any()).DoAndReturn(func(_ context.Context, account entities.Account) error {
+		assert.Equal(t, held, account.Held.String())
+		return nil
+	})
+}
+
+func TestBankingSvcAuthorize(t *testing.T) {
+	barry := entities.Account{ID: 1, Name: "barry", Type: entities.UserAccount, Status: entities.ActiveAccount, Balance: decimal.New(10, 0), Held: decimal.New(3, 0), Currency: entities.USD}
+	shop := entities.Account{ID: 2, Name: "shop", Type: entities.UserAccount, Status: entities.ActiveAccount, Balance: decimal.New(0, 0), Currency: entities.USD}
+
+	t.Run("reserves the amount on sender account", func(t *testing.T) {
+		mCtrl := gomock.NewController(t)
+		defer mCtrl.Finish()
+		storage := mocks.NewMockStorage(mCtrl)
+
+		storage.EXPECT().BeginTx(ctx, nil).Return(storage, nil)
+		expectAccountsLocked(storage, barry, shop)
+		expectHeldSet(t, storage, "7")
+		storage.EXPECT().CreateHold(ctx, gomock.Any()).DoAndReturn(func(_ context.Context, hold entities.Hold) (entities.Hold, error) {
+			assert.Equal(t, "barry", hold.Account.Name)
+			assert.Equal(t, shop, hold.Counterparty)
+			assert.Equal(t, entities.PendingHold, hold.Status)
+			assert.Equal(t, entities.USD, hold.Currency)
+			assert.WithinDuration(t, time.Now().Add(time.Hour), hold.ExpiresAt, time.Minute)
+			hold.ID = 5
+			return hold, nil
+		})
+		storage.EXPECT().CommitTx(ctx).Return(nil)
+		storage.EXPECT().RollbackTx(ctx).Return(nil)
+
+		svc := banking.NewService(storage, banking.WithHoldTTL(time.Hour))
+		hold, err := svc.Authorize(ctx, entities.Account{Name: "barry"}, entities.Account{Name: "shop"}, decimal.New(4, 0))
+		require.NoError(t, err)
+		assert.Equal(t, 5, hold.ID)
+		assert.Equal(t, "4", hold.Amount.String())
+	})
+
+	t.Run("takes amounts held previously into account", func(t *testing.T) {
+		mCtrl := gomock.NewController(t)
+		defer mCtrl.Finish()
+		storage := mocks.NewMockStorage(mCtrl)
+
+		storage.EXPECT().BeginTx(ctx, nil).Return(storage, nil)
+		expectAccountsLocked(storage, barry, shop)
+		storage.EXPECT().RollbackTx(ctx).Return(nil)
+
+		_, err := banking.NewService(storage).Authorize(ctx, entities.Account{Name: "barry"}, entities.Account{Name: "shop"}, decimal.New(8, 0))
+		require.Error(t, err)
+		assert.Equal(t, "sender account has insufficient funds", err.Error())
+	})
+
+	t.Run("rejects holds on frozen accounts", func(t *testing.T) {
+		mCtrl := gomock.NewController(t)
+		defer mCtrl.Finish()
+		storage := mocks.NewMockStorage(mCtrl)
+
+		frozen := barry
+		frozen.Status = entities.FrozenAccount
+
+		storage.EXPECT().BeginTx(ctx, nil).Return(storage, nil)
+		expectAccountsLocked(storage, frozen, shop)
+		storage.EXPECT().RollbackTx(ctx).Return(nil)
+
+		_, err := banking.NewService(storage).Authorize(ctx, entities.Account{Name: "barry"}, entities.Account{Name: "shop"}, decimal.New(1, 0))
+		require.Error(t, err)
+		assert.Equal(t, "sender account is frozen", err.Error())
+	})
+
+	t.Run("rejects holds of non-positive amount", func(t *testing.T) {
+		mCtrl := gomock.NewController(t)
+		defer mCtrl.Finish()
+		storage := mocks.NewMockStorage(mCtrl)
+
+		_, err := banking.NewService(storage).Authorize(ctx, entities.Account{Name: "barry"}, entities.Account{Name: "shop"}, decimal.New(0, 0))
+		require.Error(t, err)
+		assert.Equal(t, "amount transferred should be a positive number", err.Error())
+	})
+}
+
+func TestBankingSvcCaptureHold(t *testing.T) {
+	barry := entities.Account{ID: 1, Name: "barry", Type: entities.UserAccount, Status: entities.ActiveAccount, Balance: decimal.New(10, 0), Held: decimal.New(5, 0), Currency: entities.USD}
+	shop := entities.Account{ID: 2, Name: "shop", Type: entities.UserAccount, Status: entities.ActiveAccount, Balance: decimal.New(0, 0), Currency: entities.USD}
+	pending := entities.Hold{
+		ID:           5,
+		Account:      entities.Account{ID: 1, Name: "barry"},
+		Counterparty: entities.Account{ID: 2, Name: "shop"},
+		Amount:       decimal.New(5, 0),
+		Currency:     entities.USD,
+		Status:       entities.PendingHold,
+		ExpiresAt:    time.Now().Add(time.Hour),
+	}
+
+	expectHoldLocked := func(storage *mocks.MockStorage, current entities.Hold) {
+		storage.EXPECT().GetHold(ctx, 5).Return(pending, nil)
+		storage.EXPECT().BeginTx(ctx, nil).Return(storage, nil)
+		expectAccountsLocked(storage, barry, shop)
+		storage.EXPECT().GetHold(ctx, 5).Return(current, nil)
+	}
+
+	t.Run("transfers the captured amount and releases the hold", func(t *testing.T) {
+		cases := []struct {
+			title    string
+			amount   decimal.Decimal
+			captured string
+		}{
+			{title: "in full", amount: decimal.New(0, 0), captured: "5"},
+			{title: "in part", amount: decimal.New(3, 0), captured: "3"},
+		}
+
+		for _, tc := range cases {
+			t.Run(tc.title, func(t *testing.T) {
+				mCtrl := gomock.NewController(t)
+				defer mCtrl.Finish()
+				storage := mocks.NewMockStorage(mCtrl)
+
+				transaction := entities.Transaction{ID: 9, Kind: entities.TransferTransaction}
+
+				expectHoldLocked(storage, pending)
+				expectHeldSet(t, storage, "0")
+				storage.EXPECT().CreateTransaction(ctx, entities.Transaction{Kind: entities.TransferTransaction}).Return(transaction, nil)
+				storage.EXPECT().SendPayment(ctx, gomock.Any()).Times(2).Return(nil)
+				storage.EXPECT().SetAccountBalance(ctx, gomock.Any()).Times(2).Return(nil)
+				storage.EXPECT().SetHoldStatus(ctx, gomock.Any()).DoAndReturn(func(_ context.Context, hold entities.Hold) error {
+					assert.Equal(t, entities.CapturedHold, hold.Status)
+					assert.Equal(t, 9, hold.TransactionID)
+					return nil
+				})
+				storage.EXPECT().CommitTx(ctx).Return(nil)
+				storage.EXPECT().RollbackTx(ctx).Return(nil)
+
+				receipt, err := banking.NewService(storage).CaptureHold(ctx, 5, tc.amount)
+				require.NoError(t, err)
+				assert.Equal(t, transaction, receipt.Transaction)
+				require.Len(t, receipt.Payments, 2)
+				assert.Equal(t, tc.captured, receipt.Payments[0].Amount.String())
+				assert.Equal(t, decimal.New(10, 0).Sub(receipt.Payments[0].Amount).String(), receipt.SenderBalance.String())
+			})
+		}
+	})
+
+	t.Run("rejects captures", func(t *testing.T) {
+		captured := pending
+		captured.Status = entities.CapturedHold
+		expired := pending
+		expired.ExpiresAt = time.Now().Add(-time.Second)
+
+		cases := []struct {
+			title   string
+			current entities.Hold
+			amount  decimal.Decimal
+			err     string
+		}{
+			{
+				title:   "of captured hold",
+				current: captured,
+				err:     "authorization has already been captured, voided or expired",
+			},
+			{
+				title:   "of expired hold",
+				current: expired,
+				err:     "authorization has expired",
+			},
+			{
+				title:   "exceeding the authorized amount",
+				current: pending,
+				amount:  decimal.New(501, -2),
+				err:     "captured amount can't exceed the authorized one",
+			},
+		}
+
+		for _, tc := range cases {
+			t.Run(tc.title, func(t *testing.T) {
+				mCtrl := gomock.NewController(t)
+				defer mCtrl.Finish()
+				storage := mocks.NewMockStorage(mCtrl)
+
+				expectHoldLocked(storage, tc.current)
+				storage.EXPECT().RollbackTx(ctx).Return(nil)
+
+				_, err := banking.NewService(storage).CaptureHold(ctx, 5, tc.amount)
+				require.Error(t, err)
+				assert.Equal(t, tc.err, err.Error())
+			})
+		}
+	})
+
+	t.Run("voids the hold", func(t *testing.T) {
+		mCtrl := gomock.NewController(t)
+		defer mCtrl.Finish()
+		storage := mocks.NewMockStorage(mCtrl)
+
+		expectHoldLocked(storage, pending)
+		expectHeldSet(t, storage, "0")
+		storage.EXPECT().SetHoldStatus(ctx, gomock.Any()).DoAndReturn(func(_ context.Context, hold entities.Hold) error {
+			assert.Equal(t, entities.VoidedHold, hold.Status)
+			assert.Zero(t, hold.TransactionID)
+			return nil
+		})
+		storage.EXPECT().CommitTx(ctx).Return(nil)
+		storage.EXPECT().RollbackTx(ctx).Return(nil)
+
+		hold, err := banking.NewService(storage).VoidHold(ctx, 5)
+		require.NoError(t, err)
+		assert.Equal(t, entities.VoidedHold, hold.Status)
+	})
+
+	t.Run("reports missing hold", func(t *testing.T) {
+		mCtrl := gomock.NewController(t)
+		defer mCtrl.Finish()
+		storage := mocks.NewMockStorage(mCtrl)
+
+		storage.EXPECT().BeginTx(ctx, nil).Return(storage, nil)
+		storage.EXPECT().GetHold(ctx, 5).Return(entities.Hold{}, errors.Wrap(pkgstorage.ErrNotFound, "hold 5"))
+		storage.EXPECT().RollbackTx(ctx).Return(nil)
+
+		_, err := banking.NewService(storage).VoidHold(ctx, 5)
+		require.Error(t, err)
+		assert.Equal(t, "authorization not found", err.Error())
+	})
+}
+
+func TestBankingSvcExpireHolds(t *testing.T) {
+	mCtrl := gomock.NewController(t)
+	defer mCtrl.Finish()
+	storage := mocks.NewMockStorage(mCtrl)
+
+	barry := entities.Account{ID: 1, Name: "barry", Type: entities.UserAccount, Status: entities.ActiveAccount, Balance: decimal.New(10, 0), Held: decimal.New(5, 0), Currency: entities.USD}
+	shop := entities.Account{ID: 2, Name: "shop", Type: entities.UserAccount, Status: entities.ActiveAccount, Balance: decimal.New(0, 0), Currency: entities.USD}
+	stale := entities.Hold{ID: 5, Account: barry, Counterparty: shop, Amount: decimal.New(2, 0), Status: entities.PendingHold}
+	captured := entities.Hold{ID: 6, Account: barry, Counterparty: shop, Amount: decimal.New(3, 0), Status: entities.PendingHold}
+
+	storage.EXPECT().GetExpiredHolds(ctx, gomock.Any(), gomock.Any()).Return([]entities.Hold{stale, captured}, nil)
+
+	storage.EXPECT().GetHold(ctx, 5).Times(2).Return(stale, nil)
+	storage.EXPECT().BeginTx(ctx, nil).Times(2).Return(storage, nil)
+	expectAccountsLocked(storage, barry, shop, barry, shop)
+	expectHeldSet(t, storage, "3")
+	storage.EXPECT().SetHoldStatus(ctx, gomock.Any()).DoAndReturn(func(_ context.Context, hold entities.Hold) error {
+		assert.Equal(t, 5, hold.ID)
+		assert.Equal(t, entities.ExpiredHold, hold.Status)
+		return nil
+	})
+	storage.EXPECT().CommitTx(ctx).Return(nil)
+
+	// the second one gets captured before the worker locks it
+	capturedMeanwhile := captured
+	capturedMeanwhile.Status = entities.CapturedHold
+	storage.EXPECT().GetHold(ctx, 6).Return(captured, nil)
+	storage.EXPECT().GetHold(ctx, 6).Return(capturedMeanwhile, nil)
+	storage.EXPECT().RollbackTx(ctx).Times(2).Return(nil)
+
+	count, err := banking.NewService(storage).ExpireHolds(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, count)
+}
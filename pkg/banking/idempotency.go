@@ -0,0 +1,253 @@
+package banking
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/pkg/errors"
+	"github.com/twonegatives/coinsph_challenge/pkg/entities"
+	"github.com/twonegatives/coinsph_challenge/pkg/storage"
+)
+
+// IdempotencyKeyHeader is a request header clients use to make retries of POST requests safe.
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+const (
+	maxIdempotencyKeyLength = 255
+	idempotencyLockIDBytes  = 16
+
+	// idempotencyLockTTL is the time a key is reserved for a request. Requests which
+	// committed nothing by then (e.g. because the service crashed) may be retried.
+	idempotencyLockTTL = 5 * time.Minute
+)
+
+var (
+	errIdempotencyKeyTooLong       = errors.New("idempotency key should not be longer than 255 characters")
+	errIdempotencyKeyReused        = errors.New("idempotency key was already used for a different request")
+	errIdempotentRequestInProgress = errors.New("request with this idempotency key is still being processed")
+	errIdempotentRequestProcessed  = errors.New("request with this idempotency key was already processed, but its response was lost")
+	errIdempotencyKeyTakenOver     = errors.New("idempotency key was taken over by a retry of the request")
+)
+
+// replayedResponseHeaders lists response headers stored along with an idempotent response
+var replayedResponseHeaders = []string{"Content-Type", "Location"}
+
+// IdempotencyStore is an abstraction over persistence of idempotency keys.
+// It is satisfied by storage.Storage implementations.
+type IdempotencyStore interface {
+	CreateIdempotencyRecord(ctx context.Context, record entities.IdempotencyRecord) (bool, error)
+	GetIdempotencyRecord(ctx context.Context, key string) (entities.IdempotencyRecord, error)
+	CompleteIdempotencyRecord(ctx context.Context, record entities.IdempotencyRecord) error
+	DeleteIdempotencyRecord(ctx context.Context, record entities.IdempotencyRecord) error
+}
+
+type idempotencyContextKey struct{}
+
+// pendingIdempotency is the reserved idempotency key of a request in progress.
+// The key is committed by the first storage transaction the request commits,
+// along with the transaction booked within it (if any).
+type pendingIdempotency struct {
+	record       entities.IdempotencyRecord
+	transactions map[storage.Storage]int
+	committed    bool
+}
+
+// idempotent wraps a handler so that requests carrying Idempotency-Key header are processed once.
+// The key is reserved before the request is passed further, committed within the same storage
+// transaction as the request changes, and the response is stored afterwards.
+// Retries with the same key and payload get the stored response back, while reuse of the key
+// with a different payload is rejected. Responses with 5xx status are not stored, and keys of
+// requests which committed nothing are released, which allows to retry such requests with
+// the same key. Reservations outlive crashed requests by idempotencyLockTTL at most.
+// Keys of authenticated callers are scoped by the caller, so that they can't replay
+// responses of each other.
+func idempotent(store IdempotencyStore, logger log.Logger, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get(IdempotencyKeyHeader)
+		if key == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		ctx := r.Context()
+		if len(key) > maxIdempotencyKeyLength {
+			errorEncoder(ctx, errIdempotencyKeyTooLong, w)
+			return
+		}
+
+		if principal, ok := PrincipalFromContext(ctx); ok {
+			key = principal.Subject + "/" + key
+		}
+
+		body, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			errorEncoder(ctx, errBadRequest, w)
+			return
+		}
+		r.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+		lockID, err := randomHex(idempotencyLockIDBytes)
+		if err != nil {
+			logger.Log("func", "idempotent", "err", err)
+			errorEncoder(ctx, err, w)
+			return
+		}
+
+		record := entities.IdempotencyRecord{
+			Key:         key,
+			RequestHash: requestHash(r, body),
+			LockID:      lockID,
+			LockedUntil: time.Now().Add(idempotencyLockTTL),
+		}
+		created, err := store.CreateIdempotencyRecord(ctx, record)
+		if err != nil {
+			logger.Log("func", "idempotent", "err", err)
+			errorEncoder(ctx, err, w)
+			return
+		}
+
+		if !created {
+			replayIdempotentResponse(ctx, store, record, w)
+			return
+		}
+
+		pending := &pendingIdempotency{record: record, transactions: make(map[storage.Storage]int)}
+		recorder := &responseRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(recorder, r.WithContext(context.WithValue(ctx, idempotencyContextKey{}, pending)))
+
+		// the client may be gone by now, while the key still has to be released or completed
+		ctx = detach(ctx)
+		if recorder.status >= http.StatusInternalServerError {
+			if err := store.DeleteIdempotencyRecord(ctx, record); err != nil {
+				logger.Log("func", "idempotent", "err", err)
+			}
+			return
+		}
+
+		record.ResponseStatus = recorder.status
+		record.ResponseBody = recorder.body.Bytes()
+		record.ResponseHeaders = make(map[string]string)
+		for _, header := range replayedResponseHeaders {
+			if value := recorder.Header().Get(header); value != "" {
+				record.ResponseHeaders[header] = value
+			}
+		}
+
+		if err := store.CompleteIdempotencyRecord(ctx, record); err != nil {
+			logger.Log("func", "idempotent", "err", err)
+		}
+	})
+}
+
+func replayIdempotentResponse(ctx context.Context, store IdempotencyStore, request entities.IdempotencyRecord, w http.ResponseWriter) {
+	record, err := store.GetIdempotencyRecord(ctx, request.Key)
+	if err != nil {
+		errorEncoder(ctx, err, w)
+		return
+	}
+
+	if record.RequestHash != request.RequestHash {
+		errorEncoder(ctx, errIdempotencyKeyReused, w)
+		return
+	}
+
+	if !record.IsCompleted() {
+		// changes of a request which is not running anymore are committed,
+		// so the transaction it booked is the only thing left to point to
+		if record.IsCommitted() && !record.IsLocked(time.Now()) {
+			if record.TransactionID != 0 {
+				w.Header().Set("Location", transactionLocation(entities.Transaction{ID: record.TransactionID}))
+			}
+			errorEncoder(ctx, errIdempotentRequestProcessed, w)
+			return
+		}
+
+		errorEncoder(ctx, errIdempotentRequestInProgress, w)
+		return
+	}
+
+	for header, value := range record.ResponseHeaders {
+		w.Header().Set(header, value)
+	}
+	w.Header().Set("Idempotent-Replayed", "true")
+	w.WriteHeader(record.ResponseStatus)
+	w.Write(record.ResponseBody)
+}
+
+// createTransaction persists the transaction within the storage transaction
+// and remembers it as the one booked by the idempotent request in progress
+func createTransaction(ctx context.Context, txStorage storage.Storage, transaction entities.Transaction) (entities.Transaction, error) {
+	transaction, err := txStorage.CreateTransaction(ctx, transaction)
+	if err != nil {
+		return transaction, err
+	}
+
+	if pending, ok := ctx.Value(idempotencyContextKey{}).(*pendingIdempotency); ok {
+		if _, booked := pending.transactions[txStorage]; !booked {
+			pending.transactions[txStorage] = transaction.ID
+		}
+	}
+	return transaction, nil
+}
+
+// commitIdempotencyRecord commits the idempotency key of the request in progress
+// within the storage transaction, unless it was committed already
+func commitIdempotencyRecord(ctx context.Context, txStorage storage.Storage) (*pendingIdempotency, error) {
+	pending, ok := ctx.Value(idempotencyContextKey{}).(*pendingIdempotency)
+	if !ok || pending.committed {
+		return nil, nil
+	}
+
+	record := pending.record
+	record.TransactionID = pending.transactions[txStorage]
+	if err := txStorage.CommitIdempotencyRecord(ctx, record); err != nil {
+		if errors.Cause(err) == storage.ErrAlreadyExists {
+			return nil, errIdempotencyKeyTakenOver
+		}
+		return nil, errors.Wrap(err, "can't commit idempotency key")
+	}
+	return pending, nil
+}
+
+// requestHash fingerprints the request by its method, path and payload.
+// JSON payloads are compared regardless of their formatting and keys order.
+func requestHash(r *http.Request, body []byte) string {
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+
+	var payload interface{}
+	if err := decoder.Decode(&payload); err == nil {
+		if canonical, err := json.Marshal(payload); err == nil {
+			body = canonical
+		}
+	}
+
+	hash := sha256.New()
+	hash.Write([]byte(r.Method + " " + r.URL.Path + "\n"))
+	hash.Write(body)
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// responseRecorder passes response through while keeping its status and body
+type responseRecorder struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (r *responseRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *responseRecorder) Write(data []byte) (int, error) {
+	r.body.Write(data)
+	return r.ResponseWriter.Write(data)
+}
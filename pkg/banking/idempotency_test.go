@@ -0,0 +1,273 @@
+package banking_test
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/twonegatives/coinsph_challenge/pkg/banking"
+	"github.com/twonegatives/coinsph_challenge/pkg/entities"
+	"github.com/twonegatives/coinsph_challenge/pkg/memstorage"
+	"github.com/twonegatives/coinsph_challenge/pkg/mocks"
+)
+
+type idempotencyDependencies struct {
+	TestServer *httptest.Server
+	Service    *mocks.MockBankingService
+	Store      *mocks.MockStorage
+}
+
+func setupIdempotentServer(t *testing.T) (idempotencyDependencies, func()) {
+	mockCtrl := gomock.NewController(t)
+
+	svc := mocks.NewMockBankingService(mockCtrl)
+	store := mocks.NewMockStorage(mockCtrl)
+
+	router := banking.MakeHandler(svc, mocks.TestLogger{T: t}, banking.WithIdempotencyStore(store))
+	srv := httptest.NewServer(router)
+	return idempotencyDependencies{TestServer: srv, Service: svc, Store: store}, func() {
+		mockCtrl.Finish()
+		srv.Close()
+	}
+}
+
+func postWithIdempotencyKey(t *testing.T, srv *httptest.Server, path, key, body string) *http.Response {
+	req, err := http.NewRequest(http.MethodPost, srv.URL+path, strings.NewReader(body))
+	require.NoError(t, err)
+	req.Header.Set(banking.IdempotencyKeyHeader, key)
+
+	resp, err := srv.Client().Do(req)
+	require.NoError(t, err)
+	return resp
+}
+
+func TestIdempotentRoutes(t *testing.T) {
+	from := entities.Account{Name: "barry"}
+	to := entities.Account{Name: "wicky"}
+	amount := decimal.New(1426, -2)
+	requestBody := `{"payment": {"from": "barry", "to": "wicky", "amount": 14.26}}`
+	receipt := entities.TransferReceipt{Transaction: entities.Transaction{ID: 42}}
+
+	t.Run("processes request and stores its response", func(t *testing.T) {
+		dep, cleanUp := setupIdempotentServer(t)
+		defer cleanUp()
+
+		var stored entities.IdempotencyRecord
+		dep.Store.EXPECT().CreateIdempotencyRecord(gomock.Any(), gomock.Any()).Return(true, nil)
+		dep.Service.EXPECT().SendPayment(gomock.Any(), from, to, amount, gomock.Any()).Return(receipt, nil)
+		dep.Store.EXPECT().CompleteIdempotencyRecord(gomock.Any(), gomock.Any()).DoAndReturn(
+			func(_ context.Context, record entities.IdempotencyRecord) error {
+				stored = record
+				return nil
+			},
+		)
+
+		resp := postWithIdempotencyKey(t, dep.TestServer, "/payments", "key-1", requestBody)
+		defer resp.Body.Close()
+
+		assert.Equal(t, http.StatusCreated, resp.StatusCode)
+		assert.Equal(t, "key-1", stored.Key)
+		assert.Equal(t, http.StatusCreated, stored.ResponseStatus)
+		assert.Contains(t, string(stored.ResponseBody), `"transaction_id":42`)
+		assert.Equal(t, "application/json; charset=utf-8", stored.ResponseHeaders["Content-Type"])
+		assert.Equal(t, "/api/v1/transactions/42", stored.ResponseHeaders["Location"])
+	})
+
+	t.Run("replays stored response for the same payload", func(t *testing.T) {
+		dep, cleanUp := setupIdempotentServer(t)
+		defer cleanUp()
+
+		var reserved entities.IdempotencyRecord
+		dep.Store.EXPECT().CreateIdempotencyRecord(gomock.Any(), gomock.Any()).DoAndReturn(
+			func(_ context.Context, record entities.IdempotencyRecord) (bool, error) {
+				reserved = record
+				return true, nil
+			},
+		)
+		dep.Service.EXPECT().SendPayment(gomock.Any(), from, to, amount, gomock.Any()).Return(receipt, nil)
+		dep.Store.EXPECT().CompleteIdempotencyRecord(gomock.Any(), gomock.Any()).Return(nil)
+
+		resp := postWithIdempotencyKey(t, dep.TestServer, "/payments", "key-2", requestBody)
+		resp.Body.Close()
+
+		dep.Store.EXPECT().CreateIdempotencyRecord(gomock.Any(), gomock.Any()).Return(false, nil)
+		dep.Store.EXPECT().GetIdempotencyRecord(gomock.Any(), "key-2").Return(entities.IdempotencyRecord{
+			Key:             "key-2",
+			RequestHash:     reserved.RequestHash,
+			ResponseStatus:  http.StatusCreated,
+			ResponseHeaders: map[string]string{"Content-Type": "application/json; charset=utf-8", "Location": "/api/v1/transactions/42"},
+			ResponseBody:    []byte(`{"receipt":{"transaction_id":42}}`),
+		}, nil)
+
+		reformattedBody := `{"payment":{"amount":14.26,"to":"wicky","from":"barry"}}`
+		resp = postWithIdempotencyKey(t, dep.TestServer, "/payments", "key-2", reformattedBody)
+		defer resp.Body.Close()
+
+		body, err := ioutil.ReadAll(resp.Body)
+		require.NoError(t, err)
+
+		assert.Equal(t, http.StatusCreated, resp.StatusCode)
+		assert.Equal(t, "true", resp.Header.Get("Idempotent-Replayed"))
+		assert.Equal(t, "/api/v1/transactions/42", resp.Header.Get("Location"))
+		assert.Equal(t, `{"receipt":{"transaction_id":42}}`, string(body))
+	})
+
+	t.Run("returns 409 when key is reused with different payload", func(t *testing.T) {
+		dep, cleanUp := setupIdempotentServer(t)
+		defer cleanUp()
+
+		dep.Store.EXPECT().CreateIdempotencyRecord(gomock.Any(), gomock.Any()).Return(false, nil)
+		dep.Store.EXPECT().GetIdempotencyRecord(gomock.Any(), "key-3").Return(entities.IdempotencyRecord{
+			Key:            "key-3",
+			RequestHash:    "another request hash",
+			ResponseStatus: http.StatusOK,
+		}, nil)
+
+		resp := postWithIdempotencyKey(t, dep.TestServer, "/payments", "key-3", requestBody)
+		defer resp.Body.Close()
+
+		assert.Equal(t, http.StatusConflict, resp.StatusCode)
+	})
+
+	t.Run("returns 409 while original request is in progress", func(t *testing.T) {
+		dep, cleanUp := setupIdempotentServer(t)
+		defer cleanUp()
+
+		var reserved entities.IdempotencyRecord
+		dep.Store.EXPECT().CreateIdempotencyRecord(gomock.Any(), gomock.Any()).DoAndReturn(
+			func(_ context.Context, record entities.IdempotencyRecord) (bool, error) {
+				reserved = record
+				return false, nil
+			},
+		)
+		dep.Store.EXPECT().GetIdempotencyRecord(gomock.Any(), "key-4").DoAndReturn(
+			func(_ context.Context, key string) (entities.IdempotencyRecord, error) {
+				return entities.IdempotencyRecord{Key: key, RequestHash: reserved.RequestHash}, nil
+			},
+		)
+
+		resp := postWithIdempotencyKey(t, dep.TestServer, "/payments", "key-4", requestBody)
+		defer resp.Body.Close()
+
+		assert.Equal(t, http.StatusConflict, resp.StatusCode)
+	})
+
+	t.Run("releases key on server error", func(t *testing.T) {
+		dep, cleanUp := setupIdempotentServer(t)
+		defer cleanUp()
+
+		var reserved, released entities.IdempotencyRecord
+		dep.Store.EXPECT().CreateIdempotencyRecord(gomock.Any(), gomock.Any()).DoAndReturn(
+			func(_ context.Context, record entities.IdempotencyRecord) (bool, error) {
+				reserved = record
+				return true, nil
+			},
+		)
+		dep.Service.EXPECT().SendPayment(gomock.Any(), from, to, amount, gomock.Any()).Return(entities.TransferReceipt{}, ErrSvc)
+		dep.Store.EXPECT().DeleteIdempotencyRecord(gomock.Any(), gomock.Any()).DoAndReturn(
+			func(ctx context.Context, record entities.IdempotencyRecord) error {
+				// the key is released even if the client has gone away
+				assert.Nil(t, ctx.Done())
+				released = record
+				return nil
+			},
+		)
+
+		resp := postWithIdempotencyKey(t, dep.TestServer, "/payments", "key-5", requestBody)
+		defer resp.Body.Close()
+
+		assert.Equal(t, http.StatusInternalServerError, resp.StatusCode)
+		assert.Equal(t, "key-5", released.Key)
+		assert.NotEmpty(t, released.LockID)
+		assert.Equal(t, reserved.LockID, released.LockID)
+	})
+
+	t.Run("points to the transaction of a request which lost its response", func(t *testing.T) {
+		dep, cleanUp := setupIdempotentServer(t)
+		defer cleanUp()
+
+		var reserved entities.IdempotencyRecord
+		dep.Store.EXPECT().CreateIdempotencyRecord(gomock.Any(), gomock.Any()).DoAndReturn(
+			func(_ context.Context, record entities.IdempotencyRecord) (bool, error) {
+				reserved = record
+				return false, nil
+			},
+		)
+		dep.Store.EXPECT().GetIdempotencyRecord(gomock.Any(), "key-6").DoAndReturn(
+			func(_ context.Context, key string) (entities.IdempotencyRecord, error) {
+				return entities.IdempotencyRecord{
+					Key:           key,
+					RequestHash:   reserved.RequestHash,
+					TransactionID: 42,
+					LockedUntil:   time.Now().Add(-time.Minute),
+					CommittedAt:   time.Now().Add(-time.Hour),
+				}, nil
+			},
+		)
+
+		resp := postWithIdempotencyKey(t, dep.TestServer, "/payments", "key-6", requestBody)
+		defer resp.Body.Close()
+
+		assert.Equal(t, http.StatusConflict, resp.StatusCode)
+		assert.Equal(t, "/api/v1/transactions/42", resp.Header.Get("Location"))
+	})
+
+	t.Run("skips requests without key", func(t *testing.T) {
+		dep, cleanUp := setupIdempotentServer(t)
+		defer cleanUp()
+
+		dep.Service.EXPECT().CreateAccount(gomock.Any(), "barry", entities.USD, entities.AccountProfile{}).Return(entities.Account{Name: "barry"}, nil)
+
+		resp, err := dep.TestServer.Client().Post(dep.TestServer.URL+"/accounts", "application/json", strings.NewReader(`{"account": {"name": "barry"}}`))
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+	})
+}
+
+func TestIdempotencyKeyCommit(t *testing.T) {
+	store := memstorage.NewMemStorage()
+	svc := banking.NewService(store)
+	for _, name := range []string{"barry", "wicky"} {
+		_, err := svc.CreateAccount(ctx, name, entities.USD, entities.AccountProfile{})
+		require.NoError(t, err)
+	}
+	_, err := svc.Deposit(ctx, "barry", decimal.New(100, 0), "wire-1")
+	require.NoError(t, err)
+
+	srv := httptest.NewServer(banking.MakeHandler(svc, mocks.TestLogger{T: t}, banking.WithIdempotencyStore(store)))
+	defer srv.Close()
+
+	t.Run("commits key along with the payment", func(t *testing.T) {
+		resp := postWithIdempotencyKey(t, srv, "/payments", "key-1", `{"payment": {"from": "barry", "to": "wicky", "amount": 10}}`)
+		resp.Body.Close()
+		require.Equal(t, http.StatusCreated, resp.StatusCode)
+
+		record, err := store.GetIdempotencyRecord(ctx, "key-1")
+		require.NoError(t, err)
+		assert.True(t, record.IsCommitted())
+		assert.True(t, record.IsCompleted())
+		assert.Equal(t, resp.Header.Get("Location"), "/api/v1/transactions/"+strconv.Itoa(record.TransactionID))
+	})
+
+	t.Run("leaves key of failed request uncommitted", func(t *testing.T) {
+		resp := postWithIdempotencyKey(t, srv, "/payments", "key-2", `{"payment": {"from": "barry", "to": "wicky", "amount": 1000}}`)
+		resp.Body.Close()
+		require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+		record, err := store.GetIdempotencyRecord(ctx, "key-2")
+		require.NoError(t, err)
+		assert.False(t, record.IsCommitted())
+		assert.Zero(t, record.TransactionID)
+	})
+}
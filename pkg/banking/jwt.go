@@ -0,0 +1,101 @@
+package banking
+
+import (
+	"context"
+	"strings"
+
+	"github.com/go-kit/kit/endpoint"
+	kithttp "github.com/go-kit/kit/transport/http"
+	"github.com/pkg/errors"
+	"github.com/twonegatives/coinsph_challenge/pkg/entities"
+	"github.com/twonegatives/coinsph_challenge/pkg/jwt"
+)
+
+// DefaultOwnerClaim is a claim of JWTs holding the owner of accounts the caller manages.
+const DefaultOwnerClaim = "owner_id"
+
+// jwtSubjectPrefix keeps subjects of JWT callers apart from API key ids
+const jwtSubjectPrefix = "jwt:"
+
+var errScopeNotPermitted = errors.New("token scopes do not permit the operation")
+
+// tokenAuthority turns JWTs issued by the gateway into principals.
+type tokenAuthority struct {
+	verifier   *jwt.Verifier
+	ownerClaim string
+}
+
+// WithJWT makes routes accept JWTs verified by the verifier as bearer tokens.
+// JWT callers are account owners: the owner claim links them to accounts, and
+// 'scope' (or 'scp') claim limits them to the listed scopes.
+// Blank owner claim stands for DefaultOwnerClaim.
+func WithJWT(verifier *jwt.Verifier, ownerClaim string) HandlerOption {
+	if ownerClaim == "" {
+		ownerClaim = DefaultOwnerClaim
+	}
+
+	return func(cfg *handlerConfig) {
+		cfg.tokens = &tokenAuthority{verifier: verifier, ownerClaim: ownerClaim}
+	}
+}
+
+// principal verifies the token and returns the caller it was issued to
+func (a *tokenAuthority) principal(token string) (entities.Principal, error) {
+	claims, err := a.verifier.Verify(token)
+	if err != nil {
+		return entities.Principal{}, errCredentialsInvalid
+	}
+
+	if claims.Subject == "" {
+		return entities.Principal{}, errCredentialsInvalid
+	}
+
+	scopes := []entities.Scope{}
+	for _, name := range []string{"scope", "scp"} {
+		for _, scope := range claims.Strings(name) {
+			scopes = append(scopes, entities.Scope(scope))
+		}
+	}
+
+	return entities.Principal{
+		Subject: jwtSubjectPrefix + claims.Subject,
+		Role:    entities.AccountOwnerRole,
+		OwnerID: claims.String(a.ownerClaim),
+		Scopes:  scopes,
+	}, nil
+}
+
+// scoped returns an endpoint middleware serving callers granted the scope only.
+// Callers authenticated by the transport are taken from the context. Otherwise
+// JWT bearer tokens put into the context by kithttp.PopulateRequestContext
+// are verified and their principal is passed further within the context.
+// Requests without a principal or a JWT are passed as they are.
+func scoped(tokens *tokenAuthority, scope entities.Scope) endpoint.Middleware {
+	return func(next endpoint.Endpoint) endpoint.Endpoint {
+		return func(ctx context.Context, request interface{}) (interface{}, error) {
+			principal, ok := PrincipalFromContext(ctx)
+			if !ok && tokens != nil {
+				authorization, _ := ctx.Value(kithttp.ContextKeyRequestAuthorization).(string)
+				if token := strings.TrimPrefix(authorization, "Bearer "); isJWT(token) {
+					var err error
+					if principal, err = tokens.principal(token); err != nil {
+						return nil, err
+					}
+
+					ok = true
+					ctx = ContextWithPrincipal(ctx, principal)
+				}
+			}
+
+			if ok && !principal.Allows(scope) {
+				return nil, errScopeNotPermitted
+			}
+			return next(ctx, request)
+		}
+	}
+}
+
+// isJWT tells JWTs from API keys and static tokens by their three dot separated segments
+func isJWT(token string) bool {
+	return strings.Count(token, ".") == 2
+}
@@ -0,0 +1,147 @@
+package banking_test
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/twonegatives/coinsph_challenge/pkg/banking"
+	"github.com/twonegatives/coinsph_challenge/pkg/entities"
+	"github.com/twonegatives/coinsph_challenge/pkg/jwt"
+	"github.com/twonegatives/coinsph_challenge/pkg/memstorage"
+)
+
+func TestJWTAuthentication(t *testing.T) {
+	secret := []byte("gateway-secret")
+	keys := jwt.NewKeySet()
+	require.NoError(t, keys.AddSecret("gw-1", secret))
+	verifier := jwt.NewVerifier(keys, jwt.WithIssuer("gateway"))
+
+	// token returns a HS256 token issued by the gateway to 'user-1' of 'customer-1' with the scope
+	token := func(t *testing.T, scope string, expiresAt time.Time, key []byte) string {
+		encode := func(value interface{}) string {
+			data, err := json.Marshal(value)
+			require.NoError(t, err)
+			return base64.RawURLEncoding.EncodeToString(data)
+		}
+
+		input := encode(map[string]string{"alg": "HS256", "kid": "gw-1"}) + "." + encode(map[string]interface{}{
+			"sub":       "user-1",
+			"iss":       "gateway",
+			"exp":       expiresAt.Unix(),
+			"scope":     scope,
+			"tenant_id": "customer-1",
+		})
+
+		mac := hmac.New(sha256.New, key)
+		mac.Write([]byte(input))
+		return input + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+	}
+
+	valid := func(t *testing.T, scope string) string {
+		return token(t, scope, time.Now().Add(time.Hour), secret)
+	}
+
+	do := func(t *testing.T, dep dependencies, method string, path string, body string, bearer string) (int, string) {
+		req, err := http.NewRequest(method, dep.TestServer.URL+path, strings.NewReader(body))
+		require.NoError(t, err)
+		req.Header.Set("Authorization", "Bearer "+bearer)
+
+		resp, err := dep.TestServer.Client().Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		var response map[string]interface{}
+		json.NewDecoder(resp.Body).Decode(&response)
+		description, _ := response["error"].(string)
+		return resp.StatusCode, description
+	}
+
+	paymentBody := `{"payment": {"from": "alice", "to": "bob", "amount": 1}}`
+	caller := entities.Principal{
+		Subject: "jwt:user-1",
+		Role:    entities.AccountOwnerRole,
+		OwnerID: "customer-1",
+		Scopes:  []entities.Scope{entities.AccountsReadScope, entities.PaymentsWriteScope},
+	}
+
+	t.Run("passes the caller to service", func(t *testing.T) {
+		for _, opts := range [][]banking.HandlerOption{
+			{banking.WithJWT(verifier, "tenant_id")},
+			{banking.WithJWT(verifier, "tenant_id"), banking.WithAuthentication(memstorage.NewMemStorage())},
+		} {
+			dep, cleanUp := setupServer(t, opts...)
+
+			dep.Service.EXPECT().SendPayment(gomock.Any(), entities.Account{Name: "alice"}, entities.Account{Name: "bob"}, decimal.New(1, 0), entities.PaymentDetails{}).
+				DoAndReturn(func(ctx context.Context, _, _ entities.Account, _ decimal.Decimal, _ entities.PaymentDetails) (entities.TransferReceipt, error) {
+					principal, ok := banking.PrincipalFromContext(ctx)
+					require.True(t, ok)
+					assert.Equal(t, caller, principal)
+					return entities.TransferReceipt{Payments: []entities.Payment{}}, nil
+				})
+
+			status, _ := do(t, dep, http.MethodPost, "/payments", paymentBody, valid(t, "accounts:read payments:write"))
+			assert.Equal(t, http.StatusCreated, status)
+			cleanUp()
+		}
+	})
+
+	t.Run("serves callers whose scopes permit the route", func(t *testing.T) {
+		dep, cleanUp := setupServer(t, banking.WithJWT(verifier, "tenant_id"))
+		defer cleanUp()
+
+		readOnly := valid(t, "accounts:read")
+		status, description := do(t, dep, http.MethodPost, "/payments", paymentBody, readOnly)
+		assert.Equal(t, http.StatusForbidden, status)
+		assert.Equal(t, "token scopes do not permit the operation", description)
+
+		status, _ = do(t, dep, http.MethodPost, "/accounts", `{"account": {"name": "carol", "currency": "usd"}}`, readOnly)
+		assert.Equal(t, http.StatusForbidden, status)
+
+		status, _ = do(t, dep, http.MethodGet, "/accounts/alice", "", valid(t, "payments:write"))
+		assert.Equal(t, http.StatusForbidden, status)
+
+		dep.Service.EXPECT().GetAccount(gomock.Any(), "alice").Return(entities.Account{Name: "alice"}, nil)
+		status, _ = do(t, dep, http.MethodGet, "/accounts/alice", "", readOnly)
+		assert.Equal(t, http.StatusOK, status)
+	})
+
+	t.Run("keeps operator routes to operators", func(t *testing.T) {
+		dep, cleanUp := setupServer(t, banking.WithJWT(verifier, "tenant_id"), banking.WithAuthentication(memstorage.NewMemStorage()))
+		defer cleanUp()
+
+		status, description := do(t, dep, http.MethodPost, "/accounts/alice/deposits", `{"deposit": {"amount": 4, "reference": "wire-1"}}`, valid(t, "payments:write"))
+		assert.Equal(t, http.StatusForbidden, status)
+		assert.Equal(t, "role of the caller does not permit the operation", description)
+	})
+
+	t.Run("refuses invalid tokens", func(t *testing.T) {
+		for _, opts := range [][]banking.HandlerOption{
+			{banking.WithJWT(verifier, "tenant_id")},
+			{banking.WithJWT(verifier, "tenant_id"), banking.WithAuthentication(memstorage.NewMemStorage())},
+		} {
+			dep, cleanUp := setupServer(t, opts...)
+
+			for _, bearer := range []string{
+				token(t, "payments:write", time.Now().Add(-time.Hour), secret),
+				token(t, "payments:write", time.Now().Add(time.Hour), []byte("guess")),
+				"a.b.c",
+			} {
+				status, description := do(t, dep, http.MethodPost, "/payments", paymentBody, bearer)
+				assert.Equal(t, http.StatusUnauthorized, status)
+				assert.Equal(t, "credentials are invalid", description)
+			}
+			cleanUp()
+		}
+	})
+}
@@ -0,0 +1,124 @@
+package banking
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/pkg/errors"
+	"github.com/twonegatives/coinsph_challenge/pkg/entities"
+)
+
+var (
+	errSenderFrozen          = errors.New("sender account is frozen")
+	errReceiverFrozen        = errors.New("receiver account is frozen")
+	errSenderClosed          = errors.New("sender account is closed")
+	errReceiverClosed        = errors.New("receiver account is closed")
+	errAccountClosed         = errors.New("account is closed")
+	errAccountBalanceNotZero = errors.New("account balance should be zero to close it")
+	errHouseAccountStatus    = errors.New("status of house accounts can't be changed")
+)
+
+// FreezeAccount stops the account from sending and receiving money until it gets unfrozen.
+// Freezing a frozen account changes nothing. Closed accounts can't be frozen.
+func (svc *Service) FreezeAccount(ctx context.Context, accountName string) (entities.Account, error) {
+	return svc.changeAccountStatus(ctx, accountName, entities.FrozenAccount)
+}
+
+// UnfreezeAccount lets a frozen account send and receive money again.
+// Unfreezing an active account changes nothing. Closed accounts can't be unfrozen.
+func (svc *Service) UnfreezeAccount(ctx context.Context, accountName string) (entities.Account, error) {
+	return svc.changeAccountStatus(ctx, accountName, entities.ActiveAccount)
+}
+
+// CloseAccount freezes the account for good. Either active or frozen account
+// may be closed as long as its balance is zero and it has no pending holds. Closing a closed account changes nothing.
+func (svc *Service) CloseAccount(ctx context.Context, accountName string) (entities.Account, error) {
+	return svc.changeAccountStatus(ctx, accountName, entities.ClosedAccount)
+}
+
+// changeAccountStatus locks the account and moves it to the given status
+// unless it is already there. Returns the account with its new status on success.
+func (svc *Service) changeAccountStatus(ctx context.Context, accountName string, status entities.AccountStatus) (entities.Account, error) {
+	if accountName == "" {
+		return entities.Account{}, errAccountNameBlank
+	}
+
+	txStorage, err := svc.store.BeginTx(ctx, nil)
+	if err != nil {
+		return entities.Account{}, errors.Wrap(err, "can't open transaction")
+	}
+
+	defer txStorage.RollbackTx(ctx)
+
+	account := entities.Account{Name: accountName}
+	if err := txStorage.GetAccountForUpdate(ctx, &account); err != nil {
+		if errors.Cause(err) == sql.ErrNoRows {
+			return entities.Account{}, errAccountNotFound
+		}
+		return entities.Account{}, errors.Wrap(err, "can't obtain account")
+	}
+
+	if account.Type.IsHouse() {
+		return entities.Account{}, errHouseAccountStatus
+	}
+
+	if account.Status == status {
+		return account, nil
+	}
+
+	if account.Status == entities.ClosedAccount {
+		return entities.Account{}, errAccountClosed
+	}
+
+	if status == entities.ClosedAccount && !account.Balance.IsZero() {
+		return entities.Account{}, errAccountBalanceNotZero
+	}
+
+	if status == entities.ClosedAccount && !account.Held.IsZero() {
+		return entities.Account{}, errAccountHasHolds
+	}
+
+	account.Status = status
+	if err := txStorage.SetAccountStatus(ctx, account); err != nil {
+		return entities.Account{}, err
+	}
+
+	if err := svc.commitTx(ctx, txStorage); err != nil {
+		return entities.Account{}, errors.Wrap(err, "transaction commit failed")
+	}
+
+	return account, nil
+}
+
+// checkAccountsActive makes sure both locked sides of a transfer may move money.
+func checkAccountsActive(from entities.Account, to entities.Account) error {
+	if err := checkSenderActive(from); err != nil {
+		return err
+	}
+
+	return checkReceiverActive(to)
+}
+
+// checkSenderActive makes sure the locked account may send money.
+func checkSenderActive(account entities.Account) error {
+	switch account.Status {
+	case entities.FrozenAccount:
+		return errSenderFrozen
+	case entities.ClosedAccount:
+		return errSenderClosed
+	}
+
+	return nil
+}
+
+// checkReceiverActive makes sure the locked account may receive money.
+func checkReceiverActive(account entities.Account) error {
+	switch account.Status {
+	case entities.FrozenAccount:
+		return errReceiverFrozen
+	case entities.ClosedAccount:
+		return errReceiverClosed
+	}
+
+	return nil
+}
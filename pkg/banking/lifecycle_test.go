@@ -0,0 +1,143 @@
+package banking_test
+
+import (
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/twonegatives/coinsph_challenge/pkg/banking"
+	"github.com/twonegatives/coinsph_challenge/pkg/entities"
+	"github.com/twonegatives/coinsph_challenge/pkg/mocks"
+)
+
+func TestBankingSvcAccountLifecycle(t *testing.T) {
+	active := entities.Account{ID: 7, Name: "molly", Type: entities.UserAccount, Status: entities.ActiveAccount, Balance: decimal.New(0, 0), Currency: entities.USD}
+	frozen := active
+	frozen.Status = entities.FrozenAccount
+	closed := active
+	closed.Status = entities.ClosedAccount
+
+	t.Run("changes account status", func(t *testing.T) {
+		cases := []struct {
+			title    string
+			change   func(*banking.Service) (entities.Account, error)
+			account  entities.Account
+			expected entities.AccountStatus
+		}{
+			{
+				title:    "freezes active account",
+				change:   func(svc *banking.Service) (entities.Account, error) { return svc.FreezeAccount(ctx, "molly") },
+				account:  active,
+				expected: entities.FrozenAccount,
+			},
+			{
+				title:    "unfreezes frozen account",
+				change:   func(svc *banking.Service) (entities.Account, error) { return svc.UnfreezeAccount(ctx, "molly") },
+				account:  frozen,
+				expected: entities.ActiveAccount,
+			},
+			{
+				title:    "closes active account",
+				change:   func(svc *banking.Service) (entities.Account, error) { return svc.CloseAccount(ctx, "molly") },
+				account:  active,
+				expected: entities.ClosedAccount,
+			},
+			{
+				title:    "closes frozen account",
+				change:   func(svc *banking.Service) (entities.Account, error) { return svc.CloseAccount(ctx, "molly") },
+				account:  frozen,
+				expected: entities.ClosedAccount,
+			},
+		}
+
+		for _, tc := range cases {
+			t.Run(tc.title, func(t *testing.T) {
+				mCtrl := gomock.NewController(t)
+				defer mCtrl.Finish()
+				storage := mocks.NewMockStorage(mCtrl)
+
+				updated := tc.account
+				updated.Status = tc.expected
+
+				storage.EXPECT().BeginTx(ctx, nil).Return(storage, nil)
+				expectAccountsLocked(storage, tc.account)
+				storage.EXPECT().SetAccountStatus(ctx, updated).Return(nil)
+				storage.EXPECT().CommitTx(ctx).Return(nil)
+				storage.EXPECT().RollbackTx(ctx).Return(nil)
+
+				account, err := tc.change(banking.NewService(storage))
+				require.NoError(t, err)
+				assert.Equal(t, tc.expected, account.Status)
+			})
+		}
+	})
+
+	t.Run("leaves account in the requested status alone", func(t *testing.T) {
+		mCtrl := gomock.NewController(t)
+		defer mCtrl.Finish()
+		storage := mocks.NewMockStorage(mCtrl)
+
+		storage.EXPECT().BeginTx(ctx, nil).Return(storage, nil)
+		expectAccountsLocked(storage, frozen)
+		storage.EXPECT().RollbackTx(ctx).Return(nil)
+
+		account, err := banking.NewService(storage).FreezeAccount(ctx, "molly")
+		require.NoError(t, err)
+		assert.Equal(t, entities.FrozenAccount, account.Status)
+	})
+
+	t.Run("rejects status changes", func(t *testing.T) {
+		funded := active
+		funded.Balance = decimal.New(5, 0)
+
+		cases := []struct {
+			title   string
+			change  func(*banking.Service) (entities.Account, error)
+			account entities.Account
+			err     string
+		}{
+			{
+				title:   "of closed account",
+				change:  func(svc *banking.Service) (entities.Account, error) { return svc.UnfreezeAccount(ctx, "molly") },
+				account: closed,
+				err:     "account is closed",
+			},
+			{
+				title:   "closing account with non-zero balance",
+				change:  func(svc *banking.Service) (entities.Account, error) { return svc.CloseAccount(ctx, "molly") },
+				account: funded,
+				err:     "account balance should be zero to close it",
+			},
+			{
+				title:   "closing account with pending holds",
+				change:  func(svc *banking.Service) (entities.Account, error) { return svc.CloseAccount(ctx, "molly") },
+				account: entities.Account{Name: "molly", Type: entities.UserAccount, Status: entities.ActiveAccount, Balance: decimal.New(0, 0), Held: decimal.New(5, 0)},
+				err:     "account should have no pending authorizations to close it",
+			},
+			{
+				title:   "of house account",
+				change:  func(svc *banking.Service) (entities.Account, error) { return svc.FreezeAccount(ctx, "molly") },
+				account: entities.Account{Name: "molly", Type: entities.SystemAccount, Status: entities.ActiveAccount},
+				err:     "status of house accounts can't be changed",
+			},
+		}
+
+		for _, tc := range cases {
+			t.Run(tc.title, func(t *testing.T) {
+				mCtrl := gomock.NewController(t)
+				defer mCtrl.Finish()
+				storage := mocks.NewMockStorage(mCtrl)
+
+				storage.EXPECT().BeginTx(ctx, nil).Return(storage, nil)
+				expectAccountsLocked(storage, tc.account)
+				storage.EXPECT().RollbackTx(ctx).Return(nil)
+
+				_, err := tc.change(banking.NewService(storage))
+				require.Error(t, err)
+				assert.Equal(t, tc.err, err.Error())
+			})
+		}
+	})
+}
@@ -0,0 +1,97 @@
+package banking
+
+import (
+	"github.com/pkg/errors"
+	"github.com/twonegatives/coinsph_challenge/pkg/entities"
+)
+
+const (
+	// DefaultPageLimit is a number of items listed per page unless limit is set explicitly.
+	DefaultPageLimit = 50
+
+	// MaxPageLimit is the largest number of items which may be listed per page.
+	MaxPageLimit = 500
+)
+
+var (
+	errInvalidLimit       = errors.New("limit should be between 1 and 500")
+	errInvalidCursor      = errors.New("cursor is invalid")
+	errInvalidDirection   = errors.New("direction should be either incoming or outgoing")
+	errInvalidKind        = errors.New("kind should be one of transfer, deposit, withdrawal, reversal, refund")
+	errInvalidDateRange   = errors.New("from_date should be earlier than to_date")
+	errInvalidAmountRange = errors.New("min_amount should not exceed max_amount")
+	errInvalidSort        = errors.New("sort should be one of name, balance, created_at")
+	errInvalidOrder       = errors.New("order should be either asc or desc")
+	errCursorOrderChanged = errors.New("cursor was issued for a list in different order")
+)
+
+// validatePaymentsFilter checks the filter for consistency and sets up default limit.
+func validatePaymentsFilter(filter *entities.PaymentsFilter) error {
+	if err := validateLimit(&filter.Limit); err != nil {
+		return err
+	}
+
+	if filter.Direction != "" && filter.Direction != entities.Incoming && filter.Direction != entities.Outgoing {
+		return errInvalidDirection
+	}
+
+	if filter.Kind != "" && !filter.Kind.IsValid() {
+		return errInvalidKind
+	}
+
+	if !filter.FromDate.IsZero() && !filter.ToDate.IsZero() && !filter.FromDate.Before(filter.ToDate) {
+		return errInvalidDateRange
+	}
+
+	if filter.MinAmount.Valid && filter.MaxAmount.Valid && filter.MinAmount.Decimal.GreaterThan(filter.MaxAmount.Decimal) {
+		return errInvalidAmountRange
+	}
+
+	return nil
+}
+
+// validateAccountsFilter checks the filter for consistency and sets up default limit and order.
+func validateAccountsFilter(filter *entities.AccountsFilter) error {
+	if err := validateLimit(&filter.Limit); err != nil {
+		return err
+	}
+
+	switch filter.Sort {
+	case "":
+		filter.Sort = entities.SortAccountsByName
+	case entities.SortAccountsByName, entities.SortAccountsByBalance, entities.SortAccountsByCreatedAt:
+	default:
+		return errInvalidSort
+	}
+
+	if filter.After != nil && (filter.After.Sort != filter.Sort || filter.After.Descending != filter.Descending) {
+		return errCursorOrderChanged
+	}
+
+	return nil
+}
+
+// validateLimit replaces zero limit with the default one and
+// checks that it does not go beyond allowed bounds.
+func validateLimit(limit *int) error {
+	if *limit == 0 {
+		*limit = DefaultPageLimit
+	}
+
+	if *limit < 0 || *limit > MaxPageLimit {
+		return errInvalidLimit
+	}
+
+	return nil
+}
+
+// paymentsPage trims payments fetched with one extra item to the limit
+// and sets up the cursor of the next page if the extra item is present.
+func paymentsPage(payments []entities.Payment, limit int) entities.PaymentsPage {
+	page := entities.PaymentsPage{Payments: payments}
+	if len(payments) > limit {
+		page.Payments = payments[:limit]
+		page.NextCursor = entities.NewPaymentsCursor(payments[limit-1]).Encode()
+	}
+	return page
+}
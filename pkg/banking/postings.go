@@ -0,0 +1,293 @@
+package banking
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+	"github.com/shopspring/decimal"
+	"github.com/twonegatives/coinsph_challenge/pkg/entities"
+	"github.com/twonegatives/coinsph_challenge/pkg/storage"
+)
+
+// maxTransactionLegs limits the number of legs of a single multi-leg transaction
+const maxTransactionLegs = 100
+
+var (
+	errLegsTooFew          = errors.New("transaction should have at least two legs")
+	errLegsTooMany         = errors.New("transaction should not have more than 100 legs")
+	errInvalidLegDirection = errors.New("leg direction should be either incoming or outgoing")
+	errLegAccountRepeated  = errors.New("account can take part in a transaction only once")
+	errLegsUnbalanced      = errors.New("incoming and outgoing legs should sum up to the same amount per currency")
+)
+
+// PostTransaction books a single transaction moving money between any number of accounts,
+// e.g. one payer splitting a purchase between a few merchants. Outgoing legs take money
+// from their accounts, incoming ones put it to theirs. Amounts of the legs are in currencies
+// of their accounts and should sum up to zero per currency. Each outgoing payment is paired
+// with an incoming one, legs are split into as many pairs as it takes to balance them.
+// Accounts of outgoing legs are charged the transfer fee of their amounts on top of them,
+// the same way SendPayment charges senders.
+// Returns error in the following cases:
+// - there are less than two or more than maxTransactionLegs legs
+// - the same account is used by more than one leg
+// - leg amount is not positive or has more decimal places than its currency allows
+// - any account is a house one, is frozen or closed, or is not present in system
+// - legs do not balance per currency
+// - any account of an outgoing leg has insufficient funds to cover its amount along with the fee
+// Errors of a leg are prefixed with its position.
+// Returns a PostingReceipt with the booked transaction on success.
+func (svc *Service) PostTransaction(ctx context.Context, legs []entities.Leg) (entities.PostingReceipt, error) {
+	if len(legs) < 2 {
+		return entities.PostingReceipt{}, errLegsTooFew
+	}
+
+	if len(legs) > maxTransactionLegs {
+		return entities.PostingReceipt{}, errLegsTooMany
+	}
+
+	seen := make(map[string]bool)
+	for index, leg := range legs {
+		if err := validateLeg(leg, seen); err != nil {
+			return entities.PostingReceipt{}, errors.Wrapf(err, "legs[%d]", index)
+		}
+	}
+
+	txStorage, err := svc.store.BeginTx(ctx, nil)
+	if err != nil {
+		return entities.PostingReceipt{}, errors.Wrap(err, "can't open transaction")
+	}
+
+	defer txStorage.RollbackTx(ctx)
+
+	// Accounts of all legs are locked in the same order SendPayment locks its
+	// sender and receiver, so that postings can't deadlock with other transfers
+	accounts := make([]*entities.Account, len(legs))
+	sides := make([]paymentSide, len(legs))
+	for index, leg := range legs {
+		accounts[index] = &entities.Account{Name: leg.Account.Name}
+		sides[index] = paymentSide{account: accounts[index], label: leg.Account.Name}
+	}
+
+	if err := lockAccounts(ctx, txStorage, sides...); err != nil {
+		return entities.PostingReceipt{}, err
+	}
+
+	totals := make(map[entities.Currency]decimal.Decimal)
+	fees := make([]decimal.Decimal, len(legs))
+	for index, leg := range legs {
+		if leg.Direction == entities.Outgoing {
+			if fees[index], err = svc.transferFee(accounts[index].Currency, leg.Amount); err != nil {
+				return entities.PostingReceipt{}, errors.Wrapf(err, "legs[%d]", index)
+			}
+		}
+
+		if err := svc.checkLegAccount(*accounts[index], leg, fees[index]); err != nil {
+			return entities.PostingReceipt{}, errors.Wrapf(err, "legs[%d]", index)
+		}
+
+		if leg.Direction == entities.Outgoing {
+			if err := checkOwnership(ctx, *accounts[index]); err != nil {
+				return entities.PostingReceipt{}, errors.Wrapf(err, "legs[%d]", index)
+			}
+		}
+
+		amount := leg.Amount
+		if leg.Direction == entities.Outgoing {
+			amount = amount.Neg()
+		}
+		totals[accounts[index].Currency] = totals[accounts[index].Currency].Add(amount)
+	}
+
+	for _, total := range totals {
+		if !total.IsZero() {
+			return entities.PostingReceipt{}, errLegsUnbalanced
+		}
+	}
+
+	transaction := entities.Transaction{Kind: entities.TransferTransaction, Fee: postingFee(accounts, fees)}
+	transaction, err = createTransaction(ctx, txStorage, transaction)
+	if err != nil {
+		return entities.PostingReceipt{}, errors.Wrap(err, "can't insert new transaction")
+	}
+
+	booked := []entities.Payment{}
+	for _, pair := range pairLegs(legs, accounts) {
+		payments, err := bookPayments(ctx, txStorage, transaction, pair.from, pair.to, pair.amount)
+		if err != nil {
+			return entities.PostingReceipt{}, err
+		}
+		booked = append(booked, payments...)
+	}
+
+	feePayments, err := bookLegFees(ctx, txStorage, transaction, accounts, fees)
+	if err != nil {
+		return entities.PostingReceipt{}, err
+	}
+	booked = append(booked, feePayments...)
+
+	if err := svc.commitTx(ctx, txStorage); err != nil {
+		return entities.PostingReceipt{}, errors.Wrap(err, "transaction commit failed")
+	}
+
+	receipt := entities.PostingReceipt{Transaction: transaction, Payments: booked}
+	for _, account := range accounts {
+		receipt.Accounts = append(receipt.Accounts, *account)
+	}
+
+	return receipt, nil
+}
+
+// validateLeg makes checks of a leg which need no accounts to be locked.
+// Names of accounts used by the legs checked so far are collected in 'seen'.
+func validateLeg(leg entities.Leg, seen map[string]bool) error {
+	if leg.Account.Name == "" {
+		return errAccountNameBlank
+	}
+
+	if leg.Direction != entities.Outgoing && leg.Direction != entities.Incoming {
+		return errInvalidLegDirection
+	}
+
+	if !leg.Amount.IsPositive() {
+		return errAmountShouldBePositive
+	}
+
+	if seen[leg.Account.Name] {
+		return errLegAccountRepeated
+	}
+	seen[leg.Account.Name] = true
+
+	return nil
+}
+
+// checkLegAccount makes sure the locked account may take part in the leg charged the fee.
+func (svc *Service) checkLegAccount(account entities.Account, leg entities.Leg, fee decimal.Decimal) error {
+	if account.Type.IsHouse() {
+		return errHouseAccountTransfer
+	}
+
+	if err := svc.validateAmount(account.Currency, leg.Amount); err != nil {
+		return err
+	}
+
+	if leg.Direction == entities.Incoming {
+		return checkReceiverActive(account)
+	}
+
+	if err := checkSenderActive(account); err != nil {
+		return err
+	}
+
+	if !account.CanSpend(leg.Amount.Add(fee)) {
+		return errInsufficientFunds
+	}
+
+	return nil
+}
+
+// postingFee returns the total fee charged to outgoing legs when all of them are charged
+// in the same currency. Fees of different currencies don't sum up, so zero is returned
+// for them, while their payments still show every fee charged.
+func postingFee(accounts []*entities.Account, fees []decimal.Decimal) decimal.Decimal {
+	total := decimal.Decimal{}
+	var currency entities.Currency
+	for index, fee := range fees {
+		if !fee.IsPositive() {
+			continue
+		}
+
+		if currency != "" && currency != accounts[index].Currency {
+			return decimal.Decimal{}
+		}
+		currency = accounts[index].Currency
+		total = total.Add(fee)
+	}
+	return total
+}
+
+// bookLegFees books a pair of payments moving the fee of each outgoing leg from its
+// locked account to FEE account of its currency. FEE accounts are locked after accounts
+// of the legs, all at once in the same order as any other accounts, so that postings
+// holding some of them never wait for accounts other than FEE ones. Returns the stored payments.
+func bookLegFees(ctx context.Context, txStorage storage.Storage, transaction entities.Transaction, accounts []*entities.Account, fees []decimal.Decimal) ([]entities.Payment, error) {
+	feeAccounts := make(map[entities.Currency]*entities.Account)
+	sides := []paymentSide{}
+	for index, fee := range fees {
+		currency := accounts[index].Currency
+		if fee.IsPositive() && feeAccounts[currency] == nil {
+			feeAccounts[currency] = &entities.Account{Name: entities.FeeAccountName(currency)}
+			sides = append(sides, paymentSide{account: feeAccounts[currency], label: "fee"})
+		}
+	}
+
+	if err := lockAccounts(ctx, txStorage, sides...); err != nil {
+		return nil, err
+	}
+
+	booked := []entities.Payment{}
+	for index, fee := range fees {
+		if !fee.IsPositive() {
+			continue
+		}
+
+		payments, err := bookPayments(ctx, txStorage, transaction, accounts[index], feeAccounts[accounts[index].Currency], fee)
+		if err != nil {
+			return nil, errors.Wrap(err, "can't book fee payments")
+		}
+		booked = append(booked, payments...)
+	}
+	return booked, nil
+}
+
+// legPair is a money move between two accounts booked as a pair of payments
+type legPair struct {
+	from   *entities.Account
+	to     *entities.Account
+	amount decimal.Decimal
+}
+
+// pairLegs matches outgoing legs with incoming ones of the same currency in order of
+// the legs. A leg is split between a few pairs when its amount exceeds the one of its match,
+// e.g. outgoing 100 against incoming 50, 30 and 20 gives three pairs.
+// Legs are expected to balance per currency.
+func pairLegs(legs []entities.Leg, accounts []*entities.Account) []legPair {
+	currencies := []entities.Currency{}
+	outgoing := make(map[entities.Currency][]int)
+	incoming := make(map[entities.Currency][]int)
+	remaining := make([]decimal.Decimal, len(legs))
+	for index, leg := range legs {
+		currency := accounts[index].Currency
+		if _, ok := outgoing[currency]; !ok {
+			currencies = append(currencies, currency)
+			outgoing[currency], incoming[currency] = []int{}, []int{}
+		}
+
+		if leg.Direction == entities.Outgoing {
+			outgoing[currency] = append(outgoing[currency], index)
+		} else {
+			incoming[currency] = append(incoming[currency], index)
+		}
+		remaining[index] = leg.Amount
+	}
+
+	pairs := []legPair{}
+	for _, currency := range currencies {
+		debits, credits := outgoing[currency], incoming[currency]
+		for i, j := 0, 0; i < len(debits) && j < len(credits); {
+			debit, credit := debits[i], credits[j]
+			amount := decimal.Min(remaining[debit], remaining[credit])
+			pairs = append(pairs, legPair{from: accounts[debit], to: accounts[credit], amount: amount})
+
+			remaining[debit] = remaining[debit].Sub(amount)
+			remaining[credit] = remaining[credit].Sub(amount)
+			if remaining[debit].IsZero() {
+				i++
+			}
+			if remaining[credit].IsZero() {
+				j++
+			}
+		}
+	}
+
+	return pairs
+}
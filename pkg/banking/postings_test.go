@@ -0,0 +1,214 @@
+package banking_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/twonegatives/coinsph_challenge/pkg/banking"
+	"github.com/twonegatives/coinsph_challenge/pkg/entities"
+	"github.com/twonegatives/coinsph_challenge/pkg/memstorage"
+	"github.com/twonegatives/coinsph_challenge/pkg/mocks"
+)
+
+func TestBankingSvcPostTransaction(t *testing.T) {
+	payer := entities.Account{Name: "payer", Balance: decimal.New(100, 0), Currency: entities.USD}
+	shop := entities.Account{Name: "shop", Balance: decimal.New(0, 0), Currency: entities.USD}
+	courier := entities.Account{Name: "courier", Balance: decimal.New(0, 0), Currency: entities.USD}
+	platform := entities.Account{Name: "platform", Balance: decimal.New(0, 0), Currency: entities.USD}
+
+	leg := func(account entities.Account, direction entities.Direction, amount int64) entities.Leg {
+		return entities.Leg{Account: entities.Account{Name: account.Name}, Direction: direction, Amount: decimal.New(amount, 0)}
+	}
+
+	t.Run("splits a payment between a few receivers", func(t *testing.T) {
+		mCtrl := gomock.NewController(t)
+		defer mCtrl.Finish()
+		storage := mocks.NewMockStorage(mCtrl)
+
+		storage.EXPECT().BeginTx(ctx, nil).Return(storage, nil)
+		storage.EXPECT().RollbackTx(ctx)
+		expectAccountsLocked(storage, payer, shop, courier, platform)
+		storage.EXPECT().CreateTransaction(ctx, entities.Transaction{Kind: entities.TransferTransaction}).Return(entities.Transaction{ID: 31}, nil)
+
+		moves := []string{}
+		storage.EXPECT().SendPayment(ctx, gomock.Any()).Times(6).DoAndReturn(
+			func(_ context.Context, payment entities.Payment) error {
+				if payment.Direction == entities.Outgoing {
+					moves = append(moves, payment.Account.Name+">"+payment.Counterparty.Name+":"+payment.Amount.String())
+				}
+				return nil
+			},
+		)
+		storage.EXPECT().SetAccountBalance(ctx, gomock.Any()).Times(6)
+		storage.EXPECT().CommitTx(ctx)
+
+		receipt, err := banking.NewService(storage).PostTransaction(ctx, []entities.Leg{
+			leg(payer, entities.Outgoing, 100),
+			leg(shop, entities.Incoming, 70),
+			leg(courier, entities.Incoming, 20),
+			leg(platform, entities.Incoming, 10),
+		})
+		require.NoError(t, err)
+
+		assert.Equal(t, 31, receipt.Transaction.ID)
+		assert.Len(t, receipt.Payments, 6)
+		assert.Equal(t, []string{"payer>shop:70", "payer>courier:20", "payer>platform:10"}, moves)
+		require.Len(t, receipt.Accounts, 4)
+		assert.Equal(t, "0", receipt.Accounts[0].Balance.String())
+		assert.Equal(t, "70", receipt.Accounts[1].Balance.String())
+	})
+
+	t.Run("fails for legs which do not balance", func(t *testing.T) {
+		mCtrl := gomock.NewController(t)
+		defer mCtrl.Finish()
+		storage := mocks.NewMockStorage(mCtrl)
+
+		storage.EXPECT().BeginTx(ctx, nil).Return(storage, nil)
+		storage.EXPECT().RollbackTx(ctx)
+		expectAccountsLocked(storage, payer, shop, courier)
+
+		_, err := banking.NewService(storage).PostTransaction(ctx, []entities.Leg{
+			leg(payer, entities.Outgoing, 100),
+			leg(shop, entities.Incoming, 70),
+			leg(courier, entities.Incoming, 20),
+		})
+		assert.EqualError(t, err, "incoming and outgoing legs should sum up to the same amount per currency")
+	})
+
+	t.Run("fails for the leg its account can't afford", func(t *testing.T) {
+		mCtrl := gomock.NewController(t)
+		defer mCtrl.Finish()
+		storage := mocks.NewMockStorage(mCtrl)
+
+		storage.EXPECT().BeginTx(ctx, nil).Return(storage, nil)
+		storage.EXPECT().RollbackTx(ctx)
+		expectAccountsLocked(storage, shop, payer, courier)
+
+		_, err := banking.NewService(storage).PostTransaction(ctx, []entities.Leg{
+			leg(shop, entities.Incoming, 150),
+			leg(payer, entities.Outgoing, 120),
+			leg(courier, entities.Outgoing, 30),
+		})
+		assert.EqualError(t, err, "legs[1]: sender account has insufficient funds")
+	})
+
+	t.Run("validates legs before locking accounts", func(t *testing.T) {
+		mCtrl := gomock.NewController(t)
+		defer mCtrl.Finish()
+		svc := banking.NewService(mocks.NewMockStorage(mCtrl))
+
+		_, err := svc.PostTransaction(ctx, []entities.Leg{leg(payer, entities.Outgoing, 10)})
+		assert.EqualError(t, err, "transaction should have at least two legs")
+
+		_, err = svc.PostTransaction(ctx, make([]entities.Leg, 101))
+		assert.EqualError(t, err, "transaction should not have more than 100 legs")
+
+		_, err = svc.PostTransaction(ctx, []entities.Leg{leg(payer, entities.Outgoing, 10), leg(shop, "sideways", 10)})
+		assert.EqualError(t, err, "legs[1]: leg direction should be either incoming or outgoing")
+
+		_, err = svc.PostTransaction(ctx, []entities.Leg{leg(payer, entities.Outgoing, 10), leg(shop, entities.Incoming, 0)})
+		assert.EqualError(t, err, "legs[1]: amount transferred should be a positive number")
+
+		_, err = svc.PostTransaction(ctx, []entities.Leg{leg(payer, entities.Outgoing, 10), leg(payer, entities.Incoming, 10)})
+		assert.EqualError(t, err, "legs[1]: account can take part in a transaction only once")
+	})
+
+	t.Run("books legs of different currencies", func(t *testing.T) {
+		storage := memstorage.NewMemStorage()
+		svc := banking.NewService(storage)
+		for _, account := range []entities.Account{{Name: "ann", Currency: entities.USD}, {Name: "ben", Currency: entities.USD}, {Name: "cid", Currency: entities.PHP}, {Name: "dan", Currency: entities.PHP}} {
+			_, err := svc.CreateAccount(ctx, account.Name, account.Currency, entities.AccountProfile{})
+			require.NoError(t, err)
+		}
+		_, err := svc.Deposit(ctx, "ann", decimal.New(10, 0), "wire-1")
+		require.NoError(t, err)
+		_, err = svc.Deposit(ctx, "cid", decimal.New(500, 0), "wire-2")
+		require.NoError(t, err)
+
+		receipt, err := svc.PostTransaction(ctx, []entities.Leg{
+			{Account: entities.Account{Name: "ann"}, Direction: entities.Outgoing, Amount: decimal.New(10, 0)},
+			{Account: entities.Account{Name: "cid"}, Direction: entities.Outgoing, Amount: decimal.New(500, 0)},
+			{Account: entities.Account{Name: "ben"}, Direction: entities.Incoming, Amount: decimal.New(10, 0)},
+			{Account: entities.Account{Name: "dan"}, Direction: entities.Incoming, Amount: decimal.New(500, 0)},
+		})
+		require.NoError(t, err)
+		assert.Len(t, receipt.Payments, 4)
+
+		_, payments, err := svc.GetTransaction(ctx, receipt.Transaction.ID)
+		require.NoError(t, err)
+		assert.Len(t, payments, 4)
+
+		_, err = svc.RefundTransaction(ctx, receipt.Transaction.ID, decimal.New(1, 0))
+		assert.EqualError(t, err, "transfers between different currencies can only be reversed in full")
+
+		_, err = svc.ReverseTransaction(ctx, receipt.Transaction.ID)
+		require.NoError(t, err)
+
+		ann, err := svc.GetAccount(ctx, "ann")
+		require.NoError(t, err)
+		assert.Equal(t, "10", ann.Balance.String())
+	})
+
+	t.Run("charges outgoing legs the transfer fee", func(t *testing.T) {
+		svc := banking.NewService(memstorage.NewMemStorage(), banking.WithFeeSchedule(newFeeSchedule()))
+		for _, name := range []string{"payer", "shop", "courier"} {
+			_, err := svc.CreateAccount(ctx, name, entities.USD, entities.AccountProfile{})
+			require.NoError(t, err)
+		}
+		_, err := svc.Deposit(ctx, "payer", decimal.New(20, 0), "wire-4")
+		require.NoError(t, err)
+
+		legs := []entities.Leg{
+			{Account: entities.Account{Name: "payer"}, Direction: entities.Outgoing, Amount: decimal.New(20, 0)},
+			{Account: entities.Account{Name: "shop"}, Direction: entities.Incoming, Amount: decimal.New(15, 0)},
+			{Account: entities.Account{Name: "courier"}, Direction: entities.Incoming, Amount: decimal.New(5, 0)},
+		}
+		_, err = svc.PostTransaction(ctx, legs)
+		assert.EqualError(t, err, "legs[0]: sender account has insufficient funds")
+
+		_, err = svc.Deposit(ctx, "payer", decimal.New(1, 0), "wire-5")
+		require.NoError(t, err)
+
+		receipt, err := svc.PostTransaction(ctx, legs)
+		require.NoError(t, err)
+		assert.Equal(t, "0.45", receipt.Transaction.Fee.String())
+		require.Len(t, receipt.Payments, 6)
+		assert.Equal(t, "FEE_USD", receipt.Payments[4].Counterparty.Name)
+		assert.Equal(t, "0.55", receipt.Accounts[0].Balance.String())
+
+		feeAccount, err := svc.GetAccount(ctx, "FEE_USD")
+		require.NoError(t, err)
+		assert.Equal(t, "0.45", feeAccount.Balance.String())
+
+		_, err = svc.ReverseTransaction(ctx, receipt.Transaction.ID)
+		require.NoError(t, err)
+
+		payer, err := svc.GetAccount(ctx, "payer")
+		require.NoError(t, err)
+		assert.Equal(t, "21", payer.Balance.String())
+	})
+}
+
+func TestBankingSvcRefundSplitTransaction(t *testing.T) {
+	svc := banking.NewService(memstorage.NewMemStorage())
+	for _, name := range []string{"payer", "shop", "courier"} {
+		_, err := svc.CreateAccount(ctx, name, entities.USD, entities.AccountProfile{})
+		require.NoError(t, err)
+	}
+	_, err := svc.Deposit(ctx, "payer", decimal.New(30, 0), "wire-3")
+	require.NoError(t, err)
+
+	receipt, err := svc.PostTransaction(ctx, []entities.Leg{
+		{Account: entities.Account{Name: "payer"}, Direction: entities.Outgoing, Amount: decimal.New(30, 0)},
+		{Account: entities.Account{Name: "shop"}, Direction: entities.Incoming, Amount: decimal.New(25, 0)},
+		{Account: entities.Account{Name: "courier"}, Direction: entities.Incoming, Amount: decimal.New(5, 0)},
+	})
+	require.NoError(t, err)
+
+	_, err = svc.RefundTransaction(ctx, receipt.Transaction.ID, decimal.New(5, 0))
+	assert.EqualError(t, err, "multi-leg transactions can only be reversed in full")
+}
@@ -0,0 +1,58 @@
+package banking
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+	"github.com/twonegatives/coinsph_challenge/pkg/entities"
+)
+
+var errNotAccountOwner = errors.New("account belongs to another owner")
+
+type principalContextKey struct{}
+
+// ContextWithPrincipal returns a copy of the context carrying the authenticated caller.
+func ContextWithPrincipal(ctx context.Context, principal entities.Principal) context.Context {
+	return context.WithValue(ctx, principalContextKey{}, principal)
+}
+
+// PrincipalFromContext returns the authenticated caller carried by the context, if any.
+func PrincipalFromContext(ctx context.Context) (entities.Principal, bool) {
+	principal, ok := ctx.Value(principalContextKey{}).(entities.Principal)
+	return principal, ok
+}
+
+// checkOwnership makes sure the caller owns at least one of the accounts.
+// Calls made without a principal come from the service itself (e.g. its workers)
+// or from a handler serving anonymous callers, so they are not restricted.
+func checkOwnership(ctx context.Context, accounts ...entities.Account) error {
+	principal, ok := PrincipalFromContext(ctx)
+	if !ok {
+		return nil
+	}
+
+	for _, account := range accounts {
+		if principal.Owns(account) {
+			return nil
+		}
+	}
+	return errNotAccountOwner
+}
+
+// ownedProfile makes sure callers other than operators and admins open
+// accounts for their own owner only. Blank owner is filled in with the caller's one.
+func ownedProfile(ctx context.Context, profile entities.AccountProfile) (entities.AccountProfile, error) {
+	principal, ok := PrincipalFromContext(ctx)
+	if !ok || principal.Role.Permits(entities.OperatorRole) {
+		return profile, nil
+	}
+
+	if profile.OwnerID == "" {
+		profile.OwnerID = principal.OwnerID
+	}
+
+	if principal.OwnerID == "" || profile.OwnerID != principal.OwnerID {
+		return entities.AccountProfile{}, errNotAccountOwner
+	}
+	return profile, nil
+}
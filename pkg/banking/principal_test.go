@@ -0,0 +1,104 @@
+package banking_test
+
+import (
+	"testing"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/twonegatives/coinsph_challenge/pkg/banking"
+	"github.com/twonegatives/coinsph_challenge/pkg/entities"
+	"github.com/twonegatives/coinsph_challenge/pkg/memstorage"
+)
+
+func TestBankingSvcAccountOwnership(t *testing.T) {
+	alice := banking.ContextWithPrincipal(ctx, entities.Principal{Subject: "ak_alice", Role: entities.AccountOwnerRole, OwnerID: "customer-1"})
+	admin := banking.ContextWithPrincipal(ctx, entities.Principal{Subject: "ak_admin", Role: entities.AdminRole})
+
+	// setup returns a service with funded 'alice' account of customer-1 and 'bob' account of customer-2
+	setup := func(t *testing.T) *banking.Service {
+		svc := banking.NewService(memstorage.NewMemStorage())
+		for name, owner := range map[string]string{"alice": "customer-1", "bob": "customer-2"} {
+			_, err := svc.CreateAccount(ctx, name, entities.USD, entities.AccountProfile{OwnerID: owner})
+			require.NoError(t, err)
+
+			_, err = svc.Deposit(ctx, name, decimal.New(100, 0), "wire-"+name)
+			require.NoError(t, err)
+		}
+		return svc
+	}
+
+	t.Run("lets owners send from their accounts only", func(t *testing.T) {
+		svc := setup(t)
+		_, err := svc.SendPayment(alice, entities.Account{Name: "alice"}, entities.Account{Name: "bob"}, decimal.New(1, 0), entities.PaymentDetails{})
+		assert.NoError(t, err)
+
+		_, err = svc.SendPayment(alice, entities.Account{Name: "bob"}, entities.Account{Name: "alice"}, decimal.New(1, 0), entities.PaymentDetails{})
+		assert.EqualError(t, err, "account belongs to another owner")
+
+		_, err = svc.Authorize(alice, entities.Account{Name: "bob"}, entities.Account{Name: "alice"}, decimal.New(1, 0))
+		assert.EqualError(t, err, "account belongs to another owner")
+
+		_, err = svc.PostTransaction(alice, []entities.Leg{
+			{Account: entities.Account{Name: "bob"}, Direction: entities.Outgoing, Amount: decimal.New(1, 0)},
+			{Account: entities.Account{Name: "alice"}, Direction: entities.Incoming, Amount: decimal.New(1, 0)},
+		})
+		assert.EqualError(t, err, "legs[0]: account belongs to another owner")
+
+		_, err = svc.CreateScheduledPayment(alice, entities.ScheduledPayment{
+			Account:      entities.Account{Name: "bob"},
+			Counterparty: entities.Account{Name: "alice"},
+			Amount:       decimal.New(1, 0),
+			Frequency:    entities.DailyFrequency,
+		})
+		assert.EqualError(t, err, "account belongs to another owner")
+
+		bob, err := svc.GetAccount(ctx, "bob")
+		require.NoError(t, err)
+		assert.Equal(t, "101", bob.Balance.String())
+	})
+
+	t.Run("lets admins send from any account", func(t *testing.T) {
+		svc := setup(t)
+		_, err := svc.SendPayment(admin, entities.Account{Name: "bob"}, entities.Account{Name: "alice"}, decimal.New(1, 0), entities.PaymentDetails{})
+		assert.NoError(t, err)
+	})
+
+	t.Run("lets owners settle holds placed on or for their accounts", func(t *testing.T) {
+		svc := setup(t)
+		hold, err := svc.Authorize(ctx, entities.Account{Name: "bob"}, entities.Account{Name: "alice"}, decimal.New(5, 0))
+		require.NoError(t, err)
+
+		_, err = svc.CaptureHold(alice, hold.ID, decimal.Zero)
+		assert.NoError(t, err)
+
+		_, err = svc.CreateAccount(ctx, "carol", entities.USD, entities.AccountProfile{OwnerID: "customer-3"})
+		require.NoError(t, err)
+
+		hold, err = svc.Authorize(ctx, entities.Account{Name: "bob"}, entities.Account{Name: "carol"}, decimal.New(5, 0))
+		require.NoError(t, err)
+
+		_, err = svc.VoidHold(alice, hold.ID)
+		assert.EqualError(t, err, "account belongs to another owner")
+	})
+
+	t.Run("keeps owners of accounts their callers", func(t *testing.T) {
+		svc := setup(t)
+		account, err := svc.CreateAccount(alice, "alice-savings", entities.USD, entities.AccountProfile{})
+		require.NoError(t, err)
+		assert.Equal(t, "customer-1", account.OwnerID)
+
+		_, err = svc.CreateAccount(alice, "mallory", entities.USD, entities.AccountProfile{OwnerID: "customer-2"})
+		assert.EqualError(t, err, "account belongs to another owner")
+
+		name, other := "Savings", "customer-2"
+		_, err = svc.UpdateAccount(alice, "alice-savings", entities.AccountUpdate{DisplayName: &name})
+		assert.NoError(t, err)
+
+		_, err = svc.UpdateAccount(alice, "bob", entities.AccountUpdate{DisplayName: &name})
+		assert.EqualError(t, err, "account belongs to another owner")
+
+		_, err = svc.UpdateAccount(alice, "alice-savings", entities.AccountUpdate{OwnerID: &other})
+		assert.EqualError(t, err, "account belongs to another owner")
+	})
+}
@@ -0,0 +1,34 @@
+package banking
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+var (
+	errUnauthorized = errors.New("operator token is missing")
+	errForbidden    = errors.New("operator token is invalid")
+)
+
+// privileged wraps a handler so that it is served to callers presenting
+// the operator token as 'Authorization: Bearer <token>' header only.
+// Routes wrapped are not served at all unless the token is configured.
+func privileged(token string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		presented := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if presented == "" {
+			errorEncoder(r.Context(), errUnauthorized, w)
+			return
+		}
+
+		if token == "" || subtle.ConstantTimeCompare([]byte(presented), []byte(token)) != 1 {
+			errorEncoder(r.Context(), errForbidden, w)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
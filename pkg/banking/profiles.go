@@ -0,0 +1,127 @@
+package banking
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/pkg/errors"
+	"github.com/twonegatives/coinsph_challenge/pkg/entities"
+)
+
+const (
+	maxOwnerIDLength     = 64
+	maxDisplayNameLength = 100
+	maxAccountLabels     = 20
+	maxLabelLength       = 40
+)
+
+var (
+	errOwnerIDTooLong      = errors.Errorf("owner id should not be longer than %d characters", maxOwnerIDLength)
+	errDisplayNameTooLong  = errors.Errorf("display name should not be longer than %d characters", maxDisplayNameLength)
+	errTooManyLabels       = errors.Errorf("account should not have more than %d labels", maxAccountLabels)
+	errInvalidLabel        = errors.Errorf("labels should be 1 to %d characters long", maxLabelLength)
+	errHouseAccountProfile = errors.New("profile of house accounts can't be changed")
+)
+
+// UpdateAccount changes owner, display name or labels of a user account.
+// Attributes missing from the update are left as they are. Closed accounts
+// may be updated too, as the profile takes no part in bookkeeping.
+// Returns the updated Account on success.
+func (svc *Service) UpdateAccount(ctx context.Context, accountName string, update entities.AccountUpdate) (entities.Account, error) {
+	if accountName == "" {
+		return entities.Account{}, errAccountNameBlank
+	}
+
+	txStorage, err := svc.store.BeginTx(ctx, nil)
+	if err != nil {
+		return entities.Account{}, errors.Wrap(err, "can't open transaction")
+	}
+
+	defer txStorage.RollbackTx(ctx)
+
+	account := entities.Account{Name: accountName}
+	if err := txStorage.GetAccountForUpdate(ctx, &account); err != nil {
+		if errors.Cause(err) == sql.ErrNoRows {
+			return entities.Account{}, errAccountNotFound
+		}
+		return entities.Account{}, errors.Wrap(err, "can't obtain account")
+	}
+
+	if account.Type.IsHouse() {
+		return entities.Account{}, errHouseAccountProfile
+	}
+
+	if err := checkOwnership(ctx, account); err != nil {
+		return entities.Account{}, err
+	}
+
+	profile := account.Profile()
+	if update.OwnerID != nil {
+		profile.OwnerID = *update.OwnerID
+	}
+
+	if update.DisplayName != nil {
+		profile.DisplayName = *update.DisplayName
+	}
+
+	if update.Labels != nil {
+		profile.Labels = update.Labels
+	}
+
+	if profile, err = normalizeAccountProfile(profile); err != nil {
+		return entities.Account{}, err
+	}
+
+	// owners may not hand their accounts over to somebody else
+	account = account.WithProfile(profile)
+	if err := checkOwnership(ctx, account); err != nil {
+		return entities.Account{}, err
+	}
+
+	if err := txStorage.SetAccountProfile(ctx, account); err != nil {
+		return entities.Account{}, err
+	}
+
+	if err := svc.commitTx(ctx, txStorage); err != nil {
+		return entities.Account{}, errors.Wrap(err, "transaction commit failed")
+	}
+
+	return account, nil
+}
+
+// normalizeAccountProfile trims the profile attributes, drops duplicate labels
+// and checks the attributes fit their limits.
+func normalizeAccountProfile(profile entities.AccountProfile) (entities.AccountProfile, error) {
+	profile.OwnerID = strings.TrimSpace(profile.OwnerID)
+	if utf8.RuneCountInString(profile.OwnerID) > maxOwnerIDLength {
+		return entities.AccountProfile{}, errOwnerIDTooLong
+	}
+
+	profile.DisplayName = strings.TrimSpace(profile.DisplayName)
+	if utf8.RuneCountInString(profile.DisplayName) > maxDisplayNameLength {
+		return entities.AccountProfile{}, errDisplayNameTooLong
+	}
+
+	var labels []string
+	seen := map[string]bool{}
+	for _, label := range profile.Labels {
+		label = strings.TrimSpace(label)
+		if length := utf8.RuneCountInString(label); length == 0 || length > maxLabelLength {
+			return entities.AccountProfile{}, errInvalidLabel
+		}
+
+		if !seen[label] {
+			seen[label] = true
+			labels = append(labels, label)
+		}
+	}
+
+	if len(labels) > maxAccountLabels {
+		return entities.AccountProfile{}, errTooManyLabels
+	}
+
+	profile.Labels = labels
+	return profile, nil
+}
@@ -0,0 +1,93 @@
+package banking_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/twonegatives/coinsph_challenge/pkg/banking"
+	"github.com/twonegatives/coinsph_challenge/pkg/entities"
+	"github.com/twonegatives/coinsph_challenge/pkg/memstorage"
+)
+
+func TestBankingSvcAccountProfiles(t *testing.T) {
+	text := func(value string) *string {
+		return &value
+	}
+
+	t.Run("creates account with normalized profile", func(t *testing.T) {
+		svc := banking.NewService(memstorage.NewMemStorage())
+		account, err := svc.CreateAccount(ctx, "alice", entities.USD, entities.AccountProfile{
+			OwnerID:     " customer-1 ",
+			DisplayName: "Alice's wallet",
+			Labels:      []string{"personal", " vip", "personal"},
+		})
+		require.NoError(t, err)
+		assert.Equal(t, "customer-1", account.OwnerID)
+		assert.Equal(t, "Alice's wallet", account.DisplayName)
+		assert.Equal(t, []string{"personal", "vip"}, account.Labels)
+
+		page, err := svc.GetAccountsList(ctx, entities.AccountsFilter{OwnerID: "customer-1"})
+		require.NoError(t, err)
+		require.Len(t, page.Accounts, 1)
+		assert.Equal(t, "alice", page.Accounts[0].Name)
+	})
+
+	t.Run("updates only attributes present in the update", func(t *testing.T) {
+		svc := banking.NewService(memstorage.NewMemStorage())
+		_, err := svc.CreateAccount(ctx, "alice", entities.USD, entities.AccountProfile{OwnerID: "customer-1", Labels: []string{"personal"}})
+		require.NoError(t, err)
+
+		updated, err := svc.UpdateAccount(ctx, "alice", entities.AccountUpdate{DisplayName: text("Travel money")})
+		require.NoError(t, err)
+		assert.Equal(t, "customer-1", updated.OwnerID)
+		assert.Equal(t, "Travel money", updated.DisplayName)
+		assert.Equal(t, []string{"personal"}, updated.Labels)
+
+		updated, err = svc.UpdateAccount(ctx, "alice", entities.AccountUpdate{OwnerID: text("customer-2"), Labels: []string{}})
+		require.NoError(t, err)
+
+		stored, err := svc.GetAccount(ctx, "alice")
+		require.NoError(t, err)
+		assert.Equal(t, updated, stored)
+		assert.Equal(t, "customer-2", stored.OwnerID)
+		assert.Equal(t, "Travel money", stored.DisplayName)
+		assert.Empty(t, stored.Labels)
+	})
+
+	t.Run("refuses to update unknown and house accounts", func(t *testing.T) {
+		svc := banking.NewService(memstorage.NewMemStorage())
+
+		_, err := svc.UpdateAccount(ctx, "nobody", entities.AccountUpdate{DisplayName: text("Nobody")})
+		assert.EqualError(t, err, "account not found")
+
+		_, err = svc.UpdateAccount(ctx, "SYSTEM", entities.AccountUpdate{DisplayName: text("Bank")})
+		assert.EqualError(t, err, "profile of house accounts can't be changed")
+	})
+
+	t.Run("validates profile", func(t *testing.T) {
+		svc := banking.NewService(memstorage.NewMemStorage())
+		_, err := svc.CreateAccount(ctx, "alice", entities.USD, entities.AccountProfile{})
+		require.NoError(t, err)
+
+		tooManyLabels := strings.Split("abcdefghijklmnopqrstu", "")
+		cases := []struct {
+			update entities.AccountUpdate
+			err    string
+		}{
+			{update: entities.AccountUpdate{OwnerID: text(strings.Repeat("o", 65))}, err: "owner id should not be longer than 64 characters"},
+			{update: entities.AccountUpdate{DisplayName: text(strings.Repeat("d", 101))}, err: "display name should not be longer than 100 characters"},
+			{update: entities.AccountUpdate{Labels: tooManyLabels}, err: "account should not have more than 20 labels"},
+			{update: entities.AccountUpdate{Labels: []string{" "}}, err: "labels should be 1 to 40 characters long"},
+		}
+
+		for _, tc := range cases {
+			_, err := svc.UpdateAccount(ctx, "alice", tc.update)
+			assert.EqualError(t, err, tc.err)
+		}
+
+		_, err = svc.CreateAccount(ctx, "bob", entities.USD, entities.AccountProfile{Labels: []string{strings.Repeat("l", 41)}})
+		assert.EqualError(t, err, "labels should be 1 to 40 characters long")
+	})
+}
@@ -0,0 +1,213 @@
+package banking
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+	"github.com/shopspring/decimal"
+	"github.com/twonegatives/coinsph_challenge/pkg/entities"
+	"github.com/twonegatives/coinsph_challenge/pkg/storage"
+)
+
+var (
+	errTransactionNotRefundable = errors.New("only transfers can be reversed or refunded")
+	errPartialRefundFX          = errors.New("transfers between different currencies can only be reversed in full")
+	errPartialRefundSplit       = errors.New("multi-leg transactions can only be reversed in full")
+	errTransactionReversed      = errors.New("transaction has already been reversed")
+	errTransactionRefunded      = errors.New("transaction has already been partially refunded, refund the remaining amount instead")
+	errRefundExceedsOriginal    = errors.New("refunds can't exceed the original transfer amount")
+)
+
+// ReverseTransaction undoes a transfer in full by booking a reversal transaction,
+// which moves every payment of the original one the other way round, the fee included.
+// Returns an error if:
+// - transaction is not present in system or it is not a transfer
+// - transaction has already been reversed or refunded
+// - any of the reasons SendPayment fails with (e.g. receiver has insufficient funds by now)
+// Returns a TransferReceipt with the reversal transaction on success.
+func (svc *Service) ReverseTransaction(ctx context.Context, id int) (entities.TransferReceipt, error) {
+	return svc.compensate(ctx, id, entities.ReversalTransaction, decimal.Decimal{})
+}
+
+// RefundTransaction returns 'amount' of a transfer from its receiver back to its sender
+// by booking a refund transaction. A transfer may be refunded a few times as long as
+// refunds do not exceed its amount. The fee charged by the transfer is not returned.
+// Transfers between different currencies and multi-leg transactions can't be refunded.
+// Returns a TransferReceipt with the refund transaction on success.
+func (svc *Service) RefundTransaction(ctx context.Context, id int, amount decimal.Decimal) (entities.TransferReceipt, error) {
+	if !amount.IsPositive() {
+		return entities.TransferReceipt{}, errAmountShouldBePositive
+	}
+
+	return svc.compensate(ctx, id, entities.RefundTransaction, amount)
+}
+
+// compensate books a transaction of the given compensating kind linked to the original one.
+// Reversals move every payment back in full, refunds move back 'amount' of the single one.
+func (svc *Service) compensate(ctx context.Context, id int, kind entities.TransactionKind, amount decimal.Decimal) (entities.TransferReceipt, error) {
+	original, payments, err := svc.GetTransaction(ctx, id)
+	if err != nil {
+		return entities.TransferReceipt{}, err
+	}
+
+	if original.Kind != entities.TransferTransaction {
+		return entities.TransferReceipt{}, errTransactionNotRefundable
+	}
+
+	// every outgoing payment stands for a money move from its account to counterparty,
+	// refunds leave the fee leg alone
+	legs := []entities.Payment{}
+	for _, payment := range payments {
+		if payment.Direction != entities.Outgoing {
+			continue
+		}
+
+		if kind == entities.RefundTransaction && isFeePayment(payment) {
+			continue
+		}
+
+		legs = append(legs, payment)
+	}
+
+	if kind == entities.RefundTransaction {
+		if len(legs) != 1 {
+			return entities.TransferReceipt{}, partialRefundError(legs)
+		}
+
+		if err := svc.validateAmount(legs[0].Currency, amount); err != nil {
+			return entities.TransferReceipt{}, err
+		}
+	}
+
+	txStorage, err := svc.store.BeginTx(ctx, nil)
+	if err != nil {
+		return entities.TransferReceipt{}, errors.Wrap(err, "can't open transaction")
+	}
+
+	defer txStorage.RollbackTx(ctx)
+
+	// Locking accounts of the original transaction makes concurrent
+	// compensations of it wait for each other. FEE account is locked
+	// the last one, the same way payments charging fees do it.
+	accounts := make(map[string]*entities.Account)
+	sides, feeSides := []paymentSide{}, []paymentSide{}
+	for _, leg := range legs {
+		for _, name := range []string{leg.Account.Name, leg.Counterparty.Name} {
+			if _, ok := accounts[name]; ok {
+				continue
+			}
+
+			accounts[name] = &entities.Account{Name: name}
+			if isFeePayment(leg) && name == leg.Counterparty.Name {
+				feeSides = append(feeSides, paymentSide{account: accounts[name], label: name})
+			} else {
+				sides = append(sides, paymentSide{account: accounts[name], label: name})
+			}
+		}
+	}
+
+	if err := lockAccounts(ctx, txStorage, sides...); err != nil {
+		return entities.TransferReceipt{}, err
+	}
+
+	if err := lockAccounts(ctx, txStorage, feeSides...); err != nil {
+		return entities.TransferReceipt{}, err
+	}
+
+	linked, err := txStorage.GetLinkedTransactions(ctx, id)
+	if err != nil {
+		return entities.TransferReceipt{}, errors.Wrap(err, "can't obtain linked transactions")
+	}
+
+	for _, compensation := range linked {
+		if compensation.Kind == entities.ReversalTransaction {
+			return entities.TransferReceipt{}, errTransactionReversed
+		}
+	}
+
+	if kind == entities.ReversalTransaction && len(linked) > 0 {
+		return entities.TransferReceipt{}, errTransactionRefunded
+	}
+
+	if kind == entities.RefundTransaction {
+		refunded, err := refundedAmount(ctx, txStorage, linked)
+		if err != nil {
+			return entities.TransferReceipt{}, err
+		}
+
+		if refunded.Add(amount).GreaterThan(legs[0].Amount) {
+			return entities.TransferReceipt{}, errRefundExceedsOriginal
+		}
+	}
+
+	for _, leg := range legs {
+		if err := checkAccountsActive(*accounts[leg.Counterparty.Name], *accounts[leg.Account.Name]); err != nil {
+			return entities.TransferReceipt{}, err
+		}
+	}
+
+	transaction, err := createTransaction(ctx, txStorage, entities.Transaction{Kind: kind, OriginalID: id})
+	if err != nil {
+		return entities.TransferReceipt{}, errors.Wrap(err, "can't insert new transaction")
+	}
+
+	// legs are moved back in reverse order, e.g. receiver pays FX account back
+	// prior to FX account paying sender back
+	booked := []entities.Payment{}
+	for i := len(legs) - 1; i >= 0; i-- {
+		from, to := accounts[legs[i].Counterparty.Name], accounts[legs[i].Account.Name]
+		legAmount := amount
+		if kind == entities.ReversalTransaction {
+			legAmount = legs[i].Amount
+		}
+
+		if !from.CanSpend(legAmount) {
+			return entities.TransferReceipt{}, errInsufficientFunds
+		}
+
+		legPayments, err := bookPayments(ctx, txStorage, transaction, from, to, legAmount)
+		if err != nil {
+			return entities.TransferReceipt{}, err
+		}
+		booked = append(booked, legPayments...)
+	}
+
+	if err := svc.commitTx(ctx, txStorage); err != nil {
+		return entities.TransferReceipt{}, errors.Wrap(err, "transaction commit failed")
+	}
+
+	return entities.TransferReceipt{Transaction: transaction, Payments: booked}, nil
+}
+
+// refundedAmount sums up amounts the given refunds moved back
+func refundedAmount(ctx context.Context, txStorage storage.Storage, refunds []entities.Transaction) (decimal.Decimal, error) {
+	total := decimal.New(0, 0)
+	for _, refund := range refunds {
+		payments, err := txStorage.GetTransactionPayments(ctx, refund.ID)
+		if err != nil {
+			return total, errors.Wrapf(err, "can't obtain payments of refund %d", refund.ID)
+		}
+
+		for _, payment := range payments {
+			if payment.Direction == entities.Outgoing {
+				total = total.Add(payment.Amount)
+			}
+		}
+	}
+	return total, nil
+}
+
+// partialRefundError tells why a transaction with the given outgoing legs can't be refunded
+func partialRefundError(legs []entities.Payment) error {
+	for _, leg := range legs {
+		if leg.Currency != legs[0].Currency {
+			return errPartialRefundFX
+		}
+	}
+	return errPartialRefundSplit
+}
+
+// isFeePayment checks whether the outgoing payment charges a fee, i.e. goes to FEE account
+func isFeePayment(payment entities.Payment) bool {
+	return payment.Counterparty.Name == entities.FeeAccountName(payment.Currency)
+}
@@ -0,0 +1,242 @@
+package banking_test
+
+import (
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/twonegatives/coinsph_challenge/pkg/banking"
+	"github.com/twonegatives/coinsph_challenge/pkg/entities"
+	"github.com/twonegatives/coinsph_challenge/pkg/mocks"
+)
+
+func TestBankingSvcRefundTransaction(t *testing.T) {
+	barry := entities.Account{ID: 1, Name: "barry", Type: entities.UserAccount, Status: entities.ActiveAccount, Balance: decimal.New(80, 0), Currency: entities.USD}
+	wicky := entities.Account{ID: 2, Name: "wicky", Type: entities.UserAccount, Status: entities.ActiveAccount, Balance: decimal.New(20, 0), Currency: entities.USD}
+	original := entities.Transaction{ID: 42, Kind: entities.TransferTransaction}
+	originalPayments := []entities.Payment{
+		{Account: barry, Counterparty: wicky, Transaction: original, Direction: entities.Outgoing, Amount: decimal.New(20, 0), Currency: entities.USD},
+		{Account: wicky, Counterparty: barry, Transaction: original, Direction: entities.Incoming, Amount: decimal.New(20, 0), Currency: entities.USD},
+	}
+
+	expectOriginal := func(storage *mocks.MockStorage) {
+		storage.EXPECT().GetTransaction(ctx, 42).Return(original, nil)
+		storage.EXPECT().GetTransactionPayments(ctx, 42).Return(originalPayments, nil)
+	}
+
+	t.Run("moves the amount back from receiver to sender", func(t *testing.T) {
+		mCtrl := gomock.NewController(t)
+		defer mCtrl.Finish()
+		storage := mocks.NewMockStorage(mCtrl)
+
+		previous := entities.Transaction{ID: 43, Kind: entities.RefundTransaction, OriginalID: 42}
+		refund := entities.Transaction{ID: 44, Kind: entities.RefundTransaction, OriginalID: 42}
+
+		expectOriginal(storage)
+		storage.EXPECT().BeginTx(ctx, nil).Return(storage, nil)
+		expectAccountsLocked(storage, barry, wicky)
+		storage.EXPECT().GetLinkedTransactions(ctx, 42).Return([]entities.Transaction{previous}, nil)
+		storage.EXPECT().GetTransactionPayments(ctx, 43).Return([]entities.Payment{
+			{Account: wicky, Counterparty: barry, Transaction: previous, Direction: entities.Outgoing, Amount: decimal.New(12, 0), Currency: entities.USD},
+			{Account: barry, Counterparty: wicky, Transaction: previous, Direction: entities.Incoming, Amount: decimal.New(12, 0), Currency: entities.USD},
+		}, nil)
+		storage.EXPECT().CreateTransaction(ctx, entities.Transaction{Kind: entities.RefundTransaction, OriginalID: 42}).Return(refund, nil)
+		storage.EXPECT().SendPayment(ctx, gomock.Any()).Times(2).Return(nil)
+		storage.EXPECT().SetAccountBalance(ctx, gomock.Any()).Times(2).Return(nil)
+		storage.EXPECT().CommitTx(ctx).Return(nil)
+		storage.EXPECT().RollbackTx(ctx).Return(nil)
+
+		receipt, err := banking.NewService(storage).RefundTransaction(ctx, 42, decimal.New(8, 0))
+		require.NoError(t, err)
+		assert.Equal(t, refund, receipt.Transaction)
+		require.Len(t, receipt.Payments, 2)
+		assert.Equal(t, "wicky", receipt.Payments[0].Account.Name)
+		assert.Equal(t, entities.Outgoing, receipt.Payments[0].Direction)
+		assert.Equal(t, "8", receipt.Payments[0].Amount.String())
+		assert.Equal(t, "barry", receipt.Payments[1].Account.Name)
+	})
+
+	t.Run("keeps the fee charged by the original transfer", func(t *testing.T) {
+		mCtrl := gomock.NewController(t)
+		defer mCtrl.Finish()
+		storage := mocks.NewMockStorage(mCtrl)
+
+		feeAccount := entities.Account{ID: 3, Name: "FEE_USD", Type: entities.FeeAccount, Status: entities.ActiveAccount, Balance: decimal.New(1, 0), Currency: entities.USD}
+		charged := entities.Transaction{ID: 45, Kind: entities.TransferTransaction, Fee: decimal.New(1, 0)}
+		refund := entities.Transaction{ID: 46, Kind: entities.RefundTransaction, OriginalID: 45}
+
+		storage.EXPECT().GetTransaction(ctx, 45).Return(charged, nil)
+		storage.EXPECT().GetTransactionPayments(ctx, 45).Return([]entities.Payment{
+			{Account: barry, Counterparty: wicky, Transaction: charged, Direction: entities.Outgoing, Amount: decimal.New(20, 0), Currency: entities.USD},
+			{Account: wicky, Counterparty: barry, Transaction: charged, Direction: entities.Incoming, Amount: decimal.New(20, 0), Currency: entities.USD},
+			{Account: barry, Counterparty: feeAccount, Transaction: charged, Direction: entities.Outgoing, Amount: decimal.New(1, 0), Currency: entities.USD},
+			{Account: feeAccount, Counterparty: barry, Transaction: charged, Direction: entities.Incoming, Amount: decimal.New(1, 0), Currency: entities.USD},
+		}, nil)
+		storage.EXPECT().BeginTx(ctx, nil).Return(storage, nil)
+		expectAccountsLocked(storage, barry, wicky)
+		storage.EXPECT().GetLinkedTransactions(ctx, 45).Return([]entities.Transaction{}, nil)
+		storage.EXPECT().CreateTransaction(ctx, entities.Transaction{Kind: entities.RefundTransaction, OriginalID: 45}).Return(refund, nil)
+		storage.EXPECT().SendPayment(ctx, gomock.Any()).Times(2).Return(nil)
+		storage.EXPECT().SetAccountBalance(ctx, gomock.Any()).Times(2).Return(nil)
+		storage.EXPECT().CommitTx(ctx).Return(nil)
+		storage.EXPECT().RollbackTx(ctx).Return(nil)
+
+		receipt, err := banking.NewService(storage).RefundTransaction(ctx, 45, decimal.New(20, 0))
+		require.NoError(t, err)
+		require.Len(t, receipt.Payments, 2)
+		assert.Equal(t, "wicky", receipt.Payments[0].Account.Name)
+		assert.Equal(t, "20", receipt.Payments[0].Amount.String())
+	})
+
+	t.Run("rejects refunds exceeding the original amount", func(t *testing.T) {
+		mCtrl := gomock.NewController(t)
+		defer mCtrl.Finish()
+		storage := mocks.NewMockStorage(mCtrl)
+
+		expectOriginal(storage)
+		storage.EXPECT().BeginTx(ctx, nil).Return(storage, nil)
+		expectAccountsLocked(storage, barry, wicky)
+		storage.EXPECT().GetLinkedTransactions(ctx, 42).Return([]entities.Transaction{}, nil)
+		storage.EXPECT().RollbackTx(ctx).Return(nil)
+
+		_, err := banking.NewService(storage).RefundTransaction(ctx, 42, decimal.New(2001, -2))
+		require.Error(t, err)
+		assert.Equal(t, "refunds can't exceed the original transfer amount", err.Error())
+	})
+
+	t.Run("rejects refunds of reversed transaction", func(t *testing.T) {
+		mCtrl := gomock.NewController(t)
+		defer mCtrl.Finish()
+		storage := mocks.NewMockStorage(mCtrl)
+
+		expectOriginal(storage)
+		storage.EXPECT().BeginTx(ctx, nil).Return(storage, nil)
+		expectAccountsLocked(storage, barry, wicky)
+		storage.EXPECT().GetLinkedTransactions(ctx, 42).Return([]entities.Transaction{{ID: 43, Kind: entities.ReversalTransaction, OriginalID: 42}}, nil)
+		storage.EXPECT().RollbackTx(ctx).Return(nil)
+
+		_, err := banking.NewService(storage).RefundTransaction(ctx, 42, decimal.New(1, 0))
+		require.Error(t, err)
+		assert.Equal(t, "transaction has already been reversed", err.Error())
+	})
+
+	t.Run("rejects refunds of other transaction kinds", func(t *testing.T) {
+		mCtrl := gomock.NewController(t)
+		defer mCtrl.Finish()
+		storage := mocks.NewMockStorage(mCtrl)
+
+		storage.EXPECT().GetTransaction(ctx, 42).Return(entities.Transaction{ID: 42, Kind: entities.DepositTransaction}, nil)
+		storage.EXPECT().GetTransactionPayments(ctx, 42).Return([]entities.Payment{}, nil)
+
+		_, err := banking.NewService(storage).RefundTransaction(ctx, 42, decimal.New(1, 0))
+		require.Error(t, err)
+		assert.Equal(t, "only transfers can be reversed or refunded", err.Error())
+	})
+
+	t.Run("rejects non-positive amount", func(t *testing.T) {
+		mCtrl := gomock.NewController(t)
+		defer mCtrl.Finish()
+		storage := mocks.NewMockStorage(mCtrl)
+
+		_, err := banking.NewService(storage).RefundTransaction(ctx, 42, decimal.New(0, 0))
+		require.Error(t, err)
+		assert.Equal(t, "amount transferred should be a positive number", err.Error())
+	})
+}
+
+func TestBankingSvcReverseTransaction(t *testing.T) {
+	barry := entities.Account{ID: 1, Name: "barry", Type: entities.UserAccount, Status: entities.ActiveAccount, Balance: decimal.New(0, 0), Currency: entities.PHP}
+	wicky := entities.Account{ID: 2, Name: "wicky", Type: entities.UserAccount, Status: entities.ActiveAccount, Balance: decimal.New(191, -1), Currency: entities.USD}
+	sourceFX := entities.Account{ID: 3, Name: "FX_PHP", Type: entities.SettlementAccount, Status: entities.ActiveAccount, Balance: decimal.New(1000, 0), Currency: entities.PHP}
+	targetFX := entities.Account{ID: 4, Name: "FX_USD", Type: entities.SettlementAccount, Status: entities.ActiveAccount, Balance: decimal.New(-191, -1), Currency: entities.USD}
+	original := entities.Transaction{ID: 42, Kind: entities.TransferTransaction}
+	originalPayments := []entities.Payment{
+		{Account: barry, Counterparty: sourceFX, Transaction: original, Direction: entities.Outgoing, Amount: decimal.New(1000, 0), Currency: entities.PHP},
+		{Account: sourceFX, Counterparty: barry, Transaction: original, Direction: entities.Incoming, Amount: decimal.New(1000, 0), Currency: entities.PHP},
+		{Account: targetFX, Counterparty: wicky, Transaction: original, Direction: entities.Outgoing, Amount: decimal.New(191, -1), Currency: entities.USD},
+		{Account: wicky, Counterparty: targetFX, Transaction: original, Direction: entities.Incoming, Amount: decimal.New(191, -1), Currency: entities.USD},
+	}
+
+	expectOriginal := func(storage *mocks.MockStorage) {
+		storage.EXPECT().GetTransaction(ctx, 42).Return(original, nil)
+		storage.EXPECT().GetTransactionPayments(ctx, 42).Return(originalPayments, nil)
+	}
+
+	t.Run("moves every payment back in full", func(t *testing.T) {
+		mCtrl := gomock.NewController(t)
+		defer mCtrl.Finish()
+		storage := mocks.NewMockStorage(mCtrl)
+
+		reversal := entities.Transaction{ID: 43, Kind: entities.ReversalTransaction, OriginalID: 42}
+
+		expectOriginal(storage)
+		storage.EXPECT().BeginTx(ctx, nil).Return(storage, nil)
+		expectAccountsLocked(storage, barry, wicky, sourceFX, targetFX)
+		storage.EXPECT().GetLinkedTransactions(ctx, 42).Return([]entities.Transaction{}, nil)
+		storage.EXPECT().CreateTransaction(ctx, entities.Transaction{Kind: entities.ReversalTransaction, OriginalID: 42}).Return(reversal, nil)
+		storage.EXPECT().SendPayment(ctx, gomock.Any()).Times(4).Return(nil)
+		storage.EXPECT().SetAccountBalance(ctx, gomock.Any()).Times(4).Return(nil)
+		storage.EXPECT().CommitTx(ctx).Return(nil)
+		storage.EXPECT().RollbackTx(ctx).Return(nil)
+
+		receipt, err := banking.NewService(storage).ReverseTransaction(ctx, 42)
+		require.NoError(t, err)
+		require.Len(t, receipt.Payments, 4)
+
+		moves := make([]string, len(receipt.Payments))
+		for i, payment := range receipt.Payments {
+			moves[i] = payment.Account.Name + " " + string(payment.Direction) + " " + payment.Amount.String()
+		}
+		assert.Equal(t, []string{"wicky outgoing 19.1", "FX_USD incoming 19.1", "FX_PHP outgoing 1000", "barry incoming 1000"}, moves)
+	})
+
+	t.Run("rejects reversal of refunded transaction", func(t *testing.T) {
+		mCtrl := gomock.NewController(t)
+		defer mCtrl.Finish()
+		storage := mocks.NewMockStorage(mCtrl)
+
+		expectOriginal(storage)
+		storage.EXPECT().BeginTx(ctx, nil).Return(storage, nil)
+		expectAccountsLocked(storage, barry, wicky, sourceFX, targetFX)
+		storage.EXPECT().GetLinkedTransactions(ctx, 42).Return([]entities.Transaction{{ID: 43, Kind: entities.RefundTransaction, OriginalID: 42}}, nil)
+		storage.EXPECT().RollbackTx(ctx).Return(nil)
+
+		_, err := banking.NewService(storage).ReverseTransaction(ctx, 42)
+		require.Error(t, err)
+		assert.Equal(t, "transaction has already been partially refunded, refund the remaining amount instead", err.Error())
+	})
+
+	t.Run("rejects partial refunds of exchange transfers", func(t *testing.T) {
+		mCtrl := gomock.NewController(t)
+		defer mCtrl.Finish()
+		storage := mocks.NewMockStorage(mCtrl)
+
+		expectOriginal(storage)
+
+		_, err := banking.NewService(storage).RefundTransaction(ctx, 42, decimal.New(1, 0))
+		require.Error(t, err)
+		assert.Equal(t, "transfers between different currencies can only be reversed in full", err.Error())
+	})
+
+	t.Run("rejects reversal when receiver has spent the money", func(t *testing.T) {
+		mCtrl := gomock.NewController(t)
+		defer mCtrl.Finish()
+		storage := mocks.NewMockStorage(mCtrl)
+
+		spent := wicky
+		spent.Balance = decimal.New(5, 0)
+
+		expectOriginal(storage)
+		storage.EXPECT().BeginTx(ctx, nil).Return(storage, nil)
+		expectAccountsLocked(storage, barry, spent, sourceFX, targetFX)
+		storage.EXPECT().GetLinkedTransactions(ctx, 42).Return([]entities.Transaction{}, nil)
+		storage.EXPECT().CreateTransaction(ctx, gomock.Any()).Return(entities.Transaction{ID: 43}, nil)
+		storage.EXPECT().RollbackTx(ctx).Return(nil)
+
+		_, err := banking.NewService(storage).ReverseTransaction(ctx, 42)
+		require.Error(t, err)
+		assert.Equal(t, "sender account has insufficient funds", err.Error())
+	})
+}
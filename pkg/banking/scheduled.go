@@ -0,0 +1,487 @@
+package banking
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/twonegatives/coinsph_challenge/pkg/cron"
+	"github.com/twonegatives/coinsph_challenge/pkg/entities"
+	"github.com/twonegatives/coinsph_challenge/pkg/storage"
+)
+
+const (
+	defaultScheduleLeaseTTL      = time.Minute
+	defaultScheduleRetryInterval = time.Hour
+	defaultScheduleMaxRetries    = 3
+
+	// scheduledPaymentsBatch limits the number of scheduled payments run by a single RunScheduledPayments call
+	scheduledPaymentsBatch = 100
+)
+
+var (
+	errScheduleNotFound      = errors.New("scheduled payment not found")
+	errScheduleFinal         = errors.New("scheduled payment has already been completed or cancelled")
+	errInvalidFrequency      = errors.New("frequency should be one of once, daily, weekly, monthly or cron")
+	errInvalidCron           = errors.New("cron expression is invalid")
+	errCronWithoutFrequency  = errors.New("cron expression may only be set for cron frequency")
+	errScheduleStartInPast   = errors.New("scheduled payment can't start in the past")
+	errScheduleEndBeforeRun  = errors.New("scheduled payment should end after its first run")
+	errInvalidScheduleStatus = errors.New("scheduled payment can only be paused or resumed")
+)
+
+// CreateScheduledPayment schedules a transfer of the payment amount from its account
+// to its counterparty, once or recurring at the payment frequency. Zero StartAt schedules
+// the first run right away, zero EndAt lets the payment recur until it is cancelled.
+// Accounts are checked the same way SendPayment checks them, except for the funds
+// which are checked by every run. Returns the active ScheduledPayment on success.
+func (svc *Service) CreateScheduledPayment(ctx context.Context, payment entities.ScheduledPayment) (entities.ScheduledPayment, error) {
+	if err := validatePaymentOrder(payment.Account, payment.Counterparty, payment.Amount); err != nil {
+		return entities.ScheduledPayment{}, err
+	}
+
+	if !payment.Frequency.IsValid() {
+		return entities.ScheduledPayment{}, errInvalidFrequency
+	}
+
+	if payment.Frequency != entities.CronFrequency && payment.Cron != "" {
+		return entities.ScheduledPayment{}, errCronWithoutFrequency
+	}
+
+	now := svc.now()
+	if payment.StartAt.IsZero() {
+		payment.StartAt = now
+	}
+
+	if payment.StartAt.Before(now.Add(-time.Minute)) {
+		return entities.ScheduledPayment{}, errScheduleStartInPast
+	}
+
+	payment.NextRunAt = payment.StartAt
+	if payment.Frequency == entities.CronFrequency {
+		schedule, err := cron.Parse(payment.Cron)
+		if err != nil {
+			return entities.ScheduledPayment{}, errors.Wrap(errInvalidCron, err.Error())
+		}
+
+		// the first run is the earliest moment matching the expression since StartAt
+		payment.NextRunAt = schedule.Next(payment.StartAt.Add(-time.Minute))
+		if payment.NextRunAt.IsZero() {
+			return entities.ScheduledPayment{}, errors.Wrap(errInvalidCron, "expression never matches")
+		}
+	}
+
+	if !payment.EndAt.IsZero() && payment.EndAt.Before(payment.NextRunAt) {
+		return entities.ScheduledPayment{}, errScheduleEndBeforeRun
+	}
+
+	from, err := svc.GetAccount(ctx, payment.Account.Name)
+	if err != nil {
+		return entities.ScheduledPayment{}, errors.Wrap(err, "sender")
+	}
+
+	to, err := svc.GetAccount(ctx, payment.Counterparty.Name)
+	if err != nil {
+		return entities.ScheduledPayment{}, errors.Wrap(err, "receiver")
+	}
+
+	if from.Type.IsHouse() || to.Type.IsHouse() {
+		return entities.ScheduledPayment{}, errHouseAccountTransfer
+	}
+
+	if err := checkOwnership(ctx, from); err != nil {
+		return entities.ScheduledPayment{}, err
+	}
+
+	if err := checkAccountsActive(from, to); err != nil {
+		return entities.ScheduledPayment{}, err
+	}
+
+	if from.Currency != to.Currency {
+		return entities.ScheduledPayment{}, errCurrencyMismatch
+	}
+
+	if err := svc.validateAmount(from.Currency, payment.Amount); err != nil {
+		return entities.ScheduledPayment{}, err
+	}
+
+	payment.Account, payment.Counterparty = from, to
+	payment.Currency = from.Currency
+	payment.Status = entities.ActiveSchedule
+
+	var created entities.ScheduledPayment
+	err = svc.inTx(ctx, func(txStorage storage.Storage) (err error) {
+		created, err = txStorage.CreateScheduledPayment(ctx, payment)
+		return errors.Wrap(err, "failed to create scheduled payment in database")
+	})
+	return created, err
+}
+
+// GetScheduledPayment returns a ScheduledPayment found by its ID.
+func (svc *Service) GetScheduledPayment(ctx context.Context, id int) (entities.ScheduledPayment, error) {
+	payment, err := svc.store.GetScheduledPayment(ctx, id)
+	if errors.Cause(err) == storage.ErrNotFound {
+		return entities.ScheduledPayment{}, errScheduleNotFound
+	}
+	return payment, errors.Wrap(err, "failed to fetch scheduled payment from database")
+}
+
+// GetScheduledPayments returns payments scheduled by the account, or all of them
+// for a blank account name, in order of their creation.
+func (svc *Service) GetScheduledPayments(ctx context.Context, accountName string) ([]entities.ScheduledPayment, error) {
+	accountID := 0
+	if accountName != "" {
+		account, err := svc.GetAccount(ctx, accountName)
+		if err != nil {
+			return nil, err
+		}
+		accountID = account.ID
+	}
+
+	payments, err := svc.store.GetScheduledPayments(ctx, accountID)
+	return payments, errors.Wrap(err, "failed to fetch scheduled payments from database")
+}
+
+// UpdateScheduledPayment changes the amount, the status or the upcoming run of a scheduled payment.
+// Payments may only be paused and resumed, a resumed payment which missed its run
+// makes it right away. Completed and cancelled payments can't be changed.
+// Returns the updated ScheduledPayment on success.
+func (svc *Service) UpdateScheduledPayment(ctx context.Context, id int, update entities.ScheduledPaymentUpdate) (entities.ScheduledPayment, error) {
+	if update.Amount.Valid && !update.Amount.Decimal.IsPositive() {
+		return entities.ScheduledPayment{}, errAmountShouldBePositive
+	}
+
+	if update.Status != "" && update.Status != entities.ActiveSchedule && update.Status != entities.PausedSchedule {
+		return entities.ScheduledPayment{}, errInvalidScheduleStatus
+	}
+
+	if !update.NextRunAt.IsZero() && update.NextRunAt.Before(svc.now().Add(-time.Minute)) {
+		return entities.ScheduledPayment{}, errScheduleStartInPast
+	}
+
+	return svc.changeScheduledPayment(ctx, id, func(payment *entities.ScheduledPayment) error {
+		if update.Amount.Valid {
+			if err := svc.validateAmount(payment.Currency, update.Amount.Decimal); err != nil {
+				return err
+			}
+			payment.Amount = update.Amount.Decimal
+		}
+
+		if update.Status != "" {
+			payment.Status = update.Status
+		}
+
+		if !update.NextRunAt.IsZero() {
+			if !payment.EndAt.IsZero() && payment.EndAt.Before(update.NextRunAt) {
+				return errScheduleEndBeforeRun
+			}
+			payment.NextRunAt = update.NextRunAt
+			payment.RetryAt, payment.Attempts = time.Time{}, 0
+		}
+
+		return nil
+	})
+}
+
+// CancelScheduledPayment stops a scheduled payment for good.
+// Returns the cancelled ScheduledPayment on success.
+func (svc *Service) CancelScheduledPayment(ctx context.Context, id int) (entities.ScheduledPayment, error) {
+	return svc.changeScheduledPayment(ctx, id, func(payment *entities.ScheduledPayment) error {
+		payment.Status = entities.CancelledSchedule
+		return nil
+	})
+}
+
+// GetScheduledRuns returns the run history of a scheduled payment, oldest run first.
+func (svc *Service) GetScheduledRuns(ctx context.Context, id int) ([]entities.ScheduledRun, error) {
+	if _, err := svc.GetScheduledPayment(ctx, id); err != nil {
+		return nil, err
+	}
+
+	runs, err := svc.store.GetScheduledRuns(ctx, id)
+	return runs, errors.Wrap(err, "failed to fetch scheduled payment runs from database")
+}
+
+// RunScheduledPayments runs scheduled payments which are due. Payments are claimed
+// with a lease first, so that replicas of the service never run the same payment
+// concurrently, and a payment left behind by a crashed replica is claimed again once
+// its lease expires. Up to scheduledPaymentsBatch payments are run per call.
+// A payment which can't be run doesn't stop the others, errors of all such payments
+// are returned together once every claimed payment is tried.
+// Returns the number of runs made, both succeeded and failed ones.
+func (svc *Service) RunScheduledPayments(ctx context.Context) (int, error) {
+	now := svc.now()
+	claimed, err := svc.store.ClaimScheduledPayments(ctx, svc.schedulerID, now, now.Add(svc.scheduleLeaseTTL), scheduledPaymentsBatch)
+	if err != nil {
+		return 0, errors.Wrap(err, "failed to claim scheduled payments")
+	}
+
+	runs := 0
+	failures := []string{}
+	for _, payment := range claimed {
+		ran, err := svc.runScheduledPayment(ctx, payment.ID)
+		if err != nil {
+			failures = append(failures, fmt.Sprintf("can't run scheduled payment %d: %s", payment.ID, err))
+			continue
+		}
+
+		if ran {
+			runs++
+		}
+	}
+
+	if len(failures) > 0 {
+		return runs, errors.New(strings.Join(failures, "; "))
+	}
+	return runs, nil
+}
+
+// runScheduledPayment makes a single run of a claimed payment. A transfer which is declined
+// is recorded as a failed run and retried later if the sender lacks funds. Storage failures
+// leave the payment as it is, so that it is run again once the lease expires.
+// Returns false if the payment is not due or claimed by this scheduler anymore.
+func (svc *Service) runScheduledPayment(ctx context.Context, id int) (bool, error) {
+	ran, err := svc.bookScheduledRun(ctx, id)
+	if !isTransferDeclined(err) {
+		return ran, err
+	}
+
+	return svc.failScheduledRun(ctx, id, err)
+}
+
+// bookScheduledRun transfers the amount of a claimed payment the way SendPayment does.
+// The transfer, the record of the run and the move to the upcoming run are stored
+// within the same transaction, so that every run transfers money exactly once.
+func (svc *Service) bookScheduledRun(ctx context.Context, id int) (bool, error) {
+	txStorage, err := svc.store.BeginTx(ctx, nil)
+	if err != nil {
+		return false, errors.Wrap(err, "can't open transaction")
+	}
+
+	defer txStorage.RollbackTx(ctx)
+
+	payment, claimed, err := svc.lockClaimedPayment(ctx, txStorage, id)
+	if err != nil || !claimed {
+		return false, err
+	}
+
+	from, to := payment.Account, payment.Counterparty
+	if err := lockAccounts(ctx, txStorage, paymentSide{account: &from, label: "sender"}, paymentSide{account: &to, label: "receiver"}); err != nil {
+		return false, err
+	}
+
+	receipt, err := svc.transfer(ctx, txStorage, &from, &to, payment.Amount, entities.PaymentDetails{})
+	if err != nil {
+		return false, err
+	}
+
+	_, err = txStorage.CreateScheduledRun(ctx, entities.ScheduledRun{
+		ScheduleID:    payment.ID,
+		ScheduledFor:  payment.NextRunAt,
+		Attempt:       payment.Attempts + 1,
+		Status:        entities.SucceededRun,
+		TransactionID: receipt.Transaction.ID,
+	})
+	if err != nil {
+		return false, errors.Wrap(err, "can't insert scheduled payment run")
+	}
+
+	advanceSchedule(&payment, svc.now())
+	if err := txStorage.UpdateScheduledPayment(ctx, payment); err != nil {
+		return false, errors.Wrap(err, "can't update scheduled payment")
+	}
+
+	if err := svc.commitTx(ctx, txStorage); err != nil {
+		return false, errors.Wrap(err, "transaction commit failed")
+	}
+
+	return true, nil
+}
+
+// failScheduledRun records a declined run of a claimed payment. A run declined
+// for insufficient funds is retried after the retry interval until retries are exhausted,
+// after which the payment moves to its upcoming run the same way it does after a success.
+func (svc *Service) failScheduledRun(ctx context.Context, id int, cause error) (bool, error) {
+	txStorage, err := svc.store.BeginTx(ctx, nil)
+	if err != nil {
+		return false, errors.Wrap(err, "can't open transaction")
+	}
+
+	defer txStorage.RollbackTx(ctx)
+
+	payment, claimed, err := svc.lockClaimedPayment(ctx, txStorage, id)
+	if err != nil || !claimed {
+		return false, err
+	}
+
+	_, err = txStorage.CreateScheduledRun(ctx, entities.ScheduledRun{
+		ScheduleID:   payment.ID,
+		ScheduledFor: payment.NextRunAt,
+		Attempt:      payment.Attempts + 1,
+		Status:       entities.FailedRun,
+		Error:        cause.Error(),
+	})
+	if err != nil {
+		return false, errors.Wrap(err, "can't insert scheduled payment run")
+	}
+
+	now := svc.now()
+	if errors.Cause(cause) == errInsufficientFunds && payment.Attempts < svc.scheduleMaxRetries {
+		payment.Attempts++
+		payment.RetryAt = now.Add(svc.scheduleRetryInterval)
+		payment.LeaseOwner, payment.LeaseExpiresAt = "", time.Time{}
+	} else {
+		advanceSchedule(&payment, now)
+	}
+
+	if err := txStorage.UpdateScheduledPayment(ctx, payment); err != nil {
+		return false, errors.Wrap(err, "can't update scheduled payment")
+	}
+
+	if err := svc.commitTx(ctx, txStorage); err != nil {
+		return false, errors.Wrap(err, "transaction commit failed")
+	}
+
+	return true, nil
+}
+
+// lockClaimedPayment locks a scheduled payment and checks that it is still due and claimed
+// by this scheduler, as it could have been paused or run by another replica after its lease expired.
+func (svc *Service) lockClaimedPayment(ctx context.Context, txStorage storage.Storage, id int) (entities.ScheduledPayment, bool, error) {
+	payment := entities.ScheduledPayment{ID: id}
+	if err := txStorage.GetScheduledPaymentForUpdate(ctx, &payment); err != nil {
+		return entities.ScheduledPayment{}, false, errors.Wrap(err, "can't obtain scheduled payment")
+	}
+
+	now := svc.now()
+	claimed := payment.IsDue(now) && payment.LeaseOwner == svc.schedulerID
+	return payment, claimed, nil
+}
+
+// changeScheduledPayment locks a scheduled payment which is neither completed nor cancelled
+// and stores changes made to it by the function.
+func (svc *Service) changeScheduledPayment(ctx context.Context, id int, change func(*entities.ScheduledPayment) error) (entities.ScheduledPayment, error) {
+	txStorage, err := svc.store.BeginTx(ctx, nil)
+	if err != nil {
+		return entities.ScheduledPayment{}, errors.Wrap(err, "can't open transaction")
+	}
+
+	defer txStorage.RollbackTx(ctx)
+
+	payment := entities.ScheduledPayment{ID: id}
+	err = txStorage.GetScheduledPaymentForUpdate(ctx, &payment)
+	if errors.Cause(err) == storage.ErrNotFound {
+		return entities.ScheduledPayment{}, errScheduleNotFound
+	}
+	if err != nil {
+		return entities.ScheduledPayment{}, errors.Wrap(err, "can't obtain scheduled payment")
+	}
+
+	if payment.Status.IsFinal() {
+		return entities.ScheduledPayment{}, errScheduleFinal
+	}
+
+	if _, ok := PrincipalFromContext(ctx); ok {
+		sender, err := txStorage.GetAccount(ctx, payment.Account.Name)
+		if err != nil {
+			return entities.ScheduledPayment{}, errors.Wrap(err, "can't obtain sender account")
+		}
+
+		if err := checkOwnership(ctx, sender); err != nil {
+			return entities.ScheduledPayment{}, err
+		}
+	}
+
+	if err := change(&payment); err != nil {
+		return entities.ScheduledPayment{}, err
+	}
+
+	if err := txStorage.UpdateScheduledPayment(ctx, payment); err != nil {
+		return entities.ScheduledPayment{}, errors.Wrap(err, "can't update scheduled payment")
+	}
+
+	if err := svc.commitTx(ctx, txStorage); err != nil {
+		return entities.ScheduledPayment{}, errors.Wrap(err, "transaction commit failed")
+	}
+
+	return payment, nil
+}
+
+// advanceSchedule moves the payment to its upcoming run after now, skipping the runs
+// it has missed, and releases the lease. The payment is completed when there are no runs left.
+func advanceSchedule(payment *entities.ScheduledPayment, now time.Time) {
+	payment.RetryAt, payment.Attempts = time.Time{}, 0
+	payment.LeaseOwner, payment.LeaseExpiresAt = "", time.Time{}
+
+	next := nextRunAfter(*payment, payment.NextRunAt)
+	for !next.IsZero() && !next.After(now) {
+		next = nextRunAfter(*payment, next)
+	}
+
+	if next.IsZero() || (!payment.EndAt.IsZero() && next.After(payment.EndAt)) {
+		payment.Status = entities.CompletedSchedule
+		return
+	}
+
+	payment.NextRunAt = next
+}
+
+// nextRunAfter returns the run of the payment following the given one,
+// or zero time if the payment does not recur.
+func nextRunAfter(payment entities.ScheduledPayment, run time.Time) time.Time {
+	switch payment.Frequency {
+	case entities.DailyFrequency:
+		return run.AddDate(0, 0, 1)
+	case entities.WeeklyFrequency:
+		return run.AddDate(0, 0, 7)
+	case entities.MonthlyFrequency:
+		// months are stepped from the first day, so that runs keep to the day of StartAt
+		start := payment.StartAt
+		month := time.Date(run.Year(), run.Month()+1, 1, start.Hour(), start.Minute(), start.Second(), start.Nanosecond(), start.Location())
+		lastDay := month.AddDate(0, 1, -1).Day()
+		if start.Day() < lastDay {
+			lastDay = start.Day()
+		}
+		return month.AddDate(0, 0, lastDay-1)
+	case entities.CronFrequency:
+		schedule, err := cron.Parse(payment.Cron)
+		if err != nil {
+			return time.Time{}
+		}
+		return schedule.Next(run)
+	}
+
+	return time.Time{}
+}
+
+// isTransferDeclined checks whether the transfer was declined by the checks SendPayment makes,
+// as opposed to failing for reasons which may go away without any changes to the accounts.
+func isTransferDeclined(err error) bool {
+	switch errors.Cause(err) {
+	case errInsufficientFunds,
+		errSenderFrozen,
+		errReceiverFrozen,
+		errSenderClosed,
+		errReceiverClosed,
+		errHouseAccountTransfer,
+		errCurrencyMismatch,
+		errAmountPrecision,
+		errUnsupportedCurrency:
+
+		return true
+	}
+
+	return false
+}
+
+// defaultSchedulerID identifies the process among replicas of the service
+func defaultSchedulerID() string {
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = "localhost"
+	}
+	return fmt.Sprintf("%s-%d", hostname, os.Getpid())
+}
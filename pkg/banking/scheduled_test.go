@@ -0,0 +1,312 @@
+package banking_test
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/twonegatives/coinsph_challenge/pkg/banking"
+	"github.com/twonegatives/coinsph_challenge/pkg/entities"
+	"github.com/twonegatives/coinsph_challenge/pkg/memstorage"
+	"github.com/twonegatives/coinsph_challenge/pkg/storage"
+)
+
+// flakyStorage fails to open the given number of transactions before it starts opening them
+type flakyStorage struct {
+	*memstorage.MemStorage
+	failures int
+}
+
+func (s *flakyStorage) BeginTx(ctx context.Context, opts *sql.TxOptions) (storage.Storage, error) {
+	if s.failures > 0 {
+		s.failures--
+		return nil, errors.New("connection reset")
+	}
+	return s.MemStorage.BeginTx(ctx, opts)
+}
+
+func TestBankingSvcScheduledPayments(t *testing.T) {
+	// setup returns a service with funded 'payer' and empty 'payee' accounts.
+	// Runs declined for insufficient funds are retried once right away.
+	setup := func(t *testing.T, funds int64) (*banking.Service, *memstorage.MemStorage) {
+		storage := memstorage.NewMemStorage()
+		svc := banking.NewService(storage, banking.WithSchedulerID("test"), banking.WithScheduleRetries(1, 0))
+		for _, name := range []string{"payer", "payee"} {
+			_, err := svc.CreateAccount(ctx, name, entities.USD, entities.AccountProfile{})
+			require.NoError(t, err)
+		}
+
+		if funds > 0 {
+			_, err := svc.Deposit(ctx, "payer", decimal.New(funds, 0), "wire-1")
+			require.NoError(t, err)
+		}
+		return svc, storage
+	}
+
+	order := func(frequency entities.ScheduleFrequency, amount int64) entities.ScheduledPayment {
+		return entities.ScheduledPayment{
+			Account:      entities.Account{Name: "payer"},
+			Counterparty: entities.Account{Name: "payee"},
+			Amount:       decimal.New(amount, 0),
+			Frequency:    frequency,
+		}
+	}
+
+	balance := func(t *testing.T, svc *banking.Service, name string) string {
+		account, err := svc.GetAccount(ctx, name)
+		require.NoError(t, err)
+		return account.Balance.String()
+	}
+
+	t.Run("runs a daily payment and moves it to the next day", func(t *testing.T) {
+		svc, _ := setup(t, 10)
+		created, err := svc.CreateScheduledPayment(ctx, order(entities.DailyFrequency, 4))
+		require.NoError(t, err)
+		assert.Equal(t, entities.ActiveSchedule, created.Status)
+		assert.Equal(t, entities.USD, created.Currency)
+
+		runs, err := svc.RunScheduledPayments(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, 1, runs)
+
+		runs, err = svc.RunScheduledPayments(ctx)
+		require.NoError(t, err)
+		assert.Zero(t, runs)
+
+		assert.Equal(t, "6", balance(t, svc, "payer"))
+		assert.Equal(t, "4", balance(t, svc, "payee"))
+
+		payment, err := svc.GetScheduledPayment(ctx, created.ID)
+		require.NoError(t, err)
+		assert.True(t, payment.NextRunAt.Equal(created.NextRunAt.AddDate(0, 0, 1)))
+		assert.Empty(t, payment.LeaseOwner)
+
+		history, err := svc.GetScheduledRuns(ctx, created.ID)
+		require.NoError(t, err)
+		require.Len(t, history, 1)
+		assert.Equal(t, entities.SucceededRun, history[0].Status)
+		assert.Equal(t, 1, history[0].Attempt)
+		assert.NotZero(t, history[0].TransactionID)
+	})
+
+	t.Run("completes a one-off payment after its run", func(t *testing.T) {
+		svc, _ := setup(t, 10)
+		created, err := svc.CreateScheduledPayment(ctx, order(entities.OnceFrequency, 4))
+		require.NoError(t, err)
+
+		_, err = svc.RunScheduledPayments(ctx)
+		require.NoError(t, err)
+
+		payment, err := svc.GetScheduledPayment(ctx, created.ID)
+		require.NoError(t, err)
+		assert.Equal(t, entities.CompletedSchedule, payment.Status)
+
+		_, err = svc.UpdateScheduledPayment(ctx, created.ID, entities.ScheduledPaymentUpdate{Status: entities.PausedSchedule})
+		assert.EqualError(t, err, "scheduled payment has already been completed or cancelled")
+	})
+
+	t.Run("retries a run declined for insufficient funds", func(t *testing.T) {
+		svc, _ := setup(t, 0)
+		created, err := svc.CreateScheduledPayment(ctx, order(entities.WeeklyFrequency, 4))
+		require.NoError(t, err)
+
+		_, err = svc.RunScheduledPayments(ctx)
+		require.NoError(t, err)
+
+		payment, err := svc.GetScheduledPayment(ctx, created.ID)
+		require.NoError(t, err)
+		assert.Equal(t, 1, payment.Attempts)
+		assert.False(t, payment.RetryAt.IsZero())
+		assert.True(t, payment.NextRunAt.Equal(created.NextRunAt))
+
+		_, err = svc.RunScheduledPayments(ctx)
+		require.NoError(t, err)
+
+		payment, err = svc.GetScheduledPayment(ctx, created.ID)
+		require.NoError(t, err)
+		assert.Zero(t, payment.Attempts)
+		assert.True(t, payment.RetryAt.IsZero())
+		assert.True(t, payment.NextRunAt.Equal(created.NextRunAt.AddDate(0, 0, 7)))
+
+		history, err := svc.GetScheduledRuns(ctx, created.ID)
+		require.NoError(t, err)
+		require.Len(t, history, 2)
+		for i, run := range history {
+			assert.Equal(t, entities.FailedRun, run.Status)
+			assert.Equal(t, i+1, run.Attempt)
+			assert.Equal(t, "sender account has insufficient funds", run.Error)
+			assert.True(t, run.ScheduledFor.Equal(created.NextRunAt))
+		}
+	})
+
+	t.Run("does not retry a run declined for a frozen account", func(t *testing.T) {
+		svc, _ := setup(t, 10)
+		created, err := svc.CreateScheduledPayment(ctx, order(entities.DailyFrequency, 4))
+		require.NoError(t, err)
+		_, err = svc.FreezeAccount(ctx, "payee")
+		require.NoError(t, err)
+
+		_, err = svc.RunScheduledPayments(ctx)
+		require.NoError(t, err)
+
+		payment, err := svc.GetScheduledPayment(ctx, created.ID)
+		require.NoError(t, err)
+		assert.Zero(t, payment.Attempts)
+		assert.True(t, payment.NextRunAt.Equal(created.NextRunAt.AddDate(0, 0, 1)))
+		assert.Equal(t, "10", balance(t, svc, "payer"))
+	})
+
+	t.Run("keeps running claimed payments after one fails", func(t *testing.T) {
+		svc, storage := setup(t, 10)
+		first, err := svc.CreateScheduledPayment(ctx, order(entities.OnceFrequency, 4))
+		require.NoError(t, err)
+		_, err = svc.CreateScheduledPayment(ctx, order(entities.OnceFrequency, 3))
+		require.NoError(t, err)
+
+		flaky := &flakyStorage{MemStorage: storage, failures: 1}
+		runs, err := banking.NewService(flaky, banking.WithSchedulerID("test")).RunScheduledPayments(ctx)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), fmt.Sprintf("can't run scheduled payment %d: can't open transaction: connection reset", first.ID))
+		assert.Equal(t, 1, runs)
+		assert.Equal(t, "7", balance(t, svc, "payer"))
+	})
+
+	t.Run("leaves payments claimed by another scheduler alone", func(t *testing.T) {
+		svc, storage := setup(t, 10)
+		_, err := svc.CreateScheduledPayment(ctx, order(entities.OnceFrequency, 4))
+		require.NoError(t, err)
+
+		now := time.Now()
+		claimed, err := storage.ClaimScheduledPayments(ctx, "other", now, now.Add(time.Hour), 10)
+		require.NoError(t, err)
+		require.Len(t, claimed, 1)
+
+		runs, err := svc.RunScheduledPayments(ctx)
+		require.NoError(t, err)
+		assert.Zero(t, runs)
+		assert.Equal(t, "10", balance(t, svc, "payer"))
+	})
+
+	t.Run("skips runs missed by a monthly payment keeping to its day", func(t *testing.T) {
+		svc, storage := setup(t, 10)
+		payer, err := svc.GetAccount(ctx, "payer")
+		require.NoError(t, err)
+		payee, err := svc.GetAccount(ctx, "payee")
+		require.NoError(t, err)
+
+		start := time.Date(2019, 1, 31, 9, 0, 0, 0, time.UTC)
+		created, err := storage.CreateScheduledPayment(ctx, entities.ScheduledPayment{
+			Account:      payer,
+			Counterparty: payee,
+			Amount:       decimal.New(1, 0),
+			Currency:     entities.USD,
+			Frequency:    entities.MonthlyFrequency,
+			Status:       entities.ActiveSchedule,
+			StartAt:      start,
+			NextRunAt:    start,
+		})
+		require.NoError(t, err)
+
+		_, err = svc.RunScheduledPayments(ctx)
+		require.NoError(t, err)
+
+		payment, err := svc.GetScheduledPayment(ctx, created.ID)
+		require.NoError(t, err)
+		next := payment.NextRunAt.UTC()
+		lastDay := time.Date(next.Year(), next.Month()+1, 0, 0, 0, 0, 0, time.UTC).Day()
+		assert.True(t, next.After(time.Now()))
+		assert.True(t, next.Before(time.Now().AddDate(0, 1, 1)))
+		assert.Equal(t, lastDay, next.Day())
+		assert.Equal(t, 9, next.Hour())
+		assert.Equal(t, "9", balance(t, svc, "payer"))
+	})
+
+	t.Run("runs cron payments at the matching moments", func(t *testing.T) {
+		svc, _ := setup(t, 10)
+		payment := order(entities.CronFrequency, 1)
+		payment.Cron = "0 9 * * 1"
+
+		created, err := svc.CreateScheduledPayment(ctx, payment)
+		require.NoError(t, err)
+		assert.Equal(t, time.Monday, created.NextRunAt.Weekday())
+		assert.Equal(t, 9, created.NextRunAt.Hour())
+		assert.True(t, created.NextRunAt.After(created.StartAt))
+	})
+
+	t.Run("pauses, resumes and cancels a payment", func(t *testing.T) {
+		svc, _ := setup(t, 10)
+		created, err := svc.CreateScheduledPayment(ctx, order(entities.DailyFrequency, 4))
+		require.NoError(t, err)
+
+		paused, err := svc.UpdateScheduledPayment(ctx, created.ID, entities.ScheduledPaymentUpdate{
+			Status: entities.PausedSchedule,
+			Amount: decimal.NullDecimal{Decimal: decimal.New(2, 0), Valid: true},
+		})
+		require.NoError(t, err)
+		assert.Equal(t, entities.PausedSchedule, paused.Status)
+		assert.Equal(t, "2", paused.Amount.String())
+
+		runs, err := svc.RunScheduledPayments(ctx)
+		require.NoError(t, err)
+		assert.Zero(t, runs)
+
+		_, err = svc.UpdateScheduledPayment(ctx, created.ID, entities.ScheduledPaymentUpdate{Status: entities.ActiveSchedule})
+		require.NoError(t, err)
+
+		runs, err = svc.RunScheduledPayments(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, 1, runs)
+		assert.Equal(t, "8", balance(t, svc, "payer"))
+
+		cancelled, err := svc.CancelScheduledPayment(ctx, created.ID)
+		require.NoError(t, err)
+		assert.Equal(t, entities.CancelledSchedule, cancelled.Status)
+
+		_, err = svc.CancelScheduledPayment(ctx, created.ID)
+		assert.EqualError(t, err, "scheduled payment has already been completed or cancelled")
+
+		_, err = svc.CancelScheduledPayment(ctx, 1000)
+		assert.EqualError(t, err, "scheduled payment not found")
+	})
+
+	t.Run("validates scheduled payments", func(t *testing.T) {
+		svc, _ := setup(t, 10)
+
+		_, err := svc.CreateScheduledPayment(ctx, order("hourly", 1))
+		assert.EqualError(t, err, "frequency should be one of once, daily, weekly, monthly or cron")
+
+		daily := order(entities.DailyFrequency, 1)
+		daily.Cron = "* * * * *"
+		_, err = svc.CreateScheduledPayment(ctx, daily)
+		assert.EqualError(t, err, "cron expression may only be set for cron frequency")
+
+		invalidCron := order(entities.CronFrequency, 1)
+		invalidCron.Cron = "0 25 * * *"
+		_, err = svc.CreateScheduledPayment(ctx, invalidCron)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "cron expression is invalid")
+
+		past := order(entities.OnceFrequency, 1)
+		past.StartAt = time.Now().Add(-time.Hour)
+		_, err = svc.CreateScheduledPayment(ctx, past)
+		assert.EqualError(t, err, "scheduled payment can't start in the past")
+
+		ended := order(entities.DailyFrequency, 1)
+		ended.StartAt = time.Now().Add(time.Hour)
+		ended.EndAt = time.Now()
+		_, err = svc.CreateScheduledPayment(ctx, ended)
+		assert.EqualError(t, err, "scheduled payment should end after its first run")
+
+		_, err = svc.CreateScheduledPayment(ctx, order(entities.DailyFrequency, 0))
+		assert.EqualError(t, err, "amount transferred should be a positive number")
+
+		_, err = svc.UpdateScheduledPayment(ctx, 1, entities.ScheduledPaymentUpdate{Status: entities.CompletedSchedule})
+		assert.EqualError(t, err, "scheduled payment can only be paused or resumed")
+	})
+}
@@ -0,0 +1,64 @@
+package banking
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/hex"
+
+	"github.com/pkg/errors"
+)
+
+// SecretBoxKeySize is the size of keys SecretBox seals secrets with
+const SecretBoxKeySize = 32
+
+var errSealedSecretInvalid = errors.New("sealed secret is malformed")
+
+// SecretBox seals API key secrets with a server side key (AES-256-GCM), so that
+// the service could verify request signatures without keeping secrets in plain form.
+// Sealed secrets are bound to the id of their key and can't be moved to another one.
+type SecretBox struct {
+	aead cipher.AEAD
+}
+
+// NewSecretBox returns a SecretBox sealing secrets with the key of SecretBoxKeySize bytes.
+func NewSecretBox(key []byte) (*SecretBox, error) {
+	if len(key) != SecretBoxKeySize {
+		return nil, errors.Errorf("secret box key should be %d bytes long", SecretBoxKeySize)
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, errors.Wrap(err, "can't set up secret box cipher")
+	}
+
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, errors.Wrap(err, "can't set up secret box cipher")
+	}
+	return &SecretBox{aead: aead}, nil
+}
+
+// seal returns the secret of the key encrypted with a random nonce, both hex encoded
+func (b *SecretBox) seal(keyID string, secret string) (string, error) {
+	nonce := make([]byte, b.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", errors.Wrap(err, "can't generate nonce")
+	}
+	return hex.EncodeToString(b.aead.Seal(nonce, nonce, []byte(secret), []byte(keyID))), nil
+}
+
+// open returns the secret of the key sealed with seal
+func (b *SecretBox) open(keyID string, sealed string) (string, error) {
+	data, err := hex.DecodeString(sealed)
+	if err != nil || len(data) < b.aead.NonceSize() {
+		return "", errSealedSecretInvalid
+	}
+
+	nonce, ciphertext := data[:b.aead.NonceSize()], data[b.aead.NonceSize():]
+	secret, err := b.aead.Open(nil, nonce, ciphertext, []byte(keyID))
+	if err != nil {
+		return "", errSealedSecretInvalid
+	}
+	return string(secret), nil
+}
@@ -4,10 +4,14 @@ package banking
 
 import (
 	"context"
+	"sort"
+	"time"
 
 	"github.com/pkg/errors"
 	"github.com/shopspring/decimal"
 	"github.com/twonegatives/coinsph_challenge/pkg/entities"
+	"github.com/twonegatives/coinsph_challenge/pkg/fees"
+	"github.com/twonegatives/coinsph_challenge/pkg/fx"
 	"github.com/twonegatives/coinsph_challenge/pkg/storage"
 )
 
@@ -17,6 +21,13 @@ var (
 	errSenderIsReceiver       = errors.New("can't transfer funds to the same account")
 	errInsufficientFunds      = errors.New("sender account has insufficient funds")
 	errAccountNameBlank       = errors.New("account name should be present")
+	errAccountNameReserved    = errors.New("account name is reserved for system use")
+	errUnsupportedCurrency    = errors.New("currency is not supported")
+	errCurrencyMismatch       = errors.New("sender and receiver accounts should have the same currency")
+	errAmountPrecision        = errors.New("amount has more decimal places than its currency allows")
+	errTransactionNotFound    = errors.New("transaction not found")
+	errAccountNotFound        = errors.New("account not found")
+	errHouseAccountTransfer   = errors.New("house accounts can't take part in transfers, use deposits and withdrawals instead")
 )
 
 //go:generate mockgen -source=service.go -destination ../mocks/mock_banking_service.go -package mocks
@@ -24,53 +35,379 @@ var (
 // BankingService is an abstraction which contains declarations of methods
 // used to create/show Accounts and Payments.
 type BankingService interface {
-	CreateAccount(ctx context.Context, accountName string) (entities.Account, error)
-	GetAccountsList(ctx context.Context) ([]entities.Account, error)
-	GetPaymentsList(ctx context.Context) ([]entities.Payment, error)
-	SendPayment(ctx context.Context, from entities.Account, to entities.Account, amount decimal.Decimal) error
+	CreateAccount(ctx context.Context, accountName string, currency entities.Currency, profile entities.AccountProfile) (entities.Account, error)
+	UpdateAccount(ctx context.Context, accountName string, update entities.AccountUpdate) (entities.Account, error)
+	GetAccountsList(ctx context.Context, filter entities.AccountsFilter) (entities.AccountsPage, error)
+	GetAccount(ctx context.Context, name string) (entities.Account, error)
+	GetPaymentsList(ctx context.Context, filter entities.PaymentsFilter) (entities.PaymentsPage, error)
+	GetAccountPayments(ctx context.Context, name string, filter entities.PaymentsFilter) (entities.PaymentsPage, error)
+	SendPayment(ctx context.Context, from entities.Account, to entities.Account, amount decimal.Decimal, details entities.PaymentDetails) (entities.TransferReceipt, error)
+	PreviewPayment(ctx context.Context, from entities.Account, to entities.Account, amount decimal.Decimal) (entities.PaymentPreview, error)
+	SendPaymentBatch(ctx context.Context, orders []entities.PaymentOrder, mode entities.BatchMode) (entities.BatchReceipt, error)
+	PostTransaction(ctx context.Context, legs []entities.Leg) (entities.PostingReceipt, error)
+	GetTransaction(ctx context.Context, id int) (entities.Transaction, []entities.Payment, error)
+	ReverseTransaction(ctx context.Context, id int) (entities.TransferReceipt, error)
+	RefundTransaction(ctx context.Context, id int, amount decimal.Decimal) (entities.TransferReceipt, error)
+
+	Deposit(ctx context.Context, accountName string, amount decimal.Decimal, reference string) (entities.TransferReceipt, error)
+	Withdraw(ctx context.Context, accountName string, amount decimal.Decimal, reference string) (entities.TransferReceipt, error)
+
+	FreezeAccount(ctx context.Context, accountName string) (entities.Account, error)
+	UnfreezeAccount(ctx context.Context, accountName string) (entities.Account, error)
+	CloseAccount(ctx context.Context, accountName string) (entities.Account, error)
+
+	SetCreditLimit(ctx context.Context, accountName string, limit decimal.Decimal, changedBy string, reason string) (entities.Account, error)
+	GetCreditLimitChanges(ctx context.Context, accountName string) ([]entities.CreditLimitChange, error)
+
+	CreateQuote(ctx context.Context, from entities.Currency, to entities.Currency, amount decimal.Decimal) (entities.Quote, error)
+	SendFXPayment(ctx context.Context, from entities.Account, to entities.Account, amount decimal.Decimal, quoteID int, details entities.PaymentDetails) (entities.TransferReceipt, error)
+
+	Authorize(ctx context.Context, from entities.Account, to entities.Account, amount decimal.Decimal) (entities.Hold, error)
+	GetHold(ctx context.Context, id int) (entities.Hold, error)
+	CaptureHold(ctx context.Context, id int, amount decimal.Decimal) (entities.TransferReceipt, error)
+	VoidHold(ctx context.Context, id int) (entities.Hold, error)
+	ExpireHolds(ctx context.Context) (int, error)
+
+	CreateScheduledPayment(ctx context.Context, payment entities.ScheduledPayment) (entities.ScheduledPayment, error)
+	GetScheduledPayment(ctx context.Context, id int) (entities.ScheduledPayment, error)
+	GetScheduledPayments(ctx context.Context, accountName string) ([]entities.ScheduledPayment, error)
+	UpdateScheduledPayment(ctx context.Context, id int, update entities.ScheduledPaymentUpdate) (entities.ScheduledPayment, error)
+	CancelScheduledPayment(ctx context.Context, id int) (entities.ScheduledPayment, error)
+	GetScheduledRuns(ctx context.Context, id int) ([]entities.ScheduledRun, error)
+	RunScheduledPayments(ctx context.Context) (int, error)
+
+	CreateAPIKey(ctx context.Context, name string, role entities.Role, ownerID string) (entities.IssuedAPIKey, error)
+	GetAPIKeys(ctx context.Context) ([]entities.APIKey, error)
+	RevokeAPIKey(ctx context.Context, keyID string) (entities.APIKey, error)
+
+	GetAuditLog(ctx context.Context, filter entities.AuditFilter) (entities.AuditPage, error)
+	VerifyAuditLog(ctx context.Context) (entities.AuditVerification, error)
 }
 
 // Service is an implementation of BankingService.
 type Service struct {
-	store storage.Storage
+	store       storage.Storage
+	currencies  *entities.CurrencyRegistry
+	rates       fx.RateProvider
+	feeSchedule *fees.Schedule
+	quoteTTL    time.Duration
+	holdTTL     time.Duration
+	now         func() time.Time
+	secrets     *SecretBox
+
+	schedulerID           string
+	scheduleLeaseTTL      time.Duration
+	scheduleRetryInterval time.Duration
+	scheduleMaxRetries    int
+}
+
+// ServiceOption allows to alter Service defaults on construction.
+type ServiceOption func(*Service)
+
+// WithCurrencies sets a registry of currencies accounts may be opened in.
+// entities.DefaultCurrencyRegistry is used unless this option is passed.
+func WithCurrencies(registry *entities.CurrencyRegistry) ServiceOption {
+	return func(svc *Service) {
+		svc.currencies = registry
+	}
+}
+
+// WithRateProvider sets a source of exchange rates used to quote
+// transfers between accounts holding different currencies.
+func WithRateProvider(rates fx.RateProvider) ServiceOption {
+	return func(svc *Service) {
+		svc.rates = rates
+	}
+}
+
+// WithFeeSchedule sets fees charged to senders of transfers.
+// Transfers are free of charge unless this option is passed.
+func WithFeeSchedule(schedule *fees.Schedule) ServiceOption {
+	return func(svc *Service) {
+		svc.feeSchedule = schedule
+	}
+}
+
+// WithQuoteTTL sets how long exchange rate quotes stay valid.
+func WithQuoteTTL(ttl time.Duration) ServiceOption {
+	return func(svc *Service) {
+		svc.quoteTTL = ttl
+	}
 }
 
-func NewService(s storage.Storage) *Service {
-	return &Service{
-		store: s,
+// WithHoldTTL sets how long authorizations may be captured after being placed.
+func WithHoldTTL(ttl time.Duration) ServiceOption {
+	return func(svc *Service) {
+		svc.holdTTL = ttl
 	}
 }
 
-// CreateAccount method accepts a Account object with Name field filled in.
+// WithSchedulerID sets a name the service claims scheduled payments under.
+// It should be unique among replicas of the service, host name and process ID are used by default.
+func WithSchedulerID(id string) ServiceOption {
+	return func(svc *Service) {
+		svc.schedulerID = id
+	}
+}
+
+// WithScheduleLeaseTTL sets how long a claimed scheduled payment stays out of reach
+// of other replicas. It should be well above the time a single run takes.
+func WithScheduleLeaseTTL(ttl time.Duration) ServiceOption {
+	return func(svc *Service) {
+		svc.scheduleLeaseTTL = ttl
+	}
+}
+
+// WithScheduleRetries sets how many times and how often a scheduled payment run
+// declined for insufficient funds is retried.
+func WithScheduleRetries(maxRetries int, interval time.Duration) ServiceOption {
+	return func(svc *Service) {
+		svc.scheduleMaxRetries = maxRetries
+		svc.scheduleRetryInterval = interval
+	}
+}
+
+// WithSecretBox makes issued API keys keep their secrets sealed with the box,
+// so that they could sign requests. Keys issued without it are good for bearer tokens only.
+func WithSecretBox(box *SecretBox) ServiceOption {
+	return func(svc *Service) {
+		svc.secrets = box
+	}
+}
+
+func NewService(s storage.Storage, opts ...ServiceOption) *Service {
+	svc := &Service{
+		store:       s,
+		currencies:  entities.DefaultCurrencyRegistry(),
+		rates:       fx.NewStaticRateProvider(),
+		feeSchedule: fees.NewSchedule(),
+		quoteTTL:    defaultQuoteTTL,
+		holdTTL:     defaultHoldTTL,
+		now:         time.Now,
+
+		schedulerID:           defaultSchedulerID(),
+		scheduleLeaseTTL:      defaultScheduleLeaseTTL,
+		scheduleRetryInterval: defaultScheduleRetryInterval,
+		scheduleMaxRetries:    defaultScheduleMaxRetries,
+	}
+
+	for _, opt := range opts {
+		opt(svc)
+	}
+
+	return svc
+}
+
+// CreateAccount method accepts account name, currency and profile.
 // Tries to create a new account with this name. Returns Account entity with
 // all the attributes set up on success.
-func (svc *Service) CreateAccount(ctx context.Context, accountName string) (entities.Account, error) {
+func (svc *Service) CreateAccount(ctx context.Context, accountName string, currency entities.Currency, profile entities.AccountProfile) (entities.Account, error) {
 	if accountName == "" {
 		return entities.Account{}, errAccountNameBlank
 	}
-	account, err := svc.store.CreateAccount(ctx, accountName)
-	return account, errors.Wrap(err, "failed to create new account in database")
+
+	if entities.IsReservedAccountName(accountName) {
+		return entities.Account{}, errAccountNameReserved
+	}
+
+	if _, ok := svc.currencies.Lookup(currency); !ok {
+		return entities.Account{}, errUnsupportedCurrency
+	}
+
+	profile, err := normalizeAccountProfile(profile)
+	if err != nil {
+		return entities.Account{}, err
+	}
+
+	if profile, err = ownedProfile(ctx, profile); err != nil {
+		return entities.Account{}, err
+	}
+
+	var account entities.Account
+	err = svc.inTx(ctx, func(txStorage storage.Storage) (err error) {
+		account, err = txStorage.CreateAccount(ctx, entities.Account{Name: accountName, Type: entities.UserAccount, Currency: currency}.WithProfile(profile))
+		return errors.Wrap(err, "failed to create new account in database")
+	})
+	return account, err
 }
 
-// GetAccountsList returns all the accounts which currently exist in system.
-func (svc *Service) GetAccountsList(ctx context.Context) ([]entities.Account, error) {
-	accounts, err := svc.store.GetAccountsList(ctx)
-	return accounts, errors.Wrap(err, "failed to fetch accounts list from database")
+// GetAccountsList returns a page of accounts matching the filter.
+// Accounts are sorted by name unless the filter sets another order.
+// NextCursor of the page is set when there are more accounts to fetch.
+func (svc *Service) GetAccountsList(ctx context.Context, filter entities.AccountsFilter) (entities.AccountsPage, error) {
+	if err := validateAccountsFilter(&filter); err != nil {
+		return entities.AccountsPage{}, err
+	}
+
+	// one extra account is requested to find out whether the next page exists
+	limit := filter.Limit
+	filter.Limit++
+
+	accounts, err := svc.store.GetAccountsList(ctx, filter)
+	if err != nil {
+		return entities.AccountsPage{}, errors.Wrap(err, "failed to fetch accounts list from database")
+	}
+
+	page := entities.AccountsPage{Accounts: accounts}
+	if len(accounts) > limit {
+		page.Accounts = accounts[:limit]
+		page.NextCursor = entities.NewAccountsCursor(filter, accounts[limit-1]).Encode()
+	}
+
+	return page, nil
 }
 
-// GetPaymentsList returns all the payments which currently exist in system.
-func (svc *Service) GetPaymentsList(ctx context.Context) ([]entities.Payment, error) {
-	payments, err := svc.store.GetPaymentsList(ctx)
-	return payments, errors.Wrap(err, "failed to fetch payments list from database")
+// GetPaymentsList returns a page of payments matching the filter.
+// Up to DefaultPageLimit payments are returned unless the filter sets another limit.
+// NextCursor of the page is set when there are more payments to fetch.
+func (svc *Service) GetPaymentsList(ctx context.Context, filter entities.PaymentsFilter) (entities.PaymentsPage, error) {
+	if err := validatePaymentsFilter(&filter); err != nil {
+		return entities.PaymentsPage{}, err
+	}
+
+	// one extra payment is requested to find out whether the next page exists
+	limit := filter.Limit
+	filter.Limit++
+
+	payments, err := svc.store.GetPaymentsList(ctx, filter)
+	if err != nil {
+		return entities.PaymentsPage{}, errors.Wrap(err, "failed to fetch payments list from database")
+	}
+
+	return paymentsPage(payments, limit), nil
+}
+
+// GetAccount returns an Account found by its name.
+func (svc *Service) GetAccount(ctx context.Context, name string) (entities.Account, error) {
+	account, err := svc.store.GetAccount(ctx, name)
+	if errors.Cause(err) == storage.ErrNotFound {
+		return entities.Account{}, errAccountNotFound
+	}
+	return account, errors.Wrap(err, "failed to fetch account from database")
+}
+
+// GetAccountPayments returns a page of payments of the account matching the filter.
+// Paging works the same way as it does for GetPaymentsList.
+func (svc *Service) GetAccountPayments(ctx context.Context, name string, filter entities.PaymentsFilter) (entities.PaymentsPage, error) {
+	if err := validatePaymentsFilter(&filter); err != nil {
+		return entities.PaymentsPage{}, err
+	}
+
+	account, err := svc.GetAccount(ctx, name)
+	if err != nil {
+		return entities.PaymentsPage{}, err
+	}
+
+	// one extra payment is requested to find out whether the next page exists
+	limit := filter.Limit
+	filter.Limit++
+
+	payments, err := svc.store.GetAccountPayments(ctx, account.ID, filter)
+	if err != nil {
+		return entities.PaymentsPage{}, errors.Wrap(err, "failed to fetch account payments from database")
+	}
+
+	return paymentsPage(payments, limit), nil
 }
 
 // SendPayment attempts to transfer 'amount' of money between 'from' and 'to' Accounts.
+// The fee set by the fee schedule is charged to 'from' on top of 'amount' and booked
+// within the same transaction as a payment to FEE account of the currency.
+// Description, external reference and metadata of 'details' are stored along with the transaction.
 // Returns error in the following cases:
 // - 'from' and 'to' are the same account
-// - 'from' has insufficient funds (balance would go < 0 after transfer)
+// - either 'from' or 'to' is a house (e.g. SYSTEM or FX) account
+// - either 'from' or 'to' is frozen or closed
+// - 'from' and 'to' hold different currencies
+// - 'amount' has more decimal places than the currency allows
+// - 'details' are too large, or their external reference was already used by 'from'
+// - 'from' has insufficient funds (available balance would go below its credit limit after transfer and fee)
 // - either 'from' or 'to' account is not present in system
 // - there is an existing mismatch between Account's balance and his/her payments (checked by db trigger)
-func (svc *Service) SendPayment(ctx context.Context, from entities.Account, to entities.Account, amount decimal.Decimal) error {
+// Returns a TransferReceipt with the booked transaction on success.
+func (svc *Service) SendPayment(ctx context.Context, from entities.Account, to entities.Account, amount decimal.Decimal, details entities.PaymentDetails) (entities.TransferReceipt, error) {
+	if err := validatePaymentOrder(from, to, amount); err != nil {
+		return entities.TransferReceipt{}, err
+	}
+
+	if err := validatePaymentDetails(details); err != nil {
+		return entities.TransferReceipt{}, err
+	}
+
+	txStorage, err := svc.store.BeginTx(ctx, nil)
+	defer txStorage.RollbackTx(ctx)
+
+	if err != nil {
+		return entities.TransferReceipt{}, errors.Wrap(err, "can't open transaction")
+	}
+
+	// We need to lock Account rows safely in a determined order.
+	// By having sender and receiver sorted by name and locked in this
+	// order we ensure that our code is not a subject to a deadlock
+	if err := lockAccounts(ctx, txStorage, paymentSide{account: &from, label: "sender"}, paymentSide{account: &to, label: "receiver"}); err != nil {
+		return entities.TransferReceipt{}, err
+	}
+
+	receipt, err := svc.transfer(ctx, txStorage, &from, &to, amount, details)
+	if err != nil {
+		return entities.TransferReceipt{}, err
+	}
+
+	if err := svc.commitTx(ctx, txStorage); err != nil {
+		return entities.TransferReceipt{}, errors.Wrap(err, "transaction commit failed")
+	}
+
+	return receipt, nil
+}
+
+// commitTx commits the storage transaction along with the records of the call in progress:
+// its idempotency key, unless a previous transaction committed it, and a succeeded audit entry.
+func (svc *Service) commitTx(ctx context.Context, txStorage storage.Storage) error {
+	idempotency, err := commitIdempotencyRecord(ctx, txStorage)
+	if err != nil {
+		return err
+	}
+
+	audit, ok := ctx.Value(auditContextKey{}).(*pendingAudit)
+	if ok {
+		if err := svc.appendAuditEntry(ctx, txStorage, audit.entry); err != nil {
+			return err
+		}
+	}
+
+	if err := txStorage.CommitTx(ctx); err != nil {
+		return err
+	}
+
+	if idempotency != nil {
+		idempotency.committed = true
+	}
+
+	if audit != nil {
+		audit.commits++
+	}
+	return nil
+}
+
+// inTx runs the write within a storage transaction, committed along with
+// the records of the call in progress
+func (svc *Service) inTx(ctx context.Context, write func(storage.Storage) error) error {
+	txStorage, err := svc.store.BeginTx(ctx, nil)
+	if err != nil {
+		return errors.Wrap(err, "can't open transaction")
+	}
+
+	defer txStorage.RollbackTx(ctx)
+
+	if err := write(txStorage); err != nil {
+		return err
+	}
+
+	return errors.Wrap(svc.commitTx(ctx, txStorage), "transaction commit failed")
+}
+
+// validatePaymentOrder makes checks of a transfer which need no accounts to be locked.
+func validatePaymentOrder(from entities.Account, to entities.Account, amount decimal.Decimal) error {
 	if amount.LessThanOrEqual(decimal.NewFromFloat(0)) {
 		return errAmountShouldBePositive
 	}
@@ -79,72 +416,146 @@ func (svc *Service) SendPayment(ctx context.Context, from entities.Account, to e
 		return errNamesNotPresent
 	}
 
-	if from == to {
+	if from.Name == to.Name {
 		return errSenderIsReceiver
 	}
 
-	txStorage, err := svc.store.BeginTx(ctx, nil)
-	defer txStorage.RollbackTx(ctx)
+	return nil
+}
 
+// transfer books 'amount' along with its fee and details from one locked account to another
+// within the storage transaction and updates balances of the accounts accordingly.
+// Returns a TransferReceipt with the booked transaction on success.
+func (svc *Service) transfer(ctx context.Context, txStorage storage.Storage, from *entities.Account, to *entities.Account, amount decimal.Decimal, details entities.PaymentDetails) (entities.TransferReceipt, error) {
+	if from.Type.IsHouse() || to.Type.IsHouse() {
+		return entities.TransferReceipt{}, errHouseAccountTransfer
+	}
+
+	if err := checkOwnership(ctx, *from); err != nil {
+		return entities.TransferReceipt{}, err
+	}
+
+	if err := checkAccountsActive(*from, *to); err != nil {
+		return entities.TransferReceipt{}, err
+	}
+
+	if from.Currency != to.Currency {
+		return entities.TransferReceipt{}, errCurrencyMismatch
+	}
+
+	if err := svc.validateAmount(from.Currency, amount); err != nil {
+		return entities.TransferReceipt{}, err
+	}
+
+	fee, err := svc.transferFee(from.Currency, amount)
 	if err != nil {
-		return errors.Wrap(err, "can't open transaction")
+		return entities.TransferReceipt{}, err
 	}
 
-	// We need to lock Account rows safely in a determined order.
-	// By having sender and receiver sorted by name and locked in this
-	// order we ensure that our code is not a subject to a deadlock
-	paymentSides := getSortedPaymentSides(&from, &to)
-	for _, side := range paymentSides {
-		if err := txStorage.GetAccountForUpdate(ctx, side.account); err != nil {
-			return errors.Wrapf(err, "can't obtain %s account", side.label)
-		}
+	if !from.CanSpend(amount.Add(fee)) {
+		return entities.TransferReceipt{}, errInsufficientFunds
+	}
+
+	transaction := withDetails(entities.Transaction{Kind: entities.TransferTransaction}, *from, details)
+	if fee.IsPositive() {
+		transaction.Fee = fee
+	}
+
+	transaction, err = createTransaction(ctx, txStorage, transaction)
+	if errors.Cause(err) == storage.ErrAlreadyExists {
+		return entities.TransferReceipt{}, errExternalReferenceUsed
+	}
+	if err != nil {
+		return entities.TransferReceipt{}, errors.Wrap(err, "can't insert new transaction")
 	}
 
-	if from.Balance.LessThan(amount) && !from.MayGoBelowZero() {
-		return errInsufficientFunds
+	payments, err := bookPayments(ctx, txStorage, transaction, from, to, amount)
+	if err != nil {
+		return entities.TransferReceipt{}, err
 	}
 
-	transaction, err := txStorage.CreateTransaction(ctx)
+	feePayments, err := bookFee(ctx, txStorage, transaction, from, fee)
 	if err != nil {
-		return errors.Wrap(err, "can't insert new transaction")
+		return entities.TransferReceipt{}, err
 	}
 
+	return entities.TransferReceipt{
+		Transaction:     transaction,
+		Payments:        append(payments, feePayments...),
+		Fee:             transaction.Fee,
+		SenderBalance:   from.Balance,
+		ReceiverBalance: to.Balance,
+	}, nil
+}
+
+// GetTransaction returns a Transaction found by its ID along with all of its payments.
+func (svc *Service) GetTransaction(ctx context.Context, id int) (entities.Transaction, []entities.Payment, error) {
+	transaction, err := svc.store.GetTransaction(ctx, id)
+	if errors.Cause(err) == storage.ErrNotFound {
+		return entities.Transaction{}, nil, errTransactionNotFound
+	}
+	if err != nil {
+		return entities.Transaction{}, nil, errors.Wrap(err, "failed to fetch transaction from database")
+	}
+
+	payments, err := svc.store.GetTransactionPayments(ctx, id)
+	return transaction, payments, errors.Wrap(err, "failed to fetch transaction payments from database")
+}
+
+// validateAmount checks that amount may be expressed in the given currency.
+func (svc *Service) validateAmount(code entities.Currency, amount decimal.Decimal) error {
+	currency, ok := svc.currencies.Lookup(code)
+	if !ok {
+		return errUnsupportedCurrency
+	}
+
+	if !currency.IsValidAmount(amount) {
+		return errAmountPrecision
+	}
+
+	return nil
+}
+
+// bookPayments stores a pair of opposite payments moving 'amount' from one
+// locked account to another and updates balances of both accounts accordingly.
+// Returns the stored payments.
+func bookPayments(ctx context.Context, txStorage storage.Storage, transaction entities.Transaction, from *entities.Account, to *entities.Account, amount decimal.Decimal) ([]entities.Payment, error) {
 	outgoingPayment := entities.Payment{
-		Account:      from,
-		Counterparty: to,
+		Account:      *from,
+		Counterparty: *to,
 		Amount:       amount,
 		Transaction:  transaction,
 		Direction:    entities.Outgoing,
-		Currency:     entities.USD,
+		Currency:     from.Currency,
 	}
 	if err := txStorage.SendPayment(ctx, outgoingPayment); err != nil {
-		return errors.Wrap(err, "can't insert outgoing payment")
+		return nil, errors.Wrap(err, "can't insert outgoing payment")
 	}
 
 	incomingPayment := entities.Payment{
-		Account:      to,
-		Counterparty: from,
+		Account:      *to,
+		Counterparty: *from,
 		Amount:       amount,
 		Transaction:  transaction,
 		Direction:    entities.Incoming,
-		Currency:     entities.USD,
+		Currency:     to.Currency,
 	}
 
 	if err := txStorage.SendPayment(ctx, incomingPayment); err != nil {
-		return errors.Wrap(err, "can't insert incoming payment")
+		return nil, errors.Wrap(err, "can't insert incoming payment")
 	}
 
 	from.Balance = from.Balance.Sub(amount)
-	if err := txStorage.SetAccountBalance(ctx, from); err != nil {
-		return errors.Wrap(err, "can't update sender account balance")
+	if err := txStorage.SetAccountBalance(ctx, *from); err != nil {
+		return nil, errors.Wrap(err, "can't update sender account balance")
 	}
 
 	to.Balance = to.Balance.Add(amount)
-	if err := txStorage.SetAccountBalance(ctx, to); err != nil {
-		return errors.Wrap(err, "can't update counterparty balance")
+	if err := txStorage.SetAccountBalance(ctx, *to); err != nil {
+		return nil, errors.Wrap(err, "can't update counterparty balance")
 	}
 
-	return errors.Wrap(txStorage.CommitTx(ctx), "transaction commit failed")
+	return []entities.Payment{outgoingPayment, incomingPayment}, nil
 }
 
 type paymentSide struct {
@@ -152,14 +563,23 @@ type paymentSide struct {
 	label   string
 }
 
-// sorts [from, to] accounts by name and returns a slice in determined order
-func getSortedPaymentSides(from *entities.Account, to *entities.Account) []paymentSide {
-	sender := paymentSide{account: from, label: "sender"}
-	receiver := paymentSide{account: to, label: "receiver"}
+// sorts payment sides by account name and returns a slice in determined order
+func getSortedPaymentSides(sides ...paymentSide) []paymentSide {
+	sorted := make([]paymentSide, len(sides))
+	copy(sorted, sides)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].account.Name > sorted[j].account.Name
+	})
+	return sorted
+}
 
-	if from.Name > to.Name {
-		return []paymentSide{sender, receiver}
+// lockAccounts obtains row locks of the given accounts in a determined order
+// and fills in their attributes with the current state from storage
+func lockAccounts(ctx context.Context, txStorage storage.Storage, sides ...paymentSide) error {
+	for _, side := range getSortedPaymentSides(sides...) {
+		if err := txStorage.GetAccountForUpdate(ctx, side.account); err != nil {
+			return errors.Wrapf(err, "can't obtain %s account", side.label)
+		}
 	}
-
-	return []paymentSide{receiver, sender}
+	return nil
 }
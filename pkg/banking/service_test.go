@@ -3,6 +3,7 @@ package banking_test
 import (
 	"context"
 	"testing"
+	"time"
 
 	"github.com/golang/mock/gomock"
 	"github.com/pkg/errors"
@@ -12,6 +13,7 @@ import (
 	"github.com/twonegatives/coinsph_challenge/pkg/banking"
 	"github.com/twonegatives/coinsph_challenge/pkg/entities"
 	"github.com/twonegatives/coinsph_challenge/pkg/mocks"
+	pkgstorage "github.com/twonegatives/coinsph_challenge/pkg/storage"
 )
 
 var (
@@ -26,23 +28,49 @@ func TestBankingSvcCreateAccount(t *testing.T) {
 		storage := mocks.NewMockStorage(mCtrl)
 
 		accName := "bunny"
-		storageResult := entities.Account{Name: accName}
-		storage.EXPECT().CreateAccount(ctx, accName).Return(storageResult, nil)
+		storageResult := entities.Account{Name: accName, Type: entities.UserAccount, Currency: entities.EUR}
+		storage.EXPECT().BeginTx(ctx, nil).Return(storage, nil)
+		storage.EXPECT().CreateAccount(ctx, entities.Account{Name: accName, Type: entities.UserAccount, Currency: entities.EUR}).Return(storageResult, nil)
+		storage.EXPECT().CommitTx(ctx).Return(nil)
+		storage.EXPECT().RollbackTx(ctx).Return(nil)
 
-		account, err := banking.NewService(storage).CreateAccount(ctx, accName)
+		account, err := banking.NewService(storage).CreateAccount(ctx, accName, entities.EUR, entities.AccountProfile{})
 		require.NoError(t, err)
 		assert.Equal(t, storageResult, account)
 	})
 
+	t.Run("rejects unsupported currency", func(t *testing.T) {
+		mCtrl := gomock.NewController(t)
+		defer mCtrl.Finish()
+		storage := mocks.NewMockStorage(mCtrl)
+
+		registry := entities.NewCurrencyRegistry(entities.CurrencyInfo{Code: entities.USD, Precision: 2})
+		_, err := banking.NewService(storage, banking.WithCurrencies(registry)).CreateAccount(ctx, "bunny", entities.EUR, entities.AccountProfile{})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "currency is not supported")
+	})
+
+	t.Run("rejects names reserved for SYSTEM accounts", func(t *testing.T) {
+		mCtrl := gomock.NewController(t)
+		defer mCtrl.Finish()
+		storage := mocks.NewMockStorage(mCtrl)
+
+		_, err := banking.NewService(storage).CreateAccount(ctx, "SYSTEM_XYZ", entities.USD, entities.AccountProfile{})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "account name is reserved for system use")
+	})
+
 	t.Run("propagates storage exceptions", func(t *testing.T) {
 		mCtrl := gomock.NewController(t)
 		defer mCtrl.Finish()
 		storage := mocks.NewMockStorage(mCtrl)
 
 		accName := "duplicated_name"
-		storage.EXPECT().CreateAccount(ctx, accName).Return(entities.Account{}, ErrDB)
+		storage.EXPECT().BeginTx(ctx, nil).Return(storage, nil)
+		storage.EXPECT().CreateAccount(ctx, entities.Account{Name: accName, Type: entities.UserAccount, Currency: entities.USD}).Return(entities.Account{}, ErrDB)
+		storage.EXPECT().RollbackTx(ctx).Return(nil)
 
-		_, err := banking.NewService(storage).CreateAccount(ctx, accName)
+		_, err := banking.NewService(storage).CreateAccount(ctx, accName, entities.USD, entities.AccountProfile{})
 		require.Error(t, err)
 	})
 }
@@ -59,11 +87,59 @@ func TestBankingSvcGetAccountsList(t *testing.T) {
 				Currency: entities.USD,
 			},
 		}
-		storage.EXPECT().GetAccountsList(ctx).Return(storageResult, nil)
+		expectedFilter := entities.AccountsFilter{Sort: entities.SortAccountsByName, Limit: banking.DefaultPageLimit + 1}
+		storage.EXPECT().GetAccountsList(ctx, expectedFilter).Return(storageResult, nil)
+
+		page, err := banking.NewService(storage).GetAccountsList(ctx, entities.AccountsFilter{})
+		require.NoError(t, err)
+		assert.Equal(t, storageResult, page.Accounts)
+		assert.Empty(t, page.NextCursor)
+	})
+
+	t.Run("returns cursor of the next page", func(t *testing.T) {
+		mCtrl := gomock.NewController(t)
+		defer mCtrl.Finish()
+		storage := mocks.NewMockStorage(mCtrl)
+
+		storageResult := []entities.Account{
+			{ID: 3, Name: "rich", Balance: decimal.New(900, 0)},
+			{ID: 1, Name: "poor", Balance: decimal.New(10, 0)},
+		}
+		filter := entities.AccountsFilter{NamePrefix: "p", Sort: entities.SortAccountsByBalance, Descending: true, Limit: 1}
+		expectedFilter := filter
+		expectedFilter.Limit = 2
+		storage.EXPECT().GetAccountsList(ctx, expectedFilter).Return(storageResult, nil)
+
+		page, err := banking.NewService(storage).GetAccountsList(ctx, filter)
+		require.NoError(t, err)
+		assert.Equal(t, storageResult[:1], page.Accounts)
 
-		accounts, err := banking.NewService(storage).GetAccountsList(ctx)
+		cursor, err := entities.DecodeAccountsCursor(page.NextCursor)
 		require.NoError(t, err)
-		assert.Equal(t, storageResult, accounts)
+		assert.Equal(t, entities.SortAccountsByBalance, cursor.Sort)
+		assert.True(t, cursor.Descending)
+		assert.Equal(t, 3, cursor.ID)
+		assert.Equal(t, "900", cursor.Balance.String())
+	})
+
+	t.Run("validates filter", func(t *testing.T) {
+		invalidFilters := map[string]entities.AccountsFilter{
+			"limit should be between 1 and 500":               {Limit: -1},
+			"sort should be one of name, balance, created_at": {Sort: "currency"},
+			"cursor was issued for a list in different order": {Sort: entities.SortAccountsByBalance, After: &entities.AccountsCursor{Sort: entities.SortAccountsByName}},
+		}
+
+		for message, filter := range invalidFilters {
+			t.Run(message, func(t *testing.T) {
+				mCtrl := gomock.NewController(t)
+				defer mCtrl.Finish()
+				storage := mocks.NewMockStorage(mCtrl)
+
+				_, err := banking.NewService(storage).GetAccountsList(ctx, filter)
+				require.Error(t, err)
+				assert.Equal(t, message, err.Error())
+			})
+		}
 	})
 
 	t.Run("propagates storage exceptions", func(t *testing.T) {
@@ -71,12 +147,73 @@ func TestBankingSvcGetAccountsList(t *testing.T) {
 		defer mCtrl.Finish()
 		storage := mocks.NewMockStorage(mCtrl)
 
-		storage.EXPECT().GetAccountsList(ctx).Return(nil, ErrDB)
+		storage.EXPECT().GetAccountsList(ctx, gomock.Any()).Return(nil, ErrDB)
+
+		_, err := banking.NewService(storage).GetAccountsList(ctx, entities.AccountsFilter{})
+		require.Error(t, err)
+	})
+}
+
+func TestBankingSvcGetAccount(t *testing.T) {
+	t.Run("returns account", func(t *testing.T) {
+		mCtrl := gomock.NewController(t)
+		defer mCtrl.Finish()
+		storage := mocks.NewMockStorage(mCtrl)
+
+		storageResult := entities.Account{ID: 5, Name: "ben", Balance: decimal.New(19, 0), Currency: entities.USD}
+		storage.EXPECT().GetAccount(ctx, "ben").Return(storageResult, nil)
+
+		account, err := banking.NewService(storage).GetAccount(ctx, "ben")
+		require.NoError(t, err)
+		assert.Equal(t, storageResult, account)
+	})
+
+	t.Run("returns not found error for unknown account", func(t *testing.T) {
+		mCtrl := gomock.NewController(t)
+		defer mCtrl.Finish()
+		storage := mocks.NewMockStorage(mCtrl)
+
+		storage.EXPECT().GetAccount(ctx, "nobody").Return(entities.Account{}, errors.Wrap(pkgstorage.ErrNotFound, "account nobody"))
+
+		_, err := banking.NewService(storage).GetAccount(ctx, "nobody")
+		require.Error(t, err)
+		assert.Equal(t, "account not found", err.Error())
+	})
+}
+
+func TestBankingSvcGetAccountPayments(t *testing.T) {
+	t.Run("returns page of account payments", func(t *testing.T) {
+		mCtrl := gomock.NewController(t)
+		defer mCtrl.Finish()
+		storage := mocks.NewMockStorage(mCtrl)
+
+		createdAt := time.Date(2019, 4, 8, 10, 0, 0, 0, time.UTC)
+		storageResult := []entities.Payment{
+			{ID: 7, Transaction: entities.Transaction{ID: 4, CreatedAt: createdAt}},
+			{ID: 9, Transaction: entities.Transaction{ID: 5, CreatedAt: createdAt}},
+		}
+		storage.EXPECT().GetAccount(ctx, "ben").Return(entities.Account{ID: 5, Name: "ben"}, nil)
+		storage.EXPECT().GetAccountPayments(ctx, 5, entities.PaymentsFilter{Direction: entities.Incoming, Limit: 2}).Return(storageResult, nil)
 
-		_, err := banking.NewService(storage).GetAccountsList(ctx)
+		page, err := banking.NewService(storage).GetAccountPayments(ctx, "ben", entities.PaymentsFilter{Direction: entities.Incoming, Limit: 1})
+		require.NoError(t, err)
+		assert.Equal(t, storageResult[:1], page.Payments)
+		assert.Equal(t, entities.NewPaymentsCursor(storageResult[0]).Encode(), page.NextCursor)
+	})
+
+	t.Run("returns not found error for unknown account", func(t *testing.T) {
+		mCtrl := gomock.NewController(t)
+		defer mCtrl.Finish()
+		storage := mocks.NewMockStorage(mCtrl)
+
+		storage.EXPECT().GetAccount(ctx, "nobody").Return(entities.Account{}, errors.Wrap(pkgstorage.ErrNotFound, "account nobody"))
+
+		_, err := banking.NewService(storage).GetAccountPayments(ctx, "nobody", entities.PaymentsFilter{})
 		require.Error(t, err)
+		assert.Equal(t, "account not found", err.Error())
 	})
 }
+
 func TestBankingSvcGetPaymentsList(t *testing.T) {
 	t.Run("returns payments list", func(t *testing.T) {
 		mCtrl := gomock.NewController(t)
@@ -90,11 +227,58 @@ func TestBankingSvcGetPaymentsList(t *testing.T) {
 				Amount:       decimal.New(1000, 0),
 			},
 		}
-		storage.EXPECT().GetPaymentsList(ctx).Return(storageResult, nil)
+		storage.EXPECT().GetPaymentsList(ctx, entities.PaymentsFilter{Limit: banking.DefaultPageLimit + 1}).Return(storageResult, nil)
 
-		payments, err := banking.NewService(storage).GetPaymentsList(ctx)
+		page, err := banking.NewService(storage).GetPaymentsList(ctx, entities.PaymentsFilter{})
 		require.NoError(t, err)
-		assert.Equal(t, storageResult, payments)
+		assert.Equal(t, storageResult, page.Payments)
+		assert.Empty(t, page.NextCursor)
+	})
+
+	t.Run("returns cursor of the next page", func(t *testing.T) {
+		mCtrl := gomock.NewController(t)
+		defer mCtrl.Finish()
+		storage := mocks.NewMockStorage(mCtrl)
+
+		createdAt := time.Date(2019, 4, 8, 10, 0, 0, 0, time.UTC)
+		storageResult := []entities.Payment{
+			{ID: 1, Transaction: entities.Transaction{ID: 1, CreatedAt: createdAt}},
+			{ID: 2, Transaction: entities.Transaction{ID: 1, CreatedAt: createdAt}},
+			{ID: 3, Transaction: entities.Transaction{ID: 2, CreatedAt: createdAt.Add(time.Second)}},
+		}
+		filter := entities.PaymentsFilter{AccountName: "businessman", Limit: 2}
+		storage.EXPECT().GetPaymentsList(ctx, entities.PaymentsFilter{AccountName: "businessman", Limit: 3}).Return(storageResult, nil)
+
+		page, err := banking.NewService(storage).GetPaymentsList(ctx, filter)
+		require.NoError(t, err)
+		assert.Equal(t, storageResult[:2], page.Payments)
+
+		cursor, err := entities.DecodePaymentsCursor(page.NextCursor)
+		require.NoError(t, err)
+		assert.Equal(t, 2, cursor.ID)
+		assert.True(t, createdAt.Equal(cursor.CreatedAt))
+	})
+
+	t.Run("validates filter", func(t *testing.T) {
+		invalidFilters := map[string]entities.PaymentsFilter{
+			"limit should be between 1 and 500":                                     {Limit: banking.MaxPageLimit + 1},
+			"direction should be either incoming or outgoing":                       {Direction: "sideways"},
+			"kind should be one of transfer, deposit, withdrawal, reversal, refund": {Kind: "gift"},
+			"from_date should be earlier than to_date":                              {FromDate: time.Date(2019, 4, 8, 0, 0, 0, 0, time.UTC), ToDate: time.Date(2019, 4, 1, 0, 0, 0, 0, time.UTC)},
+			"min_amount should not exceed max_amount":                               {MinAmount: decimal.NullDecimal{Decimal: decimal.New(10, 0), Valid: true}, MaxAmount: decimal.NullDecimal{Decimal: decimal.New(1, 0), Valid: true}},
+		}
+
+		for message, filter := range invalidFilters {
+			t.Run(message, func(t *testing.T) {
+				mCtrl := gomock.NewController(t)
+				defer mCtrl.Finish()
+				storage := mocks.NewMockStorage(mCtrl)
+
+				_, err := banking.NewService(storage).GetPaymentsList(ctx, filter)
+				require.Error(t, err)
+				assert.Equal(t, message, err.Error())
+			})
+		}
 	})
 
 	t.Run("propagates storage exceptions", func(t *testing.T) {
@@ -102,9 +286,54 @@ func TestBankingSvcGetPaymentsList(t *testing.T) {
 		defer mCtrl.Finish()
 		storage := mocks.NewMockStorage(mCtrl)
 
-		storage.EXPECT().GetPaymentsList(ctx).Return(nil, ErrDB)
+		storage.EXPECT().GetPaymentsList(ctx, gomock.Any()).Return(nil, ErrDB)
 
-		_, err := banking.NewService(storage).GetPaymentsList(ctx)
+		_, err := banking.NewService(storage).GetPaymentsList(ctx, entities.PaymentsFilter{})
+		require.Error(t, err)
+	})
+}
+
+func TestBankingSvcGetTransaction(t *testing.T) {
+	t.Run("returns transaction with its payments", func(t *testing.T) {
+		mCtrl := gomock.NewController(t)
+		defer mCtrl.Finish()
+		storage := mocks.NewMockStorage(mCtrl)
+
+		transaction := entities.Transaction{ID: 42}
+		storagePayments := []entities.Payment{
+			{Transaction: transaction, Direction: entities.Outgoing, Amount: decimal.New(10, 0)},
+			{Transaction: transaction, Direction: entities.Incoming, Amount: decimal.New(10, 0)},
+		}
+		storage.EXPECT().GetTransaction(ctx, 42).Return(transaction, nil)
+		storage.EXPECT().GetTransactionPayments(ctx, 42).Return(storagePayments, nil)
+
+		actualTransaction, payments, err := banking.NewService(storage).GetTransaction(ctx, 42)
+		require.NoError(t, err)
+		assert.Equal(t, transaction, actualTransaction)
+		assert.Equal(t, storagePayments, payments)
+	})
+
+	t.Run("returns not found error for unknown transaction", func(t *testing.T) {
+		mCtrl := gomock.NewController(t)
+		defer mCtrl.Finish()
+		storage := mocks.NewMockStorage(mCtrl)
+
+		storage.EXPECT().GetTransaction(ctx, 404).Return(entities.Transaction{}, errors.Wrap(pkgstorage.ErrNotFound, "transaction 404"))
+
+		_, _, err := banking.NewService(storage).GetTransaction(ctx, 404)
+		require.Error(t, err)
+		assert.Equal(t, "transaction not found", err.Error())
+	})
+
+	t.Run("propagates storage exceptions", func(t *testing.T) {
+		mCtrl := gomock.NewController(t)
+		defer mCtrl.Finish()
+		storage := mocks.NewMockStorage(mCtrl)
+
+		storage.EXPECT().GetTransaction(ctx, 42).Return(entities.Transaction{ID: 42}, nil)
+		storage.EXPECT().GetTransactionPayments(ctx, 42).Return(nil, ErrDB)
+
+		_, _, err := banking.NewService(storage).GetTransaction(ctx, 42)
 		require.Error(t, err)
 	})
 }
@@ -130,12 +359,6 @@ func TestBankingSvcSendPayment(t *testing.T) {
 			amount:   decimal.New(1550, -2),
 			title:    "for transfer between user accounts",
 		},
-		{
-			sender:   account{name: "SYSTEM", balanceBefore: decimal.New(0, 0), balanceAfter: decimal.New(-150, 0)},
-			receiver: account{name: "receiver", balanceBefore: decimal.New(0, 0), balanceAfter: decimal.New(150, 0)},
-			amount:   decimal.New(150, 0),
-			title:    "for transfer of SYSTEM with overdraft (allows going below zero)",
-		},
 	}
 
 	t.Run("creates payment silently", func(t *testing.T) {
@@ -145,18 +368,20 @@ func TestBankingSvcSendPayment(t *testing.T) {
 				defer mCtrl.Finish()
 				storage := mocks.NewMockStorage(mCtrl)
 
-				from := entities.Account{Name: tt.sender.name, Balance: tt.sender.balanceBefore}
-				to := entities.Account{Name: tt.receiver.name, Balance: tt.receiver.balanceBefore}
+				from := entities.Account{Name: tt.sender.name, Balance: tt.sender.balanceBefore, Currency: entities.USD}
+				to := entities.Account{Name: tt.receiver.name, Balance: tt.receiver.balanceBefore, Currency: entities.USD}
 				amount := tt.amount
 
 				newSender := entities.Account{
-					Name:    from.Name,
-					Balance: tt.sender.balanceAfter,
+					Name:     from.Name,
+					Balance:  tt.sender.balanceAfter,
+					Currency: entities.USD,
 				}
 
 				newReceiver := entities.Account{
-					Name:    to.Name,
-					Balance: tt.receiver.balanceAfter,
+					Name:     to.Name,
+					Balance:  tt.receiver.balanceAfter,
+					Currency: entities.USD,
 				}
 
 				outgoing := entities.Payment{
@@ -180,7 +405,7 @@ func TestBankingSvcSendPayment(t *testing.T) {
 				storage.EXPECT().BeginTx(gomock.Any(), nil).Return(storage, nil)
 				storage.EXPECT().GetAccountForUpdate(gomock.Any(), &from).Return(nil)
 				storage.EXPECT().GetAccountForUpdate(gomock.Any(), &to).Return(nil)
-				storage.EXPECT().CreateTransaction(gomock.Any()).Return(entities.Transaction{}, nil)
+				storage.EXPECT().CreateTransaction(gomock.Any(), entities.Transaction{Kind: entities.TransferTransaction}).Return(entities.Transaction{}, nil)
 				storage.EXPECT().SendPayment(gomock.Any(), outgoing).Return(nil)
 				storage.EXPECT().SendPayment(gomock.Any(), incoming).Return(nil)
 				storage.EXPECT().SetAccountBalance(gomock.Any(), newSender).Return(nil)
@@ -188,8 +413,11 @@ func TestBankingSvcSendPayment(t *testing.T) {
 				storage.EXPECT().CommitTx(gomock.Any()).Return(nil)
 				storage.EXPECT().RollbackTx(gomock.Any()).Return(nil)
 
-				err := banking.NewService(storage).SendPayment(ctx, from, to, amount)
+				receipt, err := banking.NewService(storage).SendPayment(ctx, from, to, amount, entities.PaymentDetails{})
 				require.NoError(t, err)
+				assert.Equal(t, []entities.Payment{outgoing, incoming}, receipt.Payments)
+				assert.Equal(t, tt.sender.balanceAfter.String(), receipt.SenderBalance.String())
+				assert.Equal(t, tt.receiver.balanceAfter.String(), receipt.ReceiverBalance.String())
 			})
 		}
 
@@ -204,18 +432,79 @@ func TestBankingSvcSendPayment(t *testing.T) {
 		receiver := entities.Account{Name: "benjamin"}
 		amount := decimal.New(1356, -2)
 
-		err := banking.NewService(storage).SendPayment(ctx, sender, receiver, amount)
+		_, err := banking.NewService(storage).SendPayment(ctx, sender, receiver, amount, entities.PaymentDetails{})
 		require.Error(t, err)
 		assert.Contains(t, err.Error(), "can't transfer funds to the same account")
 	})
 
+	t.Run("catches transfer attempts involving house accounts", func(t *testing.T) {
+		user := entities.Account{Name: "receiver", Type: entities.UserAccount, Currency: entities.USD}
+		for _, house := range []entities.Account{
+			{Name: "SYSTEM", Type: entities.SystemAccount, Currency: entities.USD},
+			{Name: "FX_USD", Type: entities.SettlementAccount, Currency: entities.USD},
+			{Name: "FEES", Type: entities.FeeAccount, Currency: entities.USD},
+		} {
+			for _, pair := range [][2]entities.Account{{house, user}, {user, house}} {
+				mCtrl := gomock.NewController(t)
+				storage := mocks.NewMockStorage(mCtrl)
+
+				storage.EXPECT().BeginTx(gomock.Any(), nil).Return(storage, nil)
+				expectAccountsLocked(storage, pair[0], pair[1])
+				storage.EXPECT().RollbackTx(gomock.Any()).Return(nil)
+
+				_, err := banking.NewService(storage).SendPayment(ctx, entities.Account{Name: pair[0].Name}, entities.Account{Name: pair[1].Name}, decimal.New(150, 0), entities.PaymentDetails{})
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), "use deposits and withdrawals instead")
+				mCtrl.Finish()
+			}
+		}
+	})
+
+	t.Run("lets sender go below zero within its credit limit", func(t *testing.T) {
+		mCtrl := gomock.NewController(t)
+		defer mCtrl.Finish()
+		storage := mocks.NewMockStorage(mCtrl)
+
+		from := entities.Account{Name: "sender", Type: entities.UserAccount, Balance: decimal.New(15, 0), CreditLimit: decimal.New(35, 0), Currency: entities.USD}
+		to := entities.Account{Name: "receiver", Type: entities.UserAccount, Currency: entities.USD}
+
+		storage.EXPECT().BeginTx(gomock.Any(), nil).Return(storage, nil)
+		expectAccountsLocked(storage, from, to)
+		storage.EXPECT().CreateTransaction(gomock.Any(), gomock.Any()).Return(entities.Transaction{}, nil)
+		storage.EXPECT().SendPayment(gomock.Any(), gomock.Any()).Times(2).Return(nil)
+		storage.EXPECT().SetAccountBalance(gomock.Any(), gomock.Any()).Times(2).Return(nil)
+		storage.EXPECT().CommitTx(gomock.Any()).Return(nil)
+		storage.EXPECT().RollbackTx(gomock.Any()).Return(nil)
+
+		receipt, err := banking.NewService(storage).SendPayment(ctx, entities.Account{Name: "sender"}, entities.Account{Name: "receiver"}, decimal.New(50, 0), entities.PaymentDetails{})
+		require.NoError(t, err)
+		assert.Equal(t, "-35", receipt.SenderBalance.String())
+	})
+
+	t.Run("catches transfer attempts exceeding credit limit", func(t *testing.T) {
+		mCtrl := gomock.NewController(t)
+		defer mCtrl.Finish()
+		storage := mocks.NewMockStorage(mCtrl)
+
+		from := entities.Account{Name: "sender", Type: entities.UserAccount, Balance: decimal.New(15, 0), CreditLimit: decimal.New(35, 0), Currency: entities.USD}
+		to := entities.Account{Name: "receiver", Type: entities.UserAccount, Currency: entities.USD}
+
+		storage.EXPECT().BeginTx(gomock.Any(), nil).Return(storage, nil)
+		expectAccountsLocked(storage, from, to)
+		storage.EXPECT().RollbackTx(gomock.Any()).Return(nil)
+
+		_, err := banking.NewService(storage).SendPayment(ctx, entities.Account{Name: "sender"}, entities.Account{Name: "receiver"}, decimal.New(5001, -2), entities.PaymentDetails{})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "sender account has insufficient funds")
+	})
+
 	t.Run("catches transfer attempts exceeding balance", func(t *testing.T) {
 		mCtrl := gomock.NewController(t)
 		defer mCtrl.Finish()
 		storage := mocks.NewMockStorage(mCtrl)
 
-		from := entities.Account{Name: "sender", Balance: decimal.New(15, 0)}
-		to := entities.Account{Name: "receiver"}
+		from := entities.Account{Name: "sender", Balance: decimal.New(15, 0), Currency: entities.USD}
+		to := entities.Account{Name: "receiver", Currency: entities.USD}
 		amount := decimal.New(50, 0)
 
 		storage.EXPECT().BeginTx(gomock.Any(), nil).Return(storage, nil)
@@ -223,14 +512,85 @@ func TestBankingSvcSendPayment(t *testing.T) {
 		storage.EXPECT().GetAccountForUpdate(gomock.Any(), &to).Return(nil)
 		storage.EXPECT().RollbackTx(gomock.Any()).Return(nil)
 
-		err := banking.NewService(storage).SendPayment(ctx, from, to, amount)
+		_, err := banking.NewService(storage).SendPayment(ctx, from, to, amount, entities.PaymentDetails{})
 		require.Error(t, err)
 		assert.Contains(t, err.Error(), "sender account has insufficient funds")
 	})
 
+	t.Run("catches transfers touching frozen or closed accounts", func(t *testing.T) {
+		cases := []struct {
+			title        string
+			fromStatus   entities.AccountStatus
+			toStatus     entities.AccountStatus
+			errorMessage string
+		}{
+			{title: "frozen sender", fromStatus: entities.FrozenAccount, toStatus: entities.ActiveAccount, errorMessage: "sender account is frozen"},
+			{title: "closed sender", fromStatus: entities.ClosedAccount, toStatus: entities.ActiveAccount, errorMessage: "sender account is closed"},
+			{title: "frozen receiver", fromStatus: entities.ActiveAccount, toStatus: entities.FrozenAccount, errorMessage: "receiver account is frozen"},
+			{title: "closed receiver", fromStatus: entities.ActiveAccount, toStatus: entities.ClosedAccount, errorMessage: "receiver account is closed"},
+		}
+
+		for _, tc := range cases {
+			t.Run(tc.title, func(t *testing.T) {
+				mCtrl := gomock.NewController(t)
+				defer mCtrl.Finish()
+				storage := mocks.NewMockStorage(mCtrl)
+
+				from := entities.Account{Name: "sender", Type: entities.UserAccount, Status: tc.fromStatus, Balance: decimal.New(15, 0), Currency: entities.USD}
+				to := entities.Account{Name: "receiver", Type: entities.UserAccount, Status: tc.toStatus, Currency: entities.USD}
+
+				storage.EXPECT().BeginTx(gomock.Any(), nil).Return(storage, nil)
+				expectAccountsLocked(storage, from, to)
+				storage.EXPECT().RollbackTx(gomock.Any()).Return(nil)
+
+				_, err := banking.NewService(storage).SendPayment(ctx, entities.Account{Name: "sender"}, entities.Account{Name: "receiver"}, decimal.New(5, 0), entities.PaymentDetails{})
+				require.Error(t, err)
+				assert.Equal(t, tc.errorMessage, err.Error())
+			})
+		}
+	})
+
+	t.Run("catches transfer attempts between different currencies", func(t *testing.T) {
+		mCtrl := gomock.NewController(t)
+		defer mCtrl.Finish()
+		storage := mocks.NewMockStorage(mCtrl)
+
+		from := entities.Account{Name: "sender", Balance: decimal.New(15, 0), Currency: entities.PHP}
+		to := entities.Account{Name: "receiver", Currency: entities.USD}
+		amount := decimal.New(10, 0)
+
+		storage.EXPECT().BeginTx(gomock.Any(), nil).Return(storage, nil)
+		storage.EXPECT().GetAccountForUpdate(gomock.Any(), &from).Return(nil)
+		storage.EXPECT().GetAccountForUpdate(gomock.Any(), &to).Return(nil)
+		storage.EXPECT().RollbackTx(gomock.Any()).Return(nil)
+
+		_, err := banking.NewService(storage).SendPayment(ctx, from, to, amount, entities.PaymentDetails{})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "sender and receiver accounts should have the same currency")
+	})
+
+	t.Run("catches amounts exceeding currency precision", func(t *testing.T) {
+		mCtrl := gomock.NewController(t)
+		defer mCtrl.Finish()
+		storage := mocks.NewMockStorage(mCtrl)
+
+		from := entities.Account{Name: "sender", Balance: decimal.New(15000, 0), Currency: entities.JPY}
+		to := entities.Account{Name: "receiver", Currency: entities.JPY}
+		amount := decimal.New(105, -1)
+
+		storage.EXPECT().BeginTx(gomock.Any(), nil).Return(storage, nil)
+		storage.EXPECT().GetAccountForUpdate(gomock.Any(), &from).Return(nil)
+		storage.EXPECT().GetAccountForUpdate(gomock.Any(), &to).Return(nil)
+		storage.EXPECT().RollbackTx(gomock.Any()).Return(nil)
+
+		_, err := banking.NewService(storage).SendPayment(ctx, from, to, amount, entities.PaymentDetails{})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "amount has more decimal places than its currency allows")
+	})
+
 	t.Run("propagates storage exceptions", func(t *testing.T) {
-		from := entities.Account{Name: "sender", Balance: decimal.New(15, 0)}
-		to := entities.Account{Name: "receiver"}
+		from := entities.Account{Name: "sender", Balance: decimal.New(15, 0), Currency: entities.USD}
+		to := entities.Account{Name: "receiver", Currency: entities.USD}
 		amount := decimal.New(10, 0)
 
 		t.Run("on db tx opening", func(t *testing.T) {
@@ -241,7 +601,7 @@ func TestBankingSvcSendPayment(t *testing.T) {
 			storage.EXPECT().BeginTx(gomock.Any(), nil).Return(storage, ErrDB)
 			storage.EXPECT().RollbackTx(gomock.Any()).Return(nil)
 
-			err := banking.NewService(storage).SendPayment(ctx, from, to, amount)
+			_, err := banking.NewService(storage).SendPayment(ctx, from, to, amount, entities.PaymentDetails{})
 			require.Error(t, err)
 			assert.Contains(t, err.Error(), "can't open transaction")
 		})
@@ -256,7 +616,7 @@ func TestBankingSvcSendPayment(t *testing.T) {
 				storage.EXPECT().GetAccountForUpdate(gomock.Any(), &from).Return(ErrDB)
 				storage.EXPECT().RollbackTx(gomock.Any()).Return(nil)
 
-				err := banking.NewService(storage).SendPayment(ctx, from, to, amount)
+				_, err := banking.NewService(storage).SendPayment(ctx, from, to, amount, entities.PaymentDetails{})
 				require.Error(t, err)
 				assert.Contains(t, err.Error(), "can't obtain sender account")
 			})
@@ -271,7 +631,7 @@ func TestBankingSvcSendPayment(t *testing.T) {
 				storage.EXPECT().GetAccountForUpdate(gomock.Any(), &to).Return(ErrDB)
 				storage.EXPECT().RollbackTx(gomock.Any()).Return(nil)
 
-				err := banking.NewService(storage).SendPayment(ctx, from, to, amount)
+				_, err := banking.NewService(storage).SendPayment(ctx, from, to, amount, entities.PaymentDetails{})
 				require.Error(t, err)
 				assert.Contains(t, err.Error(), "can't obtain receiver account")
 			})
@@ -282,7 +642,7 @@ func TestBankingSvcSendPayment(t *testing.T) {
 				storage.EXPECT().BeginTx(gomock.Any(), nil).Return(storage, nil)
 				storage.EXPECT().GetAccountForUpdate(gomock.Any(), &from).Return(nil)
 				storage.EXPECT().GetAccountForUpdate(gomock.Any(), &to).Return(nil)
-				storage.EXPECT().CreateTransaction(gomock.Any()).Return(entities.Transaction{}, nil)
+				storage.EXPECT().CreateTransaction(gomock.Any(), entities.Transaction{Kind: entities.TransferTransaction}).Return(entities.Transaction{}, nil)
 				storage.EXPECT().RollbackTx(gomock.Any()).Return(nil)
 			}
 			t.Run("outgoing", func(t *testing.T) {
@@ -293,7 +653,7 @@ func TestBankingSvcSendPayment(t *testing.T) {
 				setupCommonExpectations(storage)
 				storage.EXPECT().SendPayment(gomock.Any(), gomock.Any()).Return(ErrDB)
 
-				err := banking.NewService(storage).SendPayment(ctx, from, to, amount)
+				_, err := banking.NewService(storage).SendPayment(ctx, from, to, amount, entities.PaymentDetails{})
 				require.Error(t, err)
 				assert.Contains(t, err.Error(), "can't insert outgoing payment")
 			})
@@ -307,7 +667,7 @@ func TestBankingSvcSendPayment(t *testing.T) {
 				storage.EXPECT().SendPayment(gomock.Any(), gomock.Any()).Return(nil)
 				storage.EXPECT().SendPayment(gomock.Any(), gomock.Any()).Return(ErrDB)
 
-				err := banking.NewService(storage).SendPayment(ctx, from, to, amount)
+				_, err := banking.NewService(storage).SendPayment(ctx, from, to, amount, entities.PaymentDetails{})
 				require.Error(t, err)
 				assert.Contains(t, err.Error(), "can't insert incoming payment")
 			})
@@ -318,7 +678,7 @@ func TestBankingSvcSendPayment(t *testing.T) {
 				storage.EXPECT().BeginTx(gomock.Any(), nil).Return(storage, nil)
 				storage.EXPECT().GetAccountForUpdate(gomock.Any(), &from).Return(nil)
 				storage.EXPECT().GetAccountForUpdate(gomock.Any(), &to).Return(nil)
-				storage.EXPECT().CreateTransaction(gomock.Any()).Return(entities.Transaction{}, nil)
+				storage.EXPECT().CreateTransaction(gomock.Any(), entities.Transaction{Kind: entities.TransferTransaction}).Return(entities.Transaction{}, nil)
 				storage.EXPECT().SendPayment(gomock.Any(), gomock.Any()).Return(nil)
 				storage.EXPECT().SendPayment(gomock.Any(), gomock.Any()).Return(nil)
 				storage.EXPECT().RollbackTx(gomock.Any()).Return(nil)
@@ -331,7 +691,7 @@ func TestBankingSvcSendPayment(t *testing.T) {
 				setupCommonExpectations(storage)
 				storage.EXPECT().SetAccountBalance(gomock.Any(), gomock.Any()).Return(ErrDB)
 
-				err := banking.NewService(storage).SendPayment(ctx, from, to, amount)
+				_, err := banking.NewService(storage).SendPayment(ctx, from, to, amount, entities.PaymentDetails{})
 				require.Error(t, err)
 				assert.Contains(t, err.Error(), "can't update sender account balance")
 			})
@@ -345,7 +705,7 @@ func TestBankingSvcSendPayment(t *testing.T) {
 				storage.EXPECT().SetAccountBalance(gomock.Any(), gomock.Any()).Return(nil)
 				storage.EXPECT().SetAccountBalance(gomock.Any(), gomock.Any()).Return(ErrDB)
 
-				err := banking.NewService(storage).SendPayment(ctx, from, to, amount)
+				_, err := banking.NewService(storage).SendPayment(ctx, from, to, amount, entities.PaymentDetails{})
 				require.Error(t, err)
 				assert.Contains(t, err.Error(), "can't update counterparty balance")
 			})
@@ -359,7 +719,7 @@ func TestBankingSvcSendPayment(t *testing.T) {
 			storage.EXPECT().BeginTx(gomock.Any(), nil).Return(storage, nil)
 			storage.EXPECT().GetAccountForUpdate(gomock.Any(), &from).Return(nil)
 			storage.EXPECT().GetAccountForUpdate(gomock.Any(), &to).Return(nil)
-			storage.EXPECT().CreateTransaction(gomock.Any()).Return(entities.Transaction{}, nil)
+			storage.EXPECT().CreateTransaction(gomock.Any(), entities.Transaction{Kind: entities.TransferTransaction}).Return(entities.Transaction{}, nil)
 			storage.EXPECT().SendPayment(gomock.Any(), gomock.Any()).Return(nil)
 			storage.EXPECT().SendPayment(gomock.Any(), gomock.Any()).Return(nil)
 			storage.EXPECT().SetAccountBalance(gomock.Any(), gomock.Any()).Return(nil)
@@ -367,7 +727,7 @@ func TestBankingSvcSendPayment(t *testing.T) {
 			storage.EXPECT().CommitTx(gomock.Any()).Return(ErrDB)
 			storage.EXPECT().RollbackTx(gomock.Any()).Return(nil)
 
-			err := banking.NewService(storage).SendPayment(ctx, from, to, amount)
+			_, err := banking.NewService(storage).SendPayment(ctx, from, to, amount, entities.PaymentDetails{})
 			require.Error(t, err)
 			assert.Contains(t, err.Error(), "transaction commit failed")
 		})
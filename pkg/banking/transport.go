@@ -4,8 +4,14 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
 
+	"github.com/go-kit/kit/endpoint"
 	"github.com/go-kit/kit/log"
 	kithttp "github.com/go-kit/kit/transport/http"
 	"github.com/gorilla/mux"
@@ -14,28 +20,175 @@ import (
 	"github.com/twonegatives/coinsph_challenge/pkg/entities"
 )
 
+// APIPrefix is a path prefix the banking handler is expected to be mounted at.
+const APIPrefix = "/api/v1"
+
 var (
 	errBadRequest = errors.New("bad request")
 )
 
 type payment struct {
-	From   string          `json:"from"`
-	To     string          `json:"to"`
-	Amount decimal.Decimal `json:"amount"`
+	From        string            `json:"from"`
+	To          string            `json:"to"`
+	Amount      decimal.Decimal   `json:"amount"`
+	QuoteID     int               `json:"quote_id"`
+	Description string            `json:"description"`
+	Reference   string            `json:"reference"`
+	Metadata    map[string]string `json:"metadata"`
+}
+
+// details returns the attributes the client attached to the payment
+func (p payment) details() entities.PaymentDetails {
+	return entities.PaymentDetails{
+		Description:       p.Description,
+		ExternalReference: p.Reference,
+		Metadata:          p.Metadata,
+	}
 }
 
 type sendPaymentBody struct {
 	Payment payment `json:"payment"`
 }
 
+type paymentBatch struct {
+	Mode     string    `json:"mode"`
+	Payments []payment `json:"payments"`
+}
+
+type sendPaymentBatchBody struct {
+	Batch paymentBatch `json:"batch"`
+}
+
+type leg struct {
+	Account   string          `json:"account"`
+	Direction string          `json:"direction"`
+	Amount    decimal.Decimal `json:"amount"`
+}
+
+type multiLegTransaction struct {
+	Legs []leg `json:"legs"`
+}
+
+type postTransactionBody struct {
+	Transaction multiLegTransaction `json:"transaction"`
+}
+
 type account struct {
-	Name string `json:"name"`
+	Name        string   `json:"name"`
+	Currency    string   `json:"currency"`
+	OwnerID     string   `json:"owner_id"`
+	DisplayName string   `json:"display_name"`
+	Labels      []string `json:"labels"`
 }
 
 type createAccountBody struct {
 	Account account `json:"account"`
 }
 
+// accountUpdate tells attributes missing from the body from blank ones
+type accountUpdate struct {
+	OwnerID     *string  `json:"owner_id"`
+	DisplayName *string  `json:"display_name"`
+	Labels      []string `json:"labels"`
+}
+
+type updateAccountBody struct {
+	Account accountUpdate `json:"account"`
+}
+
+type externalOperation struct {
+	Amount    decimal.Decimal `json:"amount"`
+	Reference string          `json:"reference"`
+}
+
+type depositBody struct {
+	Deposit externalOperation `json:"deposit"`
+}
+
+type withdrawalBody struct {
+	Withdrawal externalOperation `json:"withdrawal"`
+}
+
+type authorization struct {
+	From   string          `json:"from"`
+	To     string          `json:"to"`
+	Amount decimal.Decimal `json:"amount"`
+}
+
+type authorizeBody struct {
+	Authorization authorization `json:"authorization"`
+}
+
+type capture struct {
+	Amount decimal.Decimal `json:"amount"`
+}
+
+type captureBody struct {
+	Capture capture `json:"capture"`
+}
+
+type scheduledPayment struct {
+	From      string          `json:"from"`
+	To        string          `json:"to"`
+	Amount    decimal.Decimal `json:"amount"`
+	Frequency string          `json:"frequency"`
+	Cron      string          `json:"cron"`
+	StartAt   time.Time       `json:"start_at"`
+	EndAt     time.Time       `json:"end_at"`
+}
+
+type createScheduledPaymentBody struct {
+	ScheduledPayment scheduledPayment `json:"scheduled_payment"`
+}
+
+type scheduledPaymentUpdate struct {
+	Amount    decimal.NullDecimal `json:"amount"`
+	Status    string              `json:"status"`
+	NextRunAt time.Time           `json:"next_run_at"`
+}
+
+type updateScheduledPaymentBody struct {
+	ScheduledPayment scheduledPaymentUpdate `json:"scheduled_payment"`
+}
+
+type refund struct {
+	Amount decimal.Decimal `json:"amount"`
+}
+
+type refundBody struct {
+	Refund refund `json:"refund"`
+}
+
+type creditLimit struct {
+	Limit     decimal.Decimal `json:"limit"`
+	ChangedBy string          `json:"changed_by"`
+	Reason    string          `json:"reason"`
+}
+
+type setCreditLimitBody struct {
+	CreditLimit creditLimit `json:"credit_limit"`
+}
+
+type quote struct {
+	FromCurrency string          `json:"from_currency"`
+	ToCurrency   string          `json:"to_currency"`
+	Amount       decimal.Decimal `json:"amount"`
+}
+
+type createQuoteBody struct {
+	Quote quote `json:"quote"`
+}
+
+type apiKey struct {
+	Name    string        `json:"name"`
+	Role    entities.Role `json:"role"`
+	OwnerID string        `json:"owner_id"`
+}
+
+type createAPIKeyBody struct {
+	APIKey apiKey `json:"api_key"`
+}
+
 func decodeCreateAccountRequest(_ context.Context, r *http.Request) (interface{}, error) {
 	var body createAccountBody
 	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
@@ -43,12 +196,39 @@ func decodeCreateAccountRequest(_ context.Context, r *http.Request) (interface{}
 	}
 
 	newAccountRequest := createAccountRequest{
-		Name: body.Account.Name,
+		Name:     body.Account.Name,
+		Currency: entities.Currency(strings.ToLower(body.Account.Currency)),
+		Profile: entities.AccountProfile{
+			OwnerID:     body.Account.OwnerID,
+			DisplayName: body.Account.DisplayName,
+			Labels:      body.Account.Labels,
+		},
+	}
+
+	// accounts are opened in USD unless currency is set explicitly
+	if newAccountRequest.Currency == "" {
+		newAccountRequest.Currency = entities.USD
 	}
 
 	return newAccountRequest, nil
 }
 
+func decodeUpdateAccountRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	var body updateAccountBody
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		return nil, errBadRequest
+	}
+
+	return updateAccountRequest{
+		Name: mux.Vars(r)["name"],
+		Update: entities.AccountUpdate{
+			OwnerID:     body.Account.OwnerID,
+			DisplayName: body.Account.DisplayName,
+			Labels:      body.Account.Labels,
+		},
+	}, nil
+}
+
 func decodeSendPaymentRequest(_ context.Context, r *http.Request) (interface{}, error) {
 	var body sendPaymentBody
 	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
@@ -56,20 +236,438 @@ func decodeSendPaymentRequest(_ context.Context, r *http.Request) (interface{},
 	}
 
 	paymentRequest := sendPaymentRequest{
-		From:   entities.Account{Name: body.Payment.From},
-		To:     entities.Account{Name: body.Payment.To},
-		Amount: body.Payment.Amount,
+		From:    entities.Account{Name: body.Payment.From},
+		To:      entities.Account{Name: body.Payment.To},
+		Amount:  body.Payment.Amount,
+		QuoteID: body.Payment.QuoteID,
+		Details: body.Payment.details(),
 	}
 
 	return paymentRequest, nil
 }
 
+func decodeSendPaymentBatchRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	var body sendPaymentBatchBody
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		return nil, errBadRequest
+	}
+
+	// batches are booked atomically unless the mode is set explicitly
+	batchRequest := sendPaymentBatchRequest{Mode: entities.BatchMode(body.Batch.Mode)}
+	if batchRequest.Mode == "" {
+		batchRequest.Mode = entities.AtomicBatch
+	}
+
+	for _, order := range body.Batch.Payments {
+		batchRequest.Orders = append(batchRequest.Orders, entities.PaymentOrder{
+			From:    entities.Account{Name: order.From},
+			To:      entities.Account{Name: order.To},
+			Amount:  order.Amount,
+			Details: order.details(),
+		})
+	}
+
+	return batchRequest, nil
+}
+
+func decodePostTransactionRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	var body postTransactionBody
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		return nil, errBadRequest
+	}
+
+	transactionRequest := postTransactionRequest{}
+	for _, leg := range body.Transaction.Legs {
+		transactionRequest.Legs = append(transactionRequest.Legs, entities.Leg{
+			Account:   entities.Account{Name: leg.Account},
+			Direction: entities.Direction(strings.ToLower(leg.Direction)),
+			Amount:    leg.Amount,
+		})
+	}
+
+	return transactionRequest, nil
+}
+
+func decodeDepositRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	var body depositBody
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		return nil, errBadRequest
+	}
+
+	return externalOperationRequest{
+		Name:      mux.Vars(r)["name"],
+		Amount:    body.Deposit.Amount,
+		Reference: strings.TrimSpace(body.Deposit.Reference),
+	}, nil
+}
+
+func decodeWithdrawalRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	var body withdrawalBody
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		return nil, errBadRequest
+	}
+
+	return externalOperationRequest{
+		Name:      mux.Vars(r)["name"],
+		Amount:    body.Withdrawal.Amount,
+		Reference: strings.TrimSpace(body.Withdrawal.Reference),
+	}, nil
+}
+
+func decodeSetCreditLimitRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	var body setCreditLimitBody
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		return nil, errBadRequest
+	}
+
+	return setCreditLimitRequest{
+		Name:      mux.Vars(r)["name"],
+		Limit:     body.CreditLimit.Limit,
+		ChangedBy: body.CreditLimit.ChangedBy,
+		Reason:    body.CreditLimit.Reason,
+	}, nil
+}
+
+func decodeChangeAccountStatusRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	return changeAccountStatusRequest{Name: mux.Vars(r)["name"]}, nil
+}
+
+func decodeGetCreditLimitChangesRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	return getCreditLimitChangesRequest{Name: mux.Vars(r)["name"]}, nil
+}
+
+func decodeGetTransactionRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		return nil, errBadRequest
+	}
+
+	return getTransactionRequest{ID: id}, nil
+}
+
+func decodeReverseTransactionRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		return nil, errBadRequest
+	}
+
+	return compensateTransactionRequest{ID: id}, nil
+}
+
+func decodeRefundTransactionRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		return nil, errBadRequest
+	}
+
+	var body refundBody
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		return nil, errBadRequest
+	}
+
+	return compensateTransactionRequest{ID: id, Amount: body.Refund.Amount}, nil
+}
+
+func decodeAuthorizeRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	var body authorizeBody
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		return nil, errBadRequest
+	}
+
+	return authorizeRequest{
+		From:   entities.Account{Name: body.Authorization.From},
+		To:     entities.Account{Name: body.Authorization.To},
+		Amount: body.Authorization.Amount,
+	}, nil
+}
+
+func decodeHoldRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		return nil, errBadRequest
+	}
+
+	return holdRequest{ID: id}, nil
+}
+
+// decodeCaptureHoldRequest accepts an empty body, which captures the authorized amount in full
+func decodeCaptureHoldRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		return nil, errBadRequest
+	}
+
+	var body captureBody
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil && err != io.EOF {
+		return nil, errBadRequest
+	}
+
+	return captureHoldRequest{ID: id, Amount: body.Capture.Amount}, nil
+}
+
+func decodeCreateScheduledPaymentRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	var body createScheduledPaymentBody
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		return nil, errBadRequest
+	}
+
+	return createScheduledPaymentRequest{
+		Payment: entities.ScheduledPayment{
+			Account:      entities.Account{Name: body.ScheduledPayment.From},
+			Counterparty: entities.Account{Name: body.ScheduledPayment.To},
+			Amount:       body.ScheduledPayment.Amount,
+			Frequency:    entities.ScheduleFrequency(strings.ToLower(body.ScheduledPayment.Frequency)),
+			Cron:         body.ScheduledPayment.Cron,
+			StartAt:      body.ScheduledPayment.StartAt,
+			EndAt:        body.ScheduledPayment.EndAt,
+		},
+	}, nil
+}
+
+func decodeGetScheduledPaymentsRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	return getScheduledPaymentsRequest{AccountName: r.URL.Query().Get("account")}, nil
+}
+
+func decodeScheduledPaymentRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		return nil, errBadRequest
+	}
+
+	return scheduledPaymentRequest{ID: id}, nil
+}
+
+func decodeUpdateScheduledPaymentRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		return nil, errBadRequest
+	}
+
+	var body updateScheduledPaymentBody
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		return nil, errBadRequest
+	}
+
+	return updateScheduledPaymentRequest{
+		ID: id,
+		Update: entities.ScheduledPaymentUpdate{
+			Amount:    body.ScheduledPayment.Amount,
+			Status:    entities.ScheduleStatus(strings.ToLower(body.ScheduledPayment.Status)),
+			NextRunAt: body.ScheduledPayment.NextRunAt,
+		},
+	}, nil
+}
+
+func decodeGetAccountsRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	query := r.URL.Query()
+	filter := entities.AccountsFilter{
+		NamePrefix: query.Get("name_prefix"),
+		OwnerID:    query.Get("owner"),
+		Sort:       entities.AccountsSort(query.Get("sort")),
+	}
+
+	switch query.Get("order") {
+	case "", "asc":
+	case "desc":
+		filter.Descending = true
+	default:
+		return nil, errInvalidOrder
+	}
+
+	var err error
+	if filter.Limit, err = parseLimitParam(query); err != nil {
+		return nil, err
+	}
+
+	if encoded := query.Get("cursor"); encoded != "" {
+		cursor, err := entities.DecodeAccountsCursor(encoded)
+		if err != nil {
+			return nil, errInvalidCursor
+		}
+		filter.After = &cursor
+	}
+
+	return getAccountsRequest{Filter: filter}, nil
+}
+
+func decodeGetPaymentsRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	filter, err := parsePaymentsFilter(r.URL.Query())
+	if err != nil {
+		return nil, err
+	}
+
+	return getPaymentsRequest{Filter: filter}, nil
+}
+
+func decodeGetAccountRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	return getAccountRequest{Name: mux.Vars(r)["name"]}, nil
+}
+
+func decodeGetAccountPaymentsRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	filter, err := parsePaymentsFilter(r.URL.Query())
+	if err != nil {
+		return nil, err
+	}
+
+	return getAccountPaymentsRequest{Name: mux.Vars(r)["name"], Filter: filter}, nil
+}
+
+// parsePaymentsFilter builds a PaymentsFilter out of query parameters
+func parsePaymentsFilter(query url.Values) (entities.PaymentsFilter, error) {
+	filter := entities.PaymentsFilter{
+		AccountName: query.Get("account"),
+		Direction:   entities.Direction(query.Get("direction")),
+		Kind:        entities.TransactionKind(query.Get("kind")),
+		Reference:   query.Get("reference"),
+	}
+
+	var err error
+	if filter.FromDate, err = parseDateParam(query, "from_date"); err != nil {
+		return filter, err
+	}
+
+	if filter.ToDate, err = parseDateParam(query, "to_date"); err != nil {
+		return filter, err
+	}
+
+	if filter.MinAmount, err = parseAmountParam(query, "min_amount"); err != nil {
+		return filter, err
+	}
+
+	if filter.MaxAmount, err = parseAmountParam(query, "max_amount"); err != nil {
+		return filter, err
+	}
+
+	if filter.Limit, err = parseLimitParam(query); err != nil {
+		return filter, err
+	}
+
+	if encoded := query.Get("cursor"); encoded != "" {
+		cursor, err := entities.DecodePaymentsCursor(encoded)
+		if err != nil {
+			return filter, errInvalidCursor
+		}
+		filter.After = &cursor
+	}
+
+	return filter, nil
+}
+
+func decodeGetAuditLogRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	query := r.URL.Query()
+	filter := entities.AuditFilter{
+		Subject:       query.Get("subject"),
+		Action:        query.Get("action"),
+		CorrelationID: query.Get("correlation_id"),
+		Outcome:       entities.AuditOutcome(query.Get("outcome")),
+	}
+
+	var err error
+	if filter.FromDate, err = parseDateParam(query, "from_date"); err != nil {
+		return nil, err
+	}
+
+	if filter.ToDate, err = parseDateParam(query, "to_date"); err != nil {
+		return nil, err
+	}
+
+	if filter.Limit, err = parseLimitParam(query); err != nil {
+		return nil, err
+	}
+
+	if encoded := query.Get("cursor"); encoded != "" {
+		cursor, err := entities.DecodeAuditCursor(encoded)
+		if err != nil {
+			return nil, errInvalidCursor
+		}
+		filter.After = &cursor
+	}
+
+	return getAuditLogRequest{Filter: filter}, nil
+}
+
+func decodeCreateQuoteRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	var body createQuoteBody
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		return nil, errBadRequest
+	}
+
+	quoteRequest := createQuoteRequest{
+		From:   entities.Currency(strings.ToLower(body.Quote.FromCurrency)),
+		To:     entities.Currency(strings.ToLower(body.Quote.ToCurrency)),
+		Amount: body.Quote.Amount,
+	}
+
+	return quoteRequest, nil
+}
+
+func decodeCreateAPIKeyRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	var body createAPIKeyBody
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		return nil, errBadRequest
+	}
+
+	return createAPIKeyRequest{
+		Name:    body.APIKey.Name,
+		Role:    entities.Role(strings.ToLower(string(body.APIKey.Role))),
+		OwnerID: body.APIKey.OwnerID,
+	}, nil
+}
+
+func decodeRevokeAPIKeyRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	return revokeAPIKeyRequest{KeyID: mux.Vars(r)["key_id"]}, nil
+}
+
+// parseDateParam accepts either RFC 3339 timestamp or a plain date (which is treated as UTC midnight)
+func parseDateParam(query url.Values, name string) (time.Time, error) {
+	value := query.Get(name)
+	if value == "" {
+		return time.Time{}, nil
+	}
+
+	for _, layout := range []string{time.RFC3339Nano, "2006-01-02"} {
+		if parsed, err := time.Parse(layout, value); err == nil {
+			return parsed, nil
+		}
+	}
+
+	return time.Time{}, errors.Wrapf(errBadRequest, "can't parse %s", name)
+}
+
+func parseAmountParam(query url.Values, name string) (decimal.NullDecimal, error) {
+	value := query.Get(name)
+	if value == "" {
+		return decimal.NullDecimal{}, nil
+	}
+
+	amount, err := decimal.NewFromString(value)
+	if err != nil {
+		return decimal.NullDecimal{}, errors.Wrapf(errBadRequest, "can't parse %s", name)
+	}
+
+	return decimal.NullDecimal{Decimal: amount, Valid: true}, nil
+}
+
+func parseLimitParam(query url.Values) (int, error) {
+	value := query.Get("limit")
+	if value == "" {
+		return 0, nil
+	}
+
+	limit, err := strconv.Atoi(value)
+	if err != nil || limit <= 0 {
+		return 0, errInvalidLimit
+	}
+
+	return limit, nil
+}
+
 func encodePaymentsAsJSON(_ context.Context, w http.ResponseWriter, response interface{}) error {
 	w.Header().Set("Content-Type", "application/json; charset=utf-8")
 	resp := response.(getPaymentsResponse)
 
 	encoder := paymentsJSONEncoder{}
-	encoded, err := encoder.encode(resp.Payments)
+	encoded, err := encoder.encode(resp.Page)
 	if err != nil {
 		return errors.Wrap(err, "Can't encode payments into bytes")
 	}
@@ -78,71 +676,553 @@ func encodePaymentsAsJSON(_ context.Context, w http.ResponseWriter, response int
 	return errors.Wrap(writeErr, "Can't write response body")
 }
 
-func MakeHandler(svc BankingService, l log.Logger) http.Handler {
+// HandlerOption allows to alter handler defaults on construction.
+type HandlerOption func(*handlerConfig)
+
+type handlerConfig struct {
+	idempotencyStore IdempotencyStore
+	operatorToken    string
+	adminToken       string
+	apiKeys          APIKeyStore
+	tokens           *tokenAuthority
+	secrets          *SecretBox
+	signatures       IdempotencyStore
+	adminHandlers    map[string]http.Handler
+}
+
+// WithIdempotencyStore enables Idempotency-Key header support for
+// POST /accounts, POST /payments, authorizations, deposits and withdrawals routes.
+func WithIdempotencyStore(store IdempotencyStore) HandlerOption {
+	return func(cfg *handlerConfig) {
+		cfg.idempotencyStore = store
+	}
+}
+
+// WithOperatorToken sets up a token privileged callers present in order to
+// book deposits and withdrawals or manage credit limits.
+// These routes are forbidden unless it is set.
+func WithOperatorToken(token string) HandlerOption {
+	return func(cfg *handlerConfig) {
+		cfg.operatorToken = token
+	}
+}
+
+// WithAdminToken sets up a token administrators present in order to manage API keys.
+// It is meant to issue the first keys, admin API keys may be used later on.
+func WithAdminToken(token string) HandlerOption {
+	return func(cfg *handlerConfig) {
+		cfg.adminToken = token
+	}
+}
+
+// WithAuthentication makes every route require credentials: API keys found in the store
+// (as bearer tokens or request signatures) or operator and admin tokens.
+// Routes are served to callers whose role permits them, and API key management routes are enabled.
+// Without this option only operator routes are restricted, to callers presenting the operator token.
+func WithAuthentication(keys APIKeyStore) HandlerOption {
+	return func(cfg *handlerConfig) {
+		cfg.apiKeys = keys
+	}
+}
+
+// WithSignedRequests makes API keys sign requests, verifying signatures with their secrets
+// unsealed by the box. It should be the box the service issues keys with (see WithSecretBox).
+// Used signatures are kept in the store until they expire, and requests reusing them are refused.
+// Without this option API keys are presented as bearer tokens only.
+func WithSignedRequests(box *SecretBox, signatures IdempotencyStore) HandlerOption {
+	return func(cfg *handlerConfig) {
+		cfg.secrets = box
+		cfg.signatures = signatures
+	}
+}
+
+// WithAdminHandler serves GET requests to the path with a handler of another package,
+// restricted to admins just like the rest of the admin routes.
+func WithAdminHandler(path string, h http.Handler) HandlerOption {
+	return func(cfg *handlerConfig) {
+		if cfg.adminHandlers == nil {
+			cfg.adminHandlers = make(map[string]http.Handler)
+		}
+		cfg.adminHandlers[path] = h
+	}
+}
+
+func MakeHandler(svc BankingService, l log.Logger, handlerOpts ...HandlerOption) http.Handler {
+	cfg := handlerConfig{}
+	for _, opt := range handlerOpts {
+		opt(&cfg)
+	}
+
+	auth := authenticator{keys: cfg.apiKeys, tokens: cfg.tokens, secrets: cfg.secrets, signatures: cfg.signatures, operatorToken: cfg.operatorToken, adminToken: cfg.adminToken, logger: l, now: time.Now}
+
+	// restricted wraps handlers so that they are served to callers having at least the role.
+	// Without authentication only operator routes are restricted, to the operator token holders
+	restricted := func(role entities.Role, h http.Handler) http.Handler {
+		switch {
+		case cfg.apiKeys != nil:
+			return authenticated(auth, role, h)
+		case role.Permits(entities.OperatorRole):
+			return privileged(cfg.operatorToken, h)
+		}
+		return h
+	}
+
+	// mutating wraps handlers of non-safe methods with optional middlewares
+	mutating := func(h http.Handler) http.Handler {
+		if cfg.idempotencyStore != nil {
+			h = idempotent(cfg.idempotencyStore, l, h)
+		}
+		return h
+	}
+
+	// scope wraps endpoints so that they are served to callers granted the scope
+	scope := func(scope entities.Scope, e endpoint.Endpoint) endpoint.Endpoint {
+		return scoped(cfg.tokens, scope)(e)
+	}
+
 	opts := []kithttp.ServerOption{
+		kithttp.ServerBefore(kithttp.PopulateRequestContext),
 		kithttp.ServerErrorEncoder(errorEncoder),
 		kithttp.ServerErrorLogger(l),
 	}
 
 	createAccount := kithttp.NewServer(
-		MakeCreateAccountEndpoint(svc),
+		scope(entities.AccountsWriteScope, MakeCreateAccountEndpoint(svc)),
 		decodeCreateAccountRequest,
 		kithttp.EncodeJSONResponse,
 		opts...,
 	)
 
 	getAccounts := kithttp.NewServer(
-		MakeGetAccountsEndpoint(svc),
-		kithttp.NopRequestDecoder,
+		scope(entities.AccountsReadScope, MakeGetAccountsEndpoint(svc)),
+		decodeGetAccountsRequest,
 		kithttp.EncodeJSONResponse,
 		opts...,
 	)
 
 	getPayments := kithttp.NewServer(
-		MakeGetPaymentsEndpoint(svc),
-		kithttp.NopRequestDecoder,
+		scope(entities.AccountsReadScope, MakeGetPaymentsEndpoint(svc)),
+		decodeGetPaymentsRequest,
+		encodePaymentsAsJSON,
+		opts...,
+	)
+
+	getAccount := kithttp.NewServer(
+		scope(entities.AccountsReadScope, MakeGetAccountEndpoint(svc)),
+		decodeGetAccountRequest,
+		kithttp.EncodeJSONResponse,
+		opts...,
+	)
+
+	updateAccount := kithttp.NewServer(
+		scope(entities.AccountsWriteScope, MakeUpdateAccountEndpoint(svc)),
+		decodeUpdateAccountRequest,
+		kithttp.EncodeJSONResponse,
+		opts...,
+	)
+
+	getAccountPayments := kithttp.NewServer(
+		scope(entities.AccountsReadScope, MakeGetAccountPaymentsEndpoint(svc)),
+		decodeGetAccountPaymentsRequest,
 		encodePaymentsAsJSON,
 		opts...,
 	)
 
 	sendPayment := kithttp.NewServer(
-		MakeSendPaymentEndpoint(svc),
+		scope(entities.PaymentsWriteScope, MakeSendPaymentEndpoint(svc)),
 		decodeSendPaymentRequest,
 		kithttp.EncodeJSONResponse,
 		opts...,
 	)
 
+	previewPayment := kithttp.NewServer(
+		scope(entities.AccountsReadScope, MakePreviewPaymentEndpoint(svc)),
+		decodeSendPaymentRequest,
+		kithttp.EncodeJSONResponse,
+		opts...,
+	)
+
+	sendPaymentBatch := kithttp.NewServer(
+		scope(entities.PaymentsWriteScope, MakeSendPaymentBatchEndpoint(svc)),
+		decodeSendPaymentBatchRequest,
+		kithttp.EncodeJSONResponse,
+		opts...,
+	)
+
+	postTransaction := kithttp.NewServer(
+		scope(entities.PaymentsWriteScope, MakePostTransactionEndpoint(svc)),
+		decodePostTransactionRequest,
+		kithttp.EncodeJSONResponse,
+		opts...,
+	)
+
+	getTransaction := kithttp.NewServer(
+		scope(entities.AccountsReadScope, MakeGetTransactionEndpoint(svc)),
+		decodeGetTransactionRequest,
+		kithttp.EncodeJSONResponse,
+		opts...,
+	)
+
+	reverseTransaction := kithttp.NewServer(
+		MakeReverseTransactionEndpoint(svc),
+		decodeReverseTransactionRequest,
+		kithttp.EncodeJSONResponse,
+		opts...,
+	)
+
+	refundTransaction := kithttp.NewServer(
+		MakeRefundTransactionEndpoint(svc),
+		decodeRefundTransactionRequest,
+		kithttp.EncodeJSONResponse,
+		opts...,
+	)
+
+	deposit := kithttp.NewServer(
+		MakeDepositEndpoint(svc),
+		decodeDepositRequest,
+		kithttp.EncodeJSONResponse,
+		opts...,
+	)
+
+	withdraw := kithttp.NewServer(
+		MakeWithdrawEndpoint(svc),
+		decodeWithdrawalRequest,
+		kithttp.EncodeJSONResponse,
+		opts...,
+	)
+
+	freezeAccount := kithttp.NewServer(
+		MakeFreezeAccountEndpoint(svc),
+		decodeChangeAccountStatusRequest,
+		kithttp.EncodeJSONResponse,
+		opts...,
+	)
+
+	unfreezeAccount := kithttp.NewServer(
+		MakeUnfreezeAccountEndpoint(svc),
+		decodeChangeAccountStatusRequest,
+		kithttp.EncodeJSONResponse,
+		opts...,
+	)
+
+	closeAccount := kithttp.NewServer(
+		MakeCloseAccountEndpoint(svc),
+		decodeChangeAccountStatusRequest,
+		kithttp.EncodeJSONResponse,
+		opts...,
+	)
+
+	setCreditLimit := kithttp.NewServer(
+		MakeSetCreditLimitEndpoint(svc),
+		decodeSetCreditLimitRequest,
+		kithttp.EncodeJSONResponse,
+		opts...,
+	)
+
+	getCreditLimitChanges := kithttp.NewServer(
+		MakeGetCreditLimitChangesEndpoint(svc),
+		decodeGetCreditLimitChangesRequest,
+		kithttp.EncodeJSONResponse,
+		opts...,
+	)
+
+	authorize := kithttp.NewServer(
+		scope(entities.PaymentsWriteScope, MakeAuthorizeEndpoint(svc)),
+		decodeAuthorizeRequest,
+		kithttp.EncodeJSONResponse,
+		opts...,
+	)
+
+	getHold := kithttp.NewServer(
+		scope(entities.AccountsReadScope, MakeGetHoldEndpoint(svc)),
+		decodeHoldRequest,
+		kithttp.EncodeJSONResponse,
+		opts...,
+	)
+
+	captureHold := kithttp.NewServer(
+		scope(entities.PaymentsWriteScope, MakeCaptureHoldEndpoint(svc)),
+		decodeCaptureHoldRequest,
+		kithttp.EncodeJSONResponse,
+		opts...,
+	)
+
+	voidHold := kithttp.NewServer(
+		scope(entities.PaymentsWriteScope, MakeVoidHoldEndpoint(svc)),
+		decodeHoldRequest,
+		kithttp.EncodeJSONResponse,
+		opts...,
+	)
+
+	createScheduledPayment := kithttp.NewServer(
+		scope(entities.PaymentsWriteScope, MakeCreateScheduledPaymentEndpoint(svc)),
+		decodeCreateScheduledPaymentRequest,
+		kithttp.EncodeJSONResponse,
+		opts...,
+	)
+
+	getScheduledPayments := kithttp.NewServer(
+		scope(entities.AccountsReadScope, MakeGetScheduledPaymentsEndpoint(svc)),
+		decodeGetScheduledPaymentsRequest,
+		kithttp.EncodeJSONResponse,
+		opts...,
+	)
+
+	getScheduledPayment := kithttp.NewServer(
+		scope(entities.AccountsReadScope, MakeGetScheduledPaymentEndpoint(svc)),
+		decodeScheduledPaymentRequest,
+		kithttp.EncodeJSONResponse,
+		opts...,
+	)
+
+	updateScheduledPayment := kithttp.NewServer(
+		scope(entities.PaymentsWriteScope, MakeUpdateScheduledPaymentEndpoint(svc)),
+		decodeUpdateScheduledPaymentRequest,
+		kithttp.EncodeJSONResponse,
+		opts...,
+	)
+
+	cancelScheduledPayment := kithttp.NewServer(
+		scope(entities.PaymentsWriteScope, MakeCancelScheduledPaymentEndpoint(svc)),
+		decodeScheduledPaymentRequest,
+		kithttp.EncodeJSONResponse,
+		opts...,
+	)
+
+	getScheduledRuns := kithttp.NewServer(
+		scope(entities.AccountsReadScope, MakeGetScheduledRunsEndpoint(svc)),
+		decodeScheduledPaymentRequest,
+		kithttp.EncodeJSONResponse,
+		opts...,
+	)
+
+	createQuote := kithttp.NewServer(
+		scope(entities.PaymentsWriteScope, MakeCreateQuoteEndpoint(svc)),
+		decodeCreateQuoteRequest,
+		kithttp.EncodeJSONResponse,
+		opts...,
+	)
+
+	createAPIKey := kithttp.NewServer(
+		MakeCreateAPIKeyEndpoint(svc),
+		decodeCreateAPIKeyRequest,
+		kithttp.EncodeJSONResponse,
+		opts...,
+	)
+
+	getAPIKeys := kithttp.NewServer(
+		MakeGetAPIKeysEndpoint(svc),
+		kithttp.NopRequestDecoder,
+		kithttp.EncodeJSONResponse,
+		opts...,
+	)
+
+	revokeAPIKey := kithttp.NewServer(
+		MakeRevokeAPIKeyEndpoint(svc),
+		decodeRevokeAPIKeyRequest,
+		kithttp.EncodeJSONResponse,
+		opts...,
+	)
+
+	getAuditLog := kithttp.NewServer(
+		MakeGetAuditLogEndpoint(svc),
+		decodeGetAuditLogRequest,
+		kithttp.EncodeJSONResponse,
+		opts...,
+	)
+
+	verifyAuditLog := kithttp.NewServer(
+		MakeVerifyAuditLogEndpoint(svc),
+		kithttp.NopRequestDecoder,
+		kithttp.EncodeJSONResponse,
+		opts...,
+	)
+
+	viewer := func(h http.Handler) http.Handler { return restricted(entities.ViewerRole, h) }
+	owner := func(h http.Handler) http.Handler { return restricted(entities.AccountOwnerRole, h) }
+	operator := func(h http.Handler) http.Handler { return restricted(entities.OperatorRole, h) }
+
 	m := mux.NewRouter()
-	m.Handle("/accounts", createAccount).Methods(http.MethodPost)
-	m.Handle("/accounts", getAccounts).Methods(http.MethodGet)
-	m.Handle("/payments", getPayments).Methods(http.MethodGet)
-	m.Handle("/payments", sendPayment).Methods(http.MethodPost)
+	m.Handle("/accounts", owner(mutating(createAccount))).Methods(http.MethodPost)
+	m.Handle("/accounts", viewer(getAccounts)).Methods(http.MethodGet)
+	m.Handle("/accounts/{name}", viewer(getAccount)).Methods(http.MethodGet)
+	m.Handle("/accounts/{name}", owner(mutating(updateAccount))).Methods(http.MethodPatch)
+	m.Handle("/accounts/{name}/payments", viewer(getAccountPayments)).Methods(http.MethodGet)
+	m.Handle("/accounts/{name}/deposits", operator(mutating(deposit))).Methods(http.MethodPost)
+	m.Handle("/accounts/{name}/withdrawals", operator(mutating(withdraw))).Methods(http.MethodPost)
+	m.Handle("/accounts/{name}/freeze", operator(freezeAccount)).Methods(http.MethodPost)
+	m.Handle("/accounts/{name}/unfreeze", operator(unfreezeAccount)).Methods(http.MethodPost)
+	m.Handle("/accounts/{name}/close", operator(closeAccount)).Methods(http.MethodPost)
+	m.Handle("/payments", viewer(getPayments)).Methods(http.MethodGet)
+	m.Handle("/payments", owner(mutating(sendPayment))).Methods(http.MethodPost)
+	m.Handle("/payments/preview", viewer(previewPayment)).Methods(http.MethodPost)
+	m.Handle("/payment-batches", owner(mutating(sendPaymentBatch))).Methods(http.MethodPost)
+	m.Handle("/transactions", owner(mutating(postTransaction))).Methods(http.MethodPost)
+	m.Handle("/transactions/{id:[0-9]+}", viewer(getTransaction)).Methods(http.MethodGet)
+	m.Handle("/transactions/{id:[0-9]+}/reverse", operator(mutating(reverseTransaction))).Methods(http.MethodPost)
+	m.Handle("/transactions/{id:[0-9]+}/refund", operator(mutating(refundTransaction))).Methods(http.MethodPost)
+	m.Handle("/authorizations", owner(mutating(authorize))).Methods(http.MethodPost)
+	m.Handle("/authorizations/{id:[0-9]+}", viewer(getHold)).Methods(http.MethodGet)
+	m.Handle("/authorizations/{id:[0-9]+}/capture", owner(mutating(captureHold))).Methods(http.MethodPost)
+	m.Handle("/authorizations/{id:[0-9]+}/void", owner(mutating(voidHold))).Methods(http.MethodPost)
+	m.Handle("/scheduled-payments", owner(mutating(createScheduledPayment))).Methods(http.MethodPost)
+	m.Handle("/scheduled-payments", viewer(getScheduledPayments)).Methods(http.MethodGet)
+	m.Handle("/scheduled-payments/{id:[0-9]+}", viewer(getScheduledPayment)).Methods(http.MethodGet)
+	m.Handle("/scheduled-payments/{id:[0-9]+}", owner(mutating(updateScheduledPayment))).Methods(http.MethodPatch)
+	m.Handle("/scheduled-payments/{id:[0-9]+}", owner(mutating(cancelScheduledPayment))).Methods(http.MethodDelete)
+	m.Handle("/scheduled-payments/{id:[0-9]+}/runs", viewer(getScheduledRuns)).Methods(http.MethodGet)
+	m.Handle("/quotes", owner(createQuote)).Methods(http.MethodPost)
+	m.Handle("/admin/accounts/{name}/credit-limit", operator(setCreditLimit)).Methods(http.MethodPut)
+	m.Handle("/admin/accounts/{name}/credit-limit-changes", operator(getCreditLimitChanges)).Methods(http.MethodGet)
+	admin := func(h http.Handler) http.Handler { return restricted(entities.AdminRole, h) }
+	m.Handle("/admin/audit", admin(getAuditLog)).Methods(http.MethodGet)
+	m.Handle("/admin/audit/verify", admin(verifyAuditLog)).Methods(http.MethodGet)
+	for path, h := range cfg.adminHandlers {
+		m.Handle(path, admin(h)).Methods(http.MethodGet)
+	}
+	if cfg.apiKeys != nil {
+		m.Handle("/admin/api-keys", admin(createAPIKey)).Methods(http.MethodPost)
+		m.Handle("/admin/api-keys", admin(getAPIKeys)).Methods(http.MethodGet)
+		m.Handle("/admin/api-keys/{key_id}", admin(revokeAPIKey)).Methods(http.MethodDelete)
+	}
 	m.NotFoundHandler = http.HandlerFunc(notFoundEncoder)
-	return m
+	return correlated(m)
 }
 
 func errorEncoder(_ context.Context, err error, w http.ResponseWriter) {
 	w.Header().Set("Content-Type", "application/json; charset=utf-8")
 
-	var exposedErrDescription string
+	status, exposedErrDescription := errorStatus(err)
+	w.WriteHeader(status)
+
+	encodeErr := json.NewEncoder(w).Encode(map[string]string{"error": exposedErrDescription})
+	if encodeErr != nil {
+		panic(fmt.Sprintf("Can't encode error, %s. Original error: %s", encodeErr, err))
+	}
+}
 
+// errorStatus maps the error to HTTP status code and description exposed to clients.
+// Descriptions of unexpected errors are hidden.
+func errorStatus(err error) (int, string) {
 	switch errors.Cause(err) {
 	case errAmountShouldBePositive,
 		errNamesNotPresent,
 		errSenderIsReceiver,
 		errInsufficientFunds,
-		errAccountNameBlank:
+		errAccountNameBlank,
+		errAccountNameReserved,
+		errUnsupportedCurrency,
+		errCurrencyMismatch,
+		errAmountPrecision,
+		errSameCurrencyQuote,
+		errRateUnavailable,
+		errAmountTooSmall,
+		errQuoteExpired,
+		errQuoteAlreadyUsed,
+		errQuoteAmountMismatch,
+		errQuoteCurrencyMismatch,
+		errIdempotencyKeyTooLong,
+		errInvalidLimit,
+		errInvalidCursor,
+		errInvalidDirection,
+		errInvalidKind,
+		errInvalidDateRange,
+		errInvalidOutcome,
+		errInvalidAmountRange,
+		errInvalidSort,
+		errInvalidOrder,
+		errCursorOrderChanged,
+		errHouseAccountTransfer,
+		errHouseAccountExternal,
+		errCreditLimitNegative,
+		errCreditLimitBelowBalance,
+		errCreditLimitHouseAccount,
+		errChangedByBlank,
+		errHouseAccountStatus,
+		errHouseAccountProfile,
+		errOwnerIDTooLong,
+		errDisplayNameTooLong,
+		errTooManyLabels,
+		errInvalidLabel,
+		errTransactionNotRefundable,
+		errPartialRefundFX,
+		errRefundExceedsOriginal,
+		errExternalReferenceBlank,
+		errHoldExpired,
+		errCaptureExceedsHold,
+		errBatchEmpty,
+		errBatchTooLarge,
+		errInvalidBatchMode,
+		errLegsTooFew,
+		errLegsTooMany,
+		errInvalidLegDirection,
+		errLegAccountRepeated,
+		errLegsUnbalanced,
+		errPartialRefundSplit,
+		errDescriptionTooLong,
+		errReferenceTooLong,
+		errMetadataTooLarge,
+		errInvalidMetadataKey,
+		errMetadataValueTooLong,
+		errInvalidFrequency,
+		errInvalidCron,
+		errCronWithoutFrequency,
+		errScheduleStartInPast,
+		errScheduleEndBeforeRun,
+		errInvalidScheduleStatus,
+		errAPIKeyNameBlank,
+		errAPIKeyNameTooLong,
+		errInvalidRole,
+		errAPIKeyOwnerMissing,
+		errBadRequest:
 
-		w.WriteHeader(http.StatusBadRequest)
-		exposedErrDescription = err.Error()
-	default:
-		w.WriteHeader(http.StatusInternalServerError)
-		exposedErrDescription = "internal server error"
-	}
+		return http.StatusBadRequest, err.Error()
+	case errUnauthorized,
+		errCredentialsMissing,
+		errCredentialsInvalid,
+		errSignatureExpired,
+		errSignatureReplayed:
 
-	encodeErr := json.NewEncoder(w).Encode(map[string]string{"error": exposedErrDescription})
-	if encodeErr != nil {
-		panic(fmt.Sprintf("Can't encode error, %s. Original error: %s", encodeErr, err))
+		return http.StatusUnauthorized, err.Error()
+	case errForbidden,
+		errRoleNotPermitted,
+		errScopeNotPermitted,
+		errNotAccountOwner,
+		errChangedByMismatch:
+
+		return http.StatusForbidden, err.Error()
+	case errSenderFrozen,
+		errReceiverFrozen:
+
+		return http.StatusLocked, err.Error()
+	case errSenderClosed,
+		errReceiverClosed,
+		errAccountClosed:
+
+		return http.StatusGone, err.Error()
+	case errQuoteNotFound,
+		errHoldNotFound,
+		errScheduleNotFound,
+		errAPIKeyNotFound,
+		errTransactionNotFound,
+		errAccountNotFound:
+
+		return http.StatusNotFound, err.Error()
+	case errIdempotencyKeyReused,
+		errExternalReferenceUsed,
+		errAccountBalanceNotZero,
+		errTransactionReversed,
+		errTransactionRefunded,
+		errHoldNotPending,
+		errAccountHasHolds,
+		errScheduleFinal,
+		errIdempotentRequestInProgress,
+		errIdempotentRequestProcessed,
+		errIdempotencyKeyTakenOver:
+
+		return http.StatusConflict, err.Error()
+	default:
+		return http.StatusInternalServerError, "internal server error"
 	}
 }
 
@@ -1,11 +1,14 @@
 package banking_test
 
 import (
+	"context"
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/golang/mock/gomock"
 	"github.com/pkg/errors"
@@ -14,6 +17,8 @@ import (
 	"github.com/stretchr/testify/require"
 	"github.com/twonegatives/coinsph_challenge/pkg/banking"
 	"github.com/twonegatives/coinsph_challenge/pkg/entities"
+	"github.com/twonegatives/coinsph_challenge/pkg/fx"
+	"github.com/twonegatives/coinsph_challenge/pkg/memstorage"
 	"github.com/twonegatives/coinsph_challenge/pkg/mocks"
 )
 
@@ -26,12 +31,12 @@ type dependencies struct {
 	Service    *mocks.MockBankingService
 }
 
-func setupServer(t *testing.T) (dependencies, func()) {
+func setupServer(t *testing.T, opts ...banking.HandlerOption) (dependencies, func()) {
 	mockCtrl := gomock.NewController(t)
 
 	svc := mocks.NewMockBankingService(mockCtrl)
 
-	router := banking.MakeHandler(svc, mocks.TestLogger{T: t})
+	router := banking.MakeHandler(svc, mocks.TestLogger{T: t}, opts...)
 	srv := httptest.NewServer(router)
 	srv.Client()
 	return dependencies{TestServer: srv, Service: svc}, func() {
@@ -49,16 +54,18 @@ type getAccountsListResponse struct {
 }
 
 type payment struct {
-	Account     string             `json:"account"`
-	Amount      decimal.Decimal    `json:"amount"`
-	Direction   entities.Direction `json:"direction"`
-	Currency    entities.Currency  `json:"currency"`
-	ToAccount   *string            `json:"to_account"`
-	FromAccount *string            `json:"from_account"`
+	Account     string                   `json:"account"`
+	Amount      decimal.Decimal          `json:"amount"`
+	Direction   entities.Direction       `json:"direction"`
+	Currency    entities.Currency        `json:"currency"`
+	Kind        entities.TransactionKind `json:"kind"`
+	ToAccount   *string                  `json:"to_account"`
+	FromAccount *string                  `json:"from_account"`
 }
 
 type getPaymentsListResponse struct {
-	Payments []payment `json:"payments"`
+	Payments   []payment `json:"payments"`
+	NextCursor string    `json:"next_cursor"`
 }
 
 func TestCreateAccountRoute(t *testing.T) {
@@ -70,13 +77,15 @@ func TestCreateAccountRoute(t *testing.T) {
 		name := "barry"
 		expectedBody := createAccountResponse{
 			Account: entities.Account{
-				Name:     name,
-				Balance:  decimal.New(19, 0),
-				Currency: entities.USD,
+				Name:        name,
+				Balance:     decimal.New(19, 0),
+				Held:        decimal.New(4, 0),
+				CreditLimit: decimal.New(10, 0),
+				Currency:    entities.USD,
 			},
 		}
 
-		dep.Service.EXPECT().CreateAccount(gomock.Any(), name).Return(expectedBody.Account, nil)
+		dep.Service.EXPECT().CreateAccount(gomock.Any(), name, entities.USD, entities.AccountProfile{}).Return(expectedBody.Account, nil)
 
 		requestBody := `{"account": {"name": "barry"}}`
 		resp, err := client.Post(dep.TestServer.URL+"/accounts", "application/json", strings.NewReader(requestBody))
@@ -92,13 +101,52 @@ func TestCreateAccountRoute(t *testing.T) {
 		assert.Equal(t, expectedBody, actualBody)
 	})
 
+	t.Run("passes requested currency", func(t *testing.T) {
+		dep, cleanUp := setupServer(t)
+		client := dep.TestServer.Client()
+		defer cleanUp()
+
+		account := entities.Account{Name: "barry", Balance: decimal.New(0, 0), Currency: entities.PHP}
+		dep.Service.EXPECT().CreateAccount(gomock.Any(), account.Name, entities.PHP, entities.AccountProfile{}).Return(account, nil)
+
+		requestBody := `{"account": {"name": "barry", "currency": "PHP"}}`
+		resp, err := client.Post(dep.TestServer.URL+"/accounts", "application/json", strings.NewReader(requestBody))
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+	})
+
+	t.Run("passes and renders profile", func(t *testing.T) {
+		dep, cleanUp := setupServer(t)
+		client := dep.TestServer.Client()
+		defer cleanUp()
+
+		profile := entities.AccountProfile{OwnerID: "customer-1", DisplayName: "Barry's wallet", Labels: []string{"personal"}}
+		account := entities.Account{Name: "barry", Balance: decimal.New(0, 0), Currency: entities.USD}.WithProfile(profile)
+		dep.Service.EXPECT().CreateAccount(gomock.Any(), "barry", entities.USD, profile).Return(account, nil)
+
+		requestBody := `{"account": {"name": "barry", "owner_id": "customer-1", "display_name": "Barry's wallet", "labels": ["personal"]}}`
+		resp, err := client.Post(dep.TestServer.URL+"/accounts", "application/json", strings.NewReader(requestBody))
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		var actualBody map[string]map[string]interface{}
+		assert.NoError(t, json.NewDecoder(resp.Body).Decode(&actualBody))
+
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, "customer-1", actualBody["account"]["owner_id"])
+		assert.Equal(t, "Barry's wallet", actualBody["account"]["display_name"])
+		assert.Equal(t, []interface{}{"personal"}, actualBody["account"]["labels"])
+	})
+
 	t.Run("returns 500 on server error", func(t *testing.T) {
 		dep, cleanUp := setupServer(t)
 		client := dep.TestServer.Client()
 		defer cleanUp()
 
 		name := "barry"
-		dep.Service.EXPECT().CreateAccount(gomock.Any(), name).Return(entities.Account{}, ErrSvc)
+		dep.Service.EXPECT().CreateAccount(gomock.Any(), name, entities.USD, entities.AccountProfile{}).Return(entities.Account{}, ErrSvc)
 
 		requestBody := `{"account": {"name": "barry"}}`
 		resp, err := client.Post(dep.TestServer.URL+"/accounts", "application/json", strings.NewReader(requestBody))
@@ -119,14 +167,16 @@ func TestGetAccountsListRoute(t *testing.T) {
 		expectedBody := getAccountsListResponse{
 			Accounts: []entities.Account{
 				{
-					Name:     "ben",
-					Balance:  decimal.New(19, 0),
-					Currency: entities.USD,
+					Name:        "ben",
+					Balance:     decimal.New(19, 0),
+					Held:        decimal.New(4, 0),
+					CreditLimit: decimal.New(10, 0),
+					Currency:    entities.USD,
 				},
 			},
 		}
 
-		dep.Service.EXPECT().GetAccountsList(gomock.Any()).Return(expectedBody.Accounts, nil)
+		dep.Service.EXPECT().GetAccountsList(gomock.Any(), entities.AccountsFilter{}).Return(entities.AccountsPage{Accounts: expectedBody.Accounts}, nil)
 
 		resp, err := client.Get(dep.TestServer.URL + "/accounts")
 		defer resp.Body.Close()
@@ -145,7 +195,7 @@ func TestGetAccountsListRoute(t *testing.T) {
 		client := dep.TestServer.Client()
 		defer cleanUp()
 
-		dep.Service.EXPECT().GetAccountsList(gomock.Any()).Return(nil, ErrSvc)
+		dep.Service.EXPECT().GetAccountsList(gomock.Any(), gomock.Any()).Return(entities.AccountsPage{}, ErrSvc)
 
 		resp, err := client.Get(dep.TestServer.URL + "/accounts")
 		defer resp.Body.Close()
@@ -154,6 +204,148 @@ func TestGetAccountsListRoute(t *testing.T) {
 		assert.Equal(t, http.StatusInternalServerError, resp.StatusCode)
 		assert.Equal(t, "application/json; charset=utf-8", resp.Header.Get("Content-Type"))
 	})
+
+	t.Run("passes filter to service", func(t *testing.T) {
+		dep, cleanUp := setupServer(t)
+		client := dep.TestServer.Client()
+		defer cleanUp()
+
+		cursor := entities.AccountsCursor{Sort: entities.SortAccountsByBalance, Descending: true, Balance: decimal.New(19, 0), ID: 4}
+		expectedFilter := entities.AccountsFilter{
+			NamePrefix: "be",
+			OwnerID:    "customer-1",
+			Sort:       entities.SortAccountsByBalance,
+			Descending: true,
+			Limit:      10,
+			After:      &cursor,
+		}
+		svcResponse := entities.AccountsPage{Accounts: []entities.Account{}, NextCursor: "next-page"}
+		dep.Service.EXPECT().GetAccountsList(gomock.Any(), expectedFilter).Return(svcResponse, nil)
+
+		resp, err := client.Get(dep.TestServer.URL + "/accounts?name_prefix=be&owner=customer-1&sort=balance&order=desc&limit=10&cursor=" + cursor.Encode())
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		var actualBody map[string]interface{}
+		assert.NoError(t, json.NewDecoder(resp.Body).Decode(&actualBody))
+
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, map[string]interface{}{"accounts": []interface{}{}, "next_cursor": "next-page"}, actualBody)
+	})
+
+	t.Run("returns 400 on malformed parameters", func(t *testing.T) {
+		dep, cleanUp := setupServer(t)
+		client := dep.TestServer.Client()
+		defer cleanUp()
+
+		for _, query := range []string{"limit=0", "order=random", "cursor=garbage"} {
+			resp, err := client.Get(dep.TestServer.URL + "/accounts?" + query)
+			require.NoError(t, err)
+			resp.Body.Close()
+
+			assert.Equal(t, http.StatusBadRequest, resp.StatusCode, query)
+		}
+	})
+}
+
+func TestGetAccountRoute(t *testing.T) {
+	t.Run("renders account", func(t *testing.T) {
+		dep, cleanUp := setupServer(t)
+		client := dep.TestServer.Client()
+		defer cleanUp()
+
+		account := entities.Account{Name: "ben", Balance: decimal.New(19, 0), Held: decimal.New(4, 0), CreditLimit: decimal.New(10, 0), Currency: entities.USD}
+		dep.Service.EXPECT().GetAccount(gomock.Any(), "ben").Return(account, nil)
+
+		resp, err := client.Get(dep.TestServer.URL + "/accounts/ben")
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		var actualBody createAccountResponse
+		assert.NoError(t, json.NewDecoder(resp.Body).Decode(&actualBody))
+
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, account, actualBody.Account)
+	})
+}
+
+func TestUpdateAccountRoute(t *testing.T) {
+	t.Run("passes present attributes to service", func(t *testing.T) {
+		dep, cleanUp := setupServer(t)
+		client := dep.TestServer.Client()
+		defer cleanUp()
+
+		displayName := ""
+		account := entities.Account{Name: "ben", Balance: decimal.New(0, 0), Held: decimal.New(0, 0), CreditLimit: decimal.New(0, 0), Currency: entities.USD, Labels: []string{"savings"}}
+		dep.Service.EXPECT().UpdateAccount(gomock.Any(), "ben", entities.AccountUpdate{DisplayName: &displayName, Labels: []string{"savings"}}).Return(account, nil)
+
+		req, err := http.NewRequest(http.MethodPatch, dep.TestServer.URL+"/accounts/ben", strings.NewReader(`{"account": {"display_name": "", "labels": ["savings"]}}`))
+		require.NoError(t, err)
+
+		resp, err := client.Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		var actualBody createAccountResponse
+		assert.NoError(t, json.NewDecoder(resp.Body).Decode(&actualBody))
+
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, account, actualBody.Account)
+	})
+
+	t.Run("returns 400 on malformed body", func(t *testing.T) {
+		dep, cleanUp := setupServer(t)
+		client := dep.TestServer.Client()
+		defer cleanUp()
+
+		req, err := http.NewRequest(http.MethodPatch, dep.TestServer.URL+"/accounts/ben", strings.NewReader(`{"account": {"labels": "savings"}}`))
+		require.NoError(t, err)
+
+		resp, err := client.Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	})
+}
+
+func TestGetAccountPaymentsRoute(t *testing.T) {
+	t.Run("renders account payments", func(t *testing.T) {
+		dep, cleanUp := setupServer(t)
+		client := dep.TestServer.Client()
+		defer cleanUp()
+
+		ben := entities.Account{Name: "ben"}
+		jill := entities.Account{Name: "jill"}
+		svcResponse := entities.PaymentsPage{
+			Payments: []entities.Payment{
+				{Account: ben, Counterparty: jill, Direction: entities.Incoming, Amount: decimal.New(5, 0), Currency: entities.USD},
+			},
+		}
+		dep.Service.EXPECT().GetAccountPayments(gomock.Any(), "ben", entities.PaymentsFilter{Limit: 5}).Return(svcResponse, nil)
+
+		resp, err := client.Get(dep.TestServer.URL + "/accounts/ben/payments?limit=5")
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		var actualBody getPaymentsListResponse
+		assert.NoError(t, json.NewDecoder(resp.Body).Decode(&actualBody))
+
+		expectedBody := getPaymentsListResponse{
+			Payments: []payment{
+				{
+					Account:     ben.Name,
+					Direction:   entities.Incoming,
+					Currency:    entities.USD,
+					FromAccount: &jill.Name,
+					Amount:      decimal.New(5, 0),
+				},
+			},
+		}
+
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, expectedBody, actualBody)
+	})
 }
 
 func TestGetPaymentsListRoute(t *testing.T) {
@@ -166,11 +358,13 @@ func TestGetPaymentsListRoute(t *testing.T) {
 		vlad := entities.Account{Name: "vlad", ID: 250}
 
 		expectedBody := getPaymentsListResponse{
+			NextCursor: "next-page",
 			Payments: []payment{
 				{
 					Account:   mark.Name,
 					Direction: entities.Outgoing,
 					Currency:  entities.USD,
+					Kind:      entities.TransferTransaction,
 					ToAccount: &vlad.Name,
 					Amount:    decimal.New(173, 0),
 				},
@@ -181,14 +375,14 @@ func TestGetPaymentsListRoute(t *testing.T) {
 			{
 				Account:      mark,
 				Counterparty: vlad,
-				Transaction:  entities.Transaction{},
+				Transaction:  entities.Transaction{Kind: entities.TransferTransaction},
 				Direction:    entities.Outgoing,
 				Amount:       decimal.New(173, 0),
 				Currency:     entities.USD,
 			},
 		}
 
-		dep.Service.EXPECT().GetPaymentsList(gomock.Any()).Return(svcResponse, nil)
+		dep.Service.EXPECT().GetPaymentsList(gomock.Any(), entities.PaymentsFilter{}).Return(entities.PaymentsPage{Payments: svcResponse, NextCursor: "next-page"}, nil)
 
 		resp, err := client.Get(dep.TestServer.URL + "/payments")
 		defer resp.Body.Close()
@@ -207,7 +401,7 @@ func TestGetPaymentsListRoute(t *testing.T) {
 		client := dep.TestServer.Client()
 		defer cleanUp()
 
-		dep.Service.EXPECT().GetPaymentsList(gomock.Any()).Return(nil, ErrSvc)
+		dep.Service.EXPECT().GetPaymentsList(gomock.Any(), gomock.Any()).Return(entities.PaymentsPage{}, ErrSvc)
 
 		resp, err := client.Get(dep.TestServer.URL + "/payments")
 		defer resp.Body.Close()
@@ -216,10 +410,57 @@ func TestGetPaymentsListRoute(t *testing.T) {
 		assert.Equal(t, http.StatusInternalServerError, resp.StatusCode)
 		assert.Equal(t, "application/json; charset=utf-8", resp.Header.Get("Content-Type"))
 	})
+
+	t.Run("passes filter to service", func(t *testing.T) {
+		dep, cleanUp := setupServer(t)
+		client := dep.TestServer.Client()
+		defer cleanUp()
+
+		cursor := entities.PaymentsCursor{CreatedAt: time.Date(2019, 4, 8, 10, 0, 0, 0, time.UTC), ID: 12}
+		expectedFilter := entities.PaymentsFilter{
+			AccountName: "mark",
+			Direction:   entities.Incoming,
+			Kind:        entities.DepositTransaction,
+			Reference:   "wire-7",
+			FromDate:    time.Date(2019, 4, 1, 0, 0, 0, 0, time.UTC),
+			ToDate:      time.Date(2019, 4, 8, 12, 30, 0, 0, time.UTC),
+			MinAmount:   decimal.NullDecimal{Decimal: decimal.New(10, 0), Valid: true},
+			MaxAmount:   decimal.NullDecimal{Decimal: decimal.New(995, -1), Valid: true},
+			Limit:       20,
+			After:       &cursor,
+		}
+		dep.Service.EXPECT().GetPaymentsList(gomock.Any(), expectedFilter).Return(entities.PaymentsPage{Payments: []entities.Payment{}}, nil)
+
+		query := "account=mark&direction=incoming&kind=deposit&reference=wire-7&from_date=2019-04-01&to_date=2019-04-08T12:30:00Z" +
+			"&min_amount=10&max_amount=99.5&limit=20&cursor=" + cursor.Encode()
+		resp, err := client.Get(dep.TestServer.URL + "/payments?" + query)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		var actualBody map[string]interface{}
+		assert.NoError(t, json.NewDecoder(resp.Body).Decode(&actualBody))
+
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, map[string]interface{}{"payments": []interface{}{}, "next_cursor": nil}, actualBody)
+	})
+
+	t.Run("returns 400 on malformed parameters", func(t *testing.T) {
+		dep, cleanUp := setupServer(t)
+		client := dep.TestServer.Client()
+		defer cleanUp()
+
+		for _, query := range []string{"limit=ten", "limit=-1", "cursor=garbage", "from_date=yesterday", "min_amount=lots"} {
+			resp, err := client.Get(dep.TestServer.URL + "/payments?" + query)
+			require.NoError(t, err)
+			resp.Body.Close()
+
+			assert.Equal(t, http.StatusBadRequest, resp.StatusCode, query)
+		}
+	})
 }
 
 func TestSendPaymentRoute(t *testing.T) {
-	t.Run("returns blank object", func(t *testing.T) {
+	t.Run("renders transfer receipt", func(t *testing.T) {
 		dep, cleanUp := setupServer(t)
 		client := dep.TestServer.Client()
 		defer cleanUp()
@@ -227,7 +468,16 @@ func TestSendPaymentRoute(t *testing.T) {
 		from := entities.Account{Name: "barry"}
 		to := entities.Account{Name: "wicky"}
 		amount := decimal.New(1426, -2)
-		dep.Service.EXPECT().SendPayment(gomock.Any(), from, to, amount).Return(nil)
+		transaction := entities.Transaction{ID: 42, CreatedAt: time.Date(2019, 4, 8, 10, 0, 0, 0, time.UTC), Kind: entities.TransferTransaction}
+		receipt := entities.TransferReceipt{
+			Transaction: transaction,
+			Payments: []entities.Payment{
+				{Account: from, Counterparty: to, Transaction: transaction, Direction: entities.Outgoing, Amount: amount, Currency: entities.USD},
+				{Account: to, Counterparty: from, Transaction: transaction, Direction: entities.Incoming, Amount: amount, Currency: entities.USD},
+			},
+			SenderBalance: decimal.New(8574, -2),
+		}
+		dep.Service.EXPECT().SendPayment(gomock.Any(), from, to, amount, gomock.Any()).Return(receipt, nil)
 
 		requestBody := `{"payment": {"from": "barry", "to": "wicky", "amount": 14.26}}`
 		resp, err := client.Post(dep.TestServer.URL+"/payments", "application/json", strings.NewReader(requestBody))
@@ -237,9 +487,74 @@ func TestSendPaymentRoute(t *testing.T) {
 		var actualBody map[string]interface{}
 		assert.NoError(t, json.NewDecoder(resp.Body).Decode(&actualBody))
 
-		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		expectedBody := map[string]interface{}{
+			"receipt": map[string]interface{}{
+				"transaction_id": float64(42),
+				"created_at":     "2019-04-08T10:00:00Z",
+				"sender_balance": "85.74",
+				"payments": []interface{}{
+					map[string]interface{}{"account": "barry", "amount": "14.26", "currency": "usd", "direction": "outgoing", "kind": "transfer", "to_account": "wicky"},
+					map[string]interface{}{"account": "wicky", "amount": "14.26", "currency": "usd", "direction": "incoming", "kind": "transfer", "from_account": "barry"},
+				},
+			},
+		}
+
+		assert.Equal(t, http.StatusCreated, resp.StatusCode)
 		assert.Equal(t, "application/json; charset=utf-8", resp.Header.Get("Content-Type"))
-		assert.Equal(t, map[string]interface{}{}, actualBody)
+		assert.Equal(t, "/api/v1/transactions/42", resp.Header.Get("Location"))
+		assert.Equal(t, expectedBody, actualBody)
+	})
+
+	t.Run("passes payment details to service and renders them", func(t *testing.T) {
+		dep, cleanUp := setupServer(t)
+		client := dep.TestServer.Client()
+		defer cleanUp()
+
+		from := entities.Account{Name: "barry"}
+		to := entities.Account{Name: "wicky"}
+		amount := decimal.New(5, 0)
+		details := entities.PaymentDetails{
+			Description:       "April rent",
+			ExternalReference: "invoice-17",
+			Metadata:          map[string]string{"flat": "12"},
+		}
+		transaction := entities.Transaction{
+			ID:                44,
+			CreatedAt:         time.Date(2019, 4, 18, 10, 0, 0, 0, time.UTC),
+			Kind:              entities.TransferTransaction,
+			ExternalReference: details.ExternalReference,
+			Description:       details.Description,
+			Metadata:          details.Metadata,
+		}
+		receipt := entities.TransferReceipt{
+			Transaction: transaction,
+			Payments: []entities.Payment{
+				{Account: from, Counterparty: to, Transaction: transaction, Direction: entities.Outgoing, Amount: amount, Currency: entities.USD},
+				{Account: to, Counterparty: from, Transaction: transaction, Direction: entities.Incoming, Amount: amount, Currency: entities.USD},
+			},
+			SenderBalance: decimal.New(95, 0),
+		}
+		dep.Service.EXPECT().SendPayment(gomock.Any(), from, to, amount, details).Return(receipt, nil)
+
+		requestBody := `{"payment": {"from": "barry", "to": "wicky", "amount": 5, "description": "April rent", "reference": "invoice-17", "metadata": {"flat": "12"}}}`
+		resp, err := client.Post(dep.TestServer.URL+"/payments", "application/json", strings.NewReader(requestBody))
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		var actualBody struct {
+			Receipt struct {
+				Payments []map[string]interface{} `json:"payments"`
+			} `json:"receipt"`
+		}
+		assert.NoError(t, json.NewDecoder(resp.Body).Decode(&actualBody))
+
+		assert.Equal(t, http.StatusCreated, resp.StatusCode)
+		require.Len(t, actualBody.Receipt.Payments, 2)
+		for _, element := range actualBody.Receipt.Payments {
+			assert.Equal(t, "April rent", element["description"])
+			assert.Equal(t, "invoice-17", element["external_reference"])
+			assert.Equal(t, map[string]interface{}{"flat": "12"}, element["metadata"])
+		}
 	})
 
 	t.Run("returns 500 on server error", func(t *testing.T) {
@@ -250,7 +565,7 @@ func TestSendPaymentRoute(t *testing.T) {
 		from := entities.Account{Name: "barry"}
 		to := entities.Account{Name: "wicky"}
 		amount := decimal.New(1426, -2)
-		dep.Service.EXPECT().SendPayment(gomock.Any(), from, to, amount).Return(ErrSvc)
+		dep.Service.EXPECT().SendPayment(gomock.Any(), from, to, amount, gomock.Any()).Return(entities.TransferReceipt{}, ErrSvc)
 
 		requestBody := `{"payment": {"from": "barry", "to": "wicky", "amount": 14.26}}`
 		resp, err := client.Post(dep.TestServer.URL+"/payments", "application/json", strings.NewReader(requestBody))
@@ -261,3 +576,1225 @@ func TestSendPaymentRoute(t *testing.T) {
 		assert.Equal(t, "application/json; charset=utf-8", resp.Header.Get("Content-Type"))
 	})
 }
+
+func TestSendPaymentWithFeeRoute(t *testing.T) {
+	dep, cleanUp := setupServer(t)
+	client := dep.TestServer.Client()
+	defer cleanUp()
+
+	from := entities.Account{Name: "barry"}
+	to := entities.Account{Name: "wicky"}
+	feeAccount := entities.Account{Name: "FEE_USD"}
+	amount := decimal.New(10, 0)
+	fee := decimal.New(35, -2)
+	transaction := entities.Transaction{ID: 43, CreatedAt: time.Date(2019, 4, 16, 10, 0, 0, 0, time.UTC), Kind: entities.TransferTransaction, Fee: fee}
+	receipt := entities.TransferReceipt{
+		Transaction: transaction,
+		Payments: []entities.Payment{
+			{Account: from, Counterparty: to, Transaction: transaction, Direction: entities.Outgoing, Amount: amount, Currency: entities.USD},
+			{Account: to, Counterparty: from, Transaction: transaction, Direction: entities.Incoming, Amount: amount, Currency: entities.USD},
+			{Account: from, Counterparty: feeAccount, Transaction: transaction, Direction: entities.Outgoing, Amount: fee, Currency: entities.USD},
+			{Account: feeAccount, Counterparty: from, Transaction: transaction, Direction: entities.Incoming, Amount: fee, Currency: entities.USD},
+		},
+		Fee:           fee,
+		SenderBalance: decimal.New(965, -2),
+	}
+	dep.Service.EXPECT().SendPayment(gomock.Any(), from, to, amount, gomock.Any()).Return(receipt, nil)
+
+	requestBody := `{"payment": {"from": "barry", "to": "wicky", "amount": 10}}`
+	resp, err := client.Post(dep.TestServer.URL+"/payments", "application/json", strings.NewReader(requestBody))
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	var actualBody map[string]interface{}
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&actualBody))
+
+	expectedBody := map[string]interface{}{
+		"receipt": map[string]interface{}{
+			"transaction_id": float64(43),
+			"created_at":     "2019-04-16T10:00:00Z",
+			"fee":            "0.35",
+			"sender_balance": "9.65",
+			"payments": []interface{}{
+				map[string]interface{}{"account": "barry", "amount": "10", "currency": "usd", "direction": "outgoing", "kind": "transfer", "fee": "0.35", "to_account": "wicky"},
+				map[string]interface{}{"account": "wicky", "amount": "10", "currency": "usd", "direction": "incoming", "kind": "transfer", "fee": "0.35", "from_account": "barry"},
+				map[string]interface{}{"account": "barry", "amount": "0.35", "currency": "usd", "direction": "outgoing", "kind": "transfer", "fee": "0.35", "to_account": "FEE_USD"},
+				map[string]interface{}{"account": "FEE_USD", "amount": "0.35", "currency": "usd", "direction": "incoming", "kind": "transfer", "fee": "0.35", "from_account": "barry"},
+			},
+		},
+	}
+
+	assert.Equal(t, http.StatusCreated, resp.StatusCode)
+	assert.Equal(t, expectedBody, actualBody)
+}
+
+func TestPreviewPaymentRoute(t *testing.T) {
+	t.Run("renders payment preview", func(t *testing.T) {
+		dep, cleanUp := setupServer(t)
+		client := dep.TestServer.Client()
+		defer cleanUp()
+
+		from := entities.Account{Name: "barry"}
+		to := entities.Account{Name: "wicky"}
+		amount := decimal.New(10, 0)
+		preview := entities.PaymentPreview{Amount: amount, Fee: decimal.New(35, -2), Total: decimal.New(1035, -2), Currency: entities.USD}
+		dep.Service.EXPECT().PreviewPayment(gomock.Any(), from, to, amount).Return(preview, nil)
+
+		requestBody := `{"payment": {"from": "barry", "to": "wicky", "amount": 10}}`
+		resp, err := client.Post(dep.TestServer.URL+"/payments/preview", "application/json", strings.NewReader(requestBody))
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		var actualBody map[string]interface{}
+		assert.NoError(t, json.NewDecoder(resp.Body).Decode(&actualBody))
+
+		expectedBody := map[string]interface{}{
+			"preview": map[string]interface{}{"amount": "10", "fee": "0.35", "total": "10.35", "currency": "usd"},
+		}
+
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, expectedBody, actualBody)
+	})
+
+	t.Run("returns 400 when sender can't afford the payment", func(t *testing.T) {
+		dep, cleanUp := setupServer(t)
+		client := dep.TestServer.Client()
+		defer cleanUp()
+
+		svc := banking.NewService(memstorage.NewMemStorage(), banking.WithFeeSchedule(newFeeSchedule()))
+		_, err := svc.CreateAccount(ctx, "barry", entities.USD, entities.AccountProfile{})
+		require.NoError(t, err)
+		_, err = svc.CreateAccount(ctx, "wicky", entities.USD, entities.AccountProfile{})
+		require.NoError(t, err)
+		_, previewErr := svc.PreviewPayment(ctx, entities.Account{Name: "barry"}, entities.Account{Name: "wicky"}, decimal.New(10, 0))
+		require.Error(t, previewErr)
+
+		dep.Service.EXPECT().PreviewPayment(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(entities.PaymentPreview{}, previewErr)
+
+		requestBody := `{"payment": {"from": "barry", "to": "wicky", "amount": 10}}`
+		resp, err := client.Post(dep.TestServer.URL+"/payments/preview", "application/json", strings.NewReader(requestBody))
+		require.NoError(t, err)
+		resp.Body.Close()
+
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	})
+}
+
+func TestSendPaymentBatchRoute(t *testing.T) {
+	barry := entities.Account{Name: "barry"}
+	wicky := entities.Account{Name: "wicky"}
+	orders := []entities.PaymentOrder{
+		{From: barry, To: wicky, Amount: decimal.New(0, 0)},
+		{From: barry, To: wicky, Amount: decimal.New(5, 0)},
+	}
+	requestBody := `{"batch": {%s"payments": [{"from": "barry", "to": "wicky", "amount": 0}, {"from": "barry", "to": "wicky", "amount": 5}]}}`
+
+	transaction := entities.Transaction{ID: 44, CreatedAt: time.Date(2019, 4, 17, 10, 0, 0, 0, time.UTC), Kind: entities.TransferTransaction}
+	receipt := entities.TransferReceipt{
+		Transaction: transaction,
+		Payments: []entities.Payment{
+			{Account: barry, Counterparty: wicky, Transaction: transaction, Direction: entities.Outgoing, Amount: decimal.New(5, 0), Currency: entities.USD},
+			{Account: wicky, Counterparty: barry, Transaction: transaction, Direction: entities.Incoming, Amount: decimal.New(5, 0), Currency: entities.USD},
+		},
+		SenderBalance: decimal.New(95, 0),
+	}
+	receiptBody := map[string]interface{}{
+		"transaction_id": float64(44),
+		"created_at":     "2019-04-17T10:00:00Z",
+		"sender_balance": "95",
+		"payments": []interface{}{
+			map[string]interface{}{"account": "barry", "amount": "5", "currency": "usd", "direction": "outgoing", "kind": "transfer", "to_account": "wicky"},
+			map[string]interface{}{"account": "wicky", "amount": "5", "currency": "usd", "direction": "incoming", "kind": "transfer", "from_account": "barry"},
+		},
+	}
+
+	_, amountErr := banking.NewService(memstorage.NewMemStorage()).SendPayment(ctx, barry, wicky, decimal.New(0, 0), entities.PaymentDetails{})
+	require.Error(t, amountErr)
+
+	t.Run("renders outcomes of best effort batch with 207 status", func(t *testing.T) {
+		dep, cleanUp := setupServer(t)
+		client := dep.TestServer.Client()
+		defer cleanUp()
+
+		batchReceipt := entities.BatchReceipt{
+			Mode:  entities.BestEffortBatch,
+			Items: []entities.BatchItem{{Err: amountErr}, {Receipt: receipt}},
+		}
+		dep.Service.EXPECT().SendPaymentBatch(gomock.Any(), orders, entities.BestEffortBatch).Return(batchReceipt, nil)
+
+		resp, err := client.Post(dep.TestServer.URL+"/payment-batches", "application/json", strings.NewReader(fmt.Sprintf(requestBody, `"mode": "best_effort", `)))
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		var actualBody map[string]interface{}
+		assert.NoError(t, json.NewDecoder(resp.Body).Decode(&actualBody))
+
+		expectedBody := map[string]interface{}{
+			"batch": map[string]interface{}{
+				"mode":      "best_effort",
+				"succeeded": float64(1),
+				"failed":    float64(1),
+				"items": []interface{}{
+					map[string]interface{}{"index": float64(0), "status": float64(400), "error": "amount transferred should be a positive number"},
+					map[string]interface{}{"index": float64(1), "status": float64(201), "receipt": receiptBody},
+				},
+			},
+		}
+
+		assert.Equal(t, http.StatusMultiStatus, resp.StatusCode)
+		assert.Equal(t, expectedBody, actualBody)
+	})
+
+	t.Run("books batch atomically by default", func(t *testing.T) {
+		dep, cleanUp := setupServer(t)
+		client := dep.TestServer.Client()
+		defer cleanUp()
+
+		batchReceipt := entities.BatchReceipt{Mode: entities.AtomicBatch, Items: []entities.BatchItem{{Receipt: receipt}, {Receipt: receipt}}}
+		dep.Service.EXPECT().SendPaymentBatch(gomock.Any(), orders, entities.AtomicBatch).Return(batchReceipt, nil)
+
+		resp, err := client.Post(dep.TestServer.URL+"/payment-batches", "application/json", strings.NewReader(fmt.Sprintf(requestBody, "")))
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		var actualBody map[string]interface{}
+		assert.NoError(t, json.NewDecoder(resp.Body).Decode(&actualBody))
+
+		assert.Equal(t, http.StatusCreated, resp.StatusCode)
+		assert.Equal(t, float64(2), actualBody["batch"].(map[string]interface{})["succeeded"])
+	})
+
+	t.Run("renders failure of atomic batch", func(t *testing.T) {
+		dep, cleanUp := setupServer(t)
+		client := dep.TestServer.Client()
+		defer cleanUp()
+
+		_, batchErr := banking.NewService(memstorage.NewMemStorage()).SendPaymentBatch(ctx, orders, entities.AtomicBatch)
+		require.Error(t, batchErr)
+		dep.Service.EXPECT().SendPaymentBatch(gomock.Any(), orders, entities.AtomicBatch).Return(entities.BatchReceipt{}, batchErr)
+
+		resp, err := client.Post(dep.TestServer.URL+"/payment-batches", "application/json", strings.NewReader(fmt.Sprintf(requestBody, `"mode": "atomic", `)))
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		var actualBody map[string]interface{}
+		assert.NoError(t, json.NewDecoder(resp.Body).Decode(&actualBody))
+
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+		assert.Equal(t, map[string]interface{}{"error": "payments[0]: amount transferred should be a positive number"}, actualBody)
+	})
+}
+
+func TestPostTransactionRoute(t *testing.T) {
+	payer := entities.Account{Name: "payer"}
+	shop := entities.Account{Name: "shop"}
+	courier := entities.Account{Name: "courier"}
+	legs := []entities.Leg{
+		{Account: payer, Direction: entities.Outgoing, Amount: decimal.New(30, 0)},
+		{Account: shop, Direction: entities.Incoming, Amount: decimal.New(25, 0)},
+		{Account: courier, Direction: entities.Incoming, Amount: decimal.New(5, 0)},
+	}
+	requestBody := `{"transaction": {"legs": [
+		{"account": "payer", "direction": "outgoing", "amount": 30},
+		{"account": "shop", "direction": "incoming", "amount": 25},
+		{"account": "courier", "direction": "INCOMING", "amount": 5}
+	]}}`
+
+	t.Run("renders booked transaction with balances of its accounts", func(t *testing.T) {
+		dep, cleanUp := setupServer(t)
+		client := dep.TestServer.Client()
+		defer cleanUp()
+
+		transaction := entities.Transaction{ID: 51, CreatedAt: time.Date(2019, 4, 18, 10, 0, 0, 0, time.UTC), Kind: entities.TransferTransaction}
+		receipt := entities.PostingReceipt{
+			Transaction: transaction,
+			Payments: []entities.Payment{
+				{Account: payer, Counterparty: shop, Transaction: transaction, Direction: entities.Outgoing, Amount: decimal.New(25, 0), Currency: entities.USD},
+				{Account: shop, Counterparty: payer, Transaction: transaction, Direction: entities.Incoming, Amount: decimal.New(25, 0), Currency: entities.USD},
+			},
+			Accounts: []entities.Account{
+				{Name: "payer", Balance: decimal.New(70, 0)},
+				{Name: "shop", Balance: decimal.New(25, 0)},
+				{Name: "courier", Balance: decimal.New(5, 0)},
+			},
+		}
+		dep.Service.EXPECT().PostTransaction(gomock.Any(), legs).Return(receipt, nil)
+
+		resp, err := client.Post(dep.TestServer.URL+"/transactions", "application/json", strings.NewReader(requestBody))
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		var actualBody map[string]interface{}
+		assert.NoError(t, json.NewDecoder(resp.Body).Decode(&actualBody))
+
+		expectedBody := map[string]interface{}{
+			"receipt": map[string]interface{}{
+				"transaction_id": float64(51),
+				"created_at":     "2019-04-18T10:00:00Z",
+				"kind":           "transfer",
+				"payments": []interface{}{
+					map[string]interface{}{"account": "payer", "amount": "25", "currency": "usd", "direction": "outgoing", "kind": "transfer", "to_account": "shop"},
+					map[string]interface{}{"account": "shop", "amount": "25", "currency": "usd", "direction": "incoming", "kind": "transfer", "from_account": "payer"},
+				},
+				"balances": map[string]interface{}{"payer": "70", "shop": "25", "courier": "5"},
+			},
+		}
+
+		assert.Equal(t, http.StatusCreated, resp.StatusCode)
+		assert.Equal(t, "/api/v1/transactions/51", resp.Header.Get("Location"))
+		assert.Equal(t, expectedBody, actualBody)
+	})
+
+	t.Run("renders invalid legs as bad request", func(t *testing.T) {
+		dep, cleanUp := setupServer(t)
+		client := dep.TestServer.Client()
+		defer cleanUp()
+
+		_, postErr := banking.NewService(memstorage.NewMemStorage()).PostTransaction(ctx, legs[:1])
+		require.Error(t, postErr)
+		dep.Service.EXPECT().PostTransaction(gomock.Any(), legs).Return(entities.PostingReceipt{}, postErr)
+
+		resp, err := client.Post(dep.TestServer.URL+"/transactions", "application/json", strings.NewReader(requestBody))
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		var actualBody map[string]interface{}
+		assert.NoError(t, json.NewDecoder(resp.Body).Decode(&actualBody))
+
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+		assert.Equal(t, map[string]interface{}{"error": "transaction should have at least two legs"}, actualBody)
+	})
+}
+
+func TestScheduledPaymentRoutes(t *testing.T) {
+	startAt := time.Date(2019, 5, 1, 9, 0, 0, 0, time.UTC)
+	payment := entities.ScheduledPayment{
+		ID:           7,
+		Account:      entities.Account{Name: "payer"},
+		Counterparty: entities.Account{Name: "payee"},
+		Amount:       decimal.New(25, 0),
+		Currency:     entities.USD,
+		Frequency:    entities.MonthlyFrequency,
+		Status:       entities.ActiveSchedule,
+		StartAt:      startAt,
+		NextRunAt:    startAt,
+		CreatedAt:    time.Date(2019, 4, 18, 10, 0, 0, 0, time.UTC),
+	}
+	expectedPayment := map[string]interface{}{
+		"id":          float64(7),
+		"account":     "payer",
+		"to_account":  "payee",
+		"amount":      "25",
+		"currency":    "usd",
+		"frequency":   "monthly",
+		"status":      "active",
+		"start_at":    "2019-05-01T09:00:00Z",
+		"next_run_at": "2019-05-01T09:00:00Z",
+		"attempts":    float64(0),
+		"created_at":  "2019-04-18T10:00:00Z",
+	}
+
+	t.Run("renders created scheduled payment", func(t *testing.T) {
+		dep, cleanUp := setupServer(t)
+		client := dep.TestServer.Client()
+		defer cleanUp()
+
+		dep.Service.EXPECT().CreateScheduledPayment(gomock.Any(), entities.ScheduledPayment{
+			Account:      entities.Account{Name: "payer"},
+			Counterparty: entities.Account{Name: "payee"},
+			Amount:       decimal.New(25, 0),
+			Frequency:    entities.MonthlyFrequency,
+			StartAt:      startAt,
+		}).Return(payment, nil)
+
+		requestBody := `{"scheduled_payment": {"from": "payer", "to": "payee", "amount": 25, "frequency": "Monthly", "start_at": "2019-05-01T09:00:00Z"}}`
+		resp, err := client.Post(dep.TestServer.URL+"/scheduled-payments", "application/json", strings.NewReader(requestBody))
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		var actualBody map[string]interface{}
+		assert.NoError(t, json.NewDecoder(resp.Body).Decode(&actualBody))
+
+		assert.Equal(t, http.StatusCreated, resp.StatusCode)
+		assert.Equal(t, "/api/v1/scheduled-payments/7", resp.Header.Get("Location"))
+		assert.Equal(t, map[string]interface{}{"scheduled_payment": expectedPayment}, actualBody)
+	})
+
+	t.Run("lists scheduled payments of the account", func(t *testing.T) {
+		dep, cleanUp := setupServer(t)
+		client := dep.TestServer.Client()
+		defer cleanUp()
+
+		dep.Service.EXPECT().GetScheduledPayments(gomock.Any(), "payer").Return([]entities.ScheduledPayment{payment}, nil)
+
+		resp, err := client.Get(dep.TestServer.URL + "/scheduled-payments?account=payer")
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		var actualBody map[string]interface{}
+		assert.NoError(t, json.NewDecoder(resp.Body).Decode(&actualBody))
+
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, map[string]interface{}{"scheduled_payments": []interface{}{expectedPayment}}, actualBody)
+	})
+
+	t.Run("updates scheduled payment", func(t *testing.T) {
+		dep, cleanUp := setupServer(t)
+		client := dep.TestServer.Client()
+		defer cleanUp()
+
+		paused := payment
+		paused.Status = entities.PausedSchedule
+		dep.Service.EXPECT().UpdateScheduledPayment(gomock.Any(), 7, entities.ScheduledPaymentUpdate{
+			Amount: decimal.NullDecimal{Decimal: decimal.New(30, 0), Valid: true},
+			Status: entities.PausedSchedule,
+		}).Return(paused, nil)
+
+		req, err := http.NewRequest(http.MethodPatch, dep.TestServer.URL+"/scheduled-payments/7", strings.NewReader(`{"scheduled_payment": {"amount": "30", "status": "paused"}}`))
+		require.NoError(t, err)
+
+		resp, err := client.Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		var actualBody map[string]map[string]interface{}
+		assert.NoError(t, json.NewDecoder(resp.Body).Decode(&actualBody))
+
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, "paused", actualBody["scheduled_payment"]["status"])
+	})
+
+	t.Run("cancels scheduled payment", func(t *testing.T) {
+		dep, cleanUp := setupServer(t)
+		client := dep.TestServer.Client()
+		defer cleanUp()
+
+		_, cancelErr := banking.NewService(memstorage.NewMemStorage()).CancelScheduledPayment(ctx, 7)
+		require.Error(t, cancelErr)
+		dep.Service.EXPECT().CancelScheduledPayment(gomock.Any(), 7).Return(entities.ScheduledPayment{}, cancelErr)
+
+		req, err := http.NewRequest(http.MethodDelete, dep.TestServer.URL+"/scheduled-payments/7", nil)
+		require.NoError(t, err)
+
+		resp, err := client.Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		var actualBody map[string]interface{}
+		assert.NoError(t, json.NewDecoder(resp.Body).Decode(&actualBody))
+
+		assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+		assert.Equal(t, map[string]interface{}{"error": "scheduled payment not found"}, actualBody)
+	})
+
+	t.Run("renders run history", func(t *testing.T) {
+		dep, cleanUp := setupServer(t)
+		client := dep.TestServer.Client()
+		defer cleanUp()
+
+		dep.Service.EXPECT().GetScheduledRuns(gomock.Any(), 7).Return([]entities.ScheduledRun{
+			{ID: 1, ScheduleID: 7, ScheduledFor: startAt, Attempt: 1, Status: entities.FailedRun, Error: "sender account has insufficient funds", CreatedAt: startAt},
+			{ID: 2, ScheduleID: 7, ScheduledFor: startAt, Attempt: 2, Status: entities.SucceededRun, TransactionID: 12, CreatedAt: startAt.Add(time.Hour)},
+		}, nil)
+
+		resp, err := client.Get(dep.TestServer.URL + "/scheduled-payments/7/runs")
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		var actualBody map[string]interface{}
+		assert.NoError(t, json.NewDecoder(resp.Body).Decode(&actualBody))
+
+		expectedBody := map[string]interface{}{
+			"runs": []interface{}{
+				map[string]interface{}{"id": float64(1), "scheduled_for": "2019-05-01T09:00:00Z", "attempt": float64(1), "status": "failed", "error": "sender account has insufficient funds", "created_at": "2019-05-01T09:00:00Z"},
+				map[string]interface{}{"id": float64(2), "scheduled_for": "2019-05-01T09:00:00Z", "attempt": float64(2), "status": "succeeded", "transaction_id": float64(12), "created_at": "2019-05-01T10:00:00Z"},
+			},
+		}
+
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, expectedBody, actualBody)
+	})
+}
+
+func TestChangeAccountStatusRoutes(t *testing.T) {
+	operatorToken := banking.WithOperatorToken("s3cret")
+
+	t.Run("renders account with its new status", func(t *testing.T) {
+		for path, status := range map[string]entities.AccountStatus{
+			"freeze":   entities.FrozenAccount,
+			"unfreeze": entities.ActiveAccount,
+			"close":    entities.ClosedAccount,
+		} {
+			dep, cleanUp := setupServer(t, operatorToken)
+			client := dep.TestServer.Client()
+
+			account := entities.Account{Name: "molly", Type: entities.UserAccount, Status: status, Balance: decimal.New(1, 0), CreditLimit: decimal.New(1, 0), Currency: entities.USD}
+			switch status {
+			case entities.FrozenAccount:
+				dep.Service.EXPECT().FreezeAccount(gomock.Any(), "molly").Return(account, nil)
+			case entities.ActiveAccount:
+				dep.Service.EXPECT().UnfreezeAccount(gomock.Any(), "molly").Return(account, nil)
+			case entities.ClosedAccount:
+				dep.Service.EXPECT().CloseAccount(gomock.Any(), "molly").Return(account, nil)
+			}
+
+			req, err := http.NewRequest(http.MethodPost, dep.TestServer.URL+"/accounts/molly/"+path, nil)
+			require.NoError(t, err)
+			req.Header.Set("Authorization", "Bearer s3cret")
+
+			resp, err := client.Do(req)
+			require.NoError(t, err)
+
+			var actualBody map[string]map[string]interface{}
+			assert.NoError(t, json.NewDecoder(resp.Body).Decode(&actualBody))
+			resp.Body.Close()
+
+			assert.Equal(t, http.StatusOK, resp.StatusCode, path)
+			assert.Equal(t, string(status), actualBody["account"]["status"], path)
+			cleanUp()
+		}
+	})
+
+	t.Run("is restricted to privileged callers", func(t *testing.T) {
+		dep, cleanUp := setupServer(t, operatorToken)
+		client := dep.TestServer.Client()
+		defer cleanUp()
+
+		for _, path := range []string{"freeze", "unfreeze", "close"} {
+			resp, err := client.Post(dep.TestServer.URL+"/accounts/molly/"+path, "application/json", nil)
+			require.NoError(t, err)
+			resp.Body.Close()
+
+			assert.Equal(t, http.StatusUnauthorized, resp.StatusCode, path)
+		}
+	})
+}
+
+func TestAccountStatusErrors(t *testing.T) {
+	// real service errors are obtained by walking accounts through their lifecycle
+	svc := banking.NewService(memstorage.NewMemStorage())
+	for _, name := range []string{"frozen", "closed", "funded"} {
+		_, err := svc.CreateAccount(ctx, name, entities.USD, entities.AccountProfile{})
+		require.NoError(t, err)
+	}
+	_, err := svc.FreezeAccount(ctx, "frozen")
+	require.NoError(t, err)
+	_, err = svc.CloseAccount(ctx, "closed")
+	require.NoError(t, err)
+	_, err = svc.Deposit(ctx, "funded", decimal.New(10, 0), "wire-1")
+	require.NoError(t, err)
+
+	errorOf := func(_ interface{}, err error) error {
+		return err
+	}
+
+	cases := []struct {
+		title  string
+		err    error
+		status int
+	}{
+		{title: "frozen sender", err: errorOf(svc.SendPayment(ctx, entities.Account{Name: "frozen"}, entities.Account{Name: "funded"}, decimal.New(1, 0), entities.PaymentDetails{})), status: http.StatusLocked},
+		{title: "frozen receiver", err: errorOf(svc.SendPayment(ctx, entities.Account{Name: "funded"}, entities.Account{Name: "frozen"}, decimal.New(1, 0), entities.PaymentDetails{})), status: http.StatusLocked},
+		{title: "closed sender", err: errorOf(svc.SendPayment(ctx, entities.Account{Name: "closed"}, entities.Account{Name: "funded"}, decimal.New(1, 0), entities.PaymentDetails{})), status: http.StatusGone},
+		{title: "closed receiver", err: errorOf(svc.SendPayment(ctx, entities.Account{Name: "funded"}, entities.Account{Name: "closed"}, decimal.New(1, 0), entities.PaymentDetails{})), status: http.StatusGone},
+		{title: "closed account", err: errorOf(svc.FreezeAccount(ctx, "closed")), status: http.StatusGone},
+		{title: "non-zero balance", err: errorOf(svc.CloseAccount(ctx, "funded")), status: http.StatusConflict},
+		{title: "house account", err: errorOf(svc.FreezeAccount(ctx, "SYSTEM")), status: http.StatusBadRequest},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.title, func(t *testing.T) {
+			require.Error(t, tc.err)
+
+			dep, cleanUp := setupServer(t)
+			client := dep.TestServer.Client()
+			defer cleanUp()
+
+			dep.Service.EXPECT().SendPayment(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(entities.TransferReceipt{}, tc.err)
+
+			requestBody := `{"payment": {"from": "barry", "to": "wicky", "amount": 1}}`
+			resp, err := client.Post(dep.TestServer.URL+"/payments", "application/json", strings.NewReader(requestBody))
+			require.NoError(t, err)
+			defer resp.Body.Close()
+
+			var actualBody map[string]string
+			assert.NoError(t, json.NewDecoder(resp.Body).Decode(&actualBody))
+
+			assert.Equal(t, tc.status, resp.StatusCode)
+			assert.Equal(t, tc.err.Error(), actualBody["error"])
+		})
+	}
+}
+
+func TestSendFXPaymentRoute(t *testing.T) {
+	t.Run("passes quote to service", func(t *testing.T) {
+		dep, cleanUp := setupServer(t)
+		client := dep.TestServer.Client()
+		defer cleanUp()
+
+		from := entities.Account{Name: "juan"}
+		to := entities.Account{Name: "john"}
+		amount := decimal.New(1000, 0)
+		receipt := entities.TransferReceipt{Transaction: entities.Transaction{ID: 43}}
+		dep.Service.EXPECT().SendFXPayment(gomock.Any(), from, to, amount, 7, gomock.Any()).Return(receipt, nil)
+
+		requestBody := `{"payment": {"from": "juan", "to": "john", "amount": 1000, "quote_id": 7}}`
+		resp, err := client.Post(dep.TestServer.URL+"/payments", "application/json", strings.NewReader(requestBody))
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		assert.Equal(t, http.StatusCreated, resp.StatusCode)
+		assert.Equal(t, "/api/v1/transactions/43", resp.Header.Get("Location"))
+	})
+}
+
+func TestDepositRoute(t *testing.T) {
+	operatorToken := banking.WithOperatorToken("s3cret")
+
+	t.Run("renders deposit receipt", func(t *testing.T) {
+		dep, cleanUp := setupServer(t, operatorToken)
+		client := dep.TestServer.Client()
+		defer cleanUp()
+
+		molly := entities.Account{Name: "molly"}
+		system := entities.Account{Name: "SYSTEM"}
+		amount := decimal.New(25, 0)
+		transaction := entities.Transaction{ID: 42, CreatedAt: time.Date(2019, 4, 8, 10, 0, 0, 0, time.UTC), Kind: entities.DepositTransaction, ExternalReference: "wire-1"}
+		receipt := entities.TransferReceipt{
+			Transaction: transaction,
+			Payments: []entities.Payment{
+				{Account: system, Counterparty: molly, Transaction: transaction, Direction: entities.Outgoing, Amount: amount, Currency: entities.USD},
+				{Account: molly, Counterparty: system, Transaction: transaction, Direction: entities.Incoming, Amount: amount, Currency: entities.USD},
+			},
+			SenderBalance:   decimal.New(-25, 0),
+			ReceiverBalance: decimal.New(25, 0),
+		}
+		dep.Service.EXPECT().Deposit(gomock.Any(), "molly", amount, "wire-1").Return(receipt, nil)
+
+		requestBody := `{"deposit": {"amount": 25, "reference": "wire-1"}}`
+		req, err := http.NewRequest(http.MethodPost, dep.TestServer.URL+"/accounts/molly/deposits", strings.NewReader(requestBody))
+		require.NoError(t, err)
+		req.Header.Set("Authorization", "Bearer s3cret")
+
+		resp, err := client.Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		var actualBody map[string]interface{}
+		assert.NoError(t, json.NewDecoder(resp.Body).Decode(&actualBody))
+
+		expectedBody := map[string]interface{}{
+			"receipt": map[string]interface{}{
+				"transaction_id":     float64(42),
+				"created_at":         "2019-04-08T10:00:00Z",
+				"kind":               "deposit",
+				"external_reference": "wire-1",
+				"balance":            "25",
+				"payments": []interface{}{
+					map[string]interface{}{"account": "SYSTEM", "amount": "25", "currency": "usd", "direction": "outgoing", "kind": "deposit", "external_reference": "wire-1", "to_account": "molly"},
+					map[string]interface{}{"account": "molly", "amount": "25", "currency": "usd", "direction": "incoming", "kind": "deposit", "external_reference": "wire-1", "from_account": "SYSTEM"},
+				},
+			},
+		}
+
+		assert.Equal(t, http.StatusCreated, resp.StatusCode)
+		assert.Equal(t, "/api/v1/transactions/42", resp.Header.Get("Location"))
+		assert.Equal(t, expectedBody, actualBody)
+	})
+
+	t.Run("returns 400 on blank reference", func(t *testing.T) {
+		dep, cleanUp := setupServer(t, operatorToken)
+		client := dep.TestServer.Client()
+		defer cleanUp()
+
+		_, svcErr := banking.NewService(nil).Deposit(context.Background(), "molly", decimal.New(25, 0), "")
+		dep.Service.EXPECT().Deposit(gomock.Any(), "molly", decimal.New(25, 0), "").Return(entities.TransferReceipt{}, svcErr)
+
+		requestBody := `{"deposit": {"amount": 25, "reference": "  "}}`
+		req, err := http.NewRequest(http.MethodPost, dep.TestServer.URL+"/accounts/molly/deposits", strings.NewReader(requestBody))
+		require.NoError(t, err)
+		req.Header.Set("Authorization", "Bearer s3cret")
+
+		resp, err := client.Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	})
+
+	t.Run("is restricted to privileged callers", func(t *testing.T) {
+		cases := []struct {
+			title  string
+			opts   []banking.HandlerOption
+			header string
+			status int
+		}{
+			{title: "without token", opts: []banking.HandlerOption{operatorToken}, header: "", status: http.StatusUnauthorized},
+			{title: "with wrong token", opts: []banking.HandlerOption{operatorToken}, header: "Bearer guess", status: http.StatusForbidden},
+			{title: "when token is not configured", header: "Bearer s3cret", status: http.StatusForbidden},
+		}
+
+		for _, tc := range cases {
+			t.Run(tc.title, func(t *testing.T) {
+				dep, cleanUp := setupServer(t, tc.opts...)
+				client := dep.TestServer.Client()
+				defer cleanUp()
+
+				for _, path := range []string{"/accounts/molly/deposits", "/accounts/molly/withdrawals"} {
+					req, err := http.NewRequest(http.MethodPost, dep.TestServer.URL+path, strings.NewReader(`{}`))
+					require.NoError(t, err)
+					if tc.header != "" {
+						req.Header.Set("Authorization", tc.header)
+					}
+
+					resp, err := client.Do(req)
+					require.NoError(t, err)
+					resp.Body.Close()
+
+					assert.Equal(t, tc.status, resp.StatusCode, path)
+				}
+			})
+		}
+	})
+}
+
+func TestWithdrawalRoute(t *testing.T) {
+	t.Run("passes withdrawal to service", func(t *testing.T) {
+		dep, cleanUp := setupServer(t, banking.WithOperatorToken("s3cret"))
+		client := dep.TestServer.Client()
+		defer cleanUp()
+
+		transaction := entities.Transaction{ID: 43, Kind: entities.WithdrawalTransaction, ExternalReference: "card-1"}
+		receipt := entities.TransferReceipt{Transaction: transaction, Payments: []entities.Payment{}, SenderBalance: decimal.New(6, 0)}
+		dep.Service.EXPECT().Withdraw(gomock.Any(), "molly", decimal.New(4, 0), "card-1").Return(receipt, nil)
+
+		requestBody := `{"withdrawal": {"amount": 4, "reference": " card-1 "}}`
+		req, err := http.NewRequest(http.MethodPost, dep.TestServer.URL+"/accounts/molly/withdrawals", strings.NewReader(requestBody))
+		require.NoError(t, err)
+		req.Header.Set("Authorization", "Bearer s3cret")
+
+		resp, err := client.Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		var actualBody map[string]map[string]interface{}
+		assert.NoError(t, json.NewDecoder(resp.Body).Decode(&actualBody))
+
+		assert.Equal(t, http.StatusCreated, resp.StatusCode)
+		assert.Equal(t, "6", actualBody["receipt"]["balance"])
+		assert.Equal(t, "withdrawal", actualBody["receipt"]["kind"])
+	})
+}
+
+func TestSetCreditLimitRoute(t *testing.T) {
+	operatorToken := banking.WithOperatorToken("s3cret")
+
+	t.Run("renders updated account", func(t *testing.T) {
+		dep, cleanUp := setupServer(t, operatorToken)
+		client := dep.TestServer.Client()
+		defer cleanUp()
+
+		account := entities.Account{Name: "molly", Type: entities.UserAccount, Balance: decimal.New(-5, 0), CreditLimit: decimal.New(50, 0), Currency: entities.USD}
+		dep.Service.EXPECT().SetCreditLimit(gomock.Any(), "molly", decimal.New(50, 0), "alice", "salary advance").Return(account, nil)
+
+		requestBody := `{"credit_limit": {"limit": 50, "changed_by": "alice", "reason": "salary advance"}}`
+		req, err := http.NewRequest(http.MethodPut, dep.TestServer.URL+"/admin/accounts/molly/credit-limit", strings.NewReader(requestBody))
+		require.NoError(t, err)
+		req.Header.Set("Authorization", "Bearer s3cret")
+
+		resp, err := client.Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		var actualBody map[string]map[string]interface{}
+		assert.NoError(t, json.NewDecoder(resp.Body).Decode(&actualBody))
+
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, "50", actualBody["account"]["credit_limit"])
+		assert.Equal(t, "-5", actualBody["account"]["balance"])
+	})
+
+	t.Run("returns 400 on negative limit", func(t *testing.T) {
+		dep, cleanUp := setupServer(t, operatorToken)
+		client := dep.TestServer.Client()
+		defer cleanUp()
+
+		_, svcErr := banking.NewService(nil).SetCreditLimit(context.Background(), "molly", decimal.New(-1, 0), "alice", "")
+		dep.Service.EXPECT().SetCreditLimit(gomock.Any(), "molly", decimal.New(-1, 0), "alice", "").Return(entities.Account{}, svcErr)
+
+		requestBody := `{"credit_limit": {"limit": -1, "changed_by": "alice"}}`
+		req, err := http.NewRequest(http.MethodPut, dep.TestServer.URL+"/admin/accounts/molly/credit-limit", strings.NewReader(requestBody))
+		require.NoError(t, err)
+		req.Header.Set("Authorization", "Bearer s3cret")
+
+		resp, err := client.Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	})
+
+	t.Run("is restricted to privileged callers", func(t *testing.T) {
+		dep, cleanUp := setupServer(t, operatorToken)
+		client := dep.TestServer.Client()
+		defer cleanUp()
+
+		req, err := http.NewRequest(http.MethodPut, dep.TestServer.URL+"/admin/accounts/molly/credit-limit", strings.NewReader(`{}`))
+		require.NoError(t, err)
+
+		resp, err := client.Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	})
+}
+
+func TestGetCreditLimitChangesRoute(t *testing.T) {
+	dep, cleanUp := setupServer(t, banking.WithOperatorToken("s3cret"))
+	client := dep.TestServer.Client()
+	defer cleanUp()
+
+	changes := []entities.CreditLimitChange{{
+		ID:            1,
+		AccountID:     7,
+		PreviousLimit: decimal.New(10, 0),
+		NewLimit:      decimal.New(50, 0),
+		ChangedBy:     "alice",
+		CreatedAt:     time.Date(2019, 4, 12, 9, 0, 0, 0, time.UTC),
+	}}
+	dep.Service.EXPECT().GetCreditLimitChanges(gomock.Any(), "molly").Return(changes, nil)
+
+	req, err := http.NewRequest(http.MethodGet, dep.TestServer.URL+"/admin/accounts/molly/credit-limit-changes", nil)
+	require.NoError(t, err)
+	req.Header.Set("Authorization", "Bearer s3cret")
+
+	resp, err := client.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	var actualBody map[string]interface{}
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&actualBody))
+
+	expectedBody := map[string]interface{}{
+		"credit_limit_changes": []interface{}{
+			map[string]interface{}{
+				"previous_limit": "10",
+				"new_limit":      "50",
+				"changed_by":     "alice",
+				"created_at":     "2019-04-12T09:00:00Z",
+			},
+		},
+	}
+
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, expectedBody, actualBody)
+}
+
+func TestGetTransactionRoute(t *testing.T) {
+	t.Run("renders transaction with its payments", func(t *testing.T) {
+		dep, cleanUp := setupServer(t)
+		client := dep.TestServer.Client()
+		defer cleanUp()
+
+		transaction := entities.Transaction{ID: 42, CreatedAt: time.Date(2019, 4, 8, 10, 0, 0, 0, time.UTC), Kind: entities.TransferTransaction}
+		payments := []entities.Payment{
+			{
+				Account:      entities.Account{Name: "barry"},
+				Counterparty: entities.Account{Name: "wicky"},
+				Transaction:  transaction,
+				Direction:    entities.Outgoing,
+				Amount:       decimal.New(1426, -2),
+				Currency:     entities.USD,
+			},
+		}
+		dep.Service.EXPECT().GetTransaction(gomock.Any(), 42).Return(transaction, payments, nil)
+
+		resp, err := client.Get(dep.TestServer.URL + "/transactions/42")
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		var actualBody map[string]interface{}
+		assert.NoError(t, json.NewDecoder(resp.Body).Decode(&actualBody))
+
+		expectedBody := map[string]interface{}{
+			"transaction": map[string]interface{}{
+				"id":         float64(42),
+				"created_at": "2019-04-08T10:00:00Z",
+				"kind":       "transfer",
+				"payments": []interface{}{
+					map[string]interface{}{"account": "barry", "amount": "14.26", "currency": "usd", "direction": "outgoing", "kind": "transfer", "to_account": "wicky"},
+				},
+			},
+		}
+
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, expectedBody, actualBody)
+	})
+}
+
+type createQuoteResponse struct {
+	Quote entities.Quote `json:"quote"`
+}
+
+func TestCompensateTransactionRoutes(t *testing.T) {
+	operatorToken := banking.WithOperatorToken("s3cret")
+	barry := entities.Account{Name: "barry"}
+	wicky := entities.Account{Name: "wicky"}
+
+	t.Run("renders refund receipt", func(t *testing.T) {
+		dep, cleanUp := setupServer(t, operatorToken)
+		client := dep.TestServer.Client()
+		defer cleanUp()
+
+		amount := decimal.New(5, 0)
+		transaction := entities.Transaction{ID: 44, CreatedAt: time.Date(2019, 4, 14, 10, 0, 0, 0, time.UTC), Kind: entities.RefundTransaction, OriginalID: 42}
+		receipt := entities.TransferReceipt{
+			Transaction: transaction,
+			Payments: []entities.Payment{
+				{Account: wicky, Counterparty: barry, Transaction: transaction, Direction: entities.Outgoing, Amount: amount, Currency: entities.USD},
+				{Account: barry, Counterparty: wicky, Transaction: transaction, Direction: entities.Incoming, Amount: amount, Currency: entities.USD},
+			},
+		}
+		dep.Service.EXPECT().RefundTransaction(gomock.Any(), 42, amount).Return(receipt, nil)
+
+		req, err := http.NewRequest(http.MethodPost, dep.TestServer.URL+"/transactions/42/refund", strings.NewReader(`{"refund": {"amount": 5}}`))
+		require.NoError(t, err)
+		req.Header.Set("Authorization", "Bearer s3cret")
+
+		resp, err := client.Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		var actualBody map[string]interface{}
+		assert.NoError(t, json.NewDecoder(resp.Body).Decode(&actualBody))
+
+		expectedBody := map[string]interface{}{
+			"receipt": map[string]interface{}{
+				"transaction_id":          float64(44),
+				"created_at":              "2019-04-14T10:00:00Z",
+				"kind":                    "refund",
+				"original_transaction_id": float64(42),
+				"payments": []interface{}{
+					map[string]interface{}{"account": "wicky", "amount": "5", "currency": "usd", "direction": "outgoing", "kind": "refund", "original_transaction_id": float64(42), "to_account": "barry"},
+					map[string]interface{}{"account": "barry", "amount": "5", "currency": "usd", "direction": "incoming", "kind": "refund", "original_transaction_id": float64(42), "from_account": "wicky"},
+				},
+			},
+		}
+
+		assert.Equal(t, http.StatusCreated, resp.StatusCode)
+		assert.Equal(t, "/api/v1/transactions/44", resp.Header.Get("Location"))
+		assert.Equal(t, expectedBody, actualBody)
+	})
+
+	t.Run("passes reversal to service", func(t *testing.T) {
+		dep, cleanUp := setupServer(t, operatorToken)
+		client := dep.TestServer.Client()
+		defer cleanUp()
+
+		transaction := entities.Transaction{ID: 43, Kind: entities.ReversalTransaction, OriginalID: 42}
+		dep.Service.EXPECT().ReverseTransaction(gomock.Any(), 42).Return(entities.TransferReceipt{Transaction: transaction, Payments: []entities.Payment{}}, nil)
+
+		req, err := http.NewRequest(http.MethodPost, dep.TestServer.URL+"/transactions/42/reverse", nil)
+		require.NoError(t, err)
+		req.Header.Set("Authorization", "Bearer s3cret")
+
+		resp, err := client.Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		var actualBody map[string]map[string]interface{}
+		assert.NoError(t, json.NewDecoder(resp.Body).Decode(&actualBody))
+
+		assert.Equal(t, http.StatusCreated, resp.StatusCode)
+		assert.Equal(t, "reversal", actualBody["receipt"]["kind"])
+		assert.Equal(t, float64(42), actualBody["receipt"]["original_transaction_id"])
+	})
+
+	t.Run("returns 400 on refund exceeding the original amount", func(t *testing.T) {
+		dep, cleanUp := setupServer(t, operatorToken)
+		client := dep.TestServer.Client()
+		defer cleanUp()
+
+		// real service error is obtained by refunding a fresh transfer twice its amount
+		svc := banking.NewService(memstorage.NewMemStorage())
+		for _, name := range []string{"barry", "wicky"} {
+			_, err := svc.CreateAccount(ctx, name, entities.USD, entities.AccountProfile{})
+			require.NoError(t, err)
+		}
+		_, err := svc.Deposit(ctx, "barry", decimal.New(10, 0), "wire-1")
+		require.NoError(t, err)
+		transfer, err := svc.SendPayment(ctx, barry, wicky, decimal.New(10, 0), entities.PaymentDetails{})
+		require.NoError(t, err)
+		_, svcErr := svc.RefundTransaction(ctx, transfer.Transaction.ID, decimal.New(20, 0))
+		require.Error(t, svcErr)
+
+		dep.Service.EXPECT().RefundTransaction(gomock.Any(), 42, decimal.New(20, 0)).Return(entities.TransferReceipt{}, svcErr)
+
+		req, err := http.NewRequest(http.MethodPost, dep.TestServer.URL+"/transactions/42/refund", strings.NewReader(`{"refund": {"amount": 20}}`))
+		require.NoError(t, err)
+		req.Header.Set("Authorization", "Bearer s3cret")
+
+		resp, err := client.Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		var actualBody map[string]string
+		assert.NoError(t, json.NewDecoder(resp.Body).Decode(&actualBody))
+
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+		assert.Equal(t, "refunds can't exceed the original transfer amount", actualBody["error"])
+	})
+
+	t.Run("is restricted to privileged callers", func(t *testing.T) {
+		dep, cleanUp := setupServer(t, operatorToken)
+		client := dep.TestServer.Client()
+		defer cleanUp()
+
+		for _, path := range []string{"/transactions/42/reverse", "/transactions/42/refund"} {
+			resp, err := client.Post(dep.TestServer.URL+path, "application/json", strings.NewReader(`{}`))
+			require.NoError(t, err)
+			resp.Body.Close()
+
+			assert.Equal(t, http.StatusUnauthorized, resp.StatusCode, path)
+		}
+	})
+}
+
+func TestCreateQuoteRoute(t *testing.T) {
+	t.Run("renders new quote", func(t *testing.T) {
+		dep, cleanUp := setupServer(t)
+		client := dep.TestServer.Client()
+		defer cleanUp()
+
+		quote := entities.Quote{
+			ID:           3,
+			FromCurrency: entities.PHP,
+			ToCurrency:   entities.USD,
+			Rate:         decimal.New(191, -4),
+			SourceAmount: decimal.New(1000, 0),
+			TargetAmount: decimal.New(1910, -2),
+			CreatedAt:    time.Date(2019, 4, 3, 10, 0, 0, 0, time.UTC),
+			ExpiresAt:    time.Date(2019, 4, 3, 10, 0, 30, 0, time.UTC),
+		}
+		dep.Service.EXPECT().CreateQuote(gomock.Any(), entities.PHP, entities.USD, decimal.New(1000, 0)).Return(quote, nil)
+
+		requestBody := `{"quote": {"from_currency": "PHP", "to_currency": "usd", "amount": 1000}}`
+		resp, err := client.Post(dep.TestServer.URL+"/quotes", "application/json", strings.NewReader(requestBody))
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		var actualBody createQuoteResponse
+		assert.NoError(t, json.NewDecoder(resp.Body).Decode(&actualBody))
+
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, quote.ID, actualBody.Quote.ID)
+		assert.Equal(t, quote.TargetAmount.String(), actualBody.Quote.TargetAmount.String())
+		assert.True(t, quote.ExpiresAt.Equal(actualBody.Quote.ExpiresAt))
+	})
+
+	t.Run("returns 400 on unavailable rate", func(t *testing.T) {
+		dep, cleanUp := setupServer(t)
+		client := dep.TestServer.Client()
+		defer cleanUp()
+
+		rates := fx.NewStaticRateProvider()
+		_, svcErr := banking.NewService(nil, banking.WithRateProvider(rates)).CreateQuote(context.Background(), entities.USD, entities.PHP, decimal.New(1, 0))
+		dep.Service.EXPECT().CreateQuote(gomock.Any(), entities.USD, entities.PHP, decimal.New(1, 0)).Return(entities.Quote{}, svcErr)
+
+		requestBody := `{"quote": {"from_currency": "usd", "to_currency": "php", "amount": 1}}`
+		resp, err := client.Post(dep.TestServer.URL+"/quotes", "application/json", strings.NewReader(requestBody))
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	})
+}
+
+func TestAuthorizationRoutes(t *testing.T) {
+	hold := entities.Hold{
+		ID:           5,
+		Account:      entities.Account{Name: "barry"},
+		Counterparty: entities.Account{Name: "shop"},
+		Amount:       decimal.New(4, 0),
+		Currency:     entities.USD,
+		Status:       entities.PendingHold,
+		CreatedAt:    time.Date(2019, 4, 15, 10, 0, 0, 0, time.UTC),
+		ExpiresAt:    time.Date(2019, 4, 22, 10, 0, 0, 0, time.UTC),
+	}
+
+	t.Run("renders placed authorization", func(t *testing.T) {
+		dep, cleanUp := setupServer(t)
+		client := dep.TestServer.Client()
+		defer cleanUp()
+
+		dep.Service.EXPECT().Authorize(gomock.Any(), entities.Account{Name: "barry"}, entities.Account{Name: "shop"}, decimal.New(4, 0)).Return(hold, nil)
+
+		requestBody := `{"authorization": {"from": "barry", "to": "shop", "amount": 4}}`
+		resp, err := client.Post(dep.TestServer.URL+"/authorizations", "application/json", strings.NewReader(requestBody))
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		var actualBody map[string]interface{}
+		assert.NoError(t, json.NewDecoder(resp.Body).Decode(&actualBody))
+
+		expectedBody := map[string]interface{}{
+			"authorization": map[string]interface{}{
+				"id":         float64(5),
+				"account":    "barry",
+				"to_account": "shop",
+				"amount":     "4",
+				"currency":   "usd",
+				"status":     "pending",
+				"created_at": "2019-04-15T10:00:00Z",
+				"expires_at": "2019-04-22T10:00:00Z",
+			},
+		}
+
+		assert.Equal(t, http.StatusCreated, resp.StatusCode)
+		assert.Equal(t, "/api/v1/authorizations/5", resp.Header.Get("Location"))
+		assert.Equal(t, expectedBody, actualBody)
+	})
+
+	t.Run("renders authorization", func(t *testing.T) {
+		dep, cleanUp := setupServer(t)
+		client := dep.TestServer.Client()
+		defer cleanUp()
+
+		captured := hold
+		captured.Status = entities.CapturedHold
+		captured.TransactionID = 9
+		dep.Service.EXPECT().GetHold(gomock.Any(), 5).Return(captured, nil)
+
+		resp, err := client.Get(dep.TestServer.URL + "/authorizations/5")
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		var actualBody map[string]map[string]interface{}
+		assert.NoError(t, json.NewDecoder(resp.Body).Decode(&actualBody))
+
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, "captured", actualBody["authorization"]["status"])
+		assert.Equal(t, float64(9), actualBody["authorization"]["transaction_id"])
+	})
+
+	t.Run("captures authorization", func(t *testing.T) {
+		cases := []struct {
+			title  string
+			body   string
+			amount decimal.Decimal
+		}{
+			{title: "in full without body", body: "", amount: decimal.Decimal{}},
+			{title: "in part", body: `{"capture": {"amount": 3}}`, amount: decimal.New(3, 0)},
+		}
+
+		for _, tc := range cases {
+			t.Run(tc.title, func(t *testing.T) {
+				dep, cleanUp := setupServer(t)
+				client := dep.TestServer.Client()
+				defer cleanUp()
+
+				transaction := entities.Transaction{ID: 9, Kind: entities.TransferTransaction}
+				dep.Service.EXPECT().CaptureHold(gomock.Any(), 5, tc.amount).Return(entities.TransferReceipt{Transaction: transaction, Payments: []entities.Payment{}}, nil)
+
+				resp, err := client.Post(dep.TestServer.URL+"/authorizations/5/capture", "application/json", strings.NewReader(tc.body))
+				require.NoError(t, err)
+				defer resp.Body.Close()
+
+				var actualBody map[string]map[string]interface{}
+				assert.NoError(t, json.NewDecoder(resp.Body).Decode(&actualBody))
+
+				assert.Equal(t, http.StatusCreated, resp.StatusCode)
+				assert.Equal(t, "/api/v1/transactions/9", resp.Header.Get("Location"))
+				assert.Equal(t, float64(9), actualBody["receipt"]["transaction_id"])
+			})
+		}
+	})
+
+	t.Run("voids authorization", func(t *testing.T) {
+		dep, cleanUp := setupServer(t)
+		client := dep.TestServer.Client()
+		defer cleanUp()
+
+		voided := hold
+		voided.Status = entities.VoidedHold
+		dep.Service.EXPECT().VoidHold(gomock.Any(), 5).Return(voided, nil)
+
+		resp, err := client.Post(dep.TestServer.URL+"/authorizations/5/void", "application/json", nil)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		var actualBody map[string]map[string]interface{}
+		assert.NoError(t, json.NewDecoder(resp.Body).Decode(&actualBody))
+
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, "voided", actualBody["authorization"]["status"])
+	})
+
+	t.Run("returns 400 on malformed capture body", func(t *testing.T) {
+		dep, cleanUp := setupServer(t)
+		client := dep.TestServer.Client()
+		defer cleanUp()
+
+		resp, err := client.Post(dep.TestServer.URL+"/authorizations/5/capture", "application/json", strings.NewReader(`{"capture":`))
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	})
+}
+
+func TestAuthorizationErrors(t *testing.T) {
+	// real service errors are obtained by walking an authorization through its lifecycle
+	svc := banking.NewService(memstorage.NewMemStorage())
+	for _, name := range []string{"barry", "shop"} {
+		_, err := svc.CreateAccount(ctx, name, entities.USD, entities.AccountProfile{})
+		require.NoError(t, err)
+	}
+	_, err := svc.Deposit(ctx, "barry", decimal.New(10, 0), "wire-1")
+	require.NoError(t, err)
+	hold, err := svc.Authorize(ctx, entities.Account{Name: "barry"}, entities.Account{Name: "shop"}, decimal.New(4, 0))
+	require.NoError(t, err)
+	voided, err := svc.Authorize(ctx, entities.Account{Name: "barry"}, entities.Account{Name: "shop"}, decimal.New(1, 0))
+	require.NoError(t, err)
+	_, err = svc.VoidHold(ctx, voided.ID)
+	require.NoError(t, err)
+
+	errorOf := func(_ interface{}, err error) error {
+		return err
+	}
+
+	cases := []struct {
+		title  string
+		err    error
+		status int
+	}{
+		{title: "insufficient funds", err: errorOf(svc.Authorize(ctx, entities.Account{Name: "barry"}, entities.Account{Name: "shop"}, decimal.New(7, 0))), status: http.StatusBadRequest},
+		{title: "capture exceeding hold", err: errorOf(svc.CaptureHold(ctx, hold.ID, decimal.New(5, 0))), status: http.StatusBadRequest},
+		{title: "account with holds", err: errorOf(svc.CloseAccount(ctx, "barry")), status: http.StatusConflict},
+		{title: "missing hold", err: errorOf(svc.VoidHold(ctx, 1000)), status: http.StatusNotFound},
+		{title: "voided hold", err: errorOf(svc.CaptureHold(ctx, voided.ID, decimal.New(0, 0))), status: http.StatusConflict},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.title, func(t *testing.T) {
+			require.Error(t, tc.err)
+
+			dep, cleanUp := setupServer(t)
+			client := dep.TestServer.Client()
+			defer cleanUp()
+
+			dep.Service.EXPECT().CaptureHold(gomock.Any(), 5, gomock.Any()).Return(entities.TransferReceipt{}, tc.err)
+
+			resp, err := client.Post(dep.TestServer.URL+"/authorizations/5/capture", "application/json", nil)
+			require.NoError(t, err)
+			defer resp.Body.Close()
+
+			var actualBody map[string]string
+			assert.NoError(t, json.NewDecoder(resp.Body).Decode(&actualBody))
+
+			assert.Equal(t, tc.status, resp.StatusCode)
+			assert.Equal(t, tc.err.Error(), actualBody["error"])
+		})
+	}
+}
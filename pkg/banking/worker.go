@@ -0,0 +1,66 @@
+package banking
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-kit/kit/log"
+)
+
+// HoldExpirer is an abstraction of a service able to release expired holds.
+type HoldExpirer interface {
+	ExpireHolds(ctx context.Context) (int, error)
+}
+
+// ExpireHoldsPeriodically releases expired holds every interval until the context is done.
+// Failures of the release are logged.
+func ExpireHoldsPeriodically(ctx context.Context, svc HoldExpirer, interval time.Duration, l log.Logger) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			released, err := svc.ExpireHolds(ctx)
+			if err != nil {
+				l.Log("func", "banking.ExpireHoldsPeriodically", "err", err)
+				continue
+			}
+
+			if released > 0 {
+				l.Log("func", "banking.ExpireHoldsPeriodically", "msg", "expired holds released", "count", released)
+			}
+		}
+	}
+}
+
+// ScheduledPaymentsRunner is an abstraction of a service able to run due scheduled payments.
+type ScheduledPaymentsRunner interface {
+	RunScheduledPayments(ctx context.Context) (int, error)
+}
+
+// RunScheduledPaymentsPeriodically runs due scheduled payments every interval until the context is done.
+// Failures of the runs are logged.
+func RunScheduledPaymentsPeriodically(ctx context.Context, svc ScheduledPaymentsRunner, interval time.Duration, l log.Logger) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			runs, err := svc.RunScheduledPayments(ctx)
+			if err != nil {
+				l.Log("func", "banking.RunScheduledPaymentsPeriodically", "err", err)
+				continue
+			}
+
+			if runs > 0 {
+				l.Log("func", "banking.RunScheduledPaymentsPeriodically", "msg", "scheduled payments run", "count", runs)
+			}
+		}
+	}
+}
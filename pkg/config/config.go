@@ -5,16 +5,62 @@ package config
 import "github.com/spf13/viper"
 
 type configDefaults struct {
-	Listen string
-	AppEnv string
-	DB     string
+	Listen             string
+	AppEnv             string
+	Storage            string
+	DB                 string
+	Currencies         string
+	FXRates            string
+	FeeSchedule        string
+	QuoteTTL           string
+	HoldTTL            string
+	OperatorToken      string
+	AdminToken         string
+	AuthRequired       bool
+	APIKeySealingKey   string
+	JWTJWKSFile        string
+	JWTSecret          string
+	JWTPublicKeyFile   string
+	JWTIssuer          string
+	JWTAudience        string
+	JWTOwnerClaim      string
+	ReconcileInterval  string
+	HoldExpiryInterval string
+	SchedulerInterval  string
+	SchedulerID        string
+	ScheduleLeaseTTL   string
+	ScheduleRetry      string
+	ScheduleMaxRetries int
 }
 
 func getDefaults() *configDefaults {
 	return &configDefaults{
-		Listen: ":80",
-		AppEnv: "dev",
-		DB:     "postgres://localhost/coinsph?sslmode=disable",
+		Listen:             ":80",
+		AppEnv:             "dev",
+		Storage:            "postgres",
+		DB:                 "postgres://localhost/coinsph?sslmode=disable",
+		Currencies:         "usd:2,eur:2,gbp:2,jpy:0,php:2,btc:8,eth:18",
+		FXRates:            "",
+		FeeSchedule:        "",
+		QuoteTTL:           "30s",
+		HoldTTL:            "168h",
+		OperatorToken:      "",
+		AdminToken:         "",
+		AuthRequired:       true,
+		APIKeySealingKey:   "",
+		JWTJWKSFile:        "",
+		JWTSecret:          "",
+		JWTPublicKeyFile:   "",
+		JWTIssuer:          "",
+		JWTAudience:        "",
+		JWTOwnerClaim:      "owner_id",
+		ReconcileInterval:  "1h",
+		HoldExpiryInterval: "1m",
+		SchedulerInterval:  "1m",
+		SchedulerID:        "",
+		ScheduleLeaseTTL:   "1m",
+		ScheduleRetry:      "1h",
+		ScheduleMaxRetries: 3,
 	}
 }
 
@@ -27,7 +73,30 @@ func NewConfig() *viper.Viper {
 
 	cfg.SetDefault("LISTEN", defaults.Listen)
 	cfg.SetDefault("APP_ENV", defaults.AppEnv)
+	cfg.SetDefault("STORAGE", defaults.Storage)
 	cfg.SetDefault("DB", defaults.DB)
+	cfg.SetDefault("CURRENCIES", defaults.Currencies)
+	cfg.SetDefault("FX_RATES", defaults.FXRates)
+	cfg.SetDefault("FEE_SCHEDULE", defaults.FeeSchedule)
+	cfg.SetDefault("QUOTE_TTL", defaults.QuoteTTL)
+	cfg.SetDefault("HOLD_TTL", defaults.HoldTTL)
+	cfg.SetDefault("OPERATOR_TOKEN", defaults.OperatorToken)
+	cfg.SetDefault("ADMIN_TOKEN", defaults.AdminToken)
+	cfg.SetDefault("AUTH_REQUIRED", defaults.AuthRequired)
+	cfg.SetDefault("API_KEY_SEALING_KEY", defaults.APIKeySealingKey)
+	cfg.SetDefault("JWT_JWKS_FILE", defaults.JWTJWKSFile)
+	cfg.SetDefault("JWT_HS256_SECRET", defaults.JWTSecret)
+	cfg.SetDefault("JWT_PUBLIC_KEY_FILE", defaults.JWTPublicKeyFile)
+	cfg.SetDefault("JWT_ISSUER", defaults.JWTIssuer)
+	cfg.SetDefault("JWT_AUDIENCE", defaults.JWTAudience)
+	cfg.SetDefault("JWT_OWNER_CLAIM", defaults.JWTOwnerClaim)
+	cfg.SetDefault("RECONCILE_INTERVAL", defaults.ReconcileInterval)
+	cfg.SetDefault("HOLD_EXPIRY_INTERVAL", defaults.HoldExpiryInterval)
+	cfg.SetDefault("SCHEDULER_INTERVAL", defaults.SchedulerInterval)
+	cfg.SetDefault("SCHEDULER_ID", defaults.SchedulerID)
+	cfg.SetDefault("SCHEDULE_LEASE_TTL", defaults.ScheduleLeaseTTL)
+	cfg.SetDefault("SCHEDULE_RETRY_INTERVAL", defaults.ScheduleRetry)
+	cfg.SetDefault("SCHEDULE_MAX_RETRIES", defaults.ScheduleMaxRetries)
 	cfg.SetDefault("SHUTDOWN_TIMEOUT", "2s")
 	cfg.AutomaticEnv()
 
@@ -0,0 +1,168 @@
+// Package cron provides schedules defined by cron expressions,
+// which tell at what moments recurring jobs should run.
+package cron
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// searchYears limits how far Next looks for a matching moment
+const searchYears = 5
+
+type field struct {
+	name string
+	min  int
+	max  int
+}
+
+var fields = []field{
+	{name: "minute", min: 0, max: 59},
+	{name: "hour", min: 0, max: 23},
+	{name: "day of month", min: 1, max: 31},
+	{name: "month", min: 1, max: 12},
+	{name: "day of week", min: 0, max: 7},
+}
+
+// Schedule is a parsed cron expression. Moments are matched in UTC with a minute precision.
+type Schedule struct {
+	minutes     map[int]bool
+	hours       map[int]bool
+	daysOfMonth map[int]bool
+	months      map[int]bool
+	daysOfWeek  map[int]bool
+
+	// days are matched by either of the fields when both of them are restricted,
+	// just like classic cron does
+	anyDayOfMonth bool
+	anyDayOfWeek  bool
+}
+
+// Parse parses a standard five fields cron expression: minute, hour, day of month,
+// month and day of week (0 or 7 is Sunday). Each field is either `*`, a value,
+// a range `a-b` or a comma separated list of them, optionally followed by a step `/n`,
+// e.g. `30 9 * * 1-5` or `0 */6 1,15 * *`.
+func Parse(spec string) (*Schedule, error) {
+	parts := strings.Fields(spec)
+	if len(parts) != len(fields) {
+		return nil, errors.Errorf("cron expression %q should consist of %d fields", spec, len(fields))
+	}
+
+	sets := make([]map[int]bool, len(fields))
+	for index, part := range parts {
+		set, err := parseField(part, fields[index])
+		if err != nil {
+			return nil, errors.Wrapf(err, "cron expression %q", spec)
+		}
+		sets[index] = set
+	}
+
+	// Sunday may be set both as 0 and 7
+	if sets[4][7] {
+		sets[4][0] = true
+	}
+
+	return &Schedule{
+		minutes:       sets[0],
+		hours:         sets[1],
+		daysOfMonth:   sets[2],
+		months:        sets[3],
+		daysOfWeek:    sets[4],
+		anyDayOfMonth: strings.HasPrefix(parts[2], "*"),
+		anyDayOfWeek:  strings.HasPrefix(parts[4], "*"),
+	}, nil
+}
+
+// parseField returns a set of values matched by a single field of cron expression
+func parseField(part string, f field) (map[int]bool, error) {
+	set := make(map[int]bool)
+	for _, item := range strings.Split(part, ",") {
+		step := 1
+		if slash := strings.Index(item, "/"); slash >= 0 {
+			value, err := strconv.Atoi(item[slash+1:])
+			if err != nil || value <= 0 {
+				return nil, errors.Errorf("invalid step of %s field in %q", f.name, item)
+			}
+			step, item = value, item[:slash]
+		}
+
+		from, to := f.min, f.max
+		if item != "*" {
+			bounds := strings.SplitN(item, "-", 2)
+			value, err := strconv.Atoi(bounds[0])
+			if err != nil {
+				return nil, errors.Errorf("invalid %s value %q", f.name, item)
+			}
+			from, to = value, value
+
+			if len(bounds) == 2 {
+				if to, err = strconv.Atoi(bounds[1]); err != nil {
+					return nil, errors.Errorf("invalid %s value %q", f.name, item)
+				}
+			} else if step > 1 {
+				// `a/n` stands for every n-th value starting from a
+				to = f.max
+			}
+		}
+
+		if from < f.min || to > f.max || from > to {
+			return nil, errors.Errorf("%s value %q is out of range %d-%d", f.name, item, f.min, f.max)
+		}
+
+		for value := from; value <= to; value += step {
+			set[value] = true
+		}
+	}
+	return set, nil
+}
+
+// Next returns the earliest moment matching the schedule which is strictly after t.
+// Zero time is returned if nothing matches within a few years (e.g. for February 30).
+func (s *Schedule) Next(t time.Time) time.Time {
+	t = t.UTC().Truncate(time.Minute).Add(time.Minute)
+	limit := t.AddDate(searchYears, 0, 0)
+
+	for t.Before(limit) {
+		if !s.months[int(t.Month())] {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, time.UTC)
+			continue
+		}
+
+		if !s.matchesDay(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, time.UTC)
+			continue
+		}
+
+		if !s.hours[t.Hour()] {
+			t = t.Truncate(time.Hour).Add(time.Hour)
+			continue
+		}
+
+		if !s.minutes[t.Minute()] {
+			t = t.Add(time.Minute)
+			continue
+		}
+
+		return t
+	}
+
+	return time.Time{}
+}
+
+// matchesDay checks whether the day of t is matched by day of month and day of week fields
+func (s *Schedule) matchesDay(t time.Time) bool {
+	dayOfMonth, dayOfWeek := s.daysOfMonth[t.Day()], s.daysOfWeek[int(t.Weekday())]
+	switch {
+	case s.anyDayOfMonth && s.anyDayOfWeek:
+		return true
+	case s.anyDayOfMonth:
+		return dayOfWeek
+	case s.anyDayOfWeek:
+		return dayOfMonth
+	default:
+		return dayOfMonth || dayOfWeek
+	}
+}
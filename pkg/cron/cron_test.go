@@ -0,0 +1,46 @@
+package cron_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/twonegatives/coinsph_challenge/pkg/cron"
+)
+
+func TestScheduleNext(t *testing.T) {
+	// Wednesday
+	from := time.Date(2019, 4, 17, 10, 30, 0, 0, time.UTC)
+
+	cases := []struct {
+		spec string
+		next time.Time
+	}{
+		{spec: "* * * * *", next: time.Date(2019, 4, 17, 10, 31, 0, 0, time.UTC)},
+		{spec: "30 10 * * *", next: time.Date(2019, 4, 18, 10, 30, 0, 0, time.UTC)},
+		{spec: "*/20 * * * *", next: time.Date(2019, 4, 17, 10, 40, 0, 0, time.UTC)},
+		{spec: "0 9 * * 1-5", next: time.Date(2019, 4, 18, 9, 0, 0, 0, time.UTC)},
+		{spec: "0 9 * * 7", next: time.Date(2019, 4, 21, 9, 0, 0, 0, time.UTC)},
+		{spec: "0 0 1,15 * *", next: time.Date(2019, 5, 1, 0, 0, 0, 0, time.UTC)},
+		{spec: "0 0 31 * *", next: time.Date(2019, 5, 31, 0, 0, 0, 0, time.UTC)},
+		{spec: "0 0 1 1 *", next: time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{spec: "0 0 29 2 *", next: time.Date(2020, 2, 29, 0, 0, 0, 0, time.UTC)},
+		{spec: "0 12 13 * 5", next: time.Date(2019, 4, 19, 12, 0, 0, 0, time.UTC)},
+		{spec: "0 0 30 2 *", next: time.Time{}},
+	}
+
+	for _, tc := range cases {
+		schedule, err := cron.Parse(tc.spec)
+		require.NoError(t, err, tc.spec)
+		assert.Equal(t, tc.next, schedule.Next(from), tc.spec)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	specs := []string{"", "* * * *", "* * * * * *", "60 * * * *", "* 24 * * *", "* * 0 * *", "* * * 13 *", "* * * * 8", "5-1 * * * *", "*/0 * * * *", "a * * * *"}
+	for _, spec := range specs {
+		_, err := cron.Parse(spec)
+		assert.Error(t, err, spec)
+	}
+}
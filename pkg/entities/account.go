@@ -2,19 +2,210 @@
 // packages of the application.
 package entities
 
-import "github.com/shopspring/decimal"
+import (
+	"encoding/json"
+	"strings"
+	"time"
 
-// Account represents a user account in the system.
-type Account struct {
-	ID       int             `json:"-"`
-	Name     string          `json:"name"`
-	Balance  decimal.Decimal `json:"balance"`
-	Currency Currency        `json:"currency"`
+	"github.com/shopspring/decimal"
+)
+
+const (
+	// SystemAccountPrefix is a name (and a name prefix) of accounts which are used
+	// to move money over the system borders. The plain "SYSTEM" account holds USD,
+	// accounts for other currencies are named after them, e.g. "SYSTEM_EUR".
+	SystemAccountPrefix = "SYSTEM"
+
+	// FXAccountPrefix is a name prefix of liquidity accounts which take part in
+	// foreign exchange transfers, e.g. "FX_USD". There is one per each currency.
+	FXAccountPrefix = "FX_"
+
+	// FeeAccountPrefix is a name prefix of accounts collecting transfer fees, e.g. "FEE_USD".
+	// There is one per each currency.
+	FeeAccountPrefix = "FEE_"
+)
+
+// AccountType tells whom an account belongs to and which rules its balance follows.
+type AccountType string
+
+const (
+	// UserAccount belongs to a wallet user. Its balance can't go below zero.
+	UserAccount AccountType = "user"
+
+	// SystemAccount moves money over the system borders (deposits and withdrawals).
+	// Its balance goes below zero as users bring money into the wallet.
+	SystemAccount AccountType = "system"
+
+	// SettlementAccount is a house account which settles transfers between
+	// other accounts, e.g. FX liquidity one. It may go below zero.
+	SettlementAccount AccountType = "settlement"
+
+	// FeeAccount collects fees charged by the wallet. Its balance can't go below zero.
+	FeeAccount AccountType = "fee"
+
+	// SuspenseAccount temporarily holds money which can't be booked to its final
+	// destination yet. It may go below zero.
+	SuspenseAccount AccountType = "suspense"
+)
+
+// IsValid checks whether the type is one of the known ones.
+func (t AccountType) IsValid() bool {
+	switch t {
+	case UserAccount, SystemAccount, SettlementAccount, FeeAccount, SuspenseAccount:
+		return true
+	}
+	return false
 }
 
-func (a Account) MayGoBelowZero() bool {
-	if a.Name == "SYSTEM" {
+// IsHouse checks whether accounts of this type are owned by the wallet itself
+// rather than by its users.
+func (t AccountType) IsHouse() bool {
+	switch t {
+	case SystemAccount, SettlementAccount, FeeAccount, SuspenseAccount:
 		return true
 	}
 	return false
 }
+
+// MayGoBelowZero checks whether balance of accounts of this type may be negative.
+// Keep in sync with valid_balance constraint of accounts table.
+func (t AccountType) MayGoBelowZero() bool {
+	switch t {
+	case SystemAccount, SettlementAccount, SuspenseAccount:
+		return true
+	}
+	return false
+}
+
+// AccountStatus tells whether an account may take part in transfers.
+type AccountStatus string
+
+const (
+	// ActiveAccount sends and receives money freely.
+	ActiveAccount AccountStatus = "active"
+
+	// FrozenAccount can neither send nor receive money until it gets unfrozen.
+	FrozenAccount AccountStatus = "frozen"
+
+	// ClosedAccount is frozen for good. Only accounts with zero balance may be closed.
+	ClosedAccount AccountStatus = "closed"
+)
+
+// IsValid checks whether the status is one of the known ones.
+func (s AccountStatus) IsValid() bool {
+	switch s {
+	case ActiveAccount, FrozenAccount, ClosedAccount:
+		return true
+	}
+	return false
+}
+
+// Account represents an account in the system, either a user or a house one.
+// Balance is the ledger one, i.e. the sum of booked payments. Held is the amount
+// reserved by pending holds, which can't be spent until they are captured or released.
+// CreditLimit is the amount available balance may go below zero by (unless account
+// type lets it go below zero without bounds). OwnerID, DisplayName and Labels
+// describe the account for its clients and take no part in bookkeeping.
+type Account struct {
+	ID          int             `json:"-"`
+	Name        string          `json:"name"`
+	Type        AccountType     `json:"type"`
+	Status      AccountStatus   `json:"status"`
+	Balance     decimal.Decimal `json:"balance"`
+	Held        decimal.Decimal `json:"held"`
+	CreditLimit decimal.Decimal `json:"credit_limit"`
+	Currency    Currency        `json:"currency"`
+	OwnerID     string          `json:"owner_id,omitempty"`
+	DisplayName string          `json:"display_name,omitempty"`
+	Labels      []string        `json:"labels,omitempty"`
+	CreatedAt   time.Time       `json:"created_at"`
+}
+
+// AccountProfile holds descriptive attributes of an account: id of the customer
+// owning it in an external system, a human readable name and free-form labels.
+type AccountProfile struct {
+	OwnerID     string
+	DisplayName string
+	Labels      []string
+}
+
+// AccountUpdate lists profile attributes to change. Nil attributes are left as they are,
+// so that a blank display name or an empty list of labels clears them.
+type AccountUpdate struct {
+	OwnerID     *string
+	DisplayName *string
+	Labels      []string
+}
+
+// Profile returns descriptive attributes of the account.
+func (a Account) Profile() AccountProfile {
+	return AccountProfile{OwnerID: a.OwnerID, DisplayName: a.DisplayName, Labels: a.Labels}
+}
+
+// WithProfile returns the account with descriptive attributes replaced by the profile ones.
+func (a Account) WithProfile(profile AccountProfile) Account {
+	a.OwnerID, a.DisplayName, a.Labels = profile.OwnerID, profile.DisplayName, profile.Labels
+	return a
+}
+
+// MarshalJSON renders the account along with its available balance.
+func (a Account) MarshalJSON() ([]byte, error) {
+	type account Account
+	return json.Marshal(struct {
+		account
+		AvailableBalance decimal.Decimal `json:"available_balance"`
+	}{account(a), a.AvailableBalance()})
+}
+
+// AvailableBalance is the part of ledger balance which is not reserved by holds.
+func (a Account) AvailableBalance() decimal.Decimal {
+	return a.Balance.Sub(a.Held)
+}
+
+// MayGoBelowZero checks whether the account balance may be negative without bounds,
+// which depends on its type.
+func (a Account) MayGoBelowZero() bool {
+	return a.Type.MayGoBelowZero()
+}
+
+// IsBalanceValid checks whether the account available balance stays within its credit limit.
+// Keep in sync with valid_balance constraint of accounts table.
+func (a Account) IsBalanceValid() bool {
+	return a.MayGoBelowZero() || a.AvailableBalance().GreaterThanOrEqual(a.CreditLimit.Neg())
+}
+
+// CanSpend checks whether the amount may be debited from the account (or held on it)
+// without exceeding its credit limit.
+func (a Account) CanSpend(amount decimal.Decimal) bool {
+	a.Balance = a.Balance.Sub(amount)
+	return a.IsBalanceValid()
+}
+
+// SystemAccountName returns a name of the SYSTEM account holding given currency.
+func SystemAccountName(currency Currency) string {
+	if currency == USD {
+		return SystemAccountPrefix
+	}
+	return SystemAccountPrefix + "_" + strings.ToUpper(string(currency))
+}
+
+// IsSystemAccountName checks whether the name belongs to one of SYSTEM accounts.
+func IsSystemAccountName(name string) bool {
+	return name == SystemAccountPrefix || strings.HasPrefix(name, SystemAccountPrefix+"_")
+}
+
+// FXAccountName returns a name of the FX liquidity account holding given currency.
+func FXAccountName(currency Currency) string {
+	return FXAccountPrefix + strings.ToUpper(string(currency))
+}
+
+// FeeAccountName returns a name of the account collecting fees in given currency.
+func FeeAccountName(currency Currency) string {
+	return FeeAccountPrefix + strings.ToUpper(string(currency))
+}
+
+// IsReservedAccountName checks whether the name belongs to one of house
+// (SYSTEM, FX or FEE) accounts which can't be taken by users.
+func IsReservedAccountName(name string) bool {
+	return IsSystemAccountName(name) || strings.HasPrefix(name, FXAccountPrefix) || strings.HasPrefix(name, FeeAccountPrefix)
+}
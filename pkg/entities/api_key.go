@@ -0,0 +1,43 @@
+package entities
+
+import "time"
+
+// APIKey authenticates a caller of the API on behalf of its owner.
+// KeyID is a public part of the key, the secret itself is never stored in plain form:
+// SecretHash keeps a hex encoded SHA-256 hash of it to check bearer tokens against,
+// while SealedSecret keeps it encrypted with a server side key to verify request signatures.
+// Keys without SealedSecret can't sign requests. Revoked keys are kept
+// for the record but don't authenticate anyone.
+type APIKey struct {
+	ID           int
+	KeyID        string
+	Name         string
+	SecretHash   string
+	SealedSecret string
+	Role         Role
+	OwnerID      string
+	CreatedAt    time.Time
+	RevokedAt    time.Time
+}
+
+// IsRevoked tells whether the key was revoked.
+func (k APIKey) IsRevoked() bool {
+	return !k.RevokedAt.IsZero()
+}
+
+// Principal returns the caller authenticated by the key.
+func (k APIKey) Principal() Principal {
+	return Principal{Subject: k.KeyID, Role: k.Role, OwnerID: k.OwnerID}
+}
+
+// IssuedAPIKey is a freshly created key along with its secret,
+// which is shown to the client once and can't be restored later.
+type IssuedAPIKey struct {
+	APIKey
+	Secret string
+}
+
+// Token returns the value the client presents as a bearer token.
+func (k IssuedAPIKey) Token() string {
+	return k.KeyID + "." + k.Secret
+}
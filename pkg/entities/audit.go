@@ -0,0 +1,113 @@
+package entities
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"time"
+)
+
+// AuditOutcome tells how an audited call ended.
+type AuditOutcome string
+
+const (
+	// SucceededOutcome means the changes of the call were committed.
+	SucceededOutcome AuditOutcome = "succeeded"
+
+	// FailedOutcome means the call returned an error and changed nothing.
+	FailedOutcome AuditOutcome = "failed"
+)
+
+// AuditEntry records a single state changing call: who made it (Subject and Role
+// of the principal, blank for anonymous callers), what was requested (Action and
+// a hex encoded SHA-256 hash of its payload) and how it ended.
+// CorrelationID ties the entry to the request it was made by.
+// Entries form a chain: ID is a position in it starting from 1, PrevHash is
+// the hash of the previous entry (blank for the first one) and Hash covers
+// PrevHash along with all the other attributes, so that changed, removed or
+// reordered entries break the chain.
+type AuditEntry struct {
+	ID            int
+	Subject       string
+	Role          Role
+	Action        string
+	PayloadHash   string
+	Outcome       AuditOutcome
+	Error         string
+	CorrelationID string
+	CreatedAt     time.Time
+	PrevHash      string
+	Hash          string
+}
+
+// ChainHash returns a hex encoded SHA-256 hash of the entry attributes other than Hash.
+func (e AuditEntry) ChainHash() string {
+	// an array keeps attributes apart whatever characters they hold
+	encoded, _ := json.Marshal([]interface{}{
+		e.ID,
+		e.PrevHash,
+		e.CreatedAt.UTC().Format(time.RFC3339Nano),
+		e.Subject,
+		e.Role,
+		e.Action,
+		e.PayloadHash,
+		e.Outcome,
+		e.Error,
+		e.CorrelationID,
+	})
+	hash := sha256.Sum256(encoded)
+	return hex.EncodeToString(hash[:])
+}
+
+// Follows checks whether the entry is the next one after 'previous' in the chain
+// and its hash matches its attributes. Zero 'previous' stands for the chain start.
+func (e AuditEntry) Follows(previous AuditEntry) bool {
+	return e.ID == previous.ID+1 && e.PrevHash == previous.Hash && e.Hash == e.ChainHash()
+}
+
+// AuditFilter narrows down a list of audit entries. Zero values of the fields
+// mean there is no restriction. Entries are ordered by their IDs.
+type AuditFilter struct {
+	Subject       string
+	Action        string
+	CorrelationID string
+	Outcome       AuditOutcome
+	FromDate      time.Time // inclusive
+	ToDate        time.Time // exclusive
+	Limit         int
+	After         *AuditCursor
+}
+
+// AuditCursor points to the last entry of a page.
+// The next page starts right after it.
+type AuditCursor struct {
+	ID int `json:"id"`
+}
+
+// Encode represents the cursor as an opaque URL-safe string.
+func (c AuditCursor) Encode() string {
+	return encodeCursor(c)
+}
+
+// DecodeAuditCursor restores a cursor previously returned by Encode.
+func DecodeAuditCursor(encoded string) (AuditCursor, error) {
+	var cursor AuditCursor
+	err := decodeCursor(encoded, &cursor)
+	return cursor, err
+}
+
+// AuditPage is a single page of audit entries along with
+// an encoded cursor of the next page (blank on the last page).
+type AuditPage struct {
+	Entries    []AuditEntry
+	NextCursor string
+}
+
+// AuditVerification is an outcome of the audit chain check. BrokenAt is
+// the ID of the first entry which does not follow the previous one.
+type AuditVerification struct {
+	EntriesChecked int    `json:"entries_checked"`
+	Consistent     bool   `json:"consistent"`
+	BrokenAt       int    `json:"broken_at,omitempty"`
+	LastHash       string `json:"last_hash"`
+}
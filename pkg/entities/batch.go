@@ -0,0 +1,52 @@
+package entities
+
+import "github.com/shopspring/decimal"
+
+// BatchMode tells how a batch of payments deals with failures of its items.
+type BatchMode string
+
+const (
+	// AtomicBatch books either all of its payments or none of them.
+	AtomicBatch BatchMode = "atomic"
+
+	// BestEffortBatch books every payment on its own, failures of some
+	// payments do not prevent others from being booked.
+	BestEffortBatch BatchMode = "best_effort"
+)
+
+// IsValid checks whether the mode is one of the known ones.
+func (m BatchMode) IsValid() bool {
+	return m == AtomicBatch || m == BestEffortBatch
+}
+
+// PaymentOrder is a single transfer requested within a batch.
+type PaymentOrder struct {
+	From    Account
+	To      Account
+	Amount  decimal.Decimal
+	Details PaymentDetails
+}
+
+// BatchItem is an outcome of a single payment order of a batch.
+// Receipt is set for booked payments, Err for failed ones.
+type BatchItem struct {
+	Receipt TransferReceipt
+	Err     error
+}
+
+// BatchReceipt lists outcomes of payment orders of a batch in order of their submission.
+type BatchReceipt struct {
+	Mode  BatchMode
+	Items []BatchItem
+}
+
+// Failed returns the number of payment orders which were not booked.
+func (r BatchReceipt) Failed() int {
+	failed := 0
+	for _, item := range r.Items {
+		if item.Err != nil {
+			failed++
+		}
+	}
+	return failed
+}
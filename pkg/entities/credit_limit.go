@@ -0,0 +1,18 @@
+package entities
+
+import (
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// CreditLimitChange is an audit record of a single change of account credit limit.
+type CreditLimitChange struct {
+	ID            int             `json:"-"`
+	AccountID     int             `json:"-"`
+	PreviousLimit decimal.Decimal `json:"previous_limit"`
+	NewLimit      decimal.Decimal `json:"new_limit"`
+	ChangedBy     string          `json:"changed_by"`
+	Reason        string          `json:"reason,omitempty"`
+	CreatedAt     time.Time       `json:"created_at"`
+}
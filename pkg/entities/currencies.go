@@ -1,6 +1,114 @@
 package entities
 
+import (
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/shopspring/decimal"
+)
+
 // Currency is a string representation of real-word currency.
+// Fiat currencies are identified by lowercased ISO 4217 codes,
+// crypto currencies by their commonly used tickers.
 type Currency string
 
-const USD Currency = "usd"
+const (
+	USD Currency = "usd"
+	EUR Currency = "eur"
+	GBP Currency = "gbp"
+	JPY Currency = "jpy"
+	PHP Currency = "php"
+	BTC Currency = "btc"
+	ETH Currency = "eth"
+)
+
+// CurrencyInfo describes how amounts of a particular currency are handled.
+type CurrencyInfo struct {
+	Code Currency
+	// Precision is a maximum number of digits allowed after the decimal point
+	Precision int32
+}
+
+// IsValidAmount checks that amount does not exceed the currency precision.
+func (c CurrencyInfo) IsValidAmount(amount decimal.Decimal) bool {
+	return amount.Equal(amount.Truncate(c.Precision))
+}
+
+// CurrencyRegistry is a set of currencies accounts may be opened in.
+type CurrencyRegistry struct {
+	currencies map[Currency]CurrencyInfo
+}
+
+// NewCurrencyRegistry returns a registry containing the given currencies.
+func NewCurrencyRegistry(currencies ...CurrencyInfo) *CurrencyRegistry {
+	registry := &CurrencyRegistry{currencies: make(map[Currency]CurrencyInfo, len(currencies))}
+	for _, currency := range currencies {
+		registry.currencies[currency.Code] = currency
+	}
+	return registry
+}
+
+// DefaultCurrencyRegistry returns a registry of currencies
+// supported by the system out of the box.
+func DefaultCurrencyRegistry() *CurrencyRegistry {
+	return NewCurrencyRegistry(
+		CurrencyInfo{Code: USD, Precision: 2},
+		CurrencyInfo{Code: EUR, Precision: 2},
+		CurrencyInfo{Code: GBP, Precision: 2},
+		CurrencyInfo{Code: JPY, Precision: 0},
+		CurrencyInfo{Code: PHP, Precision: 2},
+		CurrencyInfo{Code: BTC, Precision: 8},
+		CurrencyInfo{Code: ETH, Precision: 18},
+	)
+}
+
+// ParseCurrencyRegistry builds a registry out of comma separated
+// list of "code:precision" pairs, e.g. "usd:2,jpy:0,btc:8".
+func ParseCurrencyRegistry(spec string) (*CurrencyRegistry, error) {
+	var currencies []CurrencyInfo
+	for _, item := range strings.Split(spec, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+
+		parts := strings.Split(item, ":")
+		if len(parts) != 2 || parts[0] == "" {
+			return nil, errors.Errorf("invalid currency definition %q, expected code:precision", item)
+		}
+
+		precision, err := strconv.ParseInt(parts[1], 10, 32)
+		if err != nil || precision < 0 {
+			return nil, errors.Errorf("invalid precision of currency %q", parts[0])
+		}
+
+		currencies = append(currencies, CurrencyInfo{
+			Code:      Currency(strings.ToLower(parts[0])),
+			Precision: int32(precision),
+		})
+	}
+
+	if len(currencies) == 0 {
+		return nil, errors.New("at least one currency should be defined")
+	}
+
+	return NewCurrencyRegistry(currencies...), nil
+}
+
+// Lookup returns currency details if the currency is present in registry.
+func (r *CurrencyRegistry) Lookup(code Currency) (CurrencyInfo, bool) {
+	currency, ok := r.currencies[code]
+	return currency, ok
+}
+
+// Currencies returns all the registered currencies ordered by code.
+func (r *CurrencyRegistry) Currencies() []CurrencyInfo {
+	result := make([]CurrencyInfo, 0, len(r.currencies))
+	for _, currency := range r.currencies {
+		result = append(result, currency)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Code < result[j].Code })
+	return result
+}
@@ -0,0 +1,115 @@
+package entities_test
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/twonegatives/coinsph_challenge/pkg/entities"
+)
+
+func TestParseCurrencyRegistry(t *testing.T) {
+	t.Run("parses currencies with precision", func(t *testing.T) {
+		registry, err := entities.ParseCurrencyRegistry("USD:2, jpy:0,btc:8")
+		require.NoError(t, err)
+
+		expected := []entities.CurrencyInfo{
+			{Code: entities.BTC, Precision: 8},
+			{Code: entities.JPY, Precision: 0},
+			{Code: entities.USD, Precision: 2},
+		}
+		assert.Equal(t, expected, registry.Currencies())
+	})
+
+	t.Run("fails on malformed definitions", func(t *testing.T) {
+		for _, spec := range []string{"", "usd", "usd:two", "usd:-1", ":2"} {
+			_, err := entities.ParseCurrencyRegistry(spec)
+			assert.Error(t, err, spec)
+		}
+	})
+}
+
+func TestCurrencyInfoIsValidAmount(t *testing.T) {
+	usd := entities.CurrencyInfo{Code: entities.USD, Precision: 2}
+
+	assert.True(t, usd.IsValidAmount(decimal.New(1426, -2)))
+	assert.True(t, usd.IsValidAmount(decimal.New(14260, -3)))
+	assert.False(t, usd.IsValidAmount(decimal.New(14261, -3)))
+}
+
+func TestSystemAccountName(t *testing.T) {
+	assert.Equal(t, "SYSTEM", entities.SystemAccountName(entities.USD))
+	assert.Equal(t, "SYSTEM_PHP", entities.SystemAccountName(entities.PHP))
+	assert.True(t, entities.IsSystemAccountName("SYSTEM_PHP"))
+	assert.False(t, entities.IsSystemAccountName("SYSTEMATIC"))
+}
+
+func TestFeeAccountName(t *testing.T) {
+	assert.Equal(t, "FEE_PHP", entities.FeeAccountName(entities.PHP))
+	assert.True(t, entities.IsReservedAccountName("FEE_PHP"))
+	assert.False(t, entities.IsReservedAccountName("FEEDBACK"))
+}
+
+func TestAccountTypes(t *testing.T) {
+	assert.True(t, entities.Account{Name: "SYSTEM_PHP", Type: entities.SystemAccount}.MayGoBelowZero())
+	assert.True(t, entities.Account{Name: "FX_PHP", Type: entities.SettlementAccount}.MayGoBelowZero())
+	assert.True(t, entities.Account{Name: "UNMATCHED", Type: entities.SuspenseAccount}.MayGoBelowZero())
+	assert.False(t, entities.Account{Name: "FEES", Type: entities.FeeAccount}.MayGoBelowZero())
+	assert.False(t, entities.Account{Name: "SYSTEM", Type: entities.UserAccount}.MayGoBelowZero())
+
+	assert.True(t, entities.FeeAccount.IsHouse())
+	assert.False(t, entities.UserAccount.IsHouse())
+	assert.False(t, entities.AccountType("").IsHouse())
+	assert.False(t, entities.AccountType("vip").IsValid())
+}
+
+func TestAccountCreditLimit(t *testing.T) {
+	account := entities.Account{Type: entities.UserAccount, Balance: decimal.New(10, 0), CreditLimit: decimal.New(5, 0)}
+	assert.True(t, account.CanSpend(decimal.New(15, 0)))
+	assert.False(t, account.CanSpend(decimal.New(1501, -2)))
+
+	account.Balance = decimal.New(-5, 0)
+	assert.True(t, account.IsBalanceValid())
+
+	account.Balance = decimal.New(-6, 0)
+	assert.False(t, account.IsBalanceValid())
+
+	account.Type = entities.SystemAccount
+	assert.True(t, account.IsBalanceValid())
+	assert.True(t, account.CanSpend(decimal.New(100, 0)))
+}
+
+func TestAccountStatuses(t *testing.T) {
+	for _, status := range []entities.AccountStatus{entities.ActiveAccount, entities.FrozenAccount, entities.ClosedAccount} {
+		assert.True(t, status.IsValid(), status)
+	}
+	assert.False(t, entities.AccountStatus("").IsValid())
+	assert.False(t, entities.AccountStatus("dormant").IsValid())
+}
+
+func TestAccountHeld(t *testing.T) {
+	account := entities.Account{Type: entities.UserAccount, Balance: decimal.New(10, 0), Held: decimal.New(4, 0), CreditLimit: decimal.New(5, 0)}
+	assert.Equal(t, "6", account.AvailableBalance().String())
+	assert.True(t, account.CanSpend(decimal.New(11, 0)))
+	assert.False(t, account.CanSpend(decimal.New(12, 0)))
+
+	encoded, err := json.Marshal(account)
+	require.NoError(t, err)
+	assert.Contains(t, string(encoded), `"held":"4"`)
+	assert.Contains(t, string(encoded), `"available_balance":"6"`)
+}
+
+func TestHoldIsExpired(t *testing.T) {
+	now := time.Now()
+	hold := entities.Hold{ExpiresAt: now}
+	assert.True(t, hold.IsExpired(now))
+	assert.False(t, hold.IsExpired(now.Add(-time.Second)))
+
+	for _, status := range []entities.HoldStatus{entities.PendingHold, entities.CapturedHold, entities.VoidedHold, entities.ExpiredHold} {
+		assert.True(t, status.IsValid(), status)
+	}
+	assert.False(t, entities.HoldStatus("released").IsValid())
+}
@@ -0,0 +1,54 @@
+package entities
+
+import (
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// HoldStatus tells whether funds reserved by a hold are still reserved.
+type HoldStatus string
+
+const (
+	// PendingHold keeps its amount reserved on the account until it is captured,
+	// voided or expired.
+	PendingHold HoldStatus = "pending"
+
+	// CapturedHold was converted into a transfer to its counterparty.
+	CapturedHold HoldStatus = "captured"
+
+	// VoidedHold was released on request.
+	VoidedHold HoldStatus = "voided"
+
+	// ExpiredHold was released as it was not captured in time.
+	ExpiredHold HoldStatus = "expired"
+)
+
+// IsValid checks whether the status is one of the known ones.
+func (s HoldStatus) IsValid() bool {
+	switch s {
+	case PendingHold, CapturedHold, VoidedHold, ExpiredHold:
+		return true
+	}
+	return false
+}
+
+// Hold (authorization) reserves Amount on an account in favour of Counterparty.
+// Pending hold may be captured (transferred to Counterparty) or voided until ExpiresAt.
+// TransactionID is set once the hold gets captured.
+type Hold struct {
+	ID            int             `json:"id"`
+	Account       Account         `json:"-"`
+	Counterparty  Account         `json:"-"`
+	Amount        decimal.Decimal `json:"amount"`
+	Currency      Currency        `json:"currency"`
+	Status        HoldStatus      `json:"status"`
+	CreatedAt     time.Time       `json:"created_at"`
+	ExpiresAt     time.Time       `json:"expires_at"`
+	TransactionID int             `json:"transaction_id,omitempty"`
+}
+
+// IsExpired tells whether the hold can't be captured anymore at the given moment.
+func (h Hold) IsExpired(now time.Time) bool {
+	return !now.Before(h.ExpiresAt)
+}
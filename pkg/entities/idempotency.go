@@ -0,0 +1,38 @@
+package entities
+
+import "time"
+
+// IdempotencyRecord keeps the outcome of a request made with an idempotency key,
+// so that retries of the same request could be answered without processing it again.
+// Record without ResponseStatus is the one whose request is still being processed.
+// CommittedAt is set within the storage transaction which commits changes of the request,
+// along with the Transaction it booked (if any). LockID tells reservations of the key apart,
+// and LockedUntil limits the time the key is reserved for: reservations of requests which
+// committed nothing may be taken over by retries after it. Zero LockedUntil reserves the key for good.
+type IdempotencyRecord struct {
+	Key             string
+	RequestHash     string
+	LockID          string
+	TransactionID   int
+	ResponseStatus  int
+	ResponseHeaders map[string]string
+	ResponseBody    []byte
+	CreatedAt       time.Time
+	LockedUntil     time.Time
+	CommittedAt     time.Time
+}
+
+// IsCompleted tells whether the response of the request was already stored.
+func (r IdempotencyRecord) IsCompleted() bool {
+	return r.ResponseStatus != 0
+}
+
+// IsCommitted tells whether changes made by the request were committed.
+func (r IdempotencyRecord) IsCommitted() bool {
+	return !r.CommittedAt.IsZero()
+}
+
+// IsLocked tells whether the key is still reserved by the request at the moment.
+func (r IdempotencyRecord) IsLocked(now time.Time) bool {
+	return r.LockedUntil.IsZero() || now.Before(r.LockedUntil)
+}
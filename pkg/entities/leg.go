@@ -0,0 +1,20 @@
+package entities
+
+import "github.com/shopspring/decimal"
+
+// Leg is a single part of a multi-leg transaction: an amount either taken
+// from the account (Outgoing, i.e. debit) or put to it (Incoming, i.e. credit).
+type Leg struct {
+	Account   Account
+	Direction Direction
+	Amount    decimal.Decimal
+}
+
+// PostingReceipt describes the outcome of a successful multi-leg transaction:
+// the Transaction it was booked with, all of its Payments and the accounts
+// of its legs (in order of the legs) along with balances they were left with.
+type PostingReceipt struct {
+	Transaction Transaction
+	Payments    []Payment
+	Accounts    []Account
+}
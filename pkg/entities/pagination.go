@@ -0,0 +1,143 @@
+package entities
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/shopspring/decimal"
+)
+
+// ErrInvalidCursor is returned when a page cursor can't be decoded.
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// PaymentsFilter narrows down a list of payments. Zero values of the
+// fields mean there is no restriction. Payments are ordered by time of
+// their transaction and then by their own ID.
+type PaymentsFilter struct {
+	AccountName string
+	Direction   Direction
+	Kind        TransactionKind
+	Reference   string
+	FromDate    time.Time // inclusive
+	ToDate      time.Time // exclusive
+	MinAmount   decimal.NullDecimal
+	MaxAmount   decimal.NullDecimal
+	Limit       int
+	After       *PaymentsCursor
+}
+
+// PaymentsCursor points to the last payment of a page.
+// The next page starts right after it.
+type PaymentsCursor struct {
+	CreatedAt time.Time `json:"t"`
+	ID        int       `json:"id"`
+}
+
+// NewPaymentsCursor returns a cursor pointing right after the payment.
+func NewPaymentsCursor(payment Payment) PaymentsCursor {
+	return PaymentsCursor{CreatedAt: payment.Transaction.CreatedAt, ID: payment.ID}
+}
+
+// Encode represents the cursor as an opaque URL-safe string.
+func (c PaymentsCursor) Encode() string {
+	return encodeCursor(c)
+}
+
+// DecodePaymentsCursor restores a cursor previously returned by Encode.
+func DecodePaymentsCursor(encoded string) (PaymentsCursor, error) {
+	var cursor PaymentsCursor
+	err := decodeCursor(encoded, &cursor)
+	return cursor, err
+}
+
+// PaymentsPage is a single page of payments list along with
+// an encoded cursor of the next page (blank on the last page).
+type PaymentsPage struct {
+	Payments   []Payment
+	NextCursor string
+}
+
+// AccountsSort is an attribute accounts list may be sorted by.
+type AccountsSort string
+
+const (
+	SortAccountsByName      AccountsSort = "name"
+	SortAccountsByBalance   AccountsSort = "balance"
+	SortAccountsByCreatedAt AccountsSort = "created_at"
+)
+
+// AccountsFilter narrows down a list of accounts and sets up its order.
+// Accounts with equal sort attribute are ordered by their IDs.
+type AccountsFilter struct {
+	NamePrefix string
+	OwnerID    string
+	Sort       AccountsSort
+	Descending bool
+	Limit      int
+	After      *AccountsCursor
+}
+
+// AccountsCursor points to the last account of a page. As the position
+// depends on the order of the list, the cursor keeps the order too.
+type AccountsCursor struct {
+	Sort       AccountsSort    `json:"s"`
+	Descending bool            `json:"d,omitempty"`
+	Name       string          `json:"n,omitempty"`
+	Balance    decimal.Decimal `json:"b"`
+	CreatedAt  time.Time       `json:"t"`
+	ID         int             `json:"id"`
+}
+
+// NewAccountsCursor returns a cursor pointing right after the account in list ordered by the filter.
+func NewAccountsCursor(filter AccountsFilter, account Account) AccountsCursor {
+	cursor := AccountsCursor{Sort: filter.Sort, Descending: filter.Descending, ID: account.ID}
+	switch filter.Sort {
+	case SortAccountsByBalance:
+		cursor.Balance = account.Balance
+	case SortAccountsByCreatedAt:
+		cursor.CreatedAt = account.CreatedAt
+	default:
+		cursor.Name = account.Name
+	}
+	return cursor
+}
+
+// Encode represents the cursor as an opaque URL-safe string.
+func (c AccountsCursor) Encode() string {
+	return encodeCursor(c)
+}
+
+// DecodeAccountsCursor restores a cursor previously returned by Encode.
+func DecodeAccountsCursor(encoded string) (AccountsCursor, error) {
+	var cursor AccountsCursor
+	err := decodeCursor(encoded, &cursor)
+	return cursor, err
+}
+
+// AccountsPage is a single page of accounts list along with
+// an encoded cursor of the next page (blank on the last page).
+type AccountsPage struct {
+	Accounts   []Account
+	NextCursor string
+}
+
+func encodeCursor(cursor interface{}) string {
+	// cursors consist of plain values only, so marshalling can't fail
+	data, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeCursor(encoded string, cursor interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return ErrInvalidCursor
+	}
+
+	if err := json.Unmarshal(data, cursor); err != nil {
+		return ErrInvalidCursor
+	}
+
+	return nil
+}
@@ -6,6 +6,7 @@ import "github.com/shopspring/decimal"
 // (either incoming or outgoing). Each payment has a corresponding opposite payment.
 // Both such payments are linked by a single Transaction.
 type Payment struct {
+	ID           int
 	Account      Account
 	Counterparty Account
 	Transaction  Transaction
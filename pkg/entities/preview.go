@@ -0,0 +1,12 @@
+package entities
+
+import "github.com/shopspring/decimal"
+
+// PaymentPreview tells how much a transfer would cost without booking it.
+// Total is the amount the sender would be charged, i.e. Amount plus Fee.
+type PaymentPreview struct {
+	Amount   decimal.Decimal `json:"amount"`
+	Fee      decimal.Decimal `json:"fee"`
+	Total    decimal.Decimal `json:"total"`
+	Currency Currency        `json:"currency"`
+}
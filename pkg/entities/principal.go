@@ -0,0 +1,88 @@
+package entities
+
+// Role tells which operations a caller of the API may perform.
+// Each role permits everything the lower ones do.
+type Role string
+
+const (
+	// ViewerRole reads accounts, payments and transactions.
+	ViewerRole Role = "viewer"
+
+	// AccountOwnerRole moves money from accounts of its owner and manages them.
+	AccountOwnerRole Role = "account-owner"
+
+	// OperatorRole books deposits and withdrawals, freezes accounts, reverses
+	// transactions and manages credit limits.
+	OperatorRole Role = "operator"
+
+	// AdminRole manages API keys and may send money from any account.
+	AdminRole Role = "admin"
+)
+
+// roleRanks orders roles from the least privileged one
+var roleRanks = map[Role]int{
+	ViewerRole:       1,
+	AccountOwnerRole: 2,
+	OperatorRole:     3,
+	AdminRole:        4,
+}
+
+// IsValid checks whether the role is one of the known ones.
+func (r Role) IsValid() bool {
+	_, ok := roleRanks[r]
+	return ok
+}
+
+// Permits checks whether the role permits operations of the required one.
+func (r Role) Permits(required Role) bool {
+	return r.IsValid() && roleRanks[r] >= roleRanks[required]
+}
+
+// Scope narrows down operations a caller may perform within its role.
+type Scope string
+
+const (
+	// AccountsReadScope reads accounts, payments and transactions.
+	AccountsReadScope Scope = "accounts:read"
+
+	// AccountsWriteScope opens accounts and changes their profile.
+	AccountsWriteScope Scope = "accounts:write"
+
+	// PaymentsWriteScope sends payments, authorizes and schedules them.
+	PaymentsWriteScope Scope = "payments:write"
+)
+
+// Principal is an authenticated caller of the API. Subject identifies the caller
+// (e.g. API key id), OwnerID links it to accounts of the same owner.
+// Scopes are granted to callers authenticated by tokens of an identity provider,
+// other callers have none and are limited by their role only.
+type Principal struct {
+	Subject string
+	Role    Role
+	OwnerID string
+	Scopes  []Scope
+}
+
+// Allows checks whether the principal was granted the scope.
+// Blank scope is allowed to everyone, as are all scopes to principals without any.
+func (p Principal) Allows(scope Scope) bool {
+	if scope == "" || p.Scopes == nil {
+		return true
+	}
+
+	for _, granted := range p.Scopes {
+		if granted == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// Owns checks whether the principal may move money from the account.
+// Admins own every account, other callers own accounts of their owner only.
+func (p Principal) Owns(account Account) bool {
+	if p.Role == AdminRole {
+		return true
+	}
+	return p.OwnerID != "" && account.OwnerID == p.OwnerID
+}
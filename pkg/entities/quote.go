@@ -0,0 +1,27 @@
+package entities
+
+import (
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// Quote is an exchange rate offer which allows to transfer SourceAmount
+// of FromCurrency and have TargetAmount of ToCurrency delivered to receiver.
+// Quote is valid until ExpiresAt and may be used for a single transfer only.
+type Quote struct {
+	ID            int             `json:"id"`
+	FromCurrency  Currency        `json:"from_currency"`
+	ToCurrency    Currency        `json:"to_currency"`
+	Rate          decimal.Decimal `json:"rate"`
+	SourceAmount  decimal.Decimal `json:"source_amount"`
+	TargetAmount  decimal.Decimal `json:"target_amount"`
+	CreatedAt     time.Time       `json:"created_at"`
+	ExpiresAt     time.Time       `json:"expires_at"`
+	TransactionID int             `json:"-"`
+}
+
+// IsUsed tells whether the quote was already spent on a transfer.
+func (q Quote) IsUsed() bool {
+	return q.TransactionID != 0
+}
@@ -0,0 +1,15 @@
+package entities
+
+import "github.com/shopspring/decimal"
+
+// TransferReceipt describes the outcome of a successful money transfer:
+// the Transaction it was booked with, all of its Payments (legs) and
+// the balances sender and receiver accounts were left with.
+// Fee is the amount the sender was charged on top of the transfer.
+type TransferReceipt struct {
+	Transaction     Transaction
+	Payments        []Payment
+	Fee             decimal.Decimal
+	SenderBalance   decimal.Decimal
+	ReceiverBalance decimal.Decimal
+}
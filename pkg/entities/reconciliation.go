@@ -0,0 +1,57 @@
+package entities
+
+import (
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// LedgerBalance is a stored account along with the sum of its payments
+// (incoming ones minus outgoing ones), which its balance should be equal to.
+type LedgerBalance struct {
+	Account       Account
+	PaymentsTotal decimal.Decimal
+}
+
+// TransactionImbalance is a non-zero sum of payments of a single currency
+// booked within a transaction.
+type TransactionImbalance struct {
+	TransactionID int
+	Currency      Currency
+	Total         decimal.Decimal
+}
+
+// DiscrepancyKind names an invariant of the ledger which turned out to be broken.
+type DiscrepancyKind string
+
+const (
+	// AccountBalanceMismatch means account balance differs from the sum of its payments.
+	AccountBalanceMismatch DiscrepancyKind = "account_balance_mismatch"
+
+	// UnbalancedTransaction means payments of a transaction do not sum up to zero.
+	UnbalancedTransaction DiscrepancyKind = "unbalanced_transaction"
+
+	// UnbalancedCurrency means balances of all accounts (house ones included)
+	// holding the currency do not sum up to zero.
+	UnbalancedCurrency DiscrepancyKind = "unbalanced_currency"
+)
+
+// Discrepancy describes a single broken invariant of the ledger.
+// Account and TransactionID are filled in when the kind relates to them.
+type Discrepancy struct {
+	Kind          DiscrepancyKind `json:"kind"`
+	Account       string          `json:"account,omitempty"`
+	TransactionID int             `json:"transaction_id,omitempty"`
+	Currency      Currency        `json:"currency"`
+	Expected      decimal.Decimal `json:"expected"`
+	Actual        decimal.Decimal `json:"actual"`
+}
+
+// ReconciliationReport is an outcome of the ledger check.
+type ReconciliationReport struct {
+	StartedAt       time.Time     `json:"started_at"`
+	FinishedAt      time.Time     `json:"finished_at"`
+	AccountsChecked int           `json:"accounts_checked"`
+	Consistent      bool          `json:"consistent"`
+	Discrepancies   []Discrepancy `json:"discrepancies"`
+}
@@ -0,0 +1,146 @@
+package entities
+
+import (
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// ScheduleFrequency tells how often a scheduled payment recurs.
+type ScheduleFrequency string
+
+const (
+	// OnceFrequency runs the payment a single time.
+	OnceFrequency ScheduleFrequency = "once"
+
+	// DailyFrequency runs the payment every day at the time of the first run.
+	DailyFrequency ScheduleFrequency = "daily"
+
+	// WeeklyFrequency runs the payment every week on the weekday of the first run.
+	WeeklyFrequency ScheduleFrequency = "weekly"
+
+	// MonthlyFrequency runs the payment every month on the day of the first run,
+	// or on the last day of shorter months.
+	MonthlyFrequency ScheduleFrequency = "monthly"
+
+	// CronFrequency runs the payment at moments matched by a cron expression.
+	CronFrequency ScheduleFrequency = "cron"
+)
+
+// IsValid checks whether the frequency is one of the known ones.
+func (f ScheduleFrequency) IsValid() bool {
+	switch f {
+	case OnceFrequency, DailyFrequency, WeeklyFrequency, MonthlyFrequency, CronFrequency:
+		return true
+	}
+	return false
+}
+
+// ScheduleStatus tells whether a scheduled payment is going to run.
+type ScheduleStatus string
+
+const (
+	// ActiveSchedule runs its payment whenever it is due.
+	ActiveSchedule ScheduleStatus = "active"
+
+	// PausedSchedule does not run until it is resumed.
+	PausedSchedule ScheduleStatus = "paused"
+
+	// CompletedSchedule has run its last payment.
+	CompletedSchedule ScheduleStatus = "completed"
+
+	// CancelledSchedule was stopped on request for good.
+	CancelledSchedule ScheduleStatus = "cancelled"
+)
+
+// IsValid checks whether the status is one of the known ones.
+func (s ScheduleStatus) IsValid() bool {
+	switch s {
+	case ActiveSchedule, PausedSchedule, CompletedSchedule, CancelledSchedule:
+		return true
+	}
+	return false
+}
+
+// IsFinal checks whether schedules of this status are never going to run again.
+func (s ScheduleStatus) IsFinal() bool {
+	return s == CompletedSchedule || s == CancelledSchedule
+}
+
+// ScheduledPayment is a transfer of Amount from Account to Counterparty made at
+// a future moment, once or recurring. NextRunAt is the moment of the upcoming run.
+// A run failing for insufficient funds is retried at RetryAt, Attempts counts
+// failed attempts of the upcoming run. StartAt anchors monthly runs to its day
+// of month, zero EndAt lets the schedule recur forever.
+// A scheduler claims due payments for LeaseOwner until LeaseExpiresAt,
+// so that a payment is run by a single scheduler at a time.
+type ScheduledPayment struct {
+	ID             int
+	Account        Account
+	Counterparty   Account
+	Amount         decimal.Decimal
+	Currency       Currency
+	Frequency      ScheduleFrequency
+	Cron           string
+	Status         ScheduleStatus
+	StartAt        time.Time
+	EndAt          time.Time
+	NextRunAt      time.Time
+	RetryAt        time.Time
+	Attempts       int
+	LeaseOwner     string
+	LeaseExpiresAt time.Time
+	CreatedAt      time.Time
+}
+
+// DueAt returns the moment the payment should be attempted at, which is either
+// the upcoming run or the retry of its failed attempt.
+func (p ScheduledPayment) DueAt() time.Time {
+	if !p.RetryAt.IsZero() {
+		return p.RetryAt
+	}
+	return p.NextRunAt
+}
+
+// IsDue checks whether an active payment should be attempted at the given moment.
+func (p ScheduledPayment) IsDue(now time.Time) bool {
+	return p.Status == ActiveSchedule && !now.Before(p.DueAt())
+}
+
+// IsLeased checks whether the payment is claimed by a scheduler at the given moment.
+func (p ScheduledPayment) IsLeased(now time.Time) bool {
+	return p.LeaseOwner != "" && now.Before(p.LeaseExpiresAt)
+}
+
+// ScheduledPaymentUpdate lists changes of a scheduled payment.
+// Fields left blank are not changed.
+type ScheduledPaymentUpdate struct {
+	Amount    decimal.NullDecimal
+	Status    ScheduleStatus
+	NextRunAt time.Time
+}
+
+// RunStatus tells the outcome of a scheduled payment run.
+type RunStatus string
+
+const (
+	// SucceededRun booked the transfer.
+	SucceededRun RunStatus = "succeeded"
+
+	// FailedRun did not book the transfer, it may be retried later.
+	FailedRun RunStatus = "failed"
+)
+
+// ScheduledRun is a record of a single attempt to run a scheduled payment.
+// ScheduledFor is the moment the run was due at, Attempt counts attempts of the run
+// starting from one. TransactionID is set for succeeded runs, Error for failed ones.
+type ScheduledRun struct {
+	ID            int       `json:"id"`
+	ScheduleID    int       `json:"-"`
+	ScheduledFor  time.Time `json:"scheduled_for"`
+	Attempt       int       `json:"attempt"`
+	Status        RunStatus `json:"status"`
+	TransactionID int       `json:"transaction_id,omitempty"`
+	Error         string    `json:"error,omitempty"`
+	CreatedAt     time.Time `json:"created_at"`
+}
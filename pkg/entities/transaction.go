@@ -1,9 +1,72 @@
 package entities
 
-import "time"
+import (
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// TransactionKind tells what a transaction was booked for.
+type TransactionKind string
+
+const (
+	// TransferTransaction moves money between accounts within the system.
+	TransferTransaction TransactionKind = "transfer"
+
+	// DepositTransaction brings money into the system through SYSTEM account.
+	DepositTransaction TransactionKind = "deposit"
+
+	// WithdrawalTransaction takes money out of the system through SYSTEM account.
+	WithdrawalTransaction TransactionKind = "withdrawal"
+
+	// ReversalTransaction undoes a transfer in full by booking its payments the other way round.
+	ReversalTransaction TransactionKind = "reversal"
+
+	// RefundTransaction returns a part of a transfer amount back to its sender.
+	// A transfer may be refunded a few times until its whole amount is returned.
+	RefundTransaction TransactionKind = "refund"
+)
+
+// IsValid checks whether the kind is one of the known ones.
+func (k TransactionKind) IsValid() bool {
+	switch k {
+	case TransferTransaction, DepositTransaction, WithdrawalTransaction, ReversalTransaction, RefundTransaction:
+		return true
+	}
+	return false
+}
+
+// IsCompensating checks whether transactions of this kind undo (a part of)
+// another transaction, which they should be linked to.
+func (k TransactionKind) IsCompensating() bool {
+	return k == ReversalTransaction || k == RefundTransaction
+}
 
 // Transaction is an object linking two related and opposite payments.
+// Deposits and withdrawals carry a reference of the operation in an external
+// system (e.g. bank transfer reference or card authorization id).
+// Reversals and refunds carry ID of the transaction they compensate.
+// Fee is the amount charged to the sender on top of a transfer, it is booked
+// within the same transaction as a payment to FEE account of the currency.
+// Transfers may carry a description, metadata and a reference the sender
+// (SenderID) has given them, e.g. an invoice number, which is unique among
+// transfers of the sender.
 type Transaction struct {
-	ID        int       `json:"-"`
-	CreatedAt time.Time `json:"created_at"`
+	ID                int               `json:"-"`
+	CreatedAt         time.Time         `json:"created_at"`
+	Kind              TransactionKind   `json:"kind"`
+	ExternalReference string            `json:"external_reference,omitempty"`
+	OriginalID        int               `json:"original_transaction_id,omitempty"`
+	Fee               decimal.Decimal   `json:"fee"`
+	SenderID          int               `json:"-"`
+	Description       string            `json:"description,omitempty"`
+	Metadata          map[string]string `json:"metadata,omitempty"`
+}
+
+// PaymentDetails are attributes a client attaches to a transfer.
+// ExternalReference is unique among transfers of the sender.
+type PaymentDetails struct {
+	Description       string
+	ExternalReference string
+	Metadata          map[string]string
 }
@@ -0,0 +1,147 @@
+// Package fees provides a schedule of fees charged for transfers
+// between accounts.
+package fees
+
+import (
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/shopspring/decimal"
+	"github.com/twonegatives/coinsph_challenge/pkg/entities"
+)
+
+var hundred = decimal.New(100, 0)
+
+// Tier is a part of a fee rule which applies to amounts starting from From.
+// Its fee consists of a Flat part and a Percent of the amount transferred.
+type Tier struct {
+	From    decimal.Decimal
+	Flat    decimal.Decimal
+	Percent decimal.Decimal
+}
+
+// Rule describes fees of transfers in a single currency. Tiers are sorted by From,
+// the first one starts from zero. The fee of the tier an amount falls into is
+// brought within Min and Max caps afterwards. Zero Max leaves the fee uncapped.
+type Rule struct {
+	Currency entities.Currency
+	Tiers    []Tier
+	Min      decimal.Decimal
+	Max      decimal.Decimal
+}
+
+// Fee calculates the fee of transferring the amount. The fee is not rounded.
+func (r Rule) Fee(amount decimal.Decimal) decimal.Decimal {
+	fee := decimal.New(0, 0)
+	for _, tier := range r.Tiers {
+		if amount.LessThan(tier.From) {
+			break
+		}
+		fee = tier.Flat.Add(amount.Mul(tier.Percent).Div(hundred))
+	}
+
+	if fee.LessThan(r.Min) {
+		fee = r.Min
+	}
+
+	if r.Max.IsPositive() && fee.GreaterThan(r.Max) {
+		fee = r.Max
+	}
+
+	return fee
+}
+
+// Schedule is a set of fee rules per currency.
+// Transfers in currencies without a rule are free of charge.
+type Schedule struct {
+	rules map[entities.Currency]Rule
+}
+
+// NewSchedule returns a schedule consisting of the given rules.
+func NewSchedule(rules ...Rule) *Schedule {
+	schedule := &Schedule{rules: make(map[entities.Currency]Rule, len(rules))}
+	for _, rule := range rules {
+		schedule.rules[rule.Currency] = rule
+	}
+	return schedule
+}
+
+// ParseSchedule builds a schedule out of semicolon separated list of
+// "currency:key=value,..." rules, e.g. "usd:flat=0.25,percent=1,min=0.5,max=10".
+// Known keys are flat, percent, min, max and from. Every "from" starts a new tier
+// applied to amounts starting from its value, flat and percent following it belong
+// to that tier, e.g. "php:flat=5,from=1000,percent=0.5" charges 5 PHP below 1000 PHP
+// and 0.5% of the amount starting from 1000 PHP.
+func ParseSchedule(spec string) (*Schedule, error) {
+	var rules []Rule
+	for _, item := range strings.Split(spec, ";") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+
+		parts := strings.SplitN(item, ":", 2)
+		if len(parts) != 2 || strings.TrimSpace(parts[0]) == "" {
+			return nil, errors.Errorf("invalid fee rule %q, expected currency:key=value,...", item)
+		}
+
+		rule, err := parseRule(entities.Currency(strings.ToLower(strings.TrimSpace(parts[0]))), parts[1])
+		if err != nil {
+			return nil, err
+		}
+
+		rules = append(rules, rule)
+	}
+
+	return NewSchedule(rules...), nil
+}
+
+// parseRule parses comma separated "key=value" definitions of a currency rule
+func parseRule(currency entities.Currency, spec string) (Rule, error) {
+	rule := Rule{Currency: currency, Tiers: []Tier{{}}}
+	for _, field := range strings.Split(spec, ",") {
+		pair := strings.Split(strings.TrimSpace(field), "=")
+		if len(pair) != 2 {
+			return Rule{}, errors.Errorf("invalid fee definition %q of %s, expected key=value", field, currency)
+		}
+
+		value, err := decimal.NewFromString(pair[1])
+		if err != nil || value.IsNegative() {
+			return Rule{}, errors.Errorf("invalid %s fee value of %s", pair[0], currency)
+		}
+
+		tier := &rule.Tiers[len(rule.Tiers)-1]
+		switch pair[0] {
+		case "flat":
+			tier.Flat = value
+		case "percent":
+			tier.Percent = value
+		case "min":
+			rule.Min = value
+		case "max":
+			rule.Max = value
+		case "from":
+			if !value.GreaterThan(tier.From) {
+				return Rule{}, errors.Errorf("fee tiers of %s should start from increasing amounts", currency)
+			}
+			rule.Tiers = append(rule.Tiers, Tier{From: value})
+		default:
+			return Rule{}, errors.Errorf("unknown fee definition %q of %s", pair[0], currency)
+		}
+	}
+
+	if rule.Max.IsPositive() && rule.Max.LessThan(rule.Min) {
+		return Rule{}, errors.Errorf("maximal fee of %s should not be less than minimal one", currency)
+	}
+
+	return rule, nil
+}
+
+// Fee calculates the fee of transferring the amount of the currency. The fee is not rounded.
+func (s *Schedule) Fee(currency entities.Currency, amount decimal.Decimal) decimal.Decimal {
+	rule, ok := s.rules[currency]
+	if !ok {
+		return decimal.New(0, 0)
+	}
+	return rule.Fee(amount)
+}
@@ -0,0 +1,51 @@
+package fees_test
+
+import (
+	"testing"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/twonegatives/coinsph_challenge/pkg/entities"
+	"github.com/twonegatives/coinsph_challenge/pkg/fees"
+)
+
+func TestSchedule(t *testing.T) {
+	t.Run("combines flat and percentage fees within caps", func(t *testing.T) {
+		schedule, err := fees.ParseSchedule("USD:flat=0.25,percent=1,min=0.5,max=10")
+		require.NoError(t, err)
+
+		cases := map[string]string{"10": "0.5", "100": "1.25", "2000": "10"}
+		for amount, fee := range cases {
+			assert.Equal(t, fee, schedule.Fee(entities.USD, decimal.RequireFromString(amount)).String(), amount)
+		}
+	})
+
+	t.Run("applies the tier an amount falls into", func(t *testing.T) {
+		schedule, err := fees.ParseSchedule("php:flat=5,from=1000,percent=0.5,from=10000,flat=25; usd:flat=1")
+		require.NoError(t, err)
+
+		cases := map[string]string{"999.99": "5", "1000": "5", "4000": "20", "10000": "25"}
+		for amount, fee := range cases {
+			assert.Equal(t, fee, schedule.Fee(entities.PHP, decimal.RequireFromString(amount)).String(), amount)
+		}
+		assert.Equal(t, "1", schedule.Fee(entities.USD, decimal.New(5, 0)).String())
+	})
+
+	t.Run("does not charge currencies without rules", func(t *testing.T) {
+		schedule, err := fees.ParseSchedule("")
+		require.NoError(t, err)
+		assert.True(t, schedule.Fee(entities.EUR, decimal.New(100, 0)).IsZero())
+	})
+
+	t.Run("fails on malformed definitions", func(t *testing.T) {
+		specs := []string{
+			"usd", ":flat=1", "usd:flat", "usd:flat=one", "usd:percent=-1", "usd:fixed=1",
+			"usd:from=0", "usd:from=10,from=5", "usd:min=5,max=1",
+		}
+		for _, spec := range specs {
+			_, err := fees.ParseSchedule(spec)
+			assert.Error(t, err, spec)
+		}
+	})
+}
@@ -0,0 +1,89 @@
+// Package fx provides foreign exchange rates used to convert
+// money between accounts holding different currencies.
+package fx
+
+import (
+	"context"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+	"github.com/shopspring/decimal"
+	"github.com/twonegatives/coinsph_challenge/pkg/entities"
+)
+
+// ErrRateUnavailable is returned by RateProvider when it has no rate for requested currencies.
+var ErrRateUnavailable = errors.New("exchange rate is not available")
+
+// RateProvider is an abstraction over sources of exchange rates.
+type RateProvider interface {
+	// Rate returns an amount of 'to' currency which is given for a single unit of 'from' currency.
+	Rate(ctx context.Context, from entities.Currency, to entities.Currency) (decimal.Decimal, error)
+}
+
+type currencyPair struct {
+	from entities.Currency
+	to   entities.Currency
+}
+
+// StaticRateProvider is an in-memory implementation of RateProvider
+// which serves a fixed set of rates. Rates are not derived from each other,
+// so both php/usd and usd/php should be set to exchange in both directions.
+type StaticRateProvider struct {
+	mutex sync.RWMutex
+	rates map[currencyPair]decimal.Decimal
+}
+
+func NewStaticRateProvider() *StaticRateProvider {
+	return &StaticRateProvider{rates: make(map[currencyPair]decimal.Decimal)}
+}
+
+// ParseStaticRates builds a StaticRateProvider out of comma separated
+// list of "from/to:rate" definitions, e.g. "php/usd:0.0191,usd/php:52.35".
+func ParseStaticRates(spec string) (*StaticRateProvider, error) {
+	provider := NewStaticRateProvider()
+	for _, item := range strings.Split(spec, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+
+		parts := strings.Split(item, ":")
+		currencies := strings.Split(parts[0], "/")
+		if len(parts) != 2 || len(currencies) != 2 || currencies[0] == "" || currencies[1] == "" {
+			return nil, errors.Errorf("invalid rate definition %q, expected from/to:rate", item)
+		}
+
+		rate, err := decimal.NewFromString(parts[1])
+		if err != nil || !rate.IsPositive() {
+			return nil, errors.Errorf("invalid rate of %s", parts[0])
+		}
+
+		provider.SetRate(
+			entities.Currency(strings.ToLower(currencies[0])),
+			entities.Currency(strings.ToLower(currencies[1])),
+			rate,
+		)
+	}
+
+	return provider, nil
+}
+
+// SetRate adds or replaces an exchange rate for a given pair of currencies.
+func (p *StaticRateProvider) SetRate(from entities.Currency, to entities.Currency, rate decimal.Decimal) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	p.rates[currencyPair{from: from, to: to}] = rate
+}
+
+// Rate returns previously set rate of the pair or ErrRateUnavailable.
+func (p *StaticRateProvider) Rate(_ context.Context, from entities.Currency, to entities.Currency) (decimal.Decimal, error) {
+	p.mutex.RLock()
+	defer p.mutex.RUnlock()
+
+	rate, ok := p.rates[currencyPair{from: from, to: to}]
+	if !ok {
+		return decimal.Decimal{}, errors.Wrapf(ErrRateUnavailable, "no %s/%s rate", from, to)
+	}
+	return rate, nil
+}
@@ -0,0 +1,46 @@
+package fx_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/twonegatives/coinsph_challenge/pkg/entities"
+	"github.com/twonegatives/coinsph_challenge/pkg/fx"
+)
+
+func TestStaticRateProvider(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("parses rates definitions", func(t *testing.T) {
+		provider, err := fx.ParseStaticRates("PHP/usd:0.0191, usd/php:52.35")
+		require.NoError(t, err)
+
+		rate, err := provider.Rate(ctx, entities.PHP, entities.USD)
+		require.NoError(t, err)
+		assert.Equal(t, decimal.New(191, -4), rate)
+
+		rate, err = provider.Rate(ctx, entities.USD, entities.PHP)
+		require.NoError(t, err)
+		assert.Equal(t, decimal.New(5235, -2), rate)
+	})
+
+	t.Run("fails on malformed definitions", func(t *testing.T) {
+		for _, spec := range []string{"php/usd", "php:0.1", "php/usd:zero", "php/usd:-1", "/usd:1"} {
+			_, err := fx.ParseStaticRates(spec)
+			assert.Error(t, err, spec)
+		}
+	})
+
+	t.Run("does not derive missing rates", func(t *testing.T) {
+		provider := fx.NewStaticRateProvider()
+		provider.SetRate(entities.PHP, entities.USD, decimal.New(191, -4))
+
+		_, err := provider.Rate(ctx, entities.USD, entities.PHP)
+		require.Error(t, err)
+		assert.Equal(t, fx.ErrRateUnavailable, errors.Cause(err))
+	})
+}
@@ -0,0 +1,328 @@
+// Package jwt verifies JSON Web Tokens signed with HS256, RS256 or ES256
+// by keys taken from pluggable sources (JWKS documents, static configuration).
+package jwt
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Algorithms of token signatures supported by Verifier.
+const (
+	HS256 = "HS256"
+	RS256 = "RS256"
+	ES256 = "ES256"
+)
+
+var (
+	// ErrMalformed is returned for tokens which are not compact JWS of JSON claims.
+	ErrMalformed = errors.New("token is malformed")
+
+	// ErrUnsupportedAlgorithm is returned for tokens signed with an algorithm other than HS256, RS256 and ES256.
+	ErrUnsupportedAlgorithm = errors.New("token signing algorithm is not supported")
+
+	// ErrUnknownKey is returned (possibly wrapped) by KeySource when it has no key for the token.
+	ErrUnknownKey = errors.New("token signing key is unknown")
+
+	// ErrInvalidSignature is returned when the token signature does not match its content.
+	ErrInvalidSignature = errors.New("token signature is invalid")
+
+	// ErrExpired is returned for tokens used after their expiration time or without one.
+	ErrExpired = errors.New("token has expired")
+
+	// ErrNotYetValid is returned for tokens used before their 'nbf' time.
+	ErrNotYetValid = errors.New("token is not valid yet")
+
+	// ErrInvalidIssuer is returned for tokens issued by somebody else than the expected issuer.
+	ErrInvalidIssuer = errors.New("token issuer is not accepted")
+
+	// ErrInvalidAudience is returned for tokens not meant for the expected audience.
+	ErrInvalidAudience = errors.New("token audience is not accepted")
+)
+
+// KeySource is an abstraction over sources of keys tokens are verified with.
+type KeySource interface {
+	// Key returns a key identified by 'kid' (which may be blank) suitable for the algorithm:
+	// a secret []byte for HS256, *rsa.PublicKey for RS256 and *ecdsa.PublicKey for ES256.
+	Key(kid string, alg string) (interface{}, error)
+}
+
+// Claims are claims of a verified token. Registered claims are parsed,
+// all the claims (registered ones included) are kept in Raw.
+type Claims struct {
+	Subject   string
+	Issuer    string
+	Audience  []string
+	ExpiresAt time.Time
+	NotBefore time.Time
+	IssuedAt  time.Time
+	Raw       map[string]interface{}
+}
+
+// String returns a claim as a string, or blank string if it is missing or is not a string.
+func (c Claims) String(name string) string {
+	value, _ := c.Raw[name].(string)
+	return value
+}
+
+// Strings returns a claim holding either a space separated string (like OAuth 'scope')
+// or an array of strings (like 'scp').
+func (c Claims) Strings(name string) []string {
+	switch value := c.Raw[name].(type) {
+	case string:
+		return strings.Fields(value)
+	case []interface{}:
+		values := make([]string, 0, len(value))
+		for _, item := range value {
+			if text, ok := item.(string); ok {
+				values = append(values, text)
+			}
+		}
+		return values
+	}
+	return nil
+}
+
+// Verifier checks signatures and validity time of tokens, and optionally their issuer and audience.
+type Verifier struct {
+	keys     KeySource
+	issuer   string
+	audience string
+	leeway   time.Duration
+	now      func() time.Time
+}
+
+// Option allows to alter Verifier defaults on construction.
+type Option func(*Verifier)
+
+// WithIssuer makes the verifier accept tokens of the issuer ('iss' claim) only.
+func WithIssuer(issuer string) Option {
+	return func(v *Verifier) {
+		v.issuer = issuer
+	}
+}
+
+// WithAudience makes the verifier accept tokens meant for the audience ('aud' claim) only.
+func WithAudience(audience string) Option {
+	return func(v *Verifier) {
+		v.audience = audience
+	}
+}
+
+// WithLeeway sets how much clocks of the issuer and the verifier may drift apart.
+// One minute is allowed unless this option is passed.
+func WithLeeway(leeway time.Duration) Option {
+	return func(v *Verifier) {
+		v.leeway = leeway
+	}
+}
+
+// WithClock sets a function returning the current time.
+func WithClock(now func() time.Time) Option {
+	return func(v *Verifier) {
+		v.now = now
+	}
+}
+
+// NewVerifier returns a verifier checking token signatures with keys of the source.
+func NewVerifier(keys KeySource, opts ...Option) *Verifier {
+	v := &Verifier{keys: keys, leeway: time.Minute, now: time.Now}
+	for _, opt := range opts {
+		opt(v)
+	}
+	return v
+}
+
+type header struct {
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+	Typ string `json:"typ"`
+}
+
+// Verify checks the compact serialized token and returns its claims.
+// Tokens should have an expiration time.
+func (v *Verifier) Verify(token string) (Claims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return Claims{}, ErrMalformed
+	}
+
+	var head header
+	if err := decodeSegment(parts[0], &head); err != nil {
+		return Claims{}, ErrMalformed
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return Claims{}, ErrMalformed
+	}
+
+	switch head.Alg {
+	case HS256, RS256, ES256:
+	default:
+		return Claims{}, ErrUnsupportedAlgorithm
+	}
+
+	key, err := v.keys.Key(head.Kid, head.Alg)
+	if err != nil {
+		return Claims{}, err
+	}
+
+	if err := verifySignature(head.Alg, key, []byte(parts[0]+"."+parts[1]), signature); err != nil {
+		return Claims{}, err
+	}
+
+	var raw map[string]interface{}
+	if err := decodeSegment(parts[1], &raw); err != nil {
+		return Claims{}, ErrMalformed
+	}
+
+	claims, err := parseClaims(raw)
+	if err != nil {
+		return Claims{}, err
+	}
+
+	return claims, v.validate(claims)
+}
+
+// validate checks validity time, issuer and audience of the claims
+func (v *Verifier) validate(claims Claims) error {
+	now := v.now()
+	if claims.ExpiresAt.IsZero() || !now.Before(claims.ExpiresAt.Add(v.leeway)) {
+		return ErrExpired
+	}
+
+	if !claims.NotBefore.IsZero() && now.Add(v.leeway).Before(claims.NotBefore) {
+		return ErrNotYetValid
+	}
+
+	if v.issuer != "" && claims.Issuer != v.issuer {
+		return ErrInvalidIssuer
+	}
+
+	if v.audience != "" && !contains(claims.Audience, v.audience) {
+		return ErrInvalidAudience
+	}
+
+	return nil
+}
+
+// verifySignature checks the signature of the signing input with the key.
+// The key type should match the algorithm, so that e.g. a public RSA key
+// can't be used as an HMAC secret.
+func verifySignature(alg string, key interface{}, input []byte, signature []byte) error {
+	digest := sha256.Sum256(input)
+	switch alg {
+	case HS256:
+		secret, ok := key.([]byte)
+		if !ok || len(secret) == 0 {
+			return ErrUnknownKey
+		}
+
+		mac := hmac.New(sha256.New, secret)
+		mac.Write(input)
+		if !hmac.Equal(mac.Sum(nil), signature) {
+			return ErrInvalidSignature
+		}
+	case RS256:
+		publicKey, ok := key.(*rsa.PublicKey)
+		if !ok {
+			return ErrUnknownKey
+		}
+
+		if rsa.VerifyPKCS1v15(publicKey, crypto.SHA256, digest[:], signature) != nil {
+			return ErrInvalidSignature
+		}
+	case ES256:
+		publicKey, ok := key.(*ecdsa.PublicKey)
+		if !ok || publicKey.Curve.Params().BitSize != 256 {
+			return ErrUnknownKey
+		}
+
+		// ES256 signatures are R and S concatenated, 32 bytes each
+		if len(signature) != 64 {
+			return ErrInvalidSignature
+		}
+
+		r := new(big.Int).SetBytes(signature[:32])
+		s := new(big.Int).SetBytes(signature[32:])
+		if !ecdsa.Verify(publicKey, digest[:], r, s) {
+			return ErrInvalidSignature
+		}
+	default:
+		return ErrUnsupportedAlgorithm
+	}
+
+	return nil
+}
+
+// parseClaims picks registered claims out of the raw ones
+func parseClaims(raw map[string]interface{}) (Claims, error) {
+	claims := Claims{Raw: raw}
+	claims.Subject, _ = raw["sub"].(string)
+	claims.Issuer, _ = raw["iss"].(string)
+
+	switch audience := raw["aud"].(type) {
+	case nil:
+	case string:
+		claims.Audience = []string{audience}
+	case []interface{}:
+		for _, item := range audience {
+			text, ok := item.(string)
+			if !ok {
+				return Claims{}, ErrMalformed
+			}
+			claims.Audience = append(claims.Audience, text)
+		}
+	default:
+		return Claims{}, ErrMalformed
+	}
+
+	for name, target := range map[string]*time.Time{"exp": &claims.ExpiresAt, "nbf": &claims.NotBefore, "iat": &claims.IssuedAt} {
+		switch value := raw[name].(type) {
+		case nil:
+		case json.Number:
+			seconds, err := value.Float64()
+			if err != nil {
+				return Claims{}, ErrMalformed
+			}
+			*target = time.Unix(int64(seconds), 0)
+		default:
+			return Claims{}, ErrMalformed
+		}
+	}
+
+	return claims, nil
+}
+
+// decodeSegment decodes a base64url encoded JSON segment of the token
+func decodeSegment(segment string, target interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	return decoder.Decode(target)
+}
+
+func contains(values []string, value string) bool {
+	for _, item := range values {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
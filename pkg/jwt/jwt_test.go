@@ -0,0 +1,238 @@
+package jwt_test
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/twonegatives/coinsph_challenge/pkg/jwt"
+)
+
+var now = time.Date(2019, 4, 21, 10, 0, 0, 0, time.UTC)
+
+// sign returns a compact token of the claims signed with the key
+func sign(t *testing.T, alg string, kid string, key interface{}, claims map[string]interface{}) string {
+	encode := func(value interface{}) string {
+		data, err := json.Marshal(value)
+		require.NoError(t, err)
+		return base64.RawURLEncoding.EncodeToString(data)
+	}
+
+	head := map[string]string{"alg": alg, "typ": "JWT"}
+	if kid != "" {
+		head["kid"] = kid
+	}
+
+	input := encode(head) + "." + encode(claims)
+	digest := sha256.Sum256([]byte(input))
+
+	var signature []byte
+	switch alg {
+	case jwt.HS256:
+		mac := hmac.New(sha256.New, key.([]byte))
+		mac.Write([]byte(input))
+		signature = mac.Sum(nil)
+	case jwt.RS256:
+		var err error
+		signature, err = rsa.SignPKCS1v15(rand.Reader, key.(*rsa.PrivateKey), crypto.SHA256, digest[:])
+		require.NoError(t, err)
+	case jwt.ES256:
+		r, s, err := ecdsa.Sign(rand.Reader, key.(*ecdsa.PrivateKey), digest[:])
+		require.NoError(t, err)
+		// r and s are left padded to 32 bytes each
+		signature = make([]byte, 64)
+		rBytes, sBytes := r.Bytes(), s.Bytes()
+		copy(signature[32-len(rBytes):32], rBytes)
+		copy(signature[64-len(sBytes):], sBytes)
+	}
+
+	return input + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+func validClaims() map[string]interface{} {
+	return map[string]interface{}{
+		"sub":   "user-1",
+		"iss":   "gateway",
+		"aud":   []string{"wallet"},
+		"exp":   now.Add(time.Hour).Unix(),
+		"scope": "accounts:read payments:write",
+	}
+}
+
+func pemEncode(t *testing.T, key interface{}) []byte {
+	der, err := x509.MarshalPKIXPublicKey(key)
+	require.NoError(t, err)
+	return pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})
+}
+
+func TestVerifier(t *testing.T) {
+	secret := []byte("gateway-secret")
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	keys := jwt.NewKeySet()
+	require.NoError(t, keys.AddSecret("hs", secret))
+	require.NoError(t, keys.AddPublicKeyPEM("rs", pemEncode(t, &rsaKey.PublicKey)))
+	require.NoError(t, keys.AddPublicKeyPEM("es", pemEncode(t, &ecKey.PublicKey)))
+	verifier := jwt.NewVerifier(keys, jwt.WithIssuer("gateway"), jwt.WithAudience("wallet"), jwt.WithClock(func() time.Time { return now }))
+
+	t.Run("verifies tokens of every supported algorithm", func(t *testing.T) {
+		tokens := map[string]string{
+			jwt.HS256: sign(t, jwt.HS256, "hs", secret, validClaims()),
+			jwt.RS256: sign(t, jwt.RS256, "rs", rsaKey, validClaims()),
+			jwt.ES256: sign(t, jwt.ES256, "", ecKey, validClaims()),
+		}
+
+		for alg, token := range tokens {
+			claims, err := verifier.Verify(token)
+			require.NoError(t, err, alg)
+			assert.Equal(t, "user-1", claims.Subject, alg)
+			assert.Equal(t, []string{"wallet"}, claims.Audience, alg)
+			assert.Equal(t, now.Add(time.Hour).Unix(), claims.ExpiresAt.Unix(), alg)
+			assert.Equal(t, []string{"accounts:read", "payments:write"}, claims.Strings("scope"), alg)
+		}
+	})
+
+	t.Run("refuses tokens with wrong signature or key", func(t *testing.T) {
+		otherKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		require.NoError(t, err)
+
+		// claims of one token combined with the signature of another
+		valid := strings.Split(sign(t, jwt.HS256, "hs", secret, validClaims()), ".")
+		forged := strings.Split(sign(t, jwt.HS256, "hs", secret, map[string]interface{}{"sub": "admin"}), ".")
+		tampered := strings.Join([]string{valid[0], forged[1], valid[2]}, ".")
+
+		cases := []struct {
+			title string
+			token string
+			err   error
+		}{
+			{title: "foreign key", token: sign(t, jwt.ES256, "es", otherKey, validClaims()), err: jwt.ErrInvalidSignature},
+			{title: "wrong secret", token: sign(t, jwt.HS256, "hs", []byte("guess"), validClaims()), err: jwt.ErrInvalidSignature},
+			{title: "tampered claims", token: tampered, err: jwt.ErrInvalidSignature},
+			{title: "unknown kid", token: sign(t, jwt.HS256, "other", secret, validClaims()), err: jwt.ErrUnknownKey},
+			{title: "public key as secret", token: sign(t, jwt.HS256, "rs", pemEncode(t, &rsaKey.PublicKey), validClaims()), err: jwt.ErrUnknownKey},
+			{title: "unsigned", token: sign(t, "none", "", nil, validClaims()), err: jwt.ErrUnsupportedAlgorithm},
+			{title: "garbage", token: "not.a.token", err: jwt.ErrMalformed},
+		}
+
+		for _, tc := range cases {
+			_, err := verifier.Verify(tc.token)
+			assert.Equal(t, tc.err, errors.Cause(err), tc.title)
+		}
+	})
+
+	t.Run("falls back to keys without kid", func(t *testing.T) {
+		keys := jwt.NewKeySet()
+		require.NoError(t, keys.AddSecret("", secret))
+		require.NoError(t, keys.AddSecret("hs-2", []byte("rotated-secret")))
+		verifier := jwt.NewVerifier(keys, jwt.WithClock(func() time.Time { return now }))
+
+		_, err := verifier.Verify(sign(t, jwt.HS256, "hs-1", secret, validClaims()))
+		assert.NoError(t, err)
+
+		_, err = verifier.Verify(sign(t, jwt.HS256, "hs-2", []byte("rotated-secret"), validClaims()))
+		assert.NoError(t, err)
+
+		_, err = verifier.Verify(sign(t, jwt.HS256, "", secret, validClaims()))
+		assert.Equal(t, jwt.ErrUnknownKey, errors.Cause(err))
+	})
+
+	t.Run("validates registered claims", func(t *testing.T) {
+		claims := func(name string, value interface{}) map[string]interface{} {
+			result := validClaims()
+			if value == nil {
+				delete(result, name)
+			} else {
+				result[name] = value
+			}
+			return result
+		}
+
+		cases := []struct {
+			claims map[string]interface{}
+			err    error
+		}{
+			{claims: claims("exp", nil), err: jwt.ErrExpired},
+			{claims: claims("exp", now.Add(-2*time.Minute).Unix()), err: jwt.ErrExpired},
+			{claims: claims("nbf", now.Add(2*time.Minute).Unix()), err: jwt.ErrNotYetValid},
+			{claims: claims("iss", "somebody"), err: jwt.ErrInvalidIssuer},
+			{claims: claims("aud", "other"), err: jwt.ErrInvalidAudience},
+			{claims: claims("exp", "tomorrow"), err: jwt.ErrMalformed},
+			{claims: claims("exp", now.Add(-30*time.Second).Unix()), err: nil},
+		}
+
+		for _, tc := range cases {
+			_, err := verifier.Verify(sign(t, jwt.HS256, "hs", secret, tc.claims))
+			assert.Equal(t, tc.err, errors.Cause(err), fmt.Sprint(tc.claims))
+		}
+	})
+}
+
+func TestJWKS(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	encode := func(data []byte) string {
+		return base64.RawURLEncoding.EncodeToString(data)
+	}
+
+	document, err := json.Marshal(map[string]interface{}{"keys": []map[string]string{
+		{"kty": "RSA", "kid": "rs-1", "use": "sig", "alg": "RS256", "n": encode(rsaKey.N.Bytes()), "e": "AQAB"},
+		{"kty": "EC", "kid": "es-1", "crv": "P-256", "x": encode(ecKey.X.Bytes()), "y": encode(ecKey.Y.Bytes())},
+		{"kty": "oct", "kid": "hs-1", "k": encode([]byte("gateway-secret"))},
+		{"kty": "RSA", "kid": "enc-1", "use": "enc", "n": encode(rsaKey.N.Bytes()), "e": "AQAB"},
+		{"kty": "OKP", "kid": "ed-1", "crv": "Ed25519", "x": "11qYAYKxCrfVS_7TyWQHOg7hcvPapiMlrwIaaPcHURo"},
+	}})
+	require.NoError(t, err)
+
+	t.Run("loads signing keys of supported types", func(t *testing.T) {
+		keys, err := jwt.ParseJWKS(document)
+		require.NoError(t, err)
+		assert.Equal(t, 3, keys.Len())
+
+		verifier := jwt.NewVerifier(keys, jwt.WithClock(func() time.Time { return now }))
+		for _, token := range []string{
+			sign(t, jwt.RS256, "rs-1", rsaKey, validClaims()),
+			sign(t, jwt.ES256, "es-1", ecKey, validClaims()),
+			sign(t, jwt.HS256, "hs-1", []byte("gateway-secret"), validClaims()),
+		} {
+			_, err := verifier.Verify(token)
+			assert.NoError(t, err)
+		}
+
+		_, err = verifier.Verify(sign(t, jwt.RS256, "enc-1", rsaKey, validClaims()))
+		assert.Equal(t, jwt.ErrUnknownKey, errors.Cause(err))
+	})
+
+	t.Run("fails on malformed keys", func(t *testing.T) {
+		for _, document := range []string{
+			`{"keys": [{"kty": "RSA", "n": "!!", "e": "AQAB"}]}`,
+			`{"keys": [{"kty": "EC", "crv": "P-256", "x": "AQAB", "y": "AQAB"}]}`,
+			`{"keys": [{"kty": "oct", "k": ""}]}`,
+			`not json`,
+		} {
+			_, err := jwt.ParseJWKS([]byte(document))
+			assert.Error(t, err, document)
+		}
+	})
+}
@@ -0,0 +1,212 @@
+package jwt
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+type signingKey struct {
+	kid string
+	alg string
+	key interface{}
+}
+
+// KeySet is an in-memory KeySource holding keys loaded from a JWKS document
+// or added one by one from static configuration. Every key is bound to a single algorithm.
+type KeySet struct {
+	mutex sync.RWMutex
+	keys  []signingKey
+}
+
+func NewKeySet() *KeySet {
+	return &KeySet{}
+}
+
+// AddSecret adds a HS256 secret identified by 'kid' (which may be blank).
+func (s *KeySet) AddSecret(kid string, secret []byte) error {
+	if len(secret) == 0 {
+		return errors.New("secret should not be empty")
+	}
+	s.add(signingKey{kid: kid, alg: HS256, key: secret})
+	return nil
+}
+
+// AddPublicKeyPEM adds a PEM encoded RSA (used with RS256) or P-256 ECDSA (used with ES256)
+// public key identified by 'kid' (which may be blank).
+func (s *KeySet) AddPublicKeyPEM(kid string, data []byte) error {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return errors.New("public key is not PEM encoded")
+	}
+
+	parsed, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return errors.Wrap(err, "can't parse public key")
+	}
+
+	switch key := parsed.(type) {
+	case *rsa.PublicKey:
+		s.add(signingKey{kid: kid, alg: RS256, key: key})
+	case *ecdsa.PublicKey:
+		if key.Curve != elliptic.P256() {
+			return errors.New("only P-256 curve is supported for ECDSA keys")
+		}
+		s.add(signingKey{kid: kid, alg: ES256, key: key})
+	default:
+		return errors.Errorf("unsupported public key type %T", parsed)
+	}
+
+	return nil
+}
+
+// Len returns a number of keys in the set.
+func (s *KeySet) Len() int {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	return len(s.keys)
+}
+
+// Key returns a key of the algorithm identified by 'kid'. Tokens having 'kid' no key
+// is identified by, as well as tokens without one, are verified with the only key
+// of their algorithm not identified by any 'kid' (or the only key at all, for the latter).
+func (s *KeySet) Key(kid string, alg string) (interface{}, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	var identified, anonymous []signingKey
+	for _, key := range s.keys {
+		switch {
+		case key.alg != alg:
+		case kid != "" && key.kid == kid:
+			identified = append(identified, key)
+		case kid == "" || key.kid == "":
+			anonymous = append(anonymous, key)
+		}
+	}
+
+	found := identified
+	if len(found) == 0 {
+		found = anonymous
+	}
+
+	if len(found) != 1 {
+		return nil, errors.Wrapf(ErrUnknownKey, "kid %q, alg %s", kid, alg)
+	}
+	return found[0].key, nil
+}
+
+func (s *KeySet) add(key signingKey) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.keys = append(s.keys, key)
+}
+
+// jwk is a JSON Web Key as defined by RFC 7517
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Alg string `json:"alg"`
+	Use string `json:"use"`
+	K   string `json:"k"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// LoadJWKS reads a JWKS document from the file and builds a KeySet out of it.
+func LoadJWKS(path string) (*KeySet, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "can't read JWKS file")
+	}
+	return ParseJWKS(data)
+}
+
+// ParseJWKS builds a KeySet out of a JWKS document. Keys of types other than
+// 'oct', 'RSA' and 'EC' (P-256), as well as keys meant for encryption, are skipped.
+func ParseJWKS(data []byte) (*KeySet, error) {
+	var document struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &document); err != nil {
+		return nil, errors.Wrap(err, "can't decode JWKS document")
+	}
+
+	set := NewKeySet()
+	for index, key := range document.Keys {
+		if key.Use != "" && key.Use != "sig" {
+			continue
+		}
+
+		parsed, err := parseJWK(key)
+		if err != nil {
+			return nil, errors.Wrapf(err, "keys[%d]", index)
+		}
+
+		if parsed.alg == "" {
+			continue
+		}
+		set.add(parsed)
+	}
+
+	return set, nil
+}
+
+// parseJWK converts JWK into a signing key. Keys of unsupported types
+// are returned without algorithm.
+func parseJWK(key jwk) (signingKey, error) {
+	parsed := signingKey{kid: key.Kid}
+	switch key.Kty {
+	case "oct":
+		secret, err := base64.RawURLEncoding.DecodeString(key.K)
+		if err != nil || len(secret) == 0 {
+			return parsed, errors.New("invalid symmetric key")
+		}
+		parsed.alg, parsed.key = HS256, secret
+	case "RSA":
+		n, errN := decodeInt(key.N)
+		e, errE := decodeInt(key.E)
+		if errN != nil || errE != nil || !e.IsInt64() {
+			return parsed, errors.New("invalid RSA key")
+		}
+		parsed.alg, parsed.key = RS256, &rsa.PublicKey{N: n, E: int(e.Int64())}
+	case "EC":
+		if key.Crv != "P-256" {
+			return parsed, nil
+		}
+
+		x, errX := decodeInt(key.X)
+		y, errY := decodeInt(key.Y)
+		if errX != nil || errY != nil || !elliptic.P256().IsOnCurve(x, y) {
+			return parsed, errors.New("invalid EC key")
+		}
+		parsed.alg, parsed.key = ES256, &ecdsa.PublicKey{Curve: elliptic.P256(), X: x, Y: y}
+	default:
+		return parsed, nil
+	}
+
+	if key.Alg != "" && key.Alg != parsed.alg {
+		return signingKey{kid: key.Kid}, nil
+	}
+	return parsed, nil
+}
+
+func decodeInt(value string) (*big.Int, error) {
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil || len(data) == 0 {
+		return nil, errors.New("invalid number")
+	}
+	return new(big.Int).SetBytes(data), nil
+}
@@ -0,0 +1,249 @@
+package memstorage
+
+import (
+	"context"
+	"database/sql"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+var (
+	errDeadlock   = errors.New("deadlock detected")
+	errReadOnlyTx = errors.New("cannot execute statement in a read-only transaction")
+)
+
+// database is a state shared by all MemStorage instances created from
+// the same NewMemStorage call. Its mutex guards every field, row locks
+// are waited for with the help of released condition.
+type database struct {
+	mutex     sync.Mutex
+	released  *sync.Cond
+	committed *state
+	version   int
+	sequences map[string]int
+	locks     map[string]*memTx
+	waiting   map[*memTx]string
+}
+
+func newDatabase() *database {
+	db := &database{
+		committed: newState(),
+		sequences: make(map[string]int),
+		locks:     make(map[string]*memTx),
+		waiting:   make(map[*memTx]string),
+	}
+	db.released = sync.NewCond(&db.mutex)
+	return db
+}
+
+// nextID emulates a serial column sequence. Just like in PostgreSQL, values
+// are not returned back on transaction rollback.
+func (db *database) nextID(table string) int {
+	db.sequences[table]++
+	return db.sequences[table]
+}
+
+// memTx is a database transaction. Changes it makes are kept in a journal
+// and are replayed on top of the committed state whenever the transaction
+// reads data, so that it sees both its own changes and changes committed
+// by others (which mimics read committed isolation level). Transactions
+// with repeatable read (or stricter) isolation level keep seeing the state
+// committed by the time of their first read instead.
+type memTx struct {
+	startedAt  time.Time
+	journal    []func(*state) error
+	view       *state
+	version    int
+	done       bool
+	readOnly   bool
+	repeatable bool
+	base       *state
+
+	// rows which deferred constraints are checked for on commit
+	createdTransactions map[int]bool
+	updatedAccounts     map[int]bool
+}
+
+func newTx(opts *sql.TxOptions) *memTx {
+	tx := &memTx{
+		startedAt:           now(),
+		createdTransactions: make(map[int]bool),
+		updatedAccounts:     make(map[int]bool),
+	}
+
+	if opts != nil {
+		tx.readOnly = opts.ReadOnly
+		tx.repeatable = opts.Isolation >= sql.LevelRepeatableRead
+	}
+
+	return tx
+}
+
+// now returns current time with the precision PostgreSQL keeps timestamps with
+func now() time.Time {
+	return time.Now().UTC().Truncate(time.Microsecond)
+}
+
+// snapshot returns the state as it is seen by the transaction
+func (db *database) snapshot(tx *memTx) (*state, error) {
+	if tx.view != nil && (tx.version == db.version || tx.base != nil) {
+		return tx.view, nil
+	}
+
+	base := db.committed
+	if tx.repeatable {
+		if tx.base == nil {
+			tx.base = db.committed
+		}
+		base = tx.base
+	}
+
+	view := base.clone()
+	for _, change := range tx.journal {
+		if err := change(view); err != nil {
+			return nil, err
+		}
+	}
+
+	tx.view, tx.version = view, db.version
+	return view, nil
+}
+
+// apply makes a change within the transaction. The change is checked
+// against current view immediately and is kept to be replayed later.
+func (db *database) apply(tx *memTx, change func(*state) error) error {
+	view, err := db.snapshot(tx)
+	if err != nil {
+		return err
+	}
+
+	if err := change(view); err != nil {
+		// the view could have been partially changed, so it is rebuilt on the next access
+		tx.view = nil
+		return err
+	}
+
+	tx.journal = append(tx.journal, change)
+	return nil
+}
+
+// commit checks deferred constraints and publishes changes of the transaction
+func (db *database) commit(tx *memTx) error {
+	defer db.finish(tx)
+
+	// changes are published on top of the latest committed state
+	tx.view, tx.base, tx.repeatable = nil, nil, false
+	view, err := db.snapshot(tx)
+	if err != nil {
+		return err
+	}
+
+	for id := range tx.createdTransactions {
+		if err := view.checkTransactionBalanced(id); err != nil {
+			return err
+		}
+	}
+
+	for id := range tx.updatedAccounts {
+		if err := view.checkAccountBalanced(id); err != nil {
+			return err
+		}
+	}
+
+	db.committed = view
+	db.version++
+	return nil
+}
+
+// finish releases row locks held by the transaction
+func (db *database) finish(tx *memTx) {
+	tx.done = true
+	tx.journal, tx.view = nil, nil
+
+	for key, holder := range db.locks {
+		if holder == tx {
+			delete(db.locks, key)
+		}
+	}
+	db.released.Broadcast()
+}
+
+// rowKey identifies a table row to be locked
+func rowKey(table string, id int) string {
+	return table + ":" + strconv.Itoa(id)
+}
+
+// uniqueKey identifies a unique index entry to be locked
+func uniqueKey(index string, value string) string {
+	return index + ":" + value
+}
+
+// lock obtains an exclusive lock of the key (a row or a unique index entry) for the
+// transaction, waiting until other transaction holding it finishes. Fails if waiting would never end.
+func (db *database) lock(ctx context.Context, tx *memTx, key string) error {
+
+	stop := make(chan struct{})
+	defer close(stop)
+	go func() {
+		select {
+		case <-ctx.Done():
+			db.mutex.Lock()
+			db.released.Broadcast()
+			db.mutex.Unlock()
+		case <-stop:
+		}
+	}()
+
+	for {
+		holder, locked := db.locks[key]
+		if !locked || holder == tx {
+			db.locks[key] = tx
+			return nil
+		}
+
+		if db.waitsFor(holder, tx) {
+			return errors.Wrapf(errDeadlock, "can't lock %s", key)
+		}
+
+		if err := ctx.Err(); err != nil {
+			return errors.Wrapf(err, "can't lock %s", key)
+		}
+
+		db.waiting[tx] = key
+		db.released.Wait()
+		delete(db.waiting, tx)
+	}
+}
+
+// waitsFor checks whether the transaction is (indirectly) waiting for another one
+func (db *database) waitsFor(tx *memTx, another *memTx) bool {
+	for current := tx; current != nil; {
+		if current == another {
+			return true
+		}
+
+		key, waiting := db.waiting[current]
+		if !waiting {
+			return false
+		}
+		current = db.locks[key]
+	}
+	return false
+}
+
+func checkTxActive(tx *memTx) error {
+	if tx.done {
+		return sql.ErrTxDone
+	}
+	return nil
+}
+
+func checkTxWritable(tx *memTx) error {
+	if tx.readOnly {
+		return errReadOnlyTx
+	}
+	return nil
+}
@@ -0,0 +1,132 @@
+package memstorage
+
+import (
+	"sort"
+
+	"github.com/twonegatives/coinsph_challenge/pkg/entities"
+)
+
+// accountFollows checks whether the account is placed after the cursor
+// in the list ordered as the filter requires
+func accountFollows(filter entities.AccountsFilter, cursor entities.AccountsCursor, account entities.Account) bool {
+	var comparison int
+	switch filter.Sort {
+	case entities.SortAccountsByBalance:
+		comparison = account.Balance.Cmp(cursor.Balance)
+	case entities.SortAccountsByCreatedAt:
+		comparison = compareInts(account.CreatedAt.UnixNano(), cursor.CreatedAt.UnixNano())
+	default:
+		comparison = compareStrings(account.Name, cursor.Name)
+	}
+
+	if comparison == 0 {
+		comparison = compareInts(int64(account.ID), int64(cursor.ID))
+	}
+
+	if filter.Descending {
+		return comparison < 0
+	}
+	return comparison > 0
+}
+
+// paymentMatchesFilter checks the payment against direction, kind, reference, date and amount
+// restrictions of the filter, as well as its cursor
+func paymentMatchesFilter(payment entities.Payment, filter entities.PaymentsFilter) bool {
+	if filter.Direction != "" && payment.Direction != filter.Direction {
+		return false
+	}
+
+	if filter.Kind != "" && payment.Transaction.Kind != filter.Kind {
+		return false
+	}
+
+	if filter.Reference != "" && payment.Transaction.ExternalReference != filter.Reference {
+		return false
+	}
+
+	createdAt := payment.Transaction.CreatedAt
+	if !filter.FromDate.IsZero() && createdAt.Before(filter.FromDate) {
+		return false
+	}
+
+	if !filter.ToDate.IsZero() && !createdAt.Before(filter.ToDate) {
+		return false
+	}
+
+	if filter.MinAmount.Valid && payment.Amount.LessThan(filter.MinAmount.Decimal) {
+		return false
+	}
+
+	if filter.MaxAmount.Valid && payment.Amount.GreaterThan(filter.MaxAmount.Decimal) {
+		return false
+	}
+
+	if cursor := filter.After; cursor != nil {
+		if createdAt.Before(cursor.CreatedAt) || createdAt.Equal(cursor.CreatedAt) && payment.ID <= cursor.ID {
+			return false
+		}
+	}
+
+	return true
+}
+
+// auditEntryMatches checks the entry against subject, action, correlation id, outcome
+// and date restrictions of the filter, as well as its cursor
+func auditEntryMatches(filter entities.AuditFilter, entry entities.AuditEntry) bool {
+	if filter.Subject != "" && entry.Subject != filter.Subject {
+		return false
+	}
+
+	if filter.Action != "" && entry.Action != filter.Action {
+		return false
+	}
+
+	if filter.CorrelationID != "" && entry.CorrelationID != filter.CorrelationID {
+		return false
+	}
+
+	if filter.Outcome != "" && entry.Outcome != filter.Outcome {
+		return false
+	}
+
+	if !filter.FromDate.IsZero() && entry.CreatedAt.Before(filter.FromDate) {
+		return false
+	}
+
+	if !filter.ToDate.IsZero() && !entry.CreatedAt.Before(filter.ToDate) {
+		return false
+	}
+
+	return filter.After == nil || entry.ID > filter.After.ID
+}
+
+// sortPayments orders payments by time of their transactions and IDs
+func sortPayments(payments []entities.Payment) {
+	sort.Slice(payments, func(i, j int) bool {
+		left, right := payments[i], payments[j]
+		if !left.Transaction.CreatedAt.Equal(right.Transaction.CreatedAt) {
+			return left.Transaction.CreatedAt.Before(right.Transaction.CreatedAt)
+		}
+		return left.ID < right.ID
+	})
+}
+
+func compareStrings(left, right string) int {
+	switch {
+	case left < right:
+		return -1
+	case left > right:
+		return 1
+	}
+	return 0
+}
+
+func compareInts(left, right int64) int {
+	switch {
+	case left < right:
+		return -1
+	case left > right:
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,1091 @@
+// Package memstorage is an in-memory implementation of storage interface.
+// It emulates transactions, row locks and constraints of PostgreSQL schema
+// and is meant to be used in tests and local development.
+package memstorage
+
+import (
+	"context"
+	"database/sql"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/shopspring/decimal"
+	"github.com/twonegatives/coinsph_challenge/pkg/entities"
+	"github.com/twonegatives/coinsph_challenge/pkg/storage"
+)
+
+const (
+	accountsTable     = "accounts"
+	transactionsTable = "transactions"
+	paymentsTable     = "payments"
+	quotesTable       = "fx_quotes"
+	holdsTable        = "holds"
+	apiKeysTable      = "api_keys"
+	auditLogTable     = "audit_log"
+
+	scheduledPaymentsTable = "scheduled_payments"
+	scheduledRunsTable     = "scheduled_payment_runs"
+
+	creditLimitChangesTable = "credit_limit_changes"
+
+	accountsNameIndex                = "accounts_name_key"
+	apiKeysKeyIndex                  = "api_keys_key_id_key"
+	idempotencyKeysIndex             = "idempotency_keys_pkey"
+	transactionsReferenceIndex       = "transactions_kind_external_reference_idx"
+	transactionsSenderReferenceIndex = "transactions_sender_external_reference_idx"
+
+	// auditChainLock stands for the advisory lock appends to the audit log are serialized with
+	auditChainLock = "audit_log_chain"
+)
+
+// MemStorage is an implementation of Storage interface.
+// It is safe for concurrent use, while storages returned by BeginTx
+// should not be shared between goroutines (just like *sql.Tx).
+type MemStorage struct {
+	db *database
+	tx *memTx
+}
+
+// NewMemStorage returns an empty storage with SYSTEM, FX and FEE accounts
+// seeded for each of the default currencies, as migrations do.
+func NewMemStorage() *MemStorage {
+	s := &MemStorage{db: newDatabase()}
+
+	seeds := []entities.Account{{Name: entities.SystemAccountName(entities.USD), Type: entities.SystemAccount, Currency: entities.USD}}
+	for _, currency := range entities.DefaultCurrencyRegistry().Currencies() {
+		if currency.Code != entities.USD {
+			seeds = append(seeds, entities.Account{Name: entities.SystemAccountName(currency.Code), Type: entities.SystemAccount, Currency: currency.Code})
+		}
+	}
+	for _, currency := range entities.DefaultCurrencyRegistry().Currencies() {
+		seeds = append(seeds, entities.Account{Name: entities.FXAccountName(currency.Code), Type: entities.SettlementAccount, Currency: currency.Code})
+	}
+	for _, currency := range entities.DefaultCurrencyRegistry().Currencies() {
+		seeds = append(seeds, entities.Account{Name: entities.FeeAccountName(currency.Code), Type: entities.FeeAccount, Currency: currency.Code})
+	}
+
+	seededAt := now()
+	for _, account := range seeds {
+		account.ID = s.db.nextID(accountsTable)
+		account.Balance = decimal.New(0, 0)
+		account.Held = decimal.New(0, 0)
+		account.CreditLimit = decimal.New(0, 0)
+		account.Status = entities.ActiveAccount
+		account.CreatedAt = seededAt
+		s.db.committed.accounts[account.ID] = account
+	}
+
+	return s
+}
+
+// BeginTx starts a transaction and returns a new Storage implementation bound to it
+func (s *MemStorage) BeginTx(ctx context.Context, opts *sql.TxOptions) (storage.Storage, error) {
+	if s.tx != nil {
+		return nil, errors.New("handler doesn't satisfy the interface TransactionBeginner")
+	}
+
+	return &MemStorage{db: s.db, tx: newTx(opts)}, nil
+}
+
+// CommitTx checks deferred constraints and commits the current transaction
+func (s *MemStorage) CommitTx(ctx context.Context) error {
+	if s.tx == nil {
+		return errors.New("nothing to commit, transaction is not started")
+	}
+
+	s.db.mutex.Lock()
+	defer s.db.mutex.Unlock()
+
+	if err := checkTxActive(s.tx); err != nil {
+		return errors.Wrap(err, "failed to commit db transaction")
+	}
+
+	return errors.Wrap(s.db.commit(s.tx), "failed to commit db transaction")
+}
+
+// RollbackTx discards changes of the current transaction
+func (s *MemStorage) RollbackTx(ctx context.Context) error {
+	if s.tx == nil {
+		return errors.New("nothing to rollback, transaction is not started")
+	}
+
+	s.db.mutex.Lock()
+	defer s.db.mutex.Unlock()
+
+	if err := checkTxActive(s.tx); err != nil {
+		return errors.Wrap(err, "failed to rollback db transaction")
+	}
+
+	s.db.finish(s.tx)
+	return nil
+}
+
+// read runs the function over the state visible to the storage
+func (s *MemStorage) read(fn func(*state) error) error {
+	s.db.mutex.Lock()
+	defer s.db.mutex.Unlock()
+
+	if s.tx == nil {
+		return fn(s.db.committed)
+	}
+
+	if err := checkTxActive(s.tx); err != nil {
+		return err
+	}
+
+	view, err := s.db.snapshot(s.tx)
+	if err != nil {
+		return err
+	}
+	return fn(view)
+}
+
+// write runs the function within the current transaction.
+// Storages without transaction run it within a new one, which is committed right away.
+func (s *MemStorage) write(fn func(*memTx) error) error {
+	s.db.mutex.Lock()
+	defer s.db.mutex.Unlock()
+
+	if s.tx != nil {
+		if err := checkTxActive(s.tx); err != nil {
+			return err
+		}
+		if err := checkTxWritable(s.tx); err != nil {
+			return err
+		}
+		return fn(s.tx)
+	}
+
+	tx := newTx(nil)
+	if err := fn(tx); err != nil {
+		s.db.finish(tx)
+		return err
+	}
+	return s.db.commit(tx)
+}
+
+// CreateAccount accepts an Account with name, currency and profile filled in and tries to
+// create a new account with such attributes and zero balance. Returns the created
+// Account object on success.
+func (s *MemStorage) CreateAccount(ctx context.Context, account entities.Account) (entities.Account, error) {
+	err := s.write(func(tx *memTx) error {
+		// just like unique index does, concurrent insertions of the same name wait for each other
+		if err := s.db.lock(ctx, tx, uniqueKey(accountsNameIndex, account.Name)); err != nil {
+			return err
+		}
+
+		if account.Type == "" {
+			account.Type = entities.UserAccount
+		}
+
+		account.ID = s.db.nextID(accountsTable)
+		account.Balance = decimal.New(0, 0)
+		account.Held = decimal.New(0, 0)
+		account.CreditLimit = decimal.New(0, 0)
+		account.Status = entities.ActiveAccount
+		account.CreatedAt = tx.startedAt
+
+		created := account
+		return s.db.apply(tx, func(st *state) error {
+			return st.insertAccount(created)
+		})
+	})
+	return account, errors.Wrap(err, "can't create new account")
+}
+
+// GetAccount returns an Account found by its name
+func (s *MemStorage) GetAccount(ctx context.Context, name string) (entities.Account, error) {
+	var account entities.Account
+	err := s.read(func(st *state) error {
+		found, ok := st.accountByName(name)
+		if !ok {
+			return errors.Wrapf(storage.ErrNotFound, "account %s", name)
+		}
+		account = found
+		return nil
+	})
+	return account, errors.Wrapf(err, "can't obtain account %s", name)
+}
+
+// GetAccountsList returns slice of Accounts matching the filter in requested order
+func (s *MemStorage) GetAccountsList(ctx context.Context, filter entities.AccountsFilter) ([]entities.Account, error) {
+	accounts := []entities.Account{}
+	err := s.read(func(st *state) error {
+		for _, account := range st.accounts {
+			if !strings.HasPrefix(account.Name, filter.NamePrefix) {
+				continue
+			}
+
+			if filter.OwnerID != "" && account.OwnerID != filter.OwnerID {
+				continue
+			}
+
+			if filter.After != nil && !accountFollows(filter, *filter.After, account) {
+				continue
+			}
+
+			accounts = append(accounts, account)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "can't query Accounts list")
+	}
+
+	sort.Slice(accounts, func(i, j int) bool {
+		return accountFollows(filter, entities.NewAccountsCursor(filter, accounts[i]), accounts[j])
+	})
+
+	if filter.Limit > 0 && len(accounts) > filter.Limit {
+		accounts = accounts[:filter.Limit]
+	}
+	return accounts, nil
+}
+
+// GetPaymentsList returns slice of Payments matching the filter ordered by
+// time of their transactions and IDs
+func (s *MemStorage) GetPaymentsList(ctx context.Context, filter entities.PaymentsFilter) ([]entities.Payment, error) {
+	payments, err := s.filterPayments(filter, func(payment entities.Payment) bool {
+		return filter.AccountName == "" || payment.Account.Name == filter.AccountName
+	})
+	return payments, errors.Wrap(err, "can't query Payments list")
+}
+
+// GetAccountPayments returns slice of Payments of the account matching the filter.
+// AccountName of the filter is not taken into account.
+func (s *MemStorage) GetAccountPayments(ctx context.Context, accountID int, filter entities.PaymentsFilter) ([]entities.Payment, error) {
+	payments, err := s.filterPayments(filter, func(payment entities.Payment) bool {
+		return payment.Account.ID == accountID
+	})
+	return payments, errors.Wrap(err, "can't query Payments list")
+}
+
+func (s *MemStorage) filterPayments(filter entities.PaymentsFilter, matches func(entities.Payment) bool) ([]entities.Payment, error) {
+	payments := []entities.Payment{}
+	err := s.read(func(st *state) error {
+		for id := range st.payments {
+			payment := st.payment(id)
+			if matches(payment) && paymentMatchesFilter(payment, filter) {
+				payments = append(payments, payment)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sortPayments(payments)
+	if filter.Limit > 0 && len(payments) > filter.Limit {
+		payments = payments[:filter.Limit]
+	}
+	return payments, nil
+}
+
+// GetTransaction returns a Transaction found by its ID
+func (s *MemStorage) GetTransaction(ctx context.Context, id int) (entities.Transaction, error) {
+	var transaction entities.Transaction
+	err := s.read(func(st *state) error {
+		found, ok := st.transactions[id]
+		if !ok {
+			return errors.Wrapf(storage.ErrNotFound, "transaction %d", id)
+		}
+		transaction = found
+		return nil
+	})
+	return transaction, errors.Wrapf(err, "can't obtain transaction %d", id)
+}
+
+// GetLinkedTransactions returns reversals and refunds of the transaction in order of their creation
+func (s *MemStorage) GetLinkedTransactions(ctx context.Context, originalID int) ([]entities.Transaction, error) {
+	transactions := []entities.Transaction{}
+	err := s.read(func(st *state) error {
+		for _, transaction := range st.transactions {
+			if transaction.OriginalID == originalID {
+				transactions = append(transactions, transaction)
+			}
+		}
+		return nil
+	})
+
+	sort.Slice(transactions, func(i, j int) bool {
+		return transactions[i].ID < transactions[j].ID
+	})
+	return transactions, errors.Wrapf(err, "can't query transactions linked to %d", originalID)
+}
+
+// GetTransactionPayments returns slice of Payments booked within the Transaction
+// in order of their creation
+func (s *MemStorage) GetTransactionPayments(ctx context.Context, transactionID int) ([]entities.Payment, error) {
+	payments := []entities.Payment{}
+	err := s.read(func(st *state) error {
+		for id, payment := range st.payments {
+			if payment.Transaction.ID == transactionID {
+				payments = append(payments, st.payment(id))
+			}
+		}
+		return nil
+	})
+
+	sortPayments(payments)
+	return payments, errors.Wrap(err, "can't query Payments list")
+}
+
+// GetAccountForUpdate fills in Account entity found by its name and locks it
+// until the end of transaction
+func (s *MemStorage) GetAccountForUpdate(ctx context.Context, account *entities.Account) error {
+	err := s.write(func(tx *memTx) error {
+		view, err := s.db.snapshot(tx)
+		if err != nil {
+			return err
+		}
+
+		found, ok := view.accountByName(account.Name)
+		if !ok {
+			return sql.ErrNoRows
+		}
+
+		if err := s.db.lock(ctx, tx, rowKey(accountsTable, found.ID)); err != nil {
+			return err
+		}
+
+		// the row could have been changed while the lock was awaited
+		if view, err = s.db.snapshot(tx); err != nil {
+			return err
+		}
+
+		*account = view.accounts[found.ID]
+		return nil
+	})
+	return errors.Wrapf(err, "can't obtain account %s", account.Name)
+}
+
+// CreateTransaction creates a Transaction entity of the given kind (transfer unless set),
+// external reference, original transaction, fee, description and metadata. Returns ErrAlreadyExists
+// if the reference was already used by the sender, or by a transaction of the same kind for references without sender.
+func (s *MemStorage) CreateTransaction(ctx context.Context, transaction entities.Transaction) (entities.Transaction, error) {
+	if transaction.Kind == "" {
+		transaction.Kind = entities.TransferTransaction
+	}
+
+	err := s.write(func(tx *memTx) error {
+		if transaction.ExternalReference != "" {
+			key := uniqueKey(transactionsReferenceIndex, string(transaction.Kind)+":"+transaction.ExternalReference)
+			if transaction.SenderID != 0 {
+				key = uniqueKey(transactionsSenderReferenceIndex, strconv.Itoa(transaction.SenderID)+":"+transaction.ExternalReference)
+			}
+
+			if err := s.db.lock(ctx, tx, key); err != nil {
+				return err
+			}
+		}
+
+		transaction.ID = s.db.nextID(transactionsTable)
+		transaction.CreatedAt = tx.startedAt
+		tx.createdTransactions[transaction.ID] = true
+
+		created := transaction
+		return s.db.apply(tx, func(st *state) error {
+			return st.insertTransaction(created)
+		})
+	})
+	return transaction, errors.Wrap(err, "can't insert new transaction")
+}
+
+// SendPayment creates a single Payment entity.
+// It is expected to be called two times per each Transaction.
+func (s *MemStorage) SendPayment(ctx context.Context, payment entities.Payment) error {
+	err := s.write(func(tx *memTx) error {
+		payment.ID = s.db.nextID(paymentsTable)
+		return s.db.apply(tx, func(st *state) error {
+			return st.insertPayment(payment)
+		})
+	})
+	return errors.Wrapf(err, "can't insert %s payment", payment.Direction)
+}
+
+// SetAccountBalance takes a single Account entity and updates the stored
+// account with balance equal to incoming Account entity's balance
+func (s *MemStorage) SetAccountBalance(ctx context.Context, account entities.Account) error {
+	err := s.write(func(tx *memTx) error {
+		if err := s.db.lock(ctx, tx, rowKey(accountsTable, account.ID)); err != nil {
+			return err
+		}
+
+		tx.updatedAccounts[account.ID] = true
+		return s.db.apply(tx, func(st *state) error {
+			return st.setAccountBalance(account.ID, account.Balance)
+		})
+	})
+	return errors.Wrapf(err, "can't update balance of %s", account.Name)
+}
+
+// SetAccountCreditLimit updates credit limit of the account
+func (s *MemStorage) SetAccountCreditLimit(ctx context.Context, account entities.Account) error {
+	err := s.write(func(tx *memTx) error {
+		if err := s.db.lock(ctx, tx, rowKey(accountsTable, account.ID)); err != nil {
+			return err
+		}
+
+		return s.db.apply(tx, func(st *state) error {
+			return st.setAccountCreditLimit(account.ID, account.CreditLimit)
+		})
+	})
+	return errors.Wrapf(err, "can't update credit limit of %s", account.Name)
+}
+
+// SetAccountStatus updates status of the account
+func (s *MemStorage) SetAccountStatus(ctx context.Context, account entities.Account) error {
+	err := s.write(func(tx *memTx) error {
+		if err := s.db.lock(ctx, tx, rowKey(accountsTable, account.ID)); err != nil {
+			return err
+		}
+
+		return s.db.apply(tx, func(st *state) error {
+			return st.setAccountStatus(account.ID, account.Status)
+		})
+	})
+	return errors.Wrapf(err, "can't update status of %s", account.Name)
+}
+
+// SetAccountHeld updates the amount reserved on the account by pending holds
+func (s *MemStorage) SetAccountHeld(ctx context.Context, account entities.Account) error {
+	err := s.write(func(tx *memTx) error {
+		if err := s.db.lock(ctx, tx, rowKey(accountsTable, account.ID)); err != nil {
+			return err
+		}
+
+		return s.db.apply(tx, func(st *state) error {
+			return st.setAccountHeld(account.ID, account.Held)
+		})
+	})
+	return errors.Wrapf(err, "can't update held amount of %s", account.Name)
+}
+
+// SetAccountProfile updates owner, display name and labels of the account
+func (s *MemStorage) SetAccountProfile(ctx context.Context, account entities.Account) error {
+	err := s.write(func(tx *memTx) error {
+		if err := s.db.lock(ctx, tx, rowKey(accountsTable, account.ID)); err != nil {
+			return err
+		}
+
+		return s.db.apply(tx, func(st *state) error {
+			return st.setAccountProfile(account.ID, account.Profile())
+		})
+	})
+	return errors.Wrapf(err, "can't update profile of %s", account.Name)
+}
+
+// CreateCreditLimitChange persists an audit record of credit limit change.
+// Returns the record with ID and creation time set up on success.
+func (s *MemStorage) CreateCreditLimitChange(ctx context.Context, change entities.CreditLimitChange) (entities.CreditLimitChange, error) {
+	err := s.write(func(tx *memTx) error {
+		change.ID = s.db.nextID(creditLimitChangesTable)
+		change.CreatedAt = tx.startedAt
+
+		created := change
+		return s.db.apply(tx, func(st *state) error {
+			return st.insertCreditLimitChange(created)
+		})
+	})
+	return change, errors.Wrap(err, "can't insert credit limit change")
+}
+
+// GetCreditLimitChanges returns credit limit changes of the account, oldest first
+func (s *MemStorage) GetCreditLimitChanges(ctx context.Context, accountID int) ([]entities.CreditLimitChange, error) {
+	changes := []entities.CreditLimitChange{}
+	err := s.read(func(st *state) error {
+		for _, change := range st.creditLimitChanges {
+			if change.AccountID == accountID {
+				changes = append(changes, change)
+			}
+		}
+		return nil
+	})
+
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].ID < changes[j].ID
+	})
+	return changes, errors.Wrap(err, "can't query credit limit changes")
+}
+
+// CreateHold persists a pending Hold of the amount on the account.
+// Returns the Hold with ID and creation time set up on success.
+func (s *MemStorage) CreateHold(ctx context.Context, hold entities.Hold) (entities.Hold, error) {
+	err := s.write(func(tx *memTx) error {
+		hold.ID = s.db.nextID(holdsTable)
+		hold.CreatedAt = tx.startedAt
+
+		created := hold
+		return s.db.apply(tx, func(st *state) error {
+			return st.insertHold(created)
+		})
+	})
+	return hold, errors.Wrap(err, "can't insert new hold")
+}
+
+// GetHold returns a Hold found by its ID
+func (s *MemStorage) GetHold(ctx context.Context, id int) (entities.Hold, error) {
+	var hold entities.Hold
+	err := s.read(func(st *state) error {
+		if _, ok := st.holds[id]; !ok {
+			return errors.Wrapf(storage.ErrNotFound, "hold %d", id)
+		}
+		hold = st.hold(id)
+		return nil
+	})
+	return hold, errors.Wrapf(err, "can't obtain hold %d", id)
+}
+
+// GetExpiredHolds returns up to limit pending Holds which expired by now, oldest expiry first
+func (s *MemStorage) GetExpiredHolds(ctx context.Context, now time.Time, limit int) ([]entities.Hold, error) {
+	holds := []entities.Hold{}
+	err := s.read(func(st *state) error {
+		for id, hold := range st.holds {
+			if hold.Status == entities.PendingHold && hold.IsExpired(now) {
+				holds = append(holds, st.hold(id))
+			}
+		}
+		return nil
+	})
+
+	sort.Slice(holds, func(i, j int) bool {
+		if !holds[i].ExpiresAt.Equal(holds[j].ExpiresAt) {
+			return holds[i].ExpiresAt.Before(holds[j].ExpiresAt)
+		}
+		return holds[i].ID < holds[j].ID
+	})
+
+	if limit > 0 && len(holds) > limit {
+		holds = holds[:limit]
+	}
+	return holds, errors.Wrap(err, "can't query holds")
+}
+
+// SetHoldStatus updates status of the Hold along with the transaction it was captured by
+func (s *MemStorage) SetHoldStatus(ctx context.Context, hold entities.Hold) error {
+	err := s.write(func(tx *memTx) error {
+		if err := s.db.lock(ctx, tx, rowKey(holdsTable, hold.ID)); err != nil {
+			return err
+		}
+
+		return s.db.apply(tx, func(st *state) error {
+			return st.setHoldStatus(hold.ID, hold.Status, hold.TransactionID)
+		})
+	})
+	return errors.Wrapf(err, "can't update status of hold %d", hold.ID)
+}
+
+// CreateScheduledPayment persists a ScheduledPayment from the account to the counterparty.
+// Returns the ScheduledPayment with ID and creation time set up on success.
+func (s *MemStorage) CreateScheduledPayment(ctx context.Context, payment entities.ScheduledPayment) (entities.ScheduledPayment, error) {
+	err := s.write(func(tx *memTx) error {
+		payment.ID = s.db.nextID(scheduledPaymentsTable)
+		payment.CreatedAt = tx.startedAt
+
+		created := payment
+		return s.db.apply(tx, func(st *state) error {
+			return st.insertScheduledPayment(created)
+		})
+	})
+	return payment, errors.Wrap(err, "can't insert new scheduled payment")
+}
+
+// GetScheduledPayment returns a ScheduledPayment found by its ID
+func (s *MemStorage) GetScheduledPayment(ctx context.Context, id int) (entities.ScheduledPayment, error) {
+	var payment entities.ScheduledPayment
+	err := s.read(func(st *state) error {
+		if _, ok := st.scheduledPayments[id]; !ok {
+			return errors.Wrapf(storage.ErrNotFound, "scheduled payment %d", id)
+		}
+		payment = st.scheduledPayment(id)
+		return nil
+	})
+	return payment, errors.Wrapf(err, "can't obtain scheduled payment %d", id)
+}
+
+// GetScheduledPayments returns ScheduledPayments sent from the account, or all of them
+// for zero accountID, in order of their creation
+func (s *MemStorage) GetScheduledPayments(ctx context.Context, accountID int) ([]entities.ScheduledPayment, error) {
+	payments := []entities.ScheduledPayment{}
+	err := s.read(func(st *state) error {
+		for id, payment := range st.scheduledPayments {
+			if accountID == 0 || payment.Account.ID == accountID {
+				payments = append(payments, st.scheduledPayment(id))
+			}
+		}
+		return nil
+	})
+
+	sort.Slice(payments, func(i, j int) bool {
+		return payments[i].ID < payments[j].ID
+	})
+	return payments, errors.Wrap(err, "can't query scheduled payments")
+}
+
+// GetScheduledPaymentForUpdate fills in ScheduledPayment entity found by its ID and locks it
+// until the end of transaction
+func (s *MemStorage) GetScheduledPaymentForUpdate(ctx context.Context, payment *entities.ScheduledPayment) error {
+	err := s.write(func(tx *memTx) error {
+		view, err := s.db.snapshot(tx)
+		if err != nil {
+			return err
+		}
+
+		if _, ok := view.scheduledPayments[payment.ID]; !ok {
+			return errors.Wrapf(storage.ErrNotFound, "scheduled payment %d", payment.ID)
+		}
+
+		if err := s.db.lock(ctx, tx, rowKey(scheduledPaymentsTable, payment.ID)); err != nil {
+			return err
+		}
+
+		if view, err = s.db.snapshot(tx); err != nil {
+			return err
+		}
+
+		*payment = view.scheduledPayment(payment.ID)
+		return nil
+	})
+	return errors.Wrapf(err, "can't obtain scheduled payment %d", payment.ID)
+}
+
+// UpdateScheduledPayment stores amount, status, run times, attempts and lease of the ScheduledPayment
+func (s *MemStorage) UpdateScheduledPayment(ctx context.Context, payment entities.ScheduledPayment) error {
+	err := s.write(func(tx *memTx) error {
+		if err := s.db.lock(ctx, tx, rowKey(scheduledPaymentsTable, payment.ID)); err != nil {
+			return err
+		}
+
+		return s.db.apply(tx, func(st *state) error {
+			return st.updateScheduledPayment(payment)
+		})
+	})
+	return errors.Wrapf(err, "can't update scheduled payment %d", payment.ID)
+}
+
+// ClaimScheduledPayments leases up to limit active ScheduledPayments which are due by now
+// to the owner until leaseUntil, earliest due first. Payments leased to others are skipped
+// until their lease expires, and so are the ones locked by other transactions.
+func (s *MemStorage) ClaimScheduledPayments(ctx context.Context, owner string, now time.Time, leaseUntil time.Time, limit int) ([]entities.ScheduledPayment, error) {
+	claimed := []entities.ScheduledPayment{}
+	err := s.write(func(tx *memTx) error {
+		view, err := s.db.snapshot(tx)
+		if err != nil {
+			return err
+		}
+
+		for id, payment := range view.scheduledPayments {
+			if holder, locked := s.db.locks[rowKey(scheduledPaymentsTable, id)]; locked && holder != tx {
+				continue
+			}
+
+			if payment.IsDue(now) && !payment.IsLeased(now) {
+				claimed = append(claimed, view.scheduledPayment(id))
+			}
+		}
+
+		sort.Slice(claimed, func(i, j int) bool {
+			if !claimed[i].DueAt().Equal(claimed[j].DueAt()) {
+				return claimed[i].DueAt().Before(claimed[j].DueAt())
+			}
+			return claimed[i].ID < claimed[j].ID
+		})
+
+		if limit > 0 && len(claimed) > limit {
+			claimed = claimed[:limit]
+		}
+
+		for i := range claimed {
+			claimed[i].LeaseOwner, claimed[i].LeaseExpiresAt = owner, leaseUntil
+			if err := s.db.lock(ctx, tx, rowKey(scheduledPaymentsTable, claimed[i].ID)); err != nil {
+				return err
+			}
+
+			leased := claimed[i]
+			if err := s.db.apply(tx, func(st *state) error { return st.updateScheduledPayment(leased) }); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "can't claim scheduled payments")
+	}
+	return claimed, nil
+}
+
+// CreateScheduledRun persists a record of a ScheduledPayment run.
+// Returns the record with ID and creation time set up on success.
+func (s *MemStorage) CreateScheduledRun(ctx context.Context, run entities.ScheduledRun) (entities.ScheduledRun, error) {
+	err := s.write(func(tx *memTx) error {
+		run.ID = s.db.nextID(scheduledRunsTable)
+		run.CreatedAt = tx.startedAt
+
+		created := run
+		return s.db.apply(tx, func(st *state) error {
+			return st.insertScheduledRun(created)
+		})
+	})
+	return run, errors.Wrap(err, "can't insert scheduled payment run")
+}
+
+// GetScheduledRuns returns runs of the ScheduledPayment, oldest first
+func (s *MemStorage) GetScheduledRuns(ctx context.Context, scheduleID int) ([]entities.ScheduledRun, error) {
+	runs := []entities.ScheduledRun{}
+	err := s.read(func(st *state) error {
+		for _, run := range st.scheduledRuns {
+			if run.ScheduleID == scheduleID {
+				runs = append(runs, run)
+			}
+		}
+		return nil
+	})
+
+	sort.Slice(runs, func(i, j int) bool {
+		return runs[i].ID < runs[j].ID
+	})
+	return runs, errors.Wrap(err, "can't query scheduled payment runs")
+}
+
+// CreateQuote persists an exchange rate Quote. Returns the Quote with ID
+// and creation time set up on success.
+func (s *MemStorage) CreateQuote(ctx context.Context, quote entities.Quote) (entities.Quote, error) {
+	err := s.write(func(tx *memTx) error {
+		quote.ID = s.db.nextID(quotesTable)
+		quote.CreatedAt = tx.startedAt
+		quote.TransactionID = 0
+
+		created := quote
+		return s.db.apply(tx, func(st *state) error {
+			return st.insertQuote(created)
+		})
+	})
+	return quote, errors.Wrap(err, "can't insert new quote")
+}
+
+// GetQuoteForUpdate fills in Quote entity found by its ID and locks it
+// until the end of transaction
+func (s *MemStorage) GetQuoteForUpdate(ctx context.Context, quote *entities.Quote) error {
+	err := s.write(func(tx *memTx) error {
+		view, err := s.db.snapshot(tx)
+		if err != nil {
+			return err
+		}
+
+		if _, ok := view.quotes[quote.ID]; !ok {
+			return errors.Wrapf(storage.ErrNotFound, "quote %d", quote.ID)
+		}
+
+		if err := s.db.lock(ctx, tx, rowKey(quotesTable, quote.ID)); err != nil {
+			return err
+		}
+
+		if view, err = s.db.snapshot(tx); err != nil {
+			return err
+		}
+
+		*quote = view.quotes[quote.ID]
+		return nil
+	})
+	return errors.Wrapf(err, "can't obtain quote %d", quote.ID)
+}
+
+// SetQuoteTransaction marks the Quote as used by the Transaction it is linked to
+func (s *MemStorage) SetQuoteTransaction(ctx context.Context, quote entities.Quote) error {
+	err := s.write(func(tx *memTx) error {
+		if err := s.db.lock(ctx, tx, rowKey(quotesTable, quote.ID)); err != nil {
+			return err
+		}
+
+		return s.db.apply(tx, func(st *state) error {
+			return st.setQuoteTransaction(quote.ID, quote.TransactionID)
+		})
+	})
+	return errors.Wrapf(err, "can't set transaction of quote %d", quote.ID)
+}
+
+// CreateIdempotencyRecord reserves an idempotency key for the request.
+// Returns false if the key is already reserved. Reservations which expired
+// before the request committed anything are taken over by requests of the same payload.
+func (s *MemStorage) CreateIdempotencyRecord(ctx context.Context, record entities.IdempotencyRecord) (bool, error) {
+	var created bool
+	err := s.write(func(tx *memTx) error {
+		if err := s.db.lock(ctx, tx, uniqueKey(idempotencyKeysIndex, record.Key)); err != nil {
+			return err
+		}
+
+		reserved := entities.IdempotencyRecord{
+			Key:         record.Key,
+			RequestHash: record.RequestHash,
+			LockID:      record.LockID,
+			CreatedAt:   tx.startedAt,
+			LockedUntil: record.LockedUntil,
+		}
+		return s.db.apply(tx, func(st *state) error {
+			created = st.reserveIdempotencyRecord(reserved)
+			return nil
+		})
+	})
+	return created, errors.Wrap(err, "can't insert idempotency key")
+}
+
+// GetIdempotencyRecord returns a record stored for the idempotency key
+func (s *MemStorage) GetIdempotencyRecord(ctx context.Context, key string) (entities.IdempotencyRecord, error) {
+	var record entities.IdempotencyRecord
+	err := s.read(func(st *state) error {
+		found, ok := st.idempotencyRecords[key]
+		if !ok {
+			return errors.Wrapf(storage.ErrNotFound, "idempotency key %s", key)
+		}
+		record = found
+		return nil
+	})
+	return record, errors.Wrapf(err, "can't obtain idempotency key %s", key)
+}
+
+// CommitIdempotencyRecord marks the reservation of the key as committed along with the
+// transaction booked by the request. It is meant to be called within the storage transaction
+// committing the request changes. Returns ErrAlreadyExists if the reservation was taken over
+// or committed before.
+func (s *MemStorage) CommitIdempotencyRecord(ctx context.Context, record entities.IdempotencyRecord) error {
+	err := s.write(func(tx *memTx) error {
+		if err := s.db.lock(ctx, tx, uniqueKey(idempotencyKeysIndex, record.Key)); err != nil {
+			return err
+		}
+
+		committedAt := tx.startedAt
+		return s.db.apply(tx, func(st *state) error {
+			stored, ok := st.idempotencyRecords[record.Key]
+			if !ok || stored.LockID != record.LockID || stored.IsCommitted() {
+				return errors.Wrapf(storage.ErrAlreadyExists, "committed request with idempotency key %s", record.Key)
+			}
+
+			stored.TransactionID = record.TransactionID
+			stored.CommittedAt = committedAt
+			st.idempotencyRecords[record.Key] = stored
+			return nil
+		})
+	})
+	return errors.Wrapf(err, "can't commit idempotency key %s", record.Key)
+}
+
+// CompleteIdempotencyRecord stores the response of a request made with the idempotency key.
+// Records reserved by other requests since then are left intact.
+func (s *MemStorage) CompleteIdempotencyRecord(ctx context.Context, record entities.IdempotencyRecord) error {
+	err := s.write(func(tx *memTx) error {
+		if err := s.db.lock(ctx, tx, uniqueKey(idempotencyKeysIndex, record.Key)); err != nil {
+			return err
+		}
+
+		return s.db.apply(tx, func(st *state) error {
+			stored, ok := st.idempotencyRecords[record.Key]
+			if !ok || stored.LockID != record.LockID {
+				return nil
+			}
+
+			stored.ResponseStatus = record.ResponseStatus
+			stored.ResponseHeaders = record.ResponseHeaders
+			stored.ResponseBody = record.ResponseBody
+			st.idempotencyRecords[record.Key] = stored
+			return nil
+		})
+	})
+	return errors.Wrapf(err, "can't complete idempotency key %s", record.Key)
+}
+
+// DeleteIdempotencyRecord releases the idempotency key so that it could be used again.
+// Keys of requests which committed their changes are never released.
+func (s *MemStorage) DeleteIdempotencyRecord(ctx context.Context, record entities.IdempotencyRecord) error {
+	err := s.write(func(tx *memTx) error {
+		if err := s.db.lock(ctx, tx, uniqueKey(idempotencyKeysIndex, record.Key)); err != nil {
+			return err
+		}
+
+		return s.db.apply(tx, func(st *state) error {
+			if stored, ok := st.idempotencyRecords[record.Key]; ok && stored.LockID == record.LockID && !stored.IsCommitted() {
+				delete(st.idempotencyRecords, record.Key)
+			}
+			return nil
+		})
+	})
+	return errors.Wrapf(err, "can't delete idempotency key %s", record.Key)
+}
+
+// CreateAPIKey persists an APIKey. Returns the APIKey with ID and
+// creation time set up on success.
+func (s *MemStorage) CreateAPIKey(ctx context.Context, key entities.APIKey) (entities.APIKey, error) {
+	err := s.write(func(tx *memTx) error {
+		if err := s.db.lock(ctx, tx, uniqueKey(apiKeysKeyIndex, key.KeyID)); err != nil {
+			return err
+		}
+
+		key.ID = s.db.nextID(apiKeysTable)
+		key.CreatedAt = tx.startedAt
+		key.RevokedAt = time.Time{}
+
+		created := key
+		return s.db.apply(tx, func(st *state) error {
+			return st.insertAPIKey(created)
+		})
+	})
+	return key, errors.Wrap(err, "can't insert new api key")
+}
+
+// GetAPIKey returns an APIKey found by its public key id
+func (s *MemStorage) GetAPIKey(ctx context.Context, keyID string) (entities.APIKey, error) {
+	var key entities.APIKey
+	err := s.read(func(st *state) error {
+		found, ok := st.apiKeyByKeyID(keyID)
+		if !ok {
+			return errors.Wrapf(storage.ErrNotFound, "api key %s", keyID)
+		}
+		key = found
+		return nil
+	})
+	return key, errors.Wrapf(err, "can't obtain api key %s", keyID)
+}
+
+// GetAPIKeys returns all the API keys, revoked ones included, ordered by creation
+func (s *MemStorage) GetAPIKeys(ctx context.Context) ([]entities.APIKey, error) {
+	keys := []entities.APIKey{}
+	err := s.read(func(st *state) error {
+		for _, key := range st.apiKeys {
+			keys = append(keys, key)
+		}
+		return nil
+	})
+
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].ID < keys[j].ID
+	})
+	return keys, errors.Wrap(err, "can't query api keys")
+}
+
+// RevokeAPIKey marks the APIKey as revoked at the given time. Keys which were
+// revoked already keep their original revocation time.
+func (s *MemStorage) RevokeAPIKey(ctx context.Context, key entities.APIKey) error {
+	err := s.write(func(tx *memTx) error {
+		return s.db.apply(tx, func(st *state) error {
+			stored, ok := st.apiKeyByKeyID(key.KeyID)
+			if !ok || stored.IsRevoked() {
+				return nil
+			}
+
+			stored.RevokedAt = key.RevokedAt
+			st.apiKeys[stored.ID] = stored
+			return nil
+		})
+	})
+	return errors.Wrapf(err, "can't revoke api key %s", key.KeyID)
+}
+
+// AppendAuditEntry adds the entry to the end of the audit log chain. Appends wait for
+// each other until the transaction finishes, and the entry is stamped once it is its turn.
+// Returns the AuditEntry with ID, CreatedAt, PrevHash and Hash set up on success.
+func (s *MemStorage) AppendAuditEntry(ctx context.Context, entry entities.AuditEntry) (entities.AuditEntry, error) {
+	err := s.write(func(tx *memTx) error {
+		if err := s.db.lock(ctx, tx, auditChainLock); err != nil {
+			return err
+		}
+
+		view, err := s.db.snapshot(tx)
+		if err != nil {
+			return err
+		}
+
+		last := view.lastAuditEntry()
+		entry.ID = last.ID + 1
+		entry.CreatedAt = now()
+		entry.PrevHash = last.Hash
+		entry.Hash = entry.ChainHash()
+
+		appended := entry
+		return s.db.apply(tx, func(st *state) error {
+			return st.insertAuditEntry(appended)
+		})
+	})
+	return entry, errors.Wrap(err, "can't append audit entry")
+}
+
+// GetAuditEntries returns audit entries matching the filter ordered by ID
+func (s *MemStorage) GetAuditEntries(ctx context.Context, filter entities.AuditFilter) ([]entities.AuditEntry, error) {
+	entries := []entities.AuditEntry{}
+	err := s.read(func(st *state) error {
+		for _, entry := range st.auditEntries {
+			if auditEntryMatches(filter, entry) {
+				entries = append(entries, entry)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "can't query audit entries")
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].ID < entries[j].ID
+	})
+
+	if filter.Limit > 0 && len(entries) > filter.Limit {
+		entries = entries[:filter.Limit]
+	}
+	return entries, nil
+}
+
+// GetLedgerBalances returns every account along with the sum of its payments
+func (s *MemStorage) GetLedgerBalances(ctx context.Context) ([]entities.LedgerBalance, error) {
+	balances := []entities.LedgerBalance{}
+	err := s.read(func(st *state) error {
+		totals := make(map[int]decimal.Decimal)
+		for _, payment := range st.payments {
+			totals[payment.Account.ID] = totals[payment.Account.ID].Add(signedAmount(payment))
+		}
+
+		for id, account := range st.accounts {
+			balances = append(balances, entities.LedgerBalance{Account: account, PaymentsTotal: totals[id]})
+		}
+		return nil
+	})
+
+	sort.Slice(balances, func(i, j int) bool {
+		return balances[i].Account.ID < balances[j].Account.ID
+	})
+	return balances, errors.Wrap(err, "can't query ledger balances")
+}
+
+// GetUnbalancedTransactions returns sums of payments per transaction and currency
+// which are not equal to zero
+func (s *MemStorage) GetUnbalancedTransactions(ctx context.Context) ([]entities.TransactionImbalance, error) {
+	imbalances := []entities.TransactionImbalance{}
+	err := s.read(func(st *state) error {
+		type key struct {
+			transactionID int
+			currency      entities.Currency
+		}
+
+		totals := make(map[key]decimal.Decimal)
+		for _, payment := range st.payments {
+			k := key{payment.Transaction.ID, payment.Currency}
+			totals[k] = totals[k].Add(signedAmount(payment))
+		}
+
+		for k, total := range totals {
+			if !total.IsZero() {
+				imbalances = append(imbalances, entities.TransactionImbalance{TransactionID: k.transactionID, Currency: k.currency, Total: total})
+			}
+		}
+		return nil
+	})
+
+	sort.Slice(imbalances, func(i, j int) bool {
+		if imbalances[i].TransactionID != imbalances[j].TransactionID {
+			return imbalances[i].TransactionID < imbalances[j].TransactionID
+		}
+		return imbalances[i].Currency < imbalances[j].Currency
+	})
+	return imbalances, errors.Wrap(err, "can't query unbalanced transactions")
+}
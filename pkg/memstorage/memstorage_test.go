@@ -0,0 +1,190 @@
+package memstorage_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/twonegatives/coinsph_challenge/pkg/entities"
+	"github.com/twonegatives/coinsph_challenge/pkg/memstorage"
+	"github.com/twonegatives/coinsph_challenge/pkg/storage"
+	"github.com/twonegatives/coinsph_challenge/pkg/storage/storagetest"
+)
+
+// transfer moves the amount from one account to another the way banking service does
+func transfer(ctx context.Context, s storage.Storage, from, to string, amount decimal.Decimal) (err error) {
+	txStorage, err := s.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		if err != nil {
+			txStorage.RollbackTx(ctx)
+		}
+	}()
+
+	sender, receiver := entities.Account{Name: from}, entities.Account{Name: to}
+	if err = txStorage.GetAccountForUpdate(ctx, &sender); err != nil {
+		return err
+	}
+
+	if err = txStorage.GetAccountForUpdate(ctx, &receiver); err != nil {
+		return err
+	}
+
+	transaction, err := txStorage.CreateTransaction(ctx, entities.Transaction{Kind: entities.TransferTransaction})
+	if err != nil {
+		return err
+	}
+
+	if err = bookPayments(ctx, txStorage, transaction, sender, receiver, amount); err != nil {
+		return err
+	}
+
+	sender.Balance = sender.Balance.Sub(amount)
+	if err = txStorage.SetAccountBalance(ctx, sender); err != nil {
+		return err
+	}
+
+	receiver.Balance = receiver.Balance.Add(amount)
+	if err = txStorage.SetAccountBalance(ctx, receiver); err != nil {
+		return err
+	}
+
+	return txStorage.CommitTx(ctx)
+}
+
+func bookPayments(ctx context.Context, s storage.Storage, transaction entities.Transaction, from, to entities.Account, amount decimal.Decimal) error {
+	err := s.SendPayment(ctx, entities.Payment{
+		Transaction:  transaction,
+		Account:      from,
+		Counterparty: to,
+		Direction:    entities.Outgoing,
+		Amount:       amount,
+		Currency:     from.Currency,
+	})
+	if err != nil {
+		return err
+	}
+
+	return s.SendPayment(ctx, entities.Payment{
+		Transaction:  transaction,
+		Account:      to,
+		Counterparty: from,
+		Direction:    entities.Incoming,
+		Amount:       amount,
+		Currency:     to.Currency,
+	})
+}
+
+func createAccount(t *testing.T, s storage.Storage, name string) entities.Account {
+	account, err := s.CreateAccount(context.Background(), entities.Account{Name: name, Currency: entities.USD})
+	require.NoError(t, err)
+	return account
+}
+
+func TestMemStorage(t *testing.T) {
+	storagetest.Run(t, func() storage.Storage {
+		return memstorage.NewMemStorage()
+	})
+}
+
+func TestMemStorageSeedsHouseAccounts(t *testing.T) {
+	s := memstorage.NewMemStorage()
+	ctx := context.Background()
+
+	system, err := s.GetAccount(ctx, "SYSTEM")
+	require.NoError(t, err)
+	assert.Equal(t, 1, system.ID)
+	assert.Equal(t, entities.USD, system.Currency)
+
+	for _, name := range []string{"SYSTEM_EUR", "FX_USD", "FX_PHP", "FEE_USD", "FEE_BTC"} {
+		_, err := s.GetAccount(ctx, name)
+		assert.NoError(t, err, name)
+	}
+}
+
+func TestMemStorageGetAccountForUpdate(t *testing.T) {
+	s := memstorage.NewMemStorage()
+	ctx := context.Background()
+	createAccount(t, s, "erin")
+	createAccount(t, s, "frank")
+
+	t.Run("gives up waiting when context is done", func(t *testing.T) {
+		holder, err := s.BeginTx(ctx, nil)
+		require.NoError(t, err)
+		defer holder.RollbackTx(ctx)
+
+		require.NoError(t, holder.GetAccountForUpdate(ctx, &entities.Account{Name: "erin"}))
+
+		waiter, err := s.BeginTx(ctx, nil)
+		require.NoError(t, err)
+		defer waiter.RollbackTx(ctx)
+
+		timeoutCtx, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+		defer cancel()
+		err = waiter.GetAccountForUpdate(timeoutCtx, &entities.Account{Name: "erin"})
+		assert.Equal(t, context.DeadlineExceeded, errors.Cause(err))
+	})
+
+	t.Run("detects deadlocks", func(t *testing.T) {
+		first, err := s.BeginTx(ctx, nil)
+		require.NoError(t, err)
+		defer first.RollbackTx(ctx)
+
+		second, err := s.BeginTx(ctx, nil)
+		require.NoError(t, err)
+		defer second.RollbackTx(ctx)
+
+		require.NoError(t, first.GetAccountForUpdate(ctx, &entities.Account{Name: "erin"}))
+		require.NoError(t, second.GetAccountForUpdate(ctx, &entities.Account{Name: "frank"}))
+
+		waited := make(chan error)
+		go func() {
+			waited <- first.GetAccountForUpdate(ctx, &entities.Account{Name: "frank"})
+		}()
+
+		time.Sleep(20 * time.Millisecond)
+		assert.Error(t, second.GetAccountForUpdate(ctx, &entities.Account{Name: "erin"}))
+		require.NoError(t, second.RollbackTx(ctx))
+		assert.NoError(t, <-waited)
+	})
+}
+
+func TestMemStorageConcurrentTransfers(t *testing.T) {
+	s := memstorage.NewMemStorage()
+	ctx := context.Background()
+	createAccount(t, s, "gina")
+	createAccount(t, s, "hugo")
+	require.NoError(t, transfer(ctx, s, "SYSTEM", "gina", decimal.New(50, 0)))
+
+	var wg sync.WaitGroup
+	failures := make(chan error, 100)
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := transfer(ctx, s, "gina", "hugo", decimal.New(1, 0)); err != nil {
+				failures <- err
+			}
+		}()
+	}
+	wg.Wait()
+	close(failures)
+
+	assert.Len(t, failures, 50)
+
+	gina, err := s.GetAccount(ctx, "gina")
+	require.NoError(t, err)
+	assert.True(t, gina.Balance.IsZero())
+
+	hugo, err := s.GetAccount(ctx, "hugo")
+	require.NoError(t, err)
+	assert.Equal(t, "50", hugo.Balance.String())
+}
@@ -0,0 +1,619 @@
+package memstorage
+
+import (
+	"github.com/pkg/errors"
+	"github.com/shopspring/decimal"
+	"github.com/twonegatives/coinsph_challenge/pkg/entities"
+	"github.com/twonegatives/coinsph_challenge/pkg/storage"
+)
+
+// state is a set of tables the storage consists of. Rows are kept by value,
+// payments refer to their accounts and transactions by IDs only.
+type state struct {
+	accounts           map[int]entities.Account
+	transactions       map[int]entities.Transaction
+	payments           map[int]entities.Payment
+	quotes             map[int]entities.Quote
+	idempotencyRecords map[string]entities.IdempotencyRecord
+	creditLimitChanges map[int]entities.CreditLimitChange
+	holds              map[int]entities.Hold
+	scheduledPayments  map[int]entities.ScheduledPayment
+	scheduledRuns      map[int]entities.ScheduledRun
+	apiKeys            map[int]entities.APIKey
+	auditEntries       map[int]entities.AuditEntry
+}
+
+func newState() *state {
+	return &state{
+		accounts:           make(map[int]entities.Account),
+		transactions:       make(map[int]entities.Transaction),
+		payments:           make(map[int]entities.Payment),
+		quotes:             make(map[int]entities.Quote),
+		idempotencyRecords: make(map[string]entities.IdempotencyRecord),
+		creditLimitChanges: make(map[int]entities.CreditLimitChange),
+		holds:              make(map[int]entities.Hold),
+		scheduledPayments:  make(map[int]entities.ScheduledPayment),
+		scheduledRuns:      make(map[int]entities.ScheduledRun),
+		apiKeys:            make(map[int]entities.APIKey),
+		auditEntries:       make(map[int]entities.AuditEntry),
+	}
+}
+
+func (st *state) clone() *state {
+	result := newState()
+	for id, account := range st.accounts {
+		result.accounts[id] = account
+	}
+	for id, transaction := range st.transactions {
+		result.transactions[id] = transaction
+	}
+	for id, payment := range st.payments {
+		result.payments[id] = payment
+	}
+	for id, quote := range st.quotes {
+		result.quotes[id] = quote
+	}
+	for key, record := range st.idempotencyRecords {
+		result.idempotencyRecords[key] = record
+	}
+	for id, change := range st.creditLimitChanges {
+		result.creditLimitChanges[id] = change
+	}
+	for id, hold := range st.holds {
+		result.holds[id] = hold
+	}
+	for id, payment := range st.scheduledPayments {
+		result.scheduledPayments[id] = payment
+	}
+	for id, run := range st.scheduledRuns {
+		result.scheduledRuns[id] = run
+	}
+	for id, key := range st.apiKeys {
+		result.apiKeys[id] = key
+	}
+	for id, entry := range st.auditEntries {
+		result.auditEntries[id] = entry
+	}
+	return result
+}
+
+func (st *state) accountByName(name string) (entities.Account, bool) {
+	for _, account := range st.accounts {
+		if account.Name == name {
+			return account, true
+		}
+	}
+	return entities.Account{}, false
+}
+
+func (st *state) insertAccount(account entities.Account) error {
+	if _, exists := st.accountByName(account.Name); exists {
+		return errors.Errorf("duplicate key value violates unique constraint: account name %s", account.Name)
+	}
+
+	if !account.Type.IsValid() {
+		return errors.Errorf("check constraint violation: invalid account type %s", account.Type)
+	}
+
+	if err := checkValidBalance(account); err != nil {
+		return err
+	}
+
+	if err := checkValidStatus(account); err != nil {
+		return err
+	}
+
+	// labels are copied so that the caller's slice can't change the stored row
+	account.Labels = copyLabels(account.Labels)
+	st.accounts[account.ID] = account
+	return nil
+}
+
+func (st *state) setAccountBalance(id int, balance decimal.Decimal) error {
+	account, ok := st.accounts[id]
+	if !ok {
+		return nil // UPDATE of a missing row affects nothing
+	}
+
+	account.Balance = balance
+	if err := checkValidBalance(account); err != nil {
+		return err
+	}
+
+	if err := checkValidStatus(account); err != nil {
+		return err
+	}
+
+	st.accounts[id] = account
+	return nil
+}
+
+func (st *state) setAccountStatus(id int, status entities.AccountStatus) error {
+	account, ok := st.accounts[id]
+	if !ok {
+		return nil
+	}
+
+	account.Status = status
+	if err := checkValidStatus(account); err != nil {
+		return err
+	}
+
+	st.accounts[id] = account
+	return nil
+}
+
+func (st *state) setAccountCreditLimit(id int, limit decimal.Decimal) error {
+	account, ok := st.accounts[id]
+	if !ok {
+		return nil
+	}
+
+	if limit.IsNegative() {
+		return errors.New("check constraint violation: credit limit can't be negative")
+	}
+
+	account.CreditLimit = limit
+	if err := checkValidBalance(account); err != nil {
+		return err
+	}
+
+	st.accounts[id] = account
+	return nil
+}
+
+func (st *state) setAccountHeld(id int, held decimal.Decimal) error {
+	account, ok := st.accounts[id]
+	if !ok {
+		return nil
+	}
+
+	if held.IsNegative() {
+		return errors.New("check constraint violation: held amount can't be negative")
+	}
+
+	account.Held = held
+	if err := checkValidBalance(account); err != nil {
+		return err
+	}
+
+	if err := checkValidStatus(account); err != nil {
+		return err
+	}
+
+	st.accounts[id] = account
+	return nil
+}
+
+func (st *state) setAccountProfile(id int, profile entities.AccountProfile) error {
+	account, ok := st.accounts[id]
+	if !ok {
+		return nil
+	}
+
+	profile.Labels = copyLabels(profile.Labels)
+	st.accounts[id] = account.WithProfile(profile)
+	return nil
+}
+
+func copyLabels(labels []string) []string {
+	if len(labels) == 0 {
+		return nil
+	}
+	return append([]string(nil), labels...)
+}
+
+func (st *state) insertCreditLimitChange(change entities.CreditLimitChange) error {
+	if _, ok := st.accounts[change.AccountID]; !ok {
+		return errors.Errorf("foreign key violation: account %d is not present", change.AccountID)
+	}
+
+	st.creditLimitChanges[change.ID] = change
+	return nil
+}
+
+func (st *state) insertTransaction(transaction entities.Transaction) error {
+	if !transaction.Kind.IsValid() {
+		return errors.Errorf("check constraint violation: invalid transaction kind %s", transaction.Kind)
+	}
+
+	if transaction.Kind.IsCompensating() != (transaction.OriginalID != 0) {
+		return errors.New("check constraint violation: reversals and refunds should be linked to the original transaction")
+	}
+
+	if transaction.Fee.IsNegative() {
+		return errors.Errorf("check constraint violation: invalid fee %s", transaction.Fee)
+	}
+
+	if _, ok := st.transactions[transaction.OriginalID]; transaction.OriginalID != 0 && !ok {
+		return errors.Errorf("foreign key violation: transaction %d is not present", transaction.OriginalID)
+	}
+
+	if transaction.SenderID != 0 {
+		if _, ok := st.accounts[transaction.SenderID]; !ok {
+			return errors.Errorf("foreign key violation: account %d is not present", transaction.SenderID)
+		}
+
+		if transaction.ExternalReference == "" {
+			return errors.New("check constraint violation: sender is only set along with the reference")
+		}
+	}
+
+	if transaction.ExternalReference != "" {
+		for _, other := range st.transactions {
+			// references of senders are unique per sender, other ones are unique per kind
+			sameScope := other.SenderID == transaction.SenderID && (transaction.SenderID != 0 || other.Kind == transaction.Kind)
+			if sameScope && other.ExternalReference == transaction.ExternalReference {
+				return errors.Wrapf(storage.ErrAlreadyExists, "%s with reference %s", transaction.Kind, transaction.ExternalReference)
+			}
+		}
+	}
+
+	st.transactions[transaction.ID] = transaction
+	return nil
+}
+
+func (st *state) insertPayment(payment entities.Payment) error {
+	if _, ok := st.transactions[payment.Transaction.ID]; !ok {
+		return errors.Errorf("foreign key violation: transaction %d is not present", payment.Transaction.ID)
+	}
+
+	if _, ok := st.accounts[payment.Account.ID]; !ok {
+		return errors.Errorf("foreign key violation: account %d is not present", payment.Account.ID)
+	}
+
+	if _, ok := st.accounts[payment.Counterparty.ID]; !ok {
+		return errors.Errorf("foreign key violation: account %d is not present", payment.Counterparty.ID)
+	}
+
+	if !payment.Amount.IsPositive() {
+		return errors.New("check constraint violation: payment amount should be positive")
+	}
+
+	st.payments[payment.ID] = entities.Payment{
+		ID:           payment.ID,
+		Account:      entities.Account{ID: payment.Account.ID},
+		Counterparty: entities.Account{ID: payment.Counterparty.ID},
+		Transaction:  entities.Transaction{ID: payment.Transaction.ID},
+		Direction:    payment.Direction,
+		Amount:       payment.Amount,
+		Currency:     payment.Currency,
+	}
+	return nil
+}
+
+// payment returns a stored payment with names of its accounts and its transaction filled in
+func (st *state) payment(id int) entities.Payment {
+	payment := st.payments[id]
+	payment.Account.Name = st.accounts[payment.Account.ID].Name
+	payment.Counterparty.Name = st.accounts[payment.Counterparty.ID].Name
+	payment.Transaction = st.transactions[payment.Transaction.ID]
+	return payment
+}
+
+func (st *state) insertQuote(quote entities.Quote) error {
+	valid := quote.Rate.IsPositive() &&
+		quote.SourceAmount.IsPositive() &&
+		quote.TargetAmount.IsPositive() &&
+		quote.FromCurrency != quote.ToCurrency
+	if !valid {
+		return errors.New("check constraint violation: invalid quote")
+	}
+
+	st.quotes[quote.ID] = quote
+	return nil
+}
+
+func (st *state) setQuoteTransaction(id int, transactionID int) error {
+	quote, ok := st.quotes[id]
+	if !ok {
+		return nil
+	}
+
+	if _, ok := st.transactions[transactionID]; !ok {
+		return errors.Errorf("foreign key violation: transaction %d is not present", transactionID)
+	}
+
+	for _, other := range st.quotes {
+		if other.ID != id && other.TransactionID == transactionID {
+			return errors.Errorf("duplicate key value violates unique constraint: quote transaction %d", transactionID)
+		}
+	}
+
+	quote.TransactionID = transactionID
+	st.quotes[id] = quote
+	return nil
+}
+
+func (st *state) insertHold(hold entities.Hold) error {
+	if _, ok := st.accounts[hold.Account.ID]; !ok {
+		return errors.Errorf("foreign key violation: account %d is not present", hold.Account.ID)
+	}
+
+	if _, ok := st.accounts[hold.Counterparty.ID]; !ok {
+		return errors.Errorf("foreign key violation: account %d is not present", hold.Counterparty.ID)
+	}
+
+	if !hold.Amount.IsPositive() || hold.Account.ID == hold.Counterparty.ID {
+		return errors.New("check constraint violation: invalid hold")
+	}
+
+	st.holds[hold.ID] = entities.Hold{
+		ID:           hold.ID,
+		Account:      entities.Account{ID: hold.Account.ID},
+		Counterparty: entities.Account{ID: hold.Counterparty.ID},
+		Amount:       hold.Amount,
+		Currency:     hold.Currency,
+		CreatedAt:    hold.CreatedAt,
+		ExpiresAt:    hold.ExpiresAt,
+	}
+	return st.setHoldStatus(hold.ID, hold.Status, hold.TransactionID)
+}
+
+func (st *state) setHoldStatus(id int, status entities.HoldStatus, transactionID int) error {
+	hold, ok := st.holds[id]
+	if !ok {
+		return nil
+	}
+
+	if !status.IsValid() {
+		return errors.Errorf("check constraint violation: invalid hold status %s", status)
+	}
+
+	if (status == entities.CapturedHold) != (transactionID != 0) {
+		return errors.New("check constraint violation: captured holds should be linked to their transaction")
+	}
+
+	if _, ok := st.transactions[transactionID]; transactionID != 0 && !ok {
+		return errors.Errorf("foreign key violation: transaction %d is not present", transactionID)
+	}
+
+	for _, other := range st.holds {
+		if transactionID != 0 && other.ID != id && other.TransactionID == transactionID {
+			return errors.Errorf("duplicate key value violates unique constraint: hold transaction %d", transactionID)
+		}
+	}
+
+	hold.Status = status
+	hold.TransactionID = transactionID
+	st.holds[id] = hold
+	return nil
+}
+
+// hold returns a stored hold with names of its accounts filled in
+func (st *state) hold(id int) entities.Hold {
+	hold := st.holds[id]
+	hold.Account.Name = st.accounts[hold.Account.ID].Name
+	hold.Counterparty.Name = st.accounts[hold.Counterparty.ID].Name
+	return hold
+}
+
+func (st *state) insertScheduledPayment(payment entities.ScheduledPayment) error {
+	if _, ok := st.accounts[payment.Account.ID]; !ok {
+		return errors.Errorf("foreign key violation: account %d is not present", payment.Account.ID)
+	}
+
+	if _, ok := st.accounts[payment.Counterparty.ID]; !ok {
+		return errors.Errorf("foreign key violation: account %d is not present", payment.Counterparty.ID)
+	}
+
+	if payment.Account.ID == payment.Counterparty.ID {
+		return errors.New("check constraint violation: invalid scheduled payment")
+	}
+
+	if !payment.Frequency.IsValid() {
+		return errors.Errorf("check constraint violation: invalid frequency %s", payment.Frequency)
+	}
+
+	if (payment.Frequency == entities.CronFrequency) != (payment.Cron != "") {
+		return errors.New("check constraint violation: cron scheduled payments should have an expression")
+	}
+
+	st.scheduledPayments[payment.ID] = entities.ScheduledPayment{
+		ID:           payment.ID,
+		Account:      entities.Account{ID: payment.Account.ID},
+		Counterparty: entities.Account{ID: payment.Counterparty.ID},
+		Currency:     payment.Currency,
+		Frequency:    payment.Frequency,
+		Cron:         payment.Cron,
+		StartAt:      payment.StartAt,
+		EndAt:        payment.EndAt,
+		CreatedAt:    payment.CreatedAt,
+	}
+	return st.updateScheduledPayment(payment)
+}
+
+func (st *state) updateScheduledPayment(payment entities.ScheduledPayment) error {
+	stored, ok := st.scheduledPayments[payment.ID]
+	if !ok {
+		return nil
+	}
+
+	if !payment.Amount.IsPositive() || payment.Attempts < 0 {
+		return errors.New("check constraint violation: invalid scheduled payment")
+	}
+
+	if !payment.Status.IsValid() {
+		return errors.Errorf("check constraint violation: invalid schedule status %s", payment.Status)
+	}
+
+	stored.Amount = payment.Amount
+	stored.Status = payment.Status
+	stored.NextRunAt = payment.NextRunAt
+	stored.RetryAt = payment.RetryAt
+	stored.Attempts = payment.Attempts
+	stored.LeaseOwner = payment.LeaseOwner
+	stored.LeaseExpiresAt = payment.LeaseExpiresAt
+	st.scheduledPayments[payment.ID] = stored
+	return nil
+}
+
+// scheduledPayment returns a stored scheduled payment with names of its accounts filled in
+func (st *state) scheduledPayment(id int) entities.ScheduledPayment {
+	payment := st.scheduledPayments[id]
+	payment.Account.Name = st.accounts[payment.Account.ID].Name
+	payment.Counterparty.Name = st.accounts[payment.Counterparty.ID].Name
+	return payment
+}
+
+func (st *state) insertScheduledRun(run entities.ScheduledRun) error {
+	if _, ok := st.scheduledPayments[run.ScheduleID]; !ok {
+		return errors.Errorf("foreign key violation: scheduled payment %d is not present", run.ScheduleID)
+	}
+
+	if run.Status != entities.SucceededRun && run.Status != entities.FailedRun {
+		return errors.Errorf("check constraint violation: invalid run status %s", run.Status)
+	}
+
+	if (run.Status == entities.SucceededRun) != (run.TransactionID != 0) {
+		return errors.New("check constraint violation: succeeded runs should be linked to their transaction")
+	}
+
+	if _, ok := st.transactions[run.TransactionID]; run.TransactionID != 0 && !ok {
+		return errors.Errorf("foreign key violation: transaction %d is not present", run.TransactionID)
+	}
+
+	for _, other := range st.scheduledRuns {
+		if run.TransactionID != 0 && other.TransactionID == run.TransactionID {
+			return errors.Errorf("duplicate key value violates unique constraint: run transaction %d", run.TransactionID)
+		}
+	}
+
+	st.scheduledRuns[run.ID] = run
+	return nil
+}
+
+// checkValidBalance emulates valid_balance check constraint of accounts table,
+// which lets accounts go below zero by their credit limit, or without bounds for some types
+func checkValidBalance(account entities.Account) error {
+	if !account.IsBalanceValid() {
+		return errors.Errorf("check constraint violation: balance of %s can't go below zero", account.Name)
+	}
+	return nil
+}
+
+// checkValidStatus emulates valid_status, closed_with_zero_balance and closed_without_holds constraints
+func checkValidStatus(account entities.Account) error {
+	if !account.Status.IsValid() {
+		return errors.Errorf("check constraint violation: invalid account status %s", account.Status)
+	}
+
+	if account.Status == entities.ClosedAccount && !account.Balance.IsZero() {
+		return errors.Errorf("check constraint violation: closed account %s should have zero balance", account.Name)
+	}
+
+	if account.Status == entities.ClosedAccount && !account.Held.IsZero() {
+		return errors.Errorf("check constraint violation: closed account %s should have no holds", account.Name)
+	}
+	return nil
+}
+
+// checkTransactionBalanced emulates check_if_tx_balanced trigger:
+// payments of each currency within a transaction should sum up to zero
+func (st *state) checkTransactionBalanced(transactionID int) error {
+	totals := make(map[entities.Currency]decimal.Decimal)
+	for _, payment := range st.payments {
+		if payment.Transaction.ID == transactionID {
+			totals[payment.Currency] = totals[payment.Currency].Add(signedAmount(payment))
+		}
+	}
+
+	for currency, total := range totals {
+		if !total.IsZero() {
+			return errors.Errorf("sum of %s payments (%s) not equals zero for given transaction (id %d)", currency, total, transactionID)
+		}
+	}
+	return nil
+}
+
+// checkAccountBalanced emulates check_if_account_balanced trigger:
+// balance of an account should be equal to the sum of its payments
+func (st *state) checkAccountBalanced(accountID int) error {
+	account, ok := st.accounts[accountID]
+	if !ok {
+		return nil
+	}
+
+	total := decimal.New(0, 0)
+	for _, payment := range st.payments {
+		if payment.Account.ID == accountID {
+			total = total.Add(signedAmount(payment))
+		}
+	}
+
+	if !total.Equal(account.Balance) {
+		return errors.Errorf("account balance (%s) does not correspond to its payments (%s)", account.Balance, total)
+	}
+	return nil
+}
+
+func signedAmount(payment entities.Payment) decimal.Decimal {
+	if payment.Direction == entities.Outgoing {
+		return payment.Amount.Neg()
+	}
+	return payment.Amount
+}
+
+func (st *state) apiKeyByKeyID(keyID string) (entities.APIKey, bool) {
+	for _, key := range st.apiKeys {
+		if key.KeyID == keyID {
+			return key, true
+		}
+	}
+	return entities.APIKey{}, false
+}
+
+func (st *state) insertAPIKey(key entities.APIKey) error {
+	if _, exists := st.apiKeyByKeyID(key.KeyID); exists {
+		return errors.Errorf("duplicate key value violates unique constraint: api key %s", key.KeyID)
+	}
+
+	if !key.Role.IsValid() {
+		return errors.Errorf("check constraint violation: invalid api key role %s", key.Role)
+	}
+
+	if key.Role == entities.AccountOwnerRole && key.OwnerID == "" {
+		return errors.New("check constraint violation: account owner api key without owner")
+	}
+
+	st.apiKeys[key.ID] = key
+	return nil
+}
+
+// lastAuditEntry returns the entry at the end of the chain, or zero entry for the empty one
+func (st *state) lastAuditEntry() entities.AuditEntry {
+	var last entities.AuditEntry
+	for _, entry := range st.auditEntries {
+		if entry.ID > last.ID {
+			last = entry
+		}
+	}
+	return last
+}
+
+// reserveIdempotencyRecord stores the reservation unless the key is already reserved.
+// Expired reservations of the same payload which committed nothing are taken over.
+func (st *state) reserveIdempotencyRecord(record entities.IdempotencyRecord) bool {
+	stored, exists := st.idempotencyRecords[record.Key]
+	if exists {
+		if stored.RequestHash != record.RequestHash || stored.IsCommitted() || stored.IsCompleted() || stored.IsLocked(record.CreatedAt) {
+			return false
+		}
+	}
+
+	st.idempotencyRecords[record.Key] = record
+	return true
+}
+
+func (st *state) insertAuditEntry(entry entities.AuditEntry) error {
+	if _, exists := st.auditEntries[entry.ID]; exists {
+		return errors.Errorf("duplicate key value violates unique constraint: audit entry %d", entry.ID)
+	}
+
+	if entry.Outcome != entities.SucceededOutcome && entry.Outcome != entities.FailedOutcome {
+		return errors.Errorf("check constraint violation: invalid audit outcome %s", entry.Outcome)
+	}
+
+	st.auditEntries[entry.ID] = entry
+	return nil
+}
@@ -36,52 +36,509 @@ func (m *MockBankingService) EXPECT() *MockBankingServiceMockRecorder {
 }
 
 // CreateAccount mocks base method
-func (m *MockBankingService) CreateAccount(ctx context.Context, accountName string) (entities.Account, error) {
-	ret := m.ctrl.Call(m, "CreateAccount", ctx, accountName)
+func (m *MockBankingService) CreateAccount(ctx context.Context, accountName string, currency entities.Currency, profile entities.AccountProfile) (entities.Account, error) {
+	ret := m.ctrl.Call(m, "CreateAccount", ctx, accountName, currency, profile)
 	ret0, _ := ret[0].(entities.Account)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // CreateAccount indicates an expected call of CreateAccount
-func (mr *MockBankingServiceMockRecorder) CreateAccount(ctx, accountName interface{}) *gomock.Call {
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateAccount", reflect.TypeOf((*MockBankingService)(nil).CreateAccount), ctx, accountName)
+func (mr *MockBankingServiceMockRecorder) CreateAccount(ctx, accountName, currency, profile interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateAccount", reflect.TypeOf((*MockBankingService)(nil).CreateAccount), ctx, accountName, currency, profile)
+}
+
+// UpdateAccount mocks base method
+func (m *MockBankingService) UpdateAccount(ctx context.Context, accountName string, update entities.AccountUpdate) (entities.Account, error) {
+	ret := m.ctrl.Call(m, "UpdateAccount", ctx, accountName, update)
+	ret0, _ := ret[0].(entities.Account)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UpdateAccount indicates an expected call of UpdateAccount
+func (mr *MockBankingServiceMockRecorder) UpdateAccount(ctx, accountName, update interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateAccount", reflect.TypeOf((*MockBankingService)(nil).UpdateAccount), ctx, accountName, update)
 }
 
 // GetAccountsList mocks base method
-func (m *MockBankingService) GetAccountsList(ctx context.Context) ([]entities.Account, error) {
-	ret := m.ctrl.Call(m, "GetAccountsList", ctx)
-	ret0, _ := ret[0].([]entities.Account)
+func (m *MockBankingService) GetAccountsList(ctx context.Context, filter entities.AccountsFilter) (entities.AccountsPage, error) {
+	ret := m.ctrl.Call(m, "GetAccountsList", ctx, filter)
+	ret0, _ := ret[0].(entities.AccountsPage)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // GetAccountsList indicates an expected call of GetAccountsList
-func (mr *MockBankingServiceMockRecorder) GetAccountsList(ctx interface{}) *gomock.Call {
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAccountsList", reflect.TypeOf((*MockBankingService)(nil).GetAccountsList), ctx)
+func (mr *MockBankingServiceMockRecorder) GetAccountsList(ctx, filter interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAccountsList", reflect.TypeOf((*MockBankingService)(nil).GetAccountsList), ctx, filter)
+}
+
+// GetAccount mocks base method
+func (m *MockBankingService) GetAccount(ctx context.Context, name string) (entities.Account, error) {
+	ret := m.ctrl.Call(m, "GetAccount", ctx, name)
+	ret0, _ := ret[0].(entities.Account)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetAccount indicates an expected call of GetAccount
+func (mr *MockBankingServiceMockRecorder) GetAccount(ctx, name interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAccount", reflect.TypeOf((*MockBankingService)(nil).GetAccount), ctx, name)
 }
 
 // GetPaymentsList mocks base method
-func (m *MockBankingService) GetPaymentsList(ctx context.Context) ([]entities.Payment, error) {
-	ret := m.ctrl.Call(m, "GetPaymentsList", ctx)
-	ret0, _ := ret[0].([]entities.Payment)
+func (m *MockBankingService) GetPaymentsList(ctx context.Context, filter entities.PaymentsFilter) (entities.PaymentsPage, error) {
+	ret := m.ctrl.Call(m, "GetPaymentsList", ctx, filter)
+	ret0, _ := ret[0].(entities.PaymentsPage)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // GetPaymentsList indicates an expected call of GetPaymentsList
-func (mr *MockBankingServiceMockRecorder) GetPaymentsList(ctx interface{}) *gomock.Call {
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetPaymentsList", reflect.TypeOf((*MockBankingService)(nil).GetPaymentsList), ctx)
+func (mr *MockBankingServiceMockRecorder) GetPaymentsList(ctx, filter interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetPaymentsList", reflect.TypeOf((*MockBankingService)(nil).GetPaymentsList), ctx, filter)
+}
+
+// GetAccountPayments mocks base method
+func (m *MockBankingService) GetAccountPayments(ctx context.Context, name string, filter entities.PaymentsFilter) (entities.PaymentsPage, error) {
+	ret := m.ctrl.Call(m, "GetAccountPayments", ctx, name, filter)
+	ret0, _ := ret[0].(entities.PaymentsPage)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetAccountPayments indicates an expected call of GetAccountPayments
+func (mr *MockBankingServiceMockRecorder) GetAccountPayments(ctx, name, filter interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAccountPayments", reflect.TypeOf((*MockBankingService)(nil).GetAccountPayments), ctx, name, filter)
 }
 
 // SendPayment mocks base method
-func (m *MockBankingService) SendPayment(ctx context.Context, from, to entities.Account, amount decimal.Decimal) error {
-	ret := m.ctrl.Call(m, "SendPayment", ctx, from, to, amount)
-	ret0, _ := ret[0].(error)
-	return ret0
+func (m *MockBankingService) SendPayment(ctx context.Context, from, to entities.Account, amount decimal.Decimal, details entities.PaymentDetails) (entities.TransferReceipt, error) {
+	ret := m.ctrl.Call(m, "SendPayment", ctx, from, to, amount, details)
+	ret0, _ := ret[0].(entities.TransferReceipt)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
 }
 
 // SendPayment indicates an expected call of SendPayment
-func (mr *MockBankingServiceMockRecorder) SendPayment(ctx, from, to, amount interface{}) *gomock.Call {
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SendPayment", reflect.TypeOf((*MockBankingService)(nil).SendPayment), ctx, from, to, amount)
+func (mr *MockBankingServiceMockRecorder) SendPayment(ctx, from, to, amount, details interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SendPayment", reflect.TypeOf((*MockBankingService)(nil).SendPayment), ctx, from, to, amount, details)
+}
+
+// PreviewPayment mocks base method
+func (m *MockBankingService) PreviewPayment(ctx context.Context, from, to entities.Account, amount decimal.Decimal) (entities.PaymentPreview, error) {
+	ret := m.ctrl.Call(m, "PreviewPayment", ctx, from, to, amount)
+	ret0, _ := ret[0].(entities.PaymentPreview)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// PreviewPayment indicates an expected call of PreviewPayment
+func (mr *MockBankingServiceMockRecorder) PreviewPayment(ctx, from, to, amount interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PreviewPayment", reflect.TypeOf((*MockBankingService)(nil).PreviewPayment), ctx, from, to, amount)
+}
+
+// SendPaymentBatch mocks base method
+func (m *MockBankingService) SendPaymentBatch(ctx context.Context, orders []entities.PaymentOrder, mode entities.BatchMode) (entities.BatchReceipt, error) {
+	ret := m.ctrl.Call(m, "SendPaymentBatch", ctx, orders, mode)
+	ret0, _ := ret[0].(entities.BatchReceipt)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SendPaymentBatch indicates an expected call of SendPaymentBatch
+func (mr *MockBankingServiceMockRecorder) SendPaymentBatch(ctx, orders, mode interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SendPaymentBatch", reflect.TypeOf((*MockBankingService)(nil).SendPaymentBatch), ctx, orders, mode)
+}
+
+// PostTransaction mocks base method
+func (m *MockBankingService) PostTransaction(ctx context.Context, legs []entities.Leg) (entities.PostingReceipt, error) {
+	ret := m.ctrl.Call(m, "PostTransaction", ctx, legs)
+	ret0, _ := ret[0].(entities.PostingReceipt)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// PostTransaction indicates an expected call of PostTransaction
+func (mr *MockBankingServiceMockRecorder) PostTransaction(ctx, legs interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PostTransaction", reflect.TypeOf((*MockBankingService)(nil).PostTransaction), ctx, legs)
+}
+
+// GetTransaction mocks base method
+func (m *MockBankingService) GetTransaction(ctx context.Context, id int) (entities.Transaction, []entities.Payment, error) {
+	ret := m.ctrl.Call(m, "GetTransaction", ctx, id)
+	ret0, _ := ret[0].(entities.Transaction)
+	ret1, _ := ret[1].([]entities.Payment)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// GetTransaction indicates an expected call of GetTransaction
+func (mr *MockBankingServiceMockRecorder) GetTransaction(ctx, id interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetTransaction", reflect.TypeOf((*MockBankingService)(nil).GetTransaction), ctx, id)
+}
+
+// ReverseTransaction mocks base method
+func (m *MockBankingService) ReverseTransaction(ctx context.Context, id int) (entities.TransferReceipt, error) {
+	ret := m.ctrl.Call(m, "ReverseTransaction", ctx, id)
+	ret0, _ := ret[0].(entities.TransferReceipt)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ReverseTransaction indicates an expected call of ReverseTransaction
+func (mr *MockBankingServiceMockRecorder) ReverseTransaction(ctx, id interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReverseTransaction", reflect.TypeOf((*MockBankingService)(nil).ReverseTransaction), ctx, id)
+}
+
+// RefundTransaction mocks base method
+func (m *MockBankingService) RefundTransaction(ctx context.Context, id int, amount decimal.Decimal) (entities.TransferReceipt, error) {
+	ret := m.ctrl.Call(m, "RefundTransaction", ctx, id, amount)
+	ret0, _ := ret[0].(entities.TransferReceipt)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RefundTransaction indicates an expected call of RefundTransaction
+func (mr *MockBankingServiceMockRecorder) RefundTransaction(ctx, id, amount interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RefundTransaction", reflect.TypeOf((*MockBankingService)(nil).RefundTransaction), ctx, id, amount)
+}
+
+// Deposit mocks base method
+func (m *MockBankingService) Deposit(ctx context.Context, accountName string, amount decimal.Decimal, reference string) (entities.TransferReceipt, error) {
+	ret := m.ctrl.Call(m, "Deposit", ctx, accountName, amount, reference)
+	ret0, _ := ret[0].(entities.TransferReceipt)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Deposit indicates an expected call of Deposit
+func (mr *MockBankingServiceMockRecorder) Deposit(ctx, accountName, amount, reference interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Deposit", reflect.TypeOf((*MockBankingService)(nil).Deposit), ctx, accountName, amount, reference)
+}
+
+// Withdraw mocks base method
+func (m *MockBankingService) Withdraw(ctx context.Context, accountName string, amount decimal.Decimal, reference string) (entities.TransferReceipt, error) {
+	ret := m.ctrl.Call(m, "Withdraw", ctx, accountName, amount, reference)
+	ret0, _ := ret[0].(entities.TransferReceipt)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Withdraw indicates an expected call of Withdraw
+func (mr *MockBankingServiceMockRecorder) Withdraw(ctx, accountName, amount, reference interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Withdraw", reflect.TypeOf((*MockBankingService)(nil).Withdraw), ctx, accountName, amount, reference)
+}
+
+// FreezeAccount mocks base method
+func (m *MockBankingService) FreezeAccount(ctx context.Context, accountName string) (entities.Account, error) {
+	ret := m.ctrl.Call(m, "FreezeAccount", ctx, accountName)
+	ret0, _ := ret[0].(entities.Account)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FreezeAccount indicates an expected call of FreezeAccount
+func (mr *MockBankingServiceMockRecorder) FreezeAccount(ctx, accountName interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FreezeAccount", reflect.TypeOf((*MockBankingService)(nil).FreezeAccount), ctx, accountName)
+}
+
+// UnfreezeAccount mocks base method
+func (m *MockBankingService) UnfreezeAccount(ctx context.Context, accountName string) (entities.Account, error) {
+	ret := m.ctrl.Call(m, "UnfreezeAccount", ctx, accountName)
+	ret0, _ := ret[0].(entities.Account)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UnfreezeAccount indicates an expected call of UnfreezeAccount
+func (mr *MockBankingServiceMockRecorder) UnfreezeAccount(ctx, accountName interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UnfreezeAccount", reflect.TypeOf((*MockBankingService)(nil).UnfreezeAccount), ctx, accountName)
+}
+
+// CloseAccount mocks base method
+func (m *MockBankingService) CloseAccount(ctx context.Context, accountName string) (entities.Account, error) {
+	ret := m.ctrl.Call(m, "CloseAccount", ctx, accountName)
+	ret0, _ := ret[0].(entities.Account)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CloseAccount indicates an expected call of CloseAccount
+func (mr *MockBankingServiceMockRecorder) CloseAccount(ctx, accountName interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CloseAccount", reflect.TypeOf((*MockBankingService)(nil).CloseAccount), ctx, accountName)
+}
+
+// SetCreditLimit mocks base method
+func (m *MockBankingService) SetCreditLimit(ctx context.Context, accountName string, limit decimal.Decimal, changedBy, reason string) (entities.Account, error) {
+	ret := m.ctrl.Call(m, "SetCreditLimit", ctx, accountName, limit, changedBy, reason)
+	ret0, _ := ret[0].(entities.Account)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SetCreditLimit indicates an expected call of SetCreditLimit
+func (mr *MockBankingServiceMockRecorder) SetCreditLimit(ctx, accountName, limit, changedBy, reason interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetCreditLimit", reflect.TypeOf((*MockBankingService)(nil).SetCreditLimit), ctx, accountName, limit, changedBy, reason)
+}
+
+// GetCreditLimitChanges mocks base method
+func (m *MockBankingService) GetCreditLimitChanges(ctx context.Context, accountName string) ([]entities.CreditLimitChange, error) {
+	ret := m.ctrl.Call(m, "GetCreditLimitChanges", ctx, accountName)
+	ret0, _ := ret[0].([]entities.CreditLimitChange)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetCreditLimitChanges indicates an expected call of GetCreditLimitChanges
+func (mr *MockBankingServiceMockRecorder) GetCreditLimitChanges(ctx, accountName interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetCreditLimitChanges", reflect.TypeOf((*MockBankingService)(nil).GetCreditLimitChanges), ctx, accountName)
+}
+
+// CreateQuote mocks base method
+func (m *MockBankingService) CreateQuote(ctx context.Context, from, to entities.Currency, amount decimal.Decimal) (entities.Quote, error) {
+	ret := m.ctrl.Call(m, "CreateQuote", ctx, from, to, amount)
+	ret0, _ := ret[0].(entities.Quote)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateQuote indicates an expected call of CreateQuote
+func (mr *MockBankingServiceMockRecorder) CreateQuote(ctx, from, to, amount interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateQuote", reflect.TypeOf((*MockBankingService)(nil).CreateQuote), ctx, from, to, amount)
+}
+
+// SendFXPayment mocks base method
+func (m *MockBankingService) SendFXPayment(ctx context.Context, from, to entities.Account, amount decimal.Decimal, quoteID int, details entities.PaymentDetails) (entities.TransferReceipt, error) {
+	ret := m.ctrl.Call(m, "SendFXPayment", ctx, from, to, amount, quoteID, details)
+	ret0, _ := ret[0].(entities.TransferReceipt)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SendFXPayment indicates an expected call of SendFXPayment
+func (mr *MockBankingServiceMockRecorder) SendFXPayment(ctx, from, to, amount, quoteID, details interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SendFXPayment", reflect.TypeOf((*MockBankingService)(nil).SendFXPayment), ctx, from, to, amount, quoteID, details)
+}
+
+// Authorize mocks base method
+func (m *MockBankingService) Authorize(ctx context.Context, from, to entities.Account, amount decimal.Decimal) (entities.Hold, error) {
+	ret := m.ctrl.Call(m, "Authorize", ctx, from, to, amount)
+	ret0, _ := ret[0].(entities.Hold)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Authorize indicates an expected call of Authorize
+func (mr *MockBankingServiceMockRecorder) Authorize(ctx, from, to, amount interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Authorize", reflect.TypeOf((*MockBankingService)(nil).Authorize), ctx, from, to, amount)
+}
+
+// GetHold mocks base method
+func (m *MockBankingService) GetHold(ctx context.Context, id int) (entities.Hold, error) {
+	ret := m.ctrl.Call(m, "GetHold", ctx, id)
+	ret0, _ := ret[0].(entities.Hold)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetHold indicates an expected call of GetHold
+func (mr *MockBankingServiceMockRecorder) GetHold(ctx, id interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetHold", reflect.TypeOf((*MockBankingService)(nil).GetHold), ctx, id)
+}
+
+// CaptureHold mocks base method
+func (m *MockBankingService) CaptureHold(ctx context.Context, id int, amount decimal.Decimal) (entities.TransferReceipt, error) {
+	ret := m.ctrl.Call(m, "CaptureHold", ctx, id, amount)
+	ret0, _ := ret[0].(entities.TransferReceipt)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CaptureHold indicates an expected call of CaptureHold
+func (mr *MockBankingServiceMockRecorder) CaptureHold(ctx, id, amount interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CaptureHold", reflect.TypeOf((*MockBankingService)(nil).CaptureHold), ctx, id, amount)
+}
+
+// VoidHold mocks base method
+func (m *MockBankingService) VoidHold(ctx context.Context, id int) (entities.Hold, error) {
+	ret := m.ctrl.Call(m, "VoidHold", ctx, id)
+	ret0, _ := ret[0].(entities.Hold)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// VoidHold indicates an expected call of VoidHold
+func (mr *MockBankingServiceMockRecorder) VoidHold(ctx, id interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "VoidHold", reflect.TypeOf((*MockBankingService)(nil).VoidHold), ctx, id)
+}
+
+// ExpireHolds mocks base method
+func (m *MockBankingService) ExpireHolds(ctx context.Context) (int, error) {
+	ret := m.ctrl.Call(m, "ExpireHolds", ctx)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ExpireHolds indicates an expected call of ExpireHolds
+func (mr *MockBankingServiceMockRecorder) ExpireHolds(ctx interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ExpireHolds", reflect.TypeOf((*MockBankingService)(nil).ExpireHolds), ctx)
+}
+
+// CreateScheduledPayment mocks base method
+func (m *MockBankingService) CreateScheduledPayment(ctx context.Context, payment entities.ScheduledPayment) (entities.ScheduledPayment, error) {
+	ret := m.ctrl.Call(m, "CreateScheduledPayment", ctx, payment)
+	ret0, _ := ret[0].(entities.ScheduledPayment)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateScheduledPayment indicates an expected call of CreateScheduledPayment
+func (mr *MockBankingServiceMockRecorder) CreateScheduledPayment(ctx, payment interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateScheduledPayment", reflect.TypeOf((*MockBankingService)(nil).CreateScheduledPayment), ctx, payment)
+}
+
+// GetScheduledPayment mocks base method
+func (m *MockBankingService) GetScheduledPayment(ctx context.Context, id int) (entities.ScheduledPayment, error) {
+	ret := m.ctrl.Call(m, "GetScheduledPayment", ctx, id)
+	ret0, _ := ret[0].(entities.ScheduledPayment)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetScheduledPayment indicates an expected call of GetScheduledPayment
+func (mr *MockBankingServiceMockRecorder) GetScheduledPayment(ctx, id interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetScheduledPayment", reflect.TypeOf((*MockBankingService)(nil).GetScheduledPayment), ctx, id)
+}
+
+// GetScheduledPayments mocks base method
+func (m *MockBankingService) GetScheduledPayments(ctx context.Context, accountName string) ([]entities.ScheduledPayment, error) {
+	ret := m.ctrl.Call(m, "GetScheduledPayments", ctx, accountName)
+	ret0, _ := ret[0].([]entities.ScheduledPayment)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetScheduledPayments indicates an expected call of GetScheduledPayments
+func (mr *MockBankingServiceMockRecorder) GetScheduledPayments(ctx, accountName interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetScheduledPayments", reflect.TypeOf((*MockBankingService)(nil).GetScheduledPayments), ctx, accountName)
+}
+
+// UpdateScheduledPayment mocks base method
+func (m *MockBankingService) UpdateScheduledPayment(ctx context.Context, id int, update entities.ScheduledPaymentUpdate) (entities.ScheduledPayment, error) {
+	ret := m.ctrl.Call(m, "UpdateScheduledPayment", ctx, id, update)
+	ret0, _ := ret[0].(entities.ScheduledPayment)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UpdateScheduledPayment indicates an expected call of UpdateScheduledPayment
+func (mr *MockBankingServiceMockRecorder) UpdateScheduledPayment(ctx, id, update interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateScheduledPayment", reflect.TypeOf((*MockBankingService)(nil).UpdateScheduledPayment), ctx, id, update)
+}
+
+// CancelScheduledPayment mocks base method
+func (m *MockBankingService) CancelScheduledPayment(ctx context.Context, id int) (entities.ScheduledPayment, error) {
+	ret := m.ctrl.Call(m, "CancelScheduledPayment", ctx, id)
+	ret0, _ := ret[0].(entities.ScheduledPayment)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CancelScheduledPayment indicates an expected call of CancelScheduledPayment
+func (mr *MockBankingServiceMockRecorder) CancelScheduledPayment(ctx, id interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CancelScheduledPayment", reflect.TypeOf((*MockBankingService)(nil).CancelScheduledPayment), ctx, id)
+}
+
+// GetScheduledRuns mocks base method
+func (m *MockBankingService) GetScheduledRuns(ctx context.Context, id int) ([]entities.ScheduledRun, error) {
+	ret := m.ctrl.Call(m, "GetScheduledRuns", ctx, id)
+	ret0, _ := ret[0].([]entities.ScheduledRun)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetScheduledRuns indicates an expected call of GetScheduledRuns
+func (mr *MockBankingServiceMockRecorder) GetScheduledRuns(ctx, id interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetScheduledRuns", reflect.TypeOf((*MockBankingService)(nil).GetScheduledRuns), ctx, id)
+}
+
+// RunScheduledPayments mocks base method
+func (m *MockBankingService) RunScheduledPayments(ctx context.Context) (int, error) {
+	ret := m.ctrl.Call(m, "RunScheduledPayments", ctx)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RunScheduledPayments indicates an expected call of RunScheduledPayments
+func (mr *MockBankingServiceMockRecorder) RunScheduledPayments(ctx interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RunScheduledPayments", reflect.TypeOf((*MockBankingService)(nil).RunScheduledPayments), ctx)
+}
+
+// CreateAPIKey mocks base method
+func (m *MockBankingService) CreateAPIKey(ctx context.Context, name string, role entities.Role, ownerID string) (entities.IssuedAPIKey, error) {
+	ret := m.ctrl.Call(m, "CreateAPIKey", ctx, name, role, ownerID)
+	ret0, _ := ret[0].(entities.IssuedAPIKey)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateAPIKey indicates an expected call of CreateAPIKey
+func (mr *MockBankingServiceMockRecorder) CreateAPIKey(ctx, name, role, ownerID interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateAPIKey", reflect.TypeOf((*MockBankingService)(nil).CreateAPIKey), ctx, name, role, ownerID)
+}
+
+// GetAPIKeys mocks base method
+func (m *MockBankingService) GetAPIKeys(ctx context.Context) ([]entities.APIKey, error) {
+	ret := m.ctrl.Call(m, "GetAPIKeys", ctx)
+	ret0, _ := ret[0].([]entities.APIKey)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetAPIKeys indicates an expected call of GetAPIKeys
+func (mr *MockBankingServiceMockRecorder) GetAPIKeys(ctx interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAPIKeys", reflect.TypeOf((*MockBankingService)(nil).GetAPIKeys), ctx)
+}
+
+// RevokeAPIKey mocks base method
+func (m *MockBankingService) RevokeAPIKey(ctx context.Context, keyID string) (entities.APIKey, error) {
+	ret := m.ctrl.Call(m, "RevokeAPIKey", ctx, keyID)
+	ret0, _ := ret[0].(entities.APIKey)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RevokeAPIKey indicates an expected call of RevokeAPIKey
+func (mr *MockBankingServiceMockRecorder) RevokeAPIKey(ctx, keyID interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RevokeAPIKey", reflect.TypeOf((*MockBankingService)(nil).RevokeAPIKey), ctx, keyID)
+}
+
+// GetAuditLog mocks base method
+func (m *MockBankingService) GetAuditLog(ctx context.Context, filter entities.AuditFilter) (entities.AuditPage, error) {
+	ret := m.ctrl.Call(m, "GetAuditLog", ctx, filter)
+	ret0, _ := ret[0].(entities.AuditPage)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetAuditLog indicates an expected call of GetAuditLog
+func (mr *MockBankingServiceMockRecorder) GetAuditLog(ctx, filter interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAuditLog", reflect.TypeOf((*MockBankingService)(nil).GetAuditLog), ctx, filter)
+}
+
+// VerifyAuditLog mocks base method
+func (m *MockBankingService) VerifyAuditLog(ctx context.Context) (entities.AuditVerification, error) {
+	ret := m.ctrl.Call(m, "VerifyAuditLog", ctx)
+	ret0, _ := ret[0].(entities.AuditVerification)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// VerifyAuditLog indicates an expected call of VerifyAuditLog
+func (mr *MockBankingServiceMockRecorder) VerifyAuditLog(ctx interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "VerifyAuditLog", reflect.TypeOf((*MockBankingService)(nil).VerifyAuditLog), ctx)
 }
@@ -0,0 +1,61 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: service.go
+
+// Package mocks is a generated GoMock package.
+package mocks
+
+import (
+	context "context"
+	gomock "github.com/golang/mock/gomock"
+	entities "github.com/twonegatives/coinsph_challenge/pkg/entities"
+	reflect "reflect"
+)
+
+// MockReconciliationService is a mock of ReconciliationService interface
+type MockReconciliationService struct {
+	ctrl     *gomock.Controller
+	recorder *MockReconciliationServiceMockRecorder
+}
+
+// MockReconciliationServiceMockRecorder is the mock recorder for MockReconciliationService
+type MockReconciliationServiceMockRecorder struct {
+	mock *MockReconciliationService
+}
+
+// NewMockReconciliationService creates a new mock instance
+func NewMockReconciliationService(ctrl *gomock.Controller) *MockReconciliationService {
+	mock := &MockReconciliationService{ctrl: ctrl}
+	mock.recorder = &MockReconciliationServiceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use
+func (m *MockReconciliationService) EXPECT() *MockReconciliationServiceMockRecorder {
+	return m.recorder
+}
+
+// Reconcile mocks base method
+func (m *MockReconciliationService) Reconcile(ctx context.Context) (entities.ReconciliationReport, error) {
+	ret := m.ctrl.Call(m, "Reconcile", ctx)
+	ret0, _ := ret[0].(entities.ReconciliationReport)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Reconcile indicates an expected call of Reconcile
+func (mr *MockReconciliationServiceMockRecorder) Reconcile(ctx interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Reconcile", reflect.TypeOf((*MockReconciliationService)(nil).Reconcile), ctx)
+}
+
+// GetLastReport mocks base method
+func (m *MockReconciliationService) GetLastReport(ctx context.Context) (entities.ReconciliationReport, error) {
+	ret := m.ctrl.Call(m, "GetLastReport", ctx)
+	ret0, _ := ret[0].(entities.ReconciliationReport)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetLastReport indicates an expected call of GetLastReport
+func (mr *MockReconciliationServiceMockRecorder) GetLastReport(ctx interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetLastReport", reflect.TypeOf((*MockReconciliationService)(nil).GetLastReport), ctx)
+}
@@ -11,6 +11,7 @@ import (
 	entities "github.com/twonegatives/coinsph_challenge/pkg/entities"
 	storage "github.com/twonegatives/coinsph_challenge/pkg/storage"
 	reflect "reflect"
+	time "time"
 )
 
 // MockStorage is a mock of Storage interface
@@ -74,42 +75,107 @@ func (mr *MockStorageMockRecorder) RollbackTx(ctx interface{}) *gomock.Call {
 }
 
 // CreateAccount mocks base method
-func (m *MockStorage) CreateAccount(ctx context.Context, accountName string) (entities.Account, error) {
-	ret := m.ctrl.Call(m, "CreateAccount", ctx, accountName)
+func (m *MockStorage) CreateAccount(ctx context.Context, account entities.Account) (entities.Account, error) {
+	ret := m.ctrl.Call(m, "CreateAccount", ctx, account)
 	ret0, _ := ret[0].(entities.Account)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // CreateAccount indicates an expected call of CreateAccount
-func (mr *MockStorageMockRecorder) CreateAccount(ctx, accountName interface{}) *gomock.Call {
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateAccount", reflect.TypeOf((*MockStorage)(nil).CreateAccount), ctx, accountName)
+func (mr *MockStorageMockRecorder) CreateAccount(ctx, account interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateAccount", reflect.TypeOf((*MockStorage)(nil).CreateAccount), ctx, account)
+}
+
+// GetAccount mocks base method
+func (m *MockStorage) GetAccount(ctx context.Context, name string) (entities.Account, error) {
+	ret := m.ctrl.Call(m, "GetAccount", ctx, name)
+	ret0, _ := ret[0].(entities.Account)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetAccount indicates an expected call of GetAccount
+func (mr *MockStorageMockRecorder) GetAccount(ctx, name interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAccount", reflect.TypeOf((*MockStorage)(nil).GetAccount), ctx, name)
 }
 
 // GetAccountsList mocks base method
-func (m *MockStorage) GetAccountsList(ctx context.Context) ([]entities.Account, error) {
-	ret := m.ctrl.Call(m, "GetAccountsList", ctx)
+func (m *MockStorage) GetAccountsList(ctx context.Context, filter entities.AccountsFilter) ([]entities.Account, error) {
+	ret := m.ctrl.Call(m, "GetAccountsList", ctx, filter)
 	ret0, _ := ret[0].([]entities.Account)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // GetAccountsList indicates an expected call of GetAccountsList
-func (mr *MockStorageMockRecorder) GetAccountsList(ctx interface{}) *gomock.Call {
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAccountsList", reflect.TypeOf((*MockStorage)(nil).GetAccountsList), ctx)
+func (mr *MockStorageMockRecorder) GetAccountsList(ctx, filter interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAccountsList", reflect.TypeOf((*MockStorage)(nil).GetAccountsList), ctx, filter)
 }
 
 // GetPaymentsList mocks base method
-func (m *MockStorage) GetPaymentsList(ctx context.Context) ([]entities.Payment, error) {
-	ret := m.ctrl.Call(m, "GetPaymentsList", ctx)
+func (m *MockStorage) GetPaymentsList(ctx context.Context, filter entities.PaymentsFilter) ([]entities.Payment, error) {
+	ret := m.ctrl.Call(m, "GetPaymentsList", ctx, filter)
 	ret0, _ := ret[0].([]entities.Payment)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // GetPaymentsList indicates an expected call of GetPaymentsList
-func (mr *MockStorageMockRecorder) GetPaymentsList(ctx interface{}) *gomock.Call {
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetPaymentsList", reflect.TypeOf((*MockStorage)(nil).GetPaymentsList), ctx)
+func (mr *MockStorageMockRecorder) GetPaymentsList(ctx, filter interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetPaymentsList", reflect.TypeOf((*MockStorage)(nil).GetPaymentsList), ctx, filter)
+}
+
+// GetAccountPayments mocks base method
+func (m *MockStorage) GetAccountPayments(ctx context.Context, accountID int, filter entities.PaymentsFilter) ([]entities.Payment, error) {
+	ret := m.ctrl.Call(m, "GetAccountPayments", ctx, accountID, filter)
+	ret0, _ := ret[0].([]entities.Payment)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetAccountPayments indicates an expected call of GetAccountPayments
+func (mr *MockStorageMockRecorder) GetAccountPayments(ctx, accountID, filter interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAccountPayments", reflect.TypeOf((*MockStorage)(nil).GetAccountPayments), ctx, accountID, filter)
+}
+
+// GetTransaction mocks base method
+func (m *MockStorage) GetTransaction(ctx context.Context, id int) (entities.Transaction, error) {
+	ret := m.ctrl.Call(m, "GetTransaction", ctx, id)
+	ret0, _ := ret[0].(entities.Transaction)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetTransaction indicates an expected call of GetTransaction
+func (mr *MockStorageMockRecorder) GetTransaction(ctx, id interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetTransaction", reflect.TypeOf((*MockStorage)(nil).GetTransaction), ctx, id)
+}
+
+// GetTransactionPayments mocks base method
+func (m *MockStorage) GetTransactionPayments(ctx context.Context, transactionID int) ([]entities.Payment, error) {
+	ret := m.ctrl.Call(m, "GetTransactionPayments", ctx, transactionID)
+	ret0, _ := ret[0].([]entities.Payment)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetTransactionPayments indicates an expected call of GetTransactionPayments
+func (mr *MockStorageMockRecorder) GetTransactionPayments(ctx, transactionID interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetTransactionPayments", reflect.TypeOf((*MockStorage)(nil).GetTransactionPayments), ctx, transactionID)
+}
+
+// GetLinkedTransactions mocks base method
+func (m *MockStorage) GetLinkedTransactions(ctx context.Context, originalID int) ([]entities.Transaction, error) {
+	ret := m.ctrl.Call(m, "GetLinkedTransactions", ctx, originalID)
+	ret0, _ := ret[0].([]entities.Transaction)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetLinkedTransactions indicates an expected call of GetLinkedTransactions
+func (mr *MockStorageMockRecorder) GetLinkedTransactions(ctx, originalID interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetLinkedTransactions", reflect.TypeOf((*MockStorage)(nil).GetLinkedTransactions), ctx, originalID)
 }
 
 // GetAccountForUpdate mocks base method
@@ -125,16 +191,16 @@ func (mr *MockStorageMockRecorder) GetAccountForUpdate(ctx, account interface{})
 }
 
 // CreateTransaction mocks base method
-func (m *MockStorage) CreateTransaction(ctx context.Context) (entities.Transaction, error) {
-	ret := m.ctrl.Call(m, "CreateTransaction", ctx)
+func (m *MockStorage) CreateTransaction(ctx context.Context, transaction entities.Transaction) (entities.Transaction, error) {
+	ret := m.ctrl.Call(m, "CreateTransaction", ctx, transaction)
 	ret0, _ := ret[0].(entities.Transaction)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // CreateTransaction indicates an expected call of CreateTransaction
-func (mr *MockStorageMockRecorder) CreateTransaction(ctx interface{}) *gomock.Call {
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateTransaction", reflect.TypeOf((*MockStorage)(nil).CreateTransaction), ctx)
+func (mr *MockStorageMockRecorder) CreateTransaction(ctx, transaction interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateTransaction", reflect.TypeOf((*MockStorage)(nil).CreateTransaction), ctx, transaction)
 }
 
 // SendPayment mocks base method
@@ -161,6 +227,435 @@ func (mr *MockStorageMockRecorder) SetAccountBalance(ctx, account interface{}) *
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetAccountBalance", reflect.TypeOf((*MockStorage)(nil).SetAccountBalance), ctx, account)
 }
 
+// SetAccountCreditLimit mocks base method
+func (m *MockStorage) SetAccountCreditLimit(ctx context.Context, account entities.Account) error {
+	ret := m.ctrl.Call(m, "SetAccountCreditLimit", ctx, account)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetAccountCreditLimit indicates an expected call of SetAccountCreditLimit
+func (mr *MockStorageMockRecorder) SetAccountCreditLimit(ctx, account interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetAccountCreditLimit", reflect.TypeOf((*MockStorage)(nil).SetAccountCreditLimit), ctx, account)
+}
+
+// SetAccountStatus mocks base method
+func (m *MockStorage) SetAccountStatus(ctx context.Context, account entities.Account) error {
+	ret := m.ctrl.Call(m, "SetAccountStatus", ctx, account)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetAccountStatus indicates an expected call of SetAccountStatus
+func (mr *MockStorageMockRecorder) SetAccountStatus(ctx, account interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetAccountStatus", reflect.TypeOf((*MockStorage)(nil).SetAccountStatus), ctx, account)
+}
+
+// SetAccountHeld mocks base method
+func (m *MockStorage) SetAccountHeld(ctx context.Context, account entities.Account) error {
+	ret := m.ctrl.Call(m, "SetAccountHeld", ctx, account)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetAccountHeld indicates an expected call of SetAccountHeld
+func (mr *MockStorageMockRecorder) SetAccountHeld(ctx, account interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetAccountHeld", reflect.TypeOf((*MockStorage)(nil).SetAccountHeld), ctx, account)
+}
+
+// SetAccountProfile mocks base method
+func (m *MockStorage) SetAccountProfile(ctx context.Context, account entities.Account) error {
+	ret := m.ctrl.Call(m, "SetAccountProfile", ctx, account)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetAccountProfile indicates an expected call of SetAccountProfile
+func (mr *MockStorageMockRecorder) SetAccountProfile(ctx, account interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetAccountProfile", reflect.TypeOf((*MockStorage)(nil).SetAccountProfile), ctx, account)
+}
+
+// CreateCreditLimitChange mocks base method
+func (m *MockStorage) CreateCreditLimitChange(ctx context.Context, change entities.CreditLimitChange) (entities.CreditLimitChange, error) {
+	ret := m.ctrl.Call(m, "CreateCreditLimitChange", ctx, change)
+	ret0, _ := ret[0].(entities.CreditLimitChange)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateCreditLimitChange indicates an expected call of CreateCreditLimitChange
+func (mr *MockStorageMockRecorder) CreateCreditLimitChange(ctx, change interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateCreditLimitChange", reflect.TypeOf((*MockStorage)(nil).CreateCreditLimitChange), ctx, change)
+}
+
+// GetCreditLimitChanges mocks base method
+func (m *MockStorage) GetCreditLimitChanges(ctx context.Context, accountID int) ([]entities.CreditLimitChange, error) {
+	ret := m.ctrl.Call(m, "GetCreditLimitChanges", ctx, accountID)
+	ret0, _ := ret[0].([]entities.CreditLimitChange)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetCreditLimitChanges indicates an expected call of GetCreditLimitChanges
+func (mr *MockStorageMockRecorder) GetCreditLimitChanges(ctx, accountID interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetCreditLimitChanges", reflect.TypeOf((*MockStorage)(nil).GetCreditLimitChanges), ctx, accountID)
+}
+
+// CreateHold mocks base method
+func (m *MockStorage) CreateHold(ctx context.Context, hold entities.Hold) (entities.Hold, error) {
+	ret := m.ctrl.Call(m, "CreateHold", ctx, hold)
+	ret0, _ := ret[0].(entities.Hold)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateHold indicates an expected call of CreateHold
+func (mr *MockStorageMockRecorder) CreateHold(ctx, hold interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateHold", reflect.TypeOf((*MockStorage)(nil).CreateHold), ctx, hold)
+}
+
+// GetHold mocks base method
+func (m *MockStorage) GetHold(ctx context.Context, id int) (entities.Hold, error) {
+	ret := m.ctrl.Call(m, "GetHold", ctx, id)
+	ret0, _ := ret[0].(entities.Hold)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetHold indicates an expected call of GetHold
+func (mr *MockStorageMockRecorder) GetHold(ctx, id interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetHold", reflect.TypeOf((*MockStorage)(nil).GetHold), ctx, id)
+}
+
+// GetExpiredHolds mocks base method
+func (m *MockStorage) GetExpiredHolds(ctx context.Context, now time.Time, limit int) ([]entities.Hold, error) {
+	ret := m.ctrl.Call(m, "GetExpiredHolds", ctx, now, limit)
+	ret0, _ := ret[0].([]entities.Hold)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetExpiredHolds indicates an expected call of GetExpiredHolds
+func (mr *MockStorageMockRecorder) GetExpiredHolds(ctx, now, limit interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetExpiredHolds", reflect.TypeOf((*MockStorage)(nil).GetExpiredHolds), ctx, now, limit)
+}
+
+// SetHoldStatus mocks base method
+func (m *MockStorage) SetHoldStatus(ctx context.Context, hold entities.Hold) error {
+	ret := m.ctrl.Call(m, "SetHoldStatus", ctx, hold)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetHoldStatus indicates an expected call of SetHoldStatus
+func (mr *MockStorageMockRecorder) SetHoldStatus(ctx, hold interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetHoldStatus", reflect.TypeOf((*MockStorage)(nil).SetHoldStatus), ctx, hold)
+}
+
+// CreateScheduledPayment mocks base method
+func (m *MockStorage) CreateScheduledPayment(ctx context.Context, payment entities.ScheduledPayment) (entities.ScheduledPayment, error) {
+	ret := m.ctrl.Call(m, "CreateScheduledPayment", ctx, payment)
+	ret0, _ := ret[0].(entities.ScheduledPayment)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateScheduledPayment indicates an expected call of CreateScheduledPayment
+func (mr *MockStorageMockRecorder) CreateScheduledPayment(ctx, payment interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateScheduledPayment", reflect.TypeOf((*MockStorage)(nil).CreateScheduledPayment), ctx, payment)
+}
+
+// GetScheduledPayment mocks base method
+func (m *MockStorage) GetScheduledPayment(ctx context.Context, id int) (entities.ScheduledPayment, error) {
+	ret := m.ctrl.Call(m, "GetScheduledPayment", ctx, id)
+	ret0, _ := ret[0].(entities.ScheduledPayment)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetScheduledPayment indicates an expected call of GetScheduledPayment
+func (mr *MockStorageMockRecorder) GetScheduledPayment(ctx, id interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetScheduledPayment", reflect.TypeOf((*MockStorage)(nil).GetScheduledPayment), ctx, id)
+}
+
+// GetScheduledPayments mocks base method
+func (m *MockStorage) GetScheduledPayments(ctx context.Context, accountID int) ([]entities.ScheduledPayment, error) {
+	ret := m.ctrl.Call(m, "GetScheduledPayments", ctx, accountID)
+	ret0, _ := ret[0].([]entities.ScheduledPayment)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetScheduledPayments indicates an expected call of GetScheduledPayments
+func (mr *MockStorageMockRecorder) GetScheduledPayments(ctx, accountID interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetScheduledPayments", reflect.TypeOf((*MockStorage)(nil).GetScheduledPayments), ctx, accountID)
+}
+
+// GetScheduledPaymentForUpdate mocks base method
+func (m *MockStorage) GetScheduledPaymentForUpdate(ctx context.Context, payment *entities.ScheduledPayment) error {
+	ret := m.ctrl.Call(m, "GetScheduledPaymentForUpdate", ctx, payment)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// GetScheduledPaymentForUpdate indicates an expected call of GetScheduledPaymentForUpdate
+func (mr *MockStorageMockRecorder) GetScheduledPaymentForUpdate(ctx, payment interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetScheduledPaymentForUpdate", reflect.TypeOf((*MockStorage)(nil).GetScheduledPaymentForUpdate), ctx, payment)
+}
+
+// UpdateScheduledPayment mocks base method
+func (m *MockStorage) UpdateScheduledPayment(ctx context.Context, payment entities.ScheduledPayment) error {
+	ret := m.ctrl.Call(m, "UpdateScheduledPayment", ctx, payment)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateScheduledPayment indicates an expected call of UpdateScheduledPayment
+func (mr *MockStorageMockRecorder) UpdateScheduledPayment(ctx, payment interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateScheduledPayment", reflect.TypeOf((*MockStorage)(nil).UpdateScheduledPayment), ctx, payment)
+}
+
+// ClaimScheduledPayments mocks base method
+func (m *MockStorage) ClaimScheduledPayments(ctx context.Context, owner string, now, leaseUntil time.Time, limit int) ([]entities.ScheduledPayment, error) {
+	ret := m.ctrl.Call(m, "ClaimScheduledPayments", ctx, owner, now, leaseUntil, limit)
+	ret0, _ := ret[0].([]entities.ScheduledPayment)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ClaimScheduledPayments indicates an expected call of ClaimScheduledPayments
+func (mr *MockStorageMockRecorder) ClaimScheduledPayments(ctx, owner, now, leaseUntil, limit interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ClaimScheduledPayments", reflect.TypeOf((*MockStorage)(nil).ClaimScheduledPayments), ctx, owner, now, leaseUntil, limit)
+}
+
+// CreateScheduledRun mocks base method
+func (m *MockStorage) CreateScheduledRun(ctx context.Context, run entities.ScheduledRun) (entities.ScheduledRun, error) {
+	ret := m.ctrl.Call(m, "CreateScheduledRun", ctx, run)
+	ret0, _ := ret[0].(entities.ScheduledRun)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateScheduledRun indicates an expected call of CreateScheduledRun
+func (mr *MockStorageMockRecorder) CreateScheduledRun(ctx, run interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateScheduledRun", reflect.TypeOf((*MockStorage)(nil).CreateScheduledRun), ctx, run)
+}
+
+// GetScheduledRuns mocks base method
+func (m *MockStorage) GetScheduledRuns(ctx context.Context, scheduleID int) ([]entities.ScheduledRun, error) {
+	ret := m.ctrl.Call(m, "GetScheduledRuns", ctx, scheduleID)
+	ret0, _ := ret[0].([]entities.ScheduledRun)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetScheduledRuns indicates an expected call of GetScheduledRuns
+func (mr *MockStorageMockRecorder) GetScheduledRuns(ctx, scheduleID interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetScheduledRuns", reflect.TypeOf((*MockStorage)(nil).GetScheduledRuns), ctx, scheduleID)
+}
+
+// CreateQuote mocks base method
+func (m *MockStorage) CreateQuote(ctx context.Context, quote entities.Quote) (entities.Quote, error) {
+	ret := m.ctrl.Call(m, "CreateQuote", ctx, quote)
+	ret0, _ := ret[0].(entities.Quote)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateQuote indicates an expected call of CreateQuote
+func (mr *MockStorageMockRecorder) CreateQuote(ctx, quote interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateQuote", reflect.TypeOf((*MockStorage)(nil).CreateQuote), ctx, quote)
+}
+
+// GetQuoteForUpdate mocks base method
+func (m *MockStorage) GetQuoteForUpdate(ctx context.Context, quote *entities.Quote) error {
+	ret := m.ctrl.Call(m, "GetQuoteForUpdate", ctx, quote)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// GetQuoteForUpdate indicates an expected call of GetQuoteForUpdate
+func (mr *MockStorageMockRecorder) GetQuoteForUpdate(ctx, quote interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetQuoteForUpdate", reflect.TypeOf((*MockStorage)(nil).GetQuoteForUpdate), ctx, quote)
+}
+
+// SetQuoteTransaction mocks base method
+func (m *MockStorage) SetQuoteTransaction(ctx context.Context, quote entities.Quote) error {
+	ret := m.ctrl.Call(m, "SetQuoteTransaction", ctx, quote)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetQuoteTransaction indicates an expected call of SetQuoteTransaction
+func (mr *MockStorageMockRecorder) SetQuoteTransaction(ctx, quote interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetQuoteTransaction", reflect.TypeOf((*MockStorage)(nil).SetQuoteTransaction), ctx, quote)
+}
+
+// CreateIdempotencyRecord mocks base method
+func (m *MockStorage) CreateIdempotencyRecord(ctx context.Context, record entities.IdempotencyRecord) (bool, error) {
+	ret := m.ctrl.Call(m, "CreateIdempotencyRecord", ctx, record)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateIdempotencyRecord indicates an expected call of CreateIdempotencyRecord
+func (mr *MockStorageMockRecorder) CreateIdempotencyRecord(ctx, record interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateIdempotencyRecord", reflect.TypeOf((*MockStorage)(nil).CreateIdempotencyRecord), ctx, record)
+}
+
+// GetIdempotencyRecord mocks base method
+func (m *MockStorage) GetIdempotencyRecord(ctx context.Context, key string) (entities.IdempotencyRecord, error) {
+	ret := m.ctrl.Call(m, "GetIdempotencyRecord", ctx, key)
+	ret0, _ := ret[0].(entities.IdempotencyRecord)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetIdempotencyRecord indicates an expected call of GetIdempotencyRecord
+func (mr *MockStorageMockRecorder) GetIdempotencyRecord(ctx, key interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetIdempotencyRecord", reflect.TypeOf((*MockStorage)(nil).GetIdempotencyRecord), ctx, key)
+}
+
+// CommitIdempotencyRecord mocks base method
+func (m *MockStorage) CommitIdempotencyRecord(ctx context.Context, record entities.IdempotencyRecord) error {
+	ret := m.ctrl.Call(m, "CommitIdempotencyRecord", ctx, record)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CommitIdempotencyRecord indicates an expected call of CommitIdempotencyRecord
+func (mr *MockStorageMockRecorder) CommitIdempotencyRecord(ctx, record interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CommitIdempotencyRecord", reflect.TypeOf((*MockStorage)(nil).CommitIdempotencyRecord), ctx, record)
+}
+
+// CompleteIdempotencyRecord mocks base method
+func (m *MockStorage) CompleteIdempotencyRecord(ctx context.Context, record entities.IdempotencyRecord) error {
+	ret := m.ctrl.Call(m, "CompleteIdempotencyRecord", ctx, record)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CompleteIdempotencyRecord indicates an expected call of CompleteIdempotencyRecord
+func (mr *MockStorageMockRecorder) CompleteIdempotencyRecord(ctx, record interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CompleteIdempotencyRecord", reflect.TypeOf((*MockStorage)(nil).CompleteIdempotencyRecord), ctx, record)
+}
+
+// DeleteIdempotencyRecord mocks base method
+func (m *MockStorage) DeleteIdempotencyRecord(ctx context.Context, record entities.IdempotencyRecord) error {
+	ret := m.ctrl.Call(m, "DeleteIdempotencyRecord", ctx, record)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteIdempotencyRecord indicates an expected call of DeleteIdempotencyRecord
+func (mr *MockStorageMockRecorder) DeleteIdempotencyRecord(ctx, record interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteIdempotencyRecord", reflect.TypeOf((*MockStorage)(nil).DeleteIdempotencyRecord), ctx, record)
+}
+
+// CreateAPIKey mocks base method
+func (m *MockStorage) CreateAPIKey(ctx context.Context, key entities.APIKey) (entities.APIKey, error) {
+	ret := m.ctrl.Call(m, "CreateAPIKey", ctx, key)
+	ret0, _ := ret[0].(entities.APIKey)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateAPIKey indicates an expected call of CreateAPIKey
+func (mr *MockStorageMockRecorder) CreateAPIKey(ctx, key interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateAPIKey", reflect.TypeOf((*MockStorage)(nil).CreateAPIKey), ctx, key)
+}
+
+// GetAPIKey mocks base method
+func (m *MockStorage) GetAPIKey(ctx context.Context, keyID string) (entities.APIKey, error) {
+	ret := m.ctrl.Call(m, "GetAPIKey", ctx, keyID)
+	ret0, _ := ret[0].(entities.APIKey)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetAPIKey indicates an expected call of GetAPIKey
+func (mr *MockStorageMockRecorder) GetAPIKey(ctx, keyID interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAPIKey", reflect.TypeOf((*MockStorage)(nil).GetAPIKey), ctx, keyID)
+}
+
+// GetAPIKeys mocks base method
+func (m *MockStorage) GetAPIKeys(ctx context.Context) ([]entities.APIKey, error) {
+	ret := m.ctrl.Call(m, "GetAPIKeys", ctx)
+	ret0, _ := ret[0].([]entities.APIKey)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetAPIKeys indicates an expected call of GetAPIKeys
+func (mr *MockStorageMockRecorder) GetAPIKeys(ctx interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAPIKeys", reflect.TypeOf((*MockStorage)(nil).GetAPIKeys), ctx)
+}
+
+// RevokeAPIKey mocks base method
+func (m *MockStorage) RevokeAPIKey(ctx context.Context, key entities.APIKey) error {
+	ret := m.ctrl.Call(m, "RevokeAPIKey", ctx, key)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RevokeAPIKey indicates an expected call of RevokeAPIKey
+func (mr *MockStorageMockRecorder) RevokeAPIKey(ctx, key interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RevokeAPIKey", reflect.TypeOf((*MockStorage)(nil).RevokeAPIKey), ctx, key)
+}
+
+// AppendAuditEntry mocks base method
+func (m *MockStorage) AppendAuditEntry(ctx context.Context, entry entities.AuditEntry) (entities.AuditEntry, error) {
+	ret := m.ctrl.Call(m, "AppendAuditEntry", ctx, entry)
+	ret0, _ := ret[0].(entities.AuditEntry)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// AppendAuditEntry indicates an expected call of AppendAuditEntry
+func (mr *MockStorageMockRecorder) AppendAuditEntry(ctx, entry interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AppendAuditEntry", reflect.TypeOf((*MockStorage)(nil).AppendAuditEntry), ctx, entry)
+}
+
+// GetAuditEntries mocks base method
+func (m *MockStorage) GetAuditEntries(ctx context.Context, filter entities.AuditFilter) ([]entities.AuditEntry, error) {
+	ret := m.ctrl.Call(m, "GetAuditEntries", ctx, filter)
+	ret0, _ := ret[0].([]entities.AuditEntry)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetAuditEntries indicates an expected call of GetAuditEntries
+func (mr *MockStorageMockRecorder) GetAuditEntries(ctx, filter interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAuditEntries", reflect.TypeOf((*MockStorage)(nil).GetAuditEntries), ctx, filter)
+}
+
+// GetLedgerBalances mocks base method
+func (m *MockStorage) GetLedgerBalances(ctx context.Context) ([]entities.LedgerBalance, error) {
+	ret := m.ctrl.Call(m, "GetLedgerBalances", ctx)
+	ret0, _ := ret[0].([]entities.LedgerBalance)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetLedgerBalances indicates an expected call of GetLedgerBalances
+func (mr *MockStorageMockRecorder) GetLedgerBalances(ctx interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetLedgerBalances", reflect.TypeOf((*MockStorage)(nil).GetLedgerBalances), ctx)
+}
+
+// GetUnbalancedTransactions mocks base method
+func (m *MockStorage) GetUnbalancedTransactions(ctx context.Context) ([]entities.TransactionImbalance, error) {
+	ret := m.ctrl.Call(m, "GetUnbalancedTransactions", ctx)
+	ret0, _ := ret[0].([]entities.TransactionImbalance)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetUnbalancedTransactions indicates an expected call of GetUnbalancedTransactions
+func (mr *MockStorageMockRecorder) GetUnbalancedTransactions(ctx interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUnbalancedTransactions", reflect.TypeOf((*MockStorage)(nil).GetUnbalancedTransactions), ctx)
+}
+
 // MockTransactionBeginner is a mock of TransactionBeginner interface
 type MockTransactionBeginner struct {
 	ctrl     *gomock.Controller
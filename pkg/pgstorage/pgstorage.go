@@ -5,7 +5,10 @@ package pgstorage
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
+	"time"
 
+	"github.com/lib/pq"
 	"github.com/pkg/errors"
 	"github.com/shopspring/decimal"
 	"github.com/twonegatives/coinsph_challenge/pkg/entities"
@@ -65,78 +68,300 @@ func (s *PgStorage) tx() *sql.Tx {
 	return nil
 }
 
-// CreateAccount accepts account name as an argument and tries to
-// create a new account with such name. Returns the created Account
-// object on success.
-func (s *PgStorage) CreateAccount(ctx context.Context, accountName string) (entities.Account, error) {
-	query := `INSERT INTO accounts(name, balance, currency) VALUES($1, $2, $3) RETURNING id`
-	account := entities.Account{
-		Name:     accountName,
-		Currency: entities.USD,
-		Balance:  decimal.New(0, 0),
+// CreateAccount accepts an Account with name, currency, type (user unless set) and
+// profile filled in and tries to create a new account with such attributes and zero balance.
+// Returns the created Account object on success.
+func (s *PgStorage) CreateAccount(ctx context.Context, account entities.Account) (entities.Account, error) {
+	if account.Type == "" {
+		account.Type = entities.UserAccount
 	}
-	err := s.Handler.QueryRowContext(ctx, query, account.Name, account.Balance, account.Currency).Scan(&account.ID)
+
+	query := `
+		INSERT INTO accounts(name, type, balance, currency, owner_id, display_name, labels)
+		VALUES($1, $2, $3, $4, NULLIF($5, ''), NULLIF($6, ''), $7)
+		RETURNING id, created_at
+	`
+	account.Balance = decimal.New(0, 0)
+	account.Held = decimal.New(0, 0)
+	account.CreditLimit = decimal.New(0, 0)
+	account.Status = entities.ActiveAccount
+	err := s.Handler.QueryRowContext(ctx, query, account.Name, account.Type, account.Balance, account.Currency, account.OwnerID, account.DisplayName, labelsArray(account.Labels)).Scan(&account.ID, &account.CreatedAt)
 	return account, errors.Wrap(err, "can't create new account")
 }
 
-// GetAccountsList returns slice of Accounts currently existing in the system
-func (s *PgStorage) GetAccountsList(ctx context.Context) ([]entities.Account, error) {
-	query := `SELECT id, name, balance, currency FROM accounts`
-	rows, err := s.Handler.QueryContext(ctx, query)
+// GetAccount returns an Account found by its name
+func (s *PgStorage) GetAccount(ctx context.Context, name string) (entities.Account, error) {
+	account := entities.Account{Name: name}
+	query := `
+		SELECT id, type, status, balance, held, credit_limit, currency, COALESCE(owner_id, ''), COALESCE(display_name, ''), labels, created_at
+		FROM accounts
+		WHERE name = $1
+	`
+	err := s.Handler.QueryRowContext(ctx, query, name).Scan(&account.ID, &account.Type, &account.Status, &account.Balance, &account.Held, &account.CreditLimit, &account.Currency, &account.OwnerID, &account.DisplayName, pq.Array(&account.Labels), &account.CreatedAt)
+	if err == sql.ErrNoRows {
+		return account, errors.Wrapf(storage.ErrNotFound, "account %s", name)
+	}
+	return account, errors.Wrapf(err, "can't obtain account %s", name)
+}
+
+// accountsSortColumns maps sort attributes of accounts list to table columns
+var accountsSortColumns = map[entities.AccountsSort]string{
+	entities.SortAccountsByName:      "name",
+	entities.SortAccountsByBalance:   "balance",
+	entities.SortAccountsByCreatedAt: "created_at",
+}
+
+// GetAccountsList returns slice of Accounts matching the filter in requested order
+func (s *PgStorage) GetAccountsList(ctx context.Context, filter entities.AccountsFilter) ([]entities.Account, error) {
+	column, ok := accountsSortColumns[filter.Sort]
+	if !ok {
+		column = accountsSortColumns[entities.SortAccountsByName]
+	}
+
+	direction, comparison := "ASC", ">"
+	if filter.Descending {
+		direction, comparison = "DESC", "<"
+	}
+
+	where := whereClause{}
+	if filter.NamePrefix != "" {
+		where.add("name LIKE ?", escapeLikePattern(filter.NamePrefix)+"%")
+	}
+
+	if filter.OwnerID != "" {
+		where.add("owner_id = ?", filter.OwnerID)
+	}
+
+	if cursor := filter.After; cursor != nil {
+		var value interface{}
+		switch filter.Sort {
+		case entities.SortAccountsByBalance:
+			value = cursor.Balance
+		case entities.SortAccountsByCreatedAt:
+			value = cursor.CreatedAt
+		default:
+			value = cursor.Name
+		}
+		where.add("("+column+", id) "+comparison+" (?, ?)", value, cursor.ID)
+	}
+
+	query := "SELECT id, name, type, status, balance, held, credit_limit, currency, COALESCE(owner_id, ''), COALESCE(display_name, ''), labels, created_at FROM accounts" + where.String() +
+		" ORDER BY " + column + " " + direction + ", id " + direction
+	if filter.Limit > 0 {
+		query += " LIMIT " + where.arg(filter.Limit)
+	}
+
+	rows, err := s.Handler.QueryContext(ctx, query, where.args...)
 	if err != nil {
 		return nil, errors.Wrap(err, "can't query Accounts list")
 	}
 
 	defer rows.Close()
 
-	var accounts []entities.Account
+	accounts := []entities.Account{}
 	for rows.Next() {
 		var account entities.Account
-		err := rows.Scan(&account.ID, &account.Name, &account.Balance, &account.Currency)
+		err := rows.Scan(&account.ID, &account.Name, &account.Type, &account.Status, &account.Balance, &account.Held, &account.CreditLimit, &account.Currency, &account.OwnerID, &account.DisplayName, pq.Array(&account.Labels), &account.CreatedAt)
 		if err != nil {
 			return accounts, errors.Wrap(err, "can't scan Account db row")
 		}
 		accounts = append(accounts, account)
 	}
 
-	return accounts, nil
+	return accounts, errors.Wrap(rows.Err(), "can't iterate over Account db rows")
 }
 
-// GetPaymentsList returns slice of Payments currently existing in the system
-func (s *PgStorage) GetPaymentsList(ctx context.Context) ([]entities.Payment, error) {
+// selectPaymentsQuery is a base query used to fetch Payments along with
+// their accounts and transactions
+const selectPaymentsQuery = `
+	SELECT
+		payments.id,
+		owners.id,
+		owners.name,
+		counterparties.id,
+		counterparties.name,
+		transactions.id,
+		transactions.created_at,
+		transactions.kind,
+		COALESCE(transactions.external_reference, ''),
+		COALESCE(transactions.original_transaction_id, 0),
+		transactions.fee,
+		COALESCE(transactions.description, ''),
+		transactions.metadata,
+		direction,
+		amount,
+		payments.currency
+	FROM payments
+	INNER JOIN accounts AS owners ON payments.account_id = owners.id
+	INNER JOIN accounts AS counterparties ON payments.counterparty_id = counterparties.id
+	INNER JOIN transactions ON payments.transaction_id = transactions.id
+`
+
+// GetPaymentsList returns slice of Payments matching the filter ordered by
+// time of their transactions and IDs. Times are compared in UTC.
+func (s *PgStorage) GetPaymentsList(ctx context.Context, filter entities.PaymentsFilter) ([]entities.Payment, error) {
+	where := whereClause{}
+	if filter.AccountName != "" {
+		where.add("owners.name = ?", filter.AccountName)
+	}
+
+	return s.filterPayments(ctx, where, filter)
+}
+
+// GetAccountPayments returns slice of Payments of the account matching the filter.
+// AccountName of the filter is not taken into account. Payments are ordered the same
+// way GetPaymentsList orders them.
+func (s *PgStorage) GetAccountPayments(ctx context.Context, accountID int, filter entities.PaymentsFilter) ([]entities.Payment, error) {
+	where := whereClause{}
+	where.add("payments.account_id = ?", accountID)
+
+	return s.filterPayments(ctx, where, filter)
+}
+
+// filterPayments extends the conditions with ones set by the filter and queries a page of Payments
+func (s *PgStorage) filterPayments(ctx context.Context, where whereClause, filter entities.PaymentsFilter) ([]entities.Payment, error) {
+	if filter.Direction != "" {
+		where.add("payments.direction = ?", filter.Direction)
+	}
+
+	if filter.Kind != "" {
+		where.add("transactions.kind = ?", filter.Kind)
+	}
+
+	if filter.Reference != "" {
+		where.add("transactions.external_reference = ?", filter.Reference)
+	}
+
+	if !filter.FromDate.IsZero() {
+		where.add("transactions.created_at >= ?::timestamp", filter.FromDate.UTC())
+	}
+
+	if !filter.ToDate.IsZero() {
+		where.add("transactions.created_at < ?::timestamp", filter.ToDate.UTC())
+	}
+
+	if filter.MinAmount.Valid {
+		where.add("payments.amount >= ?", filter.MinAmount.Decimal)
+	}
+
+	if filter.MaxAmount.Valid {
+		where.add("payments.amount <= ?", filter.MaxAmount.Decimal)
+	}
+
+	if filter.After != nil {
+		where.add("(transactions.created_at, payments.id) > (?::timestamp, ?)", filter.After.CreatedAt.UTC(), filter.After.ID)
+	}
+
+	query := selectPaymentsQuery + where.String() + " ORDER BY transactions.created_at, payments.id"
+	if filter.Limit > 0 {
+		query += " LIMIT " + where.arg(filter.Limit)
+	}
+
+	return s.queryPayments(ctx, query, where.args...)
+}
+
+// GetTransaction returns a Transaction found by its ID
+func (s *PgStorage) GetTransaction(ctx context.Context, id int) (entities.Transaction, error) {
+	result := entities.Transaction{ID: id}
 	query := `
 		SELECT
-			owners.id,
-			owners.name,
-			counterparties.id,
-			counterparties.name,
-			transactions.id,
-			transactions.created_at,
-			direction,
-			amount,
-			payments.currency
-		FROM payments
-		INNER JOIN accounts AS owners ON payments.account_id = owners.id
-		INNER JOIN accounts AS counterparties ON payments.counterparty_id = counterparties.id
-		INNER JOIN transactions ON payments.transaction_id = transactions.id
+			created_at,
+			kind,
+			COALESCE(external_reference, ''),
+			COALESCE(original_transaction_id, 0),
+			fee,
+			COALESCE(sender_id, 0),
+			COALESCE(description, ''),
+			metadata
+		FROM transactions
+		WHERE id = $1
 	`
-	rows, err := s.Handler.QueryContext(ctx, query)
+	var metadata []byte
+	err := s.Handler.QueryRowContext(ctx, query, id).Scan(
+		&result.CreatedAt,
+		&result.Kind,
+		&result.ExternalReference,
+		&result.OriginalID,
+		&result.Fee,
+		&result.SenderID,
+		&result.Description,
+		&metadata,
+	)
+	if err == sql.ErrNoRows {
+		return result, errors.Wrapf(storage.ErrNotFound, "transaction %d", id)
+	}
+	if err != nil {
+		return result, errors.Wrapf(err, "can't obtain transaction %d", id)
+	}
+
+	result.Metadata, err = decodeMetadata(metadata)
+	return result, errors.Wrapf(err, "can't obtain transaction %d", id)
+}
+
+// GetLinkedTransactions returns reversals and refunds of the transaction in order of their creation
+func (s *PgStorage) GetLinkedTransactions(ctx context.Context, originalID int) ([]entities.Transaction, error) {
+	query := `
+		SELECT id, created_at, kind, COALESCE(external_reference, ''), original_transaction_id, fee
+		FROM transactions
+		WHERE original_transaction_id = $1
+		ORDER BY id
+	`
+	rows, err := s.Handler.QueryContext(ctx, query, originalID)
+	if err != nil {
+		return nil, errors.Wrapf(err, "can't query transactions linked to %d", originalID)
+	}
+
+	defer rows.Close()
+
+	transactions := []entities.Transaction{}
+	for rows.Next() {
+		var transaction entities.Transaction
+		err := rows.Scan(&transaction.ID, &transaction.CreatedAt, &transaction.Kind, &transaction.ExternalReference, &transaction.OriginalID, &transaction.Fee)
+		if err != nil {
+			return transactions, errors.Wrap(err, "can't scan Transaction db row")
+		}
+		transactions = append(transactions, transaction)
+	}
+
+	return transactions, errors.Wrap(rows.Err(), "can't iterate over Transaction db rows")
+}
+
+// GetTransactionPayments returns slice of Payments booked within the Transaction
+// in order of their creation
+func (s *PgStorage) GetTransactionPayments(ctx context.Context, transactionID int) ([]entities.Payment, error) {
+	query := selectPaymentsQuery + " WHERE payments.transaction_id = $1 ORDER BY payments.id"
+	return s.queryPayments(ctx, query, transactionID)
+}
+
+// queryPayments runs a query built upon selectPaymentsQuery and scans resulting Payments
+func (s *PgStorage) queryPayments(ctx context.Context, query string, args ...interface{}) ([]entities.Payment, error) {
+	rows, err := s.Handler.QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, errors.Wrap(err, "can't query Payments list")
 	}
 
 	defer rows.Close()
 
-	var payments []entities.Payment
+	payments := []entities.Payment{}
 	for rows.Next() {
-		var payment entities.Payment
+		var (
+			payment  entities.Payment
+			metadata []byte
+		)
 		err := rows.Scan(
+			&payment.ID,
 			&payment.Account.ID,
 			&payment.Account.Name,
 			&payment.Counterparty.ID,
 			&payment.Counterparty.Name,
 			&payment.Transaction.ID,
 			&payment.Transaction.CreatedAt,
+			&payment.Transaction.Kind,
+			&payment.Transaction.ExternalReference,
+			&payment.Transaction.OriginalID,
+			&payment.Transaction.Fee,
+			&payment.Transaction.Description,
+			&metadata,
 			&payment.Direction,
 			&payment.Amount,
 			&payment.Currency,
@@ -144,25 +369,61 @@ func (s *PgStorage) GetPaymentsList(ctx context.Context) ([]entities.Payment, er
 		if err != nil {
 			return payments, errors.Wrap(err, "can't scan Payment db row")
 		}
+
+		if payment.Transaction.Metadata, err = decodeMetadata(metadata); err != nil {
+			return payments, errors.Wrap(err, "can't scan Payment db row")
+		}
 		payments = append(payments, payment)
 	}
 
-	return payments, nil
+	return payments, errors.Wrap(rows.Err(), "can't iterate over Payment db rows")
 }
 
 // GetAccountForUpdate returns Account entitiy with an explicit declaration of row lock
 func (s *PgStorage) GetAccountForUpdate(ctx context.Context, account *entities.Account) error {
-	selectQuery := "SELECT id, balance FROM accounts WHERE name = $1 FOR UPDATE"
-	err := s.Handler.QueryRowContext(ctx, selectQuery, account.Name).Scan(&account.ID, &account.Balance)
+	selectQuery := `
+		SELECT id, type, status, balance, held, credit_limit, currency, COALESCE(owner_id, ''), COALESCE(display_name, ''), labels, created_at
+		FROM accounts
+		WHERE name = $1
+		FOR UPDATE
+	`
+	err := s.Handler.QueryRowContext(ctx, selectQuery, account.Name).Scan(&account.ID, &account.Type, &account.Status, &account.Balance, &account.Held, &account.CreditLimit, &account.Currency, &account.OwnerID, &account.DisplayName, pq.Array(&account.Labels), &account.CreatedAt)
 	return errors.Wrapf(err, "can't obtain account %s", account.Name)
 }
 
-// CreateTransaction creates a Transaction entity
-func (s *PgStorage) CreateTransaction(ctx context.Context) (entities.Transaction, error) {
-	var result entities.Transaction
-	insertTxQuery := "INSERT INTO transactions(created_at) VALUES(NOW()) RETURNING id"
-	err := s.Handler.QueryRowContext(ctx, insertTxQuery).Scan(&result.ID)
-	return result, errors.Wrap(err, "can't insert new transaction")
+// CreateTransaction creates a Transaction entity of the given kind (transfer unless set),
+// external reference, original transaction, fee, description and metadata. Returns ErrAlreadyExists
+// if the reference was already used by the sender, or by a transaction of the same kind for references without sender.
+func (s *PgStorage) CreateTransaction(ctx context.Context, transaction entities.Transaction) (entities.Transaction, error) {
+	if transaction.Kind == "" {
+		transaction.Kind = entities.TransferTransaction
+	}
+
+	metadata, err := encodeMetadata(transaction.Metadata)
+	if err != nil {
+		return transaction, errors.Wrap(err, "can't insert new transaction")
+	}
+
+	insertTxQuery := `
+		INSERT INTO transactions(created_at, kind, external_reference, original_transaction_id, fee, sender_id, description, metadata)
+		VALUES(NOW(), $1, NULLIF($2, ''), NULLIF($3, 0), $4, NULLIF($5, 0), NULLIF($6, ''), $7)
+		RETURNING id, created_at
+	`
+	err = s.Handler.QueryRowContext(
+		ctx,
+		insertTxQuery,
+		transaction.Kind,
+		transaction.ExternalReference,
+		transaction.OriginalID,
+		transaction.Fee,
+		transaction.SenderID,
+		transaction.Description,
+		metadata,
+	).Scan(&transaction.ID, &transaction.CreatedAt)
+	if isUniqueViolation(err) {
+		return transaction, errors.Wrapf(storage.ErrAlreadyExists, "%s with reference %s", transaction.Kind, transaction.ExternalReference)
+	}
+	return transaction, errors.Wrap(err, "can't insert new transaction")
 }
 
 // SendPayment creates a single Payment entity.
@@ -188,3 +449,950 @@ func (s *PgStorage) SetAccountBalance(ctx context.Context, account entities.Acco
 	_, err := s.Handler.ExecContext(ctx, "UPDATE accounts SET balance = $1 WHERE id = $2", account.Balance, account.ID)
 	return errors.Wrapf(err, "can't update balance of %s", account.Name)
 }
+
+// SetAccountCreditLimit updates credit limit of the account
+func (s *PgStorage) SetAccountCreditLimit(ctx context.Context, account entities.Account) error {
+	_, err := s.Handler.ExecContext(ctx, "UPDATE accounts SET credit_limit = $1 WHERE id = $2", account.CreditLimit, account.ID)
+	return errors.Wrapf(err, "can't update credit limit of %s", account.Name)
+}
+
+// SetAccountStatus updates status of the account
+func (s *PgStorage) SetAccountStatus(ctx context.Context, account entities.Account) error {
+	_, err := s.Handler.ExecContext(ctx, "UPDATE accounts SET status = $1 WHERE id = $2", account.Status, account.ID)
+	return errors.Wrapf(err, "can't update status of %s", account.Name)
+}
+
+// SetAccountHeld updates the amount reserved on the account by pending holds
+func (s *PgStorage) SetAccountHeld(ctx context.Context, account entities.Account) error {
+	_, err := s.Handler.ExecContext(ctx, "UPDATE accounts SET held = $1 WHERE id = $2", account.Held, account.ID)
+	return errors.Wrapf(err, "can't update held amount of %s", account.Name)
+}
+
+// SetAccountProfile updates owner, display name and labels of the account
+func (s *PgStorage) SetAccountProfile(ctx context.Context, account entities.Account) error {
+	query := "UPDATE accounts SET owner_id = NULLIF($1, ''), display_name = NULLIF($2, ''), labels = $3 WHERE id = $4"
+	_, err := s.Handler.ExecContext(ctx, query, account.OwnerID, account.DisplayName, labelsArray(account.Labels), account.ID)
+	return errors.Wrapf(err, "can't update profile of %s", account.Name)
+}
+
+// CreateCreditLimitChange persists an audit record of credit limit change.
+// Returns the record with ID and creation time set up on success.
+func (s *PgStorage) CreateCreditLimitChange(ctx context.Context, change entities.CreditLimitChange) (entities.CreditLimitChange, error) {
+	query := `
+		INSERT INTO credit_limit_changes(account_id, previous_limit, new_limit, changed_by, reason)
+		VALUES($1, $2, $3, $4, NULLIF($5, ''))
+		RETURNING id, created_at
+	`
+	err := s.Handler.QueryRowContext(
+		ctx,
+		query,
+		change.AccountID,
+		change.PreviousLimit,
+		change.NewLimit,
+		change.ChangedBy,
+		change.Reason,
+	).Scan(&change.ID, &change.CreatedAt)
+	return change, errors.Wrap(err, "can't insert credit limit change")
+}
+
+// GetCreditLimitChanges returns credit limit changes of the account, oldest first
+func (s *PgStorage) GetCreditLimitChanges(ctx context.Context, accountID int) ([]entities.CreditLimitChange, error) {
+	query := `
+		SELECT id, account_id, previous_limit, new_limit, changed_by, COALESCE(reason, ''), created_at
+		FROM credit_limit_changes
+		WHERE account_id = $1
+		ORDER BY id
+	`
+	rows, err := s.Handler.QueryContext(ctx, query, accountID)
+	if err != nil {
+		return nil, errors.Wrap(err, "can't query credit limit changes")
+	}
+
+	defer rows.Close()
+
+	changes := []entities.CreditLimitChange{}
+	for rows.Next() {
+		var change entities.CreditLimitChange
+		err := rows.Scan(
+			&change.ID,
+			&change.AccountID,
+			&change.PreviousLimit,
+			&change.NewLimit,
+			&change.ChangedBy,
+			&change.Reason,
+			&change.CreatedAt,
+		)
+		if err != nil {
+			return changes, errors.Wrap(err, "can't scan credit limit change db row")
+		}
+		changes = append(changes, change)
+	}
+
+	return changes, errors.Wrap(rows.Err(), "can't iterate over credit limit change db rows")
+}
+
+// selectHoldsQuery is a base query used to fetch Holds along with their accounts
+const selectHoldsQuery = `
+	SELECT
+		holds.id,
+		holds.account_id,
+		accounts.name,
+		holds.counterparty_id,
+		counterparties.name,
+		holds.amount,
+		holds.currency,
+		holds.status,
+		holds.created_at,
+		holds.expires_at,
+		COALESCE(holds.transaction_id, 0)
+	FROM holds
+	INNER JOIN accounts ON accounts.id = holds.account_id
+	INNER JOIN accounts AS counterparties ON counterparties.id = holds.counterparty_id
+`
+
+// CreateHold persists a pending Hold of the amount on the account.
+// Returns the Hold with ID and creation time set up on success.
+func (s *PgStorage) CreateHold(ctx context.Context, hold entities.Hold) (entities.Hold, error) {
+	query := `
+		INSERT INTO holds(account_id, counterparty_id, amount, currency, status, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, created_at
+	`
+	err := s.Handler.QueryRowContext(
+		ctx,
+		query,
+		hold.Account.ID,
+		hold.Counterparty.ID,
+		hold.Amount,
+		hold.Currency,
+		hold.Status,
+		hold.ExpiresAt,
+	).Scan(&hold.ID, &hold.CreatedAt)
+	return hold, errors.Wrap(err, "can't insert new hold")
+}
+
+// GetHold returns a Hold found by its ID
+func (s *PgStorage) GetHold(ctx context.Context, id int) (entities.Hold, error) {
+	holds, err := s.queryHolds(ctx, selectHoldsQuery+" WHERE holds.id = $1", id)
+	if err != nil {
+		return entities.Hold{}, errors.Wrapf(err, "can't obtain hold %d", id)
+	}
+
+	if len(holds) == 0 {
+		return entities.Hold{}, errors.Wrapf(storage.ErrNotFound, "hold %d", id)
+	}
+
+	return holds[0], nil
+}
+
+// GetExpiredHolds returns up to limit pending Holds which expired by now, oldest expiry first
+func (s *PgStorage) GetExpiredHolds(ctx context.Context, now time.Time, limit int) ([]entities.Hold, error) {
+	query := selectHoldsQuery + " WHERE holds.status = $1 AND holds.expires_at <= $2 ORDER BY holds.expires_at, holds.id LIMIT $3"
+	return s.queryHolds(ctx, query, entities.PendingHold, now, limit)
+}
+
+// queryHolds runs a query built upon selectHoldsQuery and scans resulting Holds
+func (s *PgStorage) queryHolds(ctx context.Context, query string, args ...interface{}) ([]entities.Hold, error) {
+	rows, err := s.Handler.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, errors.Wrap(err, "can't query holds")
+	}
+
+	defer rows.Close()
+
+	holds := []entities.Hold{}
+	for rows.Next() {
+		var hold entities.Hold
+		err := rows.Scan(
+			&hold.ID,
+			&hold.Account.ID,
+			&hold.Account.Name,
+			&hold.Counterparty.ID,
+			&hold.Counterparty.Name,
+			&hold.Amount,
+			&hold.Currency,
+			&hold.Status,
+			&hold.CreatedAt,
+			&hold.ExpiresAt,
+			&hold.TransactionID,
+		)
+		if err != nil {
+			return holds, errors.Wrap(err, "can't scan hold db row")
+		}
+		holds = append(holds, hold)
+	}
+
+	return holds, errors.Wrap(rows.Err(), "can't iterate over hold db rows")
+}
+
+// SetHoldStatus updates status of the Hold along with the transaction it was captured by
+func (s *PgStorage) SetHoldStatus(ctx context.Context, hold entities.Hold) error {
+	_, err := s.Handler.ExecContext(ctx, "UPDATE holds SET status = $1, transaction_id = NULLIF($2, 0) WHERE id = $3", hold.Status, hold.TransactionID, hold.ID)
+	return errors.Wrapf(err, "can't update status of hold %d", hold.ID)
+}
+
+// selectScheduledPaymentsQuery is a base query used to fetch ScheduledPayments along with their accounts
+const selectScheduledPaymentsQuery = `
+	SELECT
+		scheduled_payments.id,
+		scheduled_payments.account_id,
+		accounts.name,
+		scheduled_payments.counterparty_id,
+		counterparties.name,
+		scheduled_payments.amount,
+		scheduled_payments.currency,
+		scheduled_payments.frequency,
+		COALESCE(scheduled_payments.cron, ''),
+		scheduled_payments.status,
+		scheduled_payments.start_at,
+		scheduled_payments.end_at,
+		scheduled_payments.next_run_at,
+		scheduled_payments.retry_at,
+		scheduled_payments.attempts,
+		COALESCE(scheduled_payments.lease_owner, ''),
+		scheduled_payments.lease_expires_at,
+		scheduled_payments.created_at
+	FROM scheduled_payments
+	INNER JOIN accounts ON accounts.id = scheduled_payments.account_id
+	INNER JOIN accounts AS counterparties ON counterparties.id = scheduled_payments.counterparty_id
+`
+
+// CreateScheduledPayment persists a ScheduledPayment from the account to the counterparty.
+// Returns the ScheduledPayment with ID and creation time set up on success.
+func (s *PgStorage) CreateScheduledPayment(ctx context.Context, payment entities.ScheduledPayment) (entities.ScheduledPayment, error) {
+	query := `
+		INSERT INTO scheduled_payments(
+			account_id,
+			counterparty_id,
+			amount,
+			currency,
+			frequency,
+			cron,
+			status,
+			start_at,
+			end_at,
+			next_run_at
+		) VALUES ($1, $2, $3, $4, $5, NULLIF($6, ''), $7, $8, $9, $10)
+		RETURNING id, created_at
+	`
+	err := s.Handler.QueryRowContext(
+		ctx,
+		query,
+		payment.Account.ID,
+		payment.Counterparty.ID,
+		payment.Amount,
+		payment.Currency,
+		payment.Frequency,
+		payment.Cron,
+		payment.Status,
+		payment.StartAt,
+		nullTime(payment.EndAt),
+		payment.NextRunAt,
+	).Scan(&payment.ID, &payment.CreatedAt)
+	return payment, errors.Wrap(err, "can't insert new scheduled payment")
+}
+
+// GetScheduledPayment returns a ScheduledPayment found by its ID
+func (s *PgStorage) GetScheduledPayment(ctx context.Context, id int) (entities.ScheduledPayment, error) {
+	payments, err := s.queryScheduledPayments(ctx, selectScheduledPaymentsQuery+" WHERE scheduled_payments.id = $1", id)
+	if err != nil {
+		return entities.ScheduledPayment{}, errors.Wrapf(err, "can't obtain scheduled payment %d", id)
+	}
+
+	if len(payments) == 0 {
+		return entities.ScheduledPayment{}, errors.Wrapf(storage.ErrNotFound, "scheduled payment %d", id)
+	}
+
+	return payments[0], nil
+}
+
+// GetScheduledPayments returns ScheduledPayments sent from the account, or all of them
+// for zero accountID, in order of their creation
+func (s *PgStorage) GetScheduledPayments(ctx context.Context, accountID int) ([]entities.ScheduledPayment, error) {
+	query := selectScheduledPaymentsQuery + " WHERE $1 = 0 OR scheduled_payments.account_id = $1 ORDER BY scheduled_payments.id"
+	return s.queryScheduledPayments(ctx, query, accountID)
+}
+
+// GetScheduledPaymentForUpdate fills in ScheduledPayment entity found by its ID
+// with an explicit declaration of row lock
+func (s *PgStorage) GetScheduledPaymentForUpdate(ctx context.Context, payment *entities.ScheduledPayment) error {
+	query := selectScheduledPaymentsQuery + " WHERE scheduled_payments.id = $1 FOR UPDATE OF scheduled_payments"
+	payments, err := s.queryScheduledPayments(ctx, query, payment.ID)
+	if err != nil {
+		return errors.Wrapf(err, "can't obtain scheduled payment %d", payment.ID)
+	}
+
+	if len(payments) == 0 {
+		return errors.Wrapf(storage.ErrNotFound, "scheduled payment %d", payment.ID)
+	}
+
+	*payment = payments[0]
+	return nil
+}
+
+// UpdateScheduledPayment stores amount, status, run times, attempts and lease of the ScheduledPayment
+func (s *PgStorage) UpdateScheduledPayment(ctx context.Context, payment entities.ScheduledPayment) error {
+	query := `
+		UPDATE scheduled_payments
+		SET
+			amount = $1,
+			status = $2,
+			next_run_at = $3,
+			retry_at = $4,
+			attempts = $5,
+			lease_owner = NULLIF($6, ''),
+			lease_expires_at = $7
+		WHERE id = $8
+	`
+	_, err := s.Handler.ExecContext(
+		ctx,
+		query,
+		payment.Amount,
+		payment.Status,
+		payment.NextRunAt,
+		nullTime(payment.RetryAt),
+		payment.Attempts,
+		payment.LeaseOwner,
+		nullTime(payment.LeaseExpiresAt),
+		payment.ID,
+	)
+	return errors.Wrapf(err, "can't update scheduled payment %d", payment.ID)
+}
+
+// ClaimScheduledPayments leases up to limit active ScheduledPayments which are due by now
+// to the owner until leaseUntil, earliest due first. Payments leased to others are skipped
+// until their lease expires, so that concurrent claims never return the same payment.
+func (s *PgStorage) ClaimScheduledPayments(ctx context.Context, owner string, now time.Time, leaseUntil time.Time, limit int) ([]entities.ScheduledPayment, error) {
+	query := `
+		WITH claimed AS (
+			UPDATE scheduled_payments
+			SET lease_owner = $1, lease_expires_at = $2
+			WHERE id IN (
+				SELECT id
+				FROM scheduled_payments
+				WHERE status = $3
+					AND COALESCE(retry_at, next_run_at) <= $4
+					AND (lease_expires_at IS NULL OR lease_expires_at <= $4)
+				ORDER BY COALESCE(retry_at, next_run_at), id
+				LIMIT $5
+				FOR UPDATE SKIP LOCKED
+			)
+			RETURNING id
+		)
+	` + selectScheduledPaymentsQuery + `
+		WHERE scheduled_payments.id IN (SELECT id FROM claimed)
+		ORDER BY COALESCE(scheduled_payments.retry_at, scheduled_payments.next_run_at), scheduled_payments.id
+	`
+	payments, err := s.queryScheduledPayments(ctx, query, owner, leaseUntil, entities.ActiveSchedule, now, limit)
+	if err != nil {
+		return payments, err
+	}
+
+	// the outer query sees the snapshot taken before the update
+	for i := range payments {
+		payments[i].LeaseOwner, payments[i].LeaseExpiresAt = owner, leaseUntil
+	}
+	return payments, nil
+}
+
+// queryScheduledPayments runs a query built upon selectScheduledPaymentsQuery and scans resulting ScheduledPayments
+func (s *PgStorage) queryScheduledPayments(ctx context.Context, query string, args ...interface{}) ([]entities.ScheduledPayment, error) {
+	rows, err := s.Handler.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, errors.Wrap(err, "can't query scheduled payments")
+	}
+
+	defer rows.Close()
+
+	payments := []entities.ScheduledPayment{}
+	for rows.Next() {
+		var (
+			payment                        entities.ScheduledPayment
+			endAt, retryAt, leaseExpiresAt pq.NullTime
+		)
+		err := rows.Scan(
+			&payment.ID,
+			&payment.Account.ID,
+			&payment.Account.Name,
+			&payment.Counterparty.ID,
+			&payment.Counterparty.Name,
+			&payment.Amount,
+			&payment.Currency,
+			&payment.Frequency,
+			&payment.Cron,
+			&payment.Status,
+			&payment.StartAt,
+			&endAt,
+			&payment.NextRunAt,
+			&retryAt,
+			&payment.Attempts,
+			&payment.LeaseOwner,
+			&leaseExpiresAt,
+			&payment.CreatedAt,
+		)
+		if err != nil {
+			return payments, errors.Wrap(err, "can't scan scheduled payment db row")
+		}
+
+		payment.EndAt, payment.RetryAt, payment.LeaseExpiresAt = endAt.Time, retryAt.Time, leaseExpiresAt.Time
+		payments = append(payments, payment)
+	}
+
+	return payments, errors.Wrap(rows.Err(), "can't iterate over scheduled payment db rows")
+}
+
+// CreateScheduledRun persists a record of a ScheduledPayment run.
+// Returns the record with ID and creation time set up on success.
+func (s *PgStorage) CreateScheduledRun(ctx context.Context, run entities.ScheduledRun) (entities.ScheduledRun, error) {
+	query := `
+		INSERT INTO scheduled_payment_runs(scheduled_payment_id, scheduled_for, attempt, status, transaction_id, error)
+		VALUES ($1, $2, $3, $4, NULLIF($5, 0), NULLIF($6, ''))
+		RETURNING id, created_at
+	`
+	err := s.Handler.QueryRowContext(
+		ctx,
+		query,
+		run.ScheduleID,
+		run.ScheduledFor,
+		run.Attempt,
+		run.Status,
+		run.TransactionID,
+		run.Error,
+	).Scan(&run.ID, &run.CreatedAt)
+	return run, errors.Wrap(err, "can't insert scheduled payment run")
+}
+
+// GetScheduledRuns returns runs of the ScheduledPayment, oldest first
+func (s *PgStorage) GetScheduledRuns(ctx context.Context, scheduleID int) ([]entities.ScheduledRun, error) {
+	query := `
+		SELECT id, scheduled_payment_id, scheduled_for, attempt, status, COALESCE(transaction_id, 0), COALESCE(error, ''), created_at
+		FROM scheduled_payment_runs
+		WHERE scheduled_payment_id = $1
+		ORDER BY id
+	`
+	rows, err := s.Handler.QueryContext(ctx, query, scheduleID)
+	if err != nil {
+		return nil, errors.Wrap(err, "can't query scheduled payment runs")
+	}
+
+	defer rows.Close()
+
+	runs := []entities.ScheduledRun{}
+	for rows.Next() {
+		var run entities.ScheduledRun
+		err := rows.Scan(
+			&run.ID,
+			&run.ScheduleID,
+			&run.ScheduledFor,
+			&run.Attempt,
+			&run.Status,
+			&run.TransactionID,
+			&run.Error,
+			&run.CreatedAt,
+		)
+		if err != nil {
+			return runs, errors.Wrap(err, "can't scan scheduled payment run db row")
+		}
+		runs = append(runs, run)
+	}
+
+	return runs, errors.Wrap(rows.Err(), "can't iterate over scheduled payment run db rows")
+}
+
+// labelsArray converts account labels to a database array, which is empty rather than NULL for no labels
+func labelsArray(labels []string) interface{} {
+	if labels == nil {
+		labels = []string{}
+	}
+	return pq.Array(labels)
+}
+
+// encodeMetadata turns metadata into a JSON document, empty metadata is stored as NULL
+func encodeMetadata(metadata map[string]string) (interface{}, error) {
+	if len(metadata) == 0 {
+		return nil, nil
+	}
+
+	encoded, err := json.Marshal(metadata)
+	if err != nil {
+		return nil, errors.Wrap(err, "can't encode metadata")
+	}
+	return string(encoded), nil
+}
+
+// decodeMetadata restores metadata stored by encodeMetadata
+func decodeMetadata(encoded []byte) (map[string]string, error) {
+	if len(encoded) == 0 {
+		return nil, nil
+	}
+
+	var metadata map[string]string
+	err := json.Unmarshal(encoded, &metadata)
+	return metadata, errors.Wrap(err, "can't decode metadata")
+}
+
+// nullTime turns zero time into NULL
+func nullTime(t time.Time) interface{} {
+	if t.IsZero() {
+		return nil
+	}
+	return t
+}
+
+// CreateQuote persists an exchange rate Quote. Returns the Quote with ID
+// and creation time set up on success.
+func (s *PgStorage) CreateQuote(ctx context.Context, quote entities.Quote) (entities.Quote, error) {
+	query := `
+		INSERT INTO fx_quotes(
+			from_currency,
+			to_currency,
+			rate,
+			source_amount,
+			target_amount,
+			expires_at
+		) VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, created_at
+	`
+	err := s.Handler.QueryRowContext(ctx, query, quote.FromCurrency, quote.ToCurrency, quote.Rate, quote.SourceAmount, quote.TargetAmount, quote.ExpiresAt).Scan(&quote.ID, &quote.CreatedAt)
+	return quote, errors.Wrap(err, "can't insert new quote")
+}
+
+// GetQuoteForUpdate fills in Quote entity found by its ID with an explicit declaration of row lock
+func (s *PgStorage) GetQuoteForUpdate(ctx context.Context, quote *entities.Quote) error {
+	query := `
+		SELECT
+			from_currency,
+			to_currency,
+			rate,
+			source_amount,
+			target_amount,
+			created_at,
+			expires_at,
+			transaction_id
+		FROM fx_quotes
+		WHERE id = $1
+		FOR UPDATE
+	`
+	var transactionID sql.NullInt64
+	err := s.Handler.QueryRowContext(ctx, query, quote.ID).Scan(
+		&quote.FromCurrency,
+		&quote.ToCurrency,
+		&quote.Rate,
+		&quote.SourceAmount,
+		&quote.TargetAmount,
+		&quote.CreatedAt,
+		&quote.ExpiresAt,
+		&transactionID,
+	)
+	if err == sql.ErrNoRows {
+		return errors.Wrapf(storage.ErrNotFound, "quote %d", quote.ID)
+	}
+
+	quote.TransactionID = int(transactionID.Int64)
+	return errors.Wrapf(err, "can't obtain quote %d", quote.ID)
+}
+
+// SetQuoteTransaction marks the Quote as used by the Transaction it is linked to
+func (s *PgStorage) SetQuoteTransaction(ctx context.Context, quote entities.Quote) error {
+	_, err := s.Handler.ExecContext(ctx, "UPDATE fx_quotes SET transaction_id = $1 WHERE id = $2", quote.TransactionID, quote.ID)
+	return errors.Wrapf(err, "can't set transaction of quote %d", quote.ID)
+}
+
+// CreateIdempotencyRecord reserves an idempotency key for the request.
+// Returns false if the key is already reserved. Reservations which expired
+// before the request committed anything are taken over by requests of the same payload.
+func (s *PgStorage) CreateIdempotencyRecord(ctx context.Context, record entities.IdempotencyRecord) (bool, error) {
+	query := `
+		INSERT INTO idempotency_keys(key, request_hash, lock_id, locked_until)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (key) DO UPDATE
+		SET lock_id = EXCLUDED.lock_id, locked_until = EXCLUDED.locked_until, created_at = NOW()
+		WHERE idempotency_keys.request_hash = EXCLUDED.request_hash
+			AND idempotency_keys.committed_at IS NULL
+			AND idempotency_keys.response_status IS NULL
+			AND idempotency_keys.locked_until < NOW()
+	`
+	result, err := s.Handler.ExecContext(ctx, query, record.Key, record.RequestHash, record.LockID, nullTime(record.LockedUntil))
+	if err != nil {
+		return false, errors.Wrap(err, "can't insert idempotency key")
+	}
+
+	inserted, err := result.RowsAffected()
+	return inserted == 1, errors.Wrap(err, "can't check idempotency key insertion")
+}
+
+// GetIdempotencyRecord returns a record stored for the idempotency key
+func (s *PgStorage) GetIdempotencyRecord(ctx context.Context, key string) (entities.IdempotencyRecord, error) {
+	query := `
+		SELECT
+			key,
+			request_hash,
+			lock_id,
+			transaction_id,
+			response_status,
+			response_headers,
+			response_body,
+			created_at,
+			locked_until,
+			committed_at
+		FROM idempotency_keys
+		WHERE key = $1
+	`
+	var (
+		record                   entities.IdempotencyRecord
+		transactionID, status    sql.NullInt64
+		headers                  []byte
+		lockedUntil, committedAt pq.NullTime
+	)
+	err := s.Handler.QueryRowContext(ctx, query, key).Scan(
+		&record.Key,
+		&record.RequestHash,
+		&record.LockID,
+		&transactionID,
+		&status,
+		&headers,
+		&record.ResponseBody,
+		&record.CreatedAt,
+		&lockedUntil,
+		&committedAt,
+	)
+	if err == sql.ErrNoRows {
+		return record, errors.Wrapf(storage.ErrNotFound, "idempotency key %s", key)
+	}
+	if err != nil {
+		return record, errors.Wrapf(err, "can't obtain idempotency key %s", key)
+	}
+
+	record.TransactionID = int(transactionID.Int64)
+	record.ResponseStatus = int(status.Int64)
+	record.LockedUntil = lockedUntil.Time
+	record.CommittedAt = committedAt.Time
+	if headers != nil {
+		err = json.Unmarshal(headers, &record.ResponseHeaders)
+	}
+	return record, errors.Wrapf(err, "can't decode response headers of idempotency key %s", key)
+}
+
+// CommitIdempotencyRecord marks the reservation of the key as committed along with the
+// transaction booked by the request. It is meant to be called within the storage transaction
+// committing the request changes. Returns ErrAlreadyExists if the reservation was taken over
+// or committed before.
+func (s *PgStorage) CommitIdempotencyRecord(ctx context.Context, record entities.IdempotencyRecord) error {
+	query := `
+		UPDATE idempotency_keys
+		SET transaction_id = NULLIF($1, 0), committed_at = NOW()
+		WHERE key = $2 AND lock_id = $3 AND committed_at IS NULL
+	`
+	result, err := s.Handler.ExecContext(ctx, query, record.TransactionID, record.Key, record.LockID)
+	if err != nil {
+		return errors.Wrapf(err, "can't commit idempotency key %s", record.Key)
+	}
+
+	updated, err := result.RowsAffected()
+	if err != nil {
+		return errors.Wrapf(err, "can't check idempotency key %s commit", record.Key)
+	}
+
+	if updated != 1 {
+		return errors.Wrapf(storage.ErrAlreadyExists, "committed request with idempotency key %s", record.Key)
+	}
+	return nil
+}
+
+// CompleteIdempotencyRecord stores the response of a request made with the idempotency key.
+// Records reserved by other requests since then are left intact.
+func (s *PgStorage) CompleteIdempotencyRecord(ctx context.Context, record entities.IdempotencyRecord) error {
+	headers, err := json.Marshal(record.ResponseHeaders)
+	if err != nil {
+		return errors.Wrap(err, "can't encode response headers")
+	}
+
+	query := `
+		UPDATE idempotency_keys
+		SET response_status = $1, response_headers = $2, response_body = $3, completed_at = NOW()
+		WHERE key = $4 AND lock_id = $5
+	`
+	_, err = s.Handler.ExecContext(ctx, query, record.ResponseStatus, headers, record.ResponseBody, record.Key, record.LockID)
+	return errors.Wrapf(err, "can't complete idempotency key %s", record.Key)
+}
+
+// DeleteIdempotencyRecord releases the idempotency key so that it could be used again.
+// Keys of requests which committed their changes are never released.
+func (s *PgStorage) DeleteIdempotencyRecord(ctx context.Context, record entities.IdempotencyRecord) error {
+	query := "DELETE FROM idempotency_keys WHERE key = $1 AND lock_id = $2 AND committed_at IS NULL"
+	_, err := s.Handler.ExecContext(ctx, query, record.Key, record.LockID)
+	return errors.Wrapf(err, "can't delete idempotency key %s", record.Key)
+}
+
+// CreateAPIKey persists an APIKey. Returns the APIKey with ID and
+// creation time set up on success.
+func (s *PgStorage) CreateAPIKey(ctx context.Context, key entities.APIKey) (entities.APIKey, error) {
+	query := `
+		INSERT INTO api_keys(key_id, name, secret_hash, sealed_secret, role, owner_id)
+		VALUES ($1, $2, $3, $4, $5, NULLIF($6, ''))
+		RETURNING id, created_at
+	`
+	err := s.Handler.QueryRowContext(ctx, query, key.KeyID, key.Name, key.SecretHash, key.SealedSecret, key.Role, key.OwnerID).Scan(&key.ID, &key.CreatedAt)
+	return key, errors.Wrap(err, "can't insert new api key")
+}
+
+// GetAPIKey returns an APIKey found by its public key id
+func (s *PgStorage) GetAPIKey(ctx context.Context, keyID string) (entities.APIKey, error) {
+	query := `
+		SELECT
+			id,
+			key_id,
+			name,
+			secret_hash,
+			sealed_secret,
+			role,
+			COALESCE(owner_id, ''),
+			created_at,
+			revoked_at
+		FROM api_keys
+		WHERE key_id = $1
+	`
+	key, err := scanAPIKey(s.Handler.QueryRowContext(ctx, query, keyID))
+	if err == sql.ErrNoRows {
+		return key, errors.Wrapf(storage.ErrNotFound, "api key %s", keyID)
+	}
+	return key, errors.Wrapf(err, "can't obtain api key %s", keyID)
+}
+
+// GetAPIKeys returns all the API keys, revoked ones included, ordered by creation
+func (s *PgStorage) GetAPIKeys(ctx context.Context) ([]entities.APIKey, error) {
+	query := `
+		SELECT
+			id,
+			key_id,
+			name,
+			secret_hash,
+			sealed_secret,
+			role,
+			COALESCE(owner_id, ''),
+			created_at,
+			revoked_at
+		FROM api_keys
+		ORDER BY id
+	`
+	rows, err := s.Handler.QueryContext(ctx, query)
+	if err != nil {
+		return nil, errors.Wrap(err, "can't query api keys")
+	}
+	defer rows.Close()
+
+	keys := []entities.APIKey{}
+	for rows.Next() {
+		key, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, errors.Wrap(err, "can't scan api key")
+		}
+		keys = append(keys, key)
+	}
+	return keys, errors.Wrap(rows.Err(), "can't query api keys")
+}
+
+// RevokeAPIKey marks the APIKey as revoked at the given time. Keys which were
+// revoked already keep their original revocation time.
+func (s *PgStorage) RevokeAPIKey(ctx context.Context, key entities.APIKey) error {
+	query := "UPDATE api_keys SET revoked_at = $1 WHERE key_id = $2 AND revoked_at IS NULL"
+	_, err := s.Handler.ExecContext(ctx, query, key.RevokedAt, key.KeyID)
+	return errors.Wrapf(err, "can't revoke api key %s", key.KeyID)
+}
+
+// scanAPIKey reads an APIKey from a row selected by GetAPIKey or GetAPIKeys
+func scanAPIKey(row rowScanner) (entities.APIKey, error) {
+	var (
+		key       entities.APIKey
+		revokedAt pq.NullTime
+	)
+	err := row.Scan(&key.ID, &key.KeyID, &key.Name, &key.SecretHash, &key.SealedSecret, &key.Role, &key.OwnerID, &key.CreatedAt, &revokedAt)
+	key.RevokedAt = revokedAt.Time
+	return key, err
+}
+
+// AppendAuditEntry adds the entry to the end of the audit log chain. Appends are serialized
+// with a transaction level advisory lock, so the storage should be within a transaction.
+// The entry is stamped once the lock is held, so that timestamps follow the order of the chain.
+// Returns the AuditEntry with ID, CreatedAt, PrevHash and Hash set up on success.
+func (s *PgStorage) AppendAuditEntry(ctx context.Context, entry entities.AuditEntry) (entities.AuditEntry, error) {
+	// Every mutating call appends an entry right before its commit, so the lock serializes
+	// commits of all mutating transactions. It is held until the commit, keep the rest short.
+	if _, err := s.Handler.ExecContext(ctx, "SELECT pg_advisory_xact_lock(hashtext('audit_log'))"); err != nil {
+		return entry, errors.Wrap(err, "can't lock audit log")
+	}
+
+	// NOW() is the start of the transaction, which may precede appends chained before this one
+	if err := s.Handler.QueryRowContext(ctx, "SELECT clock_timestamp()").Scan(&entry.CreatedAt); err != nil {
+		return entry, errors.Wrap(err, "can't obtain audit entry time")
+	}
+	entry.CreatedAt = entry.CreatedAt.UTC()
+
+	var last entities.AuditEntry
+	err := s.Handler.QueryRowContext(ctx, "SELECT id, hash FROM audit_log ORDER BY id DESC LIMIT 1").Scan(&last.ID, &last.Hash)
+	if err != nil && err != sql.ErrNoRows {
+		return entry, errors.Wrap(err, "can't obtain last audit entry")
+	}
+
+	entry.ID = last.ID + 1
+	entry.PrevHash = last.Hash
+	entry.Hash = entry.ChainHash()
+
+	query := `
+		INSERT INTO audit_log(id, subject, role, action, payload_hash, outcome, error, correlation_id, created_at, prev_hash, hash)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+	`
+	_, err = s.Handler.ExecContext(ctx, query, entry.ID, entry.Subject, entry.Role, entry.Action, entry.PayloadHash,
+		entry.Outcome, entry.Error, entry.CorrelationID, entry.CreatedAt, entry.PrevHash, entry.Hash)
+	return entry, errors.Wrap(err, "can't append audit entry")
+}
+
+// GetAuditEntries returns audit entries matching the filter ordered by ID
+func (s *PgStorage) GetAuditEntries(ctx context.Context, filter entities.AuditFilter) ([]entities.AuditEntry, error) {
+	where := whereClause{}
+	if filter.Subject != "" {
+		where.add("subject = ?", filter.Subject)
+	}
+
+	if filter.Action != "" {
+		where.add("action = ?", filter.Action)
+	}
+
+	if filter.CorrelationID != "" {
+		where.add("correlation_id = ?", filter.CorrelationID)
+	}
+
+	if filter.Outcome != "" {
+		where.add("outcome = ?", filter.Outcome)
+	}
+
+	if !filter.FromDate.IsZero() {
+		where.add("created_at >= ?", filter.FromDate)
+	}
+
+	if !filter.ToDate.IsZero() {
+		where.add("created_at < ?", filter.ToDate)
+	}
+
+	if filter.After != nil {
+		where.add("id > ?", filter.After.ID)
+	}
+
+	query := `
+		SELECT
+			id,
+			subject,
+			role,
+			action,
+			payload_hash,
+			outcome,
+			error,
+			correlation_id,
+			created_at,
+			prev_hash,
+			hash
+		FROM audit_log` + where.String() + " ORDER BY id"
+	if filter.Limit > 0 {
+		query += " LIMIT " + where.arg(filter.Limit)
+	}
+
+	rows, err := s.Handler.QueryContext(ctx, query, where.args...)
+	if err != nil {
+		return nil, errors.Wrap(err, "can't query audit entries")
+	}
+	defer rows.Close()
+
+	entries := []entities.AuditEntry{}
+	for rows.Next() {
+		var entry entities.AuditEntry
+		err := rows.Scan(&entry.ID, &entry.Subject, &entry.Role, &entry.Action, &entry.PayloadHash, &entry.Outcome,
+			&entry.Error, &entry.CorrelationID, &entry.CreatedAt, &entry.PrevHash, &entry.Hash)
+		if err != nil {
+			return nil, errors.Wrap(err, "can't scan audit entry")
+		}
+		entries = append(entries, entry)
+	}
+	return entries, errors.Wrap(rows.Err(), "can't query audit entries")
+}
+
+// GetLedgerBalances returns every account along with the sum of its payments
+func (s *PgStorage) GetLedgerBalances(ctx context.Context) ([]entities.LedgerBalance, error) {
+	query := `
+		SELECT
+			accounts.id,
+			accounts.name,
+			accounts.type,
+			accounts.status,
+			accounts.balance,
+			accounts.held,
+			accounts.credit_limit,
+			accounts.currency,
+			accounts.created_at,
+			COALESCE(SUM(CASE WHEN payments.direction = 'outgoing' THEN payments.amount * -1 ELSE payments.amount END), 0)
+		FROM accounts
+		LEFT JOIN payments ON payments.account_id = accounts.id
+		GROUP BY accounts.id
+		ORDER BY accounts.id
+	`
+	rows, err := s.Handler.QueryContext(ctx, query)
+	if err != nil {
+		return nil, errors.Wrap(err, "can't query ledger balances")
+	}
+
+	defer rows.Close()
+
+	balances := []entities.LedgerBalance{}
+	for rows.Next() {
+		var balance entities.LedgerBalance
+		err := rows.Scan(
+			&balance.Account.ID,
+			&balance.Account.Name,
+			&balance.Account.Type,
+			&balance.Account.Status,
+			&balance.Account.Balance,
+			&balance.Account.Held,
+			&balance.Account.CreditLimit,
+			&balance.Account.Currency,
+			&balance.Account.CreatedAt,
+			&balance.PaymentsTotal,
+		)
+		if err != nil {
+			return balances, errors.Wrap(err, "can't scan ledger balance db row")
+		}
+		balances = append(balances, balance)
+	}
+
+	return balances, errors.Wrap(rows.Err(), "can't iterate over ledger balance db rows")
+}
+
+// GetUnbalancedTransactions returns sums of payments per transaction and currency
+// which are not equal to zero
+func (s *PgStorage) GetUnbalancedTransactions(ctx context.Context) ([]entities.TransactionImbalance, error) {
+	query := `
+		SELECT
+			transaction_id,
+			currency,
+			SUM(CASE WHEN direction = 'outgoing' THEN amount * -1 ELSE amount END) AS total
+		FROM payments
+		GROUP BY transaction_id, currency
+		HAVING SUM(CASE WHEN direction = 'outgoing' THEN amount * -1 ELSE amount END) != 0
+		ORDER BY transaction_id, currency
+	`
+	rows, err := s.Handler.QueryContext(ctx, query)
+	if err != nil {
+		return nil, errors.Wrap(err, "can't query unbalanced transactions")
+	}
+
+	defer rows.Close()
+
+	imbalances := []entities.TransactionImbalance{}
+	for rows.Next() {
+		var imbalance entities.TransactionImbalance
+		if err := rows.Scan(&imbalance.TransactionID, &imbalance.Currency, &imbalance.Total); err != nil {
+			return imbalances, errors.Wrap(err, "can't scan unbalanced transaction db row")
+		}
+		imbalances = append(imbalances, imbalance)
+	}
+
+	return imbalances, errors.Wrap(rows.Err(), "can't iterate over unbalanced transaction db rows")
+}
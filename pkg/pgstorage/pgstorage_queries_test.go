@@ -63,7 +63,7 @@ const (
 	selectAccountCountQuery = `
 		SELECT COUNT(*)
 		FROM accounts
-		WHERE name != 'SYSTEM'
+		WHERE name != 'SYSTEM' AND name NOT LIKE 'SYSTEM\_%' AND name NOT LIKE 'FX\_%'
 	`
 )
 
@@ -3,18 +3,23 @@ package pgstorage_test
 import (
 	"context"
 	"testing"
+	"time"
 
+	"github.com/pkg/errors"
 	"github.com/shopspring/decimal"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"github.com/twonegatives/coinsph_challenge/pkg/entities"
 	"github.com/twonegatives/coinsph_challenge/pkg/pgstorage"
+	"github.com/twonegatives/coinsph_challenge/pkg/storage"
+	"github.com/twonegatives/coinsph_challenge/pkg/storage/storagetest"
 )
 
 var (
 	system = entities.Account{
 		ID:       1,
 		Name:     "SYSTEM",
+		Type:     entities.SystemAccount,
 		Balance:  decimal.New(0, 0),
 		Currency: entities.USD,
 	}
@@ -28,6 +33,21 @@ func setupDependencies(t *testing.T) (*pgstorage.PgStorage, func(), context.Cont
 	return pg, closeDB, ctx
 }
 
+func TestPGStorageConformance(t *testing.T) {
+	closers := []func(){}
+	defer func() {
+		for _, closeDB := range closers {
+			closeDB()
+		}
+	}()
+
+	storagetest.Run(t, func() storage.Storage {
+		db, closeDB := prepareDB(t)
+		closers = append(closers, closeDB)
+		return pgstorage.NewPgStorage(db)
+	})
+}
+
 func TestPGStorageGetAccountsList(t *testing.T) {
 	pg, closeDB, ctx := setupDependencies(t)
 	defer closeDB()
@@ -36,11 +56,97 @@ func TestPGStorageGetAccountsList(t *testing.T) {
 		charlie, err := createAccount(pg.Handler, "charlie", decimal.New(1534, -2))
 		require.NoError(t, err)
 
-		expected := []entities.Account{system, charlie}
-		accounts, err := pg.GetAccountsList(ctx)
+		accounts, err := pg.GetAccountsList(ctx, entities.AccountsFilter{})
+		require.NoError(t, err)
+
+		names := make([]string, len(accounts))
+		for index, account := range accounts {
+			names[index] = account.Name
+		}
+		assert.Contains(t, names, system.Name)
+		assert.Contains(t, names, charlie.Name)
+	})
+
+	t.Run("sorts, searches and pages accounts", func(t *testing.T) {
+		for index, name := range []string{"dave_1", "dave_2", "davey"} {
+			_, err := createAccount(pg.Handler, name, decimal.New(int64(10-index), 0))
+			require.NoError(t, err)
+		}
+
+		filter := entities.AccountsFilter{NamePrefix: "dave_", Sort: entities.SortAccountsByBalance, Limit: 1}
+		firstPage, err := pg.GetAccountsList(ctx, filter)
+		require.NoError(t, err)
+		require.Len(t, firstPage, 1)
+		assert.Equal(t, "dave_2", firstPage[0].Name)
+
+		cursor := entities.NewAccountsCursor(filter, firstPage[0])
+		filter.After = &cursor
+		secondPage, err := pg.GetAccountsList(ctx, filter)
+		require.NoError(t, err)
+		require.Len(t, secondPage, 1)
+		assert.Equal(t, "dave_1", secondPage[0].Name)
+
+		filter.After = nil
+		filter.Descending = true
+		filter.Limit = 0
+		reversed, err := pg.GetAccountsList(ctx, filter)
+		require.NoError(t, err)
+		require.Len(t, reversed, 2)
+		assert.Equal(t, "dave_1", reversed[0].Name)
+	})
+}
+
+func TestPGStorageGetAccount(t *testing.T) {
+	pg, closeDB, ctx := setupDependencies(t)
+	defer closeDB()
+
+	t.Run("returns account by name", func(t *testing.T) {
+		fred, err := createAccount(pg.Handler, "fred", decimal.New(42, 0))
+		require.NoError(t, err)
+
+		account, err := pg.GetAccount(ctx, "fred")
+		require.NoError(t, err)
+		assert.Equal(t, fred.ID, account.ID)
+		assert.Equal(t, fred.Balance.String(), account.Balance.String())
+		assert.Equal(t, entities.USD, account.Currency)
+	})
+
+	t.Run("returns ErrNotFound for unknown account", func(t *testing.T) {
+		_, err := pg.GetAccount(ctx, "nobody")
+		require.Error(t, err)
+		assert.Equal(t, storage.ErrNotFound, errors.Cause(err))
+	})
+}
+
+func TestPGStorageGetAccountPayments(t *testing.T) {
+	pg, closeDB, ctx := setupDependencies(t)
+	defer closeDB()
+
+	t.Run("returns payments of the account only", func(t *testing.T) {
+		gina, err := createAccount(pg.Handler, "gina", decimal.New(0, 0))
+		require.NoError(t, err)
+
+		hugo, err := createAccount(pg.Handler, "hugo", decimal.New(0, 0))
 		require.NoError(t, err)
 
-		assert.Equal(t, expected, accounts)
+		for _, receiver := range []entities.Account{gina, hugo, gina} {
+			transaction, err := createTransaction(pg.Handler)
+			require.NoError(t, err)
+
+			_, err = createPayment(pg.Handler, transaction.ID, receiver.ID, system.ID, decimal.New(1, 0))
+			require.NoError(t, err)
+		}
+
+		payments, err := pg.GetAccountPayments(ctx, gina.ID, entities.PaymentsFilter{})
+		require.NoError(t, err)
+		require.Len(t, payments, 2)
+		for _, payment := range payments {
+			assert.Equal(t, gina.ID, payment.Account.ID)
+		}
+
+		payments, err = pg.GetAccountPayments(ctx, gina.ID, entities.PaymentsFilter{Limit: 1})
+		require.NoError(t, err)
+		assert.Len(t, payments, 1)
 	})
 }
 
@@ -64,7 +170,7 @@ func TestPGStorageGetPaymentsList(t *testing.T) {
 
 		andy, transaction, paymentRecord := setup()
 
-		payments, err := pg.GetPaymentsList(ctx)
+		payments, err := pg.GetPaymentsList(ctx, entities.PaymentsFilter{})
 		require.NoError(t, err)
 
 		assert.Len(t, payments, 1)
@@ -76,6 +182,59 @@ func TestPGStorageGetPaymentsList(t *testing.T) {
 	})
 }
 
+func TestPGStorageGetPaymentsListFiltered(t *testing.T) {
+	pg, closeDB, ctx := setupDependencies(t)
+	defer closeDB()
+
+	kate, err := createAccount(pg.Handler, "kate", decimal.New(0, 0))
+	require.NoError(t, err)
+
+	amounts := []decimal.Decimal{decimal.New(5, 0), decimal.New(50, 0), decimal.New(500, 0)}
+	for _, amount := range amounts {
+		transaction, err := createTransaction(pg.Handler)
+		require.NoError(t, err)
+
+		_, err = createPayment(pg.Handler, transaction.ID, system.ID, kate.ID, amount)
+		require.NoError(t, err)
+	}
+
+	t.Run("orders payments and pages them with cursor", func(t *testing.T) {
+		firstPage, err := pg.GetPaymentsList(ctx, entities.PaymentsFilter{AccountName: "SYSTEM", Limit: 2})
+		require.NoError(t, err)
+		require.Len(t, firstPage, 2)
+		assert.Equal(t, amounts[0].String(), firstPage[0].Amount.String())
+		assert.Equal(t, amounts[1].String(), firstPage[1].Amount.String())
+
+		cursor := entities.NewPaymentsCursor(firstPage[1])
+		secondPage, err := pg.GetPaymentsList(ctx, entities.PaymentsFilter{AccountName: "SYSTEM", Limit: 2, After: &cursor})
+		require.NoError(t, err)
+		require.Len(t, secondPage, 1)
+		assert.Equal(t, amounts[2].String(), secondPage[0].Amount.String())
+	})
+
+	t.Run("filters by direction and amount", func(t *testing.T) {
+		filter := entities.PaymentsFilter{
+			Direction: entities.Outgoing,
+			MinAmount: decimal.NullDecimal{Decimal: decimal.New(10, 0), Valid: true},
+			MaxAmount: decimal.NullDecimal{Decimal: decimal.New(100, 0), Valid: true},
+		}
+		payments, err := pg.GetPaymentsList(ctx, filter)
+		require.NoError(t, err)
+		require.Len(t, payments, 1)
+		assert.Equal(t, amounts[1].String(), payments[0].Amount.String())
+	})
+
+	t.Run("filters by dates", func(t *testing.T) {
+		payments, err := pg.GetPaymentsList(ctx, entities.PaymentsFilter{ToDate: time.Now().Add(-time.Hour)})
+		require.NoError(t, err)
+		assert.Empty(t, payments)
+
+		payments, err = pg.GetPaymentsList(ctx, entities.PaymentsFilter{FromDate: time.Now().Add(-time.Hour)})
+		require.NoError(t, err)
+		assert.Len(t, payments, len(amounts))
+	})
+}
+
 type payment struct {
 	AccountID      int
 	CounterpartyID int
@@ -100,6 +259,7 @@ func TestPGStorageGetAccountForUpdate(t *testing.T) {
 
 		assert.Equal(t, decimal.New(22, -1), entity.Balance)
 		assert.Equal(t, emma.ID, entity.ID)
+		assert.Equal(t, entities.USD, entity.Currency)
 	})
 }
 
@@ -108,13 +268,48 @@ func TestPGStorageCreateTransaction(t *testing.T) {
 	defer closeDB()
 
 	t.Run("creates new Transaction entity", func(t *testing.T) {
-		tx, err := pg.CreateTransaction(ctx)
+		tx, err := pg.CreateTransaction(ctx, entities.Transaction{Kind: entities.TransferTransaction})
 		require.NoError(t, err)
 
 		count, err := selectTransactionsCount(pg.Handler, tx.ID)
 		require.NoError(t, err)
 
 		assert.Equal(t, 1, count)
+		assert.False(t, tx.CreatedAt.IsZero())
+	})
+}
+
+func TestPGStorageGetTransaction(t *testing.T) {
+	pg, closeDB, ctx := setupDependencies(t)
+	defer closeDB()
+
+	t.Run("returns transaction with its payments", func(t *testing.T) {
+		benny, err := createAccount(pg.Handler, "benny", decimal.New(0, 0))
+		require.NoError(t, err)
+
+		transaction, err := createTransaction(pg.Handler)
+		require.NoError(t, err)
+
+		_, err = createPayment(pg.Handler, transaction.ID, system.ID, benny.ID, decimal.New(1250, -2))
+		require.NoError(t, err)
+
+		entity, err := pg.GetTransaction(ctx, transaction.ID)
+		require.NoError(t, err)
+		assert.Equal(t, transaction.ID, entity.ID)
+		assert.False(t, entity.CreatedAt.IsZero())
+
+		payments, err := pg.GetTransactionPayments(ctx, transaction.ID)
+		require.NoError(t, err)
+		require.Len(t, payments, 1)
+		assert.Equal(t, system.ID, payments[0].Account.ID)
+		assert.Equal(t, benny.ID, payments[0].Counterparty.ID)
+		assert.Equal(t, decimal.New(1250, -2).String(), payments[0].Amount.String())
+	})
+
+	t.Run("returns ErrNotFound for unknown transaction", func(t *testing.T) {
+		_, err := pg.GetTransaction(ctx, 100500)
+		require.Error(t, err)
+		assert.Equal(t, storage.ErrNotFound, errors.Cause(err))
 	})
 }
 
@@ -126,7 +321,7 @@ func TestPGStorageSendPayment(t *testing.T) {
 		liam, err := createAccount(pg.Handler, "liam", decimal.New(220, -2))
 		require.NoError(t, err)
 
-		tx, err := pg.CreateTransaction(ctx)
+		tx, err := pg.CreateTransaction(ctx, entities.Transaction{Kind: entities.TransferTransaction})
 		require.NoError(t, err)
 
 		payment := entities.Payment{
@@ -238,12 +433,12 @@ func TestPGStorageCreateAccount(t *testing.T) {
 		defer closeDB()
 
 		name := "tony"
-		account, err := pg.CreateAccount(ctx, name)
+		account, err := pg.CreateAccount(ctx, entities.Account{Name: name, Currency: entities.PHP})
 		require.NoError(t, err)
 
 		assert.Equal(t, name, account.Name)
 		assert.Equal(t, decimal.New(0, 0), account.Balance)
-		assert.Equal(t, entities.USD, account.Currency)
+		assert.Equal(t, entities.PHP, account.Currency)
 
 		count, err := getUserAccountsCount(pg.Handler)
 		require.NoError(t, err)
@@ -256,7 +451,7 @@ func TestPGStorageCreateAccount(t *testing.T) {
 		defer closeDB()
 
 		name := "SYSTEM"
-		_, err := pg.CreateAccount(ctx, name)
+		_, err := pg.CreateAccount(ctx, entities.Account{Name: name, Currency: entities.USD})
 		require.Error(t, err)
 		assert.Contains(t, err.Error(), "can't create new account")
 
@@ -266,3 +461,88 @@ func TestPGStorageCreateAccount(t *testing.T) {
 		assert.Equal(t, 0, count)
 	})
 }
+
+func TestPGStorageQuotes(t *testing.T) {
+	t.Run("creates, locks and marks quote as used", func(t *testing.T) {
+		pg, closeDB, ctx := setupDependencies(t)
+		defer closeDB()
+
+		quote, err := pg.CreateQuote(ctx, entities.Quote{
+			FromCurrency: entities.PHP,
+			ToCurrency:   entities.USD,
+			Rate:         decimal.New(191, -4),
+			SourceAmount: decimal.New(1000, 0),
+			TargetAmount: decimal.New(1910, -2),
+			ExpiresAt:    time.Now().Add(time.Minute),
+		})
+		require.NoError(t, err)
+		assert.NotZero(t, quote.ID)
+		assert.False(t, quote.CreatedAt.IsZero())
+
+		transaction, err := createTransaction(pg.Handler)
+		require.NoError(t, err)
+
+		quote.TransactionID = transaction.ID
+		require.NoError(t, pg.SetQuoteTransaction(ctx, quote))
+
+		locked := entities.Quote{ID: quote.ID}
+		require.NoError(t, pg.GetQuoteForUpdate(ctx, &locked))
+
+		assert.Equal(t, entities.PHP, locked.FromCurrency)
+		assert.Equal(t, entities.USD, locked.ToCurrency)
+		assert.Equal(t, decimal.New(1910, -2), locked.TargetAmount)
+		assert.Equal(t, transaction.ID, locked.TransactionID)
+		assert.True(t, locked.IsUsed())
+	})
+
+	t.Run("returns ErrNotFound for unknown quote", func(t *testing.T) {
+		pg, closeDB, ctx := setupDependencies(t)
+		defer closeDB()
+
+		err := pg.GetQuoteForUpdate(ctx, &entities.Quote{ID: 100500})
+		require.Error(t, err)
+		assert.Equal(t, storage.ErrNotFound, errors.Cause(err))
+	})
+}
+
+func TestPGStorageIdempotencyRecords(t *testing.T) {
+	t.Run("reserves key once and stores response", func(t *testing.T) {
+		pg, closeDB, ctx := setupDependencies(t)
+		defer closeDB()
+
+		record := entities.IdempotencyRecord{Key: "retry-me", RequestHash: "abc"}
+		created, err := pg.CreateIdempotencyRecord(ctx, record)
+		require.NoError(t, err)
+		assert.True(t, created)
+
+		created, err = pg.CreateIdempotencyRecord(ctx, record)
+		require.NoError(t, err)
+		assert.False(t, created)
+
+		record.ResponseStatus = 201
+		record.ResponseHeaders = map[string]string{"Location": "/api/v1/transactions/1"}
+		record.ResponseBody = []byte(`{"ok":true}`)
+		require.NoError(t, pg.CompleteIdempotencyRecord(ctx, record))
+
+		stored, err := pg.GetIdempotencyRecord(ctx, "retry-me")
+		require.NoError(t, err)
+
+		assert.True(t, stored.IsCompleted())
+		assert.Equal(t, "abc", stored.RequestHash)
+		assert.Equal(t, record.ResponseHeaders, stored.ResponseHeaders)
+		assert.Equal(t, record.ResponseBody, stored.ResponseBody)
+	})
+
+	t.Run("deletes key", func(t *testing.T) {
+		pg, closeDB, ctx := setupDependencies(t)
+		defer closeDB()
+
+		record := entities.IdempotencyRecord{Key: "retry-me", RequestHash: "abc"}
+		_, err := pg.CreateIdempotencyRecord(ctx, record)
+		require.NoError(t, err)
+		require.NoError(t, pg.DeleteIdempotencyRecord(ctx, record))
+
+		_, err = pg.GetIdempotencyRecord(ctx, "retry-me")
+		assert.Equal(t, storage.ErrNotFound, errors.Cause(err))
+	})
+}
@@ -0,0 +1,62 @@
+package pgstorage
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/lib/pq"
+)
+
+// uniqueViolation is an error code PostgreSQL reports unique constraint violations with
+const uniqueViolation = "23505"
+
+// whereClause accumulates query conditions along with their arguments.
+// Conditions use "?" as argument placeholders, which are translated
+// into PostgreSQL positional ones ($1, $2, ...) in order of addition.
+type whereClause struct {
+	conditions []string
+	args       []interface{}
+}
+
+func (w *whereClause) add(condition string, args ...interface{}) {
+	for _, arg := range args {
+		w.args = append(w.args, arg)
+		condition = strings.Replace(condition, "?", w.placeholder(), 1)
+	}
+	w.conditions = append(w.conditions, condition)
+}
+
+// arg registers an argument which is not a part of WHERE conditions
+// (e.g. LIMIT value) and returns its placeholder
+func (w *whereClause) arg(arg interface{}) string {
+	w.args = append(w.args, arg)
+	return w.placeholder()
+}
+
+func (w *whereClause) placeholder() string {
+	return "$" + strconv.Itoa(len(w.args))
+}
+
+func (w *whereClause) String() string {
+	if len(w.conditions) == 0 {
+		return ""
+	}
+	return " WHERE " + strings.Join(w.conditions, " AND ")
+}
+
+// escapeLikePattern escapes LIKE wildcards so that the value is matched literally
+func escapeLikePattern(value string) string {
+	return likeEscaper.Replace(value)
+}
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
+func isUniqueViolation(err error) bool {
+	pqErr, ok := err.(*pq.Error)
+	return ok && pqErr.Code == uniqueViolation
+}
+
+// rowScanner is satisfied by both *sql.Row and *sql.Rows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
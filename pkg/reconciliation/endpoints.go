@@ -0,0 +1,14 @@
+package reconciliation
+
+import (
+	"context"
+
+	"github.com/go-kit/kit/endpoint"
+)
+
+func MakeGetReportEndpoint(svc ReconciliationService) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		report, err := svc.GetLastReport(ctx)
+		return getReportResponse{Report: report}, err
+	}
+}
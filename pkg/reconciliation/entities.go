@@ -0,0 +1,7 @@
+package reconciliation
+
+import "github.com/twonegatives/coinsph_challenge/pkg/entities"
+
+type getReportResponse struct {
+	Report entities.ReconciliationReport `json:"reconciliation"`
+}
@@ -0,0 +1,151 @@
+// Package reconciliation provides functionality for checking the ledger
+// consistency: stored balances are recomputed from payments, transactions
+// and currencies are checked to be balanced.
+package reconciliation
+
+import (
+	"context"
+	"database/sql"
+	"sync"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/twonegatives/coinsph_challenge/pkg/entities"
+	"github.com/twonegatives/coinsph_challenge/pkg/storage"
+)
+
+//go:generate mockgen -source=service.go -destination ../mocks/mock_reconciliation_service.go -package mocks
+
+// ReconciliationService is an abstraction which contains declarations
+// of methods used to check the ledger and obtain check results.
+type ReconciliationService interface {
+	Reconcile(ctx context.Context) (entities.ReconciliationReport, error)
+	GetLastReport(ctx context.Context) (entities.ReconciliationReport, error)
+}
+
+// Service is an implementation of ReconciliationService.
+type Service struct {
+	store storage.Storage
+	now   func() time.Time
+
+	mutex sync.Mutex
+	last  *entities.ReconciliationReport
+}
+
+func NewService(s storage.Storage) *Service {
+	return &Service{
+		store: s,
+		now:   time.Now,
+	}
+}
+
+// Reconcile checks that balance of each account equals to the sum of its payments,
+// payments of each transaction sum up to zero per currency and balances of all
+// accounts sum up to zero per currency. All the checks observe a single snapshot
+// of the ledger. The report is kept to be returned by GetLastReport later.
+func (svc *Service) Reconcile(ctx context.Context) (report entities.ReconciliationReport, err error) {
+	report = entities.ReconciliationReport{StartedAt: svc.now().UTC(), Discrepancies: []entities.Discrepancy{}}
+
+	txStorage, err := svc.store.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+	if err != nil {
+		return report, err
+	}
+
+	// nothing is changed, so the transaction is never committed
+	defer txStorage.RollbackTx(ctx)
+
+	balances, err := txStorage.GetLedgerBalances(ctx)
+	if err != nil {
+		return report, err
+	}
+
+	imbalances, err := txStorage.GetUnbalancedTransactions(ctx)
+	if err != nil {
+		return report, err
+	}
+
+	report.AccountsChecked = len(balances)
+	report.Discrepancies = append(report.Discrepancies, checkAccounts(balances)...)
+	report.Discrepancies = append(report.Discrepancies, checkTransactions(imbalances)...)
+	report.Discrepancies = append(report.Discrepancies, checkCurrencies(balances)...)
+	report.Consistent = len(report.Discrepancies) == 0
+	report.FinishedAt = svc.now().UTC()
+
+	svc.mutex.Lock()
+	svc.last = &report
+	svc.mutex.Unlock()
+
+	return report, nil
+}
+
+// GetLastReport returns the report of the latest check.
+// The ledger gets checked right away if it was not checked yet.
+func (svc *Service) GetLastReport(ctx context.Context) (entities.ReconciliationReport, error) {
+	svc.mutex.Lock()
+	last := svc.last
+	svc.mutex.Unlock()
+
+	if last != nil {
+		return *last, nil
+	}
+
+	return svc.Reconcile(ctx)
+}
+
+func checkAccounts(balances []entities.LedgerBalance) []entities.Discrepancy {
+	discrepancies := []entities.Discrepancy{}
+	for _, balance := range balances {
+		if !balance.Account.Balance.Equal(balance.PaymentsTotal) {
+			discrepancies = append(discrepancies, entities.Discrepancy{
+				Kind:     entities.AccountBalanceMismatch,
+				Account:  balance.Account.Name,
+				Currency: balance.Account.Currency,
+				Expected: balance.PaymentsTotal,
+				Actual:   balance.Account.Balance,
+			})
+		}
+	}
+	return discrepancies
+}
+
+func checkTransactions(imbalances []entities.TransactionImbalance) []entities.Discrepancy {
+	discrepancies := []entities.Discrepancy{}
+	for _, imbalance := range imbalances {
+		discrepancies = append(discrepancies, entities.Discrepancy{
+			Kind:          entities.UnbalancedTransaction,
+			TransactionID: imbalance.TransactionID,
+			Currency:      imbalance.Currency,
+			Expected:      decimal.New(0, 0),
+			Actual:        imbalance.Total,
+		})
+	}
+	return discrepancies
+}
+
+// checkCurrencies sums up balances of all accounts per currency. Each transfer moves
+// money between accounts of the same currency, so every sum should be zero.
+func checkCurrencies(balances []entities.LedgerBalance) []entities.Discrepancy {
+	currencies := []entities.Currency{}
+	totals := make(map[entities.Currency]decimal.Decimal)
+	for _, balance := range balances {
+		currency := balance.Account.Currency
+		if _, seen := totals[currency]; !seen {
+			currencies = append(currencies, currency)
+			totals[currency] = decimal.New(0, 0)
+		}
+		totals[currency] = totals[currency].Add(balance.Account.Balance)
+	}
+
+	discrepancies := []entities.Discrepancy{}
+	for _, currency := range currencies {
+		if !totals[currency].IsZero() {
+			discrepancies = append(discrepancies, entities.Discrepancy{
+				Kind:     entities.UnbalancedCurrency,
+				Currency: currency,
+				Expected: decimal.New(0, 0),
+				Actual:   totals[currency],
+			})
+		}
+	}
+	return discrepancies
+}
@@ -0,0 +1,122 @@
+package reconciliation_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/pkg/errors"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/twonegatives/coinsph_challenge/pkg/entities"
+	"github.com/twonegatives/coinsph_challenge/pkg/mocks"
+	"github.com/twonegatives/coinsph_challenge/pkg/reconciliation"
+)
+
+var (
+	ErrDB = errors.New("db error")
+	ctx   = context.Background()
+)
+
+func ledgerBalance(name string, currency entities.Currency, balance, paymentsTotal int64) entities.LedgerBalance {
+	return entities.LedgerBalance{
+		Account:       entities.Account{Name: name, Currency: currency, Balance: decimal.New(balance, 0)},
+		PaymentsTotal: decimal.New(paymentsTotal, 0),
+	}
+}
+
+func expectLedger(storage *mocks.MockStorage, balances []entities.LedgerBalance, imbalances []entities.TransactionImbalance) {
+	storage.EXPECT().BeginTx(ctx, gomock.Any()).Return(storage, nil)
+	storage.EXPECT().GetLedgerBalances(ctx).Return(balances, nil)
+	storage.EXPECT().GetUnbalancedTransactions(ctx).Return(imbalances, nil)
+	storage.EXPECT().RollbackTx(ctx).Return(nil)
+}
+
+func TestReconciliationSvcReconcile(t *testing.T) {
+	t.Run("reports consistent ledger", func(t *testing.T) {
+		mCtrl := gomock.NewController(t)
+		defer mCtrl.Finish()
+		storage := mocks.NewMockStorage(mCtrl)
+
+		expectLedger(storage, []entities.LedgerBalance{
+			ledgerBalance("SYSTEM", entities.USD, -10, -10),
+			ledgerBalance("alice", entities.USD, 10, 10),
+			ledgerBalance("SYSTEM_EUR", entities.EUR, 0, 0),
+		}, []entities.TransactionImbalance{})
+
+		report, err := reconciliation.NewService(storage).Reconcile(ctx)
+		require.NoError(t, err)
+		assert.True(t, report.Consistent)
+		assert.Equal(t, 3, report.AccountsChecked)
+		assert.Empty(t, report.Discrepancies)
+		assert.False(t, report.StartedAt.IsZero())
+		assert.False(t, report.FinishedAt.IsZero())
+	})
+
+	t.Run("reports discrepancies", func(t *testing.T) {
+		mCtrl := gomock.NewController(t)
+		defer mCtrl.Finish()
+		storage := mocks.NewMockStorage(mCtrl)
+
+		expectLedger(storage, []entities.LedgerBalance{
+			ledgerBalance("SYSTEM", entities.USD, -10, -10),
+			ledgerBalance("alice", entities.USD, 15, 10),
+		}, []entities.TransactionImbalance{
+			{TransactionID: 7, Currency: entities.USD, Total: decimal.New(3, 0)},
+		})
+
+		report, err := reconciliation.NewService(storage).Reconcile(ctx)
+		require.NoError(t, err)
+		assert.False(t, report.Consistent)
+		require.Len(t, report.Discrepancies, 3)
+
+		mismatch := report.Discrepancies[0]
+		assert.Equal(t, entities.AccountBalanceMismatch, mismatch.Kind)
+		assert.Equal(t, "alice", mismatch.Account)
+		assert.Equal(t, "10", mismatch.Expected.String())
+		assert.Equal(t, "15", mismatch.Actual.String())
+
+		unbalanced := report.Discrepancies[1]
+		assert.Equal(t, entities.UnbalancedTransaction, unbalanced.Kind)
+		assert.Equal(t, 7, unbalanced.TransactionID)
+		assert.Equal(t, "3", unbalanced.Actual.String())
+
+		currency := report.Discrepancies[2]
+		assert.Equal(t, entities.UnbalancedCurrency, currency.Kind)
+		assert.Equal(t, entities.USD, currency.Currency)
+		assert.Equal(t, "5", currency.Actual.String())
+	})
+
+	t.Run("propagates storage exceptions", func(t *testing.T) {
+		mCtrl := gomock.NewController(t)
+		defer mCtrl.Finish()
+		storage := mocks.NewMockStorage(mCtrl)
+
+		storage.EXPECT().BeginTx(ctx, gomock.Any()).Return(storage, nil)
+		storage.EXPECT().GetLedgerBalances(ctx).Return(nil, ErrDB)
+		storage.EXPECT().RollbackTx(ctx).Return(nil)
+
+		_, err := reconciliation.NewService(storage).Reconcile(ctx)
+		assert.Equal(t, ErrDB, errors.Cause(err))
+	})
+}
+
+func TestReconciliationSvcGetLastReport(t *testing.T) {
+	t.Run("checks the ledger once if it was not checked yet", func(t *testing.T) {
+		mCtrl := gomock.NewController(t)
+		defer mCtrl.Finish()
+		storage := mocks.NewMockStorage(mCtrl)
+
+		expectLedger(storage, []entities.LedgerBalance{ledgerBalance("alice", entities.USD, 1, 0)}, []entities.TransactionImbalance{})
+
+		svc := reconciliation.NewService(storage)
+		first, err := svc.GetLastReport(ctx)
+		require.NoError(t, err)
+		assert.False(t, first.Consistent)
+
+		second, err := svc.GetLastReport(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, first, second)
+	})
+}
@@ -0,0 +1,43 @@
+package reconciliation
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/go-kit/kit/log"
+	kithttp "github.com/go-kit/kit/transport/http"
+	"github.com/gorilla/mux"
+)
+
+// ReportPath is a path the latest reconciliation report is served at
+const ReportPath = "/admin/reconciliation"
+
+func MakeHandler(svc ReconciliationService, l log.Logger) http.Handler {
+	opts := []kithttp.ServerOption{
+		kithttp.ServerErrorEncoder(errorEncoder),
+		kithttp.ServerErrorLogger(l),
+	}
+
+	getReport := kithttp.NewServer(
+		MakeGetReportEndpoint(svc),
+		kithttp.NopRequestDecoder,
+		kithttp.EncodeJSONResponse,
+		opts...,
+	)
+
+	m := mux.NewRouter()
+	m.Handle(ReportPath, getReport).Methods(http.MethodGet)
+	return m
+}
+
+func errorEncoder(_ context.Context, err error, w http.ResponseWriter) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(http.StatusInternalServerError)
+
+	encodeErr := json.NewEncoder(w).Encode(map[string]string{"error": "internal server error"})
+	if encodeErr != nil {
+		panic(fmt.Sprintf("Can't encode error, %s. Original error: %s", encodeErr, err))
+	}
+}
@@ -0,0 +1,75 @@
+package reconciliation_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/twonegatives/coinsph_challenge/pkg/entities"
+	"github.com/twonegatives/coinsph_challenge/pkg/mocks"
+	"github.com/twonegatives/coinsph_challenge/pkg/reconciliation"
+)
+
+func TestGetReportRoute(t *testing.T) {
+	t.Run("renders the latest report", func(t *testing.T) {
+		mCtrl := gomock.NewController(t)
+		defer mCtrl.Finish()
+		svc := mocks.NewMockReconciliationService(mCtrl)
+
+		srv := httptest.NewServer(reconciliation.MakeHandler(svc, mocks.TestLogger{T: t}))
+		defer srv.Close()
+
+		report := entities.ReconciliationReport{
+			AccountsChecked: 2,
+			Discrepancies: []entities.Discrepancy{{
+				Kind:     entities.AccountBalanceMismatch,
+				Account:  "alice",
+				Currency: entities.USD,
+				Expected: decimal.New(10, 0),
+				Actual:   decimal.New(15, 0),
+			}},
+		}
+		svc.EXPECT().GetLastReport(gomock.Any()).Return(report, nil)
+
+		resp, err := srv.Client().Get(srv.URL + "/admin/reconciliation")
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+		var body map[string]map[string]interface{}
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+		assert.Equal(t, false, body["reconciliation"]["consistent"])
+		assert.Equal(t, float64(2), body["reconciliation"]["accounts_checked"])
+
+		discrepancies := body["reconciliation"]["discrepancies"].([]interface{})
+		require.Len(t, discrepancies, 1)
+		assert.Equal(t, map[string]interface{}{
+			"kind":     "account_balance_mismatch",
+			"account":  "alice",
+			"currency": "usd",
+			"expected": "10",
+			"actual":   "15",
+		}, discrepancies[0])
+	})
+
+	t.Run("hides failure details", func(t *testing.T) {
+		mCtrl := gomock.NewController(t)
+		defer mCtrl.Finish()
+		svc := mocks.NewMockReconciliationService(mCtrl)
+
+		srv := httptest.NewServer(reconciliation.MakeHandler(svc, mocks.TestLogger{T: t}))
+		defer srv.Close()
+
+		svc.EXPECT().GetLastReport(gomock.Any()).Return(entities.ReconciliationReport{}, ErrDB)
+
+		resp, err := srv.Client().Get(srv.URL + "/admin/reconciliation")
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		assert.Equal(t, http.StatusInternalServerError, resp.StatusCode)
+	})
+}
@@ -0,0 +1,32 @@
+package reconciliation
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-kit/kit/log"
+)
+
+// RunPeriodically checks the ledger every interval until the context is done.
+// Discrepancies found and failures of the check are logged.
+func RunPeriodically(ctx context.Context, svc ReconciliationService, interval time.Duration, l log.Logger) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			report, err := svc.Reconcile(ctx)
+			if err != nil {
+				l.Log("func", "reconciliation.RunPeriodically", "err", err)
+				continue
+			}
+
+			if !report.Consistent {
+				l.Log("func", "reconciliation.RunPeriodically", "msg", "ledger discrepancies found", "count", len(report.Discrepancies))
+			}
+		}
+	}
+}
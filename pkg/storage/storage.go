@@ -5,10 +5,19 @@ package storage
 import (
 	"context"
 	"database/sql"
+	"time"
 
+	"github.com/pkg/errors"
 	"github.com/twonegatives/coinsph_challenge/pkg/entities"
 )
 
+// ErrNotFound is returned (possibly wrapped) when requested object does not exist.
+var ErrNotFound = errors.New("not found")
+
+// ErrAlreadyExists is returned (possibly wrapped) when an object violates uniqueness
+// of its attributes (other than account name).
+var ErrAlreadyExists = errors.New("already exists")
+
 //go:generate mockgen -source=storage.go -destination ../mocks/mock_storage.go -package mocks
 
 // Storage is an abstraction unifying methods for objects persistance.
@@ -17,14 +26,61 @@ type Storage interface {
 	CommitTx(ctx context.Context) error
 	RollbackTx(ctx context.Context) error
 
-	CreateAccount(ctx context.Context, accountName string) (entities.Account, error)
-	GetAccountsList(ctx context.Context) ([]entities.Account, error)
-	GetPaymentsList(ctx context.Context) ([]entities.Payment, error)
+	CreateAccount(ctx context.Context, account entities.Account) (entities.Account, error)
+	GetAccount(ctx context.Context, name string) (entities.Account, error)
+	GetAccountsList(ctx context.Context, filter entities.AccountsFilter) ([]entities.Account, error)
+	GetPaymentsList(ctx context.Context, filter entities.PaymentsFilter) ([]entities.Payment, error)
+	GetAccountPayments(ctx context.Context, accountID int, filter entities.PaymentsFilter) ([]entities.Payment, error)
+	GetTransaction(ctx context.Context, id int) (entities.Transaction, error)
+	GetTransactionPayments(ctx context.Context, transactionID int) ([]entities.Payment, error)
+	GetLinkedTransactions(ctx context.Context, originalID int) ([]entities.Transaction, error)
 
 	GetAccountForUpdate(ctx context.Context, account *entities.Account) error
-	CreateTransaction(ctx context.Context) (entities.Transaction, error)
+	CreateTransaction(ctx context.Context, transaction entities.Transaction) (entities.Transaction, error)
 	SendPayment(ctx context.Context, payment entities.Payment) error
 	SetAccountBalance(ctx context.Context, account entities.Account) error
+	SetAccountCreditLimit(ctx context.Context, account entities.Account) error
+	SetAccountStatus(ctx context.Context, account entities.Account) error
+	SetAccountHeld(ctx context.Context, account entities.Account) error
+	SetAccountProfile(ctx context.Context, account entities.Account) error
+
+	CreateCreditLimitChange(ctx context.Context, change entities.CreditLimitChange) (entities.CreditLimitChange, error)
+	GetCreditLimitChanges(ctx context.Context, accountID int) ([]entities.CreditLimitChange, error)
+
+	CreateHold(ctx context.Context, hold entities.Hold) (entities.Hold, error)
+	GetHold(ctx context.Context, id int) (entities.Hold, error)
+	GetExpiredHolds(ctx context.Context, now time.Time, limit int) ([]entities.Hold, error)
+	SetHoldStatus(ctx context.Context, hold entities.Hold) error
+
+	CreateScheduledPayment(ctx context.Context, payment entities.ScheduledPayment) (entities.ScheduledPayment, error)
+	GetScheduledPayment(ctx context.Context, id int) (entities.ScheduledPayment, error)
+	GetScheduledPayments(ctx context.Context, accountID int) ([]entities.ScheduledPayment, error)
+	GetScheduledPaymentForUpdate(ctx context.Context, payment *entities.ScheduledPayment) error
+	UpdateScheduledPayment(ctx context.Context, payment entities.ScheduledPayment) error
+	ClaimScheduledPayments(ctx context.Context, owner string, now time.Time, leaseUntil time.Time, limit int) ([]entities.ScheduledPayment, error)
+	CreateScheduledRun(ctx context.Context, run entities.ScheduledRun) (entities.ScheduledRun, error)
+	GetScheduledRuns(ctx context.Context, scheduleID int) ([]entities.ScheduledRun, error)
+
+	CreateQuote(ctx context.Context, quote entities.Quote) (entities.Quote, error)
+	GetQuoteForUpdate(ctx context.Context, quote *entities.Quote) error
+	SetQuoteTransaction(ctx context.Context, quote entities.Quote) error
+
+	CreateIdempotencyRecord(ctx context.Context, record entities.IdempotencyRecord) (bool, error)
+	GetIdempotencyRecord(ctx context.Context, key string) (entities.IdempotencyRecord, error)
+	CommitIdempotencyRecord(ctx context.Context, record entities.IdempotencyRecord) error
+	CompleteIdempotencyRecord(ctx context.Context, record entities.IdempotencyRecord) error
+	DeleteIdempotencyRecord(ctx context.Context, record entities.IdempotencyRecord) error
+
+	CreateAPIKey(ctx context.Context, key entities.APIKey) (entities.APIKey, error)
+	GetAPIKey(ctx context.Context, keyID string) (entities.APIKey, error)
+	GetAPIKeys(ctx context.Context) ([]entities.APIKey, error)
+	RevokeAPIKey(ctx context.Context, key entities.APIKey) error
+
+	AppendAuditEntry(ctx context.Context, entry entities.AuditEntry) (entities.AuditEntry, error)
+	GetAuditEntries(ctx context.Context, filter entities.AuditFilter) ([]entities.AuditEntry, error)
+
+	GetLedgerBalances(ctx context.Context) ([]entities.LedgerBalance, error)
+	GetUnbalancedTransactions(ctx context.Context) ([]entities.TransactionImbalance, error)
 }
 
 // TransactionBeginner is an abstraction which allows to start db transaction.
@@ -0,0 +1,77 @@
+package storagetest
+
+import (
+	"context"
+	"testing"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/require"
+	"github.com/twonegatives/coinsph_challenge/pkg/entities"
+	"github.com/twonegatives/coinsph_challenge/pkg/storage"
+)
+
+func createAccount(t *testing.T, s storage.Storage, name string) entities.Account {
+	account, err := s.CreateAccount(context.Background(), entities.Account{Name: name, Currency: entities.USD})
+	require.NoError(t, err)
+	return account
+}
+
+// fund moves the amount from SYSTEM account to the named one
+func fund(t *testing.T, s storage.Storage, name string, amount decimal.Decimal) {
+	transfer(t, s, entities.SystemAccountPrefix, name, amount)
+}
+
+// transfer moves the amount between accounts within a transaction of its own
+func transfer(t *testing.T, s storage.Storage, from, to string, amount decimal.Decimal) {
+	ctx := context.Background()
+	txStorage, err := s.BeginTx(ctx, nil)
+	require.NoError(t, err)
+
+	book(t, txStorage, from, to, amount)
+	require.NoError(t, txStorage.CommitTx(ctx))
+}
+
+// book moves the amount between accounts the way banking service does.
+// Storage passed is expected to be bound to a transaction.
+func book(t *testing.T, txStorage storage.Storage, from, to string, amount decimal.Decimal) entities.Transaction {
+	return bookAs(t, txStorage, entities.Transaction{Kind: entities.TransferTransaction}, from, to, amount)
+}
+
+// bookAs moves the amount between accounts within a transaction of the given kind and reference
+func bookAs(t *testing.T, txStorage storage.Storage, transaction entities.Transaction, from, to string, amount decimal.Decimal) entities.Transaction {
+	ctx := context.Background()
+	sender, receiver := lockAccounts(t, txStorage, from, to)
+
+	transaction, err := txStorage.CreateTransaction(ctx, transaction)
+	require.NoError(t, err)
+
+	require.NoError(t, txStorage.SendPayment(ctx, payment(transaction, sender, receiver, entities.Outgoing, amount)))
+	require.NoError(t, txStorage.SendPayment(ctx, payment(transaction, receiver, sender, entities.Incoming, amount)))
+
+	sender.Balance = sender.Balance.Sub(amount)
+	require.NoError(t, txStorage.SetAccountBalance(ctx, sender))
+
+	receiver.Balance = receiver.Balance.Add(amount)
+	require.NoError(t, txStorage.SetAccountBalance(ctx, receiver))
+
+	return transaction
+}
+
+func lockAccounts(t *testing.T, txStorage storage.Storage, from, to string) (entities.Account, entities.Account) {
+	ctx := context.Background()
+	sender, receiver := entities.Account{Name: from}, entities.Account{Name: to}
+	require.NoError(t, txStorage.GetAccountForUpdate(ctx, &sender))
+	require.NoError(t, txStorage.GetAccountForUpdate(ctx, &receiver))
+	return sender, receiver
+}
+
+func payment(transaction entities.Transaction, account, counterparty entities.Account, direction entities.Direction, amount decimal.Decimal) entities.Payment {
+	return entities.Payment{
+		Transaction:  transaction,
+		Account:      account,
+		Counterparty: counterparty,
+		Direction:    direction,
+		Amount:       amount,
+		Currency:     account.Currency,
+	}
+}
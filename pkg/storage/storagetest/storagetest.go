@@ -0,0 +1,1740 @@
+// Package storagetest provides a behavioural test suite for implementations
+// of storage.Storage interface. Every backend is expected to pass it.
+package storagetest
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/twonegatives/coinsph_challenge/pkg/entities"
+	"github.com/twonegatives/coinsph_challenge/pkg/storage"
+)
+
+// lockWaitTime is how long the suite waits to make sure a goroutine is blocked by a row lock
+const lockWaitTime = 100 * time.Millisecond
+
+// Factory returns a new storage with house accounts (SYSTEM and FX ones)
+// seeded and no other data in it.
+type Factory func() storage.Storage
+
+// Run runs the suite against storages returned by the factory.
+// Every subtest gets a storage of its own. The factory is called
+// by the goroutine running the test, so it may use the test to fail.
+func Run(t *testing.T, newStorage Factory) {
+	tests := []struct {
+		name string
+		test func(*testing.T, storage.Storage)
+	}{
+		{"CreateAccount", testCreateAccount},
+		{"GetAccount", testGetAccount},
+		{"GetAccountsList", testGetAccountsList},
+		{"Rollback", testRollback},
+		{"Commit", testCommit},
+		{"TxOptions", testTxOptions},
+		{"GetAccountForUpdate", testGetAccountForUpdate},
+		{"BalanceInvariants", testBalanceInvariants},
+		{"CreditLimits", testCreditLimits},
+		{"AccountStatuses", testAccountStatuses},
+		{"Holds", testHolds},
+		{"ScheduledPayments", testScheduledPayments},
+		{"PaymentsList", testPaymentsList},
+		{"Transactions", testTransactions},
+		{"Quotes", testQuotes},
+		{"IdempotencyRecords", testIdempotencyRecords},
+		{"APIKeys", testAPIKeys},
+		{"AuditLog", testAuditLog},
+		{"Ledger", testLedger},
+	}
+
+	for _, tc := range tests {
+		tc, s := tc, newStorage()
+		t.Run(tc.name, func(t *testing.T) {
+			tc.test(t, s)
+		})
+	}
+}
+
+func testCreateAccount(t *testing.T, s storage.Storage) {
+	ctx := context.Background()
+
+	t.Run("creates account with zero balance", func(t *testing.T) {
+		account, err := s.CreateAccount(ctx, entities.Account{Name: "alice", Currency: entities.EUR, Balance: decimal.New(10, 0)})
+		require.NoError(t, err)
+		assert.NotZero(t, account.ID)
+		assert.True(t, account.Balance.IsZero())
+		assert.Equal(t, entities.EUR, account.Currency)
+		assert.Equal(t, entities.UserAccount, account.Type)
+		assert.False(t, account.CreatedAt.IsZero())
+	})
+
+	t.Run("creates house account of the given type", func(t *testing.T) {
+		account, err := s.CreateAccount(ctx, entities.Account{Name: "FEES_EUR", Type: entities.FeeAccount, Currency: entities.EUR})
+		require.NoError(t, err)
+		assert.Equal(t, entities.FeeAccount, account.Type)
+
+		stored, err := s.GetAccount(ctx, "FEES_EUR")
+		require.NoError(t, err)
+		assert.Equal(t, entities.FeeAccount, stored.Type)
+	})
+
+	t.Run("stores and updates profile of account", func(t *testing.T) {
+		account, err := s.CreateAccount(ctx, entities.Account{
+			Name:        "carol",
+			Currency:    entities.USD,
+			OwnerID:     "customer-1",
+			DisplayName: "Carol's savings",
+			Labels:      []string{"savings", "vip"},
+		})
+		require.NoError(t, err)
+
+		stored, err := s.GetAccount(ctx, "carol")
+		require.NoError(t, err)
+		assert.Equal(t, "customer-1", stored.OwnerID)
+		assert.Equal(t, "Carol's savings", stored.DisplayName)
+		assert.Equal(t, []string{"savings", "vip"}, stored.Labels)
+
+		account.OwnerID, account.DisplayName, account.Labels = "customer-2", "", nil
+		require.NoError(t, s.SetAccountProfile(ctx, account))
+
+		stored, err = s.GetAccount(ctx, "carol")
+		require.NoError(t, err)
+		assert.Equal(t, "customer-2", stored.OwnerID)
+		assert.Empty(t, stored.DisplayName)
+		assert.Empty(t, stored.Labels)
+
+		locked := entities.Account{Name: "carol"}
+		txStorage, err := s.BeginTx(ctx, nil)
+		require.NoError(t, err)
+		defer txStorage.RollbackTx(ctx)
+		require.NoError(t, txStorage.GetAccountForUpdate(ctx, &locked))
+		assert.Equal(t, "customer-2", locked.OwnerID)
+	})
+
+	t.Run("rejects unknown account type", func(t *testing.T) {
+		_, err := s.CreateAccount(ctx, entities.Account{Name: "VIP", Type: "vip", Currency: entities.EUR})
+		assert.Error(t, err)
+	})
+
+	t.Run("rejects duplicate names", func(t *testing.T) {
+		_, err := s.CreateAccount(ctx, entities.Account{Name: "alice", Currency: entities.USD})
+		assert.Error(t, err)
+
+		_, err = s.CreateAccount(ctx, entities.Account{Name: "SYSTEM", Currency: entities.USD})
+		assert.Error(t, err)
+	})
+
+	t.Run("rejects duplicate names created by concurrent transactions", func(t *testing.T) {
+		first, err := s.BeginTx(ctx, nil)
+		require.NoError(t, err)
+
+		_, err = first.CreateAccount(ctx, entities.Account{Name: "bob", Currency: entities.USD})
+		require.NoError(t, err)
+
+		second, err := s.BeginTx(ctx, nil)
+		require.NoError(t, err)
+
+		created := make(chan error)
+		go func() {
+			if _, err := second.CreateAccount(ctx, entities.Account{Name: "bob", Currency: entities.USD}); err != nil {
+				created <- err
+				return
+			}
+			created <- second.CommitTx(ctx)
+		}()
+
+		time.Sleep(lockWaitTime)
+		require.NoError(t, first.CommitTx(ctx))
+		assert.Error(t, <-created)
+		second.RollbackTx(ctx)
+	})
+}
+
+func testGetAccount(t *testing.T, s storage.Storage) {
+	ctx := context.Background()
+
+	t.Run("returns account by name", func(t *testing.T) {
+		created := createAccount(t, s, "carol")
+		fund(t, s, "carol", decimal.New(42, 0))
+
+		account, err := s.GetAccount(ctx, "carol")
+		require.NoError(t, err)
+		assert.Equal(t, created.ID, account.ID)
+		assert.Equal(t, "42", account.Balance.String())
+		assert.Equal(t, entities.USD, account.Currency)
+	})
+
+	t.Run("returns house accounts", func(t *testing.T) {
+		types := map[string]entities.AccountType{
+			"SYSTEM":     entities.SystemAccount,
+			"SYSTEM_EUR": entities.SystemAccount,
+			"FX_USD":     entities.SettlementAccount,
+			"FEE_USD":    entities.FeeAccount,
+		}
+		for name, accountType := range types {
+			account, err := s.GetAccount(ctx, name)
+			assert.NoError(t, err, name)
+			assert.Equal(t, accountType, account.Type, name)
+		}
+	})
+
+	t.Run("returns ErrNotFound for unknown account", func(t *testing.T) {
+		_, err := s.GetAccount(ctx, "nobody")
+		assert.Equal(t, storage.ErrNotFound, errors.Cause(err))
+	})
+}
+
+func testGetAccountsList(t *testing.T, s storage.Storage) {
+	ctx := context.Background()
+	for index, name := range []string{"list_a", "list_b", "list_c", "list%"} {
+		createAccount(t, s, name)
+		fund(t, s, name, decimal.New(int64(10-index), 0))
+	}
+
+	names := func(accounts []entities.Account) []string {
+		result := make([]string, len(accounts))
+		for index, account := range accounts {
+			result[index] = account.Name
+		}
+		return result
+	}
+
+	t.Run("returns all accounts ordered by name", func(t *testing.T) {
+		accounts, err := s.GetAccountsList(ctx, entities.AccountsFilter{})
+		require.NoError(t, err)
+		assert.Contains(t, names(accounts), "SYSTEM")
+
+		filtered, err := s.GetAccountsList(ctx, entities.AccountsFilter{NamePrefix: "list_"})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"list_a", "list_b", "list_c"}, names(filtered))
+	})
+
+	t.Run("filters accounts by owner", func(t *testing.T) {
+		for _, name := range []string{"owned_a", "owned_b"} {
+			_, err := s.CreateAccount(ctx, entities.Account{Name: name, Currency: entities.USD, OwnerID: "customer-7"})
+			require.NoError(t, err)
+		}
+
+		accounts, err := s.GetAccountsList(ctx, entities.AccountsFilter{OwnerID: "customer-7"})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"owned_a", "owned_b"}, names(accounts))
+
+		accounts, err = s.GetAccountsList(ctx, entities.AccountsFilter{OwnerID: "customer-8"})
+		require.NoError(t, err)
+		assert.Empty(t, accounts)
+	})
+
+	t.Run("treats name prefix literally", func(t *testing.T) {
+		accounts, err := s.GetAccountsList(ctx, entities.AccountsFilter{NamePrefix: "list%"})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"list%"}, names(accounts))
+	})
+
+	t.Run("returns empty list when nothing matches", func(t *testing.T) {
+		accounts, err := s.GetAccountsList(ctx, entities.AccountsFilter{NamePrefix: "nobody"})
+		require.NoError(t, err)
+		assert.NotNil(t, accounts)
+		assert.Empty(t, accounts)
+	})
+
+	t.Run("sorts and pages accounts", func(t *testing.T) {
+		filter := entities.AccountsFilter{NamePrefix: "list_", Sort: entities.SortAccountsByBalance, Limit: 2}
+		firstPage, err := s.GetAccountsList(ctx, filter)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"list_c", "list_b"}, names(firstPage))
+
+		cursor := entities.NewAccountsCursor(filter, firstPage[1])
+		filter.After = &cursor
+		secondPage, err := s.GetAccountsList(ctx, filter)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"list_a"}, names(secondPage))
+
+		filter = entities.AccountsFilter{NamePrefix: "list_", Sort: entities.SortAccountsByCreatedAt, Descending: true, Limit: 1}
+		firstPage, err = s.GetAccountsList(ctx, filter)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"list_c"}, names(firstPage))
+
+		cursor = entities.NewAccountsCursor(filter, firstPage[0])
+		filter.After, filter.Limit = &cursor, 0
+		secondPage, err = s.GetAccountsList(ctx, filter)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"list_b", "list_a"}, names(secondPage))
+	})
+}
+
+func testRollback(t *testing.T, s storage.Storage) {
+	ctx := context.Background()
+	createAccount(t, s, "dave")
+
+	t.Run("hides uncommitted changes and discards them on rollback", func(t *testing.T) {
+		txStorage, err := s.BeginTx(ctx, nil)
+		require.NoError(t, err)
+
+		_, err = txStorage.CreateAccount(ctx, entities.Account{Name: "erin", Currency: entities.USD})
+		require.NoError(t, err)
+
+		_, err = txStorage.GetAccount(ctx, "erin")
+		assert.NoError(t, err)
+
+		_, err = s.GetAccount(ctx, "erin")
+		assert.Equal(t, storage.ErrNotFound, errors.Cause(err))
+
+		require.NoError(t, txStorage.RollbackTx(ctx))
+
+		_, err = s.GetAccount(ctx, "erin")
+		assert.Equal(t, storage.ErrNotFound, errors.Cause(err))
+	})
+
+	t.Run("discards transfers on rollback", func(t *testing.T) {
+		txStorage, err := s.BeginTx(ctx, nil)
+		require.NoError(t, err)
+
+		transaction := book(t, txStorage, "SYSTEM", "dave", decimal.New(5, 0))
+		require.NoError(t, txStorage.RollbackTx(ctx))
+
+		dave, err := s.GetAccount(ctx, "dave")
+		require.NoError(t, err)
+		assert.True(t, dave.Balance.IsZero())
+
+		_, err = s.GetTransaction(ctx, transaction.ID)
+		assert.Equal(t, storage.ErrNotFound, errors.Cause(err))
+
+		payments, err := s.GetPaymentsList(ctx, entities.PaymentsFilter{AccountName: "dave"})
+		require.NoError(t, err)
+		assert.Empty(t, payments)
+	})
+
+	t.Run("refuses to finish transaction twice", func(t *testing.T) {
+		txStorage, err := s.BeginTx(ctx, nil)
+		require.NoError(t, err)
+
+		require.NoError(t, txStorage.RollbackTx(ctx))
+		assert.Error(t, txStorage.CommitTx(ctx))
+		assert.Error(t, txStorage.RollbackTx(ctx))
+	})
+
+	t.Run("refuses to finish transaction which was not started", func(t *testing.T) {
+		assert.Error(t, s.CommitTx(ctx))
+		assert.Error(t, s.RollbackTx(ctx))
+	})
+}
+
+func testCommit(t *testing.T, s storage.Storage) {
+	ctx := context.Background()
+	createAccount(t, s, "fred")
+
+	t.Run("publishes changes on commit", func(t *testing.T) {
+		txStorage, err := s.BeginTx(ctx, nil)
+		require.NoError(t, err)
+
+		transaction := book(t, txStorage, "SYSTEM", "fred", decimal.New(7, 0))
+		require.NoError(t, txStorage.CommitTx(ctx))
+
+		fred, err := s.GetAccount(ctx, "fred")
+		require.NoError(t, err)
+		assert.Equal(t, "7", fred.Balance.String())
+
+		_, err = s.GetTransaction(ctx, transaction.ID)
+		assert.NoError(t, err)
+
+		assert.Error(t, txStorage.CommitTx(ctx))
+	})
+}
+
+func testTxOptions(t *testing.T, s storage.Storage) {
+	ctx := context.Background()
+	createAccount(t, s, "fiona")
+
+	t.Run("keeps the snapshot within repeatable read transaction", func(t *testing.T) {
+		txStorage, err := s.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelRepeatableRead})
+		require.NoError(t, err)
+		defer txStorage.RollbackTx(ctx)
+
+		before, err := txStorage.GetAccount(ctx, "fiona")
+		require.NoError(t, err)
+
+		fund(t, s, "fiona", decimal.New(1, 0))
+
+		after, err := txStorage.GetAccount(ctx, "fiona")
+		require.NoError(t, err)
+		assert.Equal(t, before.Balance.String(), after.Balance.String())
+	})
+
+	t.Run("sees committed changes within read committed transaction", func(t *testing.T) {
+		txStorage, err := s.BeginTx(ctx, nil)
+		require.NoError(t, err)
+		defer txStorage.RollbackTx(ctx)
+
+		before, err := txStorage.GetAccount(ctx, "fiona")
+		require.NoError(t, err)
+
+		fund(t, s, "fiona", decimal.New(1, 0))
+
+		after, err := txStorage.GetAccount(ctx, "fiona")
+		require.NoError(t, err)
+		assert.Equal(t, before.Balance.Add(decimal.New(1, 0)).String(), after.Balance.String())
+	})
+
+	t.Run("rejects changes within read only transaction", func(t *testing.T) {
+		txStorage, err := s.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+		require.NoError(t, err)
+		defer txStorage.RollbackTx(ctx)
+
+		_, err = txStorage.CreateAccount(ctx, entities.Account{Name: "george", Currency: entities.USD})
+		assert.Error(t, err)
+	})
+}
+
+func testGetAccountForUpdate(t *testing.T, s storage.Storage) {
+	ctx := context.Background()
+	createAccount(t, s, "gina")
+
+	t.Run("fills in the account", func(t *testing.T) {
+		fund(t, s, "gina", decimal.New(3, 0))
+
+		txStorage, err := s.BeginTx(ctx, nil)
+		require.NoError(t, err)
+		defer txStorage.RollbackTx(ctx)
+
+		gina := entities.Account{Name: "gina"}
+		require.NoError(t, txStorage.GetAccountForUpdate(ctx, &gina))
+		assert.NotZero(t, gina.ID)
+		assert.Equal(t, "3", gina.Balance.String())
+		assert.Equal(t, entities.USD, gina.Currency)
+		assert.Equal(t, entities.UserAccount, gina.Type)
+		assert.Equal(t, entities.ActiveAccount, gina.Status)
+		assert.True(t, gina.CreditLimit.IsZero())
+	})
+
+	t.Run("fails for unknown account", func(t *testing.T) {
+		txStorage, err := s.BeginTx(ctx, nil)
+		require.NoError(t, err)
+		defer txStorage.RollbackTx(ctx)
+
+		assert.Error(t, txStorage.GetAccountForUpdate(ctx, &entities.Account{Name: "nobody"}))
+	})
+
+	t.Run("waits for the lock holder and sees its changes", func(t *testing.T) {
+		holder, err := s.BeginTx(ctx, nil)
+		require.NoError(t, err)
+
+		book(t, holder, "SYSTEM", "gina", decimal.New(2, 0))
+
+		locked := make(chan entities.Account)
+		go func() {
+			waiter, err := s.BeginTx(ctx, nil)
+			if err != nil {
+				close(locked)
+				return
+			}
+			defer waiter.RollbackTx(ctx)
+
+			gina := entities.Account{Name: "gina"}
+			if err := waiter.GetAccountForUpdate(ctx, &gina); err != nil {
+				close(locked)
+				return
+			}
+			locked <- gina
+		}()
+
+		select {
+		case <-locked:
+			t.Fatal("lock was obtained while being held by another transaction")
+		case <-time.After(lockWaitTime):
+		}
+
+		require.NoError(t, holder.CommitTx(ctx))
+
+		gina, ok := <-locked
+		require.True(t, ok, "lock was not obtained")
+		assert.Equal(t, "5", gina.Balance.String())
+	})
+}
+
+func testBalanceInvariants(t *testing.T, s storage.Storage) {
+	ctx := context.Background()
+	createAccount(t, s, "hugo")
+	fund(t, s, "hugo", decimal.New(10, 0))
+
+	assertBalance := func(t *testing.T, name string, expected string) {
+		account, err := s.GetAccount(ctx, name)
+		require.NoError(t, err)
+		assert.Equal(t, expected, account.Balance.String())
+	}
+
+	t.Run("lets house accounts go below zero", func(t *testing.T) {
+		system, err := s.GetAccount(ctx, "SYSTEM")
+		require.NoError(t, err)
+		assert.True(t, system.Balance.IsNegative())
+	})
+
+	t.Run("lets accounts go below zero depending on their type", func(t *testing.T) {
+		for accountType, allowed := range map[entities.AccountType]bool{
+			entities.SuspenseAccount: true,
+			entities.FeeAccount:      false,
+		} {
+			name := "HOUSE_" + strings.ToUpper(string(accountType))
+			_, err := s.CreateAccount(ctx, entities.Account{Name: name, Type: accountType, Currency: entities.USD})
+			require.NoError(t, err)
+
+			txStorage, err := s.BeginTx(ctx, nil)
+			require.NoError(t, err)
+
+			house, hugo := lockAccounts(t, txStorage, name, "hugo")
+			transaction, err := txStorage.CreateTransaction(ctx, entities.Transaction{Kind: entities.TransferTransaction})
+			require.NoError(t, err)
+			require.NoError(t, txStorage.SendPayment(ctx, payment(transaction, house, hugo, entities.Outgoing, decimal.New(1, 0))))
+			require.NoError(t, txStorage.SendPayment(ctx, payment(transaction, hugo, house, entities.Incoming, decimal.New(1, 0))))
+
+			house.Balance = decimal.New(-1, 0)
+			assert.Equal(t, allowed, txStorage.SetAccountBalance(ctx, house) == nil, name)
+			require.NoError(t, txStorage.RollbackTx(ctx))
+		}
+	})
+
+	t.Run("rejects user balance going below zero", func(t *testing.T) {
+		txStorage, err := s.BeginTx(ctx, nil)
+		require.NoError(t, err)
+		defer txStorage.RollbackTx(ctx)
+
+		hugo := entities.Account{Name: "hugo"}
+		require.NoError(t, txStorage.GetAccountForUpdate(ctx, &hugo))
+
+		hugo.Balance = decimal.New(-1, 0)
+		assert.Error(t, txStorage.SetAccountBalance(ctx, hugo))
+	})
+
+	t.Run("rejects unbalanced transaction on commit", func(t *testing.T) {
+		txStorage, err := s.BeginTx(ctx, nil)
+		require.NoError(t, err)
+
+		hugo, system := lockAccounts(t, txStorage, "hugo", "SYSTEM")
+		transaction, err := txStorage.CreateTransaction(ctx, entities.Transaction{Kind: entities.TransferTransaction})
+		require.NoError(t, err)
+
+		require.NoError(t, txStorage.SendPayment(ctx, payment(transaction, hugo, system, entities.Outgoing, decimal.New(1, 0))))
+		require.NoError(t, txStorage.SendPayment(ctx, payment(transaction, system, hugo, entities.Incoming, decimal.New(2, 0))))
+
+		assert.Error(t, txStorage.CommitTx(ctx))
+		assertBalance(t, "hugo", "10")
+
+		_, err = s.GetTransaction(ctx, transaction.ID)
+		assert.Equal(t, storage.ErrNotFound, errors.Cause(err))
+	})
+
+	t.Run("rejects balance not matching payments on commit", func(t *testing.T) {
+		txStorage, err := s.BeginTx(ctx, nil)
+		require.NoError(t, err)
+
+		hugo := entities.Account{Name: "hugo"}
+		require.NoError(t, txStorage.GetAccountForUpdate(ctx, &hugo))
+
+		hugo.Balance = hugo.Balance.Add(decimal.New(1, 0))
+		require.NoError(t, txStorage.SetAccountBalance(ctx, hugo))
+
+		assert.Error(t, txStorage.CommitTx(ctx))
+		assertBalance(t, "hugo", "10")
+	})
+
+	t.Run("rejects payments with non-positive amount", func(t *testing.T) {
+		txStorage, err := s.BeginTx(ctx, nil)
+		require.NoError(t, err)
+		defer txStorage.RollbackTx(ctx)
+
+		hugo, system := lockAccounts(t, txStorage, "hugo", "SYSTEM")
+		transaction, err := txStorage.CreateTransaction(ctx, entities.Transaction{Kind: entities.TransferTransaction})
+		require.NoError(t, err)
+
+		assert.Error(t, txStorage.SendPayment(ctx, payment(transaction, hugo, system, entities.Outgoing, decimal.New(0, 0))))
+	})
+}
+
+func testCreditLimits(t *testing.T, s storage.Storage) {
+	ctx := context.Background()
+	ivan := createAccount(t, s, "ivan")
+	fund(t, s, "ivan", decimal.New(10, 0))
+
+	setLimit := func(t *testing.T, limit decimal.Decimal) error {
+		txStorage, err := s.BeginTx(ctx, nil)
+		require.NoError(t, err)
+
+		account := entities.Account{Name: "ivan"}
+		require.NoError(t, txStorage.GetAccountForUpdate(ctx, &account))
+
+		account.CreditLimit = limit
+		if err := txStorage.SetAccountCreditLimit(ctx, account); err != nil {
+			txStorage.RollbackTx(ctx)
+			return err
+		}
+		return txStorage.CommitTx(ctx)
+	}
+
+	t.Run("creates accounts without credit limit", func(t *testing.T) {
+		assert.True(t, ivan.CreditLimit.IsZero())
+	})
+
+	t.Run("rejects negative credit limit", func(t *testing.T) {
+		assert.Error(t, setLimit(t, decimal.New(-1, 0)))
+	})
+
+	t.Run("lets balance go below zero within credit limit", func(t *testing.T) {
+		require.NoError(t, setLimit(t, decimal.New(15, 0)))
+
+		account, err := s.GetAccount(ctx, "ivan")
+		require.NoError(t, err)
+		assert.Equal(t, "15", account.CreditLimit.String())
+
+		transfer(t, s, "ivan", "SYSTEM", decimal.New(25, 0))
+
+		account, err = s.GetAccount(ctx, "ivan")
+		require.NoError(t, err)
+		assert.Equal(t, "-15", account.Balance.String())
+	})
+
+	t.Run("rejects balance going beyond credit limit", func(t *testing.T) {
+		txStorage, err := s.BeginTx(ctx, nil)
+		require.NoError(t, err)
+		defer txStorage.RollbackTx(ctx)
+
+		account := entities.Account{Name: "ivan"}
+		require.NoError(t, txStorage.GetAccountForUpdate(ctx, &account))
+
+		account.Balance = decimal.New(-16, 0)
+		assert.Error(t, txStorage.SetAccountBalance(ctx, account))
+	})
+
+	t.Run("rejects credit limit the balance is below", func(t *testing.T) {
+		assert.Error(t, setLimit(t, decimal.New(14, 0)))
+	})
+
+	t.Run("stores credit limit changes", func(t *testing.T) {
+		first, err := s.CreateCreditLimitChange(ctx, entities.CreditLimitChange{
+			AccountID:     ivan.ID,
+			PreviousLimit: decimal.New(0, 0),
+			NewLimit:      decimal.New(15, 0),
+			ChangedBy:     "alice",
+			Reason:        "salary advance",
+		})
+		require.NoError(t, err)
+		assert.NotZero(t, first.ID)
+		assert.False(t, first.CreatedAt.IsZero())
+
+		_, err = s.CreateCreditLimitChange(ctx, entities.CreditLimitChange{
+			AccountID:     ivan.ID,
+			PreviousLimit: decimal.New(15, 0),
+			NewLimit:      decimal.New(20, 0),
+			ChangedBy:     "bob",
+		})
+		require.NoError(t, err)
+
+		changes, err := s.GetCreditLimitChanges(ctx, ivan.ID)
+		require.NoError(t, err)
+		require.Len(t, changes, 2)
+		assert.Equal(t, "alice", changes[0].ChangedBy)
+		assert.Equal(t, "salary advance", changes[0].Reason)
+		assert.Equal(t, "15", changes[0].NewLimit.String())
+		assert.Equal(t, "bob", changes[1].ChangedBy)
+		assert.Empty(t, changes[1].Reason)
+	})
+
+	t.Run("returns no credit limit changes of untouched account", func(t *testing.T) {
+		changes, err := s.GetCreditLimitChanges(ctx, ivan.ID+1000)
+		require.NoError(t, err)
+		assert.Empty(t, changes)
+	})
+}
+
+func testAccountStatuses(t *testing.T, s storage.Storage) {
+	ctx := context.Background()
+	judy := createAccount(t, s, "judy")
+
+	setStatus := func(t *testing.T, name string, status entities.AccountStatus) error {
+		txStorage, err := s.BeginTx(ctx, nil)
+		require.NoError(t, err)
+
+		account := entities.Account{Name: name}
+		require.NoError(t, txStorage.GetAccountForUpdate(ctx, &account))
+
+		account.Status = status
+		if err := txStorage.SetAccountStatus(ctx, account); err != nil {
+			txStorage.RollbackTx(ctx)
+			return err
+		}
+		return txStorage.CommitTx(ctx)
+	}
+
+	t.Run("creates active accounts", func(t *testing.T) {
+		assert.Equal(t, entities.ActiveAccount, judy.Status)
+
+		system, err := s.GetAccount(ctx, "SYSTEM")
+		require.NoError(t, err)
+		assert.Equal(t, entities.ActiveAccount, system.Status)
+	})
+
+	t.Run("stores account status", func(t *testing.T) {
+		require.NoError(t, setStatus(t, "judy", entities.FrozenAccount))
+
+		account, err := s.GetAccount(ctx, "judy")
+		require.NoError(t, err)
+		assert.Equal(t, entities.FrozenAccount, account.Status)
+
+		accounts, err := s.GetAccountsList(ctx, entities.AccountsFilter{NamePrefix: "judy", Limit: 1})
+		require.NoError(t, err)
+		require.Len(t, accounts, 1)
+		assert.Equal(t, entities.FrozenAccount, accounts[0].Status)
+	})
+
+	t.Run("rejects unknown status", func(t *testing.T) {
+		assert.Error(t, setStatus(t, "judy", entities.AccountStatus("dormant")))
+	})
+
+	t.Run("rejects closing account with non-zero balance", func(t *testing.T) {
+		createAccount(t, s, "kate")
+		fund(t, s, "kate", decimal.New(1, 0))
+
+		assert.Error(t, setStatus(t, "kate", entities.ClosedAccount))
+	})
+
+	t.Run("keeps balance of closed account zero", func(t *testing.T) {
+		createAccount(t, s, "leon")
+		require.NoError(t, setStatus(t, "leon", entities.ClosedAccount))
+
+		txStorage, err := s.BeginTx(ctx, nil)
+		require.NoError(t, err)
+		defer txStorage.RollbackTx(ctx)
+
+		leon := entities.Account{Name: "leon"}
+		require.NoError(t, txStorage.GetAccountForUpdate(ctx, &leon))
+
+		leon.Balance = decimal.New(1, 0)
+		assert.Error(t, txStorage.SetAccountBalance(ctx, leon))
+	})
+}
+
+func testHolds(t *testing.T, s storage.Storage) {
+	ctx := context.Background()
+	createAccount(t, s, "mia")
+	createAccount(t, s, "nick")
+	fund(t, s, "mia", decimal.New(10, 0))
+
+	// hold reserves the amount on the account the way banking service does
+	hold := func(t *testing.T, from, to string, amount decimal.Decimal, expiresAt time.Time) (entities.Hold, error) {
+		txStorage, err := s.BeginTx(ctx, nil)
+		require.NoError(t, err)
+		defer txStorage.RollbackTx(ctx)
+
+		sender, receiver := lockAccounts(t, txStorage, from, to)
+		sender.Held = sender.Held.Add(amount)
+		if err := txStorage.SetAccountHeld(ctx, sender); err != nil {
+			return entities.Hold{}, err
+		}
+
+		created, err := txStorage.CreateHold(ctx, entities.Hold{
+			Account:      sender,
+			Counterparty: receiver,
+			Amount:       amount,
+			Currency:     sender.Currency,
+			Status:       entities.PendingHold,
+			ExpiresAt:    expiresAt,
+		})
+		if err != nil {
+			return entities.Hold{}, err
+		}
+		return created, txStorage.CommitTx(ctx)
+	}
+
+	setHoldStatus := func(t *testing.T, held entities.Hold) error {
+		txStorage, err := s.BeginTx(ctx, nil)
+		require.NoError(t, err)
+		defer txStorage.RollbackTx(ctx)
+
+		if err := txStorage.SetHoldStatus(ctx, held); err != nil {
+			return err
+		}
+		return txStorage.CommitTx(ctx)
+	}
+
+	t.Run("places hold on account", func(t *testing.T) {
+		created, err := hold(t, "mia", "nick", decimal.New(4, 0), time.Now().Add(time.Hour))
+		require.NoError(t, err)
+		assert.NotZero(t, created.ID)
+		assert.False(t, created.CreatedAt.IsZero())
+
+		found, err := s.GetHold(ctx, created.ID)
+		require.NoError(t, err)
+		assert.Equal(t, "mia", found.Account.Name)
+		assert.Equal(t, "nick", found.Counterparty.Name)
+		assert.Equal(t, "4", found.Amount.String())
+		assert.Equal(t, entities.USD, found.Currency)
+		assert.Equal(t, entities.PendingHold, found.Status)
+		assert.Zero(t, found.TransactionID)
+
+		mia, err := s.GetAccount(ctx, "mia")
+		require.NoError(t, err)
+		assert.Equal(t, "4", mia.Held.String())
+		assert.Equal(t, "6", mia.AvailableBalance().String())
+
+		txStorage, err := s.BeginTx(ctx, nil)
+		require.NoError(t, err)
+		defer txStorage.RollbackTx(ctx)
+
+		locked := entities.Account{Name: "mia"}
+		require.NoError(t, txStorage.GetAccountForUpdate(ctx, &locked))
+		assert.Equal(t, "4", locked.Held.String())
+	})
+
+	t.Run("keeps available balance within credit limit", func(t *testing.T) {
+		_, err := hold(t, "mia", "nick", decimal.New(7, 0), time.Now().Add(time.Hour))
+		assert.Error(t, err)
+
+		txStorage, err := s.BeginTx(ctx, nil)
+		require.NoError(t, err)
+		defer txStorage.RollbackTx(ctx)
+
+		mia := entities.Account{Name: "mia"}
+		require.NoError(t, txStorage.GetAccountForUpdate(ctx, &mia))
+		mia.Balance = mia.Balance.Sub(decimal.New(7, 0))
+		assert.Error(t, txStorage.SetAccountBalance(ctx, mia))
+	})
+
+	t.Run("rejects negative held amount", func(t *testing.T) {
+		txStorage, err := s.BeginTx(ctx, nil)
+		require.NoError(t, err)
+		defer txStorage.RollbackTx(ctx)
+
+		nick := entities.Account{Name: "nick"}
+		require.NoError(t, txStorage.GetAccountForUpdate(ctx, &nick))
+		nick.Held = decimal.New(-1, 0)
+		assert.Error(t, txStorage.SetAccountHeld(ctx, nick))
+	})
+
+	t.Run("rejects closing account with holds", func(t *testing.T) {
+		createAccount(t, s, "olga")
+		txStorage, err := s.BeginTx(ctx, nil)
+		require.NoError(t, err)
+		defer txStorage.RollbackTx(ctx)
+
+		olga := entities.Account{Name: "olga"}
+		require.NoError(t, txStorage.GetAccountForUpdate(ctx, &olga))
+		olga.CreditLimit = decimal.New(5, 0)
+		require.NoError(t, txStorage.SetAccountCreditLimit(ctx, olga))
+		olga.Held = decimal.New(2, 0)
+		require.NoError(t, txStorage.SetAccountHeld(ctx, olga))
+
+		olga.Status = entities.ClosedAccount
+		assert.Error(t, txStorage.SetAccountStatus(ctx, olga))
+	})
+
+	t.Run("lists expired pending holds", func(t *testing.T) {
+		createAccount(t, s, "pete")
+		fund(t, s, "pete", decimal.New(10, 0))
+		now := time.Now()
+
+		older, err := hold(t, "pete", "nick", decimal.New(1, 0), now.Add(-2*time.Hour))
+		require.NoError(t, err)
+		newer, err := hold(t, "pete", "nick", decimal.New(1, 0), now.Add(-time.Hour))
+		require.NoError(t, err)
+		voided, err := hold(t, "pete", "nick", decimal.New(1, 0), now.Add(-time.Hour))
+		require.NoError(t, err)
+		_, err = hold(t, "pete", "nick", decimal.New(1, 0), now.Add(time.Hour))
+		require.NoError(t, err)
+
+		voided.Status = entities.VoidedHold
+		require.NoError(t, setHoldStatus(t, voided))
+
+		expired, err := s.GetExpiredHolds(ctx, now, 10)
+		require.NoError(t, err)
+		ids := []int{}
+		for _, found := range expired {
+			if found.Account.Name == "pete" {
+				ids = append(ids, found.ID)
+			}
+		}
+		assert.Equal(t, []int{older.ID, newer.ID}, ids)
+
+		limited, err := s.GetExpiredHolds(ctx, now, 1)
+		require.NoError(t, err)
+		assert.Len(t, limited, 1)
+	})
+
+	t.Run("links captured hold to its transaction", func(t *testing.T) {
+		created, err := hold(t, "mia", "nick", decimal.New(1, 0), time.Now().Add(time.Hour))
+		require.NoError(t, err)
+
+		created.Status = entities.CapturedHold
+		assert.Error(t, setHoldStatus(t, created), "captured hold should have a transaction")
+
+		txStorage, err := s.BeginTx(ctx, nil)
+		require.NoError(t, err)
+		transaction := book(t, txStorage, "mia", "nick", decimal.New(1, 0))
+		created.TransactionID = transaction.ID
+		require.NoError(t, txStorage.SetHoldStatus(ctx, created))
+		require.NoError(t, txStorage.CommitTx(ctx))
+
+		found, err := s.GetHold(ctx, created.ID)
+		require.NoError(t, err)
+		assert.Equal(t, entities.CapturedHold, found.Status)
+		assert.Equal(t, transaction.ID, found.TransactionID)
+	})
+
+	t.Run("returns ErrNotFound for unknown hold", func(t *testing.T) {
+		_, err := s.GetHold(ctx, 1000000)
+		assert.Equal(t, storage.ErrNotFound, errors.Cause(err))
+	})
+}
+
+func testScheduledPayments(t *testing.T, s storage.Storage) {
+	ctx := context.Background()
+	rita := createAccount(t, s, "rita")
+	sam := createAccount(t, s, "sam")
+	fund(t, s, "rita", decimal.New(10, 0))
+	now := time.Now().UTC().Truncate(time.Second)
+
+	schedule := func(t *testing.T, nextRunAt time.Time) entities.ScheduledPayment {
+		created, err := s.CreateScheduledPayment(ctx, entities.ScheduledPayment{
+			Account:      rita,
+			Counterparty: sam,
+			Amount:       decimal.New(1, 0),
+			Currency:     entities.USD,
+			Frequency:    entities.DailyFrequency,
+			Status:       entities.ActiveSchedule,
+			StartAt:      nextRunAt,
+			NextRunAt:    nextRunAt,
+		})
+		require.NoError(t, err)
+		return created
+	}
+
+	t.Run("creates scheduled payment", func(t *testing.T) {
+		created := schedule(t, now.Add(time.Hour))
+		assert.NotZero(t, created.ID)
+		assert.False(t, created.CreatedAt.IsZero())
+
+		found, err := s.GetScheduledPayment(ctx, created.ID)
+		require.NoError(t, err)
+		assert.Equal(t, "rita", found.Account.Name)
+		assert.Equal(t, "sam", found.Counterparty.Name)
+		assert.Equal(t, "1", found.Amount.String())
+		assert.Equal(t, entities.DailyFrequency, found.Frequency)
+		assert.Equal(t, entities.ActiveSchedule, found.Status)
+		assert.True(t, found.NextRunAt.Equal(now.Add(time.Hour)))
+		assert.True(t, found.EndAt.IsZero())
+		assert.True(t, found.RetryAt.IsZero())
+		assert.Empty(t, found.LeaseOwner)
+	})
+
+	t.Run("rejects invalid scheduled payment", func(t *testing.T) {
+		invalid := []entities.ScheduledPayment{
+			{Account: rita, Counterparty: rita, Amount: decimal.New(1, 0), Currency: entities.USD, Frequency: entities.OnceFrequency, Status: entities.ActiveSchedule, StartAt: now, NextRunAt: now},
+			{Account: rita, Counterparty: sam, Amount: decimal.New(0, 0), Currency: entities.USD, Frequency: entities.OnceFrequency, Status: entities.ActiveSchedule, StartAt: now, NextRunAt: now},
+			{Account: rita, Counterparty: sam, Amount: decimal.New(1, 0), Currency: entities.USD, Frequency: "hourly", Status: entities.ActiveSchedule, StartAt: now, NextRunAt: now},
+			{Account: rita, Counterparty: sam, Amount: decimal.New(1, 0), Currency: entities.USD, Frequency: entities.CronFrequency, Status: entities.ActiveSchedule, StartAt: now, NextRunAt: now},
+			{Account: rita, Counterparty: sam, Amount: decimal.New(1, 0), Currency: entities.USD, Frequency: entities.OnceFrequency, Status: "sleeping", StartAt: now, NextRunAt: now},
+		}
+		for _, payment := range invalid {
+			_, err := s.CreateScheduledPayment(ctx, payment)
+			assert.Error(t, err)
+		}
+	})
+
+	t.Run("returns ErrNotFound for unknown scheduled payment", func(t *testing.T) {
+		_, err := s.GetScheduledPayment(ctx, 1000)
+		assert.Equal(t, storage.ErrNotFound, errors.Cause(err))
+
+		txStorage, err := s.BeginTx(ctx, nil)
+		require.NoError(t, err)
+		defer txStorage.RollbackTx(ctx)
+
+		err = txStorage.GetScheduledPaymentForUpdate(ctx, &entities.ScheduledPayment{ID: 1000})
+		assert.Equal(t, storage.ErrNotFound, errors.Cause(err))
+	})
+
+	t.Run("claims due payments once until the lease expires", func(t *testing.T) {
+		later := schedule(t, now.Add(-time.Minute))
+		earlier := schedule(t, now.Add(-time.Hour))
+		paused := schedule(t, now.Add(-time.Hour))
+		paused.Status = entities.PausedSchedule
+		require.NoError(t, s.UpdateScheduledPayment(ctx, paused))
+
+		claimed, err := s.ClaimScheduledPayments(ctx, "one", now, now.Add(time.Minute), 10)
+		require.NoError(t, err)
+		require.Len(t, claimed, 2)
+		assert.Equal(t, earlier.ID, claimed[0].ID)
+		assert.Equal(t, later.ID, claimed[1].ID)
+		assert.Equal(t, "one", claimed[0].LeaseOwner)
+		assert.True(t, claimed[0].LeaseExpiresAt.Equal(now.Add(time.Minute)))
+
+		claimed, err = s.ClaimScheduledPayments(ctx, "two", now, now.Add(time.Minute), 10)
+		require.NoError(t, err)
+		assert.Empty(t, claimed)
+
+		claimed, err = s.ClaimScheduledPayments(ctx, "two", now.Add(2*time.Minute), now.Add(3*time.Minute), 1)
+		require.NoError(t, err)
+		require.Len(t, claimed, 1)
+		assert.Equal(t, earlier.ID, claimed[0].ID)
+		assert.Equal(t, "two", claimed[0].LeaseOwner)
+
+		stored, err := s.GetScheduledPayment(ctx, earlier.ID)
+		require.NoError(t, err)
+		assert.Equal(t, "two", stored.LeaseOwner)
+	})
+
+	t.Run("claims payments due for a retry", func(t *testing.T) {
+		retried := schedule(t, now.Add(-time.Hour))
+		retried.RetryAt = now.Add(time.Hour)
+		retried.Attempts = 1
+		require.NoError(t, s.UpdateScheduledPayment(ctx, retried))
+
+		claimed, err := s.ClaimScheduledPayments(ctx, "three", now, now.Add(time.Minute), 10)
+		require.NoError(t, err)
+		assert.Empty(t, claimed)
+
+		claimed, err = s.ClaimScheduledPayments(ctx, "three", now.Add(2*time.Hour), now.Add(3*time.Hour), 10)
+		require.NoError(t, err)
+		ids := []int{}
+		for _, payment := range claimed {
+			ids = append(ids, payment.ID)
+		}
+		assert.Contains(t, ids, retried.ID)
+	})
+
+	t.Run("records runs linked to transactions", func(t *testing.T) {
+		scheduled := schedule(t, now.Add(time.Hour))
+
+		txStorage, err := s.BeginTx(ctx, nil)
+		require.NoError(t, err)
+		defer txStorage.RollbackTx(ctx)
+
+		locked := entities.ScheduledPayment{ID: scheduled.ID}
+		require.NoError(t, txStorage.GetScheduledPaymentForUpdate(ctx, &locked))
+		assert.Equal(t, "rita", locked.Account.Name)
+
+		transaction := book(t, txStorage, "rita", "sam", decimal.New(1, 0))
+		_, err = txStorage.CreateScheduledRun(ctx, entities.ScheduledRun{
+			ScheduleID:    scheduled.ID,
+			ScheduledFor:  scheduled.NextRunAt,
+			Attempt:       1,
+			Status:        entities.SucceededRun,
+			TransactionID: transaction.ID,
+		})
+		require.NoError(t, err)
+
+		_, err = txStorage.CreateScheduledRun(ctx, entities.ScheduledRun{
+			ScheduleID:    scheduled.ID,
+			ScheduledFor:  scheduled.NextRunAt,
+			Attempt:       1,
+			Status:        entities.SucceededRun,
+			TransactionID: transaction.ID,
+		})
+		assert.Error(t, err)
+	})
+
+	t.Run("lists runs oldest first", func(t *testing.T) {
+		scheduled := schedule(t, now.Add(time.Hour))
+
+		first, err := s.CreateScheduledRun(ctx, entities.ScheduledRun{ScheduleID: scheduled.ID, ScheduledFor: now, Attempt: 1, Status: entities.FailedRun, Error: "sender account has insufficient funds"})
+		require.NoError(t, err)
+		second, err := s.CreateScheduledRun(ctx, entities.ScheduledRun{ScheduleID: scheduled.ID, ScheduledFor: now, Attempt: 2, Status: entities.FailedRun, Error: "sender account has insufficient funds"})
+		require.NoError(t, err)
+
+		_, err = s.CreateScheduledRun(ctx, entities.ScheduledRun{ScheduleID: scheduled.ID, ScheduledFor: now, Attempt: 3, Status: entities.SucceededRun})
+		assert.Error(t, err)
+
+		runs, err := s.GetScheduledRuns(ctx, scheduled.ID)
+		require.NoError(t, err)
+		require.Len(t, runs, 2)
+		assert.Equal(t, first.ID, runs[0].ID)
+		assert.Equal(t, second.ID, runs[1].ID)
+		assert.Equal(t, "sender account has insufficient funds", runs[1].Error)
+	})
+
+	t.Run("lists scheduled payments of the account", func(t *testing.T) {
+		createAccount(t, s, "tom")
+		payments, err := s.GetScheduledPayments(ctx, rita.ID)
+		require.NoError(t, err)
+		assert.NotEmpty(t, payments)
+		for i, payment := range payments {
+			assert.Equal(t, "rita", payment.Account.Name)
+			if i > 0 {
+				assert.True(t, payments[i-1].ID < payment.ID)
+			}
+		}
+
+		all, err := s.GetScheduledPayments(ctx, 0)
+		require.NoError(t, err)
+		assert.Len(t, all, len(payments))
+
+		tom, err := s.GetAccount(ctx, "tom")
+		require.NoError(t, err)
+		none, err := s.GetScheduledPayments(ctx, tom.ID)
+		require.NoError(t, err)
+		assert.Empty(t, none)
+	})
+}
+
+func testPaymentsList(t *testing.T, s storage.Storage) {
+	ctx := context.Background()
+	ivan := createAccount(t, s, "ivan")
+	createAccount(t, s, "jane")
+	for _, amount := range []int64{1, 2, 3} {
+		fund(t, s, "ivan", decimal.New(amount, 0))
+	}
+	transfer(t, s, "ivan", "jane", decimal.New(4, 0))
+
+	amounts := func(payments []entities.Payment) []string {
+		result := make([]string, len(payments))
+		for index, payment := range payments {
+			result[index] = payment.Amount.String()
+		}
+		return result
+	}
+
+	t.Run("returns payments of the account in order of booking", func(t *testing.T) {
+		payments, err := s.GetPaymentsList(ctx, entities.PaymentsFilter{AccountName: "ivan"})
+		require.NoError(t, err)
+		require.Equal(t, []string{"1", "2", "3", "4"}, amounts(payments))
+
+		last := payments[3]
+		assert.NotZero(t, last.ID)
+		assert.Equal(t, ivan.ID, last.Account.ID)
+		assert.Equal(t, "ivan", last.Account.Name)
+		assert.Equal(t, "jane", last.Counterparty.Name)
+		assert.Equal(t, entities.Outgoing, last.Direction)
+		assert.Equal(t, entities.USD, last.Currency)
+		assert.NotZero(t, last.Transaction.ID)
+		assert.False(t, last.Transaction.CreatedAt.IsZero())
+	})
+
+	t.Run("filters payments", func(t *testing.T) {
+		payments, err := s.GetPaymentsList(ctx, entities.PaymentsFilter{AccountName: "ivan", Direction: entities.Incoming})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"1", "2", "3"}, amounts(payments))
+
+		filter := entities.PaymentsFilter{
+			AccountName: "ivan",
+			MinAmount:   decimal.NullDecimal{Decimal: decimal.New(2, 0), Valid: true},
+			MaxAmount:   decimal.NullDecimal{Decimal: decimal.New(3, 0), Valid: true},
+		}
+		payments, err = s.GetPaymentsList(ctx, filter)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"2", "3"}, amounts(payments))
+
+		payments, err = s.GetPaymentsList(ctx, entities.PaymentsFilter{AccountName: "nobody"})
+		require.NoError(t, err)
+		assert.NotNil(t, payments)
+		assert.Empty(t, payments)
+	})
+
+	t.Run("pages payments", func(t *testing.T) {
+		filter := entities.PaymentsFilter{AccountName: "ivan", Limit: 3}
+		firstPage, err := s.GetPaymentsList(ctx, filter)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"1", "2", "3"}, amounts(firstPage))
+
+		cursor := entities.NewPaymentsCursor(firstPage[2])
+		filter.After = &cursor
+		secondPage, err := s.GetPaymentsList(ctx, filter)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"4"}, amounts(secondPage))
+	})
+
+	t.Run("returns payments of the account by its ID", func(t *testing.T) {
+		payments, err := s.GetAccountPayments(ctx, ivan.ID, entities.PaymentsFilter{AccountName: "jane", Direction: entities.Outgoing})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"4"}, amounts(payments))
+	})
+}
+
+func testTransactions(t *testing.T, s storage.Storage) {
+	ctx := context.Background()
+	createAccount(t, s, "kate")
+
+	txStorage, err := s.BeginTx(ctx, nil)
+	require.NoError(t, err)
+	created := book(t, txStorage, "SYSTEM", "kate", decimal.New(8, 0))
+	require.NoError(t, txStorage.CommitTx(ctx))
+
+	t.Run("returns transaction by ID", func(t *testing.T) {
+		transaction, err := s.GetTransaction(ctx, created.ID)
+		require.NoError(t, err)
+		assert.Equal(t, created.ID, transaction.ID)
+		assert.False(t, transaction.CreatedAt.IsZero())
+	})
+
+	t.Run("returns payments of the transaction", func(t *testing.T) {
+		payments, err := s.GetTransactionPayments(ctx, created.ID)
+		require.NoError(t, err)
+		require.Len(t, payments, 2)
+		assert.Equal(t, "SYSTEM", payments[0].Account.Name)
+		assert.Equal(t, entities.Outgoing, payments[0].Direction)
+		assert.Equal(t, "kate", payments[1].Account.Name)
+		assert.Equal(t, entities.Incoming, payments[1].Direction)
+	})
+
+	t.Run("returns ErrNotFound for unknown transaction", func(t *testing.T) {
+		_, err := s.GetTransaction(ctx, created.ID+1000)
+		assert.Equal(t, storage.ErrNotFound, errors.Cause(err))
+	})
+
+	t.Run("stores kind and external reference", func(t *testing.T) {
+		assert.Equal(t, entities.TransferTransaction, created.Kind)
+
+		txStorage, err := s.BeginTx(ctx, nil)
+		require.NoError(t, err)
+		deposit := bookAs(t, txStorage, entities.Transaction{Kind: entities.DepositTransaction, ExternalReference: "wire-1"}, "SYSTEM", "kate", decimal.New(2, 0))
+		require.NoError(t, txStorage.CommitTx(ctx))
+
+		transaction, err := s.GetTransaction(ctx, deposit.ID)
+		require.NoError(t, err)
+		assert.Equal(t, entities.DepositTransaction, transaction.Kind)
+		assert.Equal(t, "wire-1", transaction.ExternalReference)
+
+		payments, err := s.GetPaymentsList(ctx, entities.PaymentsFilter{AccountName: "kate", Kind: entities.DepositTransaction, Limit: 10})
+		require.NoError(t, err)
+		require.Len(t, payments, 1)
+		assert.Equal(t, deposit.ID, payments[0].Transaction.ID)
+		assert.Equal(t, "wire-1", payments[0].Transaction.ExternalReference)
+	})
+
+	t.Run("rejects external reference used by transaction of the same kind", func(t *testing.T) {
+		txStorage, err := s.BeginTx(ctx, nil)
+		require.NoError(t, err)
+		defer txStorage.RollbackTx(ctx)
+
+		_, err = txStorage.CreateTransaction(ctx, entities.Transaction{Kind: entities.DepositTransaction, ExternalReference: "wire-1"})
+		assert.Equal(t, storage.ErrAlreadyExists, errors.Cause(err))
+	})
+
+	t.Run("accepts external reference used by transaction of another kind", func(t *testing.T) {
+		txStorage, err := s.BeginTx(ctx, nil)
+		require.NoError(t, err)
+		defer txStorage.RollbackTx(ctx)
+
+		_, err = txStorage.CreateTransaction(ctx, entities.Transaction{Kind: entities.WithdrawalTransaction, ExternalReference: "wire-1"})
+		assert.NoError(t, err)
+	})
+
+	t.Run("stores details of transfer and keeps its reference unique per sender", func(t *testing.T) {
+		createAccount(t, s, "lena")
+		fund(t, s, "kate", decimal.New(2, 0))
+		fund(t, s, "lena", decimal.New(2, 0))
+		kate, err := s.GetAccount(ctx, "kate")
+		require.NoError(t, err)
+		lena, err := s.GetAccount(ctx, "lena")
+		require.NoError(t, err)
+
+		details := entities.Transaction{
+			Kind:              entities.TransferTransaction,
+			SenderID:          kate.ID,
+			ExternalReference: "inv-1",
+			Description:       "April rent",
+			Metadata:          map[string]string{"order": "42"},
+		}
+		txStorage, err := s.BeginTx(ctx, nil)
+		require.NoError(t, err)
+		paid := bookAs(t, txStorage, details, "kate", "lena", decimal.New(1, 0))
+		require.NoError(t, txStorage.CommitTx(ctx))
+
+		transaction, err := s.GetTransaction(ctx, paid.ID)
+		require.NoError(t, err)
+		assert.Equal(t, kate.ID, transaction.SenderID)
+		assert.Equal(t, "inv-1", transaction.ExternalReference)
+		assert.Equal(t, "April rent", transaction.Description)
+		assert.Equal(t, map[string]string{"order": "42"}, transaction.Metadata)
+
+		payments, err := s.GetPaymentsList(ctx, entities.PaymentsFilter{Reference: "inv-1"})
+		require.NoError(t, err)
+		require.Len(t, payments, 2)
+		assert.Equal(t, "April rent", payments[0].Transaction.Description)
+		assert.Equal(t, map[string]string{"order": "42"}, payments[1].Transaction.Metadata)
+
+		create := func(transaction entities.Transaction) error {
+			txStorage, err := s.BeginTx(ctx, nil)
+			require.NoError(t, err)
+			defer txStorage.RollbackTx(ctx)
+
+			_, err = txStorage.CreateTransaction(ctx, transaction)
+			return err
+		}
+
+		err = create(entities.Transaction{Kind: entities.TransferTransaction, SenderID: kate.ID, ExternalReference: "inv-1"})
+		assert.Equal(t, storage.ErrAlreadyExists, errors.Cause(err))
+
+		assert.NoError(t, create(entities.Transaction{Kind: entities.TransferTransaction, SenderID: lena.ID, ExternalReference: "inv-1"}))
+		assert.NoError(t, create(entities.Transaction{Kind: entities.DepositTransaction, ExternalReference: "inv-1"}))
+		assert.Error(t, create(entities.Transaction{Kind: entities.TransferTransaction, SenderID: 100000, ExternalReference: "inv-2"}))
+	})
+
+	t.Run("links refunds to the original transaction", func(t *testing.T) {
+		txStorage, err := s.BeginTx(ctx, nil)
+		require.NoError(t, err)
+		first := bookAs(t, txStorage, entities.Transaction{Kind: entities.RefundTransaction, OriginalID: created.ID}, "kate", "SYSTEM", decimal.New(3, 0))
+		second := bookAs(t, txStorage, entities.Transaction{Kind: entities.RefundTransaction, OriginalID: created.ID}, "kate", "SYSTEM", decimal.New(1, 0))
+		require.NoError(t, txStorage.CommitTx(ctx))
+
+		transaction, err := s.GetTransaction(ctx, first.ID)
+		require.NoError(t, err)
+		assert.Equal(t, created.ID, transaction.OriginalID)
+
+		linked, err := s.GetLinkedTransactions(ctx, created.ID)
+		require.NoError(t, err)
+		require.Len(t, linked, 2)
+		assert.Equal(t, first.ID, linked[0].ID)
+		assert.Equal(t, second.ID, linked[1].ID)
+		assert.Equal(t, entities.RefundTransaction, linked[1].Kind)
+
+		payments, err := s.GetPaymentsList(ctx, entities.PaymentsFilter{AccountName: "kate", Kind: entities.RefundTransaction, Limit: 10})
+		require.NoError(t, err)
+		require.Len(t, payments, 2)
+		assert.Equal(t, created.ID, payments[0].Transaction.OriginalID)
+	})
+
+	t.Run("returns no linked transactions of untouched transaction", func(t *testing.T) {
+		linked, err := s.GetLinkedTransactions(ctx, created.ID+1000)
+		require.NoError(t, err)
+		assert.Empty(t, linked)
+	})
+
+	t.Run("rejects reversals and refunds without original transaction", func(t *testing.T) {
+		for _, transaction := range []entities.Transaction{
+			{Kind: entities.ReversalTransaction},
+			{Kind: entities.RefundTransaction, OriginalID: created.ID + 1000},
+			{Kind: entities.TransferTransaction, OriginalID: created.ID},
+		} {
+			txStorage, err := s.BeginTx(ctx, nil)
+			require.NoError(t, err)
+
+			_, err = txStorage.CreateTransaction(ctx, transaction)
+			assert.Error(t, err, transaction.Kind)
+			require.NoError(t, txStorage.RollbackTx(ctx))
+		}
+	})
+
+	t.Run("stores fee charged by the transaction", func(t *testing.T) {
+		txStorage, err := s.BeginTx(ctx, nil)
+		require.NoError(t, err)
+		charged := bookAs(t, txStorage, entities.Transaction{Kind: entities.TransferTransaction, Fee: decimal.New(1, 0)}, "kate", "FEE_USD", decimal.New(1, 0))
+		require.NoError(t, txStorage.CommitTx(ctx))
+
+		transaction, err := s.GetTransaction(ctx, charged.ID)
+		require.NoError(t, err)
+		assert.Equal(t, "1", transaction.Fee.String())
+
+		payments, err := s.GetTransactionPayments(ctx, charged.ID)
+		require.NoError(t, err)
+		require.Len(t, payments, 2)
+		assert.Equal(t, "1", payments[0].Transaction.Fee.String())
+
+		transaction, err = s.GetTransaction(ctx, created.ID)
+		require.NoError(t, err)
+		assert.True(t, transaction.Fee.IsZero())
+	})
+
+	t.Run("rejects negative fee", func(t *testing.T) {
+		txStorage, err := s.BeginTx(ctx, nil)
+		require.NoError(t, err)
+		defer txStorage.RollbackTx(ctx)
+
+		_, err = txStorage.CreateTransaction(ctx, entities.Transaction{Kind: entities.TransferTransaction, Fee: decimal.New(-1, 0)})
+		assert.Error(t, err)
+	})
+}
+
+func testQuotes(t *testing.T, s storage.Storage) {
+	ctx := context.Background()
+	newQuote := func() entities.Quote {
+		return entities.Quote{
+			FromCurrency: entities.PHP,
+			ToCurrency:   entities.USD,
+			Rate:         decimal.RequireFromString("0.0191"),
+			SourceAmount: decimal.New(1000, 0),
+			TargetAmount: decimal.RequireFromString("19.1"),
+			ExpiresAt:    time.Now().Add(time.Minute),
+		}
+	}
+
+	t.Run("creates and links quote to transaction", func(t *testing.T) {
+		quote, err := s.CreateQuote(ctx, newQuote())
+		require.NoError(t, err)
+		assert.NotZero(t, quote.ID)
+		assert.False(t, quote.CreatedAt.IsZero())
+
+		txStorage, err := s.BeginTx(ctx, nil)
+		require.NoError(t, err)
+
+		locked := entities.Quote{ID: quote.ID}
+		require.NoError(t, txStorage.GetQuoteForUpdate(ctx, &locked))
+		assert.False(t, locked.IsUsed())
+		assert.Equal(t, "19.1", locked.TargetAmount.String())
+
+		transaction, err := txStorage.CreateTransaction(ctx, entities.Transaction{Kind: entities.TransferTransaction})
+		require.NoError(t, err)
+
+		locked.TransactionID = transaction.ID
+		require.NoError(t, txStorage.SetQuoteTransaction(ctx, locked))
+		require.NoError(t, txStorage.CommitTx(ctx))
+
+		txStorage, err = s.BeginTx(ctx, nil)
+		require.NoError(t, err)
+		defer txStorage.RollbackTx(ctx)
+
+		used := entities.Quote{ID: quote.ID}
+		require.NoError(t, txStorage.GetQuoteForUpdate(ctx, &used))
+		assert.Equal(t, transaction.ID, used.TransactionID)
+	})
+
+	t.Run("rejects invalid quote", func(t *testing.T) {
+		quote := newQuote()
+		quote.Rate = decimal.New(0, 0)
+		_, err := s.CreateQuote(ctx, quote)
+		assert.Error(t, err)
+	})
+
+	t.Run("returns ErrNotFound for unknown quote", func(t *testing.T) {
+		txStorage, err := s.BeginTx(ctx, nil)
+		require.NoError(t, err)
+		defer txStorage.RollbackTx(ctx)
+
+		err = txStorage.GetQuoteForUpdate(ctx, &entities.Quote{ID: 1000})
+		assert.Equal(t, storage.ErrNotFound, errors.Cause(err))
+	})
+}
+
+func testIdempotencyRecords(t *testing.T, s storage.Storage) {
+	ctx := context.Background()
+	record := entities.IdempotencyRecord{Key: "key-1", RequestHash: "hash"}
+
+	t.Run("reserves key once", func(t *testing.T) {
+		created, err := s.CreateIdempotencyRecord(ctx, record)
+		require.NoError(t, err)
+		assert.True(t, created)
+
+		created, err = s.CreateIdempotencyRecord(ctx, record)
+		require.NoError(t, err)
+		assert.False(t, created)
+
+		stored, err := s.GetIdempotencyRecord(ctx, record.Key)
+		require.NoError(t, err)
+		assert.Equal(t, "hash", stored.RequestHash)
+		assert.False(t, stored.IsCompleted())
+	})
+
+	t.Run("stores response", func(t *testing.T) {
+		completed := record
+		completed.ResponseStatus = 201
+		completed.ResponseHeaders = map[string]string{"Location": "/transactions/1"}
+		completed.ResponseBody = []byte(`{}`)
+		require.NoError(t, s.CompleteIdempotencyRecord(ctx, completed))
+
+		stored, err := s.GetIdempotencyRecord(ctx, record.Key)
+		require.NoError(t, err)
+		assert.True(t, stored.IsCompleted())
+		assert.Equal(t, completed.ResponseHeaders, stored.ResponseHeaders)
+		assert.Equal(t, completed.ResponseBody, stored.ResponseBody)
+	})
+
+	t.Run("releases key", func(t *testing.T) {
+		require.NoError(t, s.DeleteIdempotencyRecord(ctx, record))
+
+		_, err := s.GetIdempotencyRecord(ctx, record.Key)
+		assert.Equal(t, storage.ErrNotFound, errors.Cause(err))
+	})
+
+	expired := entities.IdempotencyRecord{Key: "key-2", RequestHash: "hash", LockID: "lock-1", LockedUntil: time.Now().Add(-time.Minute)}
+	retried := expired
+	retried.LockID, retried.LockedUntil = "lock-2", time.Now().Add(time.Hour)
+
+	t.Run("takes over expired reservations of the same request", func(t *testing.T) {
+		created, err := s.CreateIdempotencyRecord(ctx, expired)
+		require.NoError(t, err)
+		require.True(t, created)
+
+		another := retried
+		another.RequestHash = "another hash"
+		created, err = s.CreateIdempotencyRecord(ctx, another)
+		require.NoError(t, err)
+		assert.False(t, created)
+
+		created, err = s.CreateIdempotencyRecord(ctx, retried)
+		require.NoError(t, err)
+		assert.True(t, created)
+
+		created, err = s.CreateIdempotencyRecord(ctx, retried)
+		require.NoError(t, err)
+		assert.False(t, created)
+
+		stored, err := s.GetIdempotencyRecord(ctx, retried.Key)
+		require.NoError(t, err)
+		assert.Equal(t, "lock-2", stored.LockID)
+		assert.WithinDuration(t, retried.LockedUntil, stored.LockedUntil, time.Second)
+	})
+
+	t.Run("commits key within transaction", func(t *testing.T) {
+		txStorage, err := s.BeginTx(ctx, nil)
+		require.NoError(t, err)
+		defer txStorage.RollbackTx(ctx)
+
+		err = txStorage.CommitIdempotencyRecord(ctx, expired)
+		assert.Equal(t, storage.ErrAlreadyExists, errors.Cause(err))
+
+		require.NoError(t, txStorage.CommitIdempotencyRecord(ctx, retried))
+
+		stored, err := s.GetIdempotencyRecord(ctx, retried.Key)
+		require.NoError(t, err)
+		assert.False(t, stored.IsCommitted())
+
+		require.NoError(t, txStorage.CommitTx(ctx))
+
+		stored, err = s.GetIdempotencyRecord(ctx, retried.Key)
+		require.NoError(t, err)
+		assert.True(t, stored.IsCommitted())
+	})
+
+	t.Run("keeps committed key", func(t *testing.T) {
+		err := s.CommitIdempotencyRecord(ctx, retried)
+		assert.Equal(t, storage.ErrAlreadyExists, errors.Cause(err))
+
+		require.NoError(t, s.DeleteIdempotencyRecord(ctx, retried))
+
+		stored, err := s.GetIdempotencyRecord(ctx, retried.Key)
+		require.NoError(t, err)
+		assert.True(t, stored.IsCommitted())
+	})
+
+	t.Run("discards commit of rolled back transaction", func(t *testing.T) {
+		pending := entities.IdempotencyRecord{Key: "key-3", RequestHash: "hash", LockID: "lock-3"}
+		created, err := s.CreateIdempotencyRecord(ctx, pending)
+		require.NoError(t, err)
+		require.True(t, created)
+
+		txStorage, err := s.BeginTx(ctx, nil)
+		require.NoError(t, err)
+		require.NoError(t, txStorage.CommitIdempotencyRecord(ctx, pending))
+		require.NoError(t, txStorage.RollbackTx(ctx))
+
+		stored, err := s.GetIdempotencyRecord(ctx, pending.Key)
+		require.NoError(t, err)
+		assert.False(t, stored.IsCommitted())
+		assert.True(t, stored.IsLocked(time.Now()))
+	})
+}
+
+func testAPIKeys(t *testing.T, s storage.Storage) {
+	ctx := context.Background()
+
+	t.Run("stores key", func(t *testing.T) {
+		key, err := s.CreateAPIKey(ctx, entities.APIKey{
+			KeyID:        "ak_1",
+			Name:         "alice's shop",
+			SecretHash:   "hash",
+			SealedSecret: "sealed",
+			Role:         entities.AccountOwnerRole,
+			OwnerID:      "customer-1",
+		})
+		require.NoError(t, err)
+		assert.NotZero(t, key.ID)
+		assert.False(t, key.CreatedAt.IsZero())
+
+		stored, err := s.GetAPIKey(ctx, "ak_1")
+		require.NoError(t, err)
+		assert.Equal(t, key.ID, stored.ID)
+		assert.Equal(t, "alice's shop", stored.Name)
+		assert.Equal(t, "hash", stored.SecretHash)
+		assert.Equal(t, "sealed", stored.SealedSecret)
+		assert.Equal(t, entities.AccountOwnerRole, stored.Role)
+		assert.Equal(t, "customer-1", stored.OwnerID)
+		assert.False(t, stored.IsRevoked())
+	})
+
+	t.Run("refuses account owner key without owner", func(t *testing.T) {
+		_, err := s.CreateAPIKey(ctx, entities.APIKey{KeyID: "ak_2", Name: "orphan", SecretHash: "hash", Role: entities.AccountOwnerRole})
+		assert.Error(t, err)
+	})
+
+	t.Run("refuses duplicate key id", func(t *testing.T) {
+		_, err := s.CreateAPIKey(ctx, entities.APIKey{KeyID: "ak_1", Name: "copy", SecretHash: "hash", Role: entities.ViewerRole})
+		assert.Error(t, err)
+	})
+
+	t.Run("revokes key once", func(t *testing.T) {
+		_, err := s.CreateAPIKey(ctx, entities.APIKey{KeyID: "ak_3", Name: "back office", SecretHash: "hash", Role: entities.OperatorRole})
+		require.NoError(t, err)
+
+		revokedAt := time.Now().Add(-time.Hour).Truncate(time.Second)
+		require.NoError(t, s.RevokeAPIKey(ctx, entities.APIKey{KeyID: "ak_3", RevokedAt: revokedAt}))
+		require.NoError(t, s.RevokeAPIKey(ctx, entities.APIKey{KeyID: "ak_3", RevokedAt: time.Now()}))
+
+		stored, err := s.GetAPIKey(ctx, "ak_3")
+		require.NoError(t, err)
+		assert.True(t, stored.IsRevoked())
+		assert.True(t, revokedAt.Equal(stored.RevokedAt))
+
+		keys, err := s.GetAPIKeys(ctx)
+		require.NoError(t, err)
+		require.Len(t, keys, 2)
+		assert.Equal(t, "ak_1", keys[0].KeyID)
+		assert.Equal(t, "ak_3", keys[1].KeyID)
+	})
+
+	t.Run("returns ErrNotFound for unknown key", func(t *testing.T) {
+		_, err := s.GetAPIKey(ctx, "ak_unknown")
+		assert.Equal(t, storage.ErrNotFound, errors.Cause(err))
+	})
+}
+
+func testAuditLog(t *testing.T, s storage.Storage) {
+	ctx := context.Background()
+	// entries are stamped by the storage, so they are all created after it
+	since := time.Date(2019, 4, 21, 10, 0, 0, 0, time.UTC)
+
+	// appendEntry appends an entry of the action within a transaction of its own
+	appendEntry := func(t *testing.T, s storage.Storage, action string, subject string) entities.AuditEntry {
+		txStorage, err := s.BeginTx(ctx, nil)
+		require.NoError(t, err)
+		defer txStorage.RollbackTx(ctx)
+
+		entry, err := txStorage.AppendAuditEntry(ctx, entities.AuditEntry{
+			Subject:       subject,
+			Role:          entities.AccountOwnerRole,
+			Action:        action,
+			PayloadHash:   "hash",
+			Outcome:       entities.SucceededOutcome,
+			CorrelationID: "request-" + action,
+		})
+		require.NoError(t, err)
+		require.NoError(t, txStorage.CommitTx(ctx))
+		return entry
+	}
+
+	t.Run("chains entries", func(t *testing.T) {
+		first := appendEntry(t, s, "accounts.create", "ak_1")
+		assert.Equal(t, 1, first.ID)
+		assert.Empty(t, first.PrevHash)
+		assert.Equal(t, first.ChainHash(), first.Hash)
+
+		second := appendEntry(t, s, "payments.send", "ak_2")
+		assert.Equal(t, 2, second.ID)
+		assert.Equal(t, first.Hash, second.PrevHash)
+
+		entries, err := s.GetAuditEntries(ctx, entities.AuditFilter{})
+		require.NoError(t, err)
+		require.Len(t, entries, 2)
+		assert.True(t, entries[0].Follows(entities.AuditEntry{}))
+		assert.True(t, entries[1].Follows(entries[0]))
+		assert.Equal(t, "request-payments.send", entries[1].CorrelationID)
+		assert.True(t, second.CreatedAt.Equal(entries[1].CreatedAt))
+		assert.False(t, entries[1].CreatedAt.Before(entries[0].CreatedAt))
+	})
+
+	t.Run("discards entries of rolled back transactions", func(t *testing.T) {
+		txStorage, err := s.BeginTx(ctx, nil)
+		require.NoError(t, err)
+
+		_, err = txStorage.AppendAuditEntry(ctx, entities.AuditEntry{Action: "accounts.freeze", Outcome: entities.SucceededOutcome})
+		require.NoError(t, err)
+		require.NoError(t, txStorage.RollbackTx(ctx))
+
+		third := appendEntry(t, s, "accounts.close", "ak_1")
+		assert.Equal(t, 3, third.ID)
+	})
+
+	t.Run("serializes concurrent appends", func(t *testing.T) {
+		first, err := s.BeginTx(ctx, nil)
+		require.NoError(t, err)
+
+		entry, err := first.AppendAuditEntry(ctx, entities.AuditEntry{Action: "accounts.freeze", Outcome: entities.SucceededOutcome})
+		require.NoError(t, err)
+
+		second, err := s.BeginTx(ctx, nil)
+		require.NoError(t, err)
+		defer second.RollbackTx(ctx)
+
+		appended := make(chan error)
+		var next entities.AuditEntry
+		go func() {
+			var err error
+			next, err = second.AppendAuditEntry(ctx, entities.AuditEntry{Subject: "ak_1", Action: "accounts.unfreeze", Outcome: entities.SucceededOutcome})
+			if err != nil {
+				appended <- err
+				return
+			}
+			appended <- second.CommitTx(ctx)
+		}()
+
+		time.Sleep(lockWaitTime)
+		require.NoError(t, first.CommitTx(ctx))
+
+		require.NoError(t, <-appended)
+		assert.Equal(t, entry.ID+1, next.ID)
+		assert.Equal(t, entry.Hash, next.PrevHash)
+		assert.False(t, next.CreatedAt.Before(entry.CreatedAt))
+	})
+
+	t.Run("filters entries", func(t *testing.T) {
+		entries, err := s.GetAuditEntries(ctx, entities.AuditFilter{Subject: "ak_1", Limit: 2})
+		require.NoError(t, err)
+		require.Len(t, entries, 2)
+		assert.Equal(t, "accounts.create", entries[0].Action)
+		assert.Equal(t, "accounts.close", entries[1].Action)
+
+		entries, err = s.GetAuditEntries(ctx, entities.AuditFilter{Subject: "ak_1", After: &entities.AuditCursor{ID: entries[1].ID}})
+		require.NoError(t, err)
+		require.Len(t, entries, 1)
+		assert.Equal(t, "accounts.unfreeze", entries[0].Action)
+
+		entries, err = s.GetAuditEntries(ctx, entities.AuditFilter{Action: "payments.send", CorrelationID: "request-payments.send", Outcome: entities.SucceededOutcome})
+		require.NoError(t, err)
+		require.Len(t, entries, 1)
+		assert.Equal(t, "ak_2", entries[0].Subject)
+
+		entries, err = s.GetAuditEntries(ctx, entities.AuditFilter{ToDate: since})
+		require.NoError(t, err)
+		assert.Empty(t, entries)
+	})
+
+	t.Run("refuses unknown outcome", func(t *testing.T) {
+		txStorage, err := s.BeginTx(ctx, nil)
+		require.NoError(t, err)
+		defer txStorage.RollbackTx(ctx)
+
+		_, err = txStorage.AppendAuditEntry(ctx, entities.AuditEntry{Action: "accounts.close", Outcome: "unknown"})
+		assert.Error(t, err)
+	})
+}
+
+func testLedger(t *testing.T, s storage.Storage) {
+	ctx := context.Background()
+	createAccount(t, s, "liam")
+	createAccount(t, s, "mia")
+	fund(t, s, "liam", decimal.New(10, 0))
+	transfer(t, s, "liam", "mia", decimal.New(4, 0))
+
+	t.Run("returns balances along with sums of payments", func(t *testing.T) {
+		balances, err := s.GetLedgerBalances(ctx)
+		require.NoError(t, err)
+
+		totals := make(map[string]string)
+		for _, balance := range balances {
+			assert.Equal(t, balance.Account.Balance.String(), balance.PaymentsTotal.String(), balance.Account.Name)
+			totals[balance.Account.Name] = balance.PaymentsTotal.String()
+		}
+
+		assert.Equal(t, "6", totals["liam"])
+		assert.Equal(t, "4", totals["mia"])
+		assert.Equal(t, "-10", totals["SYSTEM"])
+		assert.Equal(t, "0", totals["FX_USD"])
+	})
+
+	t.Run("returns no unbalanced transactions", func(t *testing.T) {
+		imbalances, err := s.GetUnbalancedTransactions(ctx)
+		require.NoError(t, err)
+		assert.NotNil(t, imbalances)
+		assert.Empty(t, imbalances)
+	})
+}